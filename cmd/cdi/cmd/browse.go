@@ -0,0 +1,309 @@
+/*
+   Copyright © 2021 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/parser"
+)
+
+const (
+	// browseHelp describes the commands of the browser.
+	browseHelp = `commands: <number> select, .. back, /text search, / clear search, r refresh, q quit`
+)
+
+type browseFlags struct {
+	interval time.Duration
+	noClear  bool
+}
+
+// browseCmd is our command for interactively browsing the CDI cache.
+var browseCmd = &cobra.Command{
+	Use:   "browse",
+	Short: "Interactively browse the CDI cache",
+	Long: `
+The 'browse' command lets you interactively browse the CDI cache, from
+vendors to their device classes, devices and finally the container edits
+of a device. Select an entry by its number, go back with '..', search the
+current list with '/text', and clear the search with '/'. The cache is
+refreshed automatically when Spec files change, and the current view is
+redrawn when the content of the cache changes. Use 'r' to refresh the
+cache manually and 'q' to quit.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cdiBrowse(os.Stdin, os.Stdout, browseCfg.interval, !browseCfg.noClear); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// browser is the state of an interactive cache browser.
+type browser struct {
+	sync.Mutex
+	cache  *cdi.Cache
+	out    io.Writer
+	clear  bool
+	vendor string
+	class  string
+	device string
+	filter string
+	items  []string
+	notice string
+	state  string
+}
+
+func cdiBrowse(in io.Reader, out io.Writer, interval time.Duration, clear bool) error {
+	b := &browser{
+		cache: cdi.GetDefaultCache(),
+		out:   out,
+		clear: clear && isTerminal(out),
+	}
+
+	b.Lock()
+	b.state = b.cacheState()
+	b.draw()
+	b.Unlock()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	if interval > 0 {
+		go b.watch(stop, interval)
+	}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if !b.handle(strings.TrimSpace(scanner.Text())) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// isTerminal returns true if the given output is a terminal.
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// watch redraws the browser whenever the content of the cache changes.
+func (b *browser) watch(stop <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			b.Lock()
+			if state := b.cacheState(); state != b.state {
+				b.state = state
+				b.notice = "CDI cache content changed"
+				b.draw()
+			}
+			b.Unlock()
+		}
+	}
+}
+
+// cacheState returns a summary of the cache content for detecting changes.
+func (b *browser) cacheState() string {
+	state := strings.Join(b.cache.ListDevices(), ",")
+	for _, vendor := range b.cache.ListVendors() {
+		for _, spec := range b.cache.GetVendorSpecs(vendor) {
+			state += "|" + spec.GetPath()
+		}
+	}
+	return fmt.Sprintf("%s|%d", state, len(b.cache.GetErrors()))
+}
+
+// handle handles a single command. It returns false if the browser should quit.
+func (b *browser) handle(command string) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	b.notice = ""
+	switch {
+	case command == "q" || command == "quit" || command == "exit":
+		return false
+	case command == "":
+	case command == ".." || command == "b" || command == "back":
+		b.back()
+	case command == "r" || command == "refresh":
+		if err := b.cache.Refresh(); err != nil {
+			b.notice = fmt.Sprintf("CDI cache refreshed with errors: %v", err)
+		} else {
+			b.notice = "CDI cache refreshed"
+		}
+		b.state = b.cacheState()
+	case command == "?" || command == "h" || command == "help":
+		b.notice = browseHelp
+	case strings.HasPrefix(command, "/"):
+		b.filter = strings.TrimPrefix(command, "/")
+	default:
+		idx, err := strconv.Atoi(command)
+		if err != nil || idx < 0 || idx >= len(b.items) {
+			b.notice = fmt.Sprintf("invalid selection %q, %s", command, browseHelp)
+			break
+		}
+		b.selectItem(b.items[idx])
+	}
+
+	b.draw()
+	return true
+}
+
+// back returns to the previous level.
+func (b *browser) back() {
+	switch {
+	case b.device != "":
+		b.device = ""
+	case b.class != "":
+		b.class = ""
+	default:
+		b.vendor = ""
+	}
+	b.filter = ""
+}
+
+// selectItem descends into the given item of the current level.
+func (b *browser) selectItem(item string) {
+	switch {
+	case b.vendor == "":
+		b.vendor = item
+	case b.class == "":
+		b.class = item
+	default:
+		b.device = item
+	}
+	b.filter = ""
+}
+
+// listItems returns the items of the current level, unfiltered.
+func (b *browser) listItems() (string, []string) {
+	switch {
+	case b.vendor == "":
+		return "vendors", b.cache.ListVendors()
+	case b.class == "":
+		seen := map[string]struct{}{}
+		var classes []string
+		for _, spec := range b.cache.GetVendorSpecs(b.vendor) {
+			if _, ok := seen[spec.GetClass()]; !ok {
+				seen[spec.GetClass()] = struct{}{}
+				classes = append(classes, spec.GetClass())
+			}
+		}
+		sort.Strings(classes)
+		return "classes", classes
+	default:
+		var devices []string
+		for _, name := range b.cache.ListDevices() {
+			vendor, class, _ := parser.ParseDevice(name)
+			if vendor == b.vendor && class == b.class {
+				devices = append(devices, name)
+			}
+		}
+		return "devices", devices
+	}
+}
+
+// draw shows the current level of the browser.
+func (b *browser) draw() {
+	if b.clear {
+		fmt.Fprint(b.out, "\033[H\033[2J")
+	}
+
+	path := []string{"CDI cache"}
+	for _, p := range []string{b.vendor, b.class, b.device} {
+		if p != "" {
+			path = append(path, p)
+		}
+	}
+	fmt.Fprintf(b.out, "%s\n\n", strings.Join(path, " > "))
+
+	b.items = nil
+	if b.device != "" {
+		b.drawDevice()
+	} else {
+		what, items := b.listItems()
+		for _, item := range items {
+			if strings.Contains(strings.ToLower(item), strings.ToLower(b.filter)) {
+				b.items = append(b.items, item)
+			}
+		}
+		if b.filter != "" {
+			fmt.Fprintf(b.out, "%d of %d %s matching %q:\n", len(b.items), len(items), what, b.filter)
+		} else {
+			fmt.Fprintf(b.out, "%d %s:\n", len(items), what)
+		}
+		for idx, item := range b.items {
+			fmt.Fprintf(b.out, "%s%d. %s\n", indent(2), idx, item)
+		}
+	}
+
+	if b.notice != "" {
+		fmt.Fprintf(b.out, "\n%s\n", b.notice)
+	}
+	fmt.Fprintf(b.out, "\n> ")
+}
+
+// drawDevice shows the details of the selected device.
+func (b *browser) drawDevice() {
+	dev := b.cache.GetDevice(b.device)
+	if dev == nil {
+		fmt.Fprintf(b.out, "device %s no longer found\n", b.device)
+		return
+	}
+
+	spec := dev.GetSpec()
+	format := chooseFormat("", spec.GetPath())
+	fmt.Fprintf(b.out, "Spec File %s (priority %d)\n", spec.GetPath(), spec.GetPriority())
+	fmt.Fprintf(b.out, "%s", marshalObject(2, dev.Device, format))
+	edits := spec.ContainerEdits
+	if len(edits.Env)+len(edits.DeviceNodes)+len(edits.Hooks)+len(edits.Mounts) > 0 {
+		fmt.Fprintf(b.out, "%sglobal Spec containerEdits:\n", indent(2))
+		fmt.Fprintf(b.out, "%s", marshalObject(4, spec.ContainerEdits, format))
+	}
+}
+
+var (
+	browseCfg browseFlags
+)
+
+func init() {
+	rootCmd.AddCommand(browseCmd)
+	browseCmd.Flags().DurationVar(&browseCfg.interval,
+		"interval", time.Second, "interval for checking the cache for changes (0 disables)")
+	browseCmd.Flags().BoolVar(&browseCfg.noClear,
+		"no-clear", false, "don't clear the screen before redrawing")
+}
@@ -0,0 +1,143 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+)
+
+const browseVendor2Spec = `cdiVersion: "0.3.0"
+kind: vendor2.com/device
+devices:
+- name: dev1
+  containerEdits:
+    env:
+    - DEV=2
+`
+
+// browseOutput collects the output of the browser, which is also written
+// by the goroutine watching the cache.
+type browseOutput struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (o *browseOutput) Write(p []byte) (int, error) {
+	o.Lock()
+	defer o.Unlock()
+	return o.buf.Write(p)
+}
+
+func (o *browseOutput) String() string {
+	o.Lock()
+	defer o.Unlock()
+	return o.buf.String()
+}
+
+// setupBrowse sets up a default cache with a Spec directory containing
+// a single Spec file.
+func setupBrowse(t *testing.T, autoRefresh bool) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor.yaml"), []byte(editValidSpec), 0o644))
+
+	cache, err := cdi.NewCache(
+		cdi.WithSpecDirs(dir),
+		cdi.WithAutoRefresh(autoRefresh),
+	)
+	require.NoError(t, err)
+	prev := cdi.SetDefaultCache(cache)
+	t.Cleanup(func() {
+		cdi.SetDefaultCache(prev)
+		cache.Close()
+	})
+
+	return dir
+}
+
+func TestBrowseDevices(t *testing.T) {
+	setupBrowse(t, false)
+
+	out := &browseOutput{}
+	require.NoError(t, cdiBrowse(strings.NewReader("0\n0\n0\nq\n"), out, 0, false))
+
+	for _, expected := range []string{
+		"CDI cache\n\n1 vendors:\n  0. vendor.com\n",
+		"CDI cache > vendor.com\n\n1 classes:\n  0. device\n",
+		"CDI cache > vendor.com > device\n\n1 devices:\n  0. vendor.com/device=dev0\n",
+		"CDI cache > vendor.com > device > vendor.com/device=dev0\n",
+		"DEV=1",
+	} {
+		require.Contains(t, out.String(), expected)
+	}
+}
+
+func TestBrowseRefresh(t *testing.T) {
+	t.Run("manual refresh", func(t *testing.T) {
+		dir := setupBrowse(t, false)
+
+		in, cmds := io.Pipe()
+		out := &browseOutput{}
+		done := make(chan error, 1)
+		go func() {
+			done <- cdiBrowse(in, out, 0, false)
+		}()
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor2.yaml"), []byte(browseVendor2Spec), 0o644))
+		_, err := io.WriteString(cmds, "r\nq\n")
+		require.NoError(t, err)
+		require.NoError(t, <-done)
+
+		require.Contains(t, out.String(), "CDI cache refreshed\n")
+		require.Contains(t, out.String(), "2 vendors:\n  0. vendor.com\n  1. vendor2.com\n")
+	})
+
+	t.Run("automatic refresh", func(t *testing.T) {
+		dir := setupBrowse(t, true)
+
+		in, cmds := io.Pipe()
+		out := &browseOutput{}
+		done := make(chan error, 1)
+		go func() {
+			done <- cdiBrowse(in, out, 10*time.Millisecond, false)
+		}()
+
+		require.Eventually(t, func() bool {
+			return strings.Contains(out.String(), "1 vendors:")
+		}, 5*time.Second, 10*time.Millisecond)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor2.yaml"), []byte(browseVendor2Spec), 0o644))
+		require.Eventually(t, func() bool {
+			s := out.String()
+			return strings.Contains(s, "2 vendors:\n  0. vendor.com\n  1. vendor2.com\n") &&
+				strings.Contains(s, "CDI cache content changed\n")
+		}, 5*time.Second, 10*time.Millisecond)
+
+		_, err := io.WriteString(cmds, "q\n")
+		require.NoError(t, err)
+		require.NoError(t, <-done)
+	})
+}
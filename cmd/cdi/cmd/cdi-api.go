@@ -17,10 +17,14 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	oci "github.com/opencontainers/runtime-spec/specs-go"
 	gen "github.com/opencontainers/runtime-tools/generate"
@@ -30,8 +34,8 @@ import (
 
 func cdiListVendors() {
 	var (
-		cache   = cdi.GetDefaultCache()
-		vendors = cache.ListVendors()
+		cache   cdi.SpecProvider = cdi.GetDefaultCache()
+		vendors                  = cache.ListVendors()
 	)
 
 	if len(vendors) == 0 {
@@ -48,8 +52,8 @@ func cdiListVendors() {
 
 func cdiListClasses() {
 	var (
-		cache   = cdi.GetDefaultCache()
-		vendors = map[string][]string{}
+		cache   cdi.SpecProvider = cdi.GetDefaultCache()
+		vendors                  = map[string][]string{}
 	)
 
 	for _, class := range cache.ListClasses() {
@@ -78,8 +82,9 @@ func cdiListClasses() {
 
 func cdiListDevices(verbose bool, format string) {
 	var (
-		cache   = cdi.GetDefaultCache()
-		devices = cache.ListDevices()
+		cache       = cdi.GetDefaultCache()
+		devices     = cache.ListDevices()
+		unavailable = cache.GetUnavailableDevices()
 	)
 
 	if len(devices) == 0 {
@@ -90,16 +95,19 @@ func cdiListDevices(verbose bool, format string) {
 	fmt.Printf("CDI devices found:\n")
 	for idx, device := range devices {
 		cdiPrintDevice(idx, cache.GetDevice(device), verbose, format, 2)
+		if err := unavailable[device]; err != nil {
+			fmt.Printf("%s%v\n", indent(5), err)
+		}
 	}
 }
 
 func cdiPrintDevice(idx int, dev *cdi.Device, verbose bool, format string, level int) {
 	if !verbose {
 		if idx >= 0 {
-			fmt.Printf("%s%d. %s\n", indent(level), idx, dev.GetQualifiedName())
+			fmt.Printf("%s%d. %s%s\n", indent(level), idx, dev.GetQualifiedName(), deprecationNote(dev))
 			return
 		}
-		fmt.Printf("%s%s\n", indent(level), dev.GetQualifiedName())
+		fmt.Printf("%s%s%s\n", indent(level), dev.GetQualifiedName(), deprecationNote(dev))
 		return
 	}
 
@@ -110,6 +118,7 @@ func cdiPrintDevice(idx int, dev *cdi.Device, verbose bool, format string, level
 	format = chooseFormat(format, spec.GetPath())
 
 	fmt.Printf("  %s (%s)\n", dev.GetQualifiedName(), spec.GetPath())
+	fmt.Printf("%s%s\n", indent(level+2), provenanceNote(spec))
 	fmt.Printf("%s", marshalObject(level+2, dev.Device, format))
 	edits := spec.ContainerEdits
 	if len(edits.Env)+len(edits.DeviceNodes)+len(edits.Hooks)+len(edits.Mounts) > 0 {
@@ -118,6 +127,18 @@ func cdiPrintDevice(idx int, dev *cdi.Device, verbose bool, format string, level
 	}
 }
 
+// deprecationNote returns a note about the deprecation of the device, or
+// an empty string if the device is not deprecated.
+func deprecationNote(dev *cdi.Device) string {
+	if !dev.IsDeprecated() {
+		return ""
+	}
+	if l := dev.GetLifecycle(); l.ReplacedBy != "" {
+		return fmt.Sprintf(" (deprecated, replaced by %s)", l.ReplacedBy)
+	}
+	return " (deprecated)"
+}
+
 func cdiShowSpecDirs() {
 	var (
 		cache     = cdi.GetDefaultCache()
@@ -136,33 +157,60 @@ func cdiShowSpecDirs() {
 			}
 		}
 	}
+
+	aliases := cache.GetSpecAliases()
+	if len(aliases) == 0 {
+		return
+	}
+	fmt.Printf("CDI Spec files ignored as duplicates:\n")
+	for _, a := range aliases {
+		kind := "same content as"
+		if a.SameFile {
+			kind = "same file as"
+		}
+		fmt.Printf("  %s (%s %s)\n", a.Path, kind, a.AliasOf)
+	}
 }
 
-func cdiInjectDevices(format string, ociSpec *oci.Spec, patterns []string) error {
+func cdiInjectDevices(format, inventory string, dump bool, ociSpec *oci.Spec, patterns []string) error {
+	cache := cdi.GetDefaultCache()
+
+	patterns, err := cdiQualifyDevices(cache, patterns)
+	if err != nil {
+		return err
+	}
+
 	var (
-		cache   = cdi.GetDefaultCache()
-		matches = map[string]struct{}{}
-		devices = []string{}
+		machine = isMachineOutput(format)
+		out     = injectOutput{Devices: patterns}
 	)
 
-	for _, device := range cache.ListDevices() {
-		for _, glob := range patterns {
-			match, err := filepath.Match(glob, device)
-			if err != nil {
-				return fmt.Errorf("failed to match pattern %q against %q: %w",
-					glob, device, err)
-			}
-			if match {
-				matches[device] = struct{}{}
-			}
+	_ = cache.Configure(cdi.WithDeprecationHandler(func(w *cdi.DeprecationWarning) {
+		if machine {
+			out.Warnings = append(out.Warnings, w.Error())
+			return
 		}
-	}
-	for device := range matches {
-		devices = append(devices, device)
-	}
-	sort.Strings(devices)
+		fmt.Printf("Warning: %v\n", w)
+	}))
+
+	unresolved, err := cache.InjectDevices(ociSpec, patterns...)
 
-	unresolved, err := cache.InjectDevices(ociSpec, devices...)
+	if machine {
+		out.Unresolved = unresolved
+		if err != nil {
+			out.Error = err.Error()
+		} else if dump {
+			out.OCISpec = ociSpec
+		}
+		printOutput(out, format)
+		if err != nil {
+			return fmt.Errorf("OCI device injection failed: %w", err)
+		}
+		if inventory != "" {
+			return cdiWriteInventory(cache, inventory, patterns)
+		}
+		return nil
+	}
 
 	if len(unresolved) > 0 {
 		fmt.Printf("Unresolved CDI devices:\n")
@@ -174,8 +222,59 @@ func cdiInjectDevices(format string, ociSpec *oci.Spec, patterns []string) error
 		return fmt.Errorf("OCI device injection failed: %w", err)
 	}
 
-	fmt.Printf("Updated OCI Spec:\n")
-	fmt.Printf("%s", marshalObject(2, ociSpec, format))
+	if dump {
+		fmt.Printf("Updated OCI Spec:\n")
+		fmt.Printf("%s", marshalObject(2, ociSpec, format))
+	}
+
+	if inventory != "" {
+		return cdiWriteInventory(cache, inventory, patterns)
+	}
+
+	return nil
+}
+
+// cdiQualifyDevices replaces unqualified device names by the qualified
+// name of the single device of any vendor and class with that name.
+func cdiQualifyDevices(cache cdi.DeviceResolver, names []string) ([]string, error) {
+	qualified := make([]string, 0, len(names))
+	for _, name := range names {
+		if parser.IsQualifiedName(name) || strings.ContainsAny(name, `*?[\`) {
+			qualified = append(qualified, name)
+			continue
+		}
+		dev, err := cache.FindDevice(name)
+		switch {
+		case err == nil:
+			name = dev.GetQualifiedName()
+		case !errors.Is(err, cdi.ErrDeviceNotFound):
+			return nil, err
+		}
+		qualified = append(qualified, name)
+	}
+	return qualified, nil
+}
+
+func cdiWriteInventory(cache *cdi.Cache, path string, patterns []string) error {
+	inv, _, err := cache.GetInventory(patterns...)
+	if err != nil {
+		return fmt.Errorf("failed to collect inventory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal inventory: %w", err)
+	}
+	data = append(data, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(path, data, 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write inventory: %w", err)
+	}
 
 	return nil
 }
@@ -280,10 +379,25 @@ func cdiPrintSpec(spec *cdi.Spec, verbose bool, format string, level int) {
 	fmt.Printf("%sSpec File %s\n", indent(level), spec.GetPath())
 
 	if verbose {
+		fmt.Printf("%s%s\n", indent(level+2), provenanceNote(spec))
 		fmt.Printf("%s", marshalObject(level+2, spec.Spec, format))
 	}
 }
 
+// provenanceNote returns a note about the modification time and digest
+// of the Spec file the given Spec was read from.
+func provenanceNote(spec *cdi.Spec) string {
+	modTime := "unknown"
+	if t := spec.GetModTime(); !t.IsZero() {
+		modTime = t.Format(time.RFC3339)
+	}
+	digest := spec.GetDigest()
+	if digest == "" {
+		digest = "unknown"
+	}
+	return fmt.Sprintf("modified %s, digest %s", modTime, digest)
+}
+
 func cdiPrintSpecErrors(level int) {
 	var (
 		cache     = cdi.GetDefaultCache()
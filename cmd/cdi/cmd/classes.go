@@ -20,16 +20,32 @@ import (
 	"github.com/spf13/cobra"
 )
 
+type classesFlags struct {
+	output string
+}
+
 // classesCmd is our command for listing device classes in the cache.
 var classesCmd = &cobra.Command{
 	Use:   "classes",
 	Short: "List CDI device classes",
 	Long:  `List CDI device classes found in the cache.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		checkOutputFormat(classesCfg.output)
+		if isMachineOutput(classesCfg.output) {
+			cdiOutputClasses(classesCfg.output)
+			return
+		}
 		cdiListClasses()
 	},
 }
 
+var (
+	classesCfg classesFlags
+)
+
 func init() {
 	rootCmd.AddCommand(classesCmd)
+	classesCmd.Flags().StringVarP(&classesCfg.output,
+		"output", "o", "", "machine-readable output format (json|yaml)")
+	registerOutputCompletion(classesCmd)
 }
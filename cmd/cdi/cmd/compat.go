@@ -0,0 +1,211 @@
+/*
+   Copyright © 2021 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/spf13/cobra"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/parser"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// compatFingerprintVersion is the version of the fingerprint format.
+	compatFingerprintVersion = "v1"
+)
+
+type compatFlags struct {
+	output string
+}
+
+// compatCmd is our command for fingerprinting library behavior.
+var compatCmd = &cobra.Command{
+	Use:   "compat-check [file|dir|glob...]",
+	Short: "Fingerprint CDI library behavior on a corpus of Spec files",
+	Long: `
+The 'compat-check' command runs a corpus of CDI Spec files through the
+validation and resolution logic of this build and dumps a behavior
+fingerprint. For every Spec file the fingerprint records whether it is
+accepted, and if not why, its declared and minimum required version,
+its devices and groups, and a digest of the OCI Spec edits resulting
+from injecting each device into an empty OCI Spec.
+
+Arguments are handled like for 'cdi validate'. Fingerprints produced
+by different versions of the CDI library can be diffed to catch
+behavior changes before rolling out an upgrade. Note that injecting
+device nodes without major and minor numbers looks them up on the
+host, so fingerprints should be compared on the same host.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			fmt.Fprintf(os.Stderr, "Spec files, directories or glob patterns expected\n")
+			os.Exit(validateExitUsage)
+		}
+		os.Exit(cdiCompatCheck(compatCfg.output, args...))
+	},
+}
+
+// compatFingerprint is the behavior fingerprint for a corpus of Specs.
+type compatFingerprint struct {
+	Version     string              `json:"version"`
+	SpecVersion string              `json:"specVersion"`
+	Specs       []*compatSpecResult `json:"specs"`
+}
+
+// compatSpecResult is the behavior fingerprint for a single Spec file.
+type compatSpecResult struct {
+	Path            string                `json:"path"`
+	Digest          string                `json:"digest"`
+	Accepted        bool                  `json:"accepted"`
+	Errors          []string              `json:"errors,omitempty"`
+	Version         string                `json:"version,omitempty"`
+	RequiredVersion string                `json:"requiredVersion,omitempty"`
+	Devices         []*compatDeviceResult `json:"devices,omitempty"`
+	Groups          []string              `json:"groups,omitempty"`
+}
+
+// compatDeviceResult is the behavior fingerprint for a single device.
+type compatDeviceResult struct {
+	Name      string `json:"name"`
+	Injection string `json:"injection,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func cdiCompatCheck(format string, args ...string) int {
+	paths, err := collectSpecFiles(args...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return validateExitUsage
+	}
+	if len(paths) == 0 {
+		fmt.Fprintf(os.Stderr, "no CDI Spec files found\n")
+		return validateExitUsage
+	}
+
+	fp := &compatFingerprint{
+		Version:     compatFingerprintVersion,
+		SpecVersion: cdispec.CurrentVersion,
+	}
+	for _, path := range paths {
+		result, err := compatCheckSpecFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return validateExitUsage
+		}
+		fp.Specs = append(fp.Specs, result)
+	}
+
+	if format == "" {
+		format = "json"
+	}
+	fmt.Printf("%s", marshalObject(0, fp, format))
+
+	return 0
+}
+
+// compatCheckSpecFile fingerprints the behavior for a single Spec file.
+func compatCheckSpecFile(path string) (*compatSpecResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	result := &compatSpecResult{
+		Path:   path,
+		Digest: "sha256:" + hex.EncodeToString(sum[:]),
+	}
+
+	raw, err := cdi.ParseSpec(data)
+	if err != nil {
+		result.Errors = append(result.Errors, strings.TrimSpace(err.Error()))
+		return result, nil
+	}
+	result.Version = raw.Version
+	result.RequiredVersion, _ = cdispec.MinimumRequiredVersion(raw)
+
+	if _, err := cdi.ReadSpec(path, 0); err != nil {
+		result.Errors = append(result.Errors, strings.TrimSpace(err.Error()))
+		return result, nil
+	}
+	result.Accepted = true
+
+	cache, err := cdi.NewCache(cdi.WithSpecDirs(), cdi.WithAutoRefresh(false))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CDI cache: %w", err)
+	}
+	if err := cache.AddSpec(raw, 0); err != nil {
+		result.Accepted = false
+		result.Errors = append(result.Errors, strings.TrimSpace(err.Error()))
+		return result, nil
+	}
+
+	vendor, class := parser.ParseQualifier(raw.Kind)
+	for _, d := range raw.Devices {
+		result.Devices = append(result.Devices,
+			compatCheckDevice(cache, parser.QualifiedName(vendor, class, d.Name)))
+	}
+	for _, g := range raw.Groups {
+		result.Groups = append(result.Groups, parser.QualifiedName(vendor, class, g.Name))
+	}
+	sort.Slice(result.Devices, func(i, j int) bool {
+		return result.Devices[i].Name < result.Devices[j].Name
+	})
+	sort.Strings(result.Groups)
+
+	return result, nil
+}
+
+// compatCheckDevice fingerprints the injection of a single device.
+func compatCheckDevice(cache *cdi.Cache, name string) *compatDeviceResult {
+	result := &compatDeviceResult{Name: name}
+
+	ociSpec := &oci.Spec{}
+	if _, err := cache.InjectDevices(ociSpec, name); err != nil {
+		result.Error = strings.TrimSpace(err.Error())
+		return result
+	}
+
+	data, err := json.Marshal(ociSpec)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	sum := sha256.Sum256(data)
+	result.Injection = "sha256:" + hex.EncodeToString(sum[:])
+
+	return result
+}
+
+var (
+	compatCfg compatFlags
+)
+
+func init() {
+	rootCmd.AddCommand(compatCmd)
+	compatCmd.Flags().StringVarP(&compatCfg.output,
+		"output", "o", "", "output format for the fingerprint (json|yaml)")
+}
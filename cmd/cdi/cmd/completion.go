@@ -0,0 +1,110 @@
+/*
+   Copyright © 2021 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+)
+
+// completionCmd is our command for generating shell completion scripts.
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish|powershell",
+	Short: "Generate shell completion scripts",
+	Long: `
+The 'completion' command generates a completion script for the given
+shell and prints it to stdout. Besides commands and flags, devices and
+vendors are completed using the CDI cache. For instance, to enable
+completion for the current bash session run
+
+    source <(cdi completion bash)
+
+See the documentation of your shell for enabling it permanently.`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+
+		switch args[0] {
+		case "bash":
+			err = rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			err = rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = rootCmd.GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			err = rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to generate %s completion: %v\n", args[0], err)
+			os.Exit(1)
+		}
+	},
+}
+
+// completeDevices completes the qualified names of cached devices.
+func completeDevices(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeNames(completionCache().ListDevices(), toComplete)
+}
+
+// completeVendors completes the names of vendors with cached Specs.
+func completeVendors(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeNames(completionCache().ListVendors(), toComplete)
+}
+
+// completionCache returns the cache to use for completion. Completions
+// are generated before --spec-dirs is parsed, so honor it here.
+func completionCache() *cdi.Cache {
+	if len(specDirs) > 0 {
+		cache, _ := cdi.NewCache(
+			cdi.WithSpecDirs(specDirs...),
+			cdi.WithAutoRefresh(false),
+		)
+		return cache
+	}
+	return cdi.GetDefaultCache()
+}
+
+// completeNames returns the names with the given prefix.
+func completeNames(names []string, prefix string) ([]string, cobra.ShellCompDirective) {
+	var matches []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
+
+// registerOutputCompletion registers completion of the --output flag.
+func registerOutputCompletion(cmd *cobra.Command) {
+	_ = cmd.RegisterFlagCompletionFunc("output",
+		func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+			return []string{outputJSON, outputYAML}, cobra.ShellCompDirectiveNoFileComp
+		})
+}
+
+func init() {
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(completionCmd)
+}
@@ -31,9 +31,19 @@ var devicesCmd = &cobra.Command{
 	Use:     "devices",
 	Short:   "List devices in the CDI cache",
 	Long: `
-The 'devices' command lists devices found in the CDI cache.`,
+The 'devices' command lists devices found in the CDI cache.
+
+With the --output option the devices are listed in a machine-readable
+format instead. In verbose mode the full device definitions are
+included, together with the modification time and digest of the Spec
+file defining each device.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cdiListDevices(devicesCfg.verbose, devicesCfg.output)
+		checkOutputFormat(devicesCfg.output)
+		if isMachineOutput(devicesCfg.output) {
+			cdiOutputDevices(devicesCfg.verbose, devicesCfg.output)
+			return
+		}
+		cdiListDevices(devicesCfg.verbose, "")
 	},
 }
 
@@ -46,5 +56,6 @@ func init() {
 	devicesCmd.Flags().BoolVarP(&devicesCfg.verbose,
 		"verbose", "v", false, "list CDI Spec details")
 	devicesCmd.Flags().StringVarP(&devicesCfg.output,
-		"output", "o", "", "output format for details (json|yaml)")
+		"output", "o", "", "machine-readable output format (json|yaml)")
+	registerOutputCompletion(devicesCmd)
 }
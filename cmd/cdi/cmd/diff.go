@@ -0,0 +1,133 @@
+/*
+   Copyright © 2021 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/cdi/producer"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// diffExitChanged is the exit status for differing Specs.
+	diffExitChanged = 1
+)
+
+type diffFlags struct {
+	output string
+}
+
+// diffCmd is our command for diffing CDI Spec files.
+var diffCmd = &cobra.Command{
+	Use:   "diff old-file|dir new-file|dir",
+	Short: "Show semantic differences between CDI Spec files or directories",
+	Long: `
+The 'diff' command compares two CDI Spec files, or all the Spec files in
+two directories, and lists the semantic differences between them: Specs
+and devices added or removed, changed versions, annotations, groups and
+container edits. Specs are paired by their kind, so renaming or splitting
+Spec files does not show up as a difference. The order of devices and of
+container edits which are identified by a key, like environment variables
+by name or mounts by container path, is ignored.
+
+The exit status is 0 if there are no differences, 1 if there are, and 2
+if the Spec files cannot be read.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		os.Exit(cdiDiffSpecs(diffCfg.output, args[0], args[1]))
+	},
+}
+
+func cdiDiffSpecs(format, oldPath, newPath string) int {
+	oldSpecs, err := readDiffSpecs(oldPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return validateExitUsage
+	}
+	newSpecs, err := readDiffSpecs(newPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return validateExitUsage
+	}
+
+	changes, err := producer.DiffSpecSets(oldSpecs, newSpecs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return validateExitUsage
+	}
+
+	exitCode := 0
+	if len(changes) > 0 {
+		exitCode = diffExitChanged
+	}
+
+	if format == "json" {
+		if changes == nil {
+			changes = []*producer.SpecChange{}
+		}
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal differences: %v\n", err)
+			return validateExitUsage
+		}
+		fmt.Printf("%s\n", data)
+		return exitCode
+	}
+
+	for _, change := range changes {
+		fmt.Printf("%s\n", change)
+	}
+
+	return exitCode
+}
+
+// readDiffSpecs reads all Specs from the given Spec file or directory.
+func readDiffSpecs(arg string) ([]*cdispec.Spec, error) {
+	paths, err := collectSpecFiles(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []*cdispec.Spec
+	for _, path := range paths {
+		read, err := cdi.ReadSpecs(path, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range read {
+			specs = append(specs, spec.Spec)
+		}
+	}
+
+	return specs, nil
+}
+
+var (
+	diffCfg diffFlags
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVarP(&diffCfg.output,
+		"output", "o", "", "output format for the differences (json)")
+}
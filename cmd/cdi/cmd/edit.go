@@ -0,0 +1,207 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/schema"
+)
+
+type editFlags struct {
+	dryRun       bool
+	noBackup     bool
+	backupSuffix string
+}
+
+// editCmd is our command for editing CDI Spec files in place.
+var editCmd = &cobra.Command{
+	Use:   "edit <CDI Spec File>",
+	Short: "Edit a CDI Spec file with validation",
+	Long: `
+The 'edit' command opens a CDI Spec file in an editor ($VISUAL,
+$EDITOR, or vi if neither is set). Once the editor exits, the
+edited Spec is validated against the JSON schema and checked for
+semantic errors. The original file is only replaced if the edited
+Spec is valid. Otherwise the edited Spec is kept in a temporary
+file, which is reported, so the changes are not lost. The
+replacement is atomic and by default a backup of the original file
+is created. With --dry-run the edited Spec is validated but the
+original file is left untouched.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fmt.Printf("CDI Spec file argument expected\n")
+			os.Exit(1)
+		}
+		if err := cdiEditSpec(args[0]); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func cdiEditSpec(path string) error {
+	orig, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CDI Spec %q: %w", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat CDI Spec %q: %w", path, err)
+	}
+
+	// edit a copy outside of any Spec directory so the cache never sees it
+	tmp, err := os.CreateTemp("", "cdi-edit.*"+filepath.Ext(path))
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	_, err = tmp.Write(orig)
+	tmp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	if err := runEditor(tmp.Name()); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("failed to read edited CDI Spec: %w", err)
+	}
+	if bytes.Equal(data, orig) {
+		fmt.Printf("No changes to CDI Spec %s.\n", path)
+		return nil
+	}
+
+	if err := validateEditedSpec(tmp.Name(), data); err != nil {
+		keep = true
+		return fmt.Errorf("edited CDI Spec is invalid, %s left unchanged, edits saved in %s: %w",
+			path, tmp.Name(), err)
+	}
+
+	if editCfg.dryRun {
+		fmt.Printf("Edited CDI Spec is valid, %s left unchanged (dry-run).\n", path)
+		return nil
+	}
+
+	if !editCfg.noBackup {
+		backup := path + editCfg.backupSuffix
+		if err := os.WriteFile(backup, orig, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to create backup %q: %w", backup, err)
+		}
+		fmt.Printf("Created backup %s.\n", backup)
+	}
+
+	if err := replaceFile(path, data, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated CDI Spec %s.\n", path)
+	return nil
+}
+
+// runEditor runs the user's editor on the given file.
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	return nil
+}
+
+// validateEditedSpec validates the edited Spec data against the JSON schema
+// and runs the semantic checks performed when a Spec is loaded to the cache.
+func validateEditedSpec(path string, data []byte) error {
+	s, err := schema.Load(schemaName)
+	if err != nil {
+		return fmt.Errorf("failed to load JSON schema %s: %w", schemaName, err)
+	}
+	if err := s.ValidateData(data); err != nil {
+		return fmt.Errorf("schema validation failed: %w", err)
+	}
+	if _, err := cdi.ReadSpec(path, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+// replaceFile atomically replaces the content of the file at path.
+func replaceFile(path string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, ".cdi-edit.*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file in %q: %w", dir, err)
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	tmp.Close()
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace %q: %w", path, err)
+	}
+
+	return nil
+}
+
+var (
+	editCfg editFlags
+)
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+	editCmd.Flags().BoolVarP(&editCfg.dryRun,
+		"dry-run", "n", false, "validate the edited Spec without updating the file")
+	editCmd.Flags().BoolVar(&editCfg.noBackup,
+		"no-backup", false, "do not create a backup of the original file")
+	editCmd.Flags().StringVar(&editCfg.backupSuffix,
+		"backup-suffix", ".bak", "file name suffix for the backup of the original file")
+}
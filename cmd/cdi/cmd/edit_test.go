@@ -0,0 +1,115 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	editOrigSpec = `cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+- name: dev0
+  containerEdits:
+    env:
+    - DEV=0
+`
+	editValidSpec = `cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+- name: dev0
+  containerEdits:
+    env:
+    - DEV=1
+`
+	editInvalidSpec = `cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+- name: dev0
+`
+)
+
+// setupEdit creates a Spec file and an editor which replaces the edited
+// file with the given content.
+func setupEdit(t *testing.T, edited string) string {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vendor.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(editOrigSpec), 0o644))
+	content := filepath.Join(t.TempDir(), "edited.yaml")
+	require.NoError(t, os.WriteFile(content, []byte(edited), 0o644))
+
+	t.Setenv("VISUAL", "cp "+content)
+	t.Setenv("TMPDIR", t.TempDir())
+	schemaName = "builtin"
+	editCfg = editFlags{backupSuffix: ".bak"}
+	t.Cleanup(func() { editCfg = editFlags{} })
+
+	return path
+}
+
+func TestEditSpec(t *testing.T) {
+	t.Run("valid edits replace the Spec", func(t *testing.T) {
+		path := setupEdit(t, editValidSpec)
+		require.NoError(t, cdiEditSpec(path))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, editValidSpec, string(data))
+		backup, err := os.ReadFile(path + ".bak")
+		require.NoError(t, err)
+		require.Equal(t, editOrigSpec, string(backup))
+
+		leftover, err := os.ReadDir(os.Getenv("TMPDIR"))
+		require.NoError(t, err)
+		require.Empty(t, leftover)
+	})
+
+	t.Run("dry-run leaves the Spec unchanged", func(t *testing.T) {
+		path := setupEdit(t, editValidSpec)
+		editCfg.dryRun = true
+		require.NoError(t, cdiEditSpec(path))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, editOrigSpec, string(data))
+		require.NoFileExists(t, path+".bak")
+	})
+
+	t.Run("invalid edits are kept", func(t *testing.T) {
+		path := setupEdit(t, editInvalidSpec)
+		err := cdiEditSpec(path)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "left unchanged")
+
+		data, readErr := os.ReadFile(path)
+		require.NoError(t, readErr)
+		require.Equal(t, editOrigSpec, string(data))
+		require.NoFileExists(t, path+".bak")
+
+		saved := regexp.MustCompile(`edits saved in (\S+):`).FindStringSubmatch(err.Error())
+		require.Len(t, saved, 2)
+		edited, readErr := os.ReadFile(saved[1])
+		require.NoError(t, readErr)
+		require.Equal(t, editInvalidSpec, string(edited))
+	})
+}
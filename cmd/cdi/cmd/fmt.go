@@ -0,0 +1,150 @@
+/*
+   Copyright © 2021 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/cdi/producer"
+)
+
+type fmtFlags struct {
+	check      bool
+	minVersion bool
+}
+
+// fmtCmd is our command for formatting CDI Spec files.
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [file|dir|glob...]",
+	Short: "Format CDI Spec files canonically",
+	Long: `
+The 'fmt' command rewrites CDI Spec files in canonical form, keeping their
+encoding: keys in canonical order, fully qualified versions and consistent
+YAML or JSON style. With --min-version the version of every Spec is also
+set to the minimum version required by the features it uses. Arguments
+are handled like for 'cdi validate'. With --check files are not rewritten,
+only the ones which are not formatted canonically are listed.
+
+The exit status is 0 if all files were formatted, or with --check are
+formatted, 1 if any of them could not be formatted, or with --check is not
+formatted, and 2 if the arguments cannot be processed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			fmt.Fprintf(os.Stderr, "Spec files, directories or glob patterns expected\n")
+			os.Exit(validateExitUsage)
+		}
+		os.Exit(cdiFormatSpecFiles(fmtCfg.check, fmtCfg.minVersion, args...))
+	},
+}
+
+func cdiFormatSpecFiles(check, minVersion bool, args ...string) int {
+	paths, err := collectSpecFiles(args...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return validateExitUsage
+	}
+	if len(paths) == 0 {
+		fmt.Fprintf(os.Stderr, "no CDI Spec files found\n")
+		return validateExitUsage
+	}
+
+	opts := producer.FormatOptions{MinimumVersion: minVersion}
+
+	exitCode := 0
+	for _, path := range paths {
+		changed, err := formatSpecFile(path, opts, check)
+		switch {
+		case err != nil:
+			fmt.Printf("%s: failed: %v\n", path, err)
+			exitCode = validateExitInvalid
+		case changed && check:
+			fmt.Printf("%s: not formatted\n", path)
+			exitCode = validateExitInvalid
+		case changed:
+			fmt.Printf("%s: formatted\n", path)
+		}
+	}
+
+	return exitCode
+}
+
+// formatSpecFile formats a single Spec file, returning whether it changed.
+func formatSpecFile(path string, opts producer.FormatOptions, check bool) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	specs, err := cdi.ReadSpecs(path, 0)
+	if err != nil {
+		return false, err
+	}
+
+	opts.Encoding = producer.EncodingYAML
+	if filepath.Ext(path) == ".json" {
+		opts.Encoding = producer.EncodingJSON
+	}
+
+	var formatted []byte
+	for _, spec := range specs {
+		doc, err := producer.Format(spec.Spec, opts)
+		if err != nil {
+			return false, err
+		}
+		formatted = append(formatted, doc...)
+	}
+
+	if bytes.Equal(data, formatted) {
+		return false, nil
+	}
+	if check {
+		return true, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	tmp := path + ".fmt"
+	if err := os.WriteFile(tmp, formatted, info.Mode().Perm()); err != nil {
+		return false, fmt.Errorf("failed to write formatted Spec: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return false, fmt.Errorf("failed to replace Spec: %w", err)
+	}
+
+	return true, nil
+}
+
+var (
+	fmtCfg fmtFlags
+)
+
+func init() {
+	rootCmd.AddCommand(fmtCmd)
+	fmtCmd.Flags().BoolVar(&fmtCfg.check,
+		"check", false, "only list files which are not formatted, don't rewrite them")
+	fmtCmd.Flags().BoolVar(&fmtCfg.minVersion,
+		"min-version", false, "set the version of Specs to the minimum required one")
+}
@@ -17,9 +17,11 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
 	oci "github.com/opencontainers/runtime-spec/specs-go"
 	"sigs.k8s.io/yaml"
@@ -27,37 +29,103 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const (
+	// ociConfigFile is the name of the OCI Spec file in an OCI bundle.
+	ociConfigFile = "config.json"
+)
+
 type injectFlags struct {
-	output string
+	output       string
+	inventory    string
+	inPlace      bool
+	noBackup     bool
+	backupSuffix string
 }
 
 // injectCmd is our command for injecting CDI devices into an OCI Spec.
 var injectCmd = &cobra.Command{
 	Aliases: []string{"inj", "in", "oci"},
-	Use:     "inject <OCI Spec File> <CDI-device-list>",
+	Use:     "inject <OCI Spec File|OCI bundle> <CDI-device-list>",
 	Short:   "Inject CDI devices into an OCI Spec",
 	Long: `
 The 'inject' command reads an OCI Spec from a file (use "-" for stdin),
 injects a requested set of CDI devices into it and dumps the resulting
-updated OCI Spec.`,
+updated OCI Spec. Devices can be given as glob patterns, for instance
+"vendor.com/gpu=*", which are expanded to all matching devices. Devices
+can also be given by their unqualified name, for instance "gpu0", if a
+single device of any vendor and class has that name. If an
+OCI bundle directory is given, its config.json is used as the OCI Spec.
+
+With the --in-place option the updated OCI Spec is written back to the
+file it was read from instead of being dumped. The file is replaced
+atomically, after saving a backup of the original with --backup-suffix
+appended to its name, unless --no-backup is given.
+
+With the --output option the result of the injection is dumped in a
+machine-readable format instead. It lists the requested and unresolved
+devices, any warnings and the updated OCI Spec. Errors are then printed
+to stderr.
+
+With the --inventory option an inventory of everything the injection
+added (devices, device nodes, mounts, hooks, environment variables and
+additional GIDs) is written as JSON to the given file (use "-" for
+stdout).`,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return completeDevices(cmd, args, toComplete)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 2 {
 			fmt.Printf("OCI Spec argument and devices expected\n")
 			os.Exit(1)
 		}
+		checkOutputFormat(injectCfg.output)
 
-		ociSpec, err := readOCISpec(args[0])
-		if err != nil {
-			fmt.Printf("%v\n", err)
+		path := ociSpecPath(args[0])
+		if injectCfg.inPlace && path == "-" {
+			fmt.Printf("--in-place needs an OCI Spec file or bundle\n")
 			os.Exit(1)
 		}
-		if err := cdiInjectDevices(injectCfg.output, ociSpec, args[1:]); err != nil {
+		if injectCfg.inPlace {
+			if err := checkBackupSuffix(); err != nil {
+				fmt.Printf("%v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		ociSpec, err := readOCISpec(path)
+		if err != nil {
 			fmt.Printf("%v\n", err)
 			os.Exit(1)
 		}
+
+		dump := !injectCfg.inPlace
+		if err := cdiInjectDevices(injectCfg.output, injectCfg.inventory, dump, ociSpec, args[1:]); err != nil {
+			exitWithError(injectCfg.output, err)
+		}
+
+		if injectCfg.inPlace {
+			if err := writeOCISpec(path, ociSpec); err != nil {
+				exitWithError(injectCfg.output, err)
+			}
+		}
 	},
 }
 
+// ociSpecPath returns the path of the OCI Spec for the given argument,
+// which is either an OCI Spec file or an OCI bundle directory.
+func ociSpecPath(arg string) string {
+	if arg == "-" {
+		return arg
+	}
+	if info, err := os.Stat(arg); err == nil && info.IsDir() {
+		return filepath.Join(arg, ociConfigFile)
+	}
+	return arg
+}
+
 func readOCISpec(path string) (*oci.Spec, error) {
 	var (
 		spec *oci.Spec
@@ -83,6 +151,56 @@ func readOCISpec(path string) (*oci.Spec, error) {
 	return spec, nil
 }
 
+// checkBackupSuffix checks that a backup would not overwrite the OCI Spec.
+func checkBackupSuffix() error {
+	if !injectCfg.noBackup && injectCfg.backupSuffix == "" {
+		return fmt.Errorf("empty --backup-suffix, use --no-backup to skip the backup")
+	}
+	return nil
+}
+
+// writeOCISpec atomically replaces the OCI Spec file at path with the
+// given OCI Spec, creating a backup of the original unless disabled.
+func writeOCISpec(path string, spec *oci.Spec) error {
+	if err := checkBackupSuffix(); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat OCI Spec (%q): %w", path, err)
+	}
+
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal OCI Spec: %w", err)
+	}
+	data = append(data, '\n')
+
+	if !injectCfg.noBackup {
+		orig, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read OCI Spec (%q): %w", path, err)
+		}
+		backup := path + injectCfg.backupSuffix
+		if err := os.WriteFile(backup, orig, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to create backup %q: %w", backup, err)
+		}
+		if !isMachineOutput(injectCfg.output) {
+			fmt.Printf("Created backup %s.\n", backup)
+		}
+	}
+
+	if err := replaceFile(path, data, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	if !isMachineOutput(injectCfg.output) {
+		fmt.Printf("Updated OCI Spec %s.\n", path)
+	}
+	return nil
+}
+
 var (
 	injectCfg injectFlags
 )
@@ -90,5 +208,14 @@ var (
 func init() {
 	rootCmd.AddCommand(injectCmd)
 	injectCmd.Flags().StringVarP(&injectCfg.output,
-		"output", "o", "", "output format for OCI Spec (json|yaml)")
+		"output", "o", "", "machine-readable output format (json|yaml)")
+	registerOutputCompletion(injectCmd)
+	injectCmd.Flags().StringVar(&injectCfg.inventory,
+		"inventory", "", "write an inventory of the injected content to this file")
+	injectCmd.Flags().BoolVarP(&injectCfg.inPlace,
+		"in-place", "i", false, "write the updated OCI Spec back to its file")
+	injectCmd.Flags().BoolVar(&injectCfg.noBackup,
+		"no-backup", false, "do not create a backup of the original OCI Spec with --in-place")
+	injectCmd.Flags().StringVar(&injectCfg.backupSuffix,
+		"backup-suffix", ".bak", "file name suffix for the backup of the original OCI Spec")
 }
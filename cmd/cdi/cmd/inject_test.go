@@ -0,0 +1,94 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+)
+
+const injectOrigSpec = `{"ociVersion": "1.0.0"}`
+
+// setupInject creates an OCI bundle with a config.json.
+func setupInject(t *testing.T, flags injectFlags) string {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ociConfigFile)
+	require.NoError(t, os.WriteFile(path, []byte(injectOrigSpec), 0o644))
+
+	injectCfg = flags
+	t.Cleanup(func() { injectCfg = injectFlags{} })
+
+	return dir
+}
+
+func TestWriteOCISpec(t *testing.T) {
+	updated := &oci.Spec{
+		Version: "1.0.0",
+		Process: &oci.Process{Env: []string{"DEV=1"}},
+	}
+
+	t.Run("bundle updated in-place with backup", func(t *testing.T) {
+		dir := setupInject(t, injectFlags{inPlace: true, backupSuffix: ".bak"})
+		path := ociSpecPath(dir)
+		require.Equal(t, filepath.Join(dir, ociConfigFile), path)
+
+		spec, err := readOCISpec(path)
+		require.NoError(t, err)
+		require.Equal(t, "1.0.0", spec.Version)
+
+		require.NoError(t, writeOCISpec(path, updated))
+
+		spec, err = readOCISpec(path)
+		require.NoError(t, err)
+		require.Equal(t, updated, spec)
+		backup, err := os.ReadFile(path + ".bak")
+		require.NoError(t, err)
+		require.Equal(t, injectOrigSpec, string(backup))
+	})
+
+	t.Run("file updated in-place without backup", func(t *testing.T) {
+		dir := setupInject(t, injectFlags{inPlace: true, noBackup: true})
+		path := ociSpecPath(filepath.Join(dir, ociConfigFile))
+		require.Equal(t, filepath.Join(dir, ociConfigFile), path)
+
+		require.NoError(t, writeOCISpec(path, updated))
+
+		spec, err := readOCISpec(path)
+		require.NoError(t, err)
+		require.Equal(t, updated, spec)
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+	})
+
+	t.Run("empty backup suffix is rejected", func(t *testing.T) {
+		dir := setupInject(t, injectFlags{inPlace: true})
+		path := ociSpecPath(dir)
+
+		err := writeOCISpec(path, updated)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "--backup-suffix")
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, injectOrigSpec, string(data))
+	})
+}
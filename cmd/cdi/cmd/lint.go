@@ -0,0 +1,176 @@
+/*
+   Copyright © 2021 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/cdi/lint"
+)
+
+type lintFlags struct {
+	severity []string
+	failOn   string
+	output   string
+	rules    bool
+}
+
+// lintIssues are the issues found in a Spec file.
+type lintIssues struct {
+	Path   string        `json:"path"`
+	Issues []*lint.Issue `json:"issues"`
+}
+
+// lintCmd is our command for linting CDI Spec files.
+var lintCmd = &cobra.Command{
+	Use:   "lint [file|dir|glob...]",
+	Short: "Check CDI Spec files for likely problems",
+	Long: `
+The 'lint' command checks CDI Spec files for issues beyond schema validity,
+like host paths in host-specific locations, bind mounts without a bind
+mount option, hooks outside of /usr and /opt, environment variables which
+shadow well-known ones, or device names not following the recommended
+naming. Arguments are handled like for 'cdi validate'. Use --rules to list
+the rules and their severities. The severity of a rule can be changed with
+--severity rule=level, where level is one of off, info, warning or error.
+
+The exit status is 0 if no issues with a severity of at least --fail-on
+are found, 1 if any are, and 2 if the arguments cannot be processed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		linter, failOn, err := newLinter(lintCfg.severity, lintCfg.failOn)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(validateExitUsage)
+		}
+		if lintCfg.rules {
+			for _, r := range linter.Rules() {
+				fmt.Printf("%-20s %-8s %s\n", r.Name, r.Severity, r.Description)
+			}
+			return
+		}
+		if len(args) == 0 {
+			fmt.Fprintf(os.Stderr, "Spec files, directories or glob patterns expected\n")
+			os.Exit(validateExitUsage)
+		}
+		os.Exit(cdiLintSpecFiles(linter, failOn, lintCfg.output, args...))
+	},
+}
+
+// newLinter creates a linter with the given severity overrides.
+func newLinter(severities []string, failOn string) (*lint.Linter, lint.Severity, error) {
+	known := map[string]struct{}{}
+	for _, r := range lint.DefaultRules() {
+		known[r.Name] = struct{}{}
+	}
+
+	var options []lint.Option
+	for _, s := range severities {
+		rule, level, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, 0, fmt.Errorf("invalid severity %q, expected rule=level", s)
+		}
+		if _, ok := known[rule]; !ok {
+			return nil, 0, fmt.Errorf("unknown lint rule %q", rule)
+		}
+		severity, err := lint.ParseSeverity(level)
+		if err != nil {
+			return nil, 0, err
+		}
+		options = append(options, lint.WithSeverity(rule, severity))
+	}
+
+	threshold, err := lint.ParseSeverity(failOn)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return lint.New(options...), threshold, nil
+}
+
+func cdiLintSpecFiles(linter *lint.Linter, failOn lint.Severity, format string, args ...string) int {
+	paths, err := collectSpecFiles(args...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return validateExitUsage
+	}
+	if len(paths) == 0 {
+		fmt.Fprintf(os.Stderr, "no CDI Spec files found\n")
+		return validateExitUsage
+	}
+
+	var (
+		results  []*lintIssues
+		exitCode = 0
+	)
+	for _, path := range paths {
+		specs, err := cdi.ReadSpecs(path, 0)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			exitCode = validateExitInvalid
+		}
+		result := &lintIssues{Path: path, Issues: []*lint.Issue{}}
+		for _, spec := range specs {
+			result.Issues = append(result.Issues, linter.Lint(spec.Spec)...)
+		}
+		for _, issue := range result.Issues {
+			if failOn != lint.SeverityOff && issue.Severity >= failOn {
+				exitCode = validateExitInvalid
+			}
+		}
+		results = append(results, result)
+	}
+
+	if format == "json" {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal issues: %v\n", err)
+			return validateExitUsage
+		}
+		fmt.Printf("%s\n", data)
+		return exitCode
+	}
+
+	for _, result := range results {
+		for _, issue := range result.Issues {
+			fmt.Printf("%s: %s\n", result.Path, issue)
+		}
+	}
+
+	return exitCode
+}
+
+var (
+	lintCfg lintFlags
+)
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().StringSliceVar(&lintCfg.severity,
+		"severity", nil, "override the severity of a rule (rule=level)")
+	lintCmd.Flags().StringVar(&lintCfg.failOn,
+		"fail-on", lint.SeverityError.String(), "minimum severity of issues to fail on (off never fails)")
+	lintCmd.Flags().StringVarP(&lintCfg.output,
+		"output", "o", "", "output format for the issues (json)")
+	lintCmd.Flags().BoolVar(&lintCfg.rules,
+		"rules", false, "list the rules and their severities")
+}
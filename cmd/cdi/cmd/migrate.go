@@ -0,0 +1,142 @@
+/*
+   Copyright © 2021 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi/producer"
+)
+
+type migrateFlags struct {
+	version string
+	dryRun  bool
+}
+
+// migrateCmd is our command for migrating CDI Spec files.
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [file|dir|glob...]",
+	Short: "Migrate CDI Spec files to a CDI version",
+	Long: `
+The 'migrate' command rewrites CDI Spec files, migrating them to the CDI
+version given by --to, or to the current version by default. Legacy
+layouts, for instance device nodes with a 'containerPath' instead of a
+'path', are rewritten to their current form. Arguments are handled like
+for 'cdi validate'. Files are rewritten in place, keeping their encoding.
+With --dry-run the changes are only listed.
+
+The exit status is 0 if all files were migrated, 1 if any of them could
+not be migrated, and 2 if the arguments cannot be processed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			fmt.Fprintf(os.Stderr, "Spec files, directories or glob patterns expected\n")
+			os.Exit(validateExitUsage)
+		}
+		os.Exit(cdiMigrateSpecFiles(migrateCfg.version, migrateCfg.dryRun, args...))
+	},
+}
+
+func cdiMigrateSpecFiles(version string, dryRun bool, args ...string) int {
+	paths, err := collectSpecFiles(args...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return validateExitUsage
+	}
+	if len(paths) == 0 {
+		fmt.Fprintf(os.Stderr, "no CDI Spec files found\n")
+		return validateExitUsage
+	}
+
+	exitCode := 0
+	for _, path := range paths {
+		changes, err := migrateSpecFile(path, version, dryRun)
+		if err != nil {
+			fmt.Printf("%s: failed: %v\n", path, err)
+			exitCode = validateExitInvalid
+			continue
+		}
+		if len(changes) == 0 {
+			fmt.Printf("%s: unchanged\n", path)
+			continue
+		}
+		fmt.Printf("%s: migrated\n", path)
+		for idx, change := range changes {
+			fmt.Printf("  %2d: %s\n", idx, change)
+		}
+	}
+
+	return exitCode
+}
+
+// migrateSpecFile migrates a single Spec file, returning the changes made.
+func migrateSpecFile(path, version string, dryRun bool) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, changes, err := producer.MigrateSpecData(data, version)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 || dryRun {
+		return changes, nil
+	}
+
+	if filepath.Ext(path) == ".json" {
+		data, err = json.MarshalIndent(spec, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(spec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal migrated Spec: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	tmp := path + ".migrate"
+	if err := os.WriteFile(tmp, data, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to write migrated Spec: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("failed to replace Spec: %w", err)
+	}
+
+	return changes, nil
+}
+
+var (
+	migrateCfg migrateFlags
+)
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	migrateCmd.Flags().StringVar(&migrateCfg.version,
+		"to", "", "CDI version to migrate to (default current version)")
+	migrateCmd.Flags().BoolVar(&migrateCfg.dryRun,
+		"dry-run", false, "only list the changes, don't rewrite files")
+}
@@ -0,0 +1,118 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/cdi/producer"
+)
+
+type mockFlags struct {
+	dir    string
+	remove bool
+	opts   producer.MockOptions
+}
+
+// mockCmd is our command for writing Spec files with synthetic devices.
+var mockCmd = &cobra.Command{
+	Use:   "mock",
+	Short: "Write a CDI Spec with synthetic devices for testing",
+	Long: `
+The 'mock' command writes a CDI Spec file with synthetic devices into a
+Spec directory, for testing container runtimes and orchestrators without
+real hardware. Every device gets --nodes device nodes, backed by /dev/null
+on the host, and unless --env-prefix is empty, an environment variable
+marking it as injected. The same flags always write the same Spec file.
+
+The Spec file is written into the directory given by --dir, by default the
+Spec directory with the highest priority. Running the command again with
+--remove and the same --kind removes the Spec file.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			fmt.Printf("no arguments expected\n")
+			os.Exit(1)
+		}
+
+		if err := cdiMock(mockCfg.dir, mockCfg.remove, mockCfg.opts); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func cdiMock(dir string, remove bool, opts producer.MockOptions) error {
+	if dir == "" {
+		dirs := cdi.GetDefaultCache().GetSpecDirectories()
+		if len(dirs) == 0 {
+			return fmt.Errorf("no Spec directories to write to")
+		}
+		dir = dirs[len(dirs)-1]
+	}
+
+	if remove {
+		path, err := producer.MockSpecPath(dir, opts)
+		if err != nil {
+			return err
+		}
+		mock := &producer.MockSpec{Path: path}
+		if err := mock.Remove(); err != nil {
+			return err
+		}
+		fmt.Printf("Removed mock Spec %s\n", path)
+		return nil
+	}
+
+	mock, err := producer.WriteMockSpec(dir, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote mock Spec %s with devices:\n", mock.Path)
+	for _, device := range mock.Devices {
+		fmt.Printf("  %s\n", device)
+	}
+
+	return nil
+}
+
+var (
+	mockCfg mockFlags
+)
+
+func init() {
+	rootCmd.AddCommand(mockCmd)
+	mockCmd.Flags().StringVar(&mockCfg.dir,
+		"dir", "", "Spec directory to write to (default: highest priority Spec directory)")
+	mockCmd.Flags().BoolVar(&mockCfg.remove,
+		"remove", false, "remove the mock Spec instead of writing it")
+	mockCmd.Flags().StringVar(&mockCfg.opts.Kind,
+		"kind", producer.DefaultMockKind, "kind of the mock CDI Spec (vendor.com/class)")
+	mockCmd.Flags().IntVarP(&mockCfg.opts.Count,
+		"count", "n", 1, "number of mock devices")
+	mockCmd.Flags().StringVar(&mockCfg.opts.NamePrefix,
+		"name-prefix", producer.DefaultMockNamePrefix, "prefix of the mock device names")
+	mockCmd.Flags().IntVar(&mockCfg.opts.NodesPerDevice,
+		"nodes", 1, "number of device nodes per mock device")
+	mockCmd.Flags().StringVar(&mockCfg.opts.DeviceDir,
+		"device-dir", producer.DefaultMockDeviceDir, "container directory of the mock device nodes")
+	mockCmd.Flags().StringVar(&mockCfg.opts.EnvPrefix,
+		"env-prefix", "CDI_MOCK_", "prefix of the environment variables marking injected mock devices")
+}
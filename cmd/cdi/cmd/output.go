@@ -0,0 +1,234 @@
+/*
+   Copyright © 2021 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/parser"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// Machine-readable output formats, selected with --output.
+const (
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// The types below define the machine-readable output of the commands.
+// Automation depends on them, so fields must not be renamed or removed.
+
+// vendorsOutput is the output of the 'vendors' command.
+type vendorsOutput struct {
+	Vendors []vendorOutput `json:"vendors"`
+}
+
+type vendorOutput struct {
+	Name  string   `json:"name"`
+	Specs []string `json:"specs"`
+}
+
+// classesOutput is the output of the 'classes' command.
+type classesOutput struct {
+	Classes []classOutput `json:"classes"`
+}
+
+type classOutput struct {
+	Name    string   `json:"name"`
+	Vendors []string `json:"vendors"`
+}
+
+// devicesOutput is the output of the 'devices' command.
+type devicesOutput struct {
+	Devices []deviceOutput `json:"devices"`
+}
+
+type deviceOutput struct {
+	Name       string `json:"name"`
+	Spec       string `json:"spec"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+	ReplacedBy string `json:"replacedBy,omitempty"`
+	// Device and Provenance are only set in verbose mode.
+	Device     *cdispec.Device     `json:"device,omitempty"`
+	Provenance *cdi.SpecProvenance `json:"provenance,omitempty"`
+}
+
+// specsOutput is the output of the 'specs' command.
+type specsOutput struct {
+	Specs []specOutput `json:"specs"`
+}
+
+type specOutput struct {
+	Path     string   `json:"path"`
+	Vendor   string   `json:"vendor"`
+	Class    string   `json:"class"`
+	Priority int      `json:"priority"`
+	Devices  []string `json:"devices"`
+	Errors   []string `json:"errors,omitempty"`
+	// Spec and Provenance are only set in verbose mode.
+	Spec       *cdispec.Spec       `json:"spec,omitempty"`
+	Provenance *cdi.SpecProvenance `json:"provenance,omitempty"`
+}
+
+// injectOutput is the output of the 'inject' command.
+type injectOutput struct {
+	Devices    []string  `json:"devices"`
+	Unresolved []string  `json:"unresolved,omitempty"`
+	Warnings   []string  `json:"warnings,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	OCISpec    *oci.Spec `json:"ociSpec,omitempty"`
+}
+
+// isMachineOutput returns true if machine-readable output is requested.
+func isMachineOutput(format string) bool {
+	return format == outputJSON || format == outputYAML
+}
+
+// checkOutputFormat exits if the given output format is invalid.
+func checkOutputFormat(format string) {
+	if format != "" && !isMachineOutput(format) {
+		fmt.Printf("invalid output format %q, expected %s or %s\n", format, outputJSON, outputYAML)
+		os.Exit(1)
+	}
+}
+
+// exitWithError prints the given error and exits. With machine-readable
+// output the error is printed to stderr.
+func exitWithError(format string, err error) {
+	if isMachineOutput(format) {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	} else {
+		fmt.Printf("%v\n", err)
+	}
+	os.Exit(1)
+}
+
+// printOutput prints machine-readable output in the given format.
+func printOutput(obj interface{}, format string) {
+	fmt.Printf("%s", marshalObject(0, obj, format))
+}
+
+func cdiOutputVendors(format string) {
+	var (
+		cache cdi.SpecProvider = cdi.GetDefaultCache()
+		out                    = vendorsOutput{Vendors: []vendorOutput{}}
+	)
+
+	for _, vendor := range cache.ListVendors() {
+		v := vendorOutput{Name: vendor, Specs: []string{}}
+		for _, spec := range cache.GetVendorSpecs(vendor) {
+			v.Specs = append(v.Specs, spec.GetPath())
+		}
+		out.Vendors = append(out.Vendors, v)
+	}
+
+	printOutput(out, format)
+}
+
+func cdiOutputClasses(format string) {
+	var (
+		cache cdi.SpecProvider = cdi.GetDefaultCache()
+		out                    = classesOutput{Classes: []classOutput{}}
+	)
+
+	for _, class := range cache.ListClasses() {
+		c := classOutput{Name: class, Vendors: []string{}}
+		for _, vendor := range cache.ListVendors() {
+			for _, spec := range cache.GetVendorSpecs(vendor) {
+				if spec.GetClass() == class {
+					c.Vendors = append(c.Vendors, vendor)
+					break
+				}
+			}
+		}
+		sort.Strings(c.Vendors)
+		out.Classes = append(out.Classes, c)
+	}
+
+	printOutput(out, format)
+}
+
+func cdiOutputDevices(verbose bool, format string) {
+	var (
+		cache cdi.DeviceResolver = cdi.GetDefaultCache()
+		out                      = devicesOutput{Devices: []deviceOutput{}}
+	)
+
+	for _, name := range cache.ListDevices() {
+		dev := cache.GetDevice(name)
+		if dev == nil {
+			continue
+		}
+		d := deviceOutput{
+			Name:       dev.GetQualifiedName(),
+			Spec:       dev.GetSpec().GetPath(),
+			Deprecated: dev.IsDeprecated(),
+		}
+		if d.Deprecated {
+			d.ReplacedBy = dev.GetLifecycle().ReplacedBy
+		}
+		if verbose {
+			provenance := dev.GetProvenance()
+			d.Device = dev.Device
+			d.Provenance = &provenance
+		}
+		out.Devices = append(out.Devices, d)
+	}
+
+	printOutput(out, format)
+}
+
+func cdiOutputSpecs(verbose bool, format string, vendors ...string) {
+	var (
+		cache cdi.SpecProvider = cdi.GetDefaultCache()
+		out                    = specsOutput{Specs: []specOutput{}}
+	)
+
+	if len(vendors) == 0 {
+		vendors = cache.ListVendors()
+	}
+
+	for _, vendor := range vendors {
+		for _, spec := range cache.GetVendorSpecs(vendor) {
+			s := specOutput{
+				Path:     spec.GetPath(),
+				Vendor:   spec.GetVendor(),
+				Class:    spec.GetClass(),
+				Priority: spec.GetPriority(),
+				Devices:  []string{},
+			}
+			for _, dev := range spec.Devices {
+				s.Devices = append(s.Devices, parser.QualifiedName(spec.GetVendor(), spec.GetClass(), dev.Name))
+			}
+			for _, err := range cache.GetSpecErrors(spec) {
+				s.Errors = append(s.Errors, err.Error())
+			}
+			if verbose {
+				provenance := spec.GetProvenance()
+				s.Spec = spec.Spec
+				s.Provenance = &provenance
+			}
+			out.Specs = append(out.Specs, s)
+		}
+	}
+
+	printOutput(out, format)
+}
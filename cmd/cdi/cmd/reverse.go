@@ -0,0 +1,112 @@
+/*
+   Copyright © 2021 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi/producer"
+)
+
+type reverseFlags struct {
+	kind   string
+	device string
+	output string
+}
+
+// reverseCmd is our command for generating CDI Specs from OCI Spec diffs.
+var reverseCmd = &cobra.Command{
+	Use:   "reverse-engineer <original OCI Spec> <modified OCI Spec>",
+	Short: "Generate a CDI Spec from the changes between two OCI Specs",
+	Long: `
+The 'reverse-engineer' command compares an original OCI Spec with one
+modified by a legacy injector, for instance an OCI runtime hook, and
+dumps a CDI Spec with a single device which makes the same changes.
+The kind of the Spec is given by --kind and the name of the device by
+--device. OCI bundle directories can be given instead of OCI Spec files.
+
+Added or changed environment variables, device nodes, mounts, hooks,
+additional GIDs and Intel RDT parameters are converted. Any other
+changes, including removals, cannot be made by CDI. These are listed
+as warnings on stderr and should be reviewed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			fmt.Printf("original and modified OCI Spec expected\n")
+			os.Exit(1)
+		}
+		if reverseCfg.kind == "" {
+			fmt.Printf("CDI Spec kind (--kind) expected\n")
+			os.Exit(1)
+		}
+		checkOutputFormat(reverseCfg.output)
+
+		if err := cdiReverseEngineer(reverseCfg.kind, reverseCfg.device,
+			reverseCfg.output, ociSpecPath(args[0]), ociSpecPath(args[1])); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func cdiReverseEngineer(kind, device, format, originalPath, modifiedPath string) error {
+	original, err := readOCISpec(originalPath)
+	if err != nil {
+		return err
+	}
+	modified, err := readOCISpec(modifiedPath)
+	if err != nil {
+		return err
+	}
+
+	spec, unsupported, err := producer.SpecFromOCIDiff(original, modified, kind, device)
+	for _, change := range unsupported {
+		fmt.Fprintf(os.Stderr, "Warning: not converted: %s\n", change)
+	}
+	if err != nil {
+		return err
+	}
+
+	encoding := producer.EncodingYAML
+	if format == outputJSON {
+		encoding = producer.EncodingJSON
+	}
+	data, err := producer.Format(spec, producer.FormatOptions{Encoding: encoding})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+var (
+	reverseCfg reverseFlags
+)
+
+func init() {
+	rootCmd.AddCommand(reverseCmd)
+	reverseCmd.Flags().StringVar(&reverseCfg.kind,
+		"kind", "", "kind of the generated CDI Spec (vendor.com/class)")
+	reverseCmd.Flags().StringVar(&reverseCfg.device,
+		"device", "device", "name of the generated CDI device")
+	reverseCmd.Flags().StringVarP(&reverseCfg.output,
+		"output", "o", "", "encoding of the generated CDI Spec (json|yaml, default yaml)")
+	registerOutputCompletion(reverseCmd)
+}
@@ -65,16 +65,19 @@ func initSpecDirs() {
 		fmt.Printf("failed to load JSON schema %s: %v\n", schemaName, err)
 		os.Exit(1)
 	}
+	// used by ReadSpec() and ReadSpecs() and for the default cache
 	cdi.SetSpecValidator(validate.WithSchema(s))
 
 	if len(specDirs) > 0 {
 		cache, err := cdi.NewCache(
 			cdi.WithSpecDirs(specDirs...),
+			cdi.WithSpecValidator(validate.WithSchema(s)),
 		)
 		if err != nil {
 			fmt.Printf("failed to create CDI cache: %v\n", err)
 			os.Exit(1)
 		}
+		cdi.SetDefaultCache(cache)
 		if len(cache.GetErrors()) > 0 {
 			cdiPrintCacheErrors()
 			os.Exit(1)
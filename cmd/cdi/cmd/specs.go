@@ -38,9 +38,19 @@ The 'specs' command lists all CDI Specs present in the cache.
 If a vendor list is given, only CDI Specs by the given vendors are
 listed. The CDI Specs are discovered and loaded to the cache from
 CDI Spec directories. The default CDI Spec directories are:
-    %s.`, strings.Join(cdi.DefaultSpecDirs, ", ")),
+    %s.
+
+With the --output option the CDI Specs are listed in a machine-readable
+format instead. In verbose mode the full CDI Specs are included, together
+with the modification time and digest of their Spec files.`, strings.Join(cdi.DefaultSpecDirs, ", ")),
+	ValidArgsFunction: completeVendors,
 	Run: func(cmd *cobra.Command, vendors []string) {
-		cdiListSpecs(specCfg.verbose, specCfg.output, vendors...)
+		checkOutputFormat(specCfg.output)
+		if isMachineOutput(specCfg.output) {
+			cdiOutputSpecs(specCfg.verbose, specCfg.output, vendors...)
+			return
+		}
+		cdiListSpecs(specCfg.verbose, "", vendors...)
 	},
 }
 
@@ -53,5 +63,6 @@ func init() {
 	specsCmd.Flags().BoolVarP(&specCfg.verbose,
 		"verbose", "v", false, "list CDI Spec details")
 	specsCmd.Flags().StringVarP(&specCfg.output,
-		"output", "o", "", "output format for details (json|yaml)")
+		"output", "o", "", "machine-readable output format (json|yaml)")
+	registerOutputCompletion(specsCmd)
 }
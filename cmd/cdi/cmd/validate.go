@@ -17,42 +17,286 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
 
 	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/schema"
 )
 
-// validateCmd is our CDI command for validating CDI Spec files in the cache.
+const (
+	// validateExitInvalid is the exit status for invalid Spec files.
+	validateExitInvalid = 1
+	// validateExitUsage is the exit status for usage or I/O errors.
+	validateExitUsage = 2
+)
+
+type validateFlags struct {
+	strict bool
+	output string
+}
+
+// validateCmd is our CDI command for validating CDI Spec files.
 var validateCmd = &cobra.Command{
-	Use:   "validate",
-	Short: "List CDI cache errors",
+	Use:   "validate [file|dir|glob...]",
+	Short: "Validate CDI Spec files or list CDI cache errors",
 	Long: `
-The 'validate' command lists errors encountered during the population
-of the CDI cache. It exits with an exit status of 1 if any errors
-were reported by the cache.`,
+Without arguments the 'validate' command lists errors encountered
+during the population of the CDI cache. It exits with an exit status
+of 1 if any errors were reported by the cache.
+
+With arguments the 'validate' command validates the given CDI Spec
+files. Arguments can be files, directories, or glob patterns.
+Directories are searched recursively for files with a '.json' or a
+'.yaml' extension. Files are validated against the JSON schema given
+by --schema. By default each file is validated against the builtin
+schema for its CDI version. With --strict the content of the Spec
+files is validated as well, using the same checks which are done when
+a Spec file is loaded into the CDI cache, and container paths of
+mounts and device nodes are required to be absolute.
+
+The exit status is 0 if all Spec files are valid, 1 if any of them
+is invalid, and 2 if the arguments cannot be processed, for instance
+if no Spec files are found.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cache := cdi.GetDefaultCache()
-		cdiErrors := cache.GetErrors()
-		if len(cdiErrors) == 0 {
-			fmt.Printf("No CDI cache errors.\n")
+		if err := checkValidateOutput(validateCfg.output); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(validateExitUsage)
+		}
+		if len(args) == 0 {
+			cdiValidateCache()
 			return
 		}
+		os.Exit(cdiValidateSpecFiles(args...))
+	},
+}
+
+// checkValidateOutput checks the output format of the validation report.
+func checkValidateOutput(format string) error {
+	if format != "" && format != outputJSON {
+		return fmt.Errorf("invalid output format %q, expected %s", format, outputJSON)
+	}
+	return nil
+}
+
+func cdiValidateCache() {
+	cache := cdi.GetDefaultCache()
+	cdiErrors := cache.GetErrors()
+	if len(cdiErrors) == 0 {
+		fmt.Printf("No CDI cache errors.\n")
+		return
+	}
+
+	fmt.Printf("CDI cache has errors:\n")
+	for path, specErrors := range cdiErrors {
+		fmt.Printf("Spec file %s:\n", path)
+		for idx, err := range specErrors {
+			fmt.Printf("  %2d: %v\n", idx, strings.TrimSpace(err.Error()))
+		}
+	}
+	os.Exit(1)
+}
+
+// validateResult is the validation result for a single Spec file.
+type validateResult struct {
+	Path   string   `json:"path"`
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+func cdiValidateSpecFiles(args ...string) int {
+	if err := checkValidateOutput(validateCfg.output); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return validateExitUsage
+	}
+
+	paths, err := collectSpecFiles(args...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return validateExitUsage
+	}
+	if len(paths) == 0 {
+		fmt.Fprintf(os.Stderr, "no CDI Spec files found\n")
+		return validateExitUsage
+	}
+
+	s, err := schema.Load(schemaName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load JSON schema %s: %v\n", schemaName, err)
+		return validateExitUsage
+	}
+
+	var (
+		results  []*validateResult
+		exitCode int
+	)
+
+	for _, path := range paths {
+		result := &validateResult{Path: path, Valid: true}
+		for _, err := range validateSpecFile(s, path, validateCfg.strict) {
+			result.Valid = false
+			result.Errors = append(result.Errors, strings.TrimSpace(err.Error()))
+		}
+		if !result.Valid {
+			exitCode = validateExitInvalid
+		}
+		results = append(results, result)
+	}
+
+	if validateCfg.output == outputJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal validation report: %v\n", err)
+			return validateExitUsage
+		}
+		fmt.Printf("%s\n", data)
+		return exitCode
+	}
+
+	for _, result := range results {
+		if result.Valid {
+			fmt.Printf("%s: valid\n", result.Path)
+			continue
+		}
+		fmt.Printf("%s: invalid\n", result.Path)
+		for idx, err := range result.Errors {
+			fmt.Printf("  %2d: %s\n", idx, err)
+		}
+	}
+
+	return exitCode
+}
+
+// collectSpecFiles expands the given files, directories and glob patterns
+// into a sorted list of Spec files.
+func collectSpecFiles(args ...string) ([]string, error) {
+	var (
+		seen  = map[string]struct{}{}
+		paths []string
+	)
+
+	add := func(path string) {
+		path = filepath.Clean(path)
+		if _, ok := seen[path]; !ok {
+			seen[path] = struct{}{}
+			paths = append(paths, path)
+		}
+	}
+
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no such file or directory", arg)
+		}
 
-		fmt.Printf("CDI cache has errors:\n")
-		for path, specErrors := range cdiErrors {
-			fmt.Printf("Spec file %s:\n", path)
-			for idx, err := range specErrors {
-				fmt.Printf("  %2d: %v\n", idx, strings.TrimSpace(err.Error()))
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				add(match)
+				continue
+			}
+			err = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() {
+					return nil
+				}
+				if ext := filepath.Ext(path); ext == ".json" || ext == ".yaml" {
+					add(path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan directory %q: %w", match, err)
 			}
 		}
-		os.Exit(1)
-	},
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// validateSpecFile validates a single Spec file, returning any errors found.
+func validateSpecFile(s *schema.Schema, path string, strict bool) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{err}
+	}
+	if s == schema.BuiltinSchema() {
+		s = schema.ForSpecVersion(specDataVersion(data))
+	}
+	if err := s.ValidateData(data); err != nil {
+		return []error{fmt.Errorf("schema validation failed: %w", err)}
+	}
+	if !strict {
+		return nil
+	}
+
+	spec, err := cdi.ReadSpec(path, 0)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	checkPath := func(what, path string) {
+		if !filepath.IsAbs(path) {
+			errs = append(errs, fmt.Errorf("%s: container path %q is not absolute", what, path))
+		}
+	}
+	checkEdits := func(scope string, edits *cdi.ContainerEdits) {
+		if edits == nil || edits.ContainerEdits == nil {
+			return
+		}
+		for _, m := range edits.Mounts {
+			checkPath(scope+" mount", m.ContainerPath)
+		}
+		for _, d := range edits.DeviceNodes {
+			checkPath(scope+" device node", d.Path)
+		}
+	}
+
+	checkEdits("spec", &cdi.ContainerEdits{ContainerEdits: &spec.ContainerEdits})
+	for _, d := range spec.Devices {
+		d := d
+		checkEdits(fmt.Sprintf("device %q", d.Name), &cdi.ContainerEdits{ContainerEdits: &d.ContainerEdits})
+	}
+
+	return errs
 }
 
+// specDataVersion returns the cdiVersion of the given Spec data, or an
+// empty string if it can't be determined.
+func specDataVersion(data []byte) string {
+	var doc struct {
+		Version string `json:"cdiVersion"`
+	}
+	_ = yaml.Unmarshal(data, &doc)
+	return doc.Version
+}
+
+var (
+	validateCfg validateFlags
+)
+
 func init() {
 	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().BoolVar(&validateCfg.strict,
+		"strict", false, "validate Spec content in addition to the JSON schema")
+	validateCmd.Flags().StringVarP(&validateCfg.output,
+		"output", "o", "", "output format for the validation report (json)")
 }
@@ -0,0 +1,47 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateOutputFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vendor.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(editValidSpec), 0o644))
+	schemaName = "builtin"
+	t.Cleanup(func() { validateCfg = validateFlags{} })
+
+	for _, tc := range []struct {
+		output   string
+		exitCode int
+	}{
+		{output: "", exitCode: 0},
+		{output: "json", exitCode: 0},
+		{output: "yaml", exitCode: validateExitUsage},
+		{output: "JSON", exitCode: validateExitUsage},
+	} {
+		t.Run("output="+tc.output, func(t *testing.T) {
+			validateCfg = validateFlags{output: tc.output}
+			require.Equal(t, tc.exitCode, cdiValidateSpecFiles(path))
+		})
+	}
+}
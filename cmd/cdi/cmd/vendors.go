@@ -20,16 +20,32 @@ import (
 	"github.com/spf13/cobra"
 )
 
+type vendorsFlags struct {
+	output string
+}
+
 // vendorsCmd is our command for listing vendors.
 var vendorsCmd = &cobra.Command{
 	Use:   "vendors",
 	Short: "List vendors",
 	Long:  `List vendors with CDI Specs in the cache.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		checkOutputFormat(vendorsCfg.output)
+		if isMachineOutput(vendorsCfg.output) {
+			cdiOutputVendors(vendorsCfg.output)
+			return
+		}
 		cdiListVendors()
 	},
 }
 
+var (
+	vendorsCfg vendorsFlags
+)
+
 func init() {
 	rootCmd.AddCommand(vendorsCmd)
+	vendorsCmd.Flags().StringVarP(&vendorsCfg.output,
+		"output", "o", "", "machine-readable output format (json|yaml)")
+	registerOutputCompletion(vendorsCmd)
 }
@@ -40,6 +40,9 @@ You can use validate in following ways:
    3.input document content manually, ended with ctrl+d(or your self-defined EOF keys)
       validate --schema <schema.json>
       [INPUT DOCUMENT CONTENT HERE]
+
+Note: this utility is deprecated, use 'cdi validate' instead. It
+supports validating multiple files, directories and glob patterns.
 `
 
 func main() {
@@ -0,0 +1,232 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package cdi is a compatibility shim for the legacy import path of the
+// CDI package. It re-exports the API of tags.cncf.io/container-device-interface/pkg/cdi
+// which legacy users depend on, together with the legacy Registry
+// interface, so that users can switch to the new import path package by
+// package.
+//
+// Deprecated: use tags.cncf.io/container-device-interface/pkg/cdi.
+package cdi
+
+import (
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/parser"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// AnnotationPrefix is the prefix for CDI container annotation keys.
+	AnnotationPrefix = cdi.AnnotationPrefix
+
+	// DefaultStaticDir is the default directory for static CDI Specs.
+	DefaultStaticDir = cdi.DefaultStaticDir
+	// DefaultDynamicDir is the default directory for generated CDI Specs.
+	DefaultDynamicDir = cdi.DefaultDynamicDir
+
+	// PrestartHook is the name of the OCI "prestart" hook.
+	PrestartHook = cdi.PrestartHook
+	// CreateRuntimeHook is the name of the OCI "createRuntime" hook.
+	CreateRuntimeHook = cdi.CreateRuntimeHook
+	// CreateContainerHook is the name of the OCI "createContainer" hook.
+	CreateContainerHook = cdi.CreateContainerHook
+	// StartContainerHook is the name of the OCI "startContainer" hook.
+	StartContainerHook = cdi.StartContainerHook
+	// PoststartHook is the name of the OCI "poststart" hook.
+	PoststartHook = cdi.PoststartHook
+	// PoststopHook is the name of the OCI "poststop" hook.
+	PoststopHook = cdi.PoststopHook
+)
+
+var (
+	// DefaultSpecDirs is the default Spec directory configuration.
+	DefaultSpecDirs = cdi.DefaultSpecDirs
+	// ErrStopScan can be returned from a ScanSpecFunc to stop the scan.
+	ErrStopScan = cdi.ErrStopScan
+)
+
+type (
+	// Cache stores CDI Specs loaded from Spec directories.
+	Cache = cdi.Cache
+	// Option is an option to change some aspect of default CDI behavior.
+	Option = cdi.Option
+	// Spec represents a single CDI Spec.
+	Spec = cdi.Spec
+	// Device represents a CDI device of a Spec.
+	Device = cdi.Device
+	// ContainerEdits represent updates to be applied to an OCI Spec.
+	ContainerEdits = cdi.ContainerEdits
+	// DeviceNode is a CDI Spec DeviceNode wrapper.
+	DeviceNode = cdi.DeviceNode
+	// Hook is a CDI Spec Hook wrapper.
+	Hook = cdi.Hook
+	// Mount is a CDI Mount wrapper.
+	Mount = cdi.Mount
+	// IntelRdt is a CDI IntelRdt wrapper.
+	IntelRdt = cdi.IntelRdt
+)
+
+// NewCache creates a new CDI Cache.
+func NewCache(options ...Option) (*Cache, error) {
+	return cdi.NewCache(options...)
+}
+
+// WithSpecDirs returns an option to override the CDI Spec directories.
+func WithSpecDirs(dirs ...string) Option {
+	return cdi.WithSpecDirs(dirs...)
+}
+
+// WithAutoRefresh returns an option to control automatic Cache refresh.
+func WithAutoRefresh(autoRefresh bool) Option {
+	return cdi.WithAutoRefresh(autoRefresh)
+}
+
+// ReadSpec reads the given CDI Spec file.
+func ReadSpec(path string, priority int) (*Spec, error) {
+	return cdi.ReadSpec(path, priority)
+}
+
+// ParseSpec parses CDI Spec data into a raw CDI Spec.
+func ParseSpec(data []byte) (*cdispec.Spec, error) {
+	return cdi.ParseSpec(data)
+}
+
+// SetSpecValidator sets a CDI Spec validator function.
+//
+// Deprecated: use the WithSpecValidator option of the new package.
+func SetSpecValidator(fn func(*cdispec.Spec) error) {
+	if fn == nil {
+		cdi.SetSpecValidator(nil) //nolint:staticcheck // forwarding deprecated API
+		return
+	}
+	cdi.SetSpecValidator(specValidatorFunc(fn)) //nolint:staticcheck // forwarding deprecated API
+}
+
+// specValidatorFunc adapts a validator function to the validator of
+// released versions of the new package, which is an interface.
+type specValidatorFunc func(*cdispec.Spec) error
+
+// Validate the given Spec.
+func (fn specValidatorFunc) Validate(spec *cdispec.Spec) error {
+	return fn(spec)
+}
+
+// MinimumRequiredVersion determines the minimum spec version for the input spec.
+func MinimumRequiredVersion(spec *cdispec.Spec) (string, error) {
+	return cdispec.MinimumRequiredVersion(spec)
+}
+
+// GenerateSpecName generates a vendor+class scoped Spec file name.
+func GenerateSpecName(vendor, class string) string {
+	return cdi.GenerateSpecName(vendor, class)
+}
+
+// GenerateTransientSpecName generates a vendor+class scoped transient
+// Spec file name.
+func GenerateTransientSpecName(vendor, class, transientID string) string {
+	return cdi.GenerateTransientSpecName(vendor, class, transientID)
+}
+
+// GenerateNameForTransientSpec generates a name for the given transient
+// Spec and transient ID.
+func GenerateNameForTransientSpec(raw *cdispec.Spec, transientID string) (string, error) {
+	return cdi.GenerateNameForTransientSpec(raw, transientID)
+}
+
+// UpdateAnnotations updates annotations with a plugin-specific CDI device
+// injection request for the given devices.
+func UpdateAnnotations(annotations map[string]string, plugin string, deviceID string, devices []string) (map[string]string, error) {
+	return cdi.UpdateAnnotations(annotations, plugin, deviceID, devices)
+}
+
+// ParseAnnotations parses annotations for CDI device injection requests.
+func ParseAnnotations(annotations map[string]string) ([]string, []string, error) {
+	return cdi.ParseAnnotations(annotations)
+}
+
+// AnnotationKey returns a unique annotation key for an device allocation
+// by a K8s device plugin.
+func AnnotationKey(pluginName, deviceID string) (string, error) {
+	return cdi.AnnotationKey(pluginName, deviceID)
+}
+
+// AnnotationValue returns an annotation value for the given devices.
+func AnnotationValue(devices []string) (string, error) {
+	return cdi.AnnotationValue(devices)
+}
+
+// ValidateEnv validates the given environment variables.
+func ValidateEnv(env []string) error {
+	return cdi.ValidateEnv(env)
+}
+
+// QualifiedName returns the qualified name for a device.
+//
+// Deprecated: use parser.QualifiedName.
+func QualifiedName(vendor, class, name string) string {
+	return parser.QualifiedName(vendor, class, name)
+}
+
+// IsQualifiedName tests if a device name is qualified.
+//
+// Deprecated: use parser.IsQualifiedName.
+func IsQualifiedName(device string) bool {
+	return parser.IsQualifiedName(device)
+}
+
+// ParseQualifiedName splits a qualified name into device vendor, class,
+// and name.
+//
+// Deprecated: use parser.ParseQualifiedName.
+func ParseQualifiedName(device string) (string, string, string, error) {
+	return parser.ParseQualifiedName(device)
+}
+
+// ParseDevice tries to split a device name into vendor, class, and name.
+//
+// Deprecated: use parser.ParseDevice.
+func ParseDevice(device string) (string, string, string) {
+	return parser.ParseDevice(device)
+}
+
+// ParseQualifier splits a device qualifier into vendor and class.
+//
+// Deprecated: use parser.ParseQualifier.
+func ParseQualifier(kind string) (string, string) {
+	return parser.ParseQualifier(kind)
+}
+
+// ValidateVendorName checks the validity of a vendor name.
+//
+// Deprecated: use parser.ValidateVendorName.
+func ValidateVendorName(vendor string) error {
+	return parser.ValidateVendorName(vendor)
+}
+
+// ValidateClassName checks the validity of class name.
+//
+// Deprecated: use parser.ValidateClassName.
+func ValidateClassName(class string) error {
+	return parser.ValidateClassName(class)
+}
+
+// ValidateDeviceName checks the validity of a device name.
+//
+// Deprecated: use parser.ValidateDeviceName.
+func ValidateDeviceName(name string) error {
+	return parser.ValidateDeviceName(name)
+}
@@ -0,0 +1,105 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"sync"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// Registry keeps track of all CDI Specs installed on the host. It is the
+// legacy interface to the default Cache.
+//
+// Deprecated: use the Cache of the new package, or GetDefaultCache().
+type Registry interface {
+	RegistryResolver
+	RegistryRefresher
+	DeviceDB() RegistryDeviceDB
+	SpecDB() RegistrySpecDB
+}
+
+// RegistryRefresher is the registry interface for refreshing the cache
+// of CDI Specs and devices.
+type RegistryRefresher interface {
+	Configure(...Option) error
+	Refresh() error
+	GetErrors() map[string][]error
+	GetSpecDirectories() []string
+	GetSpecDirErrors() map[string]error
+}
+
+// RegistryResolver is the registry interface for injecting CDI devices
+// into an OCI Spec.
+type RegistryResolver interface {
+	InjectDevices(spec *oci.Spec, device ...string) (unresolved []string, err error)
+}
+
+// RegistryDeviceDB is the registry interface for querying devices.
+type RegistryDeviceDB interface {
+	ListDevices() []string
+	GetDevice(device string) *Device
+}
+
+// RegistrySpecDB is the registry interface for querying CDI Specs.
+type RegistrySpecDB interface {
+	ListVendors() []string
+	ListClasses() []string
+	GetVendorSpecs(vendor string) []*Spec
+	GetSpecErrors(*Spec) []error
+	WriteSpec(raw *cdispec.Spec, name string) error
+	RemoveSpec(name string) error
+}
+
+type registry struct {
+	*cdi.Cache
+}
+
+var (
+	reg      *registry
+	initOnce sync.Once
+)
+
+var _ Registry = &registry{}
+
+// GetRegistry returns the Registry, a wrapper around the default Cache,
+// configured with the given options. The options are only applied when
+// they are given.
+//
+// Deprecated: use GetDefaultCache() of the new package.
+func GetRegistry(options ...Option) Registry {
+	initOnce.Do(func() {
+		reg = &registry{cdi.GetDefaultCache()}
+	})
+	if len(options) > 0 {
+		// We don't care about errors here
+		_ = reg.Configure(options...)
+	}
+	return reg
+}
+
+// DeviceDB returns the registry interface for querying devices.
+func (r *registry) DeviceDB() RegistryDeviceDB {
+	return r
+}
+
+// SpecDB returns the registry interface for querying CDI Specs.
+func (r *registry) SpecDB() RegistrySpecDB {
+	return r
+}
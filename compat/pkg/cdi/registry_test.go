@@ -0,0 +1,61 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+
+	specs "tags.cncf.io/container-device-interface/compat/specs-go"
+)
+
+func TestRegistry(t *testing.T) {
+	dir := t.TempDir()
+	spec := `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+  - name: dev0
+    containerEdits:
+      env:
+        - DEV0=1
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor.yaml"), []byte(spec), 0o644))
+
+	registry := GetRegistry(WithSpecDirs(dir), WithAutoRefresh(false))
+	require.NoError(t, registry.Refresh())
+	require.Equal(t, []string{"vendor.com/device=dev0"}, registry.DeviceDB().ListDevices())
+	require.Equal(t, []string{"vendor.com"}, registry.SpecDB().ListVendors())
+	require.Same(t, registry, GetRegistry())
+
+	var raw *specs.Spec = registry.DeviceDB().GetDevice("vendor.com/device=dev0").GetSpec().Spec
+	require.Equal(t, "vendor.com/device", raw.Kind)
+
+	ociSpec := &oci.Spec{}
+	unresolved, err := registry.InjectDevices(ociSpec, "vendor.com/device=dev0")
+	require.NoError(t, err)
+	require.Nil(t, unresolved)
+	require.Equal(t, []string{"DEV0=1"}, ociSpec.Process.Env)
+
+	vendor, class, name, err := ParseQualifiedName("vendor.com/device=dev0")
+	require.NoError(t, err)
+	require.Equal(t, "vendor.com/device=dev0", QualifiedName(vendor, class, name))
+}
@@ -0,0 +1,58 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package specs is a compatibility shim for the legacy import path of
+// the CDI Spec types. It re-exports tags.cncf.io/container-device-interface/specs-go,
+// which should be imported directly instead.
+//
+// Deprecated: use tags.cncf.io/container-device-interface/specs-go.
+package specs
+
+import (
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// CurrentVersion is the current version of the Spec.
+	CurrentVersion = cdi.CurrentVersion
+)
+
+type (
+	// Spec is the base configuration for CDI.
+	Spec = cdi.Spec
+	// Device is a "Device" a container runtime can add to a container.
+	Device = cdi.Device
+	// ContainerEdits are edits a container runtime must make to the OCI spec.
+	ContainerEdits = cdi.ContainerEdits
+	// DeviceNode represents a device node that needs to be added to the OCI spec.
+	DeviceNode = cdi.DeviceNode
+	// Mount represents a mount that needs to be added to the OCI spec.
+	Mount = cdi.Mount
+	// Hook represents a hook that needs to be added to the OCI spec.
+	Hook = cdi.Hook
+	// IntelRdt describes the Linux IntelRdt parameters to set in the OCI spec.
+	IntelRdt = cdi.IntelRdt
+)
+
+// ValidateVersion checks whether the specified spec version is valid.
+func ValidateVersion(spec *Spec) error {
+	return cdi.ValidateVersion(spec)
+}
+
+// MinimumRequiredVersion determines the minimum spec version for the input spec.
+func MinimumRequiredVersion(spec *Spec) (string, error) {
+	return cdi.MinimumRequiredVersion(spec)
+}
@@ -0,0 +1,195 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package e2e
+
+import (
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/cdi/testutil"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	vendor1Spec = `
+cdiVersion: "0.3.0"
+kind: "vendor1.com/device"
+devices:
+  - name: foo
+    containerEdits:
+      deviceNodes:
+        - path: /dev/loop8
+          type: b
+          major: 7
+          minor: 8
+      env:
+        - FOO=injected
+containerEdits:
+  env:
+    - "VENDOR1=present"
+`
+	vendor2Spec = `
+cdiVersion: "0.3.0"
+kind: "vendor2.com/device"
+devices:
+  - name: bar
+    containerEdits:
+      deviceNodes:
+        - path: /dev/loop9
+          type: b
+          major: 7
+          minor: 9
+      env:
+        - BAR=injected
+      mounts:
+        - hostPath: /usr/lib/libbar.so
+          containerPath: /usr/lib/libbar.so
+          options: ["ro", "bind"]
+`
+	vendor2Override = `
+cdiVersion: "0.3.0"
+kind: "vendor2.com/device"
+devices:
+  - name: bar
+    containerEdits:
+      env:
+        - BAR=overridden
+`
+)
+
+func TestInjection(t *testing.T) {
+	testutil.Run(t, []testutil.Scenario{
+		{
+			Name:   "no devices",
+			Expect: &oci.Spec{},
+		},
+		{
+			Name:     "devices from annotations",
+			EtcSpecs: map[string]string{"vendor1.yaml": vendor1Spec, "vendor2.yaml": vendor2Spec},
+			Annotations: map[string]string{
+				cdi.AnnotationPrefix + "vendor1_devices": "vendor1.com/device=foo",
+				cdi.AnnotationPrefix + "vendor2_devices": "vendor2.com/device=bar",
+			},
+			ExpectEnv: []string{"VENDOR1=present", "FOO=injected", "BAR=injected"},
+			ExpectDevices: []oci.LinuxDevice{
+				{Path: "/dev/loop8", Type: "b", Major: 7, Minor: 8},
+				{Path: "/dev/loop9", Type: "b", Major: 7, Minor: 9},
+			},
+			ExpectMounts: []oci.Mount{
+				{Source: "/usr/lib/libbar.so", Destination: "/usr/lib/libbar.so", Options: []string{"ro", "bind"}},
+			},
+		},
+		{
+			Name: "invalid annotations",
+			Annotations: map[string]string{
+				cdi.AnnotationPrefix + "devices": "foobar",
+			},
+			ExpectError: "foobar",
+		},
+		{
+			Name:             "unresolvable device",
+			EtcSpecs:         map[string]string{"vendor1.yaml": vendor1Spec},
+			Devices:          []string{"vendor1.com/device=foo", "vendor1.com/device=none"},
+			ExpectUnresolved: []string{"vendor1.com/device=none"},
+		},
+		{
+			Name:      "run overrides etc",
+			EtcSpecs:  map[string]string{"vendor2.yaml": vendor2Spec},
+			RunSpecs:  map[string]string{"vendor2.yaml": vendor2Override},
+			Devices:   []string{"vendor2.com/device=bar"},
+			ExpectEnv: []string{"BAR=overridden"},
+		},
+		{
+			Name:               "invalid Spec file",
+			EtcSpecs:           map[string]string{"vendor1.yaml": vendor1Spec, "broken.yaml": "kind: [\n"},
+			ExpectRefreshError: true,
+			Devices:            []string{"vendor1.com/device=foo"},
+			ExpectEnv:          []string{"VENDOR1=present", "FOO=injected"},
+		},
+		{
+			Name:     "existing OCI Spec content",
+			EtcSpecs: map[string]string{"vendor1.yaml": vendor1Spec},
+			OCISpec: &oci.Spec{
+				Process: &oci.Process{Env: []string{"PATH=/usr/bin"}},
+			},
+			Devices:   []string{"vendor1.com/device=foo"},
+			ExpectEnv: []string{"PATH=/usr/bin", "VENDOR1=present", "FOO=injected"},
+		},
+		{
+			Name:     "Spec file changes",
+			EtcSpecs: map[string]string{"vendor2.yaml": vendor2Spec},
+			Devices:  []string{"vendor2.com/device=bar"},
+			Check: func(t *testing.T, h *testutil.Harness, _ *oci.Spec) {
+				h.WriteSpec(testutil.RunDir, "vendor2.yaml", vendor2Override)
+				require.NoError(t, h.Refresh())
+				ociSpec, _, err := h.Inject(nil, "vendor2.com/device=bar")
+				require.NoError(t, err)
+				require.Equal(t, []string{"BAR=overridden"}, ociSpec.Process.Env)
+
+				h.RemoveSpec(testutil.EtcDir, "vendor2.yaml")
+				h.RemoveSpec(testutil.RunDir, "vendor2.yaml")
+				require.NoError(t, h.Refresh())
+				_, unresolved, err := h.Inject(nil, "vendor2.com/device=bar")
+				require.Error(t, err)
+				require.Equal(t, []string{"vendor2.com/device=bar"}, unresolved)
+			},
+		},
+	})
+}
+
+func TestFixture(t *testing.T) {
+	device := func(name string) cdispec.Device {
+		return cdispec.Device{
+			Name: name,
+			ContainerEdits: cdispec.ContainerEdits{
+				Env: []string{"DEVICE=" + name},
+			},
+		}
+	}
+	vendor := &cdispec.Spec{
+		Version: "0.3.0",
+		Kind:    "vendor.com/device",
+		Devices: []cdispec.Device{device("dev0")},
+	}
+
+	f := testutil.NewFixture(t, testutil.FixtureSpec{
+		Etc: map[string]*cdispec.Spec{"vendor.yaml": vendor},
+	})
+	require.NoError(t, f.Refresh())
+	require.Equal(t, []string{"vendor.com/device=dev0"}, f.Cache.ListDevices())
+
+	f.AddDevice(testutil.EtcDir, "vendor.yaml", device("dev1"))
+	require.Len(t, vendor.Devices, 1, "Fixture modified the given Spec")
+	require.Len(t, f.Spec(testutil.EtcDir, "vendor.yaml").Devices, 2)
+	require.NoError(t, f.Refresh())
+	ociSpec, _, err := f.Inject(nil, "vendor.com/device=dev1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"DEVICE=dev1"}, ociSpec.Process.Env)
+
+	f.Corrupt(testutil.EtcDir, "vendor.yaml")
+	require.Nil(t, f.Spec(testutil.EtcDir, "vendor.yaml"))
+	require.Error(t, f.Refresh())
+	require.Empty(t, f.Cache.ListDevices())
+
+	f.WriteSpecObject(testutil.RunDir, "vendor.yaml", vendor)
+	f.Remove(testutil.EtcDir, "vendor.yaml")
+	require.NoError(t, f.Refresh())
+	require.Equal(t, []string{"vendor.com/device=dev0"}, f.Cache.ListDevices())
+}
@@ -0,0 +1,123 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// SharedContainerPathsAnnotation is the Spec or device annotation used
+	// to exempt container paths from the uniqueness check. Its value is a
+	// comma-separated list of container paths, or "*" for all paths.
+	SharedContainerPathsAnnotation = "cdi.k8s.io/shared-container-paths"
+)
+
+// ValidateContainerPaths checks that no two devices of a Spec inject the
+// same container path, either as a mount or as a device node. The global
+// Spec-level container edits are treated as one more device, since they
+// are injected together with any device of the Spec.
+//
+// A container path may be shared if it is listed in the Spec-level
+// SharedContainerPathsAnnotation, or if it is listed in the device-level
+// SharedContainerPathsAnnotation of every device injecting it. This allows
+// vendors to define mutually exclusive devices which inject the same path.
+func ValidateContainerPaths(spec *cdi.Spec) error {
+	if spec == nil {
+		return nil
+	}
+
+	var (
+		specShared = sharedContainerPaths(spec.Annotations)
+		owners     = map[string][]string{}
+		exempt     = map[string]bool{}
+		order      []string
+	)
+
+	collect := func(owner string, shared map[string]bool, edits *cdi.ContainerEdits) {
+		seen := map[string]bool{}
+		add := func(path string) {
+			if path == "" {
+				return
+			}
+			path = filepath.Clean(path)
+			if seen[path] {
+				return
+			}
+			seen[path] = true
+			isShared := specShared["*"] || specShared[path] || shared["*"] || shared[path]
+			if _, ok := owners[path]; !ok {
+				order = append(order, path)
+				exempt[path] = isShared
+			} else {
+				exempt[path] = exempt[path] && isShared
+			}
+			owners[path] = append(owners[path], owner)
+		}
+		for _, m := range edits.Mounts {
+			if m != nil {
+				add(m.ContainerPath)
+			}
+		}
+		for _, d := range edits.DeviceNodes {
+			if d != nil {
+				add(d.Path)
+			}
+		}
+	}
+
+	collect("spec", specShared, &spec.ContainerEdits)
+	for i := range spec.Devices {
+		d := &spec.Devices[i]
+		collect(fmt.Sprintf("device %q", d.Name), sharedContainerPaths(d.Annotations), &d.ContainerEdits)
+	}
+
+	for _, path := range order {
+		if len(owners[path]) > 1 && !exempt[path] {
+			return fmt.Errorf("container path %q injected by multiple devices (%s)",
+				path, strings.Join(owners[path], ", "))
+		}
+	}
+
+	return nil
+}
+
+// sharedContainerPaths returns the set of container paths listed in the
+// SharedContainerPathsAnnotation of the given annotations.
+func sharedContainerPaths(annotations map[string]string) map[string]bool {
+	value, ok := annotations[SharedContainerPathsAnnotation]
+	if !ok {
+		return nil
+	}
+
+	shared := map[string]bool{}
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if path != "*" {
+			path = filepath.Clean(path)
+		}
+		shared[path] = true
+	}
+	return shared
+}
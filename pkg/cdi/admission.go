@@ -0,0 +1,178 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// ErrAdmissionDenied is wrapped by the errors for Specs and device
+// injections rejected by the AdmissionPolicy of a Cache.
+var ErrAdmissionDenied = errors.New("denied by CDI admission policy")
+
+// AdmissionPolicy decides whether a Cache admits Specs and device
+// injections. AdmitSpec is called for every Spec during Cache refresh.
+// Rejected Specs are left out of the Cache and the rejection is reported
+// among the errors of the Spec (see GetErrors()). AdmitInjection is called
+// by InjectDevices() with the resolved edits and the injected devices,
+// before the edits are applied. A rejected injection leaves the OCI Spec
+// unchanged.
+//
+// Policies can be implemented in Go or adapt a policy engine, for instance
+// to evaluate Rego policies.
+type AdmissionPolicy interface {
+	AdmitSpec(spec *Spec) error
+	AdmitInjection(edits *ContainerEdits, devices []*Device) error
+}
+
+// WithAdmissionPolicy returns an option to set the AdmissionPolicy of the
+// Cache. A nil policy, the default, admits everything.
+func WithAdmissionPolicy(p AdmissionPolicy) Option {
+	return func(c *Cache) {
+		c.admission = p
+	}
+}
+
+// admitSpec checks the given Spec against the admission policy.
+func (c *Cache) admitSpec(spec *Spec) error {
+	if c.admission == nil {
+		return nil
+	}
+	if err := c.admission.AdmitSpec(spec); err != nil {
+		return fmt.Errorf("CDI Spec %q %w: %w", spec.GetPath(), ErrAdmissionDenied, err)
+	}
+	return nil
+}
+
+// admitInjection checks the given injection against the admission policy.
+func (c *Cache) admitInjection(edits *ContainerEdits, devices []*Device) error {
+	if c.admission == nil {
+		return nil
+	}
+	if err := c.admission.AdmitInjection(edits, devices); err != nil {
+		return fmt.Errorf("%w: %w", ErrAdmissionDenied, err)
+	}
+	return nil
+}
+
+// EditRestrictions is an AdmissionPolicy for common restrictions of the
+// vendors and the container edits of Specs. Both Specs and injections
+// are checked, so violating Specs are rejected at refresh, and the final
+// edits of an injection, for instance after hook templates have been
+// rendered, are checked again.
+type EditRestrictions struct {
+	// Vendors are the vendors admitted. If empty, all vendors are.
+	Vendors []string
+	// NoHooks rejects edits with OCI hooks.
+	NoHooks bool
+	// ReadOnlyPaths are container paths under which mounts must be
+	// read-only. Mounts are read-only if their last "ro" or "rw"
+	// option is "ro".
+	ReadOnlyPaths []string
+}
+
+var _ AdmissionPolicy = &EditRestrictions{}
+
+// AdmitSpec implements AdmissionPolicy.
+func (r *EditRestrictions) AdmitSpec(spec *Spec) error {
+	if err := r.admitVendor(spec.GetVendor()); err != nil {
+		return err
+	}
+	if err := r.admitEdits(&spec.ContainerEdits); err != nil {
+		return err
+	}
+	for _, d := range spec.Devices {
+		if err := r.admitEdits(&d.ContainerEdits); err != nil {
+			return fmt.Errorf("device %q: %w", d.Name, err)
+		}
+	}
+	return nil
+}
+
+// AdmitInjection implements AdmissionPolicy.
+func (r *EditRestrictions) AdmitInjection(edits *ContainerEdits, devices []*Device) error {
+	for _, d := range devices {
+		if spec := d.GetSpec(); spec != nil {
+			if err := r.admitVendor(spec.GetVendor()); err != nil {
+				return fmt.Errorf("device %q: %w", d.GetQualifiedName(), err)
+			}
+		}
+	}
+	if edits == nil || edits.ContainerEdits == nil {
+		return nil
+	}
+	return r.admitEdits(edits.ContainerEdits)
+}
+
+// admitVendor checks if the given vendor is admitted.
+func (r *EditRestrictions) admitVendor(vendor string) error {
+	if len(r.Vendors) == 0 {
+		return nil
+	}
+	for _, v := range r.Vendors {
+		if v == vendor {
+			return nil
+		}
+	}
+	return fmt.Errorf("vendor %q not allowed", vendor)
+}
+
+// admitEdits checks if the given edits are admitted.
+func (r *EditRestrictions) admitEdits(e *cdi.ContainerEdits) error {
+	if r.NoHooks && len(e.Hooks) > 0 {
+		return fmt.Errorf("hooks not allowed (%s hook %q)", e.Hooks[0].HookName, e.Hooks[0].Path)
+	}
+	for _, m := range e.Mounts {
+		if m == nil || isReadOnlyMount(m.Options) {
+			continue
+		}
+		for _, path := range r.ReadOnlyPaths {
+			if isPathUnder(m.ContainerPath, path) {
+				return fmt.Errorf("read-write mount %q not allowed under %q", m.ContainerPath, path)
+			}
+		}
+	}
+	return nil
+}
+
+// isReadOnlyMount returns true if the given mount options make it read-only.
+func isReadOnlyMount(options []string) bool {
+	ro := false
+	for _, o := range options {
+		switch o {
+		case "ro":
+			ro = true
+		case "rw":
+			ro = false
+		}
+	}
+	return ro
+}
+
+// isPathUnder returns true if path is dir or a path below it.
+func isPathUnder(path, dir string) bool {
+	path, dir = filepath.Clean(path), filepath.Clean(dir)
+	if path == dir || dir == "/" {
+		return true
+	}
+	return strings.HasPrefix(path, dir+"/")
+}
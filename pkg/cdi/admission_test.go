@@ -0,0 +1,161 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	"tags.cncf.io/container-device-interface/pkg/parser"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestEditRestrictions(t *testing.T) {
+	policy := &EditRestrictions{
+		Vendors:       []string{"vendor.com"},
+		NoHooks:       true,
+		ReadOnlyPaths: []string{"/usr"},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		kind      string
+		edits     cdi.ContainerEdits
+		errSubstr string
+	}{
+		{
+			name: "admitted",
+			kind: "vendor.com/device",
+			edits: cdi.ContainerEdits{
+				Env: []string{"FOO=BAR"},
+				Mounts: []*cdi.Mount{
+					{HostPath: "/lib", ContainerPath: "/usr/lib/vendor", Options: []string{"rw", "ro"}},
+					{HostPath: "/data", ContainerPath: "/usrdata"},
+				},
+			},
+		},
+		{
+			name:      "vendor not allowed",
+			kind:      "other.com/device",
+			edits:     cdi.ContainerEdits{Env: []string{"FOO=BAR"}},
+			errSubstr: `vendor "other.com" not allowed`,
+		},
+		{
+			name: "hook",
+			kind: "vendor.com/device",
+			edits: cdi.ContainerEdits{
+				Hooks: []*cdi.Hook{{HookName: "createContainer", Path: "/bin/hook"}},
+			},
+			errSubstr: "hooks not allowed",
+		},
+		{
+			name: "read-write mount",
+			kind: "vendor.com/device",
+			edits: cdi.ContainerEdits{
+				Mounts: []*cdi.Mount{
+					{HostPath: "/lib", ContainerPath: "/usr/lib/vendor", Options: []string{"ro", "rw"}},
+				},
+			},
+			errSubstr: `read-write mount "/usr/lib/vendor" not allowed under "/usr"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := newCache(
+				WithSpecDirs(),
+				WithAutoRefresh(false),
+				WithAdmissionPolicy(policy),
+			)
+			err := cache.AddSpec(&cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    tc.kind,
+				Devices: []cdi.Device{
+					{
+						Name:           "dev0",
+						ContainerEdits: tc.edits,
+					},
+				},
+			}, 0)
+			if tc.errSubstr == "" {
+				require.NoError(t, err)
+				require.NotNil(t, cache.GetDevice(tc.kind+"=dev0"))
+				return
+			}
+			require.Error(t, err)
+			require.True(t, errors.Is(err, ErrAdmissionDenied))
+			require.Contains(t, err.Error(), tc.errSubstr)
+			require.Nil(t, cache.GetDevice(tc.kind+"=dev0"))
+			require.Len(t, cache.GetErrors()["memory:"+GenerateSpecName(parser.ParseQualifier(tc.kind))], 1)
+		})
+	}
+}
+
+type injectionPolicy struct {
+	denied string
+}
+
+func (p *injectionPolicy) AdmitSpec(*Spec) error {
+	return nil
+}
+
+func (p *injectionPolicy) AdmitInjection(_ *ContainerEdits, devices []*Device) error {
+	for _, d := range devices {
+		if d.GetQualifiedName() == p.denied {
+			return errors.New("device " + p.denied + " denied")
+		}
+	}
+	return nil
+}
+
+func TestInjectionAdmission(t *testing.T) {
+	cache := newCache(
+		WithSpecDirs(),
+		WithAutoRefresh(false),
+		WithAdmissionPolicy(&injectionPolicy{denied: "vendor.com/device=dev1"}),
+	)
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/device",
+		Devices: []cdi.Device{
+			{
+				Name:           "dev0",
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"DEV0=1"}},
+			},
+			{
+				Name:           "dev1",
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"DEV1=1"}},
+			},
+		},
+	}, 0))
+
+	ociSpec := &oci.Spec{}
+	_, err := cache.InjectDevices(ociSpec, "vendor.com/device=dev0")
+	require.NoError(t, err)
+	require.Equal(t, []string{"DEV0=1"}, ociSpec.Process.Env)
+
+	ociSpec = &oci.Spec{}
+	_, err = cache.InjectDevices(ociSpec, "vendor.com/device=dev0", "vendor.com/device=dev1")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrAdmissionDenied))
+	require.Contains(t, err.Error(), "device vendor.com/device=dev1 denied")
+	require.Equal(t, &oci.Spec{}, ociSpec)
+
+	_, _, _, err = cache.GetDeviceEdits("vendor.com/device=dev1")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrAdmissionDenied))
+}
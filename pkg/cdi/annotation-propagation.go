@@ -0,0 +1,132 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// ociSpecSource is the source of edits already present in the OCI Spec
+// in conflict errors.
+const ociSpecSource = "OCI Spec"
+
+// WithAnnotationPropagation returns an option to copy device annotations
+// with the given prefix into the annotations of the OCI Spec on injection,
+// with the prefix stripped from their keys. For instance, with the prefix
+// "oci.annotation." the device annotation
+//
+//	oci.annotation.vendor.com/gpu-uuid: GPU-1234
+//
+// sets the OCI Spec annotation "vendor.com/gpu-uuid" to "GPU-1234". This
+// lets agents discover the injected devices from the OCI Spec of a
+// container. Annotations conflicting with the OCI Spec or with those of
+// other injected devices are handled according to the given policy:
+// ConflictLastWins overwrites earlier values, ConflictFirstWins keeps the
+// value of the OCI Spec or the first device and ConflictFail fails the
+// injection with an *EditConflictError for each conflict. Unknown policies
+// are replaced by DefaultConflictPolicy. An empty prefix, the default,
+// disables propagation.
+func WithAnnotationPropagation(prefix string, policy ConflictPolicy) Option {
+	return func(c *Cache) {
+		c.annotationPrefix = prefix
+		switch policy {
+		case ConflictLastWins, ConflictFirstWins, ConflictFail:
+			c.annotationPolicy = policy
+		default:
+			c.annotationPolicy = DefaultConflictPolicy
+		}
+	}
+}
+
+// propagatedAnnotations returns the annotations of the given devices to
+// set in the OCI Spec.
+func (c *Cache) propagatedAnnotations(ociSpec *oci.Spec, devices []*Device) (map[string]string, error) {
+	if c.annotationPrefix == "" {
+		return nil, nil
+	}
+
+	var (
+		values    = map[string]*editValue{}
+		conflicts []error
+	)
+	for key, value := range ociSpec.Annotations {
+		values[key] = &editValue{source: ociSpecSource, value: value}
+	}
+
+	propagated := map[string]string{}
+	for _, d := range devices {
+		name := d.GetQualifiedName()
+		for _, annotation := range sortedStringKeys(d.Annotations) {
+			key := strings.TrimPrefix(annotation, c.annotationPrefix)
+			if key == annotation || key == "" {
+				continue
+			}
+			value := d.Annotations[annotation]
+			if old, ok := values[key]; ok && old.value != value {
+				switch c.annotationPolicy {
+				case ConflictFirstWins:
+					continue
+				case ConflictFail:
+					conflicts = append(conflicts, &EditConflictError{
+						Kind:        "annotation",
+						Key:         key,
+						First:       old.source,
+						FirstValue:  old.value,
+						Second:      name,
+						SecondValue: value,
+					})
+					continue
+				}
+			}
+			values[key] = &editValue{source: name, value: value}
+			propagated[key] = value
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, errors.Join(conflicts...)
+	}
+
+	return propagated, nil
+}
+
+// propagateAnnotations sets the given annotations in the OCI Spec.
+func propagateAnnotations(ociSpec *oci.Spec, annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+	if ociSpec.Annotations == nil {
+		ociSpec.Annotations = map[string]string{}
+	}
+	for key, value := range annotations {
+		ociSpec.Annotations[key] = value
+	}
+}
+
+// sortedStringKeys returns the sorted keys of the given map.
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,142 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestAnnotationPropagation(t *testing.T) {
+	const prefix = "oci.annotation."
+
+	raw := &cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/gpu",
+		Devices: []cdi.Device{
+			{
+				Name: "gpu0",
+				Annotations: map[string]string{
+					prefix + "vendor.com/gpu-uuid": "GPU-0",
+					prefix + "vendor.com/driver":   "1.0",
+					"vendor.com/internal":          "not propagated",
+				},
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"GPU0=1"}},
+			},
+			{
+				Name: "gpu1",
+				Annotations: map[string]string{
+					prefix + "vendor.com/gpu-uuid": "GPU-1",
+					prefix + "vendor.com/driver":   "1.0",
+				},
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"GPU1=1"}},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		prefix    string
+		policy    ConflictPolicy
+		existing  map[string]string
+		devices   []string
+		result    map[string]string
+		errSubstr string
+	}{
+		{
+			name:    "disabled",
+			devices: []string{"vendor.com/gpu=gpu0"},
+		},
+		{
+			name:    "single device",
+			prefix:  prefix,
+			devices: []string{"vendor.com/gpu=gpu0"},
+			result: map[string]string{
+				"vendor.com/gpu-uuid": "GPU-0",
+				"vendor.com/driver":   "1.0",
+			},
+		},
+		{
+			name:     "last wins",
+			prefix:   prefix,
+			policy:   ConflictLastWins,
+			existing: map[string]string{"vendor.com/gpu-uuid": "GPU-X"},
+			devices:  []string{"vendor.com/gpu=gpu0", "vendor.com/gpu=gpu1"},
+			result: map[string]string{
+				"vendor.com/gpu-uuid": "GPU-1",
+				"vendor.com/driver":   "1.0",
+			},
+		},
+		{
+			name:     "first wins",
+			prefix:   prefix,
+			policy:   ConflictFirstWins,
+			existing: map[string]string{"vendor.com/gpu-uuid": "GPU-X"},
+			devices:  []string{"vendor.com/gpu=gpu0", "vendor.com/gpu=gpu1"},
+			result: map[string]string{
+				"vendor.com/gpu-uuid": "GPU-X",
+				"vendor.com/driver":   "1.0",
+			},
+		},
+		{
+			name:      "fail",
+			prefix:    prefix,
+			policy:    ConflictFail,
+			devices:   []string{"vendor.com/gpu=gpu0", "vendor.com/gpu=gpu1"},
+			errSubstr: `conflicting annotation "vendor.com/gpu-uuid": GPU-0 by vendor.com/gpu=gpu0, GPU-1 by vendor.com/gpu=gpu1`,
+		},
+		{
+			name:      "fail with OCI Spec",
+			prefix:    prefix,
+			policy:    ConflictFail,
+			existing:  map[string]string{"vendor.com/driver": "2.0"},
+			devices:   []string{"vendor.com/gpu=gpu0"},
+			errSubstr: `conflicting annotation "vendor.com/driver": 2.0 by OCI Spec`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := newCache(
+				WithSpecDirs(),
+				WithAutoRefresh(false),
+				WithAnnotationPropagation(tc.prefix, tc.policy),
+			)
+			require.NoError(t, cache.AddSpec(raw, 0))
+
+			ociSpec := &oci.Spec{}
+			if tc.existing != nil {
+				ociSpec.Annotations = map[string]string{}
+				for k, v := range tc.existing {
+					ociSpec.Annotations[k] = v
+				}
+			}
+
+			_, err := cache.InjectDevices(ociSpec, tc.devices...)
+			if tc.errSubstr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errSubstr)
+				require.Nil(t, ociSpec.Process)
+				require.Equal(t, tc.existing, ociSpec.Annotations)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.result, ociSpec.Annotations)
+		})
+	}
+}
@@ -19,14 +19,38 @@ package cdi
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
+	"tags.cncf.io/container-device-interface/internal/validation"
 	"tags.cncf.io/container-device-interface/pkg/parser"
 )
 
 const (
 	// AnnotationPrefix is the prefix for CDI container annotation keys.
 	AnnotationPrefix = "cdi.k8s.io/"
+
+	// SharedContainerPathsAnnotation is a Spec or device annotation which
+	// exempts container paths from the check that no two devices in a Spec
+	// inject the same container path (as a mount or a device node). The
+	// value is a comma-separated list of container paths, or "*" for all
+	// paths. A path may be shared if it is listed in the Spec annotation,
+	// or in the device annotation of every device which injects it. This
+	// is intended for mutually exclusive devices. The check is done by the
+	// producer Validator and at the SpecValidationStrict level, but not by
+	// default, since older Specs may legitimately share container paths.
+	SharedContainerPathsAnnotation = validation.SharedContainerPathsAnnotation
+
+	// PriorityAnnotation is a Spec or device annotation which sets the
+	// priority of devices for conflict resolution. The value is an integer,
+	// which defaults to 0 if the annotation is not set. A device annotation
+	// overrides the Spec annotation. When multiple Specs define the same
+	// device, the definition from the Spec with the highest priority, as
+	// determined by its Spec directory, is used. Only if the priorities of
+	// the Specs are equal, the definition with the highest annotated
+	// priority is used (see Cache.GetConflictResolutions()). If that is also
+	// equal, the device is considered to be conflicting and is not usable.
+	PriorityAnnotation = AnnotationPrefix + "priority"
 )
 
 // UpdateAnnotations updates annotations with a plugin-specific CDI device
@@ -60,23 +84,71 @@ func UpdateAnnotations(annotations map[string]string, plugin string, deviceID st
 // qualified CDI device names. If any device fails this check empty slices
 // are returned along with a non-nil error. The annotations are expected
 // to be formatted by, or in a compatible fashion to UpdateAnnotations().
+//
+// The result is deterministic. Requests are collected in the sorted order
+// of their keys and the devices of each request in the order they are
+// listed in its annotation. Since InjectDevices() applies device edits in
+// the order the devices are given, this ordering is preserved end to end.
+// Callers which need a strict ordering of requests across keys, for
+// instance in the order plugins allocated devices, should use
+// ParseAnnotationsInOrder().
 func ParseAnnotations(annotations map[string]string) ([]string, []string, error) {
+	var keys []string
+
+	for key := range annotations {
+		if strings.HasPrefix(key, AnnotationPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return parseAnnotations(annotations, keys)
+}
+
+// ParseAnnotationsInOrder parses annotations for CDI device injection
+// requests like ParseAnnotations(), but collects requests in the order
+// of the given keys. Keys without an annotation are ignored. If there is
+// a CDI device injection request with a key not present in order, empty
+// slices are returned along with a non-nil error.
+func ParseAnnotationsInOrder(annotations map[string]string, order []string) ([]string, []string, error) {
 	var (
-		keys    []string
-		devices []string
+		keys   []string
+		listed = map[string]struct{}{}
 	)
 
-	for key, value := range annotations {
+	for _, key := range order {
+		if _, ok := listed[key]; ok {
+			return nil, nil, fmt.Errorf("duplicate CDI annotation key %q in order", key)
+		}
+		listed[key] = struct{}{}
+		if _, ok := annotations[key]; ok && strings.HasPrefix(key, AnnotationPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range annotations {
 		if !strings.HasPrefix(key, AnnotationPrefix) {
 			continue
 		}
-		for _, d := range strings.Split(value, ",") {
+		if _, ok := listed[key]; !ok {
+			return nil, nil, fmt.Errorf("CDI annotation key %q missing from order", key)
+		}
+	}
+
+	return parseAnnotations(annotations, keys)
+}
+
+// parseAnnotations parses the CDI device injection requests with the
+// given keys in the given order.
+func parseAnnotations(annotations map[string]string, keys []string) ([]string, []string, error) {
+	var devices []string
+
+	for _, key := range keys {
+		for _, d := range strings.Split(annotations[key], ",") {
 			if !parser.IsQualifiedName(d) {
 				return nil, nil, fmt.Errorf("invalid CDI device name %q", d)
 			}
 			devices = append(devices, d)
 		}
-		keys = append(keys, key)
 	}
 
 	return keys, devices, nil
@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"tags.cncf.io/container-device-interface/pkg/parser"
 )
 
 func TestAnnotationKey(t *testing.T) {
@@ -425,6 +426,20 @@ func TestParseAnnotation(t *testing.T) {
 				"vendor3.com/class2=device3",
 			},
 		},
+		{
+			name: "requests in key order, devices in listed order",
+			annotations: map[string]string{
+				AnnotationPrefix + "vendor2.class_device": "vendor2.com/class=device2,vendor2.com/class=device1",
+				AnnotationPrefix + "vendor1.class_device": "vendor1.com/class=device3,vendor1.com/class=device1",
+				"vendor.com/not-cdi":                      "vendor.com/class=device",
+			},
+			devices: []string{
+				"vendor1.com/class=device3",
+				"vendor1.com/class=device1",
+				"vendor2.com/class=device2",
+				"vendor2.com/class=device1",
+			},
+		},
 		{
 			name: "invalid, malformed device reference",
 			annotations: map[string]string{
@@ -447,8 +462,6 @@ func TestParseAnnotation(t *testing.T) {
 			_, devices, err := ParseAnnotations(tc.annotations)
 			if !tc.invalid {
 				require.NoError(t, err, "parsing annotations")
-				sort.Strings(tc.devices)
-				sort.Strings(devices)
 				require.Equal(t, tc.devices, devices, "parsing annotations")
 			} else {
 				require.Error(t, err)
@@ -456,3 +469,88 @@ func TestParseAnnotation(t *testing.T) {
 		})
 	}
 }
+
+func TestParseAnnotationsInOrder(t *testing.T) {
+	annotations := map[string]string{
+		AnnotationPrefix + "vendor1.class_device": "vendor1.com/class=device2,vendor1.com/class=device1",
+		AnnotationPrefix + "vendor2.class_device": "vendor2.com/class=device1",
+		"vendor.com/not-cdi":                      "vendor.com/class=device",
+	}
+
+	type testCase struct {
+		name    string
+		order   []string
+		keys    []string
+		devices []string
+		invalid bool
+	}
+	for _, tc := range []*testCase{
+		{
+			name: "given order",
+			order: []string{
+				AnnotationPrefix + "vendor2.class_device",
+				AnnotationPrefix + "vendor3.class_device",
+				AnnotationPrefix + "vendor1.class_device",
+			},
+			keys: []string{
+				AnnotationPrefix + "vendor2.class_device",
+				AnnotationPrefix + "vendor1.class_device",
+			},
+			devices: []string{
+				"vendor2.com/class=device1",
+				"vendor1.com/class=device2",
+				"vendor1.com/class=device1",
+			},
+		},
+		{
+			name: "key missing from order",
+			order: []string{
+				AnnotationPrefix + "vendor2.class_device",
+			},
+			invalid: true,
+		},
+		{
+			name: "duplicate key in order",
+			order: []string{
+				AnnotationPrefix + "vendor2.class_device",
+				AnnotationPrefix + "vendor1.class_device",
+				AnnotationPrefix + "vendor2.class_device",
+			},
+			invalid: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, devices, err := ParseAnnotationsInOrder(annotations, tc.order)
+			if tc.invalid {
+				require.Error(t, err)
+				require.Nil(t, keys)
+				require.Nil(t, devices)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.keys, keys)
+			require.Equal(t, tc.devices, devices)
+		})
+	}
+}
+
+func FuzzParseAnnotations(f *testing.F) {
+	f.Add(AnnotationPrefix+"vendor.class_dev0", "vendor.com/class=dev0")
+	f.Add(AnnotationPrefix+"vendor.class_dev0", "vendor.com/class=dev0,vendor.com/class=dev1")
+	f.Add(AnnotationPrefix+"x", "vendor.com/c=0,")
+	f.Add("other.annotation", "value")
+
+	f.Fuzz(func(t *testing.T, key, value string) {
+		keys, devices, err := ParseAnnotations(map[string]string{key: value})
+		if err != nil {
+			return
+		}
+		for _, d := range devices {
+			require.True(t, parser.IsQualifiedName(d), "device %q", d)
+		}
+		if len(keys) > 0 {
+			_, err = AnnotationValue(devices)
+			require.NoError(t, err)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// Applier applies the resolved container edits of injected devices to
+// an OCI Spec. The edits are the combined Spec-level and device-level
+// edits of all injected devices, collected in the configured EditOrder.
+// The devices are the injected devices in the order they were requested,
+// with device groups and patterns expanded.
+//
+// Appliers allow runtimes to change how edits are rendered into the OCI
+// Spec, for instance to translate them for a VM-based runtime (see
+// VMApplier). The default Applier uses ContainerEdits.Apply.
+type Applier interface {
+	Apply(ociSpec *oci.Spec, edits *ContainerEdits, devices []*Device) error
+}
+
+// ApplierFunc is a function implementing Applier.
+type ApplierFunc func(ociSpec *oci.Spec, edits *ContainerEdits, devices []*Device) error
+
+// Apply implements Applier.
+func (f ApplierFunc) Apply(ociSpec *oci.Spec, edits *ContainerEdits, devices []*Device) error {
+	return f(ociSpec, edits, devices)
+}
+
+// DefaultApplier applies edits using ContainerEdits.Apply.
+var DefaultApplier Applier = ApplierFunc(
+	func(ociSpec *oci.Spec, edits *ContainerEdits, _ []*Device) error {
+		return edits.Apply(ociSpec)
+	},
+)
+
+// WithApplier returns an option to set the Applier used to apply edits
+// during device injection. Setting a nil Applier restores DefaultApplier.
+func WithApplier(a Applier) Option {
+	return func(c *Cache) {
+		c.applier = a
+	}
+}
+
+// getApplier returns the Applier of the Cache.
+func (c *Cache) getApplier() Applier {
+	if c.applier == nil {
+		return DefaultApplier
+	}
+	return c.applier
+}
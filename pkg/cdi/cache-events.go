@@ -0,0 +1,287 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// CacheEventType is the type of a CacheEvent.
+type CacheEventType int
+
+const (
+	// SpecAdded is sent for a Spec which was added to the Cache.
+	SpecAdded CacheEventType = iota
+	// SpecRemoved is sent for a Spec which was removed from the Cache.
+	SpecRemoved
+	// SpecUpdated is sent for a Spec whose content changed.
+	SpecUpdated
+	// SpecInvalid is sent for a Spec, or Spec file, with new errors.
+	SpecInvalid
+	// DeviceAdded is sent for a device which became resolvable.
+	DeviceAdded
+	// DeviceRemoved is sent for a device which is no longer resolvable.
+	DeviceRemoved
+	// ConflictDetected is sent for a new conflict between devices or
+	// device groups of Specs with the same priority.
+	ConflictDetected
+)
+
+// String returns the name of the CacheEventType.
+func (t CacheEventType) String() string {
+	switch t {
+	case SpecAdded:
+		return "SpecAdded"
+	case SpecRemoved:
+		return "SpecRemoved"
+	case SpecUpdated:
+		return "SpecUpdated"
+	case SpecInvalid:
+		return "SpecInvalid"
+	case DeviceAdded:
+		return "DeviceAdded"
+	case DeviceRemoved:
+		return "DeviceRemoved"
+	case ConflictDetected:
+		return "ConflictDetected"
+	}
+	return fmt.Sprintf("CacheEventType(%d)", int(t))
+}
+
+// CacheEvent describes a change of the Cache.
+type CacheEvent struct {
+	// Type is the type of the event.
+	Type CacheEventType
+	// Spec is the path of the Spec, or Spec file, the event is about.
+	// It is empty for device events.
+	Spec string
+	// Device is the qualified name of the device, or device group for
+	// conflicts, the event is about. With case-insensitive names it is
+	// lowercase for conflicts. It is empty for Spec events.
+	Device string
+	// Err is the error for SpecInvalid and ConflictDetected events.
+	Err error
+}
+
+// Subscribe returns a channel receiving the changes of the Cache as
+// CacheEvents, and a function to cancel the subscription, which closes
+// the channel. Events are sent after every refresh which changes the
+// Cache, in the order: SpecRemoved, SpecAdded, SpecUpdated, SpecInvalid,
+// DeviceRemoved, DeviceAdded and ConflictDetected. Events are queued, so
+// a slow receiver does not block the Cache, but receivers should drain
+// the channel until they cancel the subscription. Events are relative to
+// the state of the Cache when subscribing, so subscribers should get the
+// current state, for instance using ListDevices(), after subscribing.
+// Closing the Cache cancels all subscriptions.
+func (c *Cache) Subscribe() (<-chan CacheEvent, func()) {
+	c.Lock()
+	defer c.Unlock()
+
+	s := newCacheSubscriber()
+	if c.subscribers == nil {
+		c.subscribers = map[*cacheSubscriber]struct{}{}
+	}
+	c.subscribers[s] = struct{}{}
+
+	cancel := func() {
+		c.Lock()
+		delete(c.subscribers, s)
+		c.Unlock()
+		s.stop()
+	}
+
+	return s.out, cancel
+}
+
+// cacheState is the state of the Cache events are generated against.
+type cacheState struct {
+	// specs are the digests of the Specs by path and document.
+	specs map[string]string
+	// paths are the paths of the Specs by path and document.
+	paths map[string]string
+	// invalid are the errors by Spec path.
+	invalid map[string]string
+	// devices are the qualified names of resolvable devices.
+	devices map[string]struct{}
+	// conflicts are the errors of conflicts by device or group key.
+	conflicts map[string]string
+}
+
+// newCacheState returns the current state of the Cache, with the given
+// conflicts detected by refreshing it.
+func (c *Cache) newCacheState(conflicts map[string]error) *cacheState {
+	s := &cacheState{
+		specs:     map[string]string{},
+		paths:     map[string]string{},
+		invalid:   map[string]string{},
+		devices:   map[string]struct{}{},
+		conflicts: map[string]string{},
+	}
+	for _, specs := range c.specs {
+		for _, spec := range specs {
+			key := spec.GetPath()
+			if doc := spec.GetDocument(); doc > 0 {
+				key = fmt.Sprintf("%s#%d", key, doc)
+			}
+			s.specs[key] = spec.GetDigest()
+			s.paths[key] = spec.GetPath()
+		}
+	}
+	for path, errs := range c.errors {
+		if len(errs) > 0 {
+			s.invalid[path] = errors.Join(errs...).Error()
+		}
+	}
+	for _, d := range c.devices {
+		s.devices[d.GetQualifiedName()] = struct{}{}
+	}
+	for key, err := range conflicts {
+		s.conflicts[key] = err.Error()
+	}
+	return s
+}
+
+// publishEvents updates the event state of the Cache after a refresh
+// with the given conflicts and sends the resulting events to subscribers.
+func (c *Cache) publishEvents(conflicts map[string]error) {
+	state := c.newCacheState(conflicts)
+	old := c.eventState
+	c.eventState = state
+
+	if old == nil || len(c.subscribers) == 0 {
+		return
+	}
+
+	var events []CacheEvent
+	for _, key := range sortedStringKeys(old.specs) {
+		if _, ok := state.specs[key]; !ok {
+			events = append(events, CacheEvent{Type: SpecRemoved, Spec: old.paths[key]})
+		}
+	}
+	for _, key := range sortedStringKeys(state.specs) {
+		if _, ok := old.specs[key]; !ok {
+			events = append(events, CacheEvent{Type: SpecAdded, Spec: state.paths[key]})
+		}
+	}
+	for _, key := range sortedStringKeys(state.specs) {
+		if digest, ok := old.specs[key]; ok && digest != state.specs[key] {
+			events = append(events, CacheEvent{Type: SpecUpdated, Spec: state.paths[key]})
+		}
+	}
+	for _, path := range sortedStringKeys(state.invalid) {
+		if old.invalid[path] != state.invalid[path] {
+			events = append(events, CacheEvent{
+				Type: SpecInvalid,
+				Spec: path,
+				Err:  errors.Join(c.errors[path]...),
+			})
+		}
+	}
+	for _, name := range sortedKeys(old.devices) {
+		if _, ok := state.devices[name]; !ok {
+			events = append(events, CacheEvent{Type: DeviceRemoved, Device: name})
+		}
+	}
+	for _, name := range sortedKeys(state.devices) {
+		if _, ok := old.devices[name]; !ok {
+			events = append(events, CacheEvent{Type: DeviceAdded, Device: name})
+		}
+	}
+	for _, key := range sortedStringKeys(state.conflicts) {
+		if old.conflicts[key] != state.conflicts[key] {
+			events = append(events, CacheEvent{
+				Type:   ConflictDetected,
+				Device: key,
+				Err:    conflicts[key],
+			})
+		}
+	}
+
+	if len(events) == 0 {
+		return
+	}
+	for s := range c.subscribers {
+		s.push(events)
+	}
+}
+
+// cacheSubscriber queues events for a subscriber of the Cache.
+type cacheSubscriber struct {
+	sync.Mutex
+	queue []CacheEvent
+	out   chan CacheEvent
+	wake  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// newCacheSubscriber creates a subscriber and starts delivering events.
+func newCacheSubscriber() *cacheSubscriber {
+	s := &cacheSubscriber{
+		out:  make(chan CacheEvent),
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// push queues the given events for delivery.
+func (s *cacheSubscriber) push(events []CacheEvent) {
+	s.Lock()
+	s.queue = append(s.queue, events...)
+	s.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// stop stops delivering events and closes the channel of the subscriber.
+func (s *cacheSubscriber) stop() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// run delivers queued events until the subscriber is stopped.
+func (s *cacheSubscriber) run() {
+	defer close(s.out)
+
+	for {
+		s.Lock()
+		if len(s.queue) == 0 {
+			s.Unlock()
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		event := s.queue[0]
+		s.queue = s.queue[1:]
+		s.Unlock()
+
+		select {
+		case s.out <- event:
+		case <-s.done:
+			return
+		}
+	}
+}
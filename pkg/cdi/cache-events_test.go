@@ -0,0 +1,125 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheSubscribe(t *testing.T) {
+	spec := func(devices ...string) string {
+		data := `
+cdiVersion: "0.3.0"
+kind: "vendor.com/device"
+devices:
+`
+		for _, d := range devices {
+			data += `  - name: "` + d + `"
+    containerEdits:
+      env:
+        - "DEVICE=` + d + `"
+`
+		}
+		return data
+	}
+
+	dir, err := createSpecDirs(t, map[string]string{"vendor.yaml": spec("dev0", "dev1")}, nil)
+	require.NoError(t, err)
+	etc := filepath.Join(dir, "etc", "vendor.yaml")
+	run := filepath.Join(dir, "run", "vendor.yaml")
+	other := filepath.Join(dir, "run", "other.yaml")
+
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc"), filepath.Join(dir, "run")),
+		WithAutoRefresh(false),
+	)
+	require.NoError(t, cache.Refresh())
+
+	events, cancel := cache.Subscribe()
+
+	receive := func(n int) []CacheEvent {
+		var received []CacheEvent
+		for len(received) < n {
+			select {
+			case e := <-events:
+				e.Err = nil
+				received = append(received, e)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timeout receiving events, got %v", received)
+			}
+		}
+		select {
+		case e := <-events:
+			t.Fatalf("unexpected event %v", e)
+		case <-time.After(20 * time.Millisecond):
+		}
+		return received
+	}
+
+	require.NoError(t, updateSpecDirs(dir, nil, map[string]string{"vendor.yaml": spec("dev1", "dev2")}))
+	require.NoError(t, cache.Refresh())
+	require.Equal(t, []CacheEvent{
+		{Type: SpecAdded, Spec: run},
+		{Type: DeviceAdded, Device: "vendor.com/device=dev2"},
+	}, receive(2))
+
+	require.NoError(t, updateSpecDirs(dir, map[string]string{"vendor.yaml": "remove"}, nil))
+	require.NoError(t, cache.Refresh())
+	require.Equal(t, []CacheEvent{
+		{Type: SpecRemoved, Spec: etc},
+		{Type: DeviceRemoved, Device: "vendor.com/device=dev0"},
+	}, receive(2))
+
+	require.NoError(t, updateSpecDirs(dir, nil, map[string]string{
+		"vendor.yaml": spec("dev1", "dev3"),
+		"other.yaml":  spec("dev3"),
+	}))
+	require.Error(t, cache.Refresh())
+	require.Equal(t, []CacheEvent{
+		{Type: SpecAdded, Spec: other},
+		{Type: SpecUpdated, Spec: run},
+		{Type: SpecInvalid, Spec: other},
+		{Type: SpecInvalid, Spec: run},
+		{Type: DeviceRemoved, Device: "vendor.com/device=dev2"},
+		{Type: ConflictDetected, Device: "vendor.com/device=dev3"},
+	}, receive(6))
+
+	// unchanged refresh, no events
+	require.Error(t, cache.Refresh())
+	receive(0)
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		require.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for cancelled subscription")
+	}
+
+	events, _ = cache.Subscribe()
+	require.NoError(t, cache.Close())
+	select {
+	case _, ok := <-events:
+		require.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for subscription cancelled by Close")
+	}
+}
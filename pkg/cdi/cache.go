@@ -17,6 +17,9 @@
 package cdi
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -25,26 +28,97 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"tags.cncf.io/container-device-interface/pkg/parser"
 	cdi "tags.cncf.io/container-device-interface/specs-go"
 )
 
+const (
+	// memorySpecPrefix is the path prefix used for in-memory Specs.
+	memorySpecPrefix = "memory:"
+)
+
 // Option is an option to change some aspect of default CDI behavior.
 type Option func(*Cache)
 
 // Cache stores CDI Specs loaded from Spec directories.
+//
+// The Cache is safe for concurrent use. Lookups and device injection only
+// read the Cache and run concurrently with each other. Refreshes, whether
+// triggered by the Spec directory watch, by Refresh() or by a lookup which
+// finds the Cache out of date, build new Spec and device maps and swap
+// them in while excluding all other users of the Cache.
 type Cache struct {
-	sync.Mutex
+	sync.RWMutex
 	specDirs  []string
+	specFS    []*specFS
 	specs     map[string][]*Spec
 	devices   map[string]*Device
+	groups    map[string]*deviceGroup
+	renames   map[string]*deviceRename
 	errors    map[string][]error
 	dirErrors map[string]error
-
-	autoRefresh bool
-	watch       *watch
+	memSpecs  map[string]*Spec
+	aliases   []*SpecAlias
+	specFiles map[string]*specFile
+	// resolutions records device conflicts resolved by device priority
+	resolutions []*ConflictResolution
+	// warnings records Specs accepted despite a newer than maximum version
+	warnings map[string][]error
+	// unavailable records devices with unmatched platform constraints
+	unavailable map[string]error
+	// sortedDevices are all devices, sorted by qualified name
+	sortedDevices []*Device
+
+	autoRefresh          bool
+	caseInsensitive      bool
+	noInterning          bool
+	noDevicePatterns     bool
+	noDeduplication      bool
+	noDeviceCgroupRules  bool
+	specSizeLimit        int64
+	injectionAnnotations bool
+	refreshWorkers       int
+	editOrder            EditOrder
+	maxVersion           string
+	hostValidation       bool
+	specValidation       SpecValidationLevel
+	specValidator        func(*cdi.Spec) error
+	deviceInfo           DeviceInfoResolver
+	hostDeviceInfo       *deviceInfoCache
+	nodeOwnership        DeviceNodeOwnership
+	idMapping            *idMapping
+	refreshPending       bool
+	history              *errorHistory
+	claims               map[string][]*DeviceClaim
+	envPolicy            EnvMergePolicy
+	conflictPolicy       ConflictPolicy
+	applier              Applier
+	signatureKeys        []ed25519.PublicKey
+	watch                *watch
+	watchRetryInterval   time.Duration
+	onWatchDegraded      func(error)
+	onDeprecated         func(*DeprecationWarning)
+	admission            AdmissionPolicy
+	annotationPrefix     string
+	annotationPolicy     ConflictPolicy
+	subscribers          map[*cacheSubscriber]struct{}
+	eventState           *cacheState
+	readRetryWindow      time.Duration
+	readRetries          map[string]*readRetry
+	specVars             map[string]string
+	specVarsFile         string
+	specVarsFromEnv      bool
+	hostRoot             string
+	platform             *Platform
+	openUpdates          int
+	deferred             *deferredRefresh
+	updateBatchTimeout   time.Duration
+	readinessChecks      bool
+	anchorPolicy         AnchorPolicy
 }
 
 // WithAutoRefresh returns an option to control automatic Cache refresh.
@@ -58,6 +132,18 @@ func WithAutoRefresh(autoRefresh bool) Option {
 	}
 }
 
+// WithCaseInsensitiveNames returns an option to control whether device
+// names are resolved case-insensitively. By default vendor, class and
+// device names are case-sensitive. If case-insensitive resolution is
+// enabled, devices are looked up using the canonical form of their names
+// (see parser.CanonicalName()) and devices whose qualified names differ
+// only by case are treated as conflicting devices.
+func WithCaseInsensitiveNames(enable bool) Option {
+	return func(c *Cache) {
+		c.caseInsensitive = enable
+	}
+}
+
 // NewCache creates a new CDI Cache. The cache is populated from a set
 // of CDI Spec directories. These can be specified using a WithSpecDirs
 // option. The default set of directories is exposed in DefaultSpecDirs.
@@ -75,8 +161,9 @@ func NewCache(options ...Option) (*Cache, error) {
 // NewCache function.
 func newCache(options ...Option) *Cache {
 	c := &Cache{
-		autoRefresh: true,
-		watch:       &watch{},
+		autoRefresh:    true,
+		watch:          &watch{},
+		hostDeviceInfo: newDeviceInfoCache(HostDeviceInfoResolver),
 	}
 
 	WithSpecDirs(DefaultSpecDirs...)(c)
@@ -110,24 +197,55 @@ func (c *Cache) configure(options ...Option) {
 	}
 
 	c.dirErrors = make(map[string]error)
+	c.specFiles = nil // options might affect how Spec files are read
 
 	c.watch.stop()
 	if c.autoRefresh {
+		c.watch.onDegraded = c.watchDegraded
 		c.watch.setup(c.specDirs, c.dirErrors)
-		c.watch.start(&c.Mutex, c.refresh, c.dirErrors)
+		c.watch.start(&c.RWMutex, c.refreshWatched, c.dirErrors)
 	}
 	_ = c.refresh() // we record but ignore errors
 }
 
+// Close stops monitoring the Spec directories of the Cache, cancels all
+// event subscriptions and releases the associated resources. Afterwards
+// the Cache no longer refreshes itself automatically, but it can still be
+// used and refreshed manually.
+func (c *Cache) Close() error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.watch.stop()
+	c.autoRefresh = false
+	c.stopReadRetries()
+	for s := range c.subscribers {
+		s.stop()
+	}
+	c.subscribers = nil
+	return nil
+}
+
 // Refresh rescans the CDI Spec directories and refreshes the Cache.
 // In manual refresh mode the cache is always refreshed. In auto-
 // refresh mode the cache is only refreshed if it is out of date.
 func (c *Cache) Refresh() error {
-	c.Lock()
+	return c.RefreshContext(context.Background())
+}
+
+// RefreshContext is like Refresh but honors cancellation and deadlines
+// of the given context, both while waiting for the Cache and while
+// reading Spec files. If the context is done before the refresh is
+// complete, the Cache is left unchanged and the error of the context
+// is returned.
+func (c *Cache) RefreshContext(ctx context.Context) error {
+	if err := c.lockContext(ctx); err != nil {
+		return err
+	}
 	defer c.Unlock()
 
 	// force a refresh in manual mode
-	if refreshed, err := c.refreshIfRequired(!c.autoRefresh); refreshed {
+	if refreshed, err := c.refreshIfRequiredContext(ctx, !c.autoRefresh); refreshed {
 		return err
 	}
 
@@ -141,66 +259,288 @@ func (c *Cache) Refresh() error {
 
 // Refresh the Cache by rescanning CDI Spec directories and files.
 func (c *Cache) refresh() error {
+	return c.refreshContext(context.Background())
+}
+
+// refreshContext refreshes the Cache unless the given context is done
+// before all Spec files are read.
+func (c *Cache) refreshContext(ctx context.Context) error {
+	files, err := c.readSpecFiles(ctx)
+	if err != nil {
+		c.refreshPending = true
+		return err
+	}
+	c.refreshPending = false
+	return c.refreshSpecFiles(files)
+}
+
+// lockContext locks the Cache, unless the given context is done first.
+func (c *Cache) lockContext(ctx context.Context) error {
+	return lockWithContext(ctx, c.Lock, c.TryLock, c.Unlock)
+}
+
+// rlockContext read-locks the Cache, unless the given context is done
+// first.
+func (c *Cache) rlockContext(ctx context.Context) error {
+	return lockWithContext(ctx, c.RLock, c.TryRLock, c.RUnlock)
+}
+
+// lockWithContext acquires a lock using the given functions, unless the
+// given context is done first.
+func lockWithContext(ctx context.Context, lock func(), tryLock func() bool, unlock func()) error {
+	if ctx.Done() == nil {
+		lock()
+		return nil
+	}
+	if tryLock() {
+		return nil
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		lock()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		return nil
+	case <-ctx.Done():
+		// release the lock once we get it
+		go func() {
+			<-locked
+			unlock()
+		}()
+		return ctx.Err()
+	}
+}
+
+// lockRefreshed locks the Cache for reading, refreshing it first if it
+// is out of date. It returns the function to unlock the Cache. If the
+// Cache needs a refresh, it is refreshed and stays locked for writing,
+// otherwise it is only locked for reading, letting other readers proceed
+// concurrently.
+func (c *Cache) lockRefreshed() func() {
+	unlock, _ := c.lockRefreshedContext(context.Background())
+	return unlock
+}
+
+// lockRefreshedContext is like lockRefreshed but gives up waiting for the
+// Cache once the given context is done.
+func (c *Cache) lockRefreshedContext(ctx context.Context) (func(), error) {
+	if err := c.rlockContext(ctx); err != nil {
+		return nil, err
+	}
+	if !c.refreshRequired() {
+		return c.RUnlock, nil
+	}
+	c.RUnlock()
+
+	if err := c.lockContext(ctx); err != nil {
+		return nil, err
+	}
+	_, _ = c.refreshIfRequiredContext(ctx, false) // we record but ignore errors
+	return c.Unlock, nil
+}
+
+// refreshSpecFiles refreshes the Cache from the given Spec files, read
+// in scan order, and the in-memory Specs.
+func (c *Cache) refreshSpecFiles(files []*specFile) error {
 	var (
-		specs      = map[string][]*Spec{}
-		devices    = map[string]*Device{}
-		conflicts  = map[string]struct{}{}
-		specErrors = map[string][]error{}
+		specs       = map[string][]*Spec{}
+		devices     = map[string]*Device{}
+		groups      = map[string]*deviceGroup{}
+		renames     = map[string]*deviceRename{}
+		conflicts   = map[string]error{}
+		specErrors  = map[string][]error{}
+		resolutions []*ConflictResolution
+		warnings    = map[string][]error{}
 	)
 
+	// device nodes might have been recreated, forget what we looked up
+	c.hostDeviceInfo.reset()
+
 	// collect errors per spec file path and once globally
 	collectError := func(err error, paths ...string) {
 		for _, path := range paths {
 			specErrors[path] = append(specErrors[path], err)
 		}
 	}
-	// resolve conflicts based on device Spec priority (order of precedence)
-	resolveConflict := func(name string, dev *Device, old *Device) bool {
+	// resolve conflicts based on device Spec priority (order of precedence),
+	// then on device priority (see PriorityAnnotation)
+	resolveConflict := func(key string, dev *Device, old *Device) bool {
 		devSpec, oldSpec := dev.GetSpec(), old.GetSpec()
-		devPrio, oldPrio := devSpec.GetPriority(), oldSpec.GetPriority()
-		switch {
-		case devPrio > oldPrio:
-			return false
-		case devPrio == oldPrio:
-			devPath, oldPath := devSpec.GetPath(), oldSpec.GetPath()
-			collectError(fmt.Errorf("conflicting device %q (specs %q, %q)",
-				name, devPath, oldPath), devPath, oldPath)
-			conflicts[name] = struct{}{}
+		if devPrio, oldPrio := devSpec.GetPriority(), oldSpec.GetPriority(); devPrio != oldPrio {
+			return devPrio < oldPrio
+		}
+		if devPrio, oldPrio := dev.GetPriority(), old.GetPriority(); devPrio != oldPrio {
+			r := &ConflictResolution{
+				Device:             dev.GetQualifiedName(),
+				Selected:           devSpec.GetPath(),
+				Overridden:         oldSpec.GetPath(),
+				SelectedPriority:   devPrio,
+				OverriddenPriority: oldPrio,
+			}
+			if devPrio < oldPrio {
+				r.Selected, r.Overridden = r.Overridden, r.Selected
+				r.SelectedPriority, r.OverriddenPriority = r.OverriddenPriority, r.SelectedPriority
+			}
+			resolutions = append(resolutions, r)
+			return devPrio < oldPrio
 		}
+		devPath, oldPath := devSpec.GetPath(), oldSpec.GetPath()
+		err := fmt.Errorf("conflicting device %q (specs %q, %q)",
+			dev.GetQualifiedName(), devPath, oldPath)
+		collectError(err, devPath, oldPath)
+		conflicts[key] = err
 		return true
 	}
 
-	_ = scanSpecDirs(c.specDirs, func(path string, priority int, spec *Spec, err error) error {
-		path = filepath.Clean(path)
+	addSpec := func(spec *Spec) {
+		ok, err := c.checkSpecVersion(spec)
+		if !ok {
+			collectError(err, spec.GetPath())
+			return
+		}
 		if err != nil {
-			collectError(fmt.Errorf("failed to load CDI Spec %w", err), path)
-			return nil
+			warnings[spec.GetPath()] = append(warnings[spec.GetPath()], err)
+		}
+		if err := c.admitSpec(spec); err != nil {
+			collectError(err, spec.GetPath())
+			return
 		}
 
 		vendor := spec.GetVendor()
 		specs[vendor] = append(specs[vendor], spec)
 
 		for _, dev := range spec.devices {
-			qualified := dev.GetQualifiedName()
-			other, ok := devices[qualified]
+			key := c.deviceKey(dev.GetQualifiedName())
+			other, ok := devices[key]
 			if ok {
-				if resolveConflict(qualified, dev, other) {
+				if resolveConflict(key, dev, other) {
 					continue
 				}
 			}
-			devices[qualified] = dev
+			devices[key] = dev
 		}
 
-		return nil
-	})
+		for name, members := range spec.groups {
+			group := &deviceGroup{
+				name: parser.QualifiedName(spec.GetVendor(), spec.GetClass(), name),
+				spec: spec,
+			}
+			for _, m := range members {
+				group.members = append(group.members,
+					parser.QualifiedName(spec.GetVendor(), spec.GetClass(), m))
+			}
+			key := c.deviceKey(group.name)
+			if other, ok := groups[key]; ok {
+				devPrio, oldPrio := spec.GetPriority(), other.spec.GetPriority()
+				if devPrio < oldPrio {
+					continue
+				}
+				if devPrio == oldPrio {
+					devPath, oldPath := spec.GetPath(), other.spec.GetPath()
+					err := fmt.Errorf("conflicting device group %q (specs %q, %q)",
+						group.name, devPath, oldPath)
+					collectError(err, devPath, oldPath)
+					conflicts[key] = err
+				}
+			}
+			groups[key] = group
+		}
+
+		for _, r := range spec.renames() {
+			key := c.deviceKey(r.name)
+			if other, ok := renames[key]; ok {
+				devPrio, oldPrio := spec.GetPriority(), other.spec.GetPriority()
+				if devPrio < oldPrio {
+					continue
+				}
+				if devPrio == oldPrio {
+					devPath, oldPath := spec.GetPath(), other.spec.GetPath()
+					err := fmt.Errorf("conflicting device rename %q (specs %q, %q)",
+						r.name, devPath, oldPath)
+					collectError(err, devPath, oldPath)
+					conflicts[key] = err
+				}
+			}
+			renames[key] = r
+		}
+	}
+
+	var scanned []*Spec
+	for _, f := range files {
+		if f.err != nil {
+			collectError(fmt.Errorf("failed to load CDI Spec %w", f.err), f.path)
+		}
+		scanned = append(scanned, f.specs...)
+	}
+
+	scanned, aliases := dedupSpecs(scanned)
+	for _, spec := range scanned {
+		addSpec(spec)
+	}
+
+	names := make([]string, 0, len(c.memSpecs))
+	for name := range c.memSpecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		addSpec(c.memSpecs[name])
+	}
 
 	for conflict := range conflicts {
 		delete(devices, conflict)
+		delete(groups, conflict)
+		delete(renames, conflict)
+	}
+	for key, group := range groups {
+		if dev, ok := devices[key]; ok {
+			devPath, groupPath := dev.GetSpec().GetPath(), group.spec.GetPath()
+			collectError(fmt.Errorf("device group %q conflicts with device (specs %q, %q)",
+				group.name, groupPath, devPath), groupPath, devPath)
+			delete(groups, key)
+		}
+	}
+	unavailable := map[string]error{}
+	for key, dev := range devices {
+		if err := checkPlatform(dev, c.getPlatform()); err != nil {
+			unavailable[key] = err
+		}
+	}
+	// devices and groups using the previous name of a device take
+	// precedence, and renamed devices lost in conflicts can't be used
+	for key, r := range renames {
+		_, isDevice := devices[key]
+		_, isGroup := groups[key]
+		_, hasDevice := devices[c.deviceKey(r.device)]
+		if isDevice || isGroup || !hasDevice {
+			delete(renames, key)
+		}
 	}
 
 	c.specs = specs
 	c.devices = devices
+	c.sortedDevices = sortDevices(devices)
+	c.groups = groups
+	c.renames = renames
+	c.unavailable = unavailable
 	c.errors = specErrors
+	c.resolutions = resolutions
+	c.warnings = warnings
+	c.aliases = aliases
+
+	paths := make([]string, 0, len(files)+len(names))
+	for _, f := range files {
+		paths = append(paths, f.path)
+	}
+	for _, name := range names {
+		paths = append(paths, c.memSpecs[name].GetPath())
+	}
+	c.getHistory().record(paths, specErrors)
+	c.publishEvents(conflicts)
 
 	errs := []error{}
 	for _, specErrs := range specErrors {
@@ -209,59 +549,285 @@ func (c *Cache) refresh() error {
 	return errors.Join(errs...)
 }
 
+// readSpecs reads the Specs of the given Spec file, verifying its signature
+// if necessary. It returns whether any error is possibly transient, so
+// reading the file should be retried (see WithSpecReadRetry).
+func (c *Cache) readSpecs(f *specFile) ([]*Spec, bool, error) {
+	path, priority := f.path, f.priority
+
+	data, err := readSpecFile(f.fsys, f.fileName(), c.getSpecSizeLimit())
+	switch {
+	case os.IsNotExist(err):
+		return nil, false, err
+	case errors.Is(err, ErrSpecLocked):
+		return nil, true, fmt.Errorf("failed to read CDI Spec %q: %w", path, err)
+	case err != nil:
+		return nil, false, fmt.Errorf("failed to read CDI Spec %q: %w", path, err)
+	}
+
+	if len(c.signatureKeys) > 0 {
+		if err := verifySpecFile(f.fsys, f.fileName(), data, c.signatureKeys); err != nil {
+			return nil, true, fmt.Errorf("failed to verify CDI Spec %q: %w", path, err)
+		}
+	}
+
+	parsing := specParsing{
+		anchors: c.anchorPolicy,
+		strict:  c.specValidation == SpecValidationStrict,
+	}
+	specs, err := readSpecsData(data, path, priority, c.specPreprocessor(data), parsing)
+	if f.info != nil {
+		for _, spec := range specs {
+			spec.modTime = f.info.ModTime()
+		}
+	}
+	return specs, true, err
+}
+
+// refreshWatched refreshes the Cache for changes detected by the watch.
+// Changes to individual Spec files only cause those files to be read.
+// Other changes trigger a full refresh.
+func (c *Cache) refreshWatched(paths ...string) error {
+	if c.deferRefresh(len(paths) == 0, paths...) {
+		return nil
+	}
+	if len(paths) == 0 {
+		return c.refresh()
+	}
+	return c.refreshPaths(context.Background(), paths...)
+}
+
 // RefreshIfRequired triggers a refresh if necessary.
 func (c *Cache) refreshIfRequired(force bool) (bool, error) {
+	return c.refreshIfRequiredContext(context.Background(), force)
+}
+
+// refreshIfRequiredContext triggers a refresh if necessary, honoring
+// the given context.
+func (c *Cache) refreshIfRequiredContext(ctx context.Context, force bool) (bool, error) {
 	// We need to refresh if
 	// - it's forced by an explicit call to Refresh() in manual mode
 	// - a missing Spec dir appears (added to watch) in auto-refresh mode
-	if force || (c.autoRefresh && c.watch.update(c.dirErrors)) {
-		return true, c.refresh()
+	// - a previous refresh was cancelled
+	if force || c.refreshPending || (c.autoRefresh && c.watch.update(c.dirErrors)) {
+		return true, c.refreshContext(ctx)
 	}
 	return false, nil
 }
 
+// refreshRequired returns true if the Cache needs a refresh before use.
+// Unlike refreshIfRequired it does not change the Cache, so it can be used
+// with the Cache only locked for reading.
+func (c *Cache) refreshRequired() bool {
+	return c.refreshPending || (c.autoRefresh && c.watch.pending())
+}
+
 // InjectDevices injects the given qualified devices to an OCI Spec. It
 // returns any unresolvable devices and an error if injection fails for
-// any of the devices. Might trigger a cache refresh, in which case any
-// errors encountered can be obtained using GetErrors().
+// any of the devices. Device groups are expanded to their member devices,
+// and device name patterns to the devices they match (see the option
+// WithDevicePatterns). Spec-level and device-level edits are collected in
+// the order set by the WithEditOrder option (see EditOrder) and applied
+// using the Applier set by the WithApplier option. Might trigger a cache
+// refresh, in which case any errors encountered can be obtained using
+// GetErrors().
 func (c *Cache) InjectDevices(ociSpec *oci.Spec, devices ...string) ([]string, error) {
-	var unresolved []string
+	return c.InjectDevicesContext(context.Background(), ociSpec, devices...)
+}
 
+// InjectDevicesContext is like InjectDevices but honors cancellation and
+// deadlines of the given context, both while waiting for the Cache and
+// during any triggered refresh. If the context is done before the edits
+// are applied, the OCI Spec is left unchanged and the error of the context
+// is returned.
+func (c *Cache) InjectDevicesContext(ctx context.Context, ociSpec *oci.Spec, devices ...string) ([]string, error) {
 	if ociSpec == nil {
 		return devices, fmt.Errorf("can't inject devices, nil OCI Spec")
 	}
 
-	c.Lock()
-	defer c.Unlock()
+	// warn about deprecated devices once the Cache is unlocked
+	var warn func()
+	defer func() {
+		if warn != nil {
+			warn()
+		}
+	}()
+
+	unlock, err := c.lockRefreshedContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inject devices: %w", err)
+	}
+	defer unlock()
 
-	_, _ = c.refreshIfRequired(false) // we record but ignore errors
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to inject devices: %w", err)
+	}
+
+	resolved, injected, unresolved, err := c.resolveEdits(devices, c.getHostRoot(ctx))
+	if unresolved != nil {
+		return unresolved, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to inject devices: %w", err)
+	}
+
+	annotations, err := c.propagatedAnnotations(ociSpec, injected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inject devices: %w", err)
+	}
 
-	edits := &ContainerEdits{}
-	specs := map[*Spec]struct{}{}
+	if err := c.getApplier().Apply(ociSpec, resolved, injected); err != nil {
+		return nil, fmt.Errorf("failed to inject devices: %w", err)
+	}
+
+	propagateAnnotations(ociSpec, annotations)
+
+	if c.injectionAnnotations {
+		if err := annotateInjection(ociSpec, injected); err != nil {
+			return nil, fmt.Errorf("failed to inject devices: %w", err)
+		}
+	}
 
+	warn = c.deprecationWarnings(injected, c.renamedRequests(devices))
+
+	return nil, nil
+}
+
+// resolveEdits resolves the given devices and returns their combined,
+// resolved edits ready to be applied and the injected devices. If any
+// of the devices can't be resolved resolveEdits returns the unresolved
+// devices and an error. Host paths are re-rooted under the given root
+// and the edits are checked against the admission policy of the Cache.
+func (c *Cache) resolveEdits(devices []string, root string) (*ContainerEdits, []*Device, []string, error) {
+	edits, unresolved, err := c.collectEdits(devices, root)
+	if unresolved != nil {
+		return nil, nil, unresolved, err
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := edits.resolveConflicts(c.conflictPolicy); err != nil {
+		return nil, nil, nil, err
+	}
+
+	resolved, err := edits.edits().withHostRoot(root).resolve(c.getDeviceInfoResolver(), c.nodeOwnership, c.idMapping)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	SortEdits(resolved)
+	resolved = resolved.withEnvMergePolicy(c.envPolicy).
+		withDeduplication(!c.noDeduplication).
+		withDeviceCgroupRules(!c.noDeviceCgroupRules)
+
+	if err := c.admitInjection(resolved, edits.injected); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return resolved, edits.injected, nil, nil
+}
+
+// collectEdits resolves the given devices, expanding any device patterns
+// and groups, and collects their edits. Patterns and groups expand to the
+// devices available on the platform of the Cache. Host paths are validated,
+// if enabled, re-rooted under the given root. If any of the devices can't
+// be resolved or is unavailable, or a pattern or group has no available
+// devices, collectEdits returns the unresolved devices and an error.
+func (c *Cache) collectEdits(devices []string, root string) (*editCollector, []string, error) {
+	var (
+		unresolved []string
+		hostErrs   []error
+		host       *hostValidator
+	)
+
+	if c.hostValidation {
+		host = newHostValidator(root)
+	}
+
+	// validHost checks that the device is available and, if enabled,
+	// its host paths and readiness.
+	validHost := func(name string, d *Device) bool {
+		err := c.unavailable[c.deviceKey(d.GetQualifiedName())]
+		if err == nil && host != nil {
+			err = host.validate(d)
+		}
+		if err == nil && c.readinessChecks {
+			err = checkReadiness(d, root)
+		}
+		if err != nil {
+			unresolved = append(unresolved, name)
+			hostErrs = append(hostErrs, err)
+			return false
+		}
+		return true
+	}
+
+	edits := newEditCollector(c.editOrder, c.getDeviceInfoResolver())
+
+	// collect adds the edits of the device, unless a pattern, a group,
+	// an old name, or another request for it has already added them.
+	seen := map[*Device]struct{}{}
+	collect := func(name string, d *Device) error {
+		if _, ok := seen[d]; ok {
+			return nil
+		}
+		seen[d] = struct{}{}
+		if !validHost(name, d) {
+			return nil
+		}
+		return edits.add(d)
+	}
+
+	devices, unresolved = c.expandDevicePatterns(devices)
 	for _, device := range devices {
-		d := c.devices[device]
-		if d == nil {
+		key := c.deviceKey(device)
+		if r := c.renames[key]; r != nil {
+			key = c.deviceKey(r.device)
+		}
+		if d := c.devices[key]; d != nil {
+			if err := collect(device, d); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		group := c.groups[key]
+		if group == nil {
 			unresolved = append(unresolved, device)
 			continue
 		}
-		if _, ok := specs[d.GetSpec()]; !ok {
-			specs[d.GetSpec()] = struct{}{}
-			edits.Append(d.GetSpec().edits())
+		// like patterns, groups expand to their available members
+		available := 0
+		for _, member := range group.members {
+			key := c.deviceKey(member)
+			d := c.devices[key]
+			if d == nil {
+				unresolved = append(unresolved, member)
+				continue
+			}
+			if _, ok := c.unavailable[key]; ok {
+				continue
+			}
+			available++
+			if err := collect(member, d); err != nil {
+				return nil, nil, err
+			}
+		}
+		if available == 0 {
+			unresolved = append(unresolved, device)
 		}
-		edits.Append(d.edits())
 	}
 
 	if unresolved != nil {
-		return unresolved, fmt.Errorf("unresolvable CDI devices %s",
+		err := fmt.Errorf("unresolvable CDI devices %s",
 			strings.Join(unresolved, ", "))
+		if hostErrs != nil {
+			err = errors.Join(append([]error{err}, hostErrs...)...)
+		}
+		return nil, unresolved, err
 	}
 
-	if err := edits.Apply(ociSpec); err != nil {
-		return nil, fmt.Errorf("failed to inject devices: %w", err)
-	}
-
-	return nil, nil
+	return edits, nil, nil
 }
 
 // highestPrioritySpecDir returns the Spec directory with highest priority
@@ -294,32 +860,263 @@ func (c *Cache) WriteSpec(raw *cdi.Spec, name string) error {
 		return errors.New("no Spec directories to write to")
 	}
 
-	path = filepath.Join(specDir, name)
+	path = specFilePath(specDir, name)
+
+	spec, err = newSpecWith(raw, path, prio, c.validateSpec)
+	if err != nil {
+		return err
+	}
+
+	if err = spec.write(true); err != nil {
+		return err
+	}
+
+	c.Lock()
+	c.deferRefresh(false, spec.path)
+	c.Unlock()
+
+	return nil
+}
+
+// WriteSpecs writes several Spec files, keyed by name, into the highest
+// priority Spec directory, like WriteSpec() does for a single one. All
+// Specs are validated and written to temporary files before any of them
+// is put in place. If putting any file in place fails, the files already
+// put in place are rolled back to their previous content and the error is
+// returned. Once all files are in place the Cache is refreshed once, so
+// devices from a partially updated set of Specs are never visible.
+func (c *Cache) WriteSpecs(specs map[string]*cdi.Spec) error {
+	specDir, prio := c.highestPrioritySpecDir()
+	if specDir == "" {
+		return errors.New("no Spec directories to write to")
+	}
+
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		pending = make([]*pendingSpec, 0, len(names))
+		paths   = make(map[string]string, len(names))
+	)
+	defer func() {
+		for _, p := range pending {
+			if p.tmp != "" {
+				os.Remove(p.tmp)
+			}
+		}
+	}()
+
+	for _, name := range names {
+		raw := specs[name]
+		if raw == nil {
+			return fmt.Errorf("can't write nil CDI Spec %q", name)
+		}
+		path := specFilePath(specDir, name)
+		if other, ok := paths[path]; ok {
+			return fmt.Errorf("CDI Specs %q and %q map to the same file %q", other, name, path)
+		}
+		paths[path] = name
+
+		spec, err := newSpecWith(raw, path, prio, c.validateSpec)
+		if err != nil {
+			return fmt.Errorf("invalid CDI Spec %q: %w", name, err)
+		}
+		tmp, err := spec.writeTemp()
+		if err != nil {
+			return fmt.Errorf("failed to write CDI Spec %q: %w", name, err)
+		}
+		pending = append(pending, &pendingSpec{path: path, tmp: tmp})
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	for i, p := range pending {
+		if err := p.commit(); err != nil {
+			if rbErr := rollbackSpecs(pending[:i]); rbErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to roll back Spec files: %w", rbErr))
+			}
+			return fmt.Errorf("failed to write Spec file %q: %w", p.path, err)
+		}
+	}
+
+	written := make([]string, 0, len(pending))
+	for _, p := range pending {
+		written = append(written, p.path)
+	}
+	if !c.deferRefresh(false, written...) {
+		_ = c.refreshPaths(context.Background(), written...) // we record but ignore errors
+	}
+
+	return nil
+}
+
+// pendingSpec is a Spec file written to a temporary file by WriteSpecs().
+type pendingSpec struct {
+	path    string
+	tmp     string
+	prev    []byte
+	existed bool
+}
+
+// commit puts the temporary file in place, saving any previous content.
+func (p *pendingSpec) commit() error {
+	prev, err := os.ReadFile(p.path)
+	switch {
+	case err == nil:
+		p.prev, p.existed = prev, true
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	dir := filepath.Dir(p.path)
+	if err := renameIn(dir, filepath.Base(p.tmp), filepath.Base(p.path), true); err != nil {
+		return err
+	}
+	p.tmp = ""
+
+	return nil
+}
+
+// rollbackSpecs restores the previous content of committed Spec files.
+func rollbackSpecs(committed []*pendingSpec) error {
+	var errs []error
+	for _, p := range committed {
+		if !p.existed {
+			if err := os.Remove(p.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		dir := filepath.Dir(p.path)
+		tmp, err := writeTempFile(dir, p.prev)
+		if err == nil {
+			err = renameIn(dir, filepath.Base(tmp), filepath.Base(p.path), true)
+			if err != nil {
+				os.Remove(tmp)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// specFilePath returns the path of the Spec file with the given name in dir.
+func specFilePath(dir, name string) string {
+	path := filepath.Join(dir, name)
 	if ext := filepath.Ext(path); ext != ".json" && ext != ".yaml" {
 		path += defaultSpecExt
 	}
+	return path
+}
+
+// AddSpec adds an in-memory Spec with the given content and priority
+// to the Cache. In-memory Specs are not backed by any file. Otherwise
+// they are treated identically to Specs loaded from Spec directories:
+// their devices are listed, can be injected, and take part in conflict
+// resolution. The priority of a Spec loaded from a Spec directory is
+// the index of the directory in the configured Spec directories, this
+// can be used to choose a priority relative to Spec directories.
+//
+// The in-memory Spec is stored under the name generated for it by
+// GenerateNameForSpec(). Adding a Spec with the same vendor and class
+// replaces any previously added one. Use AddNamedSpec() to add several
+// Specs of the same vendor and class. The Spec can be removed from the
+// Cache by passing the same name to RemoveSpec(). AddSpec returns the
+// errors encountered for the added Spec, for instance device conflicts.
+// Errors for other Specs can be obtained using GetErrors().
+func (c *Cache) AddSpec(raw *cdi.Spec, priority int) error {
+	if raw == nil {
+		return errors.New("can't add nil CDI Spec")
+	}
+
+	name, err := GenerateNameForSpec(raw)
+	if err != nil {
+		return err
+	}
+
+	return c.AddNamedSpec(raw, name, priority)
+}
+
+// AddNamedSpec is like AddSpec but stores the in-memory Spec under the
+// given name, replacing any Spec previously added with the same name.
+// This allows adding several Specs of the same vendor and class, for
+// instance one per transient ID, using names generated by
+// GenerateNameForTransientSpec(). The Spec can be removed from the Cache
+// by passing the same name to RemoveSpec().
+func (c *Cache) AddNamedSpec(raw *cdi.Spec, name string, priority int) error {
+	if raw == nil {
+		return errors.New("can't add nil CDI Spec")
+	}
+	if name == "" {
+		return errors.New("can't add CDI Spec with an empty name")
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("failed to marshal CDI Spec: %w", err)
+	}
 
-	spec, err = newSpec(raw, path, prio)
+	spec, err := newSpecWith(raw, name, priority, c.validateSpec)
 	if err != nil {
 		return err
 	}
+	spec.path = memorySpecPath(name)
+	spec.digest = specDigest(data)
+	spec.modTime = time.Now()
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.memSpecs == nil {
+		c.memSpecs = make(map[string]*Spec)
+	}
+	c.memSpecs[name] = spec
 
-	return spec.write(true)
+	if c.deferRefresh(false) {
+		return nil
+	}
+	_ = c.refresh() // errors of other Specs are recorded, see GetErrors()
+	return errors.Join(c.errors[spec.GetPath()]...)
+}
+
+// memorySpecPath returns the pseudo-path used for an in-memory Spec.
+func memorySpecPath(name string) string {
+	return memorySpecPrefix + name
 }
 
 // RemoveSpec removes a Spec with the given name from the highest
 // priority Spec directory. This function can be used to remove a
 // Spec previously written by WriteSpec(). If the file exists and
-// its removal fails RemoveSpec returns an error.
+// its removal fails RemoveSpec returns an error. Any in-memory Spec
+// added with the same name by AddSpec() is also removed.
 func (c *Cache) RemoveSpec(name string) error {
 	var (
 		specDir string
 		path    string
+		removed bool
 		err     error
 	)
 
+	c.Lock()
+	if _, removed = c.memSpecs[name]; removed {
+		delete(c.memSpecs, name)
+		if !c.deferRefresh(false) {
+			_ = c.refresh() // we record but ignore errors
+		}
+	}
+	c.Unlock()
+
 	specDir, _ = c.highestPrioritySpecDir()
 	if specDir == "" {
+		if removed {
+			return nil
+		}
 		return errors.New("no Spec directories to remove from")
 	}
 
@@ -332,38 +1129,56 @@ func (c *Cache) RemoveSpec(name string) error {
 	if err != nil && errors.Is(err, fs.ErrNotExist) {
 		err = nil
 	}
+	if err == nil {
+		c.Lock()
+		c.deferRefresh(false, path)
+		c.Unlock()
+	}
 
 	return err
 }
 
+// GetDeviceGroup returns the qualified names of the member devices of the
+// given device group, or nil if there is no such group. Might trigger a
+// cache refresh, in which case any errors encountered can be obtained using
+// GetErrors().
+func (c *Cache) GetDeviceGroup(group string) []string {
+	unlock := c.lockRefreshed()
+	defer unlock()
+
+	g, ok := c.groups[c.deviceKey(group)]
+	if !ok {
+		return nil
+	}
+	members := make([]string, len(g.members))
+	copy(members, g.members)
+	return members
+}
+
+// deviceGroup is a named group of devices defined by a Spec.
+type deviceGroup struct {
+	name    string
+	spec    *Spec
+	members []string
+}
+
 // GetDevice returns the cached device for the given qualified name. Might trigger
 // a cache refresh, in which case any errors encountered can be obtained using
 // GetErrors().
 func (c *Cache) GetDevice(device string) *Device {
-	c.Lock()
-	defer c.Unlock()
+	unlock := c.lockRefreshed()
+	defer unlock()
 
-	_, _ = c.refreshIfRequired(false) // we record but ignore errors
-
-	return c.devices[device]
+	return c.devices[c.deviceKey(device)]
 }
 
 // ListDevices lists all cached devices by qualified name. Might trigger a cache
 // refresh, in which case any errors encountered can be obtained using GetErrors().
 func (c *Cache) ListDevices() []string {
-	var devices []string
-
-	c.Lock()
-	defer c.Unlock()
-
-	_, _ = c.refreshIfRequired(false) // we record but ignore errors
-
-	for name := range c.devices {
-		devices = append(devices, name)
-	}
-	sort.Strings(devices)
+	unlock := c.lockRefreshed()
+	defer unlock()
 
-	return devices
+	return deviceNames(c.sortedDevices)
 }
 
 // ListVendors lists all vendors known to the cache. Might trigger a cache refresh,
@@ -371,10 +1186,8 @@ func (c *Cache) ListDevices() []string {
 func (c *Cache) ListVendors() []string {
 	var vendors []string
 
-	c.Lock()
-	defer c.Unlock()
-
-	_, _ = c.refreshIfRequired(false) // we record but ignore errors
+	unlock := c.lockRefreshed()
+	defer unlock()
 
 	for vendor := range c.specs {
 		vendors = append(vendors, vendor)
@@ -392,10 +1205,8 @@ func (c *Cache) ListClasses() []string {
 		classes []string
 	)
 
-	c.Lock()
-	defer c.Unlock()
-
-	_, _ = c.refreshIfRequired(false) // we record but ignore errors
+	unlock := c.lockRefreshed()
+	defer unlock()
 
 	for _, specs := range c.specs {
 		for _, spec := range specs {
@@ -413,35 +1224,92 @@ func (c *Cache) ListClasses() []string {
 // GetVendorSpecs returns all specs for the given vendor. Might trigger a cache
 // refresh, in which case any errors encountered can be obtained using GetErrors().
 func (c *Cache) GetVendorSpecs(vendor string) []*Spec {
-	c.Lock()
-	defer c.Unlock()
+	unlock := c.lockRefreshed()
+	defer unlock()
 
-	_, _ = c.refreshIfRequired(false) // we record but ignore errors
+	if !c.caseInsensitive {
+		return c.specs[vendor]
+	}
+
+	var specs []*Spec
+	for v, vendorSpecs := range c.specs {
+		if strings.EqualFold(v, vendor) {
+			specs = append(specs, vendorSpecs...)
+		}
+	}
+	return specs
+}
 
-	return c.specs[vendor]
+// deviceKey returns the key used to store and look up the given qualified
+// device name in the cache.
+func (c *Cache) deviceKey(device string) string {
+	if c.caseInsensitive {
+		return parser.CanonicalName(device)
+	}
+	return device
 }
 
 // GetSpecErrors returns all errors encountered for the spec during the
-// last cache refresh.
+// last cache refresh, and any *VersionDowngrade of the spec (see
+// WithMaximumVersion).
 func (c *Cache) GetSpecErrors(spec *Spec) []error {
 	var errors []error
 
-	c.Lock()
-	defer c.Unlock()
+	c.RLock()
+	defer c.RUnlock()
 
 	if errs, ok := c.errors[spec.GetPath()]; ok {
 		errors = make([]error, len(errs))
 		copy(errors, errs)
 	}
+	errors = append(errors, c.warnings[spec.GetPath()]...)
 
 	return errors
 }
 
+// GetConflictResolutions returns the conflicts between device definitions
+// which were resolved using device priorities (see PriorityAnnotation)
+// during the last cache refresh, sorted by device name.
+func (c *Cache) GetConflictResolutions() []*ConflictResolution {
+	c.RLock()
+	defer c.RUnlock()
+
+	resolutions := make([]*ConflictResolution, len(c.resolutions))
+	copy(resolutions, c.resolutions)
+	sort.SliceStable(resolutions, func(i, j int) bool {
+		return resolutions[i].Device < resolutions[j].Device
+	})
+
+	return resolutions
+}
+
+// ConflictResolution describes how a conflict between two definitions of
+// the same device in Specs of equal priority was resolved using device
+// priorities. These are reported by GetConflictResolutions().
+type ConflictResolution struct {
+	// Device is the qualified name of the conflicting device.
+	Device string
+	// Selected is the path of the Spec whose device definition is used.
+	Selected string
+	// Overridden is the path of the Spec whose device definition is ignored.
+	Overridden string
+	// SelectedPriority is the priority of the selected device definition.
+	SelectedPriority int
+	// OverriddenPriority is the priority of the ignored device definition.
+	OverriddenPriority int
+}
+
+// Error returns a description of the conflict resolution.
+func (r *ConflictResolution) Error() string {
+	return fmt.Sprintf("device %q of %q (priority %d) overrides that of %q (priority %d)",
+		r.Device, r.Selected, r.SelectedPriority, r.Overridden, r.OverriddenPriority)
+}
+
 // GetErrors returns all errors encountered during the last
 // cache refresh.
 func (c *Cache) GetErrors() map[string][]error {
-	c.Lock()
-	defer c.Unlock()
+	c.RLock()
+	defer c.RUnlock()
 
 	errors := map[string][]error{}
 	for path, errs := range c.errors {
@@ -456,8 +1324,8 @@ func (c *Cache) GetErrors() map[string][]error {
 
 // GetSpecDirectories returns the CDI Spec directories currently in use.
 func (c *Cache) GetSpecDirectories() []string {
-	c.Lock()
-	defer c.Unlock()
+	c.RLock()
+	defer c.RUnlock()
 
 	dirs := make([]string, len(c.specDirs))
 	copy(dirs, c.specDirs)
@@ -470,8 +1338,8 @@ func (c *Cache) GetSpecDirErrors() map[string]error {
 		return nil
 	}
 
-	c.Lock()
-	defer c.Unlock()
+	c.RLock()
+	defer c.RUnlock()
 
 	errors := make(map[string]error)
 	for dir, err := range c.dirErrors {
@@ -484,47 +1352,92 @@ func (c *Cache) GetSpecDirErrors() map[string]error {
 type watch struct {
 	watcher *fsnotify.Watcher
 	tracked map[string]bool
+	// failed are the directories which can't be watched for reasons other
+	// than not existing, for instance because of exhausted inotify limits.
+	failed map[string]error
+	// err is the last error encountered by the watch.
+	err error
+	// onDegraded is called when the watch becomes degraded.
+	onDegraded func(error)
+	// retrying is true while re-arm attempts are made for the watch.
+	retrying bool
+	// done is closed when the watch is stopped.
+	done chan struct{}
 }
 
 // Setup monitoring for the given Spec directories.
 func (w *watch) setup(dirs []string, dirErrors map[string]error) {
-	var (
-		dir string
-		err error
-	)
 	w.tracked = make(map[string]bool)
-	for _, dir = range dirs {
+	for _, dir := range dirs {
 		w.tracked[dir] = false
 	}
+	w.failed = make(map[string]error)
+	w.err = nil
+	w.retrying = false
+	w.done = make(chan struct{})
 
-	w.watcher, err = fsnotify.NewWatcher()
-	if err != nil {
-		for _, dir := range dirs {
-			dirErrors[dir] = fmt.Errorf("failed to create watcher: %w", err)
-		}
+	if !w.create(dirErrors) {
+		w.degrade(w.err)
 		return
 	}
 
 	w.update(dirErrors)
 }
 
+// create creates the watcher, returning false if this fails.
+func (w *watch) create(dirErrors map[string]error) bool {
+	watcher, err := newWatcher()
+	if err != nil {
+		w.err = fmt.Errorf("failed to create watcher: %w", err)
+		for dir := range w.tracked {
+			dirErrors[dir] = w.err
+		}
+		return false
+	}
+	w.watcher = watcher
+	return true
+}
+
 // Start watching Spec directories for relevant changes.
-func (w *watch) start(m *sync.Mutex, refresh func() error, dirErrors map[string]error) {
+func (w *watch) start(m *sync.RWMutex, refresh func(...string) error, dirErrors map[string]error) {
 	go w.watch(w.watcher, m, refresh, dirErrors)
 }
 
 // Stop watching directories.
 func (w *watch) stop() {
+	if w.done != nil {
+		close(w.done)
+		w.done = nil
+	}
 	if w.watcher == nil {
+		w.tracked = nil
 		return
 	}
 
 	w.watcher.Close()
+	w.watcher = nil
 	w.tracked = nil
 }
 
-// Watch Spec directory changes, triggering a refresh if necessary.
-func (w *watch) watch(fsw *fsnotify.Watcher, m *sync.Mutex, refresh func() error, dirErrors map[string]error) {
+// healthy returns true if the watch is set up and all existing Spec
+// directories are watched.
+func (w *watch) healthy() bool {
+	return w.tracked != nil && w.watcher != nil && len(w.failed) == 0
+}
+
+// degrade records the given error, notifying about the watch becoming
+// degraded if it was healthy.
+func (w *watch) degrade(err error) {
+	w.err = err
+	if w.onDegraded != nil {
+		w.onDegraded(err)
+	}
+}
+
+// Watch Spec directory changes, triggering a refresh if necessary. Changes
+// to Spec files, or their signatures, trigger a refresh of only the changed
+// Spec file. Changes to the set of watched directories trigger a full one.
+func (w *watch) watch(fsw *fsnotify.Watcher, m *sync.RWMutex, refresh func(...string) error, dirErrors map[string]error) {
 	watch := fsw
 	if watch == nil {
 		return
@@ -536,41 +1449,71 @@ func (w *watch) watch(fsw *fsnotify.Watcher, m *sync.Mutex, refresh func() error
 				return
 			}
 
-			if (event.Op & (fsnotify.Rename | fsnotify.Remove | fsnotify.Write)) == 0 {
+			if (event.Op & (fsnotify.Create | fsnotify.Rename | fsnotify.Remove | fsnotify.Write)) == 0 {
 				continue
 			}
-			if event.Op == fsnotify.Write {
-				if ext := filepath.Ext(event.Name); ext != ".json" && ext != ".yaml" {
-					continue
-				}
-			}
+
+			path := strings.TrimSuffix(event.Name, SignatureExt)
+			ext := filepath.Ext(path)
+			isSpec := ext == ".json" || ext == ".yaml"
 
 			m.Lock()
-			if event.Op == fsnotify.Remove && w.tracked[event.Name] {
+			switch {
+			case event.Op == fsnotify.Remove && w.tracked[event.Name]:
 				w.update(dirErrors, event.Name)
-			} else {
-				w.update(dirErrors)
+				_ = refresh()
+			case w.update(dirErrors):
+				_ = refresh()
+			case isSpec:
+				_ = refresh(path)
 			}
-			_ = refresh()
 			m.Unlock()
 
-		case _, ok := <-watch.Errors:
+		case err, ok := <-watch.Errors:
 			if !ok {
 				return
 			}
+
+			// Events might have been lost, so rescan all Spec dirs.
+			m.Lock()
+			w.err = fmt.Errorf("failed to monitor for changes: %w", err)
+			_ = refresh()
+			m.Unlock()
 		}
 	}
 }
 
+// pending returns true if there are directories not watched yet, which
+// an update might start watching. Directories which still don't exist
+// are not pending, so a missing Spec directory does not force a refresh,
+// and the exclusive lock it needs, on every use of the Cache. It does not
+// change the watch.
+func (w *watch) pending() bool {
+	for dir, ok := range w.tracked {
+		if ok {
+			continue
+		}
+		if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+			return true
+		}
+	}
+	return false
+}
+
 // Update watch with pending/missing or removed directories.
 func (w *watch) update(dirErrors map[string]error, removed ...string) bool {
 	var (
-		dir    string
-		ok     bool
-		err    error
-		update bool
+		dir     string
+		ok      bool
+		err     error
+		update  bool
+		healthy = w.healthy()
 	)
 
+	if w.watcher == nil {
+		return false
+	}
+
 	for dir, ok = range w.tracked {
 		if ok {
 			continue
@@ -579,14 +1522,25 @@ func (w *watch) update(dirErrors map[string]error, removed ...string) bool {
 		err = w.watcher.Add(dir)
 		if err == nil {
 			w.tracked[dir] = true
+			delete(w.failed, dir)
 			delete(dirErrors, dir)
 			update = true
 		} else {
 			w.tracked[dir] = false
 			dirErrors[dir] = fmt.Errorf("failed to monitor for changes: %w", err)
+			if errors.Is(err, fs.ErrNotExist) {
+				delete(w.failed, dir)
+			} else {
+				w.failed[dir] = dirErrors[dir]
+				w.err = dirErrors[dir]
+			}
 		}
 	}
 
+	if healthy && !w.healthy() {
+		w.degrade(w.err)
+	}
+
 	for _, dir = range removed {
 		w.tracked[dir] = false
 		dirErrors[dir] = errors.New("directory removed")
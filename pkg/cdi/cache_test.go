@@ -17,6 +17,7 @@
 package cdi
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -675,12 +676,13 @@ devices:
 				errCh  chan error
 				stopCh chan struct{}
 
-				duration = 10 * time.Second
-				wg       = &sync.WaitGroup{}
+				duration  = 10 * time.Second
+				injectors = 4
+				wg        = &sync.WaitGroup{}
 			)
 
 			stopCh = make(chan struct{})
-			errCh = make(chan error, 2)
+			errCh = make(chan error, injectors+1)
 
 			// injector: run injection loop until an error or request to stop
 			injector := func() {
@@ -793,10 +795,12 @@ devices:
 			)
 			require.NotNil(t, cache)
 
-			go injector()
+			for i := 0; i < injectors; i++ {
+				go injector()
+			}
 			go updater()
 			go fssyncer()
-			wg.Add(3)
+			wg.Add(injectors + 2)
 
 			done := time.After(duration)
 			for {
@@ -813,6 +817,120 @@ devices:
 	}
 }
 
+func TestConcurrentInjection(t *testing.T) {
+	const (
+		injectors = 4
+		timeout   = 5 * time.Second
+	)
+
+	dir, err := createSpecDirs(t, map[string]string{
+		"vendor1.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR1_VAR1=VAL1"
+`,
+	}, nil)
+	require.NoError(t, err)
+
+	// The Applier waits until all injectors are applying edits at the
+	// same time, which is only possible if injections do not exclude
+	// each other.
+	var (
+		arrived = &sync.WaitGroup{}
+		applier = ApplierFunc(func(ociSpec *oci.Spec, edits *ContainerEdits, _ []*Device) error {
+			arrived.Done()
+			arrived.Wait()
+			return edits.Apply(ociSpec)
+		})
+		cache = newCache(
+			WithSpecDirs(filepath.Join(dir, "etc")),
+			WithApplier(applier),
+		)
+		errCh = make(chan error, injectors)
+	)
+	defer cache.Close()
+
+	arrived.Add(injectors)
+	for i := 0; i < injectors; i++ {
+		go func() {
+			ociSpec := &oci.Spec{}
+			_, err := cache.InjectDevices(ociSpec, "vendor1.com/device=dev1")
+			if err == nil && len(ociSpec.Process.Env) != 1 {
+				err = fmt.Errorf("unexpected environment %v", ociSpec.Process.Env)
+			}
+			errCh <- err
+		}()
+	}
+
+	for i := 0; i < injectors; i++ {
+		select {
+		case err := <-errCh:
+			require.NoError(t, err)
+		case <-time.After(timeout):
+			t.Fatalf("concurrent injections did not run in parallel")
+		}
+	}
+}
+
+func TestLookupWithMissingSpecDir(t *testing.T) {
+	const timeout = 5 * time.Second
+
+	dir, err := createSpecDirs(t, map[string]string{
+		"vendor1.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR1_VAR1=VAL1"
+`,
+	}, nil)
+	require.NoError(t, err)
+
+	missing := filepath.Join(dir, "missing")
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc"), missing),
+		WithAutoRefresh(true),
+	)
+	defer cache.Close()
+	require.NotNil(t, cache.GetDevice("vendor1.com/device=dev1"))
+
+	// A missing Spec directory must not force lookups to take the Cache
+	// lock exclusively, so a lookup proceeds while others hold it shared.
+	cache.RLock()
+	found := make(chan bool, 1)
+	go func() {
+		found <- cache.GetDevice("vendor1.com/device=dev1") != nil
+	}()
+	select {
+	case ok := <-found:
+		require.True(t, ok)
+	case <-time.After(timeout):
+		cache.RUnlock()
+		t.Fatalf("lookup blocked by a missing Spec directory")
+	}
+	cache.RUnlock()
+
+	// Once the missing directory appears, it is picked up.
+	require.NoError(t, os.Mkdir(missing, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(missing, "vendor2.yaml"), []byte(`
+cdiVersion: "0.3.0"
+kind:       "vendor2.com/device"
+devices:
+  - name: "dev2"
+    containerEdits:
+      env:
+      - "VENDOR2_VAR1=VAL1"
+`), 0o644))
+	require.NotNil(t, cache.GetDevice("vendor2.com/device=dev2"))
+	require.NotContains(t, cache.GetErrors(), missing)
+}
+
 func TestInjectDevice(t *testing.T) {
 	type specDirs struct {
 		etc map[string]string
@@ -1559,7 +1677,7 @@ containerEdits:
 				other *Cache
 			)
 
-			SetSpecValidator(validate.WithNamedSchema("builtin"))
+			validator := WithSpecValidator(validate.WithNamedSchema("builtin"))
 
 			if len(tc.invalid) != 0 {
 				dir, err = createSpecDirs(t, nil, nil)
@@ -1570,6 +1688,7 @@ containerEdits:
 						filepath.Join(dir, "run"),
 					),
 					WithAutoRefresh(false),
+					validator,
 				)
 
 				require.NotNil(t, cache)
@@ -1600,6 +1719,7 @@ containerEdits:
 				WithSpecDirs(
 					filepath.Join(dir, "etc"),
 				),
+				validator,
 			)
 			require.NotNil(t, cache)
 
@@ -1608,6 +1728,7 @@ containerEdits:
 					filepath.Join(dir, "run"),
 				),
 				WithAutoRefresh(false),
+				validator,
 			)
 			require.NotNil(t, other)
 
@@ -1634,6 +1755,177 @@ containerEdits:
 	}
 }
 
+func TestCacheInMemorySpecs(t *testing.T) {
+	etc := map[string]string{
+		"vendor1.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR1_DEV1=file"
+`,
+	}
+
+	dir, err := createSpecDirs(t, etc, nil)
+	require.NoError(t, err)
+
+	cache := newCache(
+		WithSpecDirs(
+			filepath.Join(dir, "etc"),
+			filepath.Join(dir, "run"),
+		),
+		WithAutoRefresh(false),
+	)
+	require.NotNil(t, cache)
+	require.Equal(t, []string{"vendor1.com/device=dev1"}, cache.ListDevices())
+
+	require.Error(t, cache.AddSpec(nil, 0))
+	require.Error(t, cache.AddSpec(&cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor2.com/device",
+		Devices: []cdi.Device{{Name: "dev1"}},
+	}, 0))
+
+	err = cache.AddSpec(&cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor2.com/device",
+		Devices: []cdi.Device{
+			{
+				Name: "dev1",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"VENDOR2_DEV1=memory"},
+				},
+			},
+		},
+	}, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"vendor1.com/device=dev1",
+		"vendor2.com/device=dev1",
+	}, cache.ListDevices())
+	require.Equal(t, []string{"vendor1.com", "vendor2.com"}, cache.ListVendors())
+
+	// higher priority in-memory Spec overrides file-based one
+	err = cache.AddSpec(&cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor1.com/device",
+		Devices: []cdi.Device{
+			{
+				Name: "dev1",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"VENDOR1_DEV1=memory"},
+				},
+			},
+		},
+	}, 1)
+	require.NoError(t, err)
+	require.Empty(t, cache.GetErrors())
+
+	ociSpec := &oci.Spec{}
+	unresolved, err := cache.InjectDevices(ociSpec, "vendor1.com/device=dev1")
+	require.NoError(t, err)
+	require.Nil(t, unresolved)
+	require.Equal(t, []string{"VENDOR1_DEV1=memory"}, ociSpec.Process.Env)
+
+	// equal priority in-memory Spec conflicts with file-based one
+	err = cache.AddSpec(&cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor1.com/device",
+		Devices: []cdi.Device{
+			{
+				Name: "dev1",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"VENDOR1_DEV1=memory"},
+				},
+			},
+		},
+	}, 0)
+	require.Error(t, err)
+	require.Nil(t, cache.GetDevice("vendor1.com/device=dev1"))
+
+	require.NoError(t, cache.RemoveSpec(GenerateSpecName("vendor1.com", "device")))
+	require.NoError(t, cache.RemoveSpec(GenerateSpecName("vendor2.com", "device")))
+	require.Empty(t, cache.GetErrors())
+	require.Equal(t, []string{"vendor1.com/device=dev1"}, cache.ListDevices())
+}
+
+func TestCacheAddSpecErrors(t *testing.T) {
+	etc := map[string]string{
+		"broken.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev1"
+`,
+	}
+
+	dir, err := createSpecDirs(t, etc, nil)
+	require.NoError(t, err)
+
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+	)
+	require.NotEmpty(t, cache.GetErrors())
+
+	// errors of other Specs are not returned
+	err = cache.AddSpec(&cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor2.com/device",
+		Devices: []cdi.Device{
+			{
+				Name: "dev1",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"VENDOR2_DEV1=memory"},
+				},
+			},
+		},
+	}, 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, cache.GetErrors())
+	require.Equal(t, []string{"vendor2.com/device=dev1"}, cache.ListDevices())
+}
+
+func TestCacheAddNamedSpec(t *testing.T) {
+	cache := newCache(WithAutoRefresh(false))
+	require.NotNil(t, cache)
+
+	spec := func(device string) *cdi.Spec {
+		return &cdi.Spec{
+			Version: cdi.CurrentVersion,
+			Kind:    "vendor.com/device",
+			Devices: []cdi.Device{
+				{
+					Name: device,
+					ContainerEdits: cdi.ContainerEdits{
+						Env: []string{"DEVICE=" + device},
+					},
+				},
+			},
+		}
+	}
+
+	require.Error(t, cache.AddNamedSpec(spec("dev0"), "", 0))
+
+	claim0, err := GenerateNameForTransientSpec(spec("dev0"), "claim0")
+	require.NoError(t, err)
+	claim1, err := GenerateNameForTransientSpec(spec("dev1"), "claim1")
+	require.NoError(t, err)
+	require.NotEqual(t, claim0, claim1)
+
+	require.NoError(t, cache.AddNamedSpec(spec("dev0"), claim0, 0))
+	require.NoError(t, cache.AddNamedSpec(spec("dev1"), claim1, 0))
+	require.NotNil(t, cache.GetDevice("vendor.com/device=dev0"))
+	require.NotNil(t, cache.GetDevice("vendor.com/device=dev1"))
+	require.Equal(t, []string{"vendor.com/device=dev0", "vendor.com/device=dev1"}, cache.ListDevices())
+
+	require.NoError(t, cache.RemoveSpec(claim0))
+	require.Nil(t, cache.GetDevice("vendor.com/device=dev0"))
+	require.NotNil(t, cache.GetDevice("vendor.com/device=dev1"))
+}
+
 func TestCacheTransientSpecs(t *testing.T) {
 	type testCase struct {
 		name         string
@@ -1842,6 +2134,350 @@ devices:
 	}
 }
 
+func TestCaseSensitiveNames(t *testing.T) {
+	type testCase struct {
+		name            string
+		etc             map[string]string
+		caseInsensitive bool
+		devices         []string
+		lookup          map[string]string
+		hasErrors       bool
+	}
+	for _, tc := range []*testCase{
+		{
+			name: "case-sensitive lookup",
+			etc: map[string]string{
+				"vendor.yaml": `
+cdiVersion: "0.3.0"
+kind:       "Vendor.com/GPU"
+devices:
+  - name: "Dev0"
+    containerEdits:
+      env:
+      - "DEV0=1"
+`,
+			},
+			devices: []string{
+				"Vendor.com/GPU=Dev0",
+			},
+			lookup: map[string]string{
+				"Vendor.com/GPU=Dev0": "Vendor.com/GPU=Dev0",
+				"vendor.com/gpu=dev0": "",
+			},
+		},
+		{
+			name: "case-insensitive lookup",
+			etc: map[string]string{
+				"vendor.yaml": `
+cdiVersion: "0.3.0"
+kind:       "Vendor.com/GPU"
+devices:
+  - name: "Dev0"
+    containerEdits:
+      env:
+      - "DEV0=1"
+`,
+			},
+			caseInsensitive: true,
+			devices: []string{
+				"Vendor.com/GPU=Dev0",
+			},
+			lookup: map[string]string{
+				"Vendor.com/GPU=Dev0": "Vendor.com/GPU=Dev0",
+				"vendor.com/gpu=dev0": "Vendor.com/GPU=Dev0",
+				"VENDOR.COM/GPU=DEV0": "Vendor.com/GPU=Dev0",
+			},
+		},
+		{
+			name: "case-sensitive, names differing by case are distinct",
+			etc: map[string]string{
+				"vendor1.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor.com/gpu"
+devices:
+  - name: "dev0"
+    containerEdits:
+      env:
+      - "DEV0=1"
+`,
+				"vendor2.yaml": `
+cdiVersion: "0.3.0"
+kind:       "Vendor.com/GPU"
+devices:
+  - name: "Dev0"
+    containerEdits:
+      env:
+      - "DEV0=2"
+`,
+			},
+			devices: []string{
+				"Vendor.com/GPU=Dev0",
+				"vendor.com/gpu=dev0",
+			},
+		},
+		{
+			name: "case-insensitive, names differing by case conflict",
+			etc: map[string]string{
+				"vendor1.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor.com/gpu"
+devices:
+  - name: "dev0"
+    containerEdits:
+      env:
+      - "DEV0=1"
+`,
+				"vendor2.yaml": `
+cdiVersion: "0.3.0"
+kind:       "Vendor.com/GPU"
+devices:
+  - name: "Dev0"
+    containerEdits:
+      env:
+      - "DEV0=2"
+`,
+			},
+			caseInsensitive: true,
+			hasErrors:       true,
+			lookup: map[string]string{
+				"vendor.com/gpu=dev0": "",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := createSpecDirs(t, tc.etc, nil)
+			require.NoError(t, err)
+
+			cache := newCache(
+				WithSpecDirs(filepath.Join(dir, "etc")),
+				WithAutoRefresh(false),
+				WithCaseInsensitiveNames(tc.caseInsensitive),
+			)
+			require.NotNil(t, cache)
+
+			require.Equal(t, tc.devices, cache.ListDevices())
+			require.Equal(t, tc.hasErrors, len(cache.GetErrors()) > 0)
+
+			for name, qualified := range tc.lookup {
+				dev := cache.GetDevice(name)
+				if qualified == "" {
+					require.Nil(t, dev, "lookup of %q", name)
+					continue
+				}
+				require.NotNil(t, dev, "lookup of %q", name)
+				require.Equal(t, qualified, dev.GetQualifiedName())
+			}
+		})
+	}
+}
+
+func TestDevicePriorityAnnotation(t *testing.T) {
+	etc := map[string]string{
+		"vendor.yaml": `
+cdiVersion: "0.6.0"
+kind:       "vendor.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "DEV1=vendor"
+  - name: "dev2"
+    containerEdits:
+      env:
+      - "DEV2=vendor"
+  - name: "dev3"
+    containerEdits:
+      env:
+      - "DEV3=vendor"
+`,
+		"override.yaml": `
+cdiVersion: "0.6.0"
+kind:       "vendor.com/device"
+annotations:
+  cdi.k8s.io/priority: "10"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "DEV1=override"
+  - name: "dev2"
+    annotations:
+      cdi.k8s.io/priority: "-1"
+    containerEdits:
+      env:
+      - "DEV2=override"
+  - name: "dev3"
+    containerEdits:
+      env:
+      - "DEV3=override"
+`,
+		"invalid.yaml": `
+cdiVersion: "0.6.0"
+kind:       "vendor.com/device"
+devices:
+  - name: "dev4"
+    annotations:
+      cdi.k8s.io/priority: "high"
+    containerEdits:
+      env:
+      - "DEV4=invalid"
+`,
+	}
+	run := map[string]string{
+		"vendor.yaml": `
+cdiVersion: "0.6.0"
+kind:       "vendor.com/device"
+annotations:
+  cdi.k8s.io/priority: "-5"
+devices:
+  - name: "dev3"
+    containerEdits:
+      env:
+      - "DEV3=run"
+`,
+	}
+
+	dir, err := createSpecDirs(t, etc, run)
+	require.NoError(t, err)
+
+	cache := newCache(
+		WithSpecDirs(
+			filepath.Join(dir, "etc"),
+			filepath.Join(dir, "run"),
+		),
+		WithAutoRefresh(false),
+	)
+	require.NotNil(t, cache)
+
+	require.Equal(t, []string{
+		"vendor.com/device=dev1",
+		"vendor.com/device=dev2",
+		"vendor.com/device=dev3",
+	}, cache.ListDevices())
+
+	// the annotated priority breaks ties within a Spec directory
+	dev1 := cache.GetDevice("vendor.com/device=dev1")
+	require.NotNil(t, dev1)
+	require.Equal(t, 10, dev1.GetPriority())
+	require.Equal(t, []string{"DEV1=override"}, dev1.ContainerEdits.Env)
+
+	dev2 := cache.GetDevice("vendor.com/device=dev2")
+	require.NotNil(t, dev2)
+	require.Equal(t, 0, dev2.GetPriority())
+	require.Equal(t, []string{"DEV2=vendor"}, dev2.ContainerEdits.Env)
+
+	// but does not override the priority of Spec directories
+	dev3 := cache.GetDevice("vendor.com/device=dev3")
+	require.NotNil(t, dev3)
+	require.Equal(t, -5, dev3.GetPriority())
+	require.Equal(t, []string{"DEV3=run"}, dev3.ContainerEdits.Env)
+
+	errors := cache.GetErrors()
+	require.Len(t, errors, 1)
+	require.Contains(t, errors, filepath.Join(dir, "etc", "invalid.yaml"))
+	require.Empty(t, cache.GetSpecErrors(dev1.GetSpec()))
+
+	var (
+		vendor   = filepath.Join(dir, "etc", "vendor.yaml")
+		override = filepath.Join(dir, "etc", "override.yaml")
+	)
+	require.Equal(t,
+		[]*ConflictResolution{
+			{
+				Device:             "vendor.com/device=dev1",
+				Selected:           override,
+				Overridden:         vendor,
+				SelectedPriority:   10,
+				OverriddenPriority: 0,
+			},
+			{
+				Device:             "vendor.com/device=dev2",
+				Selected:           vendor,
+				Overridden:         override,
+				SelectedPriority:   0,
+				OverriddenPriority: -1,
+			},
+			{
+				Device:             "vendor.com/device=dev3",
+				Selected:           override,
+				Overridden:         vendor,
+				SelectedPriority:   10,
+				OverriddenPriority: 0,
+			},
+		},
+		cache.GetConflictResolutions(),
+	)
+}
+
+func TestCacheContext(t *testing.T) {
+	spec := func(device string) string {
+		return `
+cdiVersion: "` + cdi.CurrentVersion + `"
+kind: "vendor.com/device"
+devices:
+  - name: "` + device + `"
+    containerEdits:
+      env:
+        - "DEVICE=` + device + `"
+`
+	}
+
+	dir, err := createSpecDirs(t, map[string]string{"vendor.yaml": spec("dev0")}, nil)
+	require.NoError(t, err)
+
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc"), filepath.Join(dir, "run")),
+		WithAutoRefresh(false),
+	)
+	require.NoError(t, cache.Refresh())
+	require.Equal(t, []string{"vendor.com/device=dev0"}, cache.ListDevices())
+
+	t.Run("cancelled refresh leaves the cache unchanged", func(t *testing.T) {
+		require.NoError(t, updateSpecDirs(dir, nil, map[string]string{"vendor.yaml": spec("dev1")}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, cache.RefreshContext(ctx), context.Canceled)
+		require.ErrorIs(t, cache.RefreshVendorContext(ctx, "vendor.com"), context.Canceled)
+		require.Len(t, cache.devices, 1)
+		require.True(t, cache.refreshPending)
+
+		// the cancelled refresh is completed by the next lookup
+		ociSpec := &oci.Spec{}
+		unresolved, err := cache.InjectDevicesContext(context.Background(), ociSpec, "vendor.com/device=dev1")
+		require.NoError(t, err)
+		require.Nil(t, unresolved)
+		require.Equal(t, []string{"DEVICE=dev1"}, ociSpec.Process.Env)
+		require.Equal(t, []string{"vendor.com/device=dev0", "vendor.com/device=dev1"}, cache.ListDevices())
+	})
+
+	t.Run("deadline while waiting for the cache", func(t *testing.T) {
+		cache.Lock()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		ociSpec := &oci.Spec{}
+		_, err := cache.InjectDevicesContext(ctx, ociSpec, "vendor.com/device=dev0")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Nil(t, ociSpec.Process)
+		require.ErrorIs(t, cache.RefreshPathsContext(ctx, filepath.Join(dir, "etc", "vendor.yaml")), context.DeadlineExceeded)
+		cache.Unlock()
+
+		// abandoned lock attempts must not keep the cache locked
+		done := make(chan error)
+		go func() {
+			_, err := cache.InjectDevices(ociSpec, "vendor.com/device=dev0")
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("cache left locked by cancelled operations")
+		}
+	})
+}
+
 // Create and populate automatically cleaned up spec directories.
 func createSpecDirs(t *testing.T, etc, run map[string]string) (string, error) {
 	return mkTestDir(t, map[string]map[string]string{
@@ -1872,6 +2508,74 @@ func updateSpecDirs(dir string, etc, run map[string]string) error {
 	return updateTestDir(dir, updates)
 }
 
+func TestCacheWriteSpecs(t *testing.T) {
+	spec := func(vendor, device string) *cdi.Spec {
+		return &cdi.Spec{
+			Version: "0.3.0",
+			Kind:    vendor + ".com/device",
+			Devices: []cdi.Device{
+				{
+					Name: device,
+					ContainerEdits: cdi.ContainerEdits{
+						Env: []string{"DEVICE=" + device},
+					},
+				},
+			},
+		}
+	}
+	devices := func(cache *Cache) []string {
+		require.NoError(t, cache.Refresh())
+		return cache.ListDevices()
+	}
+
+	dir := t.TempDir()
+	cache := newCache(
+		WithSpecDirs(dir),
+		WithAutoRefresh(false),
+	)
+
+	require.NoError(t, cache.WriteSpecs(map[string]*cdi.Spec{
+		"vendor1": spec("vendor1", "dev1"),
+		"vendor2": spec("vendor2", "dev1"),
+	}))
+	require.Equal(t, []string{"vendor1.com/device=dev1", "vendor2.com/device=dev1"}, cache.ListDevices())
+
+	// invalid Specs prevent writing any of the files
+	invalid := spec("vendor2", "dev2")
+	invalid.Devices[0].Name = "dev 2"
+	err := cache.WriteSpecs(map[string]*cdi.Spec{
+		"vendor1": spec("vendor1", "dev2"),
+		"vendor2": invalid,
+	})
+	require.Error(t, err)
+	require.Equal(t, []string{"vendor1.com/device=dev1", "vendor2.com/device=dev1"}, devices(cache))
+
+	require.Error(t, cache.WriteSpecs(map[string]*cdi.Spec{
+		"vendor1":      spec("vendor1", "dev2"),
+		"vendor1.yaml": spec("vendor1", "dev3"),
+	}))
+	require.Equal(t, []string{"vendor1.com/device=dev1", "vendor2.com/device=dev1"}, devices(cache))
+
+	// failure to put a file in place rolls back the ones already in place
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "vendor3.yaml"), 0o755))
+	err = cache.WriteSpecs(map[string]*cdi.Spec{
+		"vendor1": spec("vendor1", "dev2"),
+		"vendor2": spec("vendor2", "dev2"),
+		"vendor3": spec("vendor3", "dev1"),
+		"vendor0": spec("vendor0", "dev1"),
+	})
+	require.Error(t, err)
+	require.Equal(t, []string{"vendor1.com/device=dev1", "vendor2.com/device=dev1"}, devices(cache))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	require.Equal(t, []string{"vendor1.yaml", "vendor2.yaml", "vendor3.yaml"}, names)
+}
+
 func int64ptr(v int64) *int64 {
 	return &v
 }
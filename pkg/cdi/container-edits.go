@@ -22,10 +22,13 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	oci "github.com/opencontainers/runtime-spec/specs-go"
 	ocigen "github.com/opencontainers/runtime-tools/generate"
+	capsCheck "github.com/opencontainers/runtime-tools/validate/capabilities"
+	"golang.org/x/mod/semver"
 	cdi "tags.cncf.io/container-device-interface/specs-go"
 )
 
@@ -42,6 +45,30 @@ const (
 	PoststartHook = "poststart"
 	// PoststopHook is the name of the OCI "poststop" hook.
 	PoststopHook = "poststop"
+
+	// MaxHookTimeout is the maximum timeout of hooks, in seconds, in Specs
+	// of CDI version 0.10.0 or later.
+	MaxHookTimeout = 3600
+)
+
+// hookTimeoutLimitVersion is the first CDI version which limits hook
+// timeouts to MaxHookTimeout.
+const hookTimeoutLimitVersion = "0.10.0"
+
+const (
+	// BlockDeviceType is the type of block device nodes.
+	BlockDeviceType = "b"
+	// CharDeviceType is the type of character device nodes.
+	CharDeviceType = "c"
+	// UnbufferedCharDeviceType is the type of unbuffered character device nodes.
+	UnbufferedCharDeviceType = "u"
+	// FifoDeviceType is the type of FIFO device nodes.
+	FifoDeviceType = "p"
+
+	// validDevicePermissions are the valid device node permission characters.
+	validDevicePermissions = "rwm"
+	// invalidID is the reserved user and group ID (uid_t)-1.
+	invalidID = ^uint32(0)
 )
 
 var (
@@ -54,8 +81,74 @@ var (
 		PoststartHook:       {},
 		PoststopHook:        {},
 	}
+	// Recognized device node types. An empty type is filled in from the host.
+	validDeviceTypes = map[string]struct{}{
+		BlockDeviceType:          {},
+		CharDeviceType:           {},
+		UnbufferedCharDeviceType: {},
+		FifoDeviceType:           {},
+	}
 )
 
+// ValidHookNames returns the sorted names of the recognized hooks.
+func ValidHookNames() []string {
+	return sortedKeys(validHookNames)
+}
+
+// IsValidHookName returns true if the given name is a recognized hook name.
+func IsValidHookName(name string) bool {
+	_, ok := validHookNames[name]
+	return ok
+}
+
+// ValidDeviceTypes returns the sorted recognized device node types. A
+// device node may also leave its type empty, in which case the type is
+// filled in from the host device.
+func ValidDeviceTypes() []string {
+	return sortedKeys(validDeviceTypes)
+}
+
+// IsValidDeviceType returns true if the given device node type is valid.
+// The empty type is valid.
+func IsValidDeviceType(deviceType string) bool {
+	if deviceType == "" {
+		return true
+	}
+	_, ok := validDeviceTypes[deviceType]
+	return ok
+}
+
+// ValidDevicePermissions returns the valid device node permission
+// characters. Device node permissions consist of any of these characters.
+func ValidDevicePermissions() string {
+	return validDevicePermissions
+}
+
+// IsValidDevicePermissions returns true if the given device node
+// permissions are valid. Empty permissions, which grant full access,
+// and the permissions "none", which grant no access, are valid.
+func IsValidDevicePermissions(permissions string) bool {
+	if permissions == cdi.DevicePermissionsNone {
+		return true
+	}
+	for _, bit := range permissions {
+		if !strings.ContainsRune(validDevicePermissions, bit) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedKeys returns the sorted keys of the given set.
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // ContainerEdits represent updates to be applied to an OCI Spec.
 // These updates can be specific to a CDI device, or they can be
 // specific to a CDI Spec. In the former case these edits should
@@ -65,6 +158,15 @@ var (
 // is injected.
 type ContainerEdits struct {
 	*cdi.ContainerEdits
+	// envPolicies are the merge policies of the variables in Env, if
+	// they differ, for instance after appending edits with a policy.
+	envPolicies []string
+	// envPolicy is the policy of variables without one of their own.
+	envPolicy EnvMergePolicy
+	// noDedup disables skipping mounts and device nodes already present.
+	noDedup bool
+	// noCgroupRules disables adding device cgroup rules for device nodes.
+	noCgroupRules bool
 }
 
 // Apply edits to the given OCI Spec. Updates the OCI Spec in place.
@@ -77,15 +179,15 @@ func (e *ContainerEdits) Apply(spec *oci.Spec) error {
 		return nil
 	}
 
+	e.applyEnv(spec)
+	e.reserve(spec)
+
 	specgen := ocigen.NewFromSpec(spec)
-	if len(e.Env) > 0 {
-		specgen.AddMultipleProcessEnv(e.Env)
-	}
 
 	for _, d := range e.DeviceNodes {
 		dn := DeviceNode{d}
 
-		err := dn.fillMissingInfo()
+		err := dn.fillMissingInfo(HostDeviceInfoResolver)
 		if err != nil {
 			return err
 		}
@@ -101,28 +203,51 @@ func (e *ContainerEdits) Apply(spec *oci.Spec) error {
 			}
 		}
 
-		specgen.RemoveDevice(dev.Path)
-		specgen.AddDevice(dev)
+		if e.noDedup || !hasDevice(spec, &dev) {
+			specgen.RemoveDevice(dev.Path)
+			specgen.AddDevice(dev)
+		}
 
-		if dev.Type == "b" || dev.Type == "c" {
-			access := d.Permissions
-			if access == "" {
-				access = "rwm"
+		if !e.noCgroupRules && (dev.Type == "b" || dev.Type == "c") {
+			allow, access := true, d.Permissions
+			switch {
+			case access == "":
+				access = validDevicePermissions
+			case access == cdi.DevicePermissionsNone && d.DenyAccess:
+				allow, access = false, validDevicePermissions
+			case access == cdi.DevicePermissionsNone:
+				continue
+			}
+			rule := oci.LinuxDeviceCgroup{
+				Allow:  allow,
+				Type:   dev.Type,
+				Major:  &dev.Major,
+				Minor:  &dev.Minor,
+				Access: access,
+			}
+			if e.noDedup || !hasDeviceRule(spec, rule) {
+				specgen.AddLinuxResourcesDevice(allow, dev.Type, &dev.Major, &dev.Minor, access)
 			}
-			specgen.AddLinuxResourcesDevice(true, dev.Type, &dev.Major, &dev.Minor, access)
 		}
 	}
 
 	if len(e.Mounts) > 0 {
 		for _, m := range e.Mounts {
+			mnt := (&Mount{m}).toOCI()
+			if !e.noDedup && hasMount(spec, mnt) {
+				continue
+			}
 			specgen.RemoveMount(m.ContainerPath)
-			specgen.AddMount((&Mount{m}).toOCI())
+			specgen.AddMount(mnt)
 		}
 		sortMounts(&specgen)
 	}
 
 	for _, h := range e.Hooks {
 		ociHook := (&Hook{h}).toOCI()
+		if h.InheritEnv {
+			ociHook.Env = inheritedHookEnv(spec, h.Env)
+		}
 		switch h.HookName {
 		case PrestartHook:
 			specgen.AddPreStartHook(ociHook)
@@ -159,9 +284,40 @@ func (e *ContainerEdits) Apply(spec *oci.Spec) error {
 		specgen.AddProcessAdditionalGid(additionalGID)
 	}
 
+	if e.Capabilities != nil {
+		if err := (&Capabilities{e.Capabilities}).apply(&specgen); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// reserve grows the slices of the OCI Spec updated by Apply, so that
+// adding the device nodes, device cgroup rules and mounts of the edits
+// reallocates each slice at most once.
+func (e *ContainerEdits) reserve(spec *oci.Spec) {
+	if n := len(e.Mounts); n > 0 && cap(spec.Mounts)-len(spec.Mounts) < n {
+		mounts := make([]oci.Mount, len(spec.Mounts), len(spec.Mounts)+n)
+		copy(mounts, spec.Mounts)
+		spec.Mounts = mounts
+	}
+
+	n := len(e.DeviceNodes)
+	if n == 0 || spec.Linux == nil {
+		return
+	}
+	if devices := spec.Linux.Devices; cap(devices)-len(devices) < n {
+		spec.Linux.Devices = make([]oci.LinuxDevice, len(devices), len(devices)+n)
+		copy(spec.Linux.Devices, devices)
+	}
+	if r := spec.Linux.Resources; r != nil && cap(r.Devices)-len(r.Devices) < n {
+		rules := make([]oci.LinuxDeviceCgroup, len(r.Devices), len(r.Devices)+n)
+		copy(rules, r.Devices)
+		r.Devices = rules
+	}
+}
+
 // Validate container edits.
 func (e *ContainerEdits) Validate() error {
 	if e == nil || e.ContainerEdits == nil {
@@ -169,26 +325,36 @@ func (e *ContainerEdits) Validate() error {
 	}
 
 	if err := ValidateEnv(e.Env); err != nil {
-		return fmt.Errorf("invalid container edits: %w", err)
+		return withSpecPath("env", fmt.Errorf("invalid container edits: %w", err))
 	}
-	for _, d := range e.DeviceNodes {
+	if e.EnvPolicy != "" {
+		if _, err := ParseEnvMergePolicy(e.EnvPolicy); err != nil {
+			return withSpecPath("envPolicy", fmt.Errorf("invalid container edits: %w", err))
+		}
+	}
+	for i, d := range e.DeviceNodes {
 		if err := (&DeviceNode{d}).Validate(); err != nil {
-			return err
+			return withSpecPath("deviceNodes["+strconv.Itoa(i)+"]", err)
 		}
 	}
-	for _, h := range e.Hooks {
+	for i, h := range e.Hooks {
 		if err := (&Hook{h}).Validate(); err != nil {
-			return err
+			return withSpecPath("hooks["+strconv.Itoa(i)+"]", err)
 		}
 	}
-	for _, m := range e.Mounts {
+	for i, m := range e.Mounts {
 		if err := (&Mount{m}).Validate(); err != nil {
-			return err
+			return withSpecPath("mounts["+strconv.Itoa(i)+"]", err)
 		}
 	}
 	if e.IntelRdt != nil {
 		if err := (&IntelRdt{e.IntelRdt}).Validate(); err != nil {
-			return err
+			return withSpecPath("intelRdt", err)
+		}
+	}
+	if e.Capabilities != nil {
+		if err := (&Capabilities{e.Capabilities}).Validate(); err != nil {
+			return withSpecPath("capabilities", err)
 		}
 	}
 
@@ -208,6 +374,9 @@ func (e *ContainerEdits) Append(o *ContainerEdits) *ContainerEdits {
 		e.ContainerEdits = &cdi.ContainerEdits{}
 	}
 
+	if e.hasEnvPolicies() || o.hasEnvPolicies() {
+		e.envPolicies = append(e.envPolicyList(), o.envPolicyList()...)
+	}
 	e.Env = append(e.Env, o.Env...)
 	e.DeviceNodes = append(e.DeviceNodes, o.DeviceNodes...)
 	e.Hooks = append(e.Hooks, o.Hooks...)
@@ -216,6 +385,9 @@ func (e *ContainerEdits) Append(o *ContainerEdits) *ContainerEdits {
 		e.IntelRdt = o.IntelRdt
 	}
 	e.AdditionalGIDs = append(e.AdditionalGIDs, o.AdditionalGIDs...)
+	if o.Capabilities != nil {
+		e.Capabilities = appendCapabilities(e.Capabilities, o.Capabilities)
+	}
 
 	return e
 }
@@ -244,6 +416,9 @@ func (e *ContainerEdits) isEmpty() bool {
 	if e.IntelRdt != nil {
 		return false
 	}
+	if e.Capabilities != nil {
+		return false
+	}
 	return true
 }
 
@@ -264,25 +439,29 @@ type DeviceNode struct {
 
 // Validate a CDI Spec DeviceNode.
 func (d *DeviceNode) Validate() error {
-	validTypes := map[string]struct{}{
-		"":  {},
-		"b": {},
-		"c": {},
-		"u": {},
-		"p": {},
-	}
-
 	if d.Path == "" {
 		return errors.New("invalid (empty) device path")
 	}
-	if _, ok := validTypes[d.Type]; !ok {
+	if !IsValidDeviceType(d.Type) {
 		return fmt.Errorf("device %q: invalid type %q", d.Path, d.Type)
 	}
-	for _, bit := range d.Permissions {
-		if bit != 'r' && bit != 'w' && bit != 'm' {
-			return fmt.Errorf("device %q: invalid permissions %q",
-				d.Path, d.Permissions)
-		}
+	if !IsValidDevicePermissions(d.Permissions) {
+		return fmt.Errorf("device %q: invalid permissions %q",
+			d.Path, d.Permissions)
+	}
+	if d.DenyAccess && d.Permissions != cdi.DevicePermissionsNone {
+		return fmt.Errorf("device %q: denyAccess requires permissions %q",
+			d.Path, cdi.DevicePermissionsNone)
+	}
+	if d.FileMode != nil && *d.FileMode&^os.ModePerm != 0 {
+		return fmt.Errorf("device %q: invalid file mode %#o, only permission bits allowed",
+			d.Path, uint32(*d.FileMode))
+	}
+	if d.UID != nil && *d.UID == invalidID {
+		return fmt.Errorf("device %q: invalid uid %d", d.Path, *d.UID)
+	}
+	if d.GID != nil && *d.GID == invalidID {
+		return fmt.Errorf("device %q: invalid gid %d", d.Path, *d.GID)
 	}
 	return nil
 }
@@ -294,7 +473,7 @@ type Hook struct {
 
 // Validate a hook.
 func (h *Hook) Validate() error {
-	if _, ok := validHookNames[h.HookName]; !ok {
+	if !IsValidHookName(h.HookName) {
 		return fmt.Errorf("invalid hook name %q", h.HookName)
 	}
 	if h.Path == "" {
@@ -303,6 +482,28 @@ func (h *Hook) Validate() error {
 	if err := ValidateEnv(h.Env); err != nil {
 		return fmt.Errorf("invalid hook %q: %w", h.HookName, err)
 	}
+	if t := h.Timeout; t != nil && *t < 1 {
+		return fmt.Errorf("invalid hook %q: timeout %d not greater than zero",
+			h.HookName, *t)
+	}
+	return nil
+}
+
+// hasHookTimeoutLimit returns true if hook timeouts of the Spec are limited
+// to MaxHookTimeout, which depends only on the CDI version of the Spec.
+func (s *Spec) hasHookTimeoutLimit() bool {
+	return s != nil && s.Spec != nil &&
+		semver.Compare(semverOf(s.Version), semverOf(hookTimeoutLimitVersion)) >= 0
+}
+
+// validateHookTimeouts checks that no hook timeout exceeds MaxHookTimeout.
+func (e *ContainerEdits) validateHookTimeouts() error {
+	for _, h := range e.Hooks {
+		if t := h.Timeout; t != nil && *t > MaxHookTimeout {
+			return fmt.Errorf("invalid hook %q: timeout %d exceeds %d seconds",
+				h.HookName, *t, MaxHookTimeout)
+		}
+	}
 	return nil
 }
 
@@ -319,7 +520,7 @@ func (m *Mount) Validate() error {
 	if m.ContainerPath == "" {
 		return errors.New("invalid mount, empty container path")
 	}
-	return nil
+	return m.validateOptions()
 }
 
 // IntelRdt is a CDI IntelRdt wrapper.
@@ -344,6 +545,95 @@ func (i *IntelRdt) Validate() error {
 	return nil
 }
 
+// Capabilities is a CDI Capabilities wrapper, used for validating and
+// applying capability edits.
+type Capabilities struct {
+	*cdi.Capabilities
+}
+
+// Validate the capability edits. All capabilities must be known and
+// none of them can be both added and dropped.
+func (c *Capabilities) Validate() error {
+	dropped := map[string]struct{}{}
+	for _, name := range c.Drop {
+		if err := capsCheck.CapValid(name, false); err != nil {
+			return fmt.Errorf("invalid capability edits: %w", err)
+		}
+		dropped[name] = struct{}{}
+	}
+	for _, name := range c.Add {
+		if err := capsCheck.CapValid(name, false); err != nil {
+			return fmt.Errorf("invalid capability edits: %w", err)
+		}
+		if _, ok := dropped[name]; ok {
+			return fmt.Errorf("invalid capability edits: %s both added and dropped", name)
+		}
+	}
+	return nil
+}
+
+// apply the capability edits to the bounding, effective and permitted
+// sets of the OCI Spec process. Capabilities are dropped before any are
+// added, so a capability added by any device is granted even if another
+// one drops it.
+func (c *Capabilities) apply(specgen *ocigen.Generator) error {
+	for _, name := range c.Drop {
+		for _, drop := range []func(string) error{
+			specgen.DropProcessCapabilityBounding,
+			specgen.DropProcessCapabilityEffective,
+			specgen.DropProcessCapabilityPermitted,
+		} {
+			if err := drop(name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range c.Add {
+		for _, add := range []func(string) error{
+			specgen.AddProcessCapabilityBounding,
+			specgen.AddProcessCapabilityEffective,
+			specgen.AddProcessCapabilityPermitted,
+		} {
+			if err := add(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// appendCapabilities returns newly allocated capability edits with the
+// capabilities of o appended to the ones of e.
+func appendCapabilities(e, o *cdi.Capabilities) *cdi.Capabilities {
+	caps := &cdi.Capabilities{}
+	if e != nil {
+		caps.Add = append(caps.Add, e.Add...)
+		caps.Drop = append(caps.Drop, e.Drop...)
+	}
+	caps.Add = append(caps.Add, o.Add...)
+	caps.Drop = append(caps.Drop, o.Drop...)
+	return caps
+}
+
+// inheritedHookEnv returns the environment of a hook inheriting the
+// environment of the container process of the given OCI Spec. Variables
+// of the given hook environment take precedence.
+func inheritedHookEnv(spec *oci.Spec, hookEnv []string) []string {
+	var env []string
+	if spec.Process != nil {
+		set := map[string]struct{}{}
+		for _, v := range hookEnv {
+			set[envName(v)] = struct{}{}
+		}
+		for _, v := range spec.Process.Env {
+			if _, ok := set[envName(v)]; !ok {
+				env = append(env, v)
+			}
+		}
+	}
+	return append(env, hookEnv...)
+}
+
 // Ensure OCI Spec hooks are not nil so we can add hooks.
 func ensureOCIHooks(spec *oci.Spec) {
 	if spec.Hooks == nil {
@@ -353,9 +643,12 @@ func ensureOCIHooks(spec *oci.Spec) {
 
 // sortMounts sorts the mounts in the given OCI Spec.
 func sortMounts(specgen *ocigen.Generator) {
-	mounts := specgen.Mounts()
+	mounts := orderedMounts(specgen.Mounts())
+	if sort.IsSorted(mounts) {
+		return
+	}
 	specgen.ClearMounts()
-	sort.Stable(orderedMounts(mounts))
+	sort.Stable(mounts)
 	specgen.Config.Mounts = mounts
 }
 
@@ -385,5 +678,10 @@ func (m orderedMounts) Swap(i, j int) {
 
 // parts returns the number of parts in the destination of a mount. Used in sorting.
 func (m orderedMounts) parts(i int) int {
-	return strings.Count(filepath.Clean(m[i].Destination), string(os.PathSeparator))
+	return mountDepth(m[i].Destination)
+}
+
+// mountDepth returns the number of parts in the given mount destination.
+func mountDepth(path string) int {
+	return strings.Count(filepath.Clean(path), string(os.PathSeparator))
 }
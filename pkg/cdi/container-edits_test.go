@@ -17,6 +17,7 @@
 package cdi
 
 import (
+	"os"
 	"testing"
 
 	oci "github.com/opencontainers/runtime-spec/specs-go"
@@ -24,6 +25,10 @@ import (
 	cdi "tags.cncf.io/container-device-interface/specs-go"
 )
 
+func intPtr(v int) *int {
+	return &v
+}
+
 func TestValidateContainerEdits(t *testing.T) {
 	type testCase struct {
 		name    string
@@ -118,6 +123,59 @@ func TestValidateContainerEdits(t *testing.T) {
 			},
 			invalid: true,
 		},
+		{
+			name: "valid device, file mode and ownership",
+			edits: &cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path:     "/dev/vendorctl",
+						Type:     "c",
+						FileMode: fileModePtr(0o660),
+						UID:      uint32Ptr(1000),
+						GID:      uint32Ptr(0),
+					},
+				},
+			},
+		},
+		{
+			name: "invalid device, file mode with type bits",
+			edits: &cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path:     "/dev/vendorctl",
+						Type:     "c",
+						FileMode: fileModePtr(os.ModeDevice | 0o660),
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "invalid device, reserved uid",
+			edits: &cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path: "/dev/vendorctl",
+						Type: "c",
+						UID:  uint32Ptr(^uint32(0)),
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "invalid device, reserved gid",
+			edits: &cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path: "/dev/vendorctl",
+						Type: "c",
+						GID:  uint32Ptr(^uint32(0)),
+					},
+				},
+			},
+			invalid: true,
+		},
 		{
 			name: "invalid device, wrong permissions",
 			edits: &cdi.ContainerEdits{
@@ -131,6 +189,128 @@ func TestValidateContainerEdits(t *testing.T) {
 			},
 			invalid: true,
 		},
+		{
+			name: "valid device, no access",
+			edits: &cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path:        "/dev/vendorctl",
+						Type:        "b",
+						Permissions: cdi.DevicePermissionsNone,
+						DenyAccess:  true,
+					},
+				},
+			},
+		},
+		{
+			name: "invalid device, denied access with permissions",
+			edits: &cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path:        "/dev/vendorctl",
+						Type:        "b",
+						Permissions: "rw",
+						DenyAccess:  true,
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "valid mount, propagation",
+			edits: &cdi.ContainerEdits{
+				Mounts: []*cdi.Mount{
+					{
+						HostPath:      "/dev/vendorctl",
+						ContainerPath: "/dev/vendorctl",
+						Type:          "",
+						Options:       []string{"bind", "rshared"},
+					},
+				},
+			},
+		},
+		{
+			name: "valid mount, idmap",
+			edits: &cdi.ContainerEdits{
+				Mounts: []*cdi.Mount{
+					{
+						HostPath:      "/dev/vendorctl",
+						ContainerPath: "/dev/vendorctl",
+						Type:          "",
+						Options:       []string{"bind", "idmap"},
+					},
+				},
+			},
+		},
+		{
+			name: "valid mount, ridmap",
+			edits: &cdi.ContainerEdits{
+				Mounts: []*cdi.Mount{
+					{
+						HostPath:      "/dev/vendorctl",
+						ContainerPath: "/dev/vendorctl",
+						Type:          "none",
+						Options:       []string{"rbind", "ridmap", "rprivate"},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid mount, conflicting propagation",
+			edits: &cdi.ContainerEdits{
+				Mounts: []*cdi.Mount{
+					{
+						HostPath:      "/dev/vendorctl",
+						ContainerPath: "/dev/vendorctl",
+						Type:          "",
+						Options:       []string{"bind", "shared", "private"},
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "invalid mount, idmap and ridmap",
+			edits: &cdi.ContainerEdits{
+				Mounts: []*cdi.Mount{
+					{
+						HostPath:      "/dev/vendorctl",
+						ContainerPath: "/dev/vendorctl",
+						Type:          "",
+						Options:       []string{"bind", "idmap", "ridmap"},
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "invalid mount, idmap with explicit mappings",
+			edits: &cdi.ContainerEdits{
+				Mounts: []*cdi.Mount{
+					{
+						HostPath:      "/dev/vendorctl",
+						ContainerPath: "/dev/vendorctl",
+						Type:          "",
+						Options:       []string{"bind", "idmap=uids=0-1000-10"},
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "invalid mount, idmap on non-bind mount",
+			edits: &cdi.ContainerEdits{
+				Mounts: []*cdi.Mount{
+					{
+						HostPath:      "/dev/vendorctl",
+						ContainerPath: "/dev/vendorctl",
+						Type:          "tmpfs",
+						Options:       []string{"idmap"},
+					},
+				},
+			},
+			invalid: true,
+		},
 		{
 			name: "valid mount",
 			edits: &cdi.ContainerEdits{
@@ -289,9 +469,71 @@ func TestValidateContainerEdits(t *testing.T) {
 			},
 			invalid: true,
 		},
+		{
+			name: "valid hook timeout",
+			edits: &cdi.ContainerEdits{
+				Hooks: []*cdi.Hook{
+					{HookName: "prestart", Path: "/usr/bin/prestart", Timeout: intPtr(MaxHookTimeout)},
+				},
+			},
+		},
+		{
+			name: "invalid hook timeout, zero",
+			edits: &cdi.ContainerEdits{
+				Hooks: []*cdi.Hook{
+					{HookName: "prestart", Path: "/usr/bin/prestart", Timeout: intPtr(0)},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "valid hook timeout, limited by the Spec version",
+			edits: &cdi.ContainerEdits{
+				Hooks: []*cdi.Hook{
+					{HookName: "prestart", Path: "/usr/bin/prestart", Timeout: intPtr(MaxHookTimeout + 1)},
+				},
+			},
+		},
+		{
+			name: "valid capabilities",
+			edits: &cdi.ContainerEdits{
+				Capabilities: &cdi.Capabilities{
+					Add:  []string{"CAP_SYS_RAWIO", "CAP_IPC_LOCK"},
+					Drop: []string{"CAP_NET_RAW"},
+				},
+			},
+		},
+		{
+			name: "invalid capabilities, unknown capability",
+			edits: &cdi.ContainerEdits{
+				Capabilities: &cdi.Capabilities{
+					Add: []string{"CAP_FOO"},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "invalid capabilities, missing prefix",
+			edits: &cdi.ContainerEdits{
+				Capabilities: &cdi.Capabilities{
+					Drop: []string{"SYS_RAWIO"},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "invalid capabilities, added and dropped",
+			edits: &cdi.ContainerEdits{
+				Capabilities: &cdi.Capabilities{
+					Add:  []string{"CAP_SYS_RAWIO"},
+					Drop: []string{"CAP_SYS_RAWIO"},
+				},
+			},
+			invalid: true,
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
-			edits := ContainerEdits{tc.edits}
+			edits := ContainerEdits{ContainerEdits: tc.edits}
 			err := edits.Validate()
 			if tc.invalid {
 				require.Error(t, err)
@@ -302,6 +544,44 @@ func TestValidateContainerEdits(t *testing.T) {
 	}
 }
 
+func TestHookTimeoutLimit(t *testing.T) {
+	hooks := []*cdi.Hook{
+		{HookName: "prestart", Path: "/usr/bin/prestart", Timeout: intPtr(MaxHookTimeout + 1)},
+	}
+	for version, invalid := range map[string]bool{
+		"0.9.0":  false,
+		"0.10.0": true,
+	} {
+		t.Run(version, func(t *testing.T) {
+			for _, raw := range []*cdi.Spec{
+				{
+					Version:        version,
+					Kind:           "vendor.com/device",
+					ContainerEdits: cdi.ContainerEdits{Hooks: hooks},
+					Devices: []cdi.Device{
+						{Name: "dev0", ContainerEdits: cdi.ContainerEdits{Env: []string{"A=1"}}},
+					},
+				},
+				{
+					Version: version,
+					Kind:    "vendor.com/device",
+					Devices: []cdi.Device{
+						{Name: "dev0", ContainerEdits: cdi.ContainerEdits{Hooks: hooks}},
+					},
+				},
+			} {
+				_, err := newSpec(raw, "vendor.yaml", 0)
+				if invalid {
+					require.Error(t, err)
+					require.Contains(t, err.Error(), "exceeds")
+				} else {
+					require.NoError(t, err)
+				}
+			}
+		})
+	}
+}
+
 func TestApplyContainerEdits(t *testing.T) {
 	type testCase struct {
 		name   string
@@ -364,6 +644,66 @@ func TestApplyContainerEdits(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "empty spec, device without access",
+			spec: &oci.Spec{},
+			edits: &cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path:        "/dev/null",
+						Permissions: cdi.DevicePermissionsNone,
+					},
+				},
+			},
+			result: &oci.Spec{
+				Linux: &oci.Linux{
+					Devices: []oci.LinuxDevice{
+						{
+							Path:  "/dev/null",
+							Type:  "c",
+							Major: 1,
+							Minor: 3,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "empty spec, device with denied access",
+			spec: &oci.Spec{},
+			edits: &cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{
+					{
+						Path:        "/dev/null",
+						Permissions: cdi.DevicePermissionsNone,
+						DenyAccess:  true,
+					},
+				},
+			},
+			result: &oci.Spec{
+				Linux: &oci.Linux{
+					Devices: []oci.LinuxDevice{
+						{
+							Path:  "/dev/null",
+							Type:  "c",
+							Major: 1,
+							Minor: 3,
+						},
+					},
+					Resources: &oci.LinuxResources{
+						Devices: []oci.LinuxDeviceCgroup{
+							{
+								Allow:  false,
+								Type:   "c",
+								Major:  int64ptr(1),
+								Minor:  int64ptr(3),
+								Access: "rwm",
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "empty spec, device, env var",
 			spec: &oci.Spec{},
@@ -599,6 +939,75 @@ func TestApplyContainerEdits(t *testing.T) {
 			},
 			result: &oci.Spec{},
 		},
+		{
+			name: "hook inheriting the environment",
+			spec: &oci.Spec{
+				Process: &oci.Process{Env: []string{"PATH=/bin", "HOOK=container"}},
+			},
+			edits: &cdi.ContainerEdits{
+				Env: []string{"VENDOR=1"},
+				Hooks: []*cdi.Hook{
+					{HookName: "createContainer", Path: "/bin/hook", Env: []string{"HOOK=1"}, InheritEnv: true},
+					{HookName: "createContainer", Path: "/bin/other", Env: []string{"HOOK=2"}},
+				},
+			},
+			result: &oci.Spec{
+				Process: &oci.Process{Env: []string{"PATH=/bin", "HOOK=container", "VENDOR=1"}},
+				Hooks: &oci.Hooks{
+					CreateContainer: []oci.Hook{
+						{Path: "/bin/hook", Env: []string{"PATH=/bin", "VENDOR=1", "HOOK=1"}},
+						{Path: "/bin/other", Env: []string{"HOOK=2"}},
+					},
+				},
+			},
+		},
+		{
+			name: "capabilities are added",
+			spec: &oci.Spec{},
+			edits: &cdi.ContainerEdits{
+				Capabilities: &cdi.Capabilities{
+					Add: []string{"CAP_SYS_RAWIO"},
+				},
+			},
+			result: &oci.Spec{
+				Process: &oci.Process{
+					Capabilities: &oci.LinuxCapabilities{
+						Bounding:  []string{"CAP_SYS_RAWIO"},
+						Effective: []string{"CAP_SYS_RAWIO"},
+						Permitted: []string{"CAP_SYS_RAWIO"},
+					},
+				},
+			},
+		},
+		{
+			name: "capabilities are added and dropped",
+			spec: &oci.Spec{
+				Process: &oci.Process{
+					Capabilities: &oci.LinuxCapabilities{
+						Bounding:    []string{"CAP_CHOWN", "CAP_NET_RAW", "CAP_SYS_RAWIO"},
+						Effective:   []string{"CAP_CHOWN", "CAP_NET_RAW"},
+						Permitted:   []string{"CAP_CHOWN", "CAP_NET_RAW"},
+						Inheritable: []string{"CAP_NET_RAW"},
+					},
+				},
+			},
+			edits: &cdi.ContainerEdits{
+				Capabilities: &cdi.Capabilities{
+					Add:  []string{"CAP_SYS_RAWIO", "CAP_IPC_LOCK"},
+					Drop: []string{"CAP_NET_RAW"},
+				},
+			},
+			result: &oci.Spec{
+				Process: &oci.Process{
+					Capabilities: &oci.LinuxCapabilities{
+						Bounding:    []string{"CAP_CHOWN", "CAP_SYS_RAWIO", "CAP_IPC_LOCK"},
+						Effective:   []string{"CAP_CHOWN", "CAP_SYS_RAWIO", "CAP_IPC_LOCK"},
+						Permitted:   []string{"CAP_CHOWN", "CAP_SYS_RAWIO", "CAP_IPC_LOCK"},
+						Inheritable: []string{"CAP_NET_RAW"},
+					},
+				},
+			},
+		},
 		{
 			name: "apply mount edits do not change the order of original mounts",
 			spec: &oci.Spec{
@@ -679,7 +1088,7 @@ func TestApplyContainerEdits(t *testing.T) {
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
-			edits := ContainerEdits{tc.edits}
+			edits := ContainerEdits{ContainerEdits: tc.edits}
 			err := edits.Validate()
 			require.NoError(t, err)
 			err = edits.Apply(tc.spec)
@@ -862,6 +1271,41 @@ func TestAppend(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "merge capabilities",
+			dst:  nil,
+			src: []*ContainerEdits{
+				{
+					ContainerEdits: &cdi.ContainerEdits{
+						Capabilities: &cdi.Capabilities{
+							Add: []string{"CAP_SYS_RAWIO"},
+						},
+					},
+				},
+				{
+					ContainerEdits: &cdi.ContainerEdits{
+						Env: []string{"var1=val1"},
+					},
+				},
+				{
+					ContainerEdits: &cdi.ContainerEdits{
+						Capabilities: &cdi.Capabilities{
+							Add:  []string{"CAP_IPC_LOCK"},
+							Drop: []string{"CAP_NET_RAW"},
+						},
+					},
+				},
+			},
+			result: &ContainerEdits{
+				ContainerEdits: &cdi.ContainerEdits{
+					Env: []string{"var1=val1"},
+					Capabilities: &cdi.Capabilities{
+						Add:  []string{"CAP_SYS_RAWIO", "CAP_IPC_LOCK"},
+						Drop: []string{"CAP_NET_RAW"},
+					},
+				},
+			},
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			dst := tc.dst
@@ -872,3 +1316,31 @@ func TestAppend(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyAppendedCapabilities(t *testing.T) {
+	var edits *ContainerEdits
+	edits = edits.Append(&ContainerEdits{
+		ContainerEdits: &cdi.ContainerEdits{
+			Capabilities: &cdi.Capabilities{Add: []string{"CAP_SYS_RAWIO"}},
+		},
+	})
+	edits = edits.Append(&ContainerEdits{
+		ContainerEdits: &cdi.ContainerEdits{
+			Capabilities: &cdi.Capabilities{Drop: []string{"CAP_SYS_RAWIO", "CAP_NET_RAW"}},
+		},
+	})
+
+	spec := &oci.Spec{
+		Process: &oci.Process{
+			Capabilities: &oci.LinuxCapabilities{
+				Bounding: []string{"CAP_NET_RAW"},
+			},
+		},
+	}
+	require.NoError(t, edits.Apply(spec))
+	require.Equal(t, &oci.LinuxCapabilities{
+		Bounding:  []string{"CAP_SYS_RAWIO"},
+		Effective: []string{"CAP_SYS_RAWIO"},
+		Permitted: []string{"CAP_SYS_RAWIO"},
+	}, spec.Process.Capabilities)
+}
@@ -20,8 +20,7 @@
 package cdi
 
 import (
-	"errors"
-	"fmt"
+	"os"
 
 	"golang.org/x/sys/unix"
 )
@@ -33,14 +32,17 @@ const (
 )
 
 // deviceInfoFromPath takes the path to a device and returns its type,
-// major and minor device numbers.
+// major and minor device numbers, permissions and ownership.
 //
 // It was adapted from https://github.com/opencontainers/runc/blob/v1.1.9/libcontainer/devices/device_unix.go#L30-L69
-func deviceInfoFromPath(path string) (devType string, major, minor int64, _ error) {
-	var stat unix.Stat_t
+func deviceInfoFromPath(path string) (*DeviceInfo, error) {
+	var (
+		stat    unix.Stat_t
+		devType string
+	)
 	err := unix.Lstat(path, &stat)
 	if err != nil {
-		return "", 0, 0, err
+		return nil, err
 	}
 	switch stat.Mode & unix.S_IFMT {
 	case unix.S_IFBLK:
@@ -50,39 +52,15 @@ func deviceInfoFromPath(path string) (devType string, major, minor int64, _ erro
 	case unix.S_IFIFO:
 		devType = fifoDevice
 	default:
-		return "", 0, 0, errors.New("not a device node")
+		return nil, ErrNotDeviceNode
 	}
 	devNumber := uint64(stat.Rdev) //nolint:unconvert // Rdev is uint32 on e.g. MIPS.
-	return devType, int64(unix.Major(devNumber)), int64(unix.Minor(devNumber)), nil
-}
-
-// fillMissingInfo fills in missing mandatory attributes from the host device.
-func (d *DeviceNode) fillMissingInfo() error {
-	if d.HostPath == "" {
-		d.HostPath = d.Path
-	}
-
-	if d.Type != "" && (d.Major != 0 || d.Type == "p") {
-		return nil
-	}
-
-	deviceType, major, minor, err := deviceInfoFromPath(d.HostPath)
-	if err != nil {
-		return fmt.Errorf("failed to stat CDI host device %q: %w", d.HostPath, err)
-	}
-
-	if d.Type == "" {
-		d.Type = deviceType
-	} else {
-		if d.Type != deviceType {
-			return fmt.Errorf("CDI device (%q, %q), host type mismatch (%s, %s)",
-				d.Path, d.HostPath, d.Type, deviceType)
-		}
-	}
-	if d.Major == 0 && d.Type != "p" {
-		d.Major = major
-		d.Minor = minor
-	}
-
-	return nil
+	return &DeviceInfo{
+		Type:     devType,
+		Major:    int64(unix.Major(devNumber)),
+		Minor:    int64(unix.Minor(devNumber)),
+		FileMode: os.FileMode(stat.Mode) & os.ModePerm,
+		UID:      stat.Uid,
+		GID:      stat.Gid,
+	}, nil
 }
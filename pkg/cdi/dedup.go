@@ -0,0 +1,105 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// WithEditDeduplication returns an option to control whether injection
+// skips mounts and device nodes identical to ones already present in the
+// OCI Spec. Runtimes retrying a failed injection may inject the same
+// devices into the same OCI Spec more than once. With deduplication
+// enabled, which is the default, this does not result in duplicate mount,
+// device node or device cgroup entries.
+func WithEditDeduplication(enable bool) Option {
+	return func(c *Cache) {
+		c.noDeduplication = !enable
+	}
+}
+
+// withDeduplication returns the edits with deduplication enabled or
+// disabled.
+func (e *ContainerEdits) withDeduplication(enable bool) *ContainerEdits {
+	if e != nil {
+		e.noDedup = !enable
+	}
+	return e
+}
+
+// hasDevice returns true if the OCI Spec already has a device node with
+// the same path, type, major and minor numbers as the given one.
+func hasDevice(spec *oci.Spec, dev *oci.LinuxDevice) bool {
+	if spec.Linux == nil {
+		return false
+	}
+	for _, d := range spec.Linux.Devices {
+		if d.Path == dev.Path && d.Type == dev.Type &&
+			d.Major == dev.Major && d.Minor == dev.Minor {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDeviceRule returns true if the OCI Spec already has an identical
+// device cgroup rule.
+func hasDeviceRule(spec *oci.Spec, rule oci.LinuxDeviceCgroup) bool {
+	if spec.Linux == nil || spec.Linux.Resources == nil {
+		return false
+	}
+	for _, r := range spec.Linux.Resources.Devices {
+		if r.Allow == rule.Allow && r.Type == rule.Type && r.Access == rule.Access &&
+			equalID(r.Major, rule.Major) && equalID(r.Minor, rule.Minor) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalID returns true if the given optional device numbers are identical.
+func equalID(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// hasMount returns true if the OCI Spec already has a mount with the same
+// source, destination, type and options as the given one.
+func hasMount(spec *oci.Spec, mnt oci.Mount) bool {
+	for _, m := range spec.Mounts {
+		if m.Source == mnt.Source && m.Destination == mnt.Destination &&
+			m.Type == mnt.Type && equalOptions(m.Options, mnt.Options) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalOptions returns true if the given mount options are identical.
+func equalOptions(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestEditDeduplication(t *testing.T) {
+	spec := &cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/dev",
+		Devices: []cdi.Device{
+			{
+				Name: "dev0",
+				ContainerEdits: cdi.ContainerEdits{
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/vendor0", Type: "c", Major: 240, Minor: 0},
+					},
+					Mounts: []*cdi.Mount{
+						{
+							HostPath:      "/usr/lib/vendor",
+							ContainerPath: "/usr/lib/vendor",
+							Options:       []string{"ro", "bind"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		enable  bool
+		devices int
+		rules   int
+		mounts  int
+	}{
+		{
+			name:    "enabled",
+			enable:  true,
+			devices: 1,
+			rules:   1,
+			mounts:  1,
+		},
+		{
+			name:    "disabled",
+			enable:  false,
+			devices: 1,
+			rules:   2,
+			mounts:  1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := newCache(
+				WithSpecDirs(),
+				WithAutoRefresh(false),
+				WithEditDeduplication(tc.enable),
+			)
+			require.NoError(t, cache.AddSpec(spec, 0))
+
+			ociSpec := &oci.Spec{}
+			for i := 0; i < 2; i++ {
+				_, err := cache.InjectDevices(ociSpec, "vendor.com/dev=dev0")
+				require.NoError(t, err)
+			}
+			require.Len(t, ociSpec.Linux.Devices, tc.devices)
+			require.Len(t, ociSpec.Linux.Resources.Devices, tc.rules)
+			require.Len(t, ociSpec.Mounts, tc.mounts)
+		})
+	}
+}
+
+func TestEditDeduplicationKeepsOrder(t *testing.T) {
+	ociSpec := &oci.Spec{
+		Mounts: []oci.Mount{
+			{Source: "/usr/lib/vendor", Destination: "/usr/lib/vendor", Options: []string{"ro", "bind"}},
+			{Source: "/data", Destination: "/data"},
+		},
+		Linux: &oci.Linux{
+			Devices: []oci.LinuxDevice{
+				{Path: "/dev/vendor0", Type: "c", Major: 240, Minor: 0},
+				{Path: "/dev/null", Type: "c", Major: 1, Minor: 3},
+			},
+		},
+	}
+	edits := &ContainerEdits{
+		ContainerEdits: &cdi.ContainerEdits{
+			DeviceNodes: []*cdi.DeviceNode{
+				{Path: "/dev/vendor0", Type: "c", Major: 240, Minor: 0},
+			},
+			Mounts: []*cdi.Mount{
+				{
+					HostPath:      "/usr/lib/vendor",
+					ContainerPath: "/usr/lib/vendor",
+					Options:       []string{"ro", "bind"},
+				},
+			},
+		},
+	}
+
+	require.NoError(t, edits.Apply(ociSpec))
+	require.Equal(t, "/dev/vendor0", ociSpec.Linux.Devices[0].Path)
+	require.Len(t, ociSpec.Linux.Devices, 2)
+	require.Len(t, ociSpec.Mounts, 2)
+
+	edits.Mounts[0].Options = []string{"rw", "bind"}
+	require.NoError(t, edits.Apply(ociSpec))
+	require.Len(t, ociSpec.Mounts, 2)
+	for _, m := range ociSpec.Mounts {
+		if m.Destination == "/usr/lib/vendor" {
+			require.Equal(t, []string{"rw", "bind"}, m.Options)
+		}
+	}
+}
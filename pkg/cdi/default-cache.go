@@ -17,33 +17,66 @@
 package cdi
 
 import (
+	"context"
 	"sync"
 
 	oci "github.com/opencontainers/runtime-spec/specs-go"
 )
 
 var (
-	defaultCache   *Cache
-	getDefaultOnce sync.Once
+	defaultLock  sync.Mutex
+	defaultCache *Cache
 )
 
 func getOrCreateDefaultCache(options ...Option) (*Cache, bool) {
-	var created bool
-	getDefaultOnce.Do(func() {
-		defaultCache = newCache(options...)
-		created = true
-	})
-	return defaultCache, created
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+
+	if defaultCache != nil {
+		return defaultCache, false
+	}
+	if fn := getSpecValidator(); fn != nil {
+		options = append([]Option{WithSpecValidator(fn)}, options...)
+	}
+	defaultCache = newCache(options...)
+	return defaultCache, true
 }
 
-// GetDefaultCache returns the default CDI cache instance.
+// GetDefaultCache returns the default CDI cache instance. The default
+// cache is created with default options on first use, unless one has
+// been set using SetDefaultCache. A default cache created on first use
+// validates Specs using any validator set by SetSpecValidator().
 func GetDefaultCache() *Cache {
 	cache, _ := getOrCreateDefaultCache()
 	return cache
 }
 
+// SetDefaultCache sets the default CDI cache instance used by the package
+// level functions, returning the previous one, or nil if none had been
+// created yet. Setting a nil cache causes a new default cache to be
+// created on next use. The previous cache is left intact, and can be
+// closed using Close if it is no longer needed.
+//
+// Callers which do not need a process-wide cache, like tests running in
+// parallel or embedders serving several tenants, should instead use Caches
+// created with NewCache. These are fully isolated from each other and
+// from the default cache, each with its own Spec directory watch.
+func SetDefaultCache(cache *Cache) *Cache {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+
+	prev := defaultCache
+	defaultCache = cache
+	return prev
+}
+
 // Configure applies options to the default CDI cache. Updates and refreshes
-// the default cache if options are not empty.
+// the default cache if options are not empty. If the default cache does
+// not exist yet, it is created with the given options.
+//
+// Deprecated: Configure implicitly creates and reconfigures process-wide
+// state. Create a Cache with NewCache instead, and if a default cache is
+// needed, install it with SetDefaultCache.
 func Configure(options ...Option) error {
 	cache, created := getOrCreateDefaultCache(options...)
 	if len(options) == 0 || created {
@@ -57,12 +90,32 @@ func Refresh() error {
 	return GetDefaultCache().Refresh()
 }
 
+// RefreshContext explicitly refreshes the default CDI cache instance,
+// honoring cancellation and deadlines of the given context.
+func RefreshContext(ctx context.Context) error {
+	return GetDefaultCache().RefreshContext(ctx)
+}
+
 // InjectDevices injects the given qualified devices to the given OCI Spec.
 // using the default CDI cache instance to resolve devices.
 func InjectDevices(ociSpec *oci.Spec, devices ...string) ([]string, error) {
 	return GetDefaultCache().InjectDevices(ociSpec, devices...)
 }
 
+// InjectDevicesContext injects the given qualified devices to the given
+// OCI Spec using the default CDI cache instance to resolve devices,
+// honoring cancellation and deadlines of the given context.
+func InjectDevicesContext(ctx context.Context, ociSpec *oci.Spec, devices ...string) ([]string, error) {
+	return GetDefaultCache().InjectDevicesContext(ctx, ociSpec, devices...)
+}
+
+// RenderDevice returns an OCI Spec fragment with the edits injecting the
+// given device into a container, using the default CDI cache instance to
+// resolve the device.
+func RenderDevice(device string) (*oci.Spec, error) {
+	return GetDefaultCache().RenderDevice(device)
+}
+
 // GetErrors returns all errors encountered during the last refresh of
 // the default CDI cache instance.
 func GetErrors() map[string][]error {
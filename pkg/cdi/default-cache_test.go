@@ -418,3 +418,71 @@ devices:
 		})
 	}
 }
+
+func TestSetDefaultCache(t *testing.T) {
+	dir, err := createSpecDirs(t, nil, map[string]string{
+		"vendor1.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR1=dev1"
+`,
+	})
+	require.NoError(t, err)
+
+	cache, err := NewCache(
+		WithAutoRefresh(false),
+		WithSpecDirs(filepath.Join(dir, "etc"), filepath.Join(dir, "run")),
+	)
+	require.NoError(t, err)
+
+	prev := SetDefaultCache(cache)
+	t.Cleanup(func() {
+		SetDefaultCache(prev)
+	})
+
+	require.Same(t, cache, GetDefaultCache())
+	ociSpec := &oci.Spec{}
+	unresolved, err := InjectDevices(ociSpec, "vendor1.com/device=dev1")
+	require.NoError(t, err)
+	require.Nil(t, unresolved)
+	require.Equal(t, []string{"VENDOR1=dev1"}, ociSpec.Process.Env)
+
+	require.Same(t, cache, SetDefaultCache(nil))
+	other := GetDefaultCache()
+	require.NotNil(t, other)
+	require.NotSame(t, cache, other)
+	require.NoError(t, other.Close())
+}
+
+func TestCacheClose(t *testing.T) {
+	dir, err := createSpecDirs(t, nil, nil)
+	require.NoError(t, err)
+
+	cache, err := NewCache(
+		WithSpecDirs(filepath.Join(dir, "etc"), filepath.Join(dir, "run")),
+	)
+	require.NoError(t, err)
+	require.NoError(t, cache.Close())
+	require.NoError(t, cache.Close())
+
+	require.NoError(t, updateSpecDirs(dir, nil, map[string]string{
+		"vendor1.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR1=dev1"
+`,
+	}))
+	time.Sleep(10 * time.Millisecond)
+	require.Empty(t, cache.ListDevices())
+
+	require.NoError(t, cache.Refresh())
+	require.Equal(t, []string{"vendor1.com/device=dev1"}, cache.ListDevices())
+}
@@ -0,0 +1,183 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// ErrDeviceCapacityExhausted is returned by Claim() if a device is already
+// used by as many consumers as its capacity allows.
+var ErrDeviceCapacityExhausted = errors.New("device capacity exhausted")
+
+// DeviceClaim is a claim of a consumer, for instance a container, on a
+// shared device.
+type DeviceClaim struct {
+	// Device is the qualified name of the claimed device.
+	Device string
+	// Consumer is the ID of the consumer.
+	Consumer string
+	// Partition is the partition of the device assigned to the consumer,
+	// or -1 if the device is not partitioned.
+	Partition int
+}
+
+// GetCapacity returns the capacity of the device, or nil if the device
+// does not declare one.
+func (d *Device) GetCapacity() *cdi.DeviceCapacity {
+	return d.Capacity
+}
+
+// validateCapacity validates the capacity of the device.
+func (d *Device) validateCapacity() error {
+	c := d.Capacity
+	if c == nil {
+		return nil
+	}
+	if c.MaxConsumers < 0 {
+		return fmt.Errorf("invalid device %q, negative maxConsumers %d", d.Name, c.MaxConsumers)
+	}
+	if c.Partitions < 0 {
+		return fmt.Errorf("invalid device %q, negative partitions %d", d.Name, c.Partitions)
+	}
+	return nil
+}
+
+// Claim records a claim of the given consumer on a device. Claiming a
+// device is only necessary for devices shared by several consumers, like
+// an SR-IOV physical function or a partitioned GPU. Claims are limited by
+// the capacity of the device: a device can be claimed by at most as many
+// consumers as its maxConsumers or partitions, whichever is smaller, and
+// every consumer of a partitioned device is assigned a partition of its
+// own. Devices which do not declare a capacity can be claimed by any
+// number of consumers. Claiming a device again by the same consumer
+// returns the existing claim. If the capacity of the device is exhausted
+// an error wrapping ErrDeviceCapacityExhausted is returned.
+//
+// Claims are kept in memory by the Cache and survive refreshes. It is up
+// to the caller to release them using Release() once the consumer stops
+// using the device.
+func (c *Cache) Claim(device, consumerID string) (*DeviceClaim, error) {
+	if consumerID == "" {
+		return nil, errors.New("can't claim device for empty consumer ID")
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	_, _ = c.refreshIfRequired(false) // we record but ignore errors
+
+	key := c.deviceKey(device)
+	dev, ok := c.devices[key]
+	if !ok {
+		return nil, fmt.Errorf("can't claim unresolvable CDI device %q", device)
+	}
+
+	claims := c.claims[key]
+	for _, claim := range claims {
+		if claim.Consumer == consumerID {
+			cp := *claim
+			return &cp, nil
+		}
+	}
+
+	claim := &DeviceClaim{
+		Device:    dev.GetQualifiedName(),
+		Consumer:  consumerID,
+		Partition: -1,
+	}
+
+	if capacity := dev.GetCapacity(); capacity != nil {
+		limit := capacity.MaxConsumers
+		if p := capacity.Partitions; p > 0 && (limit == 0 || p < limit) {
+			limit = p
+		}
+		if limit > 0 && len(claims) >= limit {
+			return nil, fmt.Errorf("can't claim CDI device %q for %q: %w",
+				claim.Device, consumerID, ErrDeviceCapacityExhausted)
+		}
+		if capacity.Partitions > 0 {
+			claim.Partition = freePartition(claims)
+		}
+	}
+
+	if c.claims == nil {
+		c.claims = make(map[string][]*DeviceClaim)
+	}
+	c.claims[key] = append(claims, claim)
+
+	cp := *claim
+	return &cp, nil
+}
+
+// Release releases the claim of the given consumer on a device. Releasing
+// a device which is not claimed by the consumer is not an error.
+func (c *Cache) Release(device, consumerID string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	key := c.deviceKey(device)
+	claims := c.claims[key]
+	for i, claim := range claims {
+		if claim.Consumer == consumerID {
+			claims = append(claims[:i:i], claims[i+1:]...)
+			break
+		}
+	}
+
+	if len(claims) == 0 {
+		delete(c.claims, key)
+	} else {
+		c.claims[key] = claims
+	}
+
+	return nil
+}
+
+// GetClaims returns the claims on the given device, sorted by consumer ID.
+func (c *Cache) GetClaims(device string) []*DeviceClaim {
+	c.RLock()
+	defer c.RUnlock()
+
+	var claims []*DeviceClaim
+	for _, claim := range c.claims[c.deviceKey(device)] {
+		cp := *claim
+		claims = append(claims, &cp)
+	}
+	sort.Slice(claims, func(i, j int) bool {
+		return claims[i].Consumer < claims[j].Consumer
+	})
+
+	return claims
+}
+
+// freePartition returns the lowest partition not assigned by any claim.
+func freePartition(claims []*DeviceClaim) int {
+	used := make(map[int]struct{}, len(claims))
+	for _, claim := range claims {
+		used[claim.Partition] = struct{}{}
+	}
+	for p := 0; ; p++ {
+		if _, ok := used[p]; !ok {
+			return p
+		}
+	}
+}
@@ -0,0 +1,111 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestDeviceClaims(t *testing.T) {
+	device := func(name string, capacity *cdi.DeviceCapacity) cdi.Device {
+		return cdi.Device{
+			Name: name,
+			ContainerEdits: cdi.ContainerEdits{
+				Env: []string{"DEVICE=" + name},
+			},
+			Capacity: capacity,
+		}
+	}
+
+	cache := newCache(WithSpecDirs(), WithAutoRefresh(false))
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/dev",
+		Devices: []cdi.Device{
+			device("shared", &cdi.DeviceCapacity{MaxConsumers: 2}),
+			device("partitioned", &cdi.DeviceCapacity{MaxConsumers: 4, Partitions: 2}),
+			device("unlimited", nil),
+		},
+	}, 0))
+
+	// limited number of consumers
+	claim, err := cache.Claim("vendor.com/dev=shared", "ctr0")
+	require.NoError(t, err)
+	require.Equal(t, &DeviceClaim{Device: "vendor.com/dev=shared", Consumer: "ctr0", Partition: -1}, claim)
+	_, err = cache.Claim("vendor.com/dev=shared", "ctr1")
+	require.NoError(t, err)
+	_, err = cache.Claim("vendor.com/dev=shared", "ctr0")
+	require.NoError(t, err)
+	_, err = cache.Claim("vendor.com/dev=shared", "ctr2")
+	require.True(t, errors.Is(err, ErrDeviceCapacityExhausted))
+	require.NoError(t, cache.Release("vendor.com/dev=shared", "ctr0"))
+	_, err = cache.Claim("vendor.com/dev=shared", "ctr2")
+	require.NoError(t, err)
+	require.Len(t, cache.GetClaims("vendor.com/dev=shared"), 2)
+
+	// partitions are assigned to consumers
+	claim, err = cache.Claim("vendor.com/dev=partitioned", "ctr0")
+	require.NoError(t, err)
+	require.Equal(t, 0, claim.Partition)
+	claim, err = cache.Claim("vendor.com/dev=partitioned", "ctr1")
+	require.NoError(t, err)
+	require.Equal(t, 1, claim.Partition)
+	_, err = cache.Claim("vendor.com/dev=partitioned", "ctr2")
+	require.True(t, errors.Is(err, ErrDeviceCapacityExhausted))
+	require.NoError(t, cache.Release("vendor.com/dev=partitioned", "ctr0"))
+	claim, err = cache.Claim("vendor.com/dev=partitioned", "ctr2")
+	require.NoError(t, err)
+	require.Equal(t, 0, claim.Partition)
+
+	// devices without capacity can be claimed by any number of consumers
+	for _, id := range []string{"ctr0", "ctr1", "ctr2", "ctr3"} {
+		_, err = cache.Claim("vendor.com/dev=unlimited", id)
+		require.NoError(t, err)
+	}
+
+	// claims survive refreshes
+	require.NoError(t, cache.Refresh())
+	require.Len(t, cache.GetClaims("vendor.com/dev=unlimited"), 4)
+
+	_, err = cache.Claim("vendor.com/dev=unknown", "ctr0")
+	require.Error(t, err)
+	_, err = cache.Claim("vendor.com/dev=shared", "")
+	require.Error(t, err)
+	require.NoError(t, cache.Release("vendor.com/dev=unknown", "ctr0"))
+}
+
+func TestDeviceCapacityValidate(t *testing.T) {
+	for _, capacity := range []*cdi.DeviceCapacity{
+		{MaxConsumers: -1},
+		{Partitions: -1},
+	} {
+		dev := &Device{
+			Device: &cdi.Device{
+				Name: "dev",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"FOO=BAR"},
+				},
+				Capacity: capacity,
+			},
+		}
+		require.Error(t, dev.validate())
+	}
+}
@@ -0,0 +1,38 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+// WithDeviceCgroupRules returns an option to control whether injection
+// adds device cgroup rules for injected block and character devices. By
+// default, a rule granting the permissions of the device node is added
+// for each of them. Runtimes which manage device cgroups themselves can
+// disable this to only get the device nodes, together with the rest of
+// the edits, injected.
+func WithDeviceCgroupRules(enable bool) Option {
+	return func(c *Cache) {
+		c.noDeviceCgroupRules = !enable
+	}
+}
+
+// withDeviceCgroupRules returns the edits with device cgroup rule
+// generation enabled or disabled.
+func (e *ContainerEdits) withDeviceCgroupRules(enable bool) *ContainerEdits {
+	if e != nil {
+		e.noCgroupRules = !enable
+	}
+	return e
+}
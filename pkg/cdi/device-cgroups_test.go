@@ -0,0 +1,82 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestDeviceCgroupRules(t *testing.T) {
+	spec := &cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/dev",
+		Devices: []cdi.Device{
+			{
+				Name: "dev0",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"VENDOR_DEV=dev0"},
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/vendor0", Type: "c", Major: 240, Minor: 0},
+						{Path: "/dev/vendor-blk0", Type: "b", Major: 241, Minor: 0},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		name   string
+		enable bool
+		rules  int
+	}{
+		{
+			name:   "enabled",
+			enable: true,
+			rules:  2,
+		},
+		{
+			name:   "disabled",
+			enable: false,
+			rules:  0,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := newCache(
+				WithSpecDirs(),
+				WithAutoRefresh(false),
+				WithDeviceCgroupRules(tc.enable),
+			)
+			require.NoError(t, cache.AddSpec(spec, 0))
+
+			ociSpec := &oci.Spec{}
+			_, err := cache.InjectDevices(ociSpec, "vendor.com/dev=dev0")
+			require.NoError(t, err)
+			require.Len(t, ociSpec.Linux.Devices, 2)
+			require.Equal(t, []string{"VENDOR_DEV=dev0"}, ociSpec.Process.Env)
+
+			var rules []oci.LinuxDeviceCgroup
+			if ociSpec.Linux.Resources != nil {
+				rules = ociSpec.Linux.Resources.Devices
+			}
+			require.Len(t, rules, tc.rules)
+		})
+	}
+}
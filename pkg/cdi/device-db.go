@@ -0,0 +1,59 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// DeviceResolver is the interface for looking up CDI devices and injecting
+// them into OCI Specs. It is implemented by Cache. Consumers which only
+// need to resolve devices should depend on DeviceResolver instead of Cache,
+// so that alternative backends, like a remote cache or a test fake, can be
+// plugged in.
+type DeviceResolver interface {
+	// InjectDevices injects the given qualified devices into the OCI Spec.
+	// It returns any unresolved devices and an error if injection fails.
+	InjectDevices(ociSpec *oci.Spec, devices ...string) (unresolved []string, err error)
+	// GetDevice returns the device with the given qualified name, or nil
+	// if there is no such device.
+	GetDevice(device string) *Device
+	// FindDevice looks up a device by qualified or unqualified name.
+	FindDevice(name string) (*Device, error)
+	// ListDevices lists all devices by qualified name.
+	ListDevices() []string
+}
+
+// SpecProvider is the interface for querying CDI Specs. It is implemented
+// by Cache. Like DeviceResolver, it decouples consumers from the Cache.
+type SpecProvider interface {
+	// ListVendors lists all vendors with Specs.
+	ListVendors() []string
+	// ListClasses lists all device classes with Specs.
+	ListClasses() []string
+	// GetVendorSpecs returns all Specs of the given vendor.
+	GetVendorSpecs(vendor string) []*Spec
+	// GetSpecErrors returns the errors encountered for the given Spec.
+	GetSpecErrors(spec *Spec) []error
+	// GetErrors returns all errors encountered, by Spec file path.
+	GetErrors() map[string][]error
+}
+
+var (
+	_ DeviceResolver = &Cache{}
+	_ SpecProvider   = &Cache{}
+)
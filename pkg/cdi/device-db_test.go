@@ -0,0 +1,66 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestCacheInterfaces(t *testing.T) {
+	cache := newCache(
+		WithSpecDirs(),
+		WithAutoRefresh(false),
+	)
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/device",
+		Devices: []cdi.Device{
+			{
+				Name:           "dev0",
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"DEV0=1"}},
+			},
+		},
+	}, 0))
+
+	var (
+		resolver DeviceResolver = cache
+		provider SpecProvider   = cache
+	)
+
+	require.Equal(t, []string{"vendor.com/device=dev0"}, resolver.ListDevices())
+	require.NotNil(t, resolver.GetDevice("vendor.com/device=dev0"))
+	dev, err := resolver.FindDevice("dev0")
+	require.NoError(t, err)
+	require.Equal(t, "vendor.com/device=dev0", dev.GetQualifiedName())
+
+	ociSpec := &oci.Spec{}
+	unresolved, err := resolver.InjectDevices(ociSpec, "vendor.com/device=dev0")
+	require.NoError(t, err)
+	require.Nil(t, unresolved)
+	require.Equal(t, []string{"DEV0=1"}, ociSpec.Process.Env)
+
+	require.Equal(t, []string{"vendor.com"}, provider.ListVendors())
+	require.Equal(t, []string{"device"}, provider.ListClasses())
+	specs := provider.GetVendorSpecs("vendor.com")
+	require.Len(t, specs, 1)
+	require.Empty(t, provider.GetSpecErrors(specs[0]))
+	require.Empty(t, provider.GetErrors())
+}
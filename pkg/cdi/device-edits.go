@@ -0,0 +1,62 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+)
+
+// GetDeviceEdits returns the container edits the injection of the given
+// devices would apply, without an OCI Spec. Devices are resolved exactly
+// as by InjectDevices(): device groups and patterns are expanded, edits
+// are collected in the configured EditOrder, conflicts are resolved and
+// device node details are filled in. The edits are returned in canonical
+// order (see SortEdits), together with the injected devices. If any of
+// the devices can't be resolved, GetDeviceEdits returns the unresolved
+// devices and an error.
+//
+// This allows runtimes which do not use OCI Specs, for instance CRI
+// implementations building their own sandbox configuration, to consume
+// CDI devices. Since there is no OCI Spec to merge them into, environment
+// variables are returned in the order they would be applied, without any
+// merging. The returned edits share data with the Cache and must not be
+// modified.
+func (c *Cache) GetDeviceEdits(devices ...string) (*ContainerEdits, []*Device, []string, error) {
+	unlock := c.lockRefreshed()
+	defer unlock()
+
+	edits, injected, unresolved, err := c.resolveEdits(devices, c.hostRoot)
+	if unresolved != nil {
+		return nil, nil, unresolved, err
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get device edits: %w", err)
+	}
+
+	return edits, injected, nil, nil
+}
+
+// GetAnnotationEdits returns the container edits for the CDI device
+// injection requests in the given annotations, as GetDeviceEdits() does
+// for the devices returned by ParseAnnotations().
+func (c *Cache) GetAnnotationEdits(annotations map[string]string) (*ContainerEdits, []*Device, []string, error) {
+	_, devices, err := ParseAnnotations(annotations)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get device edits: %w", err)
+	}
+	return c.GetDeviceEdits(devices...)
+}
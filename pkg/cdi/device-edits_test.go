@@ -0,0 +1,108 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDeviceEdits(t *testing.T) {
+	etc := map[string]string{
+		"vendor1.yaml": `
+cdiVersion: "0.6.0"
+kind:       "vendor1.com/device"
+containerEdits:
+  env:
+  - "VENDOR1_SPEC=1"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR1_DEV1=1"
+      deviceNodes:
+      - path: "/dev/vendor1-dev1"
+        type: "c"
+        major: 10
+        minor: 1
+      mounts:
+      - hostPath: "/usr/lib/vendor1"
+        containerPath: "/usr/lib/vendor1"
+  - name: "dev2"
+    containerEdits:
+      env:
+      - "VENDOR1_DEV2=1"
+`,
+	}
+
+	dir, err := createSpecDirs(t, etc, nil)
+	require.NoError(t, err)
+
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+	)
+
+	t.Run("devices", func(t *testing.T) {
+		edits, devices, unresolved, err := cache.GetDeviceEdits("vendor1.com/device=dev1", "vendor1.com/device=dev2")
+		require.NoError(t, err)
+		require.Nil(t, unresolved)
+		require.Len(t, devices, 2)
+		require.Equal(t, "vendor1.com/device=dev1", devices[0].GetQualifiedName())
+		require.Equal(t, "vendor1.com/device=dev2", devices[1].GetQualifiedName())
+		require.Equal(t, []string{"VENDOR1_SPEC=1", "VENDOR1_DEV1=1", "VENDOR1_DEV2=1"}, edits.Env)
+		require.Len(t, edits.DeviceNodes, 1)
+		require.Equal(t, "/dev/vendor1-dev1", edits.DeviceNodes[0].HostPath)
+		require.Len(t, edits.Mounts, 1)
+
+		// applying the edits gives the same result as injection
+		applied, injected := &oci.Spec{}, &oci.Spec{}
+		require.NoError(t, edits.Apply(applied))
+		_, err = cache.InjectDevices(injected, "vendor1.com/device=dev1", "vendor1.com/device=dev2")
+		require.NoError(t, err)
+		require.Equal(t, injected, applied)
+	})
+
+	t.Run("annotations", func(t *testing.T) {
+		annotations, err := UpdateAnnotations(nil, "vendor1.device", "id1", []string{"vendor1.com/device=dev2"})
+		require.NoError(t, err)
+
+		edits, devices, unresolved, err := cache.GetAnnotationEdits(annotations)
+		require.NoError(t, err)
+		require.Nil(t, unresolved)
+		require.Len(t, devices, 1)
+		require.Equal(t, []string{"VENDOR1_SPEC=1", "VENDOR1_DEV2=1"}, edits.Env)
+	})
+
+	t.Run("unresolved devices", func(t *testing.T) {
+		edits, devices, unresolved, err := cache.GetDeviceEdits("vendor1.com/device=dev1", "vendor1.com/device=dev3")
+		require.Error(t, err)
+		require.Nil(t, edits)
+		require.Nil(t, devices)
+		require.Equal(t, []string{"vendor1.com/device=dev3"}, unresolved)
+	})
+
+	t.Run("invalid annotations", func(t *testing.T) {
+		_, _, _, err := cache.GetAnnotationEdits(map[string]string{
+			AnnotationPrefix + "vendor1.device_id1": "not-a-device",
+		})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,133 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"strings"
+
+	"tags.cncf.io/container-device-interface/pkg/parser"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// AllDevicesGroupMember is the device group member which stands for
+	// all devices of a Spec.
+	AllDevicesGroupMember = "*"
+)
+
+// GetGroup returns the names of the member devices of the given device
+// group, or nil if the Spec has no such group. Nested groups are expanded.
+func (s *Spec) GetGroup(name string) []string {
+	members, ok := s.groups[name]
+	if !ok {
+		return nil
+	}
+	devices := make([]string, len(members))
+	copy(devices, members)
+	return devices
+}
+
+// validateGroups validates the device groups of the Spec and returns
+// the expanded member devices of each group. Groups must have a valid
+// unique name which does not clash with any device name, they must not
+// be empty, and they may only contain existing devices or other groups
+// without referring to themselves directly or indirectly.
+func (s *Spec) validateGroups(devices map[string]*Device) (map[string][]string, error) {
+	if len(s.Groups) == 0 {
+		return nil, nil
+	}
+
+	defs := make(map[string]*cdi.DeviceGroup)
+	for i := range s.Groups {
+		g := &s.Groups[i]
+		if err := parser.ValidateDeviceName(g.Name); err != nil {
+			return nil, fmt.Errorf("invalid device group: %w", err)
+		}
+		if _, ok := devices[g.Name]; ok {
+			return nil, fmt.Errorf("invalid device group %q, conflicts with device", g.Name)
+		}
+		if _, ok := defs[g.Name]; ok {
+			return nil, fmt.Errorf("invalid spec, multiple device group %q", g.Name)
+		}
+		if len(g.Devices) == 0 {
+			return nil, fmt.Errorf("invalid device group %q, no member devices", g.Name)
+		}
+		defs[g.Name] = g
+	}
+
+	var (
+		groups = make(map[string][]string)
+		expand func(name string, path []string) ([]string, error)
+	)
+
+	expand = func(name string, path []string) ([]string, error) {
+		if members, ok := groups[name]; ok {
+			return members, nil
+		}
+		for _, p := range path {
+			if p == name {
+				return nil, fmt.Errorf("invalid device group %q, cycle %s",
+					name, strings.Join(append(path, name), " -> "))
+			}
+		}
+		path = append(path, name)
+
+		var (
+			members []string
+			seen    = map[string]struct{}{}
+		)
+		add := func(device string) {
+			if _, ok := seen[device]; !ok {
+				seen[device] = struct{}{}
+				members = append(members, device)
+			}
+		}
+
+		for _, m := range defs[name].Devices {
+			switch {
+			case m == AllDevicesGroupMember:
+				for _, d := range s.Devices {
+					add(d.Name)
+				}
+			case devices[m] != nil:
+				add(m)
+			case defs[m] != nil:
+				nested, err := expand(m, path)
+				if err != nil {
+					return nil, err
+				}
+				for _, d := range nested {
+					add(d)
+				}
+			default:
+				return nil, fmt.Errorf("invalid device group %q, unknown member %q", name, m)
+			}
+		}
+
+		groups[name] = members
+		return members, nil
+	}
+
+	for _, g := range s.Groups {
+		if _, err := expand(g.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,220 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+)
+
+const groupTestDevices = `
+devices:
+  - name: "dev0"
+    containerEdits:
+      env:
+      - "DEV0=1"
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "DEV1=1"
+  - name: "dev2"
+    containerEdits:
+      env:
+      - "DEV2=1"
+`
+
+func TestSpecDeviceGroups(t *testing.T) {
+	type testCase struct {
+		name    string
+		data    string
+		invalid bool
+		groups  map[string][]string
+	}
+	for _, tc := range []*testCase{
+		{
+			name: "valid groups",
+			data: `
+cdiVersion: "0.9.0"
+kind: vendor.com/device
+groups:
+  - name: "all"
+    devices: [ "*" ]
+  - name: "even"
+    devices: [ "dev0", "dev2" ]
+  - name: "first"
+    devices: [ "dev0" ]
+  - name: "nested"
+    devices: [ "first", "even", "dev1" ]
+` + groupTestDevices,
+			groups: map[string][]string{
+				"all":    {"dev0", "dev1", "dev2"},
+				"even":   {"dev0", "dev2"},
+				"first":  {"dev0"},
+				"nested": {"dev0", "dev2", "dev1"},
+			},
+		},
+		{
+			name: "invalid, version too low",
+			data: `
+cdiVersion: "0.8.0"
+kind: vendor.com/device
+groups:
+  - name: "all"
+    devices: [ "*" ]
+` + groupTestDevices,
+			invalid: true,
+		},
+		{
+			name: "invalid, group clashes with device",
+			data: `
+cdiVersion: "0.9.0"
+kind: vendor.com/device
+groups:
+  - name: "dev0"
+    devices: [ "dev1" ]
+` + groupTestDevices,
+			invalid: true,
+		},
+		{
+			name: "invalid, unknown member",
+			data: `
+cdiVersion: "0.9.0"
+kind: vendor.com/device
+groups:
+  - name: "odd"
+    devices: [ "dev1", "dev3" ]
+` + groupTestDevices,
+			invalid: true,
+		},
+		{
+			name: "invalid, empty group",
+			data: `
+cdiVersion: "0.9.0"
+kind: vendor.com/device
+groups:
+  - name: "none"
+    devices: []
+` + groupTestDevices,
+			invalid: true,
+		},
+		{
+			name: "invalid, duplicate group",
+			data: `
+cdiVersion: "0.9.0"
+kind: vendor.com/device
+groups:
+  - name: "some"
+    devices: [ "dev0" ]
+  - name: "some"
+    devices: [ "dev1" ]
+` + groupTestDevices,
+			invalid: true,
+		},
+		{
+			name: "invalid, cycle",
+			data: `
+cdiVersion: "0.9.0"
+kind: vendor.com/device
+groups:
+  - name: "a"
+    devices: [ "dev0", "b" ]
+  - name: "b"
+    devices: [ "c" ]
+  - name: "c"
+    devices: [ "a" ]
+` + groupTestDevices,
+			invalid: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, err := ParseSpec([]byte(tc.data))
+			require.NoError(t, err)
+
+			spec, err := newSpec(raw, tc.name, 0)
+			if tc.invalid {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			for name, members := range tc.groups {
+				require.Equal(t, members, spec.GetGroup(name), "group %q", name)
+			}
+			require.Nil(t, spec.GetGroup("no-such-group"))
+		})
+	}
+}
+
+func TestInjectDeviceGroups(t *testing.T) {
+	etc := map[string]string{
+		"vendor.yaml": `
+cdiVersion: "0.9.0"
+kind: vendor.com/device
+containerEdits:
+  env:
+  - "VENDOR=1"
+groups:
+  - name: "all"
+    devices: [ "*" ]
+  - name: "even"
+    devices: [ "dev0", "dev2" ]
+` + groupTestDevices,
+	}
+
+	dir, err := createSpecDirs(t, etc, nil)
+	require.NoError(t, err)
+
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+	)
+	require.Empty(t, cache.GetErrors())
+
+	require.Equal(t, []string{
+		"vendor.com/device=dev0",
+		"vendor.com/device=dev2",
+	}, cache.GetDeviceGroup("vendor.com/device=even"))
+	require.Nil(t, cache.GetDevice("vendor.com/device=even"))
+
+	ociSpec := &oci.Spec{}
+	unresolved, err := cache.InjectDevices(ociSpec, "vendor.com/device=even")
+	require.NoError(t, err)
+	require.Nil(t, unresolved)
+	require.Equal(t, []string{"VENDOR=1", "DEV0=1", "DEV2=1"}, ociSpec.Process.Env)
+
+	ociSpec = &oci.Spec{}
+	unresolved, err = cache.InjectDevices(ociSpec, "vendor.com/device=all", "vendor.com/device=dev1")
+	require.NoError(t, err)
+	require.Nil(t, unresolved)
+	require.Equal(t, []string{"VENDOR=1", "DEV0=1", "DEV1=1", "DEV2=1"}, ociSpec.Process.Env)
+
+	// devices requested more than once are collected only once
+	edits, unresolved, err := cache.collectEdits([]string{
+		"vendor.com/device=dev2",
+		"vendor.com/device=even",
+		"vendor.com/device=dev*",
+	}, "")
+	require.NoError(t, err)
+	require.Nil(t, unresolved)
+	require.Equal(t, []string{"VENDOR=1", "DEV2=1", "DEV0=1", "DEV1=1"}, edits.devices.Env)
+
+	unresolved, err = cache.InjectDevices(&oci.Spec{}, "vendor.com/device=odd")
+	require.Error(t, err)
+	require.Equal(t, []string{"vendor.com/device=odd"}, unresolved)
+}
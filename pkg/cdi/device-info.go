@@ -0,0 +1,235 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// DeviceInfo is the type, major and minor number of a host device node,
+// together with its permissions and ownership. Resolvers which can't
+// tell the permissions leave FileMode zero.
+type DeviceInfo struct {
+	Type     string
+	Major    int64
+	Minor    int64
+	FileMode os.FileMode
+	UID      uint32
+	GID      uint32
+}
+
+// DeviceInfoResolver looks up the device information for a host device
+// node. Device nodes in CDI Specs can omit their type, major and minor
+// numbers. These are then filled in at injection time using the resolver
+// configured for the Cache.
+type DeviceInfoResolver interface {
+	ResolveDeviceInfo(hostPath string) (*DeviceInfo, error)
+}
+
+// DeviceInfoResolverFunc is a function implementing DeviceInfoResolver.
+type DeviceInfoResolverFunc func(hostPath string) (*DeviceInfo, error)
+
+// ResolveDeviceInfo implements DeviceInfoResolver.
+func (f DeviceInfoResolverFunc) ResolveDeviceInfo(hostPath string) (*DeviceInfo, error) {
+	return f(hostPath)
+}
+
+var (
+	// HostDeviceInfoResolver looks up device information by stat()ing
+	// the device node on the host.
+	HostDeviceInfoResolver DeviceInfoResolver = DeviceInfoResolverFunc(
+		func(hostPath string) (*DeviceInfo, error) {
+			return deviceInfoFromPath(hostPath)
+		},
+	)
+
+	// NoDeviceInfoResolver disables host lookups. It can be used in
+	// environments where CDI Specs are consumed off-host, for instance
+	// when generating OCI Specs for another host. Device nodes without
+	// full device information then fail to inject with an error wrapping
+	// ErrDeviceInfoLookupDisabled.
+	NoDeviceInfoResolver DeviceInfoResolver = DeviceInfoResolverFunc(
+		func(string) (*DeviceInfo, error) {
+			return nil, ErrDeviceInfoLookupDisabled
+		},
+	)
+
+	// ErrNotDeviceNode is returned for host paths which are not device nodes.
+	ErrNotDeviceNode = errors.New("not a device node")
+	// ErrDeviceInfoLookupDisabled is returned by NoDeviceInfoResolver.
+	ErrDeviceInfoLookupDisabled = errors.New("host device lookup disabled")
+)
+
+// DeviceInfoError is the error for a device node with missing device
+// information which could not be looked up on the host. A host device
+// node which does not exist can be detected with errors.Is(err,
+// fs.ErrNotExist).
+type DeviceInfoError struct {
+	// Path is the path of the device node in the container.
+	Path string
+	// HostPath is the path of the device node on the host.
+	HostPath string
+	// Err is the error returned by the DeviceInfoResolver.
+	Err error
+}
+
+// Error returns the error message.
+func (e *DeviceInfoError) Error() string {
+	return fmt.Sprintf("failed to stat CDI host device %q: %v", e.HostPath, e.Err)
+}
+
+// Unwrap returns the error returned by the DeviceInfoResolver.
+func (e *DeviceInfoError) Unwrap() error {
+	return e.Err
+}
+
+// WithDeviceInfoResolver returns an option to set the DeviceInfoResolver
+// used to fill in missing device information during injection. By default
+// the Cache uses HostDeviceInfoResolver and remembers the results until
+// the next refresh. Setting a nil resolver restores the default. Use
+// NoDeviceInfoResolver to disable host lookups altogether.
+func WithDeviceInfoResolver(r DeviceInfoResolver) Option {
+	return func(c *Cache) {
+		c.deviceInfo = r
+	}
+}
+
+// getDeviceInfoResolver returns the DeviceInfoResolver of the Cache.
+func (c *Cache) getDeviceInfoResolver() DeviceInfoResolver {
+	if c.deviceInfo != nil {
+		return c.deviceInfo
+	}
+	return c.hostDeviceInfo
+}
+
+// deviceInfoCache is a DeviceInfoResolver which remembers successful
+// lookups of another resolver. Failed lookups are not remembered, so
+// that device nodes created later are found.
+type deviceInfoCache struct {
+	sync.Mutex
+	resolver DeviceInfoResolver
+	info     map[string]*DeviceInfo
+}
+
+// newDeviceInfoCache returns a caching resolver for the given one.
+func newDeviceInfoCache(r DeviceInfoResolver) *deviceInfoCache {
+	return &deviceInfoCache{
+		resolver: r,
+		info:     map[string]*DeviceInfo{},
+	}
+}
+
+// ResolveDeviceInfo implements DeviceInfoResolver.
+func (dc *deviceInfoCache) ResolveDeviceInfo(hostPath string) (*DeviceInfo, error) {
+	dc.Lock()
+	defer dc.Unlock()
+
+	if info, ok := dc.info[hostPath]; ok {
+		return info, nil
+	}
+	info, err := dc.resolver.ResolveDeviceInfo(hostPath)
+	if err != nil {
+		return nil, err
+	}
+	dc.info[hostPath] = info
+	return info, nil
+}
+
+// reset forgets all remembered lookups.
+func (dc *deviceInfoCache) reset() {
+	if dc == nil {
+		return
+	}
+	dc.Lock()
+	defer dc.Unlock()
+	dc.info = map[string]*DeviceInfo{}
+}
+
+// fillMissingInfo fills in missing mandatory attributes from the host
+// device, using the given DeviceInfoResolver.
+func (d *DeviceNode) fillMissingInfo(r DeviceInfoResolver) error {
+	if d.HostPath == "" {
+		d.HostPath = d.Path
+	}
+
+	if d.Type != "" && (d.Major != 0 || d.Type == "p") {
+		return nil
+	}
+
+	info, err := r.ResolveDeviceInfo(d.HostPath)
+	if err != nil {
+		return &DeviceInfoError{Path: d.Path, HostPath: d.HostPath, Err: err}
+	}
+
+	if d.Type == "" {
+		d.Type = info.Type
+	} else {
+		if d.Type != info.Type {
+			return fmt.Errorf("CDI device (%q, %q), host type mismatch (%s, %s)",
+				d.Path, d.HostPath, d.Type, info.Type)
+		}
+	}
+	if d.Major == 0 && d.Type != "p" {
+		d.Major = info.Major
+		d.Minor = info.Minor
+	}
+
+	return nil
+}
+
+// resolve returns a copy of the edits prepared for injection. Missing
+// device node information and ownership are filled in using the given
+// DeviceInfoResolver and ownership, and host IDs are mapped using the
+// given ID mapping. The original edits are left intact.
+func (e *ContainerEdits) resolve(r DeviceInfoResolver, o DeviceNodeOwnership, m *idMapping) (*ContainerEdits, error) {
+	if e == nil || e.ContainerEdits == nil {
+		return e, nil
+	}
+	if len(e.DeviceNodes) == 0 && (m == nil || len(e.AdditionalGIDs) == 0) {
+		return e, nil
+	}
+
+	edits := *e.ContainerEdits
+	edits.DeviceNodes = make([]*cdi.DeviceNode, 0, len(e.DeviceNodes))
+	for _, n := range e.DeviceNodes {
+		dn := &DeviceNode{&cdi.DeviceNode{}}
+		*dn.DeviceNode = *n
+		if err := dn.fillMissingInfo(r); err != nil {
+			return nil, err
+		}
+		if err := m.mapDeviceNode(dn); err != nil {
+			return nil, err
+		}
+		if err := dn.fillOwnership(r, o, m); err != nil {
+			return nil, err
+		}
+		edits.DeviceNodes = append(edits.DeviceNodes, dn.DeviceNode)
+	}
+
+	gids, err := m.mapGIDs(e.AdditionalGIDs)
+	if err != nil {
+		return nil, err
+	}
+	edits.AdditionalGIDs = gids
+
+	return e.copyWith(&edits), nil
+}
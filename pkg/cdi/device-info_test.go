@@ -0,0 +1,152 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestDeviceInfoResolver(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	spec := &cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/device",
+		Devices: []cdi.Device{
+			{
+				Name: "null",
+				ContainerEdits: cdi.ContainerEdits{
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/cdi-null", HostPath: "/dev/null"},
+					},
+				},
+			},
+			{
+				Name: "complete",
+				ContainerEdits: cdi.ContainerEdits{
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/complete", Type: "c", Major: 10, Minor: 200},
+					},
+				},
+			},
+			{
+				Name: "missing",
+				ContainerEdits: cdi.ContainerEdits{
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/missing", HostPath: missing},
+					},
+				},
+			},
+		},
+	}
+
+	t.Run("lookups are cached until refresh", func(t *testing.T) {
+		lookups := map[string]int{}
+		resolver := DeviceInfoResolverFunc(func(hostPath string) (*DeviceInfo, error) {
+			lookups[hostPath]++
+			if hostPath == missing {
+				return nil, fs.ErrNotExist
+			}
+			return &DeviceInfo{Type: "c", Major: 1, Minor: 3}, nil
+		})
+		cache := newCache(
+			WithSpecDirs(),
+			WithAutoRefresh(false),
+		)
+		cache.hostDeviceInfo = newDeviceInfoCache(resolver)
+		require.NoError(t, cache.AddSpec(spec, 0))
+
+		for i := 0; i < 3; i++ {
+			ociSpec := &oci.Spec{}
+			unresolved, err := cache.InjectDevices(ociSpec, "vendor.com/device=null", "vendor.com/device=complete")
+			require.NoError(t, err)
+			require.Nil(t, unresolved)
+			require.Len(t, ociSpec.Linux.Devices, 2)
+			require.Equal(t, "/dev/cdi-null", ociSpec.Linux.Devices[0].Path)
+			require.Equal(t, int64(1), ociSpec.Linux.Devices[0].Major)
+			require.Equal(t, int64(3), ociSpec.Linux.Devices[0].Minor)
+		}
+		require.Equal(t, map[string]int{"/dev/null": 1}, lookups)
+
+		// injection must not update the cached Spec
+		dn := cache.GetDevice("vendor.com/device=null").ContainerEdits.DeviceNodes[0]
+		require.Equal(t, "", dn.Type)
+		require.Equal(t, int64(0), dn.Major)
+
+		// failed lookups are not cached
+		for i := 0; i < 2; i++ {
+			_, err := cache.InjectDevices(&oci.Spec{}, "vendor.com/device=missing")
+			require.ErrorIs(t, err, fs.ErrNotExist)
+		}
+		require.Equal(t, 2, lookups[missing])
+
+		require.NoError(t, cache.Refresh())
+		_, err := cache.InjectDevices(&oci.Spec{}, "vendor.com/device=null")
+		require.NoError(t, err)
+		require.Equal(t, 2, lookups["/dev/null"])
+	})
+
+	t.Run("host lookup", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("host device lookups are not supported on windows")
+		}
+		cache := newCache(
+			WithSpecDirs(),
+			WithAutoRefresh(false),
+		)
+		require.NoError(t, cache.AddSpec(spec, 0))
+
+		ociSpec := &oci.Spec{}
+		_, err := cache.InjectDevices(ociSpec, "vendor.com/device=null")
+		require.NoError(t, err)
+		require.Equal(t, "c", ociSpec.Linux.Devices[0].Type)
+		require.Equal(t, int64(1), ociSpec.Linux.Devices[0].Major)
+		require.Equal(t, int64(3), ociSpec.Linux.Devices[0].Minor)
+
+		_, err = cache.InjectDevices(&oci.Spec{}, "vendor.com/device=missing")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		var infoErr *DeviceInfoError
+		require.True(t, errors.As(err, &infoErr))
+		require.Equal(t, "/dev/missing", infoErr.Path)
+		require.Equal(t, missing, infoErr.HostPath)
+	})
+
+	t.Run("lookups disabled", func(t *testing.T) {
+		cache := newCache(
+			WithSpecDirs(),
+			WithAutoRefresh(false),
+			WithDeviceInfoResolver(NoDeviceInfoResolver),
+		)
+		require.NoError(t, cache.AddSpec(spec, 0))
+
+		ociSpec := &oci.Spec{}
+		_, err := cache.InjectDevices(ociSpec, "vendor.com/device=complete")
+		require.NoError(t, err)
+		require.Equal(t, int64(10), ociSpec.Linux.Devices[0].Major)
+
+		_, err = cache.InjectDevices(&oci.Spec{}, "vendor.com/device=null")
+		require.ErrorIs(t, err, ErrDeviceInfoLookupDisabled)
+	})
+}
@@ -0,0 +1,204 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"tags.cncf.io/container-device-interface/pkg/parser"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// removalDateLayout is the layout of lifecycle removal dates.
+const removalDateLayout = "2006-01-02"
+
+// DeprecationWarning reports the injection of a deprecated device, or of
+// a device requested by its previous name. It is passed to the handler
+// set with WithDeprecationHandler().
+type DeprecationWarning struct {
+	// Device is the qualified name of the deprecated device, or the
+	// previous qualified name of a renamed device.
+	Device string
+	// Spec is the path of the Spec of the device.
+	Spec string
+	// ReplacedBy is the replacement of the device, a qualified device
+	// name, or a Spec kind if the whole Spec is deprecated.
+	ReplacedBy string
+	// RemovalDate is the date after which the device may be removed.
+	RemovalDate string
+	// Renamed is true if the device was requested by its previous name.
+	// ReplacedBy is then the current name of the device.
+	Renamed bool
+}
+
+// Error returns a description of the deprecation.
+func (w *DeprecationWarning) Error() string {
+	if w.Renamed {
+		return fmt.Sprintf("CDI device %q is deprecated, renamed to %q", w.Device, w.ReplacedBy)
+	}
+	msg := fmt.Sprintf("CDI device %q is deprecated", w.Device)
+	if w.ReplacedBy != "" {
+		msg += fmt.Sprintf(", replaced by %q", w.ReplacedBy)
+	}
+	if w.RemovalDate != "" {
+		msg += ", to be removed after " + w.RemovalDate
+	}
+	return msg
+}
+
+// WithDeprecationHandler returns an option to set a handler which is
+// called with a *DeprecationWarning for every deprecated device injected
+// by InjectDevices(), and for every device requested by its previous name
+// (see the renamedFrom field of Spec devices). The handler is called once injection has finished,
+// after the Cache has been unlocked. A nil handler, the default, disables
+// the warnings.
+func WithDeprecationHandler(fn func(*DeprecationWarning)) Option {
+	return func(c *Cache) {
+		c.onDeprecated = fn
+	}
+}
+
+// GetLifecycle returns the lifecycle of the device. This is the lifecycle
+// of the device itself if it has one, otherwise that of its Spec, or nil
+// if neither has one.
+func (d *Device) GetLifecycle() *cdi.Lifecycle {
+	if d.Lifecycle != nil {
+		return d.Lifecycle
+	}
+	if d.spec != nil {
+		return d.spec.Lifecycle
+	}
+	return nil
+}
+
+// IsDeprecated returns true if the device or its Spec is deprecated.
+func (d *Device) IsDeprecated() bool {
+	l := d.GetLifecycle()
+	return l != nil && l.Deprecated
+}
+
+// ListDeprecatedDevices lists all deprecated cached devices by qualified
+// name. Might trigger a cache refresh, in which case any errors encountered
+// can be obtained using GetErrors().
+func (c *Cache) ListDeprecatedDevices() []string {
+	var devices []string
+
+	unlock := c.lockRefreshed()
+	defer unlock()
+
+	for _, dev := range c.devices {
+		if dev.IsDeprecated() {
+			devices = append(devices, dev.GetQualifiedName())
+		}
+	}
+	sort.Strings(devices)
+
+	return devices
+}
+
+// deprecationWarnings returns a function calling the deprecation handler
+// for the deprecated devices among the given injected ones and for the
+// given renames used, or nil if there is nothing to report. It must be
+// called with the Cache locked.
+func (c *Cache) deprecationWarnings(injected []*Device, renamed []*deviceRename) func() {
+	handler := c.onDeprecated
+	if handler == nil {
+		return nil
+	}
+
+	var (
+		seen     = map[*Device]struct{}{}
+		warnings []*DeprecationWarning
+	)
+	for _, r := range renamed {
+		warnings = append(warnings, &DeprecationWarning{
+			Device:     r.name,
+			Spec:       r.spec.GetPath(),
+			ReplacedBy: r.device,
+			Renamed:    true,
+		})
+	}
+	for _, d := range injected {
+		if _, ok := seen[d]; ok || !d.IsDeprecated() {
+			continue
+		}
+		seen[d] = struct{}{}
+		l := d.GetLifecycle()
+		warnings = append(warnings, &DeprecationWarning{
+			Device:      d.GetQualifiedName(),
+			Spec:        d.GetSpec().GetPath(),
+			ReplacedBy:  l.ReplacedBy,
+			RemovalDate: l.RemovalDate,
+		})
+	}
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	return func() {
+		for _, w := range warnings {
+			handler(w)
+		}
+	}
+}
+
+// validateLifecycle validates the lifecycle of the Spec. A replacement
+// must be a Spec kind.
+func (s *Spec) validateLifecycle() error {
+	return validateLifecycle(s.Kind, s.Lifecycle, func(kind string) error {
+		vendor, class := parser.ParseQualifier(kind)
+		if err := parser.ValidateVendorName(vendor); err != nil {
+			return err
+		}
+		return parser.ValidateClassName(class)
+	})
+}
+
+// validateLifecycle validates the lifecycle of the device. A replacement
+// must be a qualified device name.
+func (d *Device) validateLifecycle(name string) error {
+	return validateLifecycle(name, d.Lifecycle, func(device string) error {
+		_, _, _, err := parser.ParseQualifiedName(device)
+		return err
+	})
+}
+
+// validateLifecycle validates a lifecycle, using the given function to
+// validate any replacement.
+func validateLifecycle(name string, l *cdi.Lifecycle, validReplacement func(string) error) error {
+	if l == nil {
+		return nil
+	}
+	if !l.Deprecated && (l.ReplacedBy != "" || l.RemovalDate != "") {
+		return fmt.Errorf("invalid lifecycle of %q, replacedBy and removalDate need deprecated", name)
+	}
+	if l.ReplacedBy != "" {
+		if err := validReplacement(l.ReplacedBy); err != nil {
+			return fmt.Errorf("invalid lifecycle of %q, invalid replacedBy %q: %w",
+				name, l.ReplacedBy, err)
+		}
+	}
+	if l.RemovalDate != "" {
+		if _, err := time.Parse(removalDateLayout, l.RemovalDate); err != nil {
+			return fmt.Errorf("invalid lifecycle of %q, invalid removalDate %q, expected YYYY-MM-DD",
+				name, l.RemovalDate)
+		}
+	}
+	return nil
+}
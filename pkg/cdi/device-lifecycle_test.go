@@ -0,0 +1,183 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestLifecycleValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		spec      *cdi.Lifecycle
+		device    *cdi.Lifecycle
+		invalid   bool
+		errSubstr string
+	}{
+		{
+			name: "no lifecycle",
+		},
+		{
+			name: "deprecated spec and device",
+			spec: &cdi.Lifecycle{
+				Deprecated:  true,
+				ReplacedBy:  "vendor.com/device2",
+				RemovalDate: "2027-01-31",
+			},
+			device: &cdi.Lifecycle{
+				Deprecated:  true,
+				ReplacedBy:  "vendor.com/device2=dev1",
+				RemovalDate: "2027-01-31",
+			},
+		},
+		{
+			name:      "replacement without deprecation",
+			device:    &cdi.Lifecycle{ReplacedBy: "vendor.com/device=dev1"},
+			invalid:   true,
+			errSubstr: "need deprecated",
+		},
+		{
+			name:      "unqualified device replacement",
+			device:    &cdi.Lifecycle{Deprecated: true, ReplacedBy: "dev1"},
+			invalid:   true,
+			errSubstr: "invalid replacedBy",
+		},
+		{
+			name:      "device name as Spec replacement",
+			spec:      &cdi.Lifecycle{Deprecated: true, ReplacedBy: "vendor.com/device=dev1"},
+			invalid:   true,
+			errSubstr: "invalid replacedBy",
+		},
+		{
+			name:      "invalid removal date",
+			device:    &cdi.Lifecycle{Deprecated: true, RemovalDate: "31.01.2027"},
+			invalid:   true,
+			errSubstr: "invalid removalDate",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := &cdi.Spec{
+				Version:   cdi.CurrentVersion,
+				Kind:      "vendor.com/device",
+				Lifecycle: tc.spec,
+				Devices: []cdi.Device{
+					{
+						Name:           "dev0",
+						Lifecycle:      tc.device,
+						ContainerEdits: cdi.ContainerEdits{Env: []string{"DEV0=1"}},
+					},
+				},
+			}
+			_, err := newSpec(raw, "/tmp/vendor.yaml", 0)
+			if tc.invalid {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errSubstr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDeprecatedDevices(t *testing.T) {
+	var (
+		warnings []*DeprecationWarning
+		cache    *Cache
+	)
+	cache = newCache(
+		WithSpecDirs(),
+		WithAutoRefresh(false),
+		WithDeprecationHandler(func(w *DeprecationWarning) {
+			// the Cache must be unlocked by now
+			require.NoError(t, cache.Configure())
+			warnings = append(warnings, w)
+		}),
+	)
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/old",
+		Lifecycle: &cdi.Lifecycle{
+			Deprecated: true,
+			ReplacedBy: "vendor.com/new",
+		},
+		Devices: []cdi.Device{
+			{
+				Name:           "dev0",
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"OLD0=1"}},
+			},
+			{
+				Name:           "dev1",
+				Lifecycle:      &cdi.Lifecycle{},
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"OLD1=1"}},
+			},
+		},
+	}, 0))
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/new",
+		Devices: []cdi.Device{
+			{
+				Name: "dev0",
+				Lifecycle: &cdi.Lifecycle{
+					Deprecated:  true,
+					ReplacedBy:  "vendor.com/new=dev2",
+					RemovalDate: "2027-01-31",
+				},
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"NEW0=1"}},
+			},
+			{
+				Name:           "dev2",
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"NEW2=1"}},
+			},
+		},
+	}, 0))
+
+	require.Equal(t, []string{"vendor.com/new=dev0", "vendor.com/old=dev0"}, cache.ListDeprecatedDevices())
+	require.False(t, cache.GetDevice("vendor.com/old=dev1").IsDeprecated())
+
+	_, err := cache.InjectDevices(&oci.Spec{}, "vendor.com/old=dev1", "vendor.com/new=dev2")
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	_, err = cache.InjectDevices(&oci.Spec{}, "vendor.com/old=dev0", "vendor.com/new=dev0", "vendor.com/new=dev2")
+	require.NoError(t, err)
+	require.Equal(t, []*DeprecationWarning{
+		{
+			Device:     "vendor.com/old=dev0",
+			Spec:       "memory:vendor.com-old",
+			ReplacedBy: "vendor.com/new",
+		},
+		{
+			Device:      "vendor.com/new=dev0",
+			Spec:        "memory:vendor.com-new",
+			ReplacedBy:  "vendor.com/new=dev2",
+			RemovalDate: "2027-01-31",
+		},
+	}, warnings)
+	require.Equal(t, `CDI device "vendor.com/new=dev0" is deprecated, replaced by "vendor.com/new=dev2", to be removed after 2027-01-31`,
+		warnings[1].Error())
+
+	warnings = nil
+	unresolved, err := cache.InjectDevices(&oci.Spec{}, "vendor.com/old=dev0", "vendor.com/none=dev0")
+	require.Error(t, err)
+	require.Equal(t, []string{"vendor.com/none=dev0"}, unresolved)
+	require.Empty(t, warnings)
+}
@@ -0,0 +1,94 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"sort"
+)
+
+// ListDevicesByVendor lists all cached devices by qualified name, grouped
+// by vendor and device class. The devices of each class are sorted. Might
+// trigger a cache refresh, in which case any errors encountered can be
+// obtained using GetErrors().
+func (c *Cache) ListDevicesByVendor() map[string]map[string][]string {
+	unlock := c.lockRefreshed()
+	defer unlock()
+
+	vendors := map[string]map[string][]string{}
+	for _, dev := range c.sortedDevices {
+		spec := dev.GetSpec()
+		classes, ok := vendors[spec.GetVendor()]
+		if !ok {
+			classes = map[string][]string{}
+			vendors[spec.GetVendor()] = classes
+		}
+		classes[spec.GetClass()] = append(classes[spec.GetClass()], dev.GetQualifiedName())
+	}
+
+	return vendors
+}
+
+// ListDevicesPage lists a page of the cached devices by qualified name, in
+// the order of ListDevices(). The page starts at the given offset and has
+// at most limit devices, or all remaining devices if limit is not positive.
+// The total number of devices is returned with the page, so callers can
+// iterate over all devices without listing them at once. Listing a page
+// does not copy the names of other devices. Might trigger a cache refresh,
+// in which case any errors encountered can be obtained using GetErrors().
+func (c *Cache) ListDevicesPage(offset, limit int) ([]string, int) {
+	unlock := c.lockRefreshed()
+	defer unlock()
+
+	total := len(c.sortedDevices)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= total {
+		return nil, total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+
+	return deviceNames(c.sortedDevices[offset:end]), total
+}
+
+// sortDevices returns the given devices sorted by qualified name.
+func sortDevices(devices map[string]*Device) []*Device {
+	sorted := make([]*Device, 0, len(devices))
+	for _, dev := range devices {
+		sorted = append(sorted, dev)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].GetQualifiedName() < sorted[j].GetQualifiedName()
+	})
+	return sorted
+}
+
+// deviceNames returns the qualified names of the given devices, or nil if
+// there are none.
+func deviceNames(devices []*Device) []string {
+	if len(devices) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(devices))
+	for _, dev := range devices {
+		names = append(names, dev.GetQualifiedName())
+	}
+	return names
+}
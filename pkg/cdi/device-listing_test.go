@@ -0,0 +1,88 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestListDevicesGroupedAndPaged(t *testing.T) {
+	cache, err := NewCache(WithSpecDirs(), WithAutoRefresh(false))
+	require.NoError(t, err)
+
+	_, total := cache.ListDevicesPage(0, 10)
+	require.Equal(t, 0, total)
+	require.Empty(t, cache.ListDevicesByVendor())
+	require.Nil(t, cache.ListDevices())
+
+	for _, kind := range []string{"vendor.com/gpu", "vendor.com/nic", "other.com/gpu"} {
+		require.NoError(t, cache.AddSpec(&cdi.Spec{
+			Version: "0.3.0",
+			Kind:    kind,
+			Devices: []cdi.Device{
+				{Name: "dev1", ContainerEdits: cdi.ContainerEdits{Env: []string{"DEV=1"}}},
+				{Name: "dev0", ContainerEdits: cdi.ContainerEdits{Env: []string{"DEV=0"}}},
+			},
+		}, 0))
+	}
+
+	require.Equal(t,
+		map[string]map[string][]string{
+			"other.com": {
+				"gpu": {"other.com/gpu=dev0", "other.com/gpu=dev1"},
+			},
+			"vendor.com": {
+				"gpu": {"vendor.com/gpu=dev0", "vendor.com/gpu=dev1"},
+				"nic": {"vendor.com/nic=dev0", "vendor.com/nic=dev1"},
+			},
+		},
+		cache.ListDevicesByVendor(),
+	)
+
+	all := cache.ListDevices()
+	require.Len(t, all, 6)
+
+	var paged []string
+	for offset := 0; ; offset += 4 {
+		page, total := cache.ListDevicesPage(offset, 4)
+		require.Equal(t, 6, total)
+		if len(page) == 0 {
+			break
+		}
+		require.LessOrEqual(t, len(page), 4)
+		paged = append(paged, page...)
+	}
+	require.Equal(t, all, paged)
+
+	page, total := cache.ListDevicesPage(4, 0)
+	require.Equal(t, 6, total)
+	require.Equal(t, all[4:], page)
+
+	page, _ = cache.ListDevicesPage(-1, 1)
+	require.Equal(t, all[:1], page)
+
+	page, _ = cache.ListDevicesPage(6, 1)
+	require.Nil(t, page)
+
+	// pages are copies
+	page, _ = cache.ListDevicesPage(0, 1)
+	page[0] = "changed"
+	require.Equal(t, all, cache.ListDevices())
+}
@@ -0,0 +1,91 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"tags.cncf.io/container-device-interface/pkg/parser"
+)
+
+// ErrDeviceNotFound is returned by FindDevice if no device matches.
+var ErrDeviceNotFound = errors.New("CDI device not found")
+
+// AmbiguousDeviceError is the error for an unqualified device name which
+// matches devices of several vendors or classes.
+type AmbiguousDeviceError struct {
+	// Name is the unqualified device name.
+	Name string
+	// Candidates are the qualified names of the matching devices, sorted.
+	Candidates []string
+}
+
+// Error returns a description of the ambiguity.
+func (e *AmbiguousDeviceError) Error() string {
+	return fmt.Sprintf("ambiguous CDI device %q, candidates: %s",
+		e.Name, strings.Join(e.Candidates, ", "))
+}
+
+// FindDevice looks up a device by qualified or unqualified name. A
+// qualified name is looked up like GetDevice() does. An unqualified name
+// is matched against the names of the devices of all vendors and classes.
+// FindDevice returns the single matching device, an error wrapping
+// ErrDeviceNotFound if there is none, or an *AmbiguousDeviceError listing
+// all candidates if there are several. Might trigger a cache refresh, in
+// which case any errors encountered can be obtained using GetErrors().
+func (c *Cache) FindDevice(name string) (*Device, error) {
+	unlock := c.lockRefreshed()
+	defer unlock()
+
+	if parser.IsQualifiedName(name) {
+		if dev, ok := c.devices[c.deviceKey(name)]; ok {
+			return dev, nil
+		}
+		return nil, fmt.Errorf("%w: %q", ErrDeviceNotFound, name)
+	}
+
+	var (
+		key     = c.deviceKey(name)
+		matches []*Device
+	)
+	for _, dev := range c.devices {
+		if c.deviceKey(dev.Name) == key {
+			matches = append(matches, dev)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %q", ErrDeviceNotFound, name)
+	case 1:
+		return matches[0], nil
+	}
+
+	candidates := make([]string, 0, len(matches))
+	for _, dev := range matches {
+		candidates = append(candidates, dev.GetQualifiedName())
+	}
+	sort.Strings(candidates)
+
+	return nil, &AmbiguousDeviceError{
+		Name:       name,
+		Candidates: candidates,
+	}
+}
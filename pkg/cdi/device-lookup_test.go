@@ -0,0 +1,77 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestFindDevice(t *testing.T) {
+	cache := newCache(
+		WithSpecDirs(),
+		WithAutoRefresh(false),
+	)
+	for _, kind := range []string{"vendor.com/gpu", "vendor.com/nic", "other.com/gpu"} {
+		devices := []cdi.Device{
+			{
+				Name:           "dev0",
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"DEV=0"}},
+			},
+		}
+		if kind == "vendor.com/nic" {
+			devices = append(devices, cdi.Device{
+				Name:           "nic1",
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"NIC=1"}},
+			})
+		}
+		require.NoError(t, cache.AddSpec(&cdi.Spec{
+			Version: cdi.CurrentVersion,
+			Kind:    kind,
+			Devices: devices,
+		}, 0))
+	}
+
+	dev, err := cache.FindDevice("nic1")
+	require.NoError(t, err)
+	require.Equal(t, "vendor.com/nic=nic1", dev.GetQualifiedName())
+
+	dev, err = cache.FindDevice("other.com/gpu=dev0")
+	require.NoError(t, err)
+	require.Equal(t, "other.com/gpu=dev0", dev.GetQualifiedName())
+
+	dev, err = cache.FindDevice("dev0")
+	require.Nil(t, dev)
+	require.Error(t, err)
+	var ambiguous *AmbiguousDeviceError
+	require.True(t, errors.As(err, &ambiguous))
+	require.Equal(t, "dev0", ambiguous.Name)
+	require.Equal(t, []string{
+		"other.com/gpu=dev0",
+		"vendor.com/gpu=dev0",
+		"vendor.com/nic=dev0",
+	}, ambiguous.Candidates)
+
+	for _, name := range []string{"dev1", "vendor.com/gpu=nic1"} {
+		dev, err = cache.FindDevice(name)
+		require.Nil(t, dev)
+		require.True(t, errors.Is(err, ErrDeviceNotFound))
+	}
+}
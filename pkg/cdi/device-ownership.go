@@ -0,0 +1,122 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// DeviceNodeOwnership defines how the ownership and permissions of device
+// nodes are set up in the container when a device node in a Spec does not
+// specify them. Device nodes with explicit UID, GID or FileMode always
+// keep these.
+type DeviceNodeOwnership int
+
+const (
+	// DeviceNodeOwnershipProcessUser makes a device node owned by the user
+	// and group of the container process, unless that is root. Otherwise
+	// the ownership is left for the runtime to decide.
+	DeviceNodeOwnershipProcessUser DeviceNodeOwnership = iota
+	// DeviceNodeOwnershipRoot makes device nodes owned by root. With user
+	// namespaces this is the root user of the container, not of the host.
+	DeviceNodeOwnershipRoot
+	// DeviceNodeOwnershipHost copies the ownership and permissions of the
+	// device node on the host, as reported by the DeviceInfoResolver.
+	// The ownership is that of the host. Runtimes using user namespaces
+	// need to map the IDs into the container.
+	DeviceNodeOwnershipHost
+
+	// DefaultDeviceNodeOwnership is the ownership used unless configured
+	// otherwise.
+	DefaultDeviceNodeOwnership = DeviceNodeOwnershipProcessUser
+)
+
+// String returns the name of the DeviceNodeOwnership.
+func (o DeviceNodeOwnership) String() string {
+	switch o {
+	case DeviceNodeOwnershipProcessUser:
+		return "process-user"
+	case DeviceNodeOwnershipRoot:
+		return "root"
+	case DeviceNodeOwnershipHost:
+		return "host"
+	}
+	return fmt.Sprintf("DeviceNodeOwnership(%d)", int(o))
+}
+
+// WithDeviceNodeOwnership returns an option to set how the ownership of
+// injected device nodes is set up. Unknown values are ignored and
+// DefaultDeviceNodeOwnership is used instead.
+func WithDeviceNodeOwnership(o DeviceNodeOwnership) Option {
+	return func(c *Cache) {
+		switch o {
+		case DeviceNodeOwnershipProcessUser, DeviceNodeOwnershipRoot, DeviceNodeOwnershipHost:
+			c.nodeOwnership = o
+		default:
+			c.nodeOwnership = DefaultDeviceNodeOwnership
+		}
+	}
+}
+
+// fillOwnership fills in missing ownership and permissions according to
+// the given DeviceNodeOwnership. Host IDs are mapped using the given ID
+// mapping. DeviceNodeOwnershipProcessUser is taken care of by
+// ContainerEdits.Apply.
+func (d *DeviceNode) fillOwnership(r DeviceInfoResolver, o DeviceNodeOwnership, m *idMapping) error {
+	switch o {
+	case DeviceNodeOwnershipRoot:
+		if d.UID == nil {
+			d.UID = new(uint32)
+		}
+		if d.GID == nil {
+			d.GID = new(uint32)
+		}
+	case DeviceNodeOwnershipHost:
+		if d.UID != nil && d.GID != nil && d.FileMode != nil {
+			return nil
+		}
+		info, err := r.ResolveDeviceInfo(d.HostPath)
+		if err != nil {
+			return &DeviceInfoError{Path: d.Path, HostPath: d.HostPath, Err: err}
+		}
+		host := &DeviceNode{&cdi.DeviceNode{Path: d.Path}}
+		if d.UID == nil {
+			uid := info.UID
+			host.UID = &uid
+		}
+		if d.GID == nil {
+			gid := info.GID
+			host.GID = &gid
+		}
+		if err := m.mapDeviceNode(host); err != nil {
+			return err
+		}
+		if host.UID != nil {
+			d.UID = host.UID
+		}
+		if host.GID != nil {
+			d.GID = host.GID
+		}
+		if d.FileMode == nil && info.FileMode != 0 {
+			mode := info.FileMode
+			d.FileMode = &mode
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"os"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func uint32Ptr(v uint32) *uint32 {
+	return &v
+}
+
+func fileModePtr(m os.FileMode) *os.FileMode {
+	return &m
+}
+
+func TestDeviceNodeOwnership(t *testing.T) {
+	resolver := DeviceInfoResolverFunc(func(string) (*DeviceInfo, error) {
+		return &DeviceInfo{Type: "c", Major: 1, Minor: 3, FileMode: 0o660, UID: 10, GID: 20}, nil
+	})
+
+	spec := &cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/device",
+		Devices: []cdi.Device{
+			{
+				Name: "implicit",
+				ContainerEdits: cdi.ContainerEdits{
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/implicit"},
+					},
+				},
+			},
+			{
+				Name: "explicit",
+				ContainerEdits: cdi.ContainerEdits{
+					DeviceNodes: []*cdi.DeviceNode{
+						{
+							Path:     "/dev/explicit",
+							FileMode: fileModePtr(0o600),
+							UID:      uint32Ptr(30),
+							GID:      uint32Ptr(40),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	type owner struct {
+		uid, gid *uint32
+		mode     *os.FileMode
+	}
+	explicit := owner{uint32Ptr(30), uint32Ptr(40), fileModePtr(0o600)}
+
+	for _, tc := range []struct {
+		ownership DeviceNodeOwnership
+		implicit  owner
+	}{
+		{
+			ownership: DeviceNodeOwnershipProcessUser,
+			implicit:  owner{uint32Ptr(1000), uint32Ptr(1000), nil},
+		},
+		{
+			ownership: DeviceNodeOwnershipRoot,
+			implicit:  owner{uint32Ptr(0), uint32Ptr(0), nil},
+		},
+		{
+			ownership: DeviceNodeOwnershipHost,
+			implicit:  owner{uint32Ptr(10), uint32Ptr(20), fileModePtr(0o660)},
+		},
+	} {
+		t.Run(tc.ownership.String(), func(t *testing.T) {
+			cache := newCache(
+				WithSpecDirs(),
+				WithAutoRefresh(false),
+				WithDeviceInfoResolver(resolver),
+				WithDeviceNodeOwnership(tc.ownership),
+			)
+			require.NoError(t, cache.AddSpec(spec, 0))
+
+			ociSpec := &oci.Spec{
+				Process: &oci.Process{
+					User: oci.User{UID: 1000, GID: 1000},
+				},
+			}
+			_, err := cache.InjectDevices(ociSpec, "vendor.com/device=implicit", "vendor.com/device=explicit")
+			require.NoError(t, err)
+			require.Len(t, ociSpec.Linux.Devices, 2)
+
+			for i, o := range []owner{tc.implicit, explicit} {
+				dev := ociSpec.Linux.Devices[i]
+				require.Equal(t, o.uid, dev.UID, dev.Path)
+				require.Equal(t, o.gid, dev.GID, dev.Path)
+				require.Equal(t, o.mode, dev.FileMode, dev.Path)
+			}
+		})
+	}
+
+	require.Equal(t, DefaultDeviceNodeOwnership,
+		newCache(WithSpecDirs(), WithAutoRefresh(false), WithDeviceNodeOwnership(DeviceNodeOwnership(42))).nodeOwnership)
+}
@@ -0,0 +1,124 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// WithDevicePatterns returns an option to control whether device names
+// passed to InjectDevices can be glob patterns. By default patterns are
+// expanded: any requested name which contains one of the characters
+// '*', '?', '[' or '\' is matched against the qualified names of all
+// cached devices, using the syntax of path.Match. A pattern is replaced
+// by the matching devices in sorted order. Since these characters are
+// not allowed in device names, this does not change how valid device
+// names are resolved. Disabling pattern expansion makes InjectDevices
+// treat all names literally, so patterns end up as unresolved devices.
+func WithDevicePatterns(enable bool) Option {
+	return func(c *Cache) {
+		c.noDevicePatterns = !enable
+	}
+}
+
+// MatchDevices returns the qualified names of all cached devices which
+// match any of the given glob patterns, in sorted order. The patterns
+// use the syntax of path.Match. Might trigger a cache refresh, in which
+// case any errors encountered can be obtained using GetErrors().
+func (c *Cache) MatchDevices(patterns ...string) ([]string, error) {
+	var (
+		matches = map[string]struct{}{}
+		devices []string
+	)
+
+	unlock := c.lockRefreshed()
+	defer unlock()
+
+	for _, pattern := range patterns {
+		names, err := c.matchDevices(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if _, ok := matches[name]; !ok {
+				matches[name] = struct{}{}
+				devices = append(devices, name)
+			}
+		}
+	}
+	sort.Strings(devices)
+
+	return devices, nil
+}
+
+// matchDevices returns the sorted qualified names of all devices which
+// match the given pattern.
+func (c *Cache) matchDevices(pattern string) ([]string, error) {
+	var devices []string
+
+	pattern = c.deviceKey(pattern)
+	for key, dev := range c.devices {
+		match, err := path.Match(pattern, key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid device pattern %q: %w", pattern, err)
+		}
+		if match {
+			devices = append(devices, dev.GetQualifiedName())
+		}
+	}
+	sort.Strings(devices)
+
+	return devices, nil
+}
+
+// expandDevicePatterns replaces any patterns among the given device names
+// with the names of the available devices they match. Patterns which are
+// invalid or match no available devices are returned as unresolved.
+func (c *Cache) expandDevicePatterns(names []string) (devices, unresolved []string) {
+	if c.noDevicePatterns {
+		return names, nil
+	}
+
+	for _, name := range names {
+		if !isDevicePattern(name) {
+			devices = append(devices, name)
+			continue
+		}
+		matches, err := c.matchDevices(name)
+		available := matches[:0]
+		for _, match := range matches {
+			if _, ok := c.unavailable[c.deviceKey(match)]; !ok {
+				available = append(available, match)
+			}
+		}
+		if err != nil || len(available) == 0 {
+			unresolved = append(unresolved, name)
+			continue
+		}
+		devices = append(devices, available...)
+	}
+
+	return devices, unresolved
+}
+
+// isDevicePattern returns true if the given name is a glob pattern.
+func isDevicePattern(name string) bool {
+	return strings.ContainsAny(name, `*?[\`)
+}
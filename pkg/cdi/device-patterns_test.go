@@ -0,0 +1,167 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectDevicePatterns(t *testing.T) {
+	etc := map[string]string{
+		"vendor1.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/gpu"
+devices:
+  - name: "gpu1"
+    containerEdits:
+      env:
+      - "VENDOR1_GPU1=1"
+  - name: "gpu0"
+    containerEdits:
+      env:
+      - "VENDOR1_GPU0=1"
+  - name: "GPU2"
+    containerEdits:
+      env:
+      - "VENDOR1_GPU2=1"
+`,
+		"vendor2.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor2.com/nic"
+devices:
+  - name: "nic0"
+    containerEdits:
+      env:
+      - "VENDOR2_NIC0=1"
+`,
+	}
+
+	type testCase struct {
+		name          string
+		options       []Option
+		devices       []string
+		env           []string
+		unresolved    []string
+		expectedError bool
+	}
+	for _, tc := range []*testCase{
+		{
+			name:    "pattern is expanded in sorted order",
+			devices: []string{"vendor1.com/gpu=gpu*"},
+			env:     []string{"VENDOR1_GPU0=1", "VENDOR1_GPU1=1"},
+		},
+		{
+			name:    "patterns mixed with names",
+			devices: []string{"vendor2.com/nic=nic0", "vendor1.com/gpu=gpu[1-9]"},
+			env:     []string{"VENDOR2_NIC0=1", "VENDOR1_GPU1=1"},
+		},
+		{
+			name:    "all devices of a vendor",
+			devices: []string{"vendor1.com/*=*"},
+			env:     []string{"VENDOR1_GPU2=1", "VENDOR1_GPU0=1", "VENDOR1_GPU1=1"},
+		},
+		{
+			name:    "case-insensitive patterns",
+			options: []Option{WithCaseInsensitiveNames(true)},
+			devices: []string{"Vendor1.com/GPU=GPU?"},
+			env:     []string{"VENDOR1_GPU2=1", "VENDOR1_GPU0=1", "VENDOR1_GPU1=1"},
+		},
+		{
+			name:          "pattern without matches is unresolved",
+			devices:       []string{"vendor1.com/gpu=gpu0", "vendor3.com/*=*"},
+			unresolved:    []string{"vendor3.com/*=*"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid pattern is unresolved",
+			devices:       []string{"vendor1.com/gpu=gpu["},
+			unresolved:    []string{"vendor1.com/gpu=gpu["},
+			expectedError: true,
+		},
+		{
+			name:          "patterns disabled",
+			options:       []Option{WithDevicePatterns(false)},
+			devices:       []string{"vendor1.com/gpu=gpu0", "vendor1.com/gpu=gpu*"},
+			unresolved:    []string{"vendor1.com/gpu=gpu*"},
+			expectedError: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := createSpecDirs(t, etc, nil)
+			require.NoError(t, err)
+
+			options := append([]Option{
+				WithSpecDirs(filepath.Join(dir, "etc")),
+				WithAutoRefresh(false),
+			}, tc.options...)
+			cache := newCache(options...)
+			require.Empty(t, cache.GetErrors())
+
+			ociSpec := &oci.Spec{}
+			unresolved, err := cache.InjectDevices(ociSpec, tc.devices...)
+			if tc.expectedError {
+				require.Error(t, err)
+				require.Equal(t, tc.unresolved, unresolved)
+				return
+			}
+			require.NoError(t, err)
+			require.Nil(t, unresolved)
+			require.Equal(t, tc.env, ociSpec.Process.Env)
+		})
+	}
+}
+
+func TestMatchDevices(t *testing.T) {
+	etc := map[string]string{
+		"vendor1.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/gpu"
+devices:
+  - name: "gpu0"
+    containerEdits:
+      env:
+      - "VENDOR1_GPU0=1"
+  - name: "gpu1"
+    containerEdits:
+      env:
+      - "VENDOR1_GPU1=1"
+`,
+	}
+
+	dir, err := createSpecDirs(t, etc, nil)
+	require.NoError(t, err)
+
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+	)
+
+	devices, err := cache.MatchDevices("vendor1.com/gpu=gpu1", "vendor1.com/gpu=*")
+	require.NoError(t, err)
+	require.Equal(t, []string{"vendor1.com/gpu=gpu0", "vendor1.com/gpu=gpu1"}, devices)
+
+	devices, err = cache.MatchDevices("vendor2.com/*=*")
+	require.NoError(t, err)
+	require.Empty(t, devices)
+
+	_, err = cache.MatchDevices("vendor1.com/gpu=[")
+	require.Error(t, err)
+}
@@ -0,0 +1,252 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// ErrDeviceUnavailable is wrapped by the errors describing why a device is
+// unavailable because of its platform constraints.
+var ErrDeviceUnavailable = errors.New("CDI device unavailable")
+
+// Platform describes a host which platform constraints of Specs and
+// devices are matched against.
+type Platform struct {
+	// OS is the operating system, using the names of GOOS.
+	OS string
+	// Arch is the architecture, using the names of GOARCH.
+	Arch string
+	// KernelVersion is the kernel release, as reported by uname -r.
+	KernelVersion string
+}
+
+// HostPlatform returns the platform of the host.
+func HostPlatform() Platform {
+	return Platform{
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		KernelVersion: kernelRelease(),
+	}
+}
+
+// WithPlatform returns an option to set the platform which the platform
+// constraints of Specs and devices are matched against. By default this
+// is the platform of the host. Devices with constraints which do not match
+// are still listed by the Cache but can't be injected. Device patterns and
+// groups only expand to the matching devices. This option can be
+// used by tools preparing Spec files for other hosts.
+func WithPlatform(p Platform) Option {
+	return func(c *Cache) {
+		c.platform = &p
+	}
+}
+
+// GetUnavailableDevices returns the cached devices which are unavailable
+// because of their platform constraints, with errors describing why, by
+// qualified name. Might trigger a cache refresh, in which case any errors
+// encountered can be obtained using GetErrors().
+func (c *Cache) GetUnavailableDevices() map[string]error {
+	unlock := c.lockRefreshed()
+	defer unlock()
+
+	unavailable := make(map[string]error, len(c.unavailable))
+	for key, err := range c.unavailable {
+		unavailable[c.devices[key].GetQualifiedName()] = err
+	}
+	return unavailable
+}
+
+// getPlatform returns the platform of the Cache, detecting that of the
+// host the first time it is needed.
+func (c *Cache) getPlatform() *Platform {
+	if c.platform == nil {
+		p := HostPlatform()
+		c.platform = &p
+	}
+	return c.platform
+}
+
+// checkPlatform checks the platform constraints of the device and of its
+// Spec against the given platform.
+func checkPlatform(d *Device, p *Platform) error {
+	for _, constraints := range []*cdi.PlatformConstraints{d.spec.Platform, d.Platform} {
+		if reason := p.mismatch(constraints); reason != "" {
+			return fmt.Errorf("%w: %q %s", ErrDeviceUnavailable, d.GetQualifiedName(), reason)
+		}
+	}
+	return nil
+}
+
+// mismatch returns why the platform does not match the given constraints,
+// or an empty string if it matches them.
+func (p *Platform) mismatch(constraints *cdi.PlatformConstraints) string {
+	if constraints == nil {
+		return ""
+	}
+	if len(constraints.OS) > 0 && !containsString(constraints.OS, p.OS) {
+		return fmt.Sprintf("requires OS %s, host OS is %s",
+			strings.Join(constraints.OS, " or "), p.OS)
+	}
+	if len(constraints.Arch) > 0 && !containsString(constraints.Arch, p.Arch) {
+		return fmt.Sprintf("requires architecture %s, host architecture is %s",
+			strings.Join(constraints.Arch, " or "), p.Arch)
+	}
+	if r := constraints.KernelVersion; r != nil && (r.Min != "" || r.Max != "") {
+		kernel, err := parseKernelRelease(p.KernelVersion)
+		if err != nil {
+			return fmt.Sprintf("requires kernel version %s, %v", describeKernelRange(r), err)
+		}
+		if (r.Min != "" && compareVersions(kernel, mustParseVersion(r.Min)) < 0) ||
+			(r.Max != "" && compareVersions(kernel, mustParseVersion(r.Max)) >= 0) {
+			return fmt.Sprintf("requires kernel version %s, host kernel is %s",
+				describeKernelRange(r), p.KernelVersion)
+		}
+	}
+	return ""
+}
+
+// describeKernelRange returns a description of the kernel version range.
+func describeKernelRange(r *cdi.KernelVersionRange) string {
+	switch {
+	case r.Min != "" && r.Max != "":
+		return ">= " + r.Min + " and < " + r.Max
+	case r.Min != "":
+		return ">= " + r.Min
+	default:
+		return "< " + r.Max
+	}
+}
+
+// validatePlatform validates the platform constraints of a Spec or a
+// device with the given name.
+func validatePlatform(name string, constraints *cdi.PlatformConstraints) error {
+	if constraints == nil {
+		return nil
+	}
+	for _, o := range constraints.OS {
+		if o == "" {
+			return fmt.Errorf("invalid platform of %q, empty OS", name)
+		}
+	}
+	for _, arch := range constraints.Arch {
+		if arch == "" {
+			return fmt.Errorf("invalid platform of %q, empty architecture", name)
+		}
+	}
+	r := constraints.KernelVersion
+	if r == nil {
+		return nil
+	}
+	var min, max []int
+	if r.Min != "" {
+		v, err := parseVersion(r.Min)
+		if err != nil {
+			return fmt.Errorf("invalid platform of %q, invalid minimum kernel version: %w", name, err)
+		}
+		min = v
+	}
+	if r.Max != "" {
+		v, err := parseVersion(r.Max)
+		if err != nil {
+			return fmt.Errorf("invalid platform of %q, invalid maximum kernel version: %w", name, err)
+		}
+		max = v
+	}
+	if min != nil && max != nil && compareVersions(min, max) >= 0 {
+		return fmt.Errorf("invalid platform of %q, empty kernel version range %s",
+			name, describeKernelRange(r))
+	}
+	return nil
+}
+
+// parseVersion parses a version of dot separated numbers.
+func parseVersion(version string) ([]int, error) {
+	fields := strings.Split(version, ".")
+	v := make([]int, 0, len(fields))
+	for _, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 || strings.HasPrefix(f, "+") {
+			return nil, fmt.Errorf("invalid version %q", version)
+		}
+		v = append(v, n)
+	}
+	return v, nil
+}
+
+// mustParseVersion parses a validated version.
+func mustParseVersion(version string) []int {
+	v, err := parseVersion(version)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// parseKernelRelease parses the numeric prefix of a kernel release, for
+// instance 6.1.0 for 6.1.0-13-amd64.
+func parseKernelRelease(release string) ([]int, error) {
+	end := strings.IndexFunc(release, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if end < 0 {
+		end = len(release)
+	}
+	v, err := parseVersion(strings.TrimSuffix(release[:end], "."))
+	if err != nil {
+		return nil, fmt.Errorf("unknown host kernel version %q", release)
+	}
+	return v, nil
+}
+
+// compareVersions compares two versions, treating missing trailing numbers
+// as zeros. It returns -1, 0 or 1 if a is lower than, equal to or greater
+// than b.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// containsString returns true if the slice contains the given string.
+func containsString(slice []string, s string) bool {
+	for _, e := range slice {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestPlatformValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		spec      *cdi.PlatformConstraints
+		device    *cdi.PlatformConstraints
+		errSubstr string
+	}{
+		{
+			name: "no constraints",
+		},
+		{
+			name: "valid constraints",
+			spec: &cdi.PlatformConstraints{
+				OS:   []string{"linux"},
+				Arch: []string{"amd64", "arm64"},
+			},
+			device: &cdi.PlatformConstraints{
+				KernelVersion: &cdi.KernelVersionRange{Min: "5.15", Max: "6"},
+			},
+		},
+		{
+			name:      "empty OS",
+			spec:      &cdi.PlatformConstraints{OS: []string{""}},
+			errSubstr: "empty OS",
+		},
+		{
+			name:      "empty architecture",
+			device:    &cdi.PlatformConstraints{Arch: []string{"amd64", ""}},
+			errSubstr: "empty architecture",
+		},
+		{
+			name: "invalid kernel version",
+			device: &cdi.PlatformConstraints{
+				KernelVersion: &cdi.KernelVersionRange{Min: "5.15-rc1"},
+			},
+			errSubstr: "invalid minimum kernel version",
+		},
+		{
+			name: "empty kernel version range",
+			spec: &cdi.PlatformConstraints{
+				KernelVersion: &cdi.KernelVersionRange{Min: "6.1", Max: "6.1.0"},
+			},
+			errSubstr: "empty kernel version range",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := &cdi.Spec{
+				Version:  cdi.CurrentVersion,
+				Kind:     "vendor.com/device",
+				Platform: tc.spec,
+				Devices: []cdi.Device{
+					{
+						Name:           "dev0",
+						Platform:       tc.device,
+						ContainerEdits: cdi.ContainerEdits{Env: []string{"DEV0=1"}},
+					},
+				},
+			}
+			_, err := newSpec(raw, "/tmp/vendor.yaml", 0)
+			if tc.errSubstr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errSubstr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPlatformMismatch(t *testing.T) {
+	host := &Platform{OS: "linux", Arch: "amd64", KernelVersion: "6.1.0-13-amd64"}
+
+	for _, tc := range []struct {
+		name        string
+		constraints *cdi.PlatformConstraints
+		reason      string
+	}{
+		{
+			name: "no constraints",
+		},
+		{
+			name: "matching constraints",
+			constraints: &cdi.PlatformConstraints{
+				OS:            []string{"linux"},
+				Arch:          []string{"arm64", "amd64"},
+				KernelVersion: &cdi.KernelVersionRange{Min: "6.1", Max: "6.2"},
+			},
+		},
+		{
+			name:        "other OS",
+			constraints: &cdi.PlatformConstraints{OS: []string{"freebsd"}},
+			reason:      "requires OS freebsd, host OS is linux",
+		},
+		{
+			name:        "other architecture",
+			constraints: &cdi.PlatformConstraints{Arch: []string{"arm64", "ppc64le"}},
+			reason:      "requires architecture arm64 or ppc64le, host architecture is amd64",
+		},
+		{
+			name: "older kernel",
+			constraints: &cdi.PlatformConstraints{
+				KernelVersion: &cdi.KernelVersionRange{Min: "6.1.1"},
+			},
+			reason: "requires kernel version >= 6.1.1, host kernel is 6.1.0-13-amd64",
+		},
+		{
+			name: "newer kernel",
+			constraints: &cdi.PlatformConstraints{
+				KernelVersion: &cdi.KernelVersionRange{Max: "6.1"},
+			},
+			reason: "requires kernel version < 6.1, host kernel is 6.1.0-13-amd64",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.reason, host.mismatch(tc.constraints))
+		})
+	}
+
+	unknown := &Platform{OS: "linux", Arch: "amd64"}
+	require.Equal(t, `requires kernel version >= 5.4, unknown host kernel version ""`,
+		unknown.mismatch(&cdi.PlatformConstraints{
+			KernelVersion: &cdi.KernelVersionRange{Min: "5.4"},
+		}))
+}
+
+func TestUnavailableDevices(t *testing.T) {
+	cache := newCache(
+		WithSpecDirs(),
+		WithAutoRefresh(false),
+		WithPlatform(Platform{OS: "linux", Arch: "arm64", KernelVersion: "5.10.0"}),
+	)
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version:  cdi.CurrentVersion,
+		Kind:     "vendor.com/gpu",
+		Platform: &cdi.PlatformConstraints{OS: []string{"linux"}},
+		Devices: []cdi.Device{
+			{
+				Name:           "arm",
+				Platform:       &cdi.PlatformConstraints{Arch: []string{"arm64"}},
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"GPU=arm"}},
+			},
+			{
+				Name:           "x86",
+				Platform:       &cdi.PlatformConstraints{Arch: []string{"amd64"}},
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"GPU=x86"}},
+			},
+		},
+		Groups: []cdi.DeviceGroup{
+			{Name: "all", Devices: []string{"*"}},
+		},
+	}, 0))
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/nic",
+		Platform: &cdi.PlatformConstraints{
+			KernelVersion: &cdi.KernelVersionRange{Min: "5.15"},
+		},
+		Devices: []cdi.Device{
+			{
+				Name:           "nic0",
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"NIC=0"}},
+			},
+		},
+		Groups: []cdi.DeviceGroup{
+			{Name: "nics", Devices: []string{"nic0"}},
+		},
+	}, 0))
+
+	// unavailable devices are still loaded
+	require.Equal(t, []string{"vendor.com/gpu=arm", "vendor.com/gpu=x86", "vendor.com/nic=nic0"},
+		cache.ListDevices())
+	require.Empty(t, cache.GetErrors())
+
+	unavailable := cache.GetUnavailableDevices()
+	require.Len(t, unavailable, 2)
+	require.True(t, errors.Is(unavailable["vendor.com/gpu=x86"], ErrDeviceUnavailable))
+	require.Equal(t,
+		`CDI device unavailable: "vendor.com/gpu=x86" requires architecture amd64, host architecture is arm64`,
+		unavailable["vendor.com/gpu=x86"].Error())
+	require.Equal(t,
+		`CDI device unavailable: "vendor.com/nic=nic0" requires kernel version >= 5.15, host kernel is 5.10.0`,
+		unavailable["vendor.com/nic=nic0"].Error())
+
+	ociSpec := &oci.Spec{}
+	_, err := cache.InjectDevices(ociSpec, "vendor.com/gpu=*")
+	require.NoError(t, err)
+	require.Equal(t, []string{"GPU=arm"}, ociSpec.Process.Env)
+
+	ociSpec = &oci.Spec{}
+	_, err = cache.InjectDevices(ociSpec, "vendor.com/gpu=all")
+	require.NoError(t, err)
+	require.Equal(t, []string{"GPU=arm"}, ociSpec.Process.Env)
+
+	unresolved, err := cache.InjectDevices(&oci.Spec{}, "vendor.com/gpu=arm", "vendor.com/gpu=x86")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrDeviceUnavailable))
+	require.Equal(t, []string{"vendor.com/gpu=x86"}, unresolved)
+
+	unresolved, err = cache.InjectDevices(&oci.Spec{}, "vendor.com/nic=*")
+	require.Error(t, err)
+	require.Equal(t, []string{"vendor.com/nic=*"}, unresolved)
+
+	unresolved, err = cache.InjectDevices(&oci.Spec{}, "vendor.com/nic=nics")
+	require.Error(t, err)
+	require.Equal(t, []string{"vendor.com/nic=nics"}, unresolved)
+}
@@ -0,0 +1,134 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"tags.cncf.io/container-device-interface/internal/validation/k8s"
+	"tags.cncf.io/container-device-interface/pkg/parser"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// pciAddressRe matches PCI addresses in extended BDF notation.
+var pciAddressRe = regexp.MustCompile(`^[0-9a-fA-F]{4}:[0-9a-fA-F]{2}:[01][0-9a-fA-F]\.[0-7]$`)
+
+// GetProperties returns the properties of the device, or nil if it has
+// none.
+func (d *Device) GetProperties() *cdi.DeviceProperties {
+	return d.Properties
+}
+
+// validateProperties validates the properties of the device.
+func (d *Device) validateProperties(name string) error {
+	p := d.Properties
+	if p == nil {
+		return nil
+	}
+
+	if t := p.Topology; t != nil {
+		if t.NUMANode != nil && *t.NUMANode < 0 {
+			return fmt.Errorf("invalid device %q, invalid NUMA node %d", d.Name, *t.NUMANode)
+		}
+		if t.PCIAddress != "" && !pciAddressRe.MatchString(t.PCIAddress) {
+			return fmt.Errorf("invalid device %q, invalid PCI address %q", d.Name, t.PCIAddress)
+		}
+		for _, l := range t.Links {
+			if err := validateLinkedDevice(l.Device); err != nil {
+				return fmt.Errorf("invalid device %q, invalid link: %w", d.Name, err)
+			}
+			if l.Device == d.Name {
+				return fmt.Errorf("invalid device %q, link to itself", d.Name)
+			}
+		}
+	}
+
+	if err := k8s.ValidateAnnotations(p.Attributes, name+".properties.attributes"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateLinkedDevice validates the name of a linked device, which is
+// either a device name or a fully qualified device name.
+func validateLinkedDevice(device string) error {
+	if strings.Contains(device, "=") {
+		_, _, _, err := parser.ParseQualifiedName(device)
+		return err
+	}
+	return parser.ValidateDeviceName(device)
+}
+
+// DeviceFilter selects devices, for instance by their properties.
+type DeviceFilter func(*Device) bool
+
+// OnNUMANode returns a DeviceFilter which selects devices attached to the
+// given NUMA node.
+func OnNUMANode(node int) DeviceFilter {
+	return func(d *Device) bool {
+		p := d.GetProperties()
+		if p == nil || p.Topology == nil || p.Topology.NUMANode == nil {
+			return false
+		}
+		return *p.Topology.NUMANode == node
+	}
+}
+
+// HasAttribute returns a DeviceFilter which selects devices with the
+// given attribute value.
+func HasAttribute(name, value string) DeviceFilter {
+	return func(d *Device) bool {
+		p := d.GetProperties()
+		if p == nil {
+			return false
+		}
+		v, ok := p.Attributes[name]
+		return ok && v == value
+	}
+}
+
+// FilterDevices lists the qualified names of devices selected by all the
+// given filters. Might trigger a cache refresh, in which case any errors
+// encountered can be obtained using GetErrors().
+func (c *Cache) FilterDevices(filters ...DeviceFilter) []string {
+	var devices []string
+
+	unlock := c.lockRefreshed()
+	defer unlock()
+
+	for _, dev := range c.devices {
+		if matchesFilters(dev, filters) {
+			devices = append(devices, dev.GetQualifiedName())
+		}
+	}
+	sort.Strings(devices)
+
+	return devices
+}
+
+func matchesFilters(d *Device, filters []DeviceFilter) bool {
+	for _, f := range filters {
+		if !f(d) {
+			return false
+		}
+	}
+	return true
+}
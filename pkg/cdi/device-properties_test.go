@@ -0,0 +1,152 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestDeviceProperties(t *testing.T) {
+	numaNode := func(n int) *int { return &n }
+
+	for _, tc := range []struct {
+		name       string
+		properties *cdi.DeviceProperties
+		invalid    bool
+	}{
+		{
+			name: "no properties",
+		},
+		{
+			name: "valid properties",
+			properties: &cdi.DeviceProperties{
+				Topology: &cdi.DeviceTopology{
+					NUMANode:   numaNode(1),
+					PCIAddress: "0000:3b:00.0",
+					Links: []cdi.DeviceLink{
+						{Device: "gpu1", Type: "nvlink"},
+						{Device: "vendor.com/nic=nic0"},
+					},
+				},
+				Attributes: map[string]string{
+					"vendor.com/model": "X100",
+					"memory":           "16Gi",
+				},
+			},
+		},
+		{
+			name: "negative NUMA node",
+			properties: &cdi.DeviceProperties{
+				Topology: &cdi.DeviceTopology{NUMANode: numaNode(-1)},
+			},
+			invalid: true,
+		},
+		{
+			name: "invalid PCI address",
+			properties: &cdi.DeviceProperties{
+				Topology: &cdi.DeviceTopology{PCIAddress: "3b:00.0"},
+			},
+			invalid: true,
+		},
+		{
+			name: "invalid link",
+			properties: &cdi.DeviceProperties{
+				Topology: &cdi.DeviceTopology{
+					Links: []cdi.DeviceLink{{Device: "vendor.com=gpu1"}},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "link to itself",
+			properties: &cdi.DeviceProperties{
+				Topology: &cdi.DeviceTopology{
+					Links: []cdi.DeviceLink{{Device: "gpu0"}},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "invalid attribute",
+			properties: &cdi.DeviceProperties{
+				Attributes: map[string]string{"not valid": "x"},
+			},
+			invalid: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dev := &Device{
+				Device: &cdi.Device{
+					Name: "gpu0",
+					ContainerEdits: cdi.ContainerEdits{
+						Env: []string{"GPU=0"},
+					},
+					Properties: tc.properties,
+				},
+			}
+			err := dev.validate()
+			if tc.invalid {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.properties, dev.GetProperties())
+		})
+	}
+}
+
+func TestFilterDevices(t *testing.T) {
+	device := func(name string, node int, model string) cdi.Device {
+		return cdi.Device{
+			Name: name,
+			ContainerEdits: cdi.ContainerEdits{
+				Env: []string{"GPU=" + name},
+			},
+			Properties: &cdi.DeviceProperties{
+				Topology:   &cdi.DeviceTopology{NUMANode: &node},
+				Attributes: map[string]string{"model": model},
+			},
+		}
+	}
+
+	cache := newCache(WithSpecDirs(), WithAutoRefresh(false))
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/gpu",
+		Devices: []cdi.Device{
+			device("gpu0", 0, "X100"),
+			device("gpu1", 1, "X100"),
+			device("gpu2", 1, "X200"),
+			{
+				Name: "plain",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"GPU=plain"},
+				},
+			},
+		},
+	}, 0))
+
+	require.Equal(t, []string{"vendor.com/gpu=gpu1", "vendor.com/gpu=gpu2"},
+		cache.FilterDevices(OnNUMANode(1)))
+	require.Equal(t, []string{"vendor.com/gpu=gpu1"},
+		cache.FilterDevices(OnNUMANode(1), HasAttribute("model", "X100")))
+	require.Empty(t, cache.FilterDevices(OnNUMANode(2)))
+	require.Len(t, cache.FilterDevices(), 4)
+}
@@ -0,0 +1,142 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// WithReadinessChecks returns an option to control whether the readiness
+// probes of devices are evaluated before injection. When enabled, devices
+// with a failing probe are reported as unresolved by InjectDevices, with
+// a NotReadyError describing the failure, instead of being injected into
+// a container which can't use them, for instance because their driver is
+// not loaded yet. Probes are evaluated on every injection and their host
+// paths are re-rooted under the host root. Readiness checks are disabled
+// by default.
+func WithReadinessChecks(enable bool) Option {
+	return func(c *Cache) {
+		c.readinessChecks = enable
+	}
+}
+
+// NotReadyError is the error for a device with a failing readiness probe.
+type NotReadyError struct {
+	// Device is the fully qualified name of the device.
+	Device string
+	// Probe is the failing readiness probe.
+	Probe cdi.ReadinessProbe
+	// Err describes why the probe failed.
+	Err error
+}
+
+// Error returns the error message.
+func (e *NotReadyError) Error() string {
+	return fmt.Sprintf("CDI device %q not ready: %v", e.Device, e.Err)
+}
+
+// Unwrap returns the error describing why the probe failed.
+func (e *NotReadyError) Unwrap() error {
+	return e.Err
+}
+
+// validateReadiness validates the readiness probes of a device.
+func validateReadiness(name string, probes []cdi.ReadinessProbe) error {
+	for i := range probes {
+		if err := validateReadinessProbe(&probes[i]); err != nil {
+			return fmt.Errorf("device %q: invalid readiness probe #%d: %w", name, i, err)
+		}
+	}
+	return nil
+}
+
+// validateReadinessProbe validates a single readiness probe.
+func validateReadinessProbe(p *cdi.ReadinessProbe) error {
+	var paths []string
+	if p.FileExists != "" {
+		paths = append(paths, p.FileExists)
+	}
+	if p.DeviceOpenable != "" {
+		paths = append(paths, p.DeviceOpenable)
+	}
+	if a := p.SysfsAttribute; a != nil {
+		if !strings.HasPrefix(a.Path, "/sys/") {
+			return fmt.Errorf("sysfs attribute %q not under /sys", a.Path)
+		}
+		paths = append(paths, a.Path)
+	}
+	switch len(paths) {
+	case 0:
+		return errors.New("no check set")
+	case 1:
+	default:
+		return errors.New("more than one check set")
+	}
+	if !strings.HasPrefix(paths[0], "/") {
+		return fmt.Errorf("path %q is not absolute", paths[0])
+	}
+	return nil
+}
+
+// checkReadiness evaluates the readiness probes of the device, with host
+// paths re-rooted under the given root.
+func checkReadiness(d *Device, root string) error {
+	for _, p := range d.Readiness {
+		if err := runReadinessProbe(&p, root); err != nil {
+			return &NotReadyError{
+				Device: d.GetQualifiedName(),
+				Probe:  p,
+				Err:    err,
+			}
+		}
+	}
+	return nil
+}
+
+// runReadinessProbe evaluates a single readiness probe.
+func runReadinessProbe(p *cdi.ReadinessProbe, root string) error {
+	switch {
+	case p.FileExists != "":
+		if _, err := os.Stat(rerootPath(root, p.FileExists)); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("file %q does not exist", p.FileExists)
+			}
+			return fmt.Errorf("failed to check file %q: %w", p.FileExists, err)
+		}
+	case p.DeviceOpenable != "":
+		f, err := os.Open(rerootPath(root, p.DeviceOpenable))
+		if err != nil {
+			return fmt.Errorf("failed to open device %q: %w", p.DeviceOpenable, err)
+		}
+		f.Close()
+	case p.SysfsAttribute != nil:
+		a := p.SysfsAttribute
+		data, err := os.ReadFile(rerootPath(root, a.Path))
+		if err != nil {
+			return fmt.Errorf("failed to read sysfs attribute %q: %w", a.Path, err)
+		}
+		if value := strings.TrimSpace(string(data)); value != a.Value {
+			return fmt.Errorf("sysfs attribute %q is %q, expected %q", a.Path, value, a.Value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestReadinessChecks(t *testing.T) {
+	root := t.TempDir()
+	for path, data := range map[string]string{
+		"/etc/vendor/ready":            "",
+		"/dev/vendor0":                 "",
+		"/sys/module/vendor/initstate": "live\n",
+	} {
+		path = filepath.Join(root, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	}
+
+	device := func(name string, probes ...cdi.ReadinessProbe) cdi.Device {
+		return cdi.Device{
+			Name:           name,
+			ContainerEdits: cdi.ContainerEdits{Env: []string{"DEVICE=" + name}},
+			Readiness:      probes,
+		}
+	}
+	spec := &cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/device",
+		Devices: []cdi.Device{
+			device("ready",
+				cdi.ReadinessProbe{FileExists: "/etc/vendor/ready"},
+				cdi.ReadinessProbe{DeviceOpenable: "/dev/vendor0"},
+				cdi.ReadinessProbe{SysfsAttribute: &cdi.SysfsAttributeProbe{
+					Path:  "/sys/module/vendor/initstate",
+					Value: "live",
+				}},
+			),
+			device("nofile", cdi.ReadinessProbe{FileExists: "/etc/vendor/missing"}),
+			device("nodevice", cdi.ReadinessProbe{DeviceOpenable: "/dev/vendor1"}),
+			device("coming", cdi.ReadinessProbe{SysfsAttribute: &cdi.SysfsAttributeProbe{
+				Path:  "/sys/module/vendor/initstate",
+				Value: "coming",
+			}}),
+			device("unprobed"),
+		},
+	}
+
+	for _, tc := range []struct {
+		name       string
+		enable     bool
+		devices    []string
+		unresolved []string
+		notReady   map[string]string
+	}{
+		{
+			name:    "disabled",
+			devices: []string{"vendor.com/device=nofile", "vendor.com/device=coming"},
+		},
+		{
+			name:    "enabled, all ready",
+			enable:  true,
+			devices: []string{"vendor.com/device=ready", "vendor.com/device=unprobed"},
+		},
+		{
+			name:   "enabled, not ready",
+			enable: true,
+			devices: []string{
+				"vendor.com/device=ready",
+				"vendor.com/device=nofile",
+				"vendor.com/device=nodevice",
+				"vendor.com/device=coming",
+			},
+			unresolved: []string{
+				"vendor.com/device=nofile",
+				"vendor.com/device=nodevice",
+				"vendor.com/device=coming",
+			},
+			notReady: map[string]string{
+				"vendor.com/device=nofile":   `file "/etc/vendor/missing" does not exist`,
+				"vendor.com/device=nodevice": `failed to open device "/dev/vendor1"`,
+				"vendor.com/device=coming":   `sysfs attribute "/sys/module/vendor/initstate" is "live", expected "coming"`,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := newCache(
+				WithSpecDirs(),
+				WithAutoRefresh(false),
+				WithHostRoot(root),
+				WithReadinessChecks(tc.enable),
+			)
+			require.NoError(t, cache.AddSpec(spec, 0))
+
+			unresolved, err := cache.InjectDevices(&oci.Spec{}, tc.devices...)
+			require.Equal(t, tc.unresolved, unresolved)
+			if tc.unresolved == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			found := map[string]string{}
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var notReady *NotReadyError
+				if errors.As(e, &notReady) {
+					found[notReady.Device] = notReady.Error()
+				}
+			}
+			require.Len(t, found, len(tc.notReady))
+			for device, msg := range tc.notReady {
+				require.Contains(t, found[device], msg)
+			}
+		})
+	}
+}
+
+func TestValidateReadiness(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		probe   cdi.ReadinessProbe
+		invalid string
+	}{
+		{
+			name:  "file",
+			probe: cdi.ReadinessProbe{FileExists: "/etc/vendor/ready"},
+		},
+		{
+			name:    "no check",
+			invalid: "no check set",
+		},
+		{
+			name:    "several checks",
+			probe:   cdi.ReadinessProbe{FileExists: "/a", DeviceOpenable: "/dev/a"},
+			invalid: "more than one check set",
+		},
+		{
+			name:    "relative path",
+			probe:   cdi.ReadinessProbe{DeviceOpenable: "dev/a"},
+			invalid: `path "dev/a" is not absolute`,
+		},
+		{
+			name: "sysfs attribute outside of /sys",
+			probe: cdi.ReadinessProbe{SysfsAttribute: &cdi.SysfsAttributeProbe{
+				Path:  "/proc/modules",
+				Value: "x",
+			}},
+			invalid: `sysfs attribute "/proc/modules" not under /sys`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateReadiness("vendor.com/device=dev", []cdi.ReadinessProbe{tc.probe})
+			if tc.invalid == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.invalid)
+		})
+	}
+}
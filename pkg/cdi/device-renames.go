@@ -0,0 +1,111 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+
+	"tags.cncf.io/container-device-interface/pkg/parser"
+)
+
+// deviceRename maps the previous qualified name of a renamed device to
+// its current one.
+type deviceRename struct {
+	name   string
+	device string
+	spec   *Spec
+}
+
+// validateRenames validates the previous names of the devices of the Spec.
+// A previous name must be a valid device name and must not be in use by
+// any device or group in the Spec, or be the previous name of another
+// device.
+func (s *Spec) validateRenames(devices map[string]*Device) error {
+	renamed := map[string]string{}
+	for _, d := range s.Devices {
+		old := d.RenamedFrom
+		if old == "" {
+			continue
+		}
+		if err := parser.ValidateDeviceName(old); err != nil {
+			return fmt.Errorf("invalid device %q, invalid renamedFrom: %w", d.Name, err)
+		}
+		if _, ok := devices[old]; ok {
+			return fmt.Errorf("invalid device %q, renamedFrom %q conflicts with device", d.Name, old)
+		}
+		for _, g := range s.Groups {
+			if g.Name == old {
+				return fmt.Errorf("invalid device %q, renamedFrom %q conflicts with device group", d.Name, old)
+			}
+		}
+		if other, ok := renamed[old]; ok {
+			return fmt.Errorf("invalid devices %q and %q, both renamed from %q", other, d.Name, old)
+		}
+		renamed[old] = d.Name
+	}
+	return nil
+}
+
+// renames returns the renames of the devices of the Spec.
+func (s *Spec) renames() []*deviceRename {
+	var renames []*deviceRename
+	for _, d := range s.devices {
+		if d.RenamedFrom == "" {
+			continue
+		}
+		renames = append(renames, &deviceRename{
+			name:   parser.QualifiedName(s.GetVendor(), s.GetClass(), d.RenamedFrom),
+			device: d.GetQualifiedName(),
+			spec:   s,
+		})
+	}
+	return renames
+}
+
+// GetRenamedDevice returns the qualified name of the device the given
+// device was renamed to, or an empty string if it was not renamed. Might
+// trigger a cache refresh, in which case any errors encountered can be
+// obtained using GetErrors().
+func (c *Cache) GetRenamedDevice(device string) string {
+	unlock := c.lockRefreshed()
+	defer unlock()
+
+	if r := c.renames[c.deviceKey(device)]; r != nil {
+		return r.device
+	}
+	return ""
+}
+
+// renamedRequests returns the renames used by the given device requests.
+// It must be called with the Cache locked.
+func (c *Cache) renamedRequests(devices []string) []*deviceRename {
+	var (
+		renamed []*deviceRename
+		seen    = map[*deviceRename]struct{}{}
+	)
+	for _, device := range devices {
+		r := c.renames[c.deviceKey(device)]
+		if r == nil {
+			continue
+		}
+		if _, ok := seen[r]; !ok {
+			seen[r] = struct{}{}
+			renamed = append(renamed, r)
+		}
+	}
+	return renamed
+}
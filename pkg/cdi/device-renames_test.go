@@ -0,0 +1,201 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestRenameValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		renames   []string
+		groups    []cdi.DeviceGroup
+		errSubstr string
+	}{
+		{
+			name:    "renamed devices",
+			renames: []string{"0", "1"},
+		},
+		{
+			name:      "invalid previous name",
+			renames:   []string{"gpu/0"},
+			errSubstr: "invalid renamedFrom",
+		},
+		{
+			name:      "previous name of a device",
+			renames:   []string{"gpu1"},
+			errSubstr: "conflicts with device",
+		},
+		{
+			name:    "previous name of a group",
+			renames: []string{"all"},
+			groups: []cdi.DeviceGroup{
+				{Name: "all", Devices: []string{"*"}},
+			},
+			errSubstr: "conflicts with device group",
+		},
+		{
+			name:      "same previous name",
+			renames:   []string{"0", "0"},
+			errSubstr: "both renamed from",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    "vendor.com/device",
+				Devices: []cdi.Device{
+					{
+						Name:           "gpu0",
+						RenamedFrom:    tc.renames[0],
+						ContainerEdits: cdi.ContainerEdits{Env: []string{"GPU0=1"}},
+					},
+					{
+						Name:           "gpu1",
+						ContainerEdits: cdi.ContainerEdits{Env: []string{"GPU1=1"}},
+					},
+				},
+				Groups: tc.groups,
+			}
+			if len(tc.renames) > 1 {
+				raw.Devices[1].RenamedFrom = tc.renames[1]
+			}
+			_, err := newSpec(raw, "/tmp/vendor.yaml", 0)
+			if tc.errSubstr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errSubstr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRenamedDevices(t *testing.T) {
+	var warnings []*DeprecationWarning
+
+	cache := newCache(
+		WithSpecDirs(),
+		WithAutoRefresh(false),
+		WithDeprecationHandler(func(w *DeprecationWarning) {
+			warnings = append(warnings, w)
+		}),
+	)
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/gpu",
+		Devices: []cdi.Device{
+			{
+				Name:           "GPU-8a3f",
+				RenamedFrom:    "0",
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"GPU=8a3f"}},
+			},
+			{
+				Name:           "GPU-51c2",
+				RenamedFrom:    "1",
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"GPU=51c2"}},
+			},
+		},
+	}, 0))
+
+	require.Equal(t, "vendor.com/gpu=GPU-8a3f", cache.GetRenamedDevice("vendor.com/gpu=0"))
+	require.Equal(t, "", cache.GetRenamedDevice("vendor.com/gpu=GPU-8a3f"))
+	require.Nil(t, cache.GetDevice("vendor.com/gpu=0"))
+
+	ociSpec := &oci.Spec{}
+	_, err := cache.InjectDevices(ociSpec, "vendor.com/gpu=GPU-51c2")
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	ociSpec = &oci.Spec{}
+	_, err = cache.InjectDevices(ociSpec, "vendor.com/gpu=0", "vendor.com/gpu=0")
+	require.NoError(t, err)
+	require.Equal(t, []string{"GPU=8a3f"}, ociSpec.Process.Env)
+	require.Equal(t, []*DeprecationWarning{
+		{
+			Device:     "vendor.com/gpu=0",
+			Spec:       "memory:vendor.com-gpu",
+			ReplacedBy: "vendor.com/gpu=GPU-8a3f",
+			Renamed:    true,
+		},
+	}, warnings)
+	require.Equal(t, `CDI device "vendor.com/gpu=0" is deprecated, renamed to "vendor.com/gpu=GPU-8a3f"`,
+		warnings[0].Error())
+}
+
+func TestRenameConflicts(t *testing.T) {
+	dir, err := mkTestDir(t, map[string]map[string]string{
+		"etc": {
+			"gpu.yaml": `
+cdiVersion: "0.10.0"
+kind: vendor.com/gpu
+devices:
+  - name: GPU-8a3f
+    renamedFrom: "0"
+    containerEdits:
+      env:
+        - GPU=8a3f
+  - name: GPU-51c2
+    renamedFrom: "1"
+    containerEdits:
+      env:
+        - GPU=51c2
+`,
+			"legacy.yaml": `
+cdiVersion: "0.10.0"
+kind: vendor.com/gpu
+devices:
+  - name: "1"
+    containerEdits:
+      env:
+        - GPU=legacy
+  - name: GPU-9e07
+    renamedFrom: "0"
+    containerEdits:
+      env:
+        - GPU=9e07
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+	)
+
+	// a device using the previous name takes precedence over the rename
+	require.Equal(t, "", cache.GetRenamedDevice("vendor.com/gpu=1"))
+	ociSpec := &oci.Spec{}
+	_, err = cache.InjectDevices(ociSpec, "vendor.com/gpu=1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"GPU=legacy"}, ociSpec.Process.Env)
+
+	// conflicting renames with the same priority are dropped
+	require.Equal(t, "", cache.GetRenamedDevice("vendor.com/gpu=0"))
+	unresolved, err := cache.InjectDevices(&oci.Spec{}, "vendor.com/gpu=0")
+	require.Error(t, err)
+	require.Equal(t, []string{"vendor.com/gpu=0"}, unresolved)
+	require.Contains(t, fmt.Sprint(cache.GetErrors()), "conflicting device rename")
+}
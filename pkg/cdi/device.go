@@ -18,6 +18,8 @@ package cdi
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	oci "github.com/opencontainers/runtime-spec/specs-go"
 	"tags.cncf.io/container-device-interface/internal/validation"
@@ -28,7 +30,8 @@ import (
 // Device represents a CDI device of a Spec.
 type Device struct {
 	*cdi.Device
-	spec *Spec
+	spec     *Spec
+	priority int
 }
 
 // Create a new Device, associate it with the given Spec.
@@ -50,6 +53,12 @@ func (d *Device) GetSpec() *Spec {
 	return d.spec
 }
 
+// GetPriority returns the priority of this device for conflict resolution,
+// as set by the PriorityAnnotation of the device or its Spec.
+func (d *Device) GetPriority() int {
+	return d.priority
+}
+
 // GetQualifiedName returns the qualified name for this device.
 func (d *Device) GetQualifiedName() string {
 	return parser.QualifiedName(d.spec.GetVendor(), d.spec.GetClass(), d.Name)
@@ -62,19 +71,57 @@ func (d *Device) ApplyEdits(ociSpec *oci.Spec) error {
 
 // edits returns the applicable container edits for this spec.
 func (d *Device) edits() *ContainerEdits {
-	return &ContainerEdits{&d.ContainerEdits}
+	return &ContainerEdits{ContainerEdits: &d.ContainerEdits}
+}
+
+// setPriority sets the device priority from the device or Spec annotations.
+func (d *Device) setPriority() error {
+	value, ok := d.Annotations[PriorityAnnotation]
+	if !ok && d.spec != nil && d.spec.Spec != nil {
+		value, ok = d.spec.Annotations[PriorityAnnotation]
+	}
+	if !ok {
+		d.priority = 0
+		return nil
+	}
+
+	prio, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("invalid device %q, invalid priority %q: %w", d.Name, value, err)
+	}
+	d.priority = prio
+
+	return nil
 }
 
 // Validate the device.
 func (d *Device) validate() error {
 	if err := parser.ValidateDeviceName(d.Name); err != nil {
-		return err
+		return withSpecPath("name", err)
 	}
 	name := d.Name
 	if d.spec != nil {
 		name = d.GetQualifiedName()
 	}
 	if err := validation.ValidateSpecAnnotations(name, d.Annotations); err != nil {
+		return withSpecPath("annotations", err)
+	}
+	if err := d.validateProperties(name); err != nil {
+		return withSpecPath("properties", err)
+	}
+	if err := d.validateCapacity(); err != nil {
+		return withSpecPath("capacity", err)
+	}
+	if err := d.validateLifecycle(name); err != nil {
+		return withSpecPath("lifecycle", err)
+	}
+	if err := validatePlatform(name, d.Platform); err != nil {
+		return withSpecPath("platform", err)
+	}
+	if err := validateReadiness(name, d.Readiness); err != nil {
+		return withSpecPath("readiness", err)
+	}
+	if err := d.setPriority(); err != nil {
 		return err
 	}
 	edits := d.edits()
@@ -82,7 +129,17 @@ func (d *Device) validate() error {
 		return fmt.Errorf("invalid device, empty device edits")
 	}
 	if err := edits.Validate(); err != nil {
-		return fmt.Errorf("invalid device %q: %w", d.Name, err)
+		return withSpecPath("containerEdits", fmt.Errorf("invalid device %q: %w", d.Name, err))
+	}
+	if d.spec.hasHookTemplates() {
+		if err := edits.validateHookTemplates(); err != nil {
+			return withSpecPath("containerEdits", fmt.Errorf("invalid device %q: %w", d.Name, err))
+		}
+	}
+	if d.spec.hasHookTimeoutLimit() {
+		if err := edits.validateHookTimeouts(); err != nil {
+			return withSpecPath("containerEdits", fmt.Errorf("invalid device %q: %w", d.Name, err))
+		}
 	}
 	return nil
 }
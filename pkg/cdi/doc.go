@@ -37,6 +37,19 @@
 // as identically named package level functions which operate on the
 // default cache instance.
 //
+// Code which does not need process-wide state, like tests running in
+// parallel or embedders serving several tenants, should create their own
+// isolated Cache instances with NewCache instead. A Cache created this way
+// can also be installed as the default one using SetDefaultCache:
+//
+//	cache, _ := cdi.NewCache(cdi.WithSpecDirs("/etc/cdi"))
+//	cdi.SetDefaultCache(cache)
+//
+// Code which only resolves devices or queries Specs can depend on the
+// small DeviceResolver and SpecProvider interfaces instead of Cache. This
+// allows plugging in alternative backends, like a remote cache or a fake
+// in tests.
+//
 // # Device Injection
 //
 // Using the Cache one can inject CDI devices into a container with code
@@ -65,11 +78,96 @@
 //	    return nil
 //	}
 //
+// The way the resolved edits are rendered into the OCI Spec can be changed
+// by configuring the Cache with a custom Applier. VM-based runtimes can use
+// a VMApplier, which additionally records the devices the VMM needs to
+// handle, like VFIO devices to pass through, in OCI Spec annotations:
+//
+//	cache, _ := cdi.NewCache(cdi.WithApplier(cdi.NewVMApplier()))
+//
+// An AdmissionPolicy can restrict which Specs a Cache accepts and which
+// injections it performs. EditRestrictions covers common restrictions,
+// like admitted vendors, forbidding hooks or read-write mounts under given
+// container paths:
+//
+//	cache, _ := cdi.NewCache(cdi.WithAdmissionPolicy(&cdi.EditRestrictions{
+//	    NoHooks:       true,
+//	    ReadOnlyPaths: []string{"/usr"},
+//	}))
+//
+// Device nodes in Spec files only need to specify their path. The device
+// type, major and minor numbers are then looked up from the host device
+// node (HostPath, if given, or Path) at injection time. Lookups are
+// remembered until the next Cache refresh. Failed lookups are reported as
+// a *DeviceInfoError. Environments which consume Specs off-host can disable
+// host lookups, requiring Specs to describe device nodes fully:
+//
+//	cache, _ := cdi.NewCache(cdi.WithDeviceInfoResolver(cdi.NoDeviceInfoResolver))
+//
+// Injection is deterministic. The edits of all injected devices are
+// collected in the configured EditOrder and then put into canonical order
+// by SortEdits before they are applied: mounts by container path depth,
+// parents before children, hooks by lifecycle stage, and everything else
+// in the order it was collected. Injecting the same devices into the same
+// OCI Spec therefore always produces the same result.
+//
+// # Spec Files
+//
+// Spec files are JSON or YAML files. A YAML Spec file can contain several
+// Spec documents separated by '---' lines, for instance to ship the Specs
+// of all device classes of a driver in a single file. Each document is
+// loaded as a separate Spec. Documents which fail to load are reported as
+// errors of the Spec file without affecting the other documents of the
+// file. Use ReadSpecs to read such files outside of a Cache.
+//
+// Besides Spec directories, a Cache can read Spec files from any fs.FS,
+// for instance Specs embedded into a binary:
+//
+//	//go:embed specs/*.yaml
+//	var specs embed.FS
+//
+//	sub, _ := fs.Sub(specs, "specs")
+//	cache, _ := cdi.NewCache(cdi.WithSpecFS(sub, 0))
+//
+// Producers should write Spec files atomically, by renaming them into
+// place. Producers which write Spec files in place should hold an advisory
+// exclusive lock (flock(2)) on the file while writing it. The Cache does
+// not read Spec files locked like this, but reads them again shortly after,
+// so it does not see partially written ones. Producers which do neither
+// can be accommodated by letting the Cache retry reading invalid Spec files
+// for a while, using the WithSpecReadRetry option.
+//
+// Spec files can reference host-specific variables as ${NAME}, for instance
+// to ship the same Spec file for hosts with different driver installation
+// roots. Variables are substituted when the Spec file is loaded, from the
+// sources enabled by the WithSpecVariables, WithSpecVariablesFile and
+// WithSpecVariablesFromEnv options:
+//
+//	cache, _ := cdi.NewCache(cdi.WithSpecVariablesFile("/etc/cdi/variables"))
+//
 // # Cache Refresh
 //
 // By default the CDI Spec cache monitors the configured Spec directories
 // and automatically refreshes itself when necessary. This behavior can be
-// disabled using the WithAutoRefresh(false) option.
+// disabled using the WithAutoRefresh(false) option. When a single Spec
+// file is created, updated or removed, only that file is read again and
+// conflicts are resolved against the other, already parsed Spec files.
+// In manual refresh mode the same can be achieved with RefreshPaths()
+// or RefreshVendor().
+//
+// Producers writing many Spec files at once can batch their updates with
+// BeginUpdates(). While a batch is open, changes to Specs do not trigger
+// refreshes. The Cache is refreshed once when the batch is committed:
+//
+//	update := cache.BeginUpdates()
+//	defer update.Close()
+//	for name, spec := range specs {
+//		_ = cache.WriteSpec(spec, name)
+//	}
+//	err := update.Commit()
+//
+// Batches which are never committed or closed expire after a while (see
+// WithUpdateBatchTimeout()), so they can't keep the Cache from refreshing.
 //
 // Failure to set up monitoring for a Spec directory causes the directory to
 // get ignored and an error to be recorded among the Spec directory errors.
@@ -78,6 +176,21 @@
 // gets created, the corresponding error will be removed once the condition
 // is over.
 //
+// Monitoring can also fail because of system limits, for instance when the
+// inotify instance (EMFILE) or watch (ENOSPC) limits are exhausted. The
+// Cache then periodically tries to re-arm monitoring, as set by the option
+// WithWatchRetryInterval(), and refreshes itself once it succeeds. The state
+// of monitoring can be queried using WatcherHealthy() and WatcherError(),
+// and the WithWatchDegradedHandler() option sets a function to be notified
+// when monitoring degrades.
+//
+// Errors of Spec files are replaced on every refresh. To diagnose files
+// which keep breaking, for instance because they are partially written or
+// rotated, the Cache also keeps a bounded, timestamped history of Spec file
+// errors, along with the last time each file was loaded successfully or
+// failed to load. This history can be queried using GetSpecFileStatus() and
+// GetSpecErrorsSince(), and its bounds set with WithErrorRetention().
+//
 // With auto-refresh enabled injecting any CDI devices can be done without
 // an explicit call to Refresh(), using a code snippet similar to the
 // following:
@@ -139,7 +252,8 @@
 // directories to scan, the higher priority Spec files loaded from that
 // directory are assigned to. When two or more Spec files define the
 // same device, conflict is resolved by choosing the definition from the
-// Spec file with the highest priority.
+// Spec file with the highest priority. Conflicts between Spec files of
+// the same priority can be resolved using the PriorityAnnotation.
 //
 // The default CDI directory configuration is chosen to encourage
 // separating dynamically generated CDI Spec files from static ones.
@@ -156,7 +270,9 @@
 // Specs from CDI Spec directories. These functions, WriteSpec() and
 // RemoveSpec() implicitly follow the principle of separating dynamic Specs
 // from the rest and therefore always write to and remove Specs from the
-// last configured directory.
+// last configured directory. WriteSpecs() writes a set of related Spec
+// files at once, putting either all or none of them in place, so that
+// devices from a partially updated set of Specs are never picked up.
 //
 // Corresponding functions are also provided for generating names for Spec
 // files. These functions follow a simple naming convention to ensure that
@@ -274,4 +390,16 @@
 // schema embedded into the binary or the now default no-op schema
 // correspondingly. Other names are interpreted as the path to the actual
 // validation schema to load and use.
+//
+// Since such settings are global, each Cache can instead be given its own
+// Spec validator with the WithSpecValidator() option. The deprecated global
+// SetSpecValidator() only affects ReadSpec(), ReadSpecs() and the default
+// cache. A Cache can also select its own level of Spec content validation
+// with the WithSpecContentValidation() option.
+// The level applies both when the Cache reads Spec files and when it writes
+// them. With SpecValidationOff producers are trusted, SpecValidationSchema
+// validates against the builtin JSON schema only and SpecValidationStrict
+// adds checks of annotations and paths to the schema and any globally set
+// validator. At the strict level Spec files are also parsed strictly, with
+// duplicate keys rejected and the offending key located in the file.
 package cdi
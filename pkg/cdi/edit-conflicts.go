@@ -0,0 +1,272 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// ConflictPolicy defines how conflicting edits of injected devices are
+// handled. Edits conflict if they come from different devices, or from
+// a device and a Spec, and set
+//   - an environment variable to different values,
+//   - a mount with the same container path differently,
+//   - a device node with the same path differently, for instance with a
+//     different host path or major and minor numbers.
+//
+// Whether an edit comes first or last is determined by the EditOrder.
+type ConflictPolicy int
+
+const (
+	// ConflictLastWins silently applies conflicting edits in order, the
+	// last edit overriding the earlier ones.
+	ConflictLastWins ConflictPolicy = iota
+	// ConflictFirstWins silently drops conflicting edits after the first.
+	ConflictFirstWins
+	// ConflictFail fails injection with an error listing all conflicts,
+	// each of which is an *EditConflictError.
+	ConflictFail
+
+	// DefaultConflictPolicy is the policy used unless configured otherwise.
+	DefaultConflictPolicy = ConflictLastWins
+)
+
+// String returns the name of the ConflictPolicy.
+func (p ConflictPolicy) String() string {
+	switch p {
+	case ConflictLastWins:
+		return "last-wins"
+	case ConflictFirstWins:
+		return "first-wins"
+	case ConflictFail:
+		return "fail"
+	}
+	return fmt.Sprintf("ConflictPolicy(%d)", int(p))
+}
+
+// WithConflictPolicy returns an option to set how conflicting edits of
+// injected devices are handled. Unknown policies are ignored and
+// DefaultConflictPolicy is used instead.
+func WithConflictPolicy(policy ConflictPolicy) Option {
+	return func(c *Cache) {
+		switch policy {
+		case ConflictLastWins, ConflictFirstWins, ConflictFail:
+			c.conflictPolicy = policy
+		default:
+			c.conflictPolicy = DefaultConflictPolicy
+		}
+	}
+}
+
+// EditConflictError describes conflicting edits of two injected devices.
+type EditConflictError struct {
+	// Kind is the kind of the conflicting edits: "env", "mount", or
+	// "device node".
+	Kind string
+	// Key identifies the conflicting edits, for instance the name of an
+	// environment variable or the container path of a mount.
+	Key string
+	// First is the device, or Spec, of the edit applied first.
+	First string
+	// FirstValue is the value of the edit applied first.
+	FirstValue string
+	// Second is the device, or Spec, of the conflicting edit.
+	Second string
+	// SecondValue is the value of the conflicting edit.
+	SecondValue string
+}
+
+// Error returns the error message.
+func (e *EditConflictError) Error() string {
+	return fmt.Sprintf("conflicting %s %q: %s by %s, %s by %s",
+		e.Kind, e.Key, e.FirstValue, e.First, e.SecondValue, e.Second)
+}
+
+// editSource is the edits of a single device or Spec.
+type editSource struct {
+	name  string
+	edits *ContainerEdits
+}
+
+// editKey identifies an edit for conflict detection.
+type editKey struct {
+	kind string
+	key  string
+}
+
+// editValue is the value and source of an edit.
+type editValue struct {
+	source string
+	value  string
+}
+
+// resolveConflicts detects conflicting edits of different sources and
+// handles them according to the given policy.
+func (ec *editCollector) resolveConflicts(policy ConflictPolicy) error {
+	if policy == ConflictLastWins {
+		return nil
+	}
+
+	var sources []*editSource
+	switch ec.order {
+	case EditOrderSpecFirst:
+		sources = append(append(sources, ec.specSources...), ec.deviceSources...)
+	case EditOrderDeviceFirst:
+		sources = append(append(sources, ec.deviceSources...), ec.specSources...)
+	default:
+		sources = ec.deviceSources
+	}
+
+	var (
+		seen      = map[editKey]*editValue{}
+		conflicts []error
+		dropped   = map[*editSource]map[editKey]struct{}{}
+	)
+
+	check := func(src *editSource, kind, key, value string) {
+		k := editKey{kind, key}
+		first, ok := seen[k]
+		if !ok {
+			seen[k] = &editValue{source: src.name, value: value}
+			return
+		}
+		if first.source == src.name || first.value == value {
+			return
+		}
+		if policy == ConflictFail {
+			conflicts = append(conflicts, &EditConflictError{
+				Kind:        kind,
+				Key:         key,
+				First:       first.source,
+				FirstValue:  first.value,
+				Second:      src.name,
+				SecondValue: value,
+			})
+			return
+		}
+		if dropped[src] == nil {
+			dropped[src] = map[editKey]struct{}{}
+		}
+		dropped[src][k] = struct{}{}
+	}
+
+	for _, src := range sources {
+		e := src.edits
+		if e == nil || e.ContainerEdits == nil {
+			continue
+		}
+		for _, env := range e.Env {
+			check(src, "env", envName(env), env)
+		}
+		for _, m := range e.Mounts {
+			check(src, "mount", m.ContainerPath, mountConflictValue(m))
+		}
+		for _, n := range e.DeviceNodes {
+			check(src, "device node", n.Path, nodeConflictValue(n))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return errors.Join(conflicts...)
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	// rebuild the collected edits without the dropped ones
+	rebuild := func(sources []*editSource) *ContainerEdits {
+		edits := &ContainerEdits{}
+		for _, src := range sources {
+			if drop, ok := dropped[src]; ok {
+				src.edits = src.edits.without(drop)
+			}
+			edits.Append(src.edits)
+		}
+		return edits
+	}
+	ec.specs = rebuild(ec.specSources)
+	ec.devices = rebuild(ec.deviceSources)
+
+	return nil
+}
+
+// without returns a copy of the edits without the given ones.
+func (e *ContainerEdits) without(drop map[editKey]struct{}) *ContainerEdits {
+	edits := *e.ContainerEdits
+	edits.Env = nil
+	var policies []string
+	for i, env := range e.Env {
+		if _, ok := drop[editKey{"env", envName(env)}]; ok {
+			continue
+		}
+		edits.Env = append(edits.Env, env)
+		if e.envPolicies != nil {
+			policies = append(policies, e.envPolicies[i])
+		}
+	}
+	edits.Mounts = nil
+	for _, m := range e.Mounts {
+		if _, ok := drop[editKey{"mount", m.ContainerPath}]; !ok {
+			edits.Mounts = append(edits.Mounts, m)
+		}
+	}
+	edits.DeviceNodes = nil
+	for _, n := range e.DeviceNodes {
+		if _, ok := drop[editKey{"device node", n.Path}]; !ok {
+			edits.DeviceNodes = append(edits.DeviceNodes, n)
+		}
+	}
+
+	c := e.copyWith(&edits)
+	if e.envPolicies != nil {
+		c.envPolicies = policies
+	}
+	return c
+}
+
+// mountConflictValue returns the value of a mount for conflict detection.
+func mountConflictValue(m *cdi.Mount) string {
+	value := m.HostPath
+	if m.Type != "" {
+		value += " type " + m.Type
+	}
+	if len(m.Options) > 0 {
+		value += " options " + strings.Join(m.Options, ",")
+	}
+	return value
+}
+
+// nodeConflictValue returns the value of a device node for conflict
+// detection. Device nodes are compared as given in the Spec, before any
+// missing details are looked up on the host.
+func nodeConflictValue(n *cdi.DeviceNode) string {
+	value := n.HostPath
+	if value == "" {
+		value = n.Path
+	}
+	if n.Type != "" {
+		value += " type " + n.Type
+	}
+	if n.Major != 0 || n.Minor != 0 {
+		value += fmt.Sprintf(" %d:%d", n.Major, n.Minor)
+	}
+	return value
+}
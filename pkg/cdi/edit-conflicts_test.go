@@ -0,0 +1,120 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestConflictPolicy(t *testing.T) {
+	specA := &cdi.Spec{
+		Version: "0.3.0",
+		Kind:    "vendor.com/deva",
+		Devices: []cdi.Device{
+			{
+				Name: "dev0",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"MODE=a", "DEV0=1"},
+					Mounts: []*cdi.Mount{
+						{HostPath: "/lib/a", ContainerPath: "/lib/dev"},
+					},
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/dev", Type: "c", Major: 10, Minor: 0},
+					},
+				},
+			},
+			{
+				Name: "dev2",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"MODE=a", "DEV2=1"},
+				},
+			},
+		},
+	}
+	specB := &cdi.Spec{
+		Version: "0.3.0",
+		Kind:    "vendor.com/devb",
+		Devices: []cdi.Device{
+			{
+				Name: "dev1",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"MODE=b", "DEV1=1"},
+					Mounts: []*cdi.Mount{
+						{HostPath: "/lib/b", ContainerPath: "/lib/dev"},
+					},
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/dev", Type: "c", Major: 10, Minor: 1},
+					},
+				},
+			},
+		},
+	}
+
+	inject := func(policy ConflictPolicy, devices ...string) (*oci.Spec, error) {
+		cache := newCache(
+			WithSpecDirs(),
+			WithAutoRefresh(false),
+			WithConflictPolicy(policy),
+		)
+		require.NoError(t, cache.AddSpec(specA, 0))
+		require.NoError(t, cache.AddSpec(specB, 0))
+		ociSpec := &oci.Spec{}
+		_, err := cache.InjectDevices(ociSpec, devices...)
+		return ociSpec, err
+	}
+
+	ociSpec, err := inject(ConflictLastWins, "vendor.com/deva=dev0", "vendor.com/devb=dev1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"MODE=b", "DEV0=1", "DEV1=1"}, ociSpec.Process.Env)
+	require.Equal(t, "/lib/b", ociSpec.Mounts[0].Source)
+	require.Equal(t, int64(1), ociSpec.Linux.Devices[0].Minor)
+
+	ociSpec, err = inject(ConflictFirstWins, "vendor.com/deva=dev0", "vendor.com/devb=dev1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"MODE=a", "DEV0=1", "DEV1=1"}, ociSpec.Process.Env)
+	require.Len(t, ociSpec.Mounts, 1)
+	require.Equal(t, "/lib/a", ociSpec.Mounts[0].Source)
+	require.Len(t, ociSpec.Linux.Devices, 1)
+	require.Equal(t, int64(0), ociSpec.Linux.Devices[0].Minor)
+
+	// the Spec itself is left intact
+	require.Len(t, specB.Devices[0].ContainerEdits.Env, 2)
+
+	_, err = inject(ConflictFail, "vendor.com/deva=dev0", "vendor.com/devb=dev1")
+	require.Error(t, err)
+	var conflict *EditConflictError
+	require.True(t, errors.As(err, &conflict))
+	require.Equal(t, &EditConflictError{
+		Kind:        "env",
+		Key:         "MODE",
+		First:       "vendor.com/deva=dev0",
+		FirstValue:  "MODE=a",
+		Second:      "vendor.com/devb=dev1",
+		SecondValue: "MODE=b",
+	}, conflict)
+	require.Contains(t, err.Error(), `conflicting mount "/lib/dev"`)
+	require.Contains(t, err.Error(), `conflicting device node "/dev/dev"`)
+
+	// identical edits do not conflict
+	_, err = inject(ConflictFail, "vendor.com/deva=dev0", "vendor.com/deva=dev2", "vendor.com/deva=dev0")
+	require.NoError(t, err)
+}
@@ -0,0 +1,199 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"sort"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// EditOrder defines the order in which the Spec-level and device-level
+// container edits are applied when devices are injected into an OCI Spec.
+//
+// The order matters whenever edits of different levels touch the same
+// entity. Later edits take precedence over earlier ones:
+//   - environment variables with the same name are overwritten,
+//   - device nodes with the same container path are replaced,
+//   - mounts with the same container path are replaced,
+//   - the last IntelRdt edit is used.
+//
+// Hooks and additional GIDs are accumulated in the order they are applied.
+// Capabilities are dropped before any are added, regardless of the order.
+// Device-level edits are always applied in the order the devices were
+// requested, and Spec-level edits in the order their Specs were first
+// referenced by a requested device.
+//
+// Once collected, the edits are put into canonical order by SortEdits.
+// Injecting the same devices with the same configuration therefore always
+// produces the same OCI Spec.
+type EditOrder int
+
+const (
+	// EditOrderInterleaved applies the Spec-level edits of each Spec right
+	// before the device-level edits of the first requested device from
+	// that Spec. Devices from a Spec can therefore override the edits of
+	// their own Spec but not the edits of Specs injected later.
+	EditOrderInterleaved EditOrder = iota
+	// EditOrderSpecFirst applies the Spec-level edits of all involved Specs
+	// before any device-level edits. Device-level edits then override all
+	// Spec-level edits.
+	EditOrderSpecFirst
+	// EditOrderDeviceFirst applies all device-level edits before any Spec-
+	// level edits. Spec-level edits then override all device-level edits.
+	EditOrderDeviceFirst
+
+	// DefaultEditOrder is the order used unless configured otherwise.
+	DefaultEditOrder = EditOrderInterleaved
+)
+
+// String returns the name of the EditOrder.
+func (o EditOrder) String() string {
+	switch o {
+	case EditOrderInterleaved:
+		return "interleaved"
+	case EditOrderSpecFirst:
+		return "spec-first"
+	case EditOrderDeviceFirst:
+		return "device-first"
+	}
+	return fmt.Sprintf("EditOrder(%d)", int(o))
+}
+
+// WithEditOrder returns an option to set the order in which Spec-level
+// and device-level edits are applied during device injection. Unknown
+// orders are ignored and DefaultEditOrder is used instead.
+func WithEditOrder(order EditOrder) Option {
+	return func(c *Cache) {
+		switch order {
+		case EditOrderInterleaved, EditOrderSpecFirst, EditOrderDeviceFirst:
+			c.editOrder = order
+		default:
+			c.editOrder = DefaultEditOrder
+		}
+	}
+}
+
+// editCollector collects the edits of injected devices and their Specs
+// in the configured order.
+type editCollector struct {
+	order    EditOrder
+	seen     map[*Spec]struct{}
+	specs    *ContainerEdits
+	devices  *ContainerEdits
+	injected []*Device
+	resolver DeviceInfoResolver
+	// sources of the edits in specs and devices, for conflict detection
+	specSources   []*editSource
+	deviceSources []*editSource
+}
+
+// newEditCollector returns a collector for the given order, using the
+// given DeviceInfoResolver for expanding hook templates.
+func newEditCollector(order EditOrder, r DeviceInfoResolver) *editCollector {
+	return &editCollector{
+		order:    order,
+		resolver: r,
+		seen:     map[*Spec]struct{}{},
+		specs:    &ContainerEdits{},
+		devices:  &ContainerEdits{},
+	}
+}
+
+// add the edits of the given device and, if this is the first device
+// from its Spec, the edits of its Spec. Hook templates are expanded.
+func (ec *editCollector) add(d *Device) error {
+	spec := d.GetSpec()
+	if _, ok := ec.seen[spec]; !ok {
+		ec.seen[spec] = struct{}{}
+		edits, err := spec.hookEdits()
+		if err != nil {
+			return err
+		}
+		source := &editSource{name: "Spec " + spec.GetPath(), edits: edits}
+		if ec.order == EditOrderInterleaved {
+			ec.devices.Append(edits)
+			ec.deviceSources = append(ec.deviceSources, source)
+		} else {
+			ec.specs.Append(edits)
+			ec.specSources = append(ec.specSources, source)
+		}
+	}
+	edits, err := d.hookEdits(ec.resolver)
+	if err != nil {
+		return err
+	}
+	ec.devices.Append(edits)
+	ec.deviceSources = append(ec.deviceSources, &editSource{name: d.GetQualifiedName(), edits: edits})
+	ec.injected = append(ec.injected, d)
+	return nil
+}
+
+// edits returns the collected edits in the order they should be applied.
+func (ec *editCollector) edits() *ContainerEdits {
+	switch ec.order {
+	case EditOrderSpecFirst:
+		return (&ContainerEdits{}).Append(ec.specs).Append(ec.devices)
+	case EditOrderDeviceFirst:
+		return (&ContainerEdits{}).Append(ec.devices).Append(ec.specs)
+	}
+	return ec.devices
+}
+
+// hookStages lists the hook names in the order of the container lifecycle.
+var hookStages = map[string]int{
+	PrestartHook:        0,
+	CreateRuntimeHook:   1,
+	CreateContainerHook: 2,
+	StartContainerHook:  3,
+	PoststartHook:       4,
+	PoststopHook:        5,
+}
+
+// SortEdits puts the given edits into canonical order, in place. It is
+// applied to the collected edits of all injected devices before they are
+// handed to the Applier. The order is guaranteed to be
+//   - mounts sorted by the depth of their container path, parents before
+//     children, mounts of the same depth in the order they are applied,
+//   - hooks grouped by lifecycle stage, hooks of the same stage in the
+//     order they are applied, which follows the EditOrder and therefore
+//     the order in which Specs and devices were referenced,
+//   - environment variables, device nodes and additional GIDs in the
+//     order they are applied.
+//
+// Since sorting is stable, sorting already sorted edits is a no-op.
+func SortEdits(e *ContainerEdits) {
+	if e == nil || e.ContainerEdits == nil {
+		return
+	}
+	sort.SliceStable(e.Mounts, func(i, j int) bool {
+		return mountDepth(e.Mounts[i].ContainerPath) < mountDepth(e.Mounts[j].ContainerPath)
+	})
+	sort.SliceStable(e.Hooks, func(i, j int) bool {
+		return hookStage(e.Hooks[i]) < hookStage(e.Hooks[j])
+	})
+}
+
+// hookStage returns the lifecycle stage of the given hook. Unknown hooks
+// sort last.
+func hookStage(h *cdi.Hook) int {
+	if stage, ok := hookStages[h.HookName]; ok {
+		return stage
+	}
+	return len(hookStages)
+}
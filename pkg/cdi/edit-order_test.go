@@ -0,0 +1,348 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestEditOrder(t *testing.T) {
+	// Edits are contributed by the Spec-level edits of two Specs, "SA"
+	// and "SB", and by the devices "a1", "a2" (from SA) and "b1" (from
+	// SB). Devices are always requested in the order a1, b1, a2.
+	//
+	// Accumulated edits (hooks, GIDs) are contributed by everyone and
+	// show the order of application directly. For edits where the last
+	// one wins, entity "x" is set by SA, a1 and SB, and entity "y" by SA
+	// and a1, which is enough to tell all orders apart.
+	labels := []string{"SA", "a1", "SB", "b1", "a2"}
+	setsX := map[string]bool{"SA": true, "a1": true, "SB": true}
+	setsY := map[string]bool{"SA": true, "a1": true}
+	index := func(label string) int {
+		for i, l := range labels {
+			if l == label {
+				return i
+			}
+		}
+		return -1
+	}
+
+	type editType struct {
+		name   string
+		edits  func(label string, x, y bool) cdi.ContainerEdits
+		result func(*oci.Spec) []string
+		expect map[EditOrder][]string
+	}
+
+	lastWins := map[EditOrder][]string{
+		EditOrderInterleaved: {"SB", "a1"},
+		EditOrderSpecFirst:   {"a1", "a1"},
+		EditOrderDeviceFirst: {"SB", "SA"},
+	}
+	accumulated := map[EditOrder][]string{
+		EditOrderInterleaved: {"SA", "a1", "SB", "b1", "a2"},
+		EditOrderSpecFirst:   {"SA", "SB", "a1", "b1", "a2"},
+		EditOrderDeviceFirst: {"a1", "b1", "a2", "SA", "SB"},
+	}
+
+	for _, et := range []*editType{
+		{
+			name: "env",
+			edits: func(label string, x, y bool) cdi.ContainerEdits {
+				e := cdi.ContainerEdits{}
+				if x {
+					e.Env = append(e.Env, "X="+label)
+				}
+				if y {
+					e.Env = append(e.Env, "Y="+label)
+				}
+				return e
+			},
+			result: func(s *oci.Spec) []string {
+				var r []string
+				for _, name := range []string{"X=", "Y="} {
+					for _, env := range s.Process.Env {
+						if strings.HasPrefix(env, name) {
+							r = append(r, strings.TrimPrefix(env, name))
+						}
+					}
+				}
+				return r
+			},
+			expect: lastWins,
+		},
+		{
+			name: "device nodes",
+			edits: func(label string, x, y bool) cdi.ContainerEdits {
+				e := cdi.ContainerEdits{}
+				for path, set := range map[string]bool{"/dev/x": x, "/dev/y": y} {
+					if set {
+						e.DeviceNodes = append(e.DeviceNodes, &cdi.DeviceNode{
+							Path:  path,
+							Type:  "c",
+							Major: int64(100 + index(label)),
+						})
+					}
+				}
+				return e
+			},
+			result: func(s *oci.Spec) []string {
+				var r []string
+				for _, path := range []string{"/dev/x", "/dev/y"} {
+					for _, d := range s.Linux.Devices {
+						if d.Path == path {
+							r = append(r, labels[d.Major-100])
+						}
+					}
+				}
+				return r
+			},
+			expect: lastWins,
+		},
+		{
+			name: "mounts",
+			edits: func(label string, x, y bool) cdi.ContainerEdits {
+				e := cdi.ContainerEdits{}
+				for path, set := range map[string]bool{"/x": x, "/y": y} {
+					if set {
+						e.Mounts = append(e.Mounts, &cdi.Mount{
+							HostPath:      "/host/" + label,
+							ContainerPath: path,
+						})
+					}
+				}
+				return e
+			},
+			result: func(s *oci.Spec) []string {
+				var r []string
+				for _, path := range []string{"/x", "/y"} {
+					for _, m := range s.Mounts {
+						if m.Destination == path {
+							r = append(r, filepath.Base(m.Source))
+						}
+					}
+				}
+				return r
+			},
+			expect: lastWins,
+		},
+		{
+			name: "IntelRdt",
+			edits: func(label string, x, _ bool) cdi.ContainerEdits {
+				e := cdi.ContainerEdits{}
+				if x {
+					e.IntelRdt = &cdi.IntelRdt{ClosID: label}
+				}
+				return e
+			},
+			result: func(s *oci.Spec) []string {
+				return []string{s.Linux.IntelRdt.ClosID}
+			},
+			expect: map[EditOrder][]string{
+				EditOrderInterleaved: {"SB"},
+				EditOrderSpecFirst:   {"a1"},
+				EditOrderDeviceFirst: {"SB"},
+			},
+		},
+		{
+			name: "hooks",
+			edits: func(label string, _, _ bool) cdi.ContainerEdits {
+				return cdi.ContainerEdits{
+					Hooks: []*cdi.Hook{
+						{
+							HookName: CreateContainerHook,
+							Path:     "/bin/" + label,
+						},
+					},
+				}
+			},
+			result: func(s *oci.Spec) []string {
+				var r []string
+				for _, h := range s.Hooks.CreateContainer {
+					r = append(r, filepath.Base(h.Path))
+				}
+				return r
+			},
+			expect: accumulated,
+		},
+		{
+			name: "additional GIDs",
+			edits: func(label string, _, _ bool) cdi.ContainerEdits {
+				return cdi.ContainerEdits{
+					AdditionalGIDs: []uint32{uint32(1000 + index(label))},
+				}
+			},
+			result: func(s *oci.Spec) []string {
+				var r []string
+				for _, gid := range s.Process.User.AdditionalGids {
+					r = append(r, labels[gid-1000])
+				}
+				return r
+			},
+			expect: accumulated,
+		},
+	} {
+		edits := func(label string) cdi.ContainerEdits {
+			e := et.edits(label, setsX[label], setsY[label])
+			// make sure no device ends up with empty edits
+			e.Env = append(e.Env, "EDITED_BY_"+label+"=1")
+			return e
+		}
+		spec := func(vendor, label string, devices ...string) *cdi.Spec {
+			s := &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    vendor + ".com/device",
+				Annotations: map[string]string{
+					SharedContainerPathsAnnotation: "*",
+				},
+				ContainerEdits: edits(label),
+			}
+			for _, d := range devices {
+				s.Devices = append(s.Devices, cdi.Device{
+					Name:           d,
+					ContainerEdits: edits(d),
+				})
+			}
+			return s
+		}
+
+		for _, order := range []EditOrder{EditOrderInterleaved, EditOrderSpecFirst, EditOrderDeviceFirst} {
+			t.Run(et.name+"/"+order.String(), func(t *testing.T) {
+				cache := newCache(
+					WithSpecDirs(),
+					WithAutoRefresh(false),
+					WithEditOrder(order),
+				)
+				require.NoError(t, cache.AddSpec(spec("vendora", "SA", "a1", "a2"), 0))
+				require.NoError(t, cache.AddSpec(spec("vendorb", "SB", "b1"), 0))
+
+				ociSpec := &oci.Spec{}
+				unresolved, err := cache.InjectDevices(ociSpec,
+					"vendora.com/device=a1",
+					"vendorb.com/device=b1",
+					"vendora.com/device=a2",
+				)
+				require.NoError(t, err)
+				require.Nil(t, unresolved)
+				require.Equal(t, et.expect[order], et.result(ociSpec))
+			})
+		}
+	}
+}
+
+func TestDefaultEditOrder(t *testing.T) {
+	require.Equal(t, EditOrderInterleaved, DefaultEditOrder)
+	require.Equal(t, DefaultEditOrder, newCache(WithSpecDirs(), WithAutoRefresh(false)).editOrder)
+	require.Equal(t, DefaultEditOrder, newCache(WithSpecDirs(), WithAutoRefresh(false), WithEditOrder(EditOrder(42))).editOrder)
+	require.Equal(t, "EditOrder(42)", EditOrder(42).String())
+}
+
+func TestSortEdits(t *testing.T) {
+	edits := &ContainerEdits{
+		ContainerEdits: &cdi.ContainerEdits{
+			Env: []string{"B=1", "A=1"},
+			Mounts: []*cdi.Mount{
+				{HostPath: "/h/c", ContainerPath: "/a/b/c"},
+				{HostPath: "/h/a", ContainerPath: "/a"},
+				{HostPath: "/h/x", ContainerPath: "/x/y"},
+				{HostPath: "/h/b", ContainerPath: "/a/b/"},
+			},
+			Hooks: []*cdi.Hook{
+				{HookName: PoststopHook, Path: "/bin/stop"},
+				{HookName: CreateContainerHook, Path: "/bin/spec-hook"},
+				{HookName: PrestartHook, Path: "/bin/prestart"},
+				{HookName: CreateContainerHook, Path: "/bin/device-hook"},
+			},
+		},
+	}
+
+	SortEdits(edits)
+
+	var mounts, hooks []string
+	for _, m := range edits.Mounts {
+		mounts = append(mounts, m.ContainerPath)
+	}
+	for _, h := range edits.Hooks {
+		hooks = append(hooks, h.Path)
+	}
+	require.Equal(t, []string{"/a", "/x/y", "/a/b/", "/a/b/c"}, mounts)
+	require.Equal(t, []string{"/bin/prestart", "/bin/spec-hook", "/bin/device-hook", "/bin/stop"}, hooks)
+	require.Equal(t, []string{"B=1", "A=1"}, edits.Env)
+
+	SortEdits(nil)
+	SortEdits(&ContainerEdits{})
+}
+
+func TestInjectionIsDeterministic(t *testing.T) {
+	spec := &cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/device",
+		ContainerEdits: cdi.ContainerEdits{
+			Mounts: []*cdi.Mount{
+				{HostPath: "/h/lib", ContainerPath: "/usr/lib/vendor/lib"},
+			},
+		},
+		Devices: []cdi.Device{
+			{
+				Name: "dev0",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"DEV0=1"},
+					Mounts: []*cdi.Mount{
+						{HostPath: "/h/vendor", ContainerPath: "/usr/lib/vendor"},
+					},
+				},
+			},
+			{
+				Name: "dev1",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"DEV1=1"},
+					Mounts: []*cdi.Mount{
+						{HostPath: "/h/conf", ContainerPath: "/usr/lib/vendor/lib/conf"},
+					},
+				},
+			},
+		},
+	}
+
+	var first *oci.Spec
+	for i := 0; i < 10; i++ {
+		cache := newCache(WithSpecDirs(), WithAutoRefresh(false))
+		require.NoError(t, cache.AddSpec(spec, 0))
+
+		ociSpec := &oci.Spec{}
+		_, err := cache.InjectDevices(ociSpec, "vendor.com/device=*")
+		require.NoError(t, err)
+		if first == nil {
+			first = ociSpec
+			continue
+		}
+		require.Equal(t, first, ociSpec)
+	}
+
+	var mounts []string
+	for _, m := range first.Mounts {
+		mounts = append(mounts, m.Destination)
+	}
+	require.Equal(t, []string{"/usr/lib/vendor", "/usr/lib/vendor/lib", "/usr/lib/vendor/lib/conf"}, mounts)
+	require.Equal(t, []string{"DEV0=1", "DEV1=1"}, first.Process.Env)
+}
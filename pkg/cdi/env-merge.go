@@ -0,0 +1,194 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"strings"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// EnvMergePolicy defines how environment variables injected by CDI are
+// merged with the ones already set in the OCI Spec. The policy can be set
+// for a Cache using WithEnvMergePolicy(), and overridden by container
+// edits in a Spec using the envPolicy field.
+//
+// Among themselves, environment variables injected by CDI are merged
+// according to the EditOrder, later ones overriding earlier ones, unless
+// the EnvMergeAppend policy applies.
+type EnvMergePolicy int
+
+const (
+	// EnvMergeOverride overrides variables already set in the OCI Spec.
+	EnvMergeOverride EnvMergePolicy = iota
+	// EnvMergeAppend appends variables to the OCI Spec, even if a variable
+	// with the same name is already set.
+	EnvMergeAppend
+	// EnvMergeSkipIfPresent leaves variables already set in the OCI Spec
+	// intact, only setting the ones which are not set yet.
+	EnvMergeSkipIfPresent
+
+	// DefaultEnvMergePolicy is the policy used unless configured otherwise.
+	DefaultEnvMergePolicy = EnvMergeOverride
+)
+
+// String returns the name of the EnvMergePolicy, which is also the value
+// used for it in Specs.
+func (p EnvMergePolicy) String() string {
+	switch p {
+	case EnvMergeOverride:
+		return cdi.EnvPolicyOverride
+	case EnvMergeAppend:
+		return cdi.EnvPolicyAppend
+	case EnvMergeSkipIfPresent:
+		return cdi.EnvPolicySkipIfPresent
+	}
+	return fmt.Sprintf("EnvMergePolicy(%d)", int(p))
+}
+
+// ParseEnvMergePolicy returns the EnvMergePolicy with the given name.
+func ParseEnvMergePolicy(name string) (EnvMergePolicy, error) {
+	switch name {
+	case cdi.EnvPolicyOverride:
+		return EnvMergeOverride, nil
+	case cdi.EnvPolicyAppend:
+		return EnvMergeAppend, nil
+	case cdi.EnvPolicySkipIfPresent:
+		return EnvMergeSkipIfPresent, nil
+	}
+	return DefaultEnvMergePolicy, fmt.Errorf("invalid environment merge policy %q", name)
+}
+
+// WithEnvMergePolicy returns an option to set how injected environment
+// variables are merged with the ones already set in the OCI Spec, unless
+// a Spec sets a policy for its container edits. Unknown policies are
+// ignored and DefaultEnvMergePolicy is used instead.
+func WithEnvMergePolicy(policy EnvMergePolicy) Option {
+	return func(c *Cache) {
+		switch policy {
+		case EnvMergeOverride, EnvMergeAppend, EnvMergeSkipIfPresent:
+			c.envPolicy = policy
+		default:
+			c.envPolicy = DefaultEnvMergePolicy
+		}
+	}
+}
+
+// withEnvMergePolicy returns the edits with the given policy used for
+// variables without a policy of their own.
+func (e *ContainerEdits) withEnvMergePolicy(policy EnvMergePolicy) *ContainerEdits {
+	if e != nil {
+		e.envPolicy = policy
+	}
+	return e
+}
+
+// copyWith returns new edits with the given content and the environment
+// merge policies of these edits.
+func (e *ContainerEdits) copyWith(edits *cdi.ContainerEdits) *ContainerEdits {
+	return &ContainerEdits{
+		ContainerEdits: edits,
+		envPolicies:    e.envPolicies,
+		envPolicy:      e.envPolicy,
+		noDedup:        e.noDedup,
+		noCgroupRules:  e.noCgroupRules,
+	}
+}
+
+// hasEnvPolicies returns true if any variable of the edits has a policy.
+func (e *ContainerEdits) hasEnvPolicies() bool {
+	return e.envPolicies != nil || e.EnvPolicy != ""
+}
+
+// envPolicyList returns the policies of all variables of the edits.
+func (e *ContainerEdits) envPolicyList() []string {
+	if e.envPolicies != nil {
+		return e.envPolicies
+	}
+	policies := make([]string, len(e.Env))
+	for i := range policies {
+		policies[i] = e.EnvPolicy
+	}
+	return policies
+}
+
+// envPolicyAt returns the policy of the variable with the given index.
+func (e *ContainerEdits) envPolicyAt(idx int) EnvMergePolicy {
+	name := e.EnvPolicy
+	if e.envPolicies != nil {
+		name = e.envPolicies[idx]
+	}
+	if name == "" {
+		return e.envPolicy
+	}
+	policy, err := ParseEnvMergePolicy(name)
+	if err != nil {
+		return e.envPolicy
+	}
+	return policy
+}
+
+// applyEnv merges the environment variables of the edits into the OCI Spec.
+func (e *ContainerEdits) applyEnv(spec *oci.Spec) {
+	if len(e.Env) == 0 {
+		return
+	}
+	if spec.Process == nil {
+		spec.Process = &oci.Process{}
+	}
+
+	var (
+		env     = spec.Process.Env
+		present = make(map[string]struct{}, len(env))
+		index   = make(map[string]int, len(env)+len(e.Env))
+	)
+	for i, v := range env {
+		name := envName(v)
+		present[name] = struct{}{}
+		index[name] = i
+	}
+
+	for i, v := range e.Env {
+		name := envName(v)
+		switch e.envPolicyAt(i) {
+		case EnvMergeAppend:
+			env = append(env, v)
+			index[name] = len(env) - 1
+			continue
+		case EnvMergeSkipIfPresent:
+			if _, ok := present[name]; ok {
+				continue
+			}
+		}
+		if idx, ok := index[name]; ok {
+			env[idx] = v
+		} else {
+			env = append(env, v)
+			index[name] = len(env) - 1
+		}
+	}
+
+	spec.Process.Env = env
+}
+
+// envName returns the name of an environment variable.
+func envName(v string) string {
+	name, _, _ := strings.Cut(v, "=")
+	return name
+}
@@ -0,0 +1,118 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestEnvMergePolicy(t *testing.T) {
+	spec := func(specPolicy, devPolicy string) *cdi.Spec {
+		return &cdi.Spec{
+			Version: cdi.CurrentVersion,
+			Kind:    "vendor.com/dev",
+			ContainerEdits: cdi.ContainerEdits{
+				Env:       []string{"PATH=/vendor/bin", "VENDOR=1"},
+				EnvPolicy: specPolicy,
+			},
+			Devices: []cdi.Device{
+				{
+					Name: "dev0",
+					ContainerEdits: cdi.ContainerEdits{
+						Env:       []string{"DEBUG=1", "DEV=0"},
+						EnvPolicy: devPolicy,
+					},
+				},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name       string
+		policy     EnvMergePolicy
+		specPolicy string
+		devPolicy  string
+		result     []string
+	}{
+		{
+			name:   "default policy overrides",
+			result: []string{"PATH=/vendor/bin", "DEBUG=1", "VENDOR=1", "DEV=0"},
+		},
+		{
+			name:   "append",
+			policy: EnvMergeAppend,
+			result: []string{"PATH=/bin", "DEBUG=0", "PATH=/vendor/bin", "VENDOR=1", "DEBUG=1", "DEV=0"},
+		},
+		{
+			name:   "skip if present",
+			policy: EnvMergeSkipIfPresent,
+			result: []string{"PATH=/bin", "DEBUG=0", "VENDOR=1", "DEV=0"},
+		},
+		{
+			name:      "per-edit policy overrides cache policy",
+			policy:    EnvMergeAppend,
+			devPolicy: cdi.EnvPolicySkipIfPresent,
+			result:    []string{"PATH=/bin", "DEBUG=0", "PATH=/vendor/bin", "VENDOR=1", "DEV=0"},
+		},
+		{
+			name:       "mixed per-edit policies",
+			specPolicy: cdi.EnvPolicySkipIfPresent,
+			devPolicy:  cdi.EnvPolicyOverride,
+			result:     []string{"PATH=/bin", "DEBUG=1", "VENDOR=1", "DEV=0"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := newCache(
+				WithSpecDirs(),
+				WithAutoRefresh(false),
+				WithEnvMergePolicy(tc.policy),
+			)
+			require.NoError(t, cache.AddSpec(spec(tc.specPolicy, tc.devPolicy), 0))
+
+			ociSpec := &oci.Spec{
+				Process: &oci.Process{
+					Env: []string{"PATH=/bin", "DEBUG=0"},
+				},
+			}
+			_, err := cache.InjectDevices(ociSpec, "vendor.com/dev=dev0")
+			require.NoError(t, err)
+			require.Equal(t, tc.result, ociSpec.Process.Env)
+		})
+	}
+}
+
+func TestParseEnvMergePolicy(t *testing.T) {
+	for _, p := range []EnvMergePolicy{EnvMergeOverride, EnvMergeAppend, EnvMergeSkipIfPresent} {
+		parsed, err := ParseEnvMergePolicy(p.String())
+		require.NoError(t, err)
+		require.Equal(t, p, parsed)
+	}
+	_, err := ParseEnvMergePolicy("replace")
+	require.Error(t, err)
+
+	edits := &ContainerEdits{
+		ContainerEdits: &cdi.ContainerEdits{
+			Env:       []string{"FOO=BAR"},
+			EnvPolicy: "replace",
+		},
+	}
+	require.Error(t, edits.Validate())
+}
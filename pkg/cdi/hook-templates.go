@@ -0,0 +1,231 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"golang.org/x/mod/semver"
+	"tags.cncf.io/container-device-interface/pkg/parser"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// HookTemplateData is the data available to templates in hook arguments
+// and environment variables. In Specs of CDI version 0.9.0 or later, any
+// hook argument or environment variable containing "{{" is treated as a
+// text/template and expanded when the device is injected. A literal "{{"
+// can be written as {{ "{{" }}. Specs of earlier versions containing "{{"
+// in hooks are invalid. For instance, the argument
+// "--device={{ .DeviceName }}" becomes "--device=gpu0" when injecting
+// the device "vendor.com/gpu=gpu0".
+//
+// Templates in device-level hooks are expanded with the data of the device.
+// Templates in Spec-level hooks are expanded with the data of the Spec, so
+// the device-specific fields are left empty.
+type HookTemplateData struct {
+	// Vendor is the vendor of the Spec.
+	Vendor string
+	// Class is the device class of the Spec.
+	Class string
+	// DeviceName is the unqualified name of the device.
+	DeviceName string
+	// QualifiedName is the fully qualified name of the device.
+	QualifiedName string
+	// Path is the container path of the first device node of the device.
+	Path string
+	// HostPath is the host path of the first device node of the device.
+	HostPath string
+	// Type is the type of the first device node of the device.
+	Type string
+	// Major is the major number of the first device node of the device.
+	Major int64
+	// Minor is the minor number of the first device node of the device.
+	Minor int64
+	// DeviceNodes are all device nodes of the device, with any missing
+	// host path, type, major and minor filled in from the host device.
+	DeviceNodes []*cdi.DeviceNode
+}
+
+// hookTemplatesVersion is the first CDI version with hook templates.
+const hookTemplatesVersion = "0.9.0"
+
+// hasHookTemplates returns true if hooks of the Spec are templates,
+// which depends only on the CDI version of the Spec.
+func (s *Spec) hasHookTemplates() bool {
+	return s != nil && s.Spec != nil &&
+		semver.Compare(semverOf(s.Version), semverOf(hookTemplatesVersion)) >= 0
+}
+
+// isHookTemplate returns true if the given string is a hook template.
+func isHookTemplate(s string) bool {
+	return strings.Contains(s, "{{")
+}
+
+// hasHookTemplates returns true if any hook of the edits uses templates.
+func (e *ContainerEdits) hasHookTemplates() bool {
+	if e == nil || e.ContainerEdits == nil {
+		return false
+	}
+	for _, h := range e.Hooks {
+		for _, s := range hookStrings(h) {
+			if isHookTemplate(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hookStrings returns all arguments and environment variables of a hook.
+func hookStrings(h *cdi.Hook) []string {
+	s := make([]string, 0, len(h.Args)+len(h.Env))
+	s = append(s, h.Args...)
+	return append(s, h.Env...)
+}
+
+// expandHookTemplate expands a single hook template with the given data.
+func expandHookTemplate(s string, data *HookTemplateData) (string, error) {
+	if !isHookTemplate(s) {
+		return s, nil
+	}
+	tmpl, err := template.New("hook").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid hook template %q: %w", s, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to expand hook template %q: %w", s, err)
+	}
+	return out.String(), nil
+}
+
+// expandHookTemplates expands all templates in the given strings.
+func expandHookTemplates(strs []string, data *HookTemplateData) ([]string, error) {
+	if strs == nil {
+		return nil, nil
+	}
+	expanded := make([]string, len(strs))
+	for i, s := range strs {
+		var err error
+		if expanded[i], err = expandHookTemplate(s, data); err != nil {
+			return nil, err
+		}
+	}
+	return expanded, nil
+}
+
+// validateHookTemplates checks that all hook templates of the edits can
+// be parsed and only refer to known template data.
+func (e *ContainerEdits) validateHookTemplates() error {
+	if e == nil || e.ContainerEdits == nil {
+		return nil
+	}
+	for _, h := range e.Hooks {
+		for _, s := range hookStrings(h) {
+			if _, err := expandHookTemplate(s, &HookTemplateData{}); err != nil {
+				return fmt.Errorf("invalid hook %q: %w", h.HookName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// expandHookTemplates returns a copy of the edits with all hook templates
+// expanded using the given data. If no hooks use templates, the edits are
+// returned as such.
+func (e *ContainerEdits) expandHookTemplates(data *HookTemplateData) (*ContainerEdits, error) {
+	if !e.hasHookTemplates() {
+		return e, nil
+	}
+
+	edits := *e.ContainerEdits
+	edits.Hooks = make([]*cdi.Hook, 0, len(e.Hooks))
+	for _, h := range e.Hooks {
+		hook := *h
+		args, err := expandHookTemplates(h.Args, data)
+		if err != nil {
+			return nil, fmt.Errorf("hook %q: %w", h.HookName, err)
+		}
+		env, err := expandHookTemplates(h.Env, data)
+		if err != nil {
+			return nil, fmt.Errorf("hook %q: %w", h.HookName, err)
+		}
+		hook.Args, hook.Env = args, env
+		edits.Hooks = append(edits.Hooks, &hook)
+	}
+
+	return e.copyWith(&edits), nil
+}
+
+// hookTemplateData returns the hook template data for the Spec.
+func (s *Spec) hookTemplateData() *HookTemplateData {
+	return &HookTemplateData{
+		Vendor: s.GetVendor(),
+		Class:  s.GetClass(),
+	}
+}
+
+// hookTemplateData returns the hook template data for the device, using
+// the given DeviceInfoResolver to fill in missing device information.
+func (d *Device) hookTemplateData(r DeviceInfoResolver) (*HookTemplateData, error) {
+	spec := d.GetSpec()
+	data := spec.hookTemplateData()
+	data.DeviceName = d.Name
+	data.QualifiedName = parser.QualifiedName(data.Vendor, data.Class, d.Name)
+
+	for _, n := range d.ContainerEdits.DeviceNodes {
+		dn := *n
+		if err := (&DeviceNode{&dn}).fillMissingInfo(r); err != nil {
+			return nil, err
+		}
+		data.DeviceNodes = append(data.DeviceNodes, &dn)
+	}
+	if len(data.DeviceNodes) > 0 {
+		dn := data.DeviceNodes[0]
+		data.Path = dn.Path
+		data.HostPath = dn.HostPath
+		data.Type = dn.Type
+		data.Major = dn.Major
+		data.Minor = dn.Minor
+	}
+
+	return data, nil
+}
+
+// hookEdits returns the edits of the Spec with hook templates expanded.
+func (s *Spec) hookEdits() (*ContainerEdits, error) {
+	if !s.hasHookTemplates() {
+		return s.edits(), nil
+	}
+	return s.edits().expandHookTemplates(s.hookTemplateData())
+}
+
+// hookEdits returns the edits of the device with hook templates expanded.
+func (d *Device) hookEdits(r DeviceInfoResolver) (*ContainerEdits, error) {
+	edits := d.edits()
+	if !d.GetSpec().hasHookTemplates() || !edits.hasHookTemplates() {
+		return edits, nil
+	}
+	data, err := d.hookTemplateData(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand hook templates for device %q: %w",
+			d.GetQualifiedName(), err)
+	}
+	return edits.expandHookTemplates(data)
+}
@@ -0,0 +1,237 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestHookTemplates(t *testing.T) {
+	etc := map[string]string{
+		"vendor1.yaml": `
+cdiVersion: "0.9.0"
+kind:       "vendor1.com/device"
+containerEdits:
+  hooks:
+  - hookName: createRuntime
+    path: "/usr/bin/setup"
+    args: ["setup", "--vendor={{ .Vendor }}", "--class={{ .Class }}", "--device={{ .DeviceName }}"]
+devices:
+  - name: "dev0"
+    containerEdits:
+      deviceNodes:
+      - path: "/dev/vendor1/dev0"
+        hostPath: "/dev/vendor1-dev0"
+        type: c
+        major: 200
+        minor: 10
+      - path: "/dev/vendor1/ctl"
+        type: c
+        major: 200
+        minor: 255
+      hooks:
+      - hookName: createContainer
+        path: "/usr/bin/hook"
+        args: ["hook", "--device={{ .QualifiedName }}", "--node={{ .Major }}:{{ .Minor }}", "--host={{ .HostPath }}"]
+        env: ["DEVICE_PATH={{ .Path }}", "NODES={{ range .DeviceNodes }}{{ .Path }},{{ end }}", "PLAIN=value"]
+  - name: "dev1"
+    containerEdits:
+      deviceNodes:
+      - path: "/dev/vendor1/dev1"
+        type: c
+        major: 200
+        minor: 11
+      hooks:
+      - hookName: createContainer
+        path: "/usr/bin/hook"
+        args: ["hook", "--device={{ .QualifiedName }}", "--node={{ .Major }}:{{ .Minor }}", "--host={{ .HostPath }}"]
+`,
+	}
+
+	dir, err := createSpecDirs(t, etc, nil)
+	require.NoError(t, err)
+
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+	)
+	require.Empty(t, cache.GetErrors())
+
+	ociSpec := &oci.Spec{}
+	unresolved, err := cache.InjectDevices(ociSpec, "vendor1.com/device=dev0", "vendor1.com/device=dev1")
+	require.NoError(t, err)
+	require.Nil(t, unresolved)
+
+	require.Equal(t,
+		[]oci.Hook{
+			{
+				Path: "/usr/bin/setup",
+				Args: []string{"setup", "--vendor=vendor1.com", "--class=device", "--device="},
+			},
+		},
+		ociSpec.Hooks.CreateRuntime,
+	)
+	require.Equal(t,
+		[]oci.Hook{
+			{
+				Path: "/usr/bin/hook",
+				Args: []string{"hook", "--device=vendor1.com/device=dev0", "--node=200:10", "--host=/dev/vendor1-dev0"},
+				Env:  []string{"DEVICE_PATH=/dev/vendor1/dev0", "NODES=/dev/vendor1/dev0,/dev/vendor1/ctl,", "PLAIN=value"},
+			},
+			{
+				Path: "/usr/bin/hook",
+				Args: []string{"hook", "--device=vendor1.com/device=dev1", "--node=200:11", "--host=/dev/vendor1/dev1"},
+			},
+		},
+		ociSpec.Hooks.CreateContainer,
+	)
+
+	// the cached Spec must not be modified by the expansion
+	dev := cache.GetDevice("vendor1.com/device=dev0")
+	require.NotNil(t, dev)
+	require.Equal(t, "--device={{ .QualifiedName }}", dev.ContainerEdits.Hooks[0].Args[1])
+}
+
+func TestValidateHookTemplates(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		version string
+		args    []string
+		env     []string
+		invalid bool
+	}{
+		{
+			name:    "no templates",
+			version: "0.9.0",
+			args:    []string{"hook", "{ .DeviceName }"},
+			env:     []string{"VAR=}}"},
+		},
+		{
+			name:    "valid templates",
+			version: "0.9.0",
+			args:    []string{"hook", "--device={{ .DeviceName }}", "{{ printf \"%d\" .Major }}"},
+			env:     []string{"VAR={{ .HostPath }}"},
+		},
+		{
+			name:    "unparsable template",
+			version: "0.9.0",
+			args:    []string{"hook", "--device={{ .DeviceName "},
+			invalid: true,
+		},
+		{
+			name:    "unknown field",
+			version: "0.9.0",
+			env:     []string{"VAR={{ .BusID }}"},
+			invalid: true,
+		},
+		{
+			name:    "templates require v0.9.0",
+			version: "0.8.0",
+			args:    []string{"hook", "--device={{ .DeviceName }}"},
+			invalid: true,
+			env:     []string{"VAR={{ .BusID }}"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hook := &cdi.Hook{
+				HookName: CreateContainerHook,
+				Path:     "/usr/bin/hook",
+				Args:     tc.args,
+				Env:      tc.env,
+			}
+			for _, raw := range []*cdi.Spec{
+				{
+					Version:        tc.version,
+					Kind:           "vendor.com/device",
+					ContainerEdits: cdi.ContainerEdits{Hooks: []*cdi.Hook{hook}},
+					Devices: []cdi.Device{
+						{
+							Name:           "dev0",
+							ContainerEdits: cdi.ContainerEdits{Env: []string{"FOO=bar"}},
+						},
+					},
+				},
+				{
+					Version: tc.version,
+					Kind:    "vendor.com/device",
+					Devices: []cdi.Device{
+						{
+							Name:           "dev0",
+							ContainerEdits: cdi.ContainerEdits{Hooks: []*cdi.Hook{hook}},
+						},
+					},
+				},
+			} {
+				_, err := newSpec(raw, "vendor.yaml", 0)
+				if tc.invalid {
+					require.Error(t, err)
+				} else {
+					require.NoError(t, err)
+				}
+			}
+		})
+	}
+}
+
+func TestHookTemplatesVersion(t *testing.T) {
+	const spec = `
+cdiVersion: "%s"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev0"
+    containerEdits:
+      hooks:
+      - hookName: createContainer
+        path: "/usr/bin/hook"
+        args: ["hook", "--device={{ .DeviceName }}", "--literal={{ \"{{\" }}"]
+`
+	for version, expected := range map[string][]string{
+		"0.8.0": nil,
+		"0.9.0": {"hook", "--device=dev0", "--literal={{"},
+	} {
+		t.Run(version, func(t *testing.T) {
+			dir, err := createSpecDirs(t, map[string]string{
+				"vendor1.yaml": fmt.Sprintf(spec, version),
+			}, nil)
+			require.NoError(t, err)
+
+			cache := newCache(
+				WithSpecDirs(filepath.Join(dir, "etc")),
+				WithAutoRefresh(false),
+			)
+			if expected == nil {
+				// templates in earlier Specs are rejected, not used as such
+				require.NotEmpty(t, cache.GetErrors())
+				require.Nil(t, cache.GetDevice("vendor1.com/device=dev0"))
+				return
+			}
+			require.Empty(t, cache.GetErrors())
+
+			ociSpec := &oci.Spec{}
+			_, err = cache.InjectDevices(ociSpec, "vendor1.com/device=dev0")
+			require.NoError(t, err)
+			require.Len(t, ociSpec.Hooks.CreateContainer, 1)
+			require.Equal(t, expected, ociSpec.Hooks.CreateContainer[0].Args)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"context"
+	"path/filepath"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// hostRootKey is the context key of per-call host roots.
+type hostRootKey struct{}
+
+// WithHostRoot returns an option to re-root the host paths of injected
+// devices under the given directory. This is meant for consumers running
+// in a container with the host file system mounted, for instance at /host.
+// The host path of device nodes (HostPath, or Path if HostPath is not set)
+// and of bind mounts are prefixed with the root, both when they are looked
+// up or validated and in the injected edits. Paths of device nodes in the
+// container are left unchanged. An empty root or "/", the default, leaves
+// host paths unchanged.
+func WithHostRoot(root string) Option {
+	return func(c *Cache) {
+		c.hostRoot = root
+	}
+}
+
+// ContextWithHostRoot returns a context which makes InjectDevicesContext
+// re-root host paths under the given directory instead of the host root
+// of the Cache (see WithHostRoot). A root of "/" disables re-rooting.
+func ContextWithHostRoot(ctx context.Context, root string) context.Context {
+	return context.WithValue(ctx, hostRootKey{}, root)
+}
+
+// getHostRoot returns the host root for the given context.
+func (c *Cache) getHostRoot(ctx context.Context) string {
+	if root, ok := ctx.Value(hostRootKey{}).(string); ok {
+		return root
+	}
+	return c.hostRoot
+}
+
+// rerootPath returns the given host path re-rooted under root.
+func rerootPath(root, path string) string {
+	if root == "" || root == "/" || path == "" {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
+// withHostRoot returns a copy of the edits with the host paths of device
+// nodes and bind mounts re-rooted under the given root.
+func (e *ContainerEdits) withHostRoot(root string) *ContainerEdits {
+	if root == "" || root == "/" || e == nil || e.ContainerEdits == nil {
+		return e
+	}
+
+	edits := *e.ContainerEdits
+	edits.DeviceNodes = make([]*cdi.DeviceNode, 0, len(e.DeviceNodes))
+	for _, n := range e.DeviceNodes {
+		dn := *n
+		hostPath := dn.HostPath
+		if hostPath == "" {
+			hostPath = dn.Path
+		}
+		dn.HostPath = rerootPath(root, hostPath)
+		edits.DeviceNodes = append(edits.DeviceNodes, &dn)
+	}
+	edits.Mounts = make([]*cdi.Mount, 0, len(e.Mounts))
+	for _, m := range e.Mounts {
+		mnt := *m
+		if isBindMount(m) {
+			mnt.HostPath = rerootPath(root, mnt.HostPath)
+		}
+		edits.Mounts = append(edits.Mounts, &mnt)
+	}
+
+	return e.copyWith(&edits)
+}
@@ -0,0 +1,108 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestHostRoot(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "usr", "lib"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "usr", "lib", "libvendor.so"), nil, 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "dev"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "dev", "vendor0"), nil, 0o644))
+
+	raw := &cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/device",
+		Devices: []cdi.Device{
+			{
+				Name: "dev0",
+				ContainerEdits: cdi.ContainerEdits{
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/vendor0", Type: "c", Major: 240, Minor: 1},
+					},
+					Mounts: []*cdi.Mount{
+						{HostPath: "/usr/lib/libvendor.so", ContainerPath: "/usr/lib/libvendor.so", Options: []string{"ro", "bind"}},
+						{HostPath: "tmpfs", ContainerPath: "/run/vendor", Type: "tmpfs"},
+					},
+				},
+			},
+		},
+	}
+
+	newTestCache := func(options ...Option) *Cache {
+		cache := newCache(append([]Option{
+			WithSpecDirs(),
+			WithAutoRefresh(false),
+			WithHostValidation(true),
+		}, options...)...)
+		require.NoError(t, cache.AddSpec(raw, 0))
+		return cache
+	}
+
+	t.Run("host paths re-rooted", func(t *testing.T) {
+		cache := newTestCache(WithHostRoot(root))
+
+		ociSpec := &oci.Spec{}
+		_, err := cache.InjectDevices(ociSpec, "vendor.com/device=dev0")
+		require.NoError(t, err)
+		require.Equal(t, []oci.Mount{
+			{Source: "tmpfs", Destination: "/run/vendor", Type: "tmpfs"},
+			{Source: filepath.Join(root, "usr/lib/libvendor.so"), Destination: "/usr/lib/libvendor.so", Options: []string{"ro", "bind"}},
+		}, ociSpec.Mounts)
+		require.Equal(t, "/dev/vendor0", ociSpec.Linux.Devices[0].Path)
+
+		edits, _, _, err := cache.GetDeviceEdits("vendor.com/device=dev0")
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(root, "dev/vendor0"), edits.DeviceNodes[0].HostPath)
+		require.Equal(t, "/dev/vendor0", edits.DeviceNodes[0].Path)
+
+		// the Spec is left intact
+		require.Equal(t, "", cache.GetDevice("vendor.com/device=dev0").ContainerEdits.DeviceNodes[0].HostPath)
+	})
+
+	t.Run("per-call override", func(t *testing.T) {
+		cache := newTestCache()
+
+		unresolved, err := cache.InjectDevices(&oci.Spec{}, "vendor.com/device=dev0")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing host paths")
+		require.Equal(t, []string{"vendor.com/device=dev0"}, unresolved)
+
+		ociSpec := &oci.Spec{}
+		ctx := ContextWithHostRoot(context.Background(), root)
+		_, err = cache.InjectDevicesContext(ctx, ociSpec, "vendor.com/device=dev0")
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(root, "usr/lib/libvendor.so"), ociSpec.Mounts[1].Source)
+
+		cache = newTestCache(WithHostRoot(root), WithHostValidation(false))
+		ociSpec = &oci.Spec{}
+		ctx = ContextWithHostRoot(context.Background(), "/")
+		_, err = cache.InjectDevicesContext(ctx, ociSpec, "vendor.com/device=dev0")
+		require.NoError(t, err)
+		require.Equal(t, "/usr/lib/libvendor.so", ociSpec.Mounts[1].Source)
+	})
+}
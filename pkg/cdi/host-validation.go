@@ -0,0 +1,133 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// WithHostValidation returns an option to control whether the host paths
+// referenced by devices are checked before injection. When enabled, the
+// host path of every device node (HostPath, or Path if HostPath is not
+// set) and the host path of every bind mount in the edits of a device or
+// its Spec must exist. Devices referencing missing host paths are then
+// reported as unresolved by InjectDevices, together with the offending
+// paths, instead of failing later in the runtime with an opaque error.
+// Host validation is disabled by default.
+func WithHostValidation(enable bool) Option {
+	return func(c *Cache) {
+		c.hostValidation = enable
+	}
+}
+
+// MissingHostPathError is the error for a device referencing host paths
+// which do not exist.
+type MissingHostPathError struct {
+	// Device is the fully qualified name of the device.
+	Device string
+	// Paths are the missing host paths.
+	Paths []string
+}
+
+// Error returns the error message.
+func (e *MissingHostPathError) Error() string {
+	return fmt.Sprintf("CDI device %q: missing host paths %s",
+		e.Device, strings.Join(e.Paths, ", "))
+}
+
+// hostValidator checks the existence of host paths, remembering the
+// results so that paths shared by several devices are checked once.
+type hostValidator struct {
+	checked map[string]bool
+	root    string
+}
+
+// newHostValidator returns a new validator for host paths re-rooted
+// under the given root.
+func newHostValidator(root string) *hostValidator {
+	return &hostValidator{
+		checked: map[string]bool{},
+		root:    root,
+	}
+}
+
+// validate checks the host paths of the device and its Spec.
+func (v *hostValidator) validate(d *Device) error {
+	var missing []string
+
+	for _, e := range []*cdi.ContainerEdits{&d.GetSpec().ContainerEdits, &d.ContainerEdits} {
+		for _, n := range e.DeviceNodes {
+			if n == nil {
+				continue
+			}
+			path := n.HostPath
+			if path == "" {
+				path = n.Path
+			}
+			if !v.exists(path) {
+				missing = append(missing, path)
+			}
+		}
+		for _, m := range e.Mounts {
+			if m == nil || !isBindMount(m) {
+				continue
+			}
+			if !v.exists(m.HostPath) {
+				missing = append(missing, m.HostPath)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return &MissingHostPathError{
+			Device: d.GetQualifiedName(),
+			Paths:  missing,
+		}
+	}
+
+	return nil
+}
+
+// exists returns true if the given host path exists.
+func (v *hostValidator) exists(path string) bool {
+	ok, checked := v.checked[path]
+	if !checked {
+		_, err := os.Stat(rerootPath(v.root, path))
+		ok = err == nil || !errors.Is(err, os.ErrNotExist)
+		v.checked[path] = ok
+	}
+	return ok
+}
+
+// isBindMount returns true if the mount refers to a host path. Mounts of
+// other types (tmpfs, proc, etc.) take a source which is not a path.
+func isBindMount(m *cdi.Mount) bool {
+	if m.Type == "" || m.Type == "bind" || m.Type == "rbind" {
+		return true
+	}
+	for _, o := range m.Options {
+		if o == "bind" || o == "rbind" {
+			return true
+		}
+	}
+	return false
+}
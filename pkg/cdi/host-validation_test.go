@@ -0,0 +1,139 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestHostValidation(t *testing.T) {
+	dir := t.TempDir()
+	present := filepath.Join(dir, "present")
+	missing := filepath.Join(dir, "missing")
+	require.NoError(t, os.WriteFile(present, nil, 0o644))
+
+	node := func(path, hostPath string) *cdi.DeviceNode {
+		return &cdi.DeviceNode{Path: path, HostPath: hostPath, Type: "c", Major: 1, Minor: 3}
+	}
+	spec := func(vendor string, specEdits cdi.ContainerEdits) *cdi.Spec {
+		return &cdi.Spec{
+			Version: cdi.CurrentVersion,
+			Kind:    vendor + ".com/device",
+			Devices: []cdi.Device{
+				{
+					Name: "ok",
+					ContainerEdits: cdi.ContainerEdits{
+						DeviceNodes: []*cdi.DeviceNode{node("/dev/ok", present)},
+						Mounts: []*cdi.Mount{
+							{HostPath: present, ContainerPath: "/ok"},
+							{HostPath: "tmpfs", ContainerPath: "/tmp", Type: "tmpfs"},
+						},
+					},
+				},
+				{
+					Name: "node",
+					ContainerEdits: cdi.ContainerEdits{
+						DeviceNodes: []*cdi.DeviceNode{node(missing, "")},
+					},
+				},
+				{
+					Name: "mount",
+					ContainerEdits: cdi.ContainerEdits{
+						Mounts: []*cdi.Mount{
+							{HostPath: missing, ContainerPath: "/m", Type: "none", Options: []string{"rbind"}},
+						},
+					},
+				},
+			},
+			ContainerEdits: specEdits,
+		}
+	}
+
+	for _, tc := range []struct {
+		name       string
+		enable     bool
+		specEdits  cdi.ContainerEdits
+		devices    []string
+		unresolved []string
+		missing    map[string][]string
+	}{
+		{
+			name:    "disabled",
+			devices: []string{"vendor.com/device=ok", "vendor.com/device=node", "vendor.com/device=mount"},
+		},
+		{
+			name:    "enabled, all present",
+			enable:  true,
+			devices: []string{"vendor.com/device=ok"},
+		},
+		{
+			name:       "enabled, missing device node and mount",
+			enable:     true,
+			devices:    []string{"vendor.com/device=ok", "vendor.com/device=node", "vendor.com/device=mount"},
+			unresolved: []string{"vendor.com/device=node", "vendor.com/device=mount"},
+			missing: map[string][]string{
+				"vendor.com/device=node":  {missing},
+				"vendor.com/device=mount": {missing},
+			},
+		},
+		{
+			name:   "enabled, missing in Spec edits",
+			enable: true,
+			specEdits: cdi.ContainerEdits{
+				DeviceNodes: []*cdi.DeviceNode{node("/dev/spec", missing)},
+			},
+			devices:    []string{"vendor.com/device=ok"},
+			unresolved: []string{"vendor.com/device=ok"},
+			missing: map[string][]string{
+				"vendor.com/device=ok": {missing},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := newCache(
+				WithSpecDirs(),
+				WithAutoRefresh(false),
+				WithHostValidation(tc.enable),
+			)
+			require.NoError(t, cache.AddSpec(spec("vendor", tc.specEdits), 0))
+
+			unresolved, err := cache.InjectDevices(&oci.Spec{}, tc.devices...)
+			require.Equal(t, tc.unresolved, unresolved)
+			if tc.unresolved == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			found := map[string][]string{}
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var hostErr *MissingHostPathError
+				if errors.As(e, &hostErr) {
+					found[hostErr.Device] = hostErr.Paths
+				}
+			}
+			require.Equal(t, tc.missing, found)
+		})
+	}
+}
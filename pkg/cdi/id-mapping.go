@@ -0,0 +1,113 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// WithIDMapping returns an option to map the user and group IDs of
+// injected devices into a user namespace. The IDs in CDI Specs are host
+// IDs. With this option, the UID and GID of device nodes and additional
+// GIDs are mapped to the corresponding IDs inside the container, using the
+// given mappings in the format of the OCI Spec. IDs which are not mapped
+// fail injection with an *UnmappedIDError. IDs set up from the container
+// process user and root ownership (see DeviceNodeOwnership) are already
+// container IDs and are not mapped.
+//
+// Giving no mappings disables ID mapping, which is the default.
+func WithIDMapping(uidMappings, gidMappings []oci.LinuxIDMapping) Option {
+	return func(c *Cache) {
+		c.idMapping = nil
+		if len(uidMappings) > 0 || len(gidMappings) > 0 {
+			c.idMapping = &idMapping{
+				uids: append([]oci.LinuxIDMapping(nil), uidMappings...),
+				gids: append([]oci.LinuxIDMapping(nil), gidMappings...),
+			}
+		}
+	}
+}
+
+// UnmappedIDError is the error for a host ID without a mapping into the
+// user namespace of the container.
+type UnmappedIDError struct {
+	// Kind is "uid" or "gid".
+	Kind string
+	// ID is the unmapped host ID.
+	ID uint32
+}
+
+// Error returns the error message.
+func (e *UnmappedIDError) Error() string {
+	return fmt.Sprintf("host %s %d is not mapped into the container", e.Kind, e.ID)
+}
+
+// idMapping maps host IDs to container IDs.
+type idMapping struct {
+	uids []oci.LinuxIDMapping
+	gids []oci.LinuxIDMapping
+}
+
+// mapDeviceNode maps the UID and GID of the device node, if set.
+func (m *idMapping) mapDeviceNode(d *DeviceNode) error {
+	if m == nil {
+		return nil
+	}
+	if d.UID != nil {
+		uid, err := mapID("uid", *d.UID, m.uids)
+		if err != nil {
+			return fmt.Errorf("device %q: %w", d.Path, err)
+		}
+		d.UID = &uid
+	}
+	if d.GID != nil {
+		gid, err := mapID("gid", *d.GID, m.gids)
+		if err != nil {
+			return fmt.Errorf("device %q: %w", d.Path, err)
+		}
+		d.GID = &gid
+	}
+	return nil
+}
+
+// mapGIDs returns the given GIDs mapped.
+func (m *idMapping) mapGIDs(gids []uint32) ([]uint32, error) {
+	if m == nil || len(gids) == 0 {
+		return gids, nil
+	}
+	mapped := make([]uint32, 0, len(gids))
+	for _, gid := range gids {
+		id, err := mapID("gid", gid, m.gids)
+		if err != nil {
+			return nil, fmt.Errorf("additional GIDs: %w", err)
+		}
+		mapped = append(mapped, id)
+	}
+	return mapped, nil
+}
+
+// mapID maps the given host ID to a container ID.
+func mapID(kind string, id uint32, mappings []oci.LinuxIDMapping) (uint32, error) {
+	for _, m := range mappings {
+		if id >= m.HostID && uint64(id) < uint64(m.HostID)+uint64(m.Size) {
+			return m.ContainerID + (id - m.HostID), nil
+		}
+	}
+	return 0, &UnmappedIDError{Kind: kind, ID: id}
+}
@@ -0,0 +1,136 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestIDMapping(t *testing.T) {
+	resolver := DeviceInfoResolverFunc(func(string) (*DeviceInfo, error) {
+		return &DeviceInfo{Type: "c", Major: 1, Minor: 3, FileMode: 0o660, UID: 100010, GID: 100020}, nil
+	})
+	uidMappings := []oci.LinuxIDMapping{
+		{ContainerID: 0, HostID: 100000, Size: 65536},
+	}
+	gidMappings := []oci.LinuxIDMapping{
+		{ContainerID: 0, HostID: 100000, Size: 1000},
+		{ContainerID: 1000, HostID: 5000, Size: 10},
+	}
+
+	spec := &cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/device",
+		Devices: []cdi.Device{
+			{
+				Name: "explicit",
+				ContainerEdits: cdi.ContainerEdits{
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/explicit", UID: uint32Ptr(100005), GID: uint32Ptr(5003)},
+					},
+					AdditionalGIDs: []uint32{5001, 100044},
+				},
+			},
+			{
+				Name: "implicit",
+				ContainerEdits: cdi.ContainerEdits{
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/implicit"},
+					},
+				},
+			},
+			{
+				Name: "unmapped",
+				ContainerEdits: cdi.ContainerEdits{
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/unmapped", UID: uint32Ptr(0)},
+					},
+				},
+			},
+			{
+				Name: "unmapped-gid",
+				ContainerEdits: cdi.ContainerEdits{
+					AdditionalGIDs: []uint32{6000},
+				},
+			},
+		},
+	}
+
+	newTestCache := func(o DeviceNodeOwnership) *Cache {
+		cache := newCache(
+			WithSpecDirs(),
+			WithAutoRefresh(false),
+			WithDeviceInfoResolver(resolver),
+			WithDeviceNodeOwnership(o),
+			WithIDMapping(uidMappings, gidMappings),
+		)
+		require.NoError(t, cache.AddSpec(spec, 0))
+		return cache
+	}
+
+	t.Run("explicit IDs", func(t *testing.T) {
+		ociSpec := &oci.Spec{}
+		_, err := newTestCache(DefaultDeviceNodeOwnership).InjectDevices(ociSpec, "vendor.com/device=explicit")
+		require.NoError(t, err)
+		require.Equal(t, uint32Ptr(5), ociSpec.Linux.Devices[0].UID)
+		require.Equal(t, uint32Ptr(1003), ociSpec.Linux.Devices[0].GID)
+		require.Equal(t, []uint32{1001, 44}, ociSpec.Process.User.AdditionalGids)
+	})
+
+	t.Run("host ownership", func(t *testing.T) {
+		ociSpec := &oci.Spec{}
+		_, err := newTestCache(DeviceNodeOwnershipHost).InjectDevices(ociSpec, "vendor.com/device=implicit")
+		require.NoError(t, err)
+		require.Equal(t, uint32Ptr(10), ociSpec.Linux.Devices[0].UID)
+		require.Equal(t, uint32Ptr(20), ociSpec.Linux.Devices[0].GID)
+	})
+
+	t.Run("root ownership is not mapped", func(t *testing.T) {
+		ociSpec := &oci.Spec{}
+		_, err := newTestCache(DeviceNodeOwnershipRoot).InjectDevices(ociSpec, "vendor.com/device=implicit")
+		require.NoError(t, err)
+		require.Equal(t, uint32Ptr(0), ociSpec.Linux.Devices[0].UID)
+		require.Equal(t, uint32Ptr(0), ociSpec.Linux.Devices[0].GID)
+	})
+
+	t.Run("unmapped IDs", func(t *testing.T) {
+		cache := newTestCache(DefaultDeviceNodeOwnership)
+		for device, expected := range map[string]*UnmappedIDError{
+			"vendor.com/device=unmapped":     {Kind: "uid", ID: 0},
+			"vendor.com/device=unmapped-gid": {Kind: "gid", ID: 6000},
+		} {
+			_, err := cache.InjectDevices(&oci.Spec{}, device)
+			var idErr *UnmappedIDError
+			require.True(t, errors.As(err, &idErr), device)
+			require.Equal(t, expected, idErr)
+		}
+	})
+
+	t.Run("no mappings", func(t *testing.T) {
+		cache := newCache(
+			WithSpecDirs(),
+			WithAutoRefresh(false),
+			WithIDMapping(nil, nil),
+		)
+		require.Nil(t, cache.idMapping)
+	})
+}
@@ -0,0 +1,213 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+)
+
+// benchSpec returns a CDI Spec with the given number of devices, each with
+// a device node, a mount and an environment variable.
+func benchSpec(count int) string {
+	var b strings.Builder
+	b.WriteString(`cdiVersion: "0.6.0"
+kind: "vendor.com/bench"
+containerEdits:
+  env:
+  - BENCH_SPEC=1
+  hooks:
+  - hookName: createContainer
+    path: /usr/bin/bench-hook
+    args: ["bench-hook", "create"]
+devices:
+`)
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&b, `- name: dev%d
+  containerEdits:
+    env:
+    - BENCH_DEV%d=%d
+    deviceNodes:
+    - path: /dev/bench%d
+      type: c
+      major: 240
+      minor: %d
+    mounts:
+    - hostPath: /opt/bench/lib%d
+      containerPath: /usr/lib/bench/lib%d
+      options: ["ro", "bind"]
+`, i, i, i, i, i, i, i)
+	}
+	return b.String()
+}
+
+// benchDevices returns the qualified names of the first count devices.
+func benchDevices(count int) []string {
+	devices := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		devices = append(devices, fmt.Sprintf("vendor.com/bench=dev%d", i))
+	}
+	return devices
+}
+
+// benchOCISpec returns an OCI Spec with the given number of existing
+// environment variables, mounts and device nodes.
+func benchOCISpec(size int) *oci.Spec {
+	spec := &oci.Spec{
+		Process: &oci.Process{},
+		Linux: &oci.Linux{
+			Resources: &oci.LinuxResources{},
+		},
+	}
+	for i := 0; i < size; i++ {
+		major, minor := int64(250), int64(i)
+		spec.Process.Env = append(spec.Process.Env, fmt.Sprintf("VAR%d=%d", i, i))
+		spec.Mounts = append(spec.Mounts, oci.Mount{
+			Source:      fmt.Sprintf("/var/lib/vol%d", i),
+			Destination: fmt.Sprintf("/data/vol%d", i),
+			Type:        "bind",
+			Options:     []string{"rbind", "rw"},
+		})
+		spec.Linux.Devices = append(spec.Linux.Devices, oci.LinuxDevice{
+			Path:  fmt.Sprintf("/dev/existing%d", i),
+			Type:  "c",
+			Major: major,
+			Minor: minor,
+		})
+		spec.Linux.Resources.Devices = append(spec.Linux.Resources.Devices,
+			oci.LinuxDeviceCgroup{
+				Allow:  true,
+				Type:   "c",
+				Major:  &major,
+				Minor:  &minor,
+				Access: "rwm",
+			},
+		)
+	}
+	return spec
+}
+
+// copyOCISpec returns a copy of the parts of the OCI Spec modified by
+// device injection.
+func copyOCISpec(spec *oci.Spec) *oci.Spec {
+	c := *spec
+	p := *spec.Process
+	l := *spec.Linux
+	r := *spec.Linux.Resources
+	p.Env = append([]string(nil), spec.Process.Env...)
+	c.Mounts = append([]oci.Mount(nil), spec.Mounts...)
+	l.Devices = append([]oci.LinuxDevice(nil), spec.Linux.Devices...)
+	r.Devices = append([]oci.LinuxDeviceCgroup(nil), spec.Linux.Resources.Devices...)
+	l.Resources = &r
+	c.Process = &p
+	c.Linux = &l
+	return &c
+}
+
+func newBenchCache(b *testing.B, count int) *Cache {
+	dir := b.TempDir()
+	name := filepath.Join(dir, "bench.yaml")
+	require.NoError(b, os.WriteFile(name, []byte(benchSpec(count)), 0o644))
+
+	cache := newCache(
+		WithSpecDirs(dir),
+		WithAutoRefresh(false),
+	)
+	require.Len(b, cache.ListDevices(), count)
+	return cache
+}
+
+func BenchmarkInjectDevices(b *testing.B) {
+	cache := newBenchCache(b, 100)
+
+	for _, count := range []int{1, 10, 100} {
+		for _, size := range []int{0, 1000} {
+			b.Run(fmt.Sprintf("devices=%d/ocispec=%d", count, size), func(b *testing.B) {
+				var (
+					devices = benchDevices(count)
+					orig    = benchOCISpec(size)
+				)
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					spec := copyOCISpec(orig)
+					b.StartTimer()
+					unresolved, err := cache.InjectDevices(spec, devices...)
+					if err != nil || unresolved != nil {
+						b.Fatalf("injection failed: %v (unresolved %v)", err, unresolved)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkInjectDevicesParallel(b *testing.B) {
+	cache := newBenchCache(b, 100)
+
+	for _, count := range []int{1, 10} {
+		b.Run(fmt.Sprintf("devices=%d", count), func(b *testing.B) {
+			devices := benchDevices(count)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					spec := benchOCISpec(0)
+					unresolved, err := cache.InjectDevices(spec, devices...)
+					if err != nil || unresolved != nil {
+						b.Errorf("injection failed: %v (unresolved %v)", err, unresolved)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkContainerEditsApply(b *testing.B) {
+	cache := newBenchCache(b, 100)
+
+	for _, count := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("devices=%d", count), func(b *testing.B) {
+			var edits *ContainerEdits
+			for _, name := range benchDevices(count) {
+				edits = edits.Append(cache.GetDevice(name).edits())
+			}
+			orig := benchOCISpec(100)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				spec := copyOCISpec(orig)
+				b.StartTimer()
+				if err := edits.Apply(spec); err != nil {
+					b.Fatalf("failed to apply edits: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+const (
+	// InjectionAnnotationPrefix is the prefix of the OCI Spec annotations
+	// recording a CDI device injection. It differs from AnnotationPrefix,
+	// so these annotations are not mistaken for injection requests.
+	InjectionAnnotationPrefix = "injected.cdi.k8s.io/"
+
+	// InjectedDevicesAnnotation is the OCI Spec annotation listing the
+	// comma-separated qualified names of the injected devices.
+	InjectedDevicesAnnotation = InjectionAnnotationPrefix + "devices"
+	// InjectedSpecsAnnotation is the OCI Spec annotation describing the
+	// Specs the injected devices came from, as a JSON array of InjectedSpec.
+	InjectedSpecsAnnotation = InjectionAnnotationPrefix + "specs"
+
+	// digestAlgorithm is the algorithm used for Spec digests.
+	digestAlgorithm = "sha256"
+)
+
+// InjectedSpec describes a Spec used during device injection.
+type InjectedSpec struct {
+	// Path is the path of the Spec file.
+	Path string `json:"path"`
+	// Digest is the digest of the Spec content (see Spec.GetDigest()).
+	Digest string `json:"digest"`
+	// Version is the CDI version of the Spec.
+	Version string `json:"cdiVersion"`
+	// Devices are the qualified names of the devices injected from the Spec.
+	Devices []string `json:"devices"`
+}
+
+// WithInjectionAnnotations returns an option to control whether the Cache
+// records successful device injections in the OCI Spec. If enabled, the
+// InjectedDevicesAnnotation and InjectedSpecsAnnotation are set to the
+// injected devices and the Specs (path, digest and CDI version) they came
+// from. This allows one to trace which Spec any edit of the OCI Spec came
+// from. Annotations from any previous injection are overwritten. This is
+// disabled by default.
+func WithInjectionAnnotations(enable bool) Option {
+	return func(c *Cache) {
+		c.injectionAnnotations = enable
+	}
+}
+
+// ParseInjectionAnnotations parses the annotations recorded by a device
+// injection into the names of the injected devices and the Specs they
+// came from. It returns nil slices if the annotations are not present.
+func ParseInjectionAnnotations(annotations map[string]string) ([]string, []*InjectedSpec, error) {
+	var (
+		devices []string
+		specs   []*InjectedSpec
+	)
+
+	if value, ok := annotations[InjectedDevicesAnnotation]; ok && value != "" {
+		devices = strings.Split(value, ",")
+	}
+	if value, ok := annotations[InjectedSpecsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(value), &specs); err != nil {
+			return nil, nil, fmt.Errorf("invalid annotation %q: %w", InjectedSpecsAnnotation, err)
+		}
+	}
+
+	return devices, specs, nil
+}
+
+// annotateInjection records the injection of the given devices in the
+// annotations of the OCI Spec.
+func annotateInjection(ociSpec *oci.Spec, devices []*Device) error {
+	var (
+		names []string
+		specs []*InjectedSpec
+		index = map[*Spec]*InjectedSpec{}
+	)
+
+	for _, d := range devices {
+		name := d.GetQualifiedName()
+		names = append(names, name)
+
+		spec := d.GetSpec()
+		injected, ok := index[spec]
+		if !ok {
+			injected = &InjectedSpec{
+				Path:    spec.GetPath(),
+				Digest:  spec.GetDigest(),
+				Version: spec.Version,
+			}
+			index[spec] = injected
+			specs = append(specs, injected)
+		}
+		injected.Devices = append(injected.Devices, name)
+	}
+
+	data, err := json.Marshal(specs)
+	if err != nil {
+		return fmt.Errorf("failed to annotate injected Specs: %w", err)
+	}
+
+	if ociSpec.Annotations == nil {
+		ociSpec.Annotations = map[string]string{}
+	}
+	ociSpec.Annotations[InjectedDevicesAnnotation] = strings.Join(names, ",")
+	ociSpec.Annotations[InjectedSpecsAnnotation] = string(data)
+
+	return nil
+}
+
+// specDigest returns the digest of the given Spec content.
+func specDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return digestAlgorithm + ":" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,126 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestInjectionAnnotations(t *testing.T) {
+	vendor1 := `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev0"
+    containerEdits:
+      env:
+      - "VENDOR1_DEV0=1"
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR1_DEV1=1"
+`
+	dir, err := createSpecDirs(t, map[string]string{"vendor1.yaml": vendor1}, nil)
+	require.NoError(t, err)
+
+	sum := sha256.Sum256([]byte(vendor1))
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+	)
+	require.Empty(t, cache.GetErrors())
+	require.Equal(t, digest, cache.GetDevice("vendor1.com/device=dev0").GetSpec().GetDigest())
+
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version: "0.6.0",
+		Kind:    "vendor2.com/device",
+		Devices: []cdi.Device{
+			{
+				Name: "dev0",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"VENDOR2_DEV0=1"},
+				},
+			},
+		},
+	}, 0))
+	memSpec := cache.GetDevice("vendor2.com/device=dev0").GetSpec()
+	require.Contains(t, memSpec.GetDigest(), "sha256:")
+
+	// disabled by default
+	ociSpec := &oci.Spec{}
+	_, err = cache.InjectDevices(ociSpec, "vendor1.com/device=dev0")
+	require.NoError(t, err)
+	require.Empty(t, ociSpec.Annotations)
+
+	require.NoError(t, cache.Configure(WithInjectionAnnotations(true)))
+	ociSpec = &oci.Spec{
+		Annotations: map[string]string{
+			AnnotationPrefix + "vendor1.device_dev0": "vendor1.com/device=dev0",
+		},
+	}
+	_, err = cache.InjectDevices(ociSpec,
+		"vendor1.com/device=dev0",
+		"vendor2.com/device=dev0",
+		"vendor1.com/device=dev1",
+	)
+	require.NoError(t, err)
+
+	devices, specs, err := ParseInjectionAnnotations(ociSpec.Annotations)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"vendor1.com/device=dev0",
+		"vendor2.com/device=dev0",
+		"vendor1.com/device=dev1",
+	}, devices)
+	require.Equal(t, []*InjectedSpec{
+		{
+			Path:    filepath.Join(dir, "etc", "vendor1.yaml"),
+			Digest:  digest,
+			Version: "0.3.0",
+			Devices: []string{"vendor1.com/device=dev0", "vendor1.com/device=dev1"},
+		},
+		{
+			Path:    memSpec.GetPath(),
+			Digest:  memSpec.GetDigest(),
+			Version: "0.6.0",
+			Devices: []string{"vendor2.com/device=dev0"},
+		},
+	}, specs)
+
+	// injection records must not be mistaken for injection requests
+	keys, requested, err := ParseAnnotations(ociSpec.Annotations)
+	require.NoError(t, err)
+	require.Equal(t, []string{AnnotationPrefix + "vendor1.device_dev0"}, keys)
+	require.Equal(t, []string{"vendor1.com/device=dev0"}, requested)
+
+	devices, specs, err = ParseInjectionAnnotations(nil)
+	require.NoError(t, err)
+	require.Nil(t, devices)
+	require.Nil(t, specs)
+
+	_, _, err = ParseInjectionAnnotations(map[string]string{InjectedSpecsAnnotation: "{"})
+	require.Error(t, err)
+}
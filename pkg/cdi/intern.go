@@ -0,0 +1,158 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// WithStringInterning returns an option to control whether the Cache
+// deduplicates identical strings across the Specs it loads. Specs for
+// large numbers of similar devices (for instance one Spec per virtual
+// function) tend to repeat the same environment variables, paths, mount
+// options and hook arguments. With interning enabled, which is the
+// default, all Specs loaded during a refresh share a single copy of each
+// such string. Interning does not change the content of any Spec.
+//
+// Only freshly read Specs are interned, before they are made available
+// by the Cache. Specs reused from a previous refresh and Specs added
+// using AddSpec are never modified.
+func WithStringInterning(enable bool) Option {
+	return func(c *Cache) {
+		c.noInterning = !enable
+	}
+}
+
+// internSpecs deduplicates the strings of the given freshly read Specs,
+// unless interning is disabled. The Specs must not be in use yet.
+func (c *Cache) internSpecs(specs []*Spec) {
+	if c.noInterning || len(specs) == 0 {
+		return
+	}
+	in := newInterner()
+	for _, spec := range specs {
+		in.spec(spec)
+	}
+}
+
+// interner deduplicates strings. It is used for the Specs read during a
+// single Cache refresh, so no strings are retained once the refresh is
+// done.
+type interner struct {
+	strings map[string]string
+}
+
+// newInterner creates a new, empty interner.
+func newInterner() *interner {
+	return &interner{
+		strings: make(map[string]string),
+	}
+}
+
+// string returns the shared copy of the given string.
+func (in *interner) string(s string) string {
+	if s == "" {
+		return s
+	}
+	if shared, ok := in.strings[s]; ok {
+		return shared
+	}
+	in.strings[s] = s
+	return s
+}
+
+// slice replaces the strings of the given slice with their shared copies.
+func (in *interner) slice(s []string) {
+	for i, str := range s {
+		s[i] = in.string(str)
+	}
+}
+
+// annotations replaces the values of the given annotations with their
+// shared copies.
+func (in *interner) annotations(m map[string]string) {
+	for k, v := range m {
+		m[k] = in.string(v)
+	}
+}
+
+// spec replaces all strings of the given Spec with their shared copies.
+func (in *interner) spec(spec *Spec) {
+	if spec == nil || spec.Spec == nil {
+		return
+	}
+	raw := spec.Spec
+	raw.Version = in.string(raw.Version)
+	raw.Kind = in.string(raw.Kind)
+	in.annotations(raw.Annotations)
+	in.edits(&raw.ContainerEdits)
+	for i := range raw.Devices {
+		raw.Devices[i].Name = in.string(raw.Devices[i].Name)
+		in.annotations(raw.Devices[i].Annotations)
+		in.edits(&raw.Devices[i].ContainerEdits)
+	}
+	// Devices hold a copy of their raw data. Slices, maps and pointers are
+	// shared with the raw Spec, but names need to be interned separately.
+	for _, dev := range spec.devices {
+		dev.Name = in.string(dev.Name)
+	}
+	for i := range raw.Groups {
+		in.slice(raw.Groups[i].Devices)
+	}
+}
+
+// edits replaces all strings of the given ContainerEdits with their
+// shared copies.
+func (in *interner) edits(e *cdi.ContainerEdits) {
+	in.slice(e.Env)
+	for _, d := range e.DeviceNodes {
+		if d == nil {
+			continue
+		}
+		d.Path = in.string(d.Path)
+		d.HostPath = in.string(d.HostPath)
+		d.Type = in.string(d.Type)
+		d.Permissions = in.string(d.Permissions)
+	}
+	for _, h := range e.Hooks {
+		if h == nil {
+			continue
+		}
+		h.HookName = in.string(h.HookName)
+		h.Path = in.string(h.Path)
+		in.slice(h.Args)
+		in.slice(h.Env)
+	}
+	for _, m := range e.Mounts {
+		if m == nil {
+			continue
+		}
+		m.HostPath = in.string(m.HostPath)
+		m.ContainerPath = in.string(m.ContainerPath)
+		m.Type = in.string(m.Type)
+		in.slice(m.Options)
+	}
+	if r := e.IntelRdt; r != nil {
+		r.ClosID = in.string(r.ClosID)
+		r.L3CacheSchema = in.string(r.L3CacheSchema)
+		r.MemBwSchema = in.string(r.MemBwSchema)
+	}
+	if c := e.Capabilities; c != nil {
+		in.slice(c.Add)
+		in.slice(c.Drop)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// vfSpec returns the Spec data for a single virtual function device.
+func vfSpec(vf int) string {
+	return fmt.Sprintf(`
+cdiVersion: "0.6.0"
+kind: "vendor.com/vf"
+annotations:
+  vendor.com/driver: "vf-driver"
+containerEdits:
+  env:
+    - "VENDOR_DRIVER_CAPABILITIES=compute,utility,video"
+    - "VENDOR_VISIBLE_DEVICES=all"
+  hooks:
+    - hookName: createContainer
+      path: "/usr/bin/vendor-ctk"
+      args: ["vendor-ctk", "hook", "update-ldcache", "--folder", "/usr/lib/x86_64-linux-gnu"]
+  mounts:
+    - hostPath: "/usr/lib/x86_64-linux-gnu/libvendor.so.1"
+      containerPath: "/usr/lib/x86_64-linux-gnu/libvendor.so.1"
+      options: ["ro", "nosuid", "nodev", "bind"]
+devices:
+  - name: "vf%d"
+    containerEdits:
+      env:
+        - "VENDOR_DRIVER_CAPABILITIES=compute,utility,video"
+      deviceNodes:
+        - path: "/dev/vendor/vf%d"
+          permissions: "rw"
+`, vf, vf)
+}
+
+func TestCacheStringInterning(t *testing.T) {
+	specs := map[string]string{
+		"vf0.yaml": vfSpec(0),
+		"vf1.yaml": vfSpec(1),
+	}
+
+	for _, enable := range []bool{true, false} {
+		t.Run(fmt.Sprintf("interning=%v", enable), func(t *testing.T) {
+			dir, err := createSpecDirs(t, specs, nil)
+			require.NoError(t, err)
+
+			cache := newCache(
+				WithSpecDirs(filepath.Join(dir, "etc")),
+				WithAutoRefresh(false),
+				WithStringInterning(enable),
+			)
+			require.Empty(t, cache.GetErrors())
+			require.Equal(t, []string{"vendor.com/vf=vf0", "vendor.com/vf=vf1"}, cache.ListDevices())
+
+			vf0, vf1 := cache.GetDevice("vendor.com/vf=vf0"), cache.GetDevice("vendor.com/vf=vf1")
+			require.NotNil(t, vf0)
+			require.NotNil(t, vf1)
+
+			env0 := vf0.GetSpec().ContainerEdits.Env[0]
+			env1 := vf1.GetSpec().ContainerEdits.Env[0]
+			devEnv0 := vf0.ContainerEdits.Env[0]
+			require.Equal(t, env0, env1)
+			require.Equal(t, env0, devEnv0)
+
+			// Depending on the decoder some strings might be shared even
+			// without interning, so only check sharing if it is enabled.
+			if enable {
+				require.Equal(t, unsafe.StringData(env0), unsafe.StringData(env1))
+			}
+
+			mount0 := vf0.GetSpec().ContainerEdits.Mounts[0]
+			mount1 := vf1.GetSpec().ContainerEdits.Mounts[0]
+			require.Equal(t, mount0, mount1)
+			if enable {
+				require.Equal(t, unsafe.StringData(mount0.HostPath), unsafe.StringData(mount1.HostPath))
+				require.Equal(t, unsafe.StringData(mount0.Options[0]), unsafe.StringData(mount1.Options[0]))
+			}
+		})
+	}
+}
+
+func TestCacheStringInterningPublishedSpecs(t *testing.T) {
+	specs := map[string]string{
+		"vf0.yaml": vfSpec(0),
+		"vf1.yaml": vfSpec(1),
+	}
+	dir, err := createSpecDirs(t, specs, nil)
+	require.NoError(t, err)
+
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+	)
+	require.Empty(t, cache.GetErrors())
+
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version: "0.6.0",
+		Kind:    "vendor.com/mem",
+		Devices: []cdi.Device{
+			{
+				Name: "mem0",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"VENDOR_VISIBLE_DEVICES=all"},
+				},
+			},
+		},
+	}, 0))
+
+	// Published Specs are read without holding the Cache lock while the
+	// Cache is refreshed. This is racy if refreshing modifies them.
+	vf0, mem0 := cache.GetDevice("vendor.com/vf=vf0"), cache.GetDevice("vendor.com/mem=mem0")
+	require.NotNil(t, vf0)
+	require.NotNil(t, mem0)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = strings.Clone(vf0.GetSpec().ContainerEdits.Env[0])
+			_ = strings.Clone(mem0.ContainerEdits.Env[0])
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, cache.Refresh())
+	}
+	<-done
+}
+
+func BenchmarkCacheRefresh(b *testing.B) {
+	const count = 1000
+
+	dir := b.TempDir()
+	for vf := 0; vf < count; vf++ {
+		name := filepath.Join(dir, fmt.Sprintf("vf%d.yaml", vf))
+		require.NoError(b, os.WriteFile(name, []byte(vfSpec(vf)), 0o644))
+	}
+
+	for _, enable := range []bool{true, false} {
+		b.Run(fmt.Sprintf("interning=%v", enable), func(b *testing.B) {
+			var (
+				cache = newCache(
+					WithSpecDirs(dir),
+					WithAutoRefresh(false),
+					WithStringInterning(enable),
+				)
+				before, after runtime.MemStats
+				retained      uint64
+			)
+			require.Len(b, cache.ListDevices(), count)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cache.specs, cache.devices = nil, nil
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				require.NoError(b, cache.refresh())
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				retained += after.HeapAlloc - before.HeapAlloc
+			}
+			b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+		})
+	}
+}
@@ -0,0 +1,188 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"strings"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// InventoryVersion is the version of the Inventory encoding.
+	InventoryVersion = "v1"
+)
+
+// Inventory is a structured record of everything the injection of a set
+// of CDI devices adds to a container: devices, device nodes, mounts, hooks,
+// environment variables and additional GIDs. Every entry records the Spec,
+// and for device-level edits the device, it originates from. Inventories
+// have a stable JSON encoding and are suitable for attaching to container
+// attestations.
+type Inventory struct {
+	// Version is the version of the encoding, InventoryVersion.
+	Version string `json:"version"`
+	// Devices are the qualified names of the injected devices.
+	Devices []string `json:"devices"`
+	// Specs are the Specs the injected devices came from.
+	Specs []*InjectedSpec `json:"specs"`
+	// DeviceNodes are the injected device nodes.
+	DeviceNodes []*InventoryDeviceNode `json:"deviceNodes,omitempty"`
+	// Mounts are the injected mounts.
+	Mounts []*InventoryMount `json:"mounts,omitempty"`
+	// Hooks are the injected hooks, with any templates expanded.
+	Hooks []*InventoryHook `json:"hooks,omitempty"`
+	// Env are the injected environment variables.
+	Env []*InventoryEnv `json:"env,omitempty"`
+	// AdditionalGIDs are the injected additional GIDs.
+	AdditionalGIDs []*InventoryGID `json:"additionalGids,omitempty"`
+}
+
+// InventorySource identifies the origin of an Inventory entry.
+type InventorySource struct {
+	// Spec is the path of the Spec the entry comes from.
+	Spec string `json:"spec"`
+	// Device is the qualified name of the device the entry comes from.
+	// It is empty for Spec-level edits.
+	Device string `json:"device,omitempty"`
+}
+
+// InventoryDeviceNode is a device node in an Inventory.
+type InventoryDeviceNode struct {
+	InventorySource
+	*cdi.DeviceNode
+}
+
+// InventoryMount is a mount in an Inventory.
+type InventoryMount struct {
+	InventorySource
+	*cdi.Mount
+}
+
+// InventoryHook is a hook in an Inventory.
+type InventoryHook struct {
+	InventorySource
+	*cdi.Hook
+}
+
+// InventoryEnv is an environment variable in an Inventory.
+type InventoryEnv struct {
+	InventorySource
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// InventoryGID is an additional GID in an Inventory.
+type InventoryGID struct {
+	InventorySource
+	GID uint32 `json:"gid"`
+}
+
+// GetInventory returns the Inventory of the content the injection of the
+// given devices adds to a container. Devices are resolved exactly as by
+// InjectDevices(), so calling GetInventory() with the same devices right
+// after a successful injection describes what was injected. If any of the
+// devices can't be resolved, GetInventory returns the unresolved devices
+// and an error.
+func (c *Cache) GetInventory(devices ...string) (*Inventory, []string, error) {
+	unlock := c.lockRefreshed()
+	defer unlock()
+
+	edits, unresolved, err := c.collectEdits(devices, c.hostRoot)
+	if unresolved != nil {
+		return nil, unresolved, err
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to collect inventory: %w", err)
+	}
+
+	inv, err := newInventory(edits.injected, c.getDeviceInfoResolver())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to collect inventory: %w", err)
+	}
+
+	return inv, nil, nil
+}
+
+// newInventory returns the Inventory for the given injected devices,
+// using the given DeviceInfoResolver for expanding hook templates.
+func newInventory(devices []*Device, r DeviceInfoResolver) (*Inventory, error) {
+	var (
+		inv = &Inventory{
+			Version: InventoryVersion,
+			Devices: []string{},
+			Specs:   []*InjectedSpec{},
+		}
+		specs = map[*Spec]*InjectedSpec{}
+	)
+
+	for _, d := range devices {
+		name := d.GetQualifiedName()
+		spec := d.GetSpec()
+		inv.Devices = append(inv.Devices, name)
+
+		injected, ok := specs[spec]
+		if !ok {
+			injected = &InjectedSpec{
+				Path:    spec.GetPath(),
+				Digest:  spec.GetDigest(),
+				Version: spec.Version,
+			}
+			specs[spec] = injected
+			inv.Specs = append(inv.Specs, injected)
+
+			edits, err := spec.hookEdits()
+			if err != nil {
+				return nil, err
+			}
+			inv.add(InventorySource{Spec: spec.GetPath()}, edits)
+		}
+		injected.Devices = append(injected.Devices, name)
+
+		edits, err := d.hookEdits(r)
+		if err != nil {
+			return nil, err
+		}
+		inv.add(InventorySource{Spec: spec.GetPath(), Device: name}, edits)
+	}
+
+	return inv, nil
+}
+
+// add the given edits from the given source to the Inventory.
+func (inv *Inventory) add(src InventorySource, edits *ContainerEdits) {
+	if edits == nil || edits.ContainerEdits == nil {
+		return
+	}
+	for _, dn := range edits.DeviceNodes {
+		inv.DeviceNodes = append(inv.DeviceNodes, &InventoryDeviceNode{src, dn})
+	}
+	for _, m := range edits.Mounts {
+		inv.Mounts = append(inv.Mounts, &InventoryMount{src, m})
+	}
+	for _, h := range edits.Hooks {
+		inv.Hooks = append(inv.Hooks, &InventoryHook{src, h})
+	}
+	for _, env := range edits.Env {
+		name, value, _ := strings.Cut(env, "=")
+		inv.Env = append(inv.Env, &InventoryEnv{src, name, value})
+	}
+	for _, gid := range edits.AdditionalGIDs {
+		inv.AdditionalGIDs = append(inv.AdditionalGIDs, &InventoryGID{src, gid})
+	}
+}
@@ -0,0 +1,128 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestGetInventory(t *testing.T) {
+	etc := map[string]string{
+		"vendor1.yaml": `
+cdiVersion: "0.9.0"
+kind:       "vendor1.com/device"
+containerEdits:
+  env:
+  - "VENDOR1_SPEC=1"
+  mounts:
+  - hostPath: "/usr/lib/vendor1"
+    containerPath: "/usr/lib/vendor1"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR1_DEV1=1"
+      deviceNodes:
+      - path: "/dev/vendor1-dev1"
+        type: "c"
+        major: 10
+        minor: 1
+      hooks:
+      - hookName: "createContainer"
+        path: "/usr/bin/vendor1-hook"
+        args: ["vendor1-hook", "--device", "{{ .DeviceName }}"]
+  - name: "dev2"
+    containerEdits:
+      additionalGids: [ 1000 ]
+`,
+	}
+
+	dir, err := createSpecDirs(t, etc, nil)
+	require.NoError(t, err)
+
+	cache, err := NewCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+	)
+	require.NoError(t, err)
+
+	specPath := filepath.Join(dir, "etc", "vendor1.yaml")
+	specSrc := InventorySource{Spec: specPath}
+	dev1Src := InventorySource{Spec: specPath, Device: "vendor1.com/device=dev1"}
+	dev2Src := InventorySource{Spec: specPath, Device: "vendor1.com/device=dev2"}
+
+	inv, unresolved, err := cache.GetInventory("vendor1.com/device=dev1", "vendor1.com/device=dev2")
+	require.NoError(t, err)
+	require.Nil(t, unresolved)
+
+	require.Equal(t, InventoryVersion, inv.Version)
+	require.Equal(t, []string{"vendor1.com/device=dev1", "vendor1.com/device=dev2"}, inv.Devices)
+	require.Len(t, inv.Specs, 1)
+	require.Equal(t, specPath, inv.Specs[0].Path)
+	require.Equal(t, cache.GetDevice("vendor1.com/device=dev1").GetSpec().GetDigest(), inv.Specs[0].Digest)
+	require.Equal(t, inv.Devices, inv.Specs[0].Devices)
+	require.Equal(t,
+		[]*InventoryEnv{
+			{specSrc, "VENDOR1_SPEC", "1"},
+			{dev1Src, "VENDOR1_DEV1", "1"},
+		},
+		inv.Env,
+	)
+	require.Equal(t,
+		[]*InventoryMount{
+			{specSrc, &cdi.Mount{HostPath: "/usr/lib/vendor1", ContainerPath: "/usr/lib/vendor1"}},
+		},
+		inv.Mounts,
+	)
+	require.Equal(t,
+		[]*InventoryDeviceNode{
+			{dev1Src, &cdi.DeviceNode{Path: "/dev/vendor1-dev1", Type: "c", Major: 10, Minor: 1}},
+		},
+		inv.DeviceNodes,
+	)
+	require.Equal(t,
+		[]*InventoryHook{
+			{dev1Src, &cdi.Hook{
+				HookName: "createContainer",
+				Path:     "/usr/bin/vendor1-hook",
+				Args:     []string{"vendor1-hook", "--device", "dev1"},
+			}},
+		},
+		inv.Hooks,
+	)
+	require.Equal(t, []*InventoryGID{{dev2Src, 1000}}, inv.AdditionalGIDs)
+
+	data, err := json.Marshal(inv.Hooks[0])
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"spec": "`+specPath+`",
+		"device": "vendor1.com/device=dev1",
+		"hookName": "createContainer",
+		"path": "/usr/bin/vendor1-hook",
+		"args": ["vendor1-hook", "--device", "dev1"]
+	}`, string(data))
+
+	inv, unresolved, err = cache.GetInventory("vendor1.com/device=dev1", "vendor1.com/device=dev3")
+	require.Error(t, err)
+	require.Nil(t, inv)
+	require.Equal(t, []string{"vendor1.com/device=dev3"}, unresolved)
+}
@@ -0,0 +1,242 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// JSON Patch operations used in JSONPatch.
+const (
+	// JSONPatchAdd is the JSON Patch operation adding a value.
+	JSONPatchAdd = "add"
+	// JSONPatchRemove is the JSON Patch operation removing a value.
+	JSONPatchRemove = "remove"
+	// JSONPatchReplace is the JSON Patch operation replacing a value.
+	JSONPatchReplace = "replace"
+)
+
+// JSONPatchOperation is a single RFC 6902 JSON Patch operation.
+type JSONPatchOperation struct {
+	// Op is the operation, one of JSONPatchAdd, JSONPatchRemove or
+	// JSONPatchReplace.
+	Op string `json:"op"`
+	// Path is the JSON Pointer (RFC 6901) of the changed value.
+	Path string `json:"path"`
+	// Value is the new value, for added and replaced values.
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch is an RFC 6902 JSON Patch.
+type JSONPatch []JSONPatchOperation
+
+// ToJSONPatch returns an RFC 6902 JSON Patch with the changes applying
+// the edits would make to the given OCI Spec, instead of changing it. It
+// lets integrators send the changes to a remote agent, for instance. The
+// patch applies to the JSON representation of the OCI Spec. Lists which
+// the edits only append to are patched item by item; other changed lists
+// are replaced as a whole.
+func (e *ContainerEdits) ToJSONPatch(baseSpec *oci.Spec) (JSONPatch, error) {
+	oldDoc, newDoc, err := e.editedDocuments(baseSpec)
+	if err != nil {
+		return nil, err
+	}
+	patch := JSONPatch{}
+	if err := patch.diff("", oldDoc, newDoc); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// ToMergePatch returns an RFC 7386 JSON Merge Patch with the changes
+// applying the edits would make to the given OCI Spec, instead of changing
+// it. Changed lists are replaced as a whole. OCI Specs have no patch
+// strategy metadata, so this is also the strategic merge patch of the
+// changes.
+func (e *ContainerEdits) ToMergePatch(baseSpec *oci.Spec) ([]byte, error) {
+	oldDoc, newDoc, err := e.editedDocuments(baseSpec)
+	if err != nil {
+		return nil, err
+	}
+	patch := mergePatch(oldDoc, newDoc)
+	if patch == nil {
+		patch = map[string]interface{}{}
+	}
+	return json.Marshal(patch)
+}
+
+// editedDocuments returns the given OCI Spec before and after applying
+// the edits as generic JSON documents. The given OCI Spec is not changed.
+func (e *ContainerEdits) editedDocuments(baseSpec *oci.Spec) (interface{}, interface{}, error) {
+	if baseSpec == nil {
+		return nil, nil, errors.New("can't edit nil OCI Spec")
+	}
+
+	data, err := json.Marshal(baseSpec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal OCI Spec: %w", err)
+	}
+	edited := &oci.Spec{}
+	if err := json.Unmarshal(data, edited); err != nil {
+		return nil, nil, fmt.Errorf("failed to copy OCI Spec: %w", err)
+	}
+	if err := e.Apply(edited); err != nil {
+		return nil, nil, err
+	}
+
+	var oldDoc, newDoc interface{}
+	if err := json.Unmarshal(data, &oldDoc); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal OCI Spec: %w", err)
+	}
+	if newDoc, err = toJSONDocument(edited); err != nil {
+		return nil, nil, err
+	}
+	return oldDoc, newDoc, nil
+}
+
+// toJSONDocument returns the given object as a generic JSON document.
+func toJSONDocument(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal OCI Spec: %w", err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal OCI Spec: %w", err)
+	}
+	return doc, nil
+}
+
+// diff appends the operations turning the old value at the given path
+// into the new one.
+func (p *JSONPatch) diff(path string, oldVal, newVal interface{}) error {
+	if reflect.DeepEqual(oldVal, newVal) {
+		return nil
+	}
+
+	switch o := oldVal.(type) {
+	case map[string]interface{}:
+		if n, ok := newVal.(map[string]interface{}); ok {
+			return p.diffObjects(path, o, n)
+		}
+	case []interface{}:
+		if n, ok := newVal.([]interface{}); ok && len(n) >= len(o) &&
+			reflect.DeepEqual(o, n[:len(o)]) {
+			for i := len(o); i < len(n); i++ {
+				if err := p.add(JSONPatchAdd, path+"/"+strconv.Itoa(i), n[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	return p.add(JSONPatchReplace, path, newVal)
+}
+
+// diffObjects appends the operations turning the old object at the given
+// path into the new one, in the sorted order of their keys.
+func (p *JSONPatch) diffObjects(path string, oldObj, newObj map[string]interface{}) error {
+	keys := make([]string, 0, len(oldObj)+len(newObj))
+	for key := range oldObj {
+		keys = append(keys, key)
+	}
+	for key := range newObj {
+		if _, ok := oldObj[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var (
+			keyPath     = path + "/" + escapeJSONPointer(key)
+			oldVal, had = oldObj[key]
+			newVal, has = newObj[key]
+			err         error
+		)
+		switch {
+		case !has:
+			*p = append(*p, JSONPatchOperation{Op: JSONPatchRemove, Path: keyPath})
+		case !had:
+			err = p.add(JSONPatchAdd, keyPath, newVal)
+		default:
+			err = p.diff(keyPath, oldVal, newVal)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add appends an operation with a value.
+func (p *JSONPatch) add(op, path string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON Patch value: %w", err)
+	}
+	*p = append(*p, JSONPatchOperation{Op: op, Path: path, Value: data})
+	return nil
+}
+
+// escapeJSONPointer escapes a reference token of a JSON Pointer.
+func escapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// mergePatch returns the JSON Merge Patch turning the old value into the
+// new one, or nil if they are equal.
+func mergePatch(oldVal, newVal interface{}) interface{} {
+	o, oldIsObj := oldVal.(map[string]interface{})
+	n, newIsObj := newVal.(map[string]interface{})
+	if !oldIsObj || !newIsObj {
+		if reflect.DeepEqual(oldVal, newVal) {
+			return nil
+		}
+		return newVal
+	}
+
+	patch := map[string]interface{}{}
+	for key, oldVal := range o {
+		newVal, ok := n[key]
+		if !ok {
+			patch[key] = nil
+			continue
+		}
+		if p := mergePatch(oldVal, newVal); p != nil {
+			patch[key] = p
+		}
+	}
+	for key, newVal := range n {
+		if _, ok := o[key]; !ok {
+			patch[key] = newVal
+		}
+	}
+	if len(patch) == 0 {
+		return nil
+	}
+	return patch
+}
@@ -0,0 +1,124 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"encoding/json"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestToJSONPatch(t *testing.T) {
+	base := &oci.Spec{
+		Process: &oci.Process{
+			Env: []string{"PATH=/bin"},
+		},
+		Mounts: []oci.Mount{
+			{Destination: "/proc", Type: "proc", Source: "proc"},
+		},
+		Annotations: map[string]string{"a/b": "c"},
+	}
+	edits := &ContainerEdits{
+		ContainerEdits: &cdi.ContainerEdits{
+			Env: []string{"VENDOR=1"},
+			Mounts: []*cdi.Mount{
+				{HostPath: "/lib/vendor", ContainerPath: "/usr/lib/vendor", Options: []string{"ro"}},
+			},
+			AdditionalGIDs: []uint32{44},
+		},
+	}
+
+	patch, err := edits.ToJSONPatch(base)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(patch)
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"op": "add", "path": "/mounts/1", "value": {"destination": "/usr/lib/vendor", "source": "/lib/vendor", "options": ["ro"]}},
+		{"op": "add", "path": "/process/env/1", "value": "VENDOR=1"},
+		{"op": "add", "path": "/process/user/additionalGids", "value": [44]}
+	]`, string(data))
+
+	require.Equal(t, []string{"PATH=/bin"}, base.Process.Env, "base OCI Spec changed")
+	require.Len(t, base.Mounts, 1, "base OCI Spec changed")
+
+	merge, err := edits.ToMergePatch(base)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"mounts": [
+			{"destination": "/proc", "type": "proc", "source": "proc"},
+			{"destination": "/usr/lib/vendor", "source": "/lib/vendor", "options": ["ro"]}
+		],
+		"process": {
+			"env": ["PATH=/bin", "VENDOR=1"],
+			"user": {"additionalGids": [44]}
+		}
+	}`, string(merge))
+}
+
+func TestJSONPatchDiff(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		old, new string
+		patch    string
+	}{
+		{
+			name:  "equal",
+			old:   `{"a": [1, 2]}`,
+			new:   `{"a": [1, 2]}`,
+			patch: `[]`,
+		},
+		{
+			name:  "added, removed and replaced keys",
+			old:   `{"a": 1, "b": {"c": "x"}, "d": true}`,
+			new:   `{"a": 2, "b": {"c": "x", "e": false}}`,
+			patch: `[{"op": "replace", "path": "/a", "value": 2}, {"op": "add", "path": "/b/e", "value": false}, {"op": "remove", "path": "/d"}]`,
+		},
+		{
+			name:  "changed list is replaced",
+			old:   `{"l": [1, 2]}`,
+			new:   `{"l": [2, 1, 3]}`,
+			patch: `[{"op": "replace", "path": "/l", "value": [2, 1, 3]}]`,
+		},
+		{
+			name:  "escaped keys",
+			old:   `{}`,
+			new:   `{"a/b~c": null}`,
+			patch: `[{"op": "add", "path": "/a~1b~0c", "value": null}]`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var oldDoc, newDoc interface{}
+			require.NoError(t, json.Unmarshal([]byte(tc.old), &oldDoc))
+			require.NoError(t, json.Unmarshal([]byte(tc.new), &newDoc))
+
+			patch := JSONPatch{}
+			require.NoError(t, patch.diff("", oldDoc, newDoc))
+			data, err := json.Marshal(patch)
+			require.NoError(t, err)
+			require.JSONEq(t, tc.patch, string(data))
+		})
+	}
+}
+
+func TestToJSONPatchNilSpec(t *testing.T) {
+	_, err := (&ContainerEdits{}).ToJSONPatch(nil)
+	require.Error(t, err)
+}
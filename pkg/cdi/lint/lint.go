@@ -0,0 +1,209 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package lint checks CDI Specs for issues beyond schema validity, like
+// paths which are unlikely to be portable across hosts, mounts which are
+// likely to fail, or device names not following the recommended naming.
+// The issues found are not errors, a Spec with issues can still be valid,
+// but they are worth a second look by the producer of the Spec.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// Severity is the severity of an issue found by a Rule.
+type Severity int
+
+const (
+	// SeverityOff disables a Rule.
+	SeverityOff Severity = iota
+	// SeverityInfo is for issues which are merely informational.
+	SeverityInfo
+	// SeverityWarning is for issues which are likely to cause problems.
+	SeverityWarning
+	// SeverityError is for issues which should be fixed.
+	SeverityError
+)
+
+// String returns the name of the Severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityOff:
+		return "off"
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity returns the Severity with the given name.
+func ParseSeverity(name string) (Severity, error) {
+	for _, s := range []Severity{SeverityOff, SeverityInfo, SeverityWarning, SeverityError} {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return SeverityOff, fmt.Errorf("invalid severity %q", name)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Rule is a lint rule for CDI Specs.
+type Rule struct {
+	// Name identifies the rule.
+	Name string
+	// Description describes what the rule checks.
+	Description string
+	// Severity is the default severity of the issues found by the rule.
+	Severity Severity
+	// Check checks the given Spec and returns the issues found. The Rule
+	// and Severity of the issues are filled in by the Linter.
+	Check func(spec *cdispec.Spec) []*Issue
+}
+
+// Issue is an issue found in a Spec by a Rule.
+type Issue struct {
+	// Rule is the name of the rule which found the issue.
+	Rule string `json:"rule"`
+	// Severity is the severity of the issue.
+	Severity Severity `json:"severity"`
+	// Device is the name of the device with the issue, empty for issues
+	// with the Spec-level content.
+	Device string `json:"device,omitempty"`
+	// Message describes the issue.
+	Message string `json:"message"`
+}
+
+// String returns a human-readable description of the issue.
+func (i *Issue) String() string {
+	if i.Device == "" {
+		return fmt.Sprintf("%s: %s [%s]", i.Severity, i.Message, i.Rule)
+	}
+	return fmt.Sprintf("%s: device %q: %s [%s]", i.Severity, i.Device, i.Message, i.Rule)
+}
+
+// Linter checks Specs using a set of rules.
+type Linter struct {
+	rules    []*Rule
+	severity map[string]Severity
+}
+
+// Option is an option to change some aspect of a Linter.
+type Option func(*Linter)
+
+// New creates a Linter with the given options applied. By default the
+// Linter uses DefaultRules with their default severities.
+func New(options ...Option) *Linter {
+	l := &Linter{
+		rules:    DefaultRules(),
+		severity: map[string]Severity{},
+	}
+	for _, o := range options {
+		o(l)
+	}
+	return l
+}
+
+// WithRules returns an option to add the given rules to the Linter. A
+// rule with the same name as an already added one replaces it.
+func WithRules(rules ...*Rule) Option {
+	return func(l *Linter) {
+		for _, r := range rules {
+			replaced := false
+			for i, old := range l.rules {
+				if old.Name == r.Name {
+					l.rules[i] = r
+					replaced = true
+				}
+			}
+			if !replaced {
+				l.rules = append(l.rules, r)
+			}
+		}
+	}
+}
+
+// WithSeverity returns an option to override the severity of the named
+// rule. SeverityOff disables the rule.
+func WithSeverity(rule string, severity Severity) Option {
+	return func(l *Linter) {
+		l.severity[rule] = severity
+	}
+}
+
+// Rules returns the rules of the Linter with their effective severity.
+func (l *Linter) Rules() []*Rule {
+	rules := make([]*Rule, 0, len(l.rules))
+	for _, r := range l.rules {
+		rule := *r
+		rule.Severity = l.ruleSeverity(r)
+		rules = append(rules, &rule)
+	}
+	return rules
+}
+
+// Lint checks the given Spec using the rules of the Linter. The issues
+// found are returned sorted by decreasing severity, rule name and device.
+func (l *Linter) Lint(spec *cdispec.Spec) []*Issue {
+	if spec == nil {
+		return nil
+	}
+
+	var issues []*Issue
+	for _, r := range l.rules {
+		severity := l.ruleSeverity(r)
+		if severity == SeverityOff || r.Check == nil {
+			continue
+		}
+		for _, issue := range r.Check(spec) {
+			issue.Rule = r.Name
+			issue.Severity = severity
+			issues = append(issues, issue)
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Device < b.Device
+	})
+
+	return issues
+}
+
+// ruleSeverity returns the effective severity of the given rule.
+func (l *Linter) ruleSeverity(r *Rule) Severity {
+	if s, ok := l.severity[r.Name]; ok {
+		return s
+	}
+	return r.Severity
+}
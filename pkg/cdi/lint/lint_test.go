@@ -0,0 +1,141 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func testSpec() *cdispec.Spec {
+	return &cdispec.Spec{
+		Version: cdispec.CurrentVersion,
+		Kind:    "vendor.com/device",
+		ContainerEdits: cdispec.ContainerEdits{
+			Env: []string{"VENDOR=1", "LD_LIBRARY_PATH=/opt/vendor/lib"},
+			Hooks: []*cdispec.Hook{
+				{HookName: "createContainer", Path: "/usr/bin/vendor-hook"},
+			},
+		},
+		Devices: []cdispec.Device{
+			{
+				Name: "dev-0",
+				ContainerEdits: cdispec.ContainerEdits{
+					Mounts: []*cdispec.Mount{
+						{
+							HostPath:      "/usr/lib/vendor",
+							ContainerPath: "/usr/lib/vendor",
+							Options:       []string{"ro", "bind"},
+						},
+						{
+							HostPath:      "/home/user/lib",
+							ContainerPath: "/lib/vendor",
+						},
+					},
+				},
+			},
+			{
+				Name: "GPU_1",
+				ContainerEdits: cdispec.ContainerEdits{
+					DeviceNodes: []*cdispec.DeviceNode{
+						{Path: "/dev/vendor1", HostPath: "/tmp/vendor1"},
+					},
+					Hooks: []*cdispec.Hook{
+						{HookName: "createRuntime", Path: "/bin/vendor-hook"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestLint(t *testing.T) {
+	issues := New().Lint(testSpec())
+
+	var result []string
+	for _, i := range issues {
+		result = append(result, i.String())
+	}
+	require.Equal(t, []string{
+		`warning: environment variable "LD_LIBRARY_PATH" shadows a well-known variable [env-shadowing]`,
+		`warning: device "GPU_1": createRuntime hook "/bin/vendor-hook" is outside of /usr and /opt [hook-location]`,
+		`warning: device "dev-0": mount "/lib/vendor" has no "bind" or "rbind" option [mount-options]`,
+		`warning: device "GPU_1": device node host path "/tmp/vendor1" is in a host-specific location [non-portable-path]`,
+		`warning: device "dev-0": mount host path "/home/user/lib" is in a host-specific location [non-portable-path]`,
+		`info: device "GPU_1": name should consist of lowercase letters and digits separated by single dashes [device-name]`,
+	}, result)
+
+	require.Nil(t, New().Lint(nil))
+}
+
+func TestSeverity(t *testing.T) {
+	l := New(
+		WithSeverity(RuleNonPortablePath, SeverityOff),
+		WithSeverity(RuleEnvShadowing, SeverityOff),
+		WithSeverity(RuleHookLocation, SeverityOff),
+		WithSeverity(RuleDeviceName, SeverityError),
+	)
+	issues := l.Lint(testSpec())
+	require.Len(t, issues, 2)
+	require.Equal(t, RuleDeviceName, issues[0].Rule)
+	require.Equal(t, SeverityError, issues[0].Severity)
+	require.Equal(t, RuleMountOptions, issues[1].Rule)
+	require.Equal(t, SeverityWarning, issues[1].Severity)
+
+	for _, r := range l.Rules() {
+		if r.Name == RuleDeviceName {
+			require.Equal(t, SeverityError, r.Severity)
+		}
+	}
+
+	for _, s := range []Severity{SeverityOff, SeverityInfo, SeverityWarning, SeverityError} {
+		parsed, err := ParseSeverity(s.String())
+		require.NoError(t, err)
+		require.Equal(t, s, parsed)
+	}
+	_, err := ParseSeverity("fatal")
+	require.Error(t, err)
+}
+
+func TestCustomRules(t *testing.T) {
+	noAnnotations := &Rule{
+		Name:     "annotations",
+		Severity: SeverityInfo,
+		Check: func(spec *cdispec.Spec) []*Issue {
+			if len(spec.Annotations) == 0 {
+				return []*Issue{{Message: "no annotations"}}
+			}
+			return nil
+		},
+	}
+	noDeviceNames := &Rule{
+		Name:     RuleDeviceName,
+		Severity: SeverityOff,
+	}
+
+	issues := New(
+		WithRules(noAnnotations, noDeviceNames),
+		WithSeverity(RuleNonPortablePath, SeverityOff),
+		WithSeverity(RuleEnvShadowing, SeverityOff),
+		WithSeverity(RuleHookLocation, SeverityOff),
+		WithSeverity(RuleMountOptions, SeverityOff),
+	).Lint(testSpec())
+	require.Len(t, issues, 1)
+	require.Equal(t, "info: no annotations [annotations]", issues[0].String())
+}
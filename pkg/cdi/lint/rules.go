@@ -0,0 +1,220 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// RuleNonPortablePath flags host paths in host-specific locations,
+	// like home or temporary directories, which are unlikely to exist on
+	// other hosts.
+	RuleNonPortablePath = "non-portable-path"
+	// RuleMountOptions flags bind mounts without a bind mount option,
+	// which runtimes fail to mount.
+	RuleMountOptions = "mount-options"
+	// RuleHookLocation flags hooks with binaries outside of /usr and /opt.
+	RuleHookLocation = "hook-location"
+	// RuleEnvShadowing flags environment variables which shadow well-known
+	// variables of the container, like PATH or HOME.
+	RuleEnvShadowing = "env-shadowing"
+	// RuleDeviceName flags device names not following the recommended
+	// naming of lowercase letters and digits separated by single dashes.
+	RuleDeviceName = "device-name"
+)
+
+var (
+	// nonPortableDirs are host-specific directories.
+	nonPortableDirs = []string{"/home", "/root", "/tmp", "/var/tmp", "/mnt", "/media", "/run/user"}
+	// hookDirs are the recommended directories for hook binaries.
+	hookDirs = []string{"/usr", "/opt"}
+	// wellKnownEnv are well-known environment variables of containers.
+	wellKnownEnv = map[string]struct{}{
+		"PATH":            {},
+		"HOME":            {},
+		"USER":            {},
+		"HOSTNAME":        {},
+		"SHELL":           {},
+		"TERM":            {},
+		"PWD":             {},
+		"LANG":            {},
+		"TZ":              {},
+		"LD_PRELOAD":      {},
+		"LD_LIBRARY_PATH": {},
+	}
+	// deviceNameRegexp matches recommended device names.
+	deviceNameRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+)
+
+// DefaultRules returns the default lint rules.
+func DefaultRules() []*Rule {
+	return []*Rule{
+		{
+			Name:        RuleNonPortablePath,
+			Description: "host paths in host-specific locations",
+			Severity:    SeverityWarning,
+			Check:       checkNonPortablePaths,
+		},
+		{
+			Name:        RuleMountOptions,
+			Description: "bind mounts without a bind mount option",
+			Severity:    SeverityWarning,
+			Check:       checkMountOptions,
+		},
+		{
+			Name:        RuleHookLocation,
+			Description: "hook binaries outside of /usr and /opt",
+			Severity:    SeverityWarning,
+			Check:       checkHookLocations,
+		},
+		{
+			Name:        RuleEnvShadowing,
+			Description: "environment variables shadowing well-known ones",
+			Severity:    SeverityWarning,
+			Check:       checkEnvShadowing,
+		},
+		{
+			Name:        RuleDeviceName,
+			Description: "device names not following the recommended naming",
+			Severity:    SeverityInfo,
+			Check:       checkDeviceNames,
+		},
+	}
+}
+
+// forEachEdits calls the given function for the Spec-level container
+// edits and the container edits of each device of the given Spec.
+func forEachEdits(spec *cdispec.Spec, fn func(device string, edits *cdispec.ContainerEdits)) {
+	fn("", &spec.ContainerEdits)
+	for i := range spec.Devices {
+		fn(spec.Devices[i].Name, &spec.Devices[i].ContainerEdits)
+	}
+}
+
+// isInDir returns true if the given path is in one of the directories.
+func isInDir(p string, dirs []string) bool {
+	p = path.Clean(p)
+	for _, dir := range dirs {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func checkNonPortablePaths(spec *cdispec.Spec) []*Issue {
+	var issues []*Issue
+	check := func(device, kind, p string) {
+		if p != "" && isInDir(p, nonPortableDirs) {
+			issues = append(issues, &Issue{
+				Device:  device,
+				Message: fmt.Sprintf("%s %q is in a host-specific location", kind, p),
+			})
+		}
+	}
+	forEachEdits(spec, func(device string, edits *cdispec.ContainerEdits) {
+		for _, m := range edits.Mounts {
+			check(device, "mount host path", m.HostPath)
+		}
+		for _, d := range edits.DeviceNodes {
+			check(device, "device node host path", d.HostPath)
+		}
+		for _, h := range edits.Hooks {
+			check(device, "hook path", h.Path)
+		}
+	})
+	return issues
+}
+
+func checkMountOptions(spec *cdispec.Spec) []*Issue {
+	var issues []*Issue
+	forEachEdits(spec, func(device string, edits *cdispec.ContainerEdits) {
+		for _, m := range edits.Mounts {
+			if m.Type != "" && m.Type != "bind" {
+				continue
+			}
+			if hasOption(m.Options, "bind") || hasOption(m.Options, "rbind") {
+				continue
+			}
+			issues = append(issues, &Issue{
+				Device:  device,
+				Message: fmt.Sprintf("mount %q has no \"bind\" or \"rbind\" option", m.ContainerPath),
+			})
+		}
+	})
+	return issues
+}
+
+// hasOption returns true if the given mount options contain the option.
+func hasOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func checkHookLocations(spec *cdispec.Spec) []*Issue {
+	var issues []*Issue
+	forEachEdits(spec, func(device string, edits *cdispec.ContainerEdits) {
+		for _, h := range edits.Hooks {
+			if !isInDir(h.Path, hookDirs) {
+				issues = append(issues, &Issue{
+					Device:  device,
+					Message: fmt.Sprintf("%s hook %q is outside of /usr and /opt", h.HookName, h.Path),
+				})
+			}
+		}
+	})
+	return issues
+}
+
+func checkEnvShadowing(spec *cdispec.Spec) []*Issue {
+	var issues []*Issue
+	forEachEdits(spec, func(device string, edits *cdispec.ContainerEdits) {
+		for _, env := range edits.Env {
+			name, _, _ := strings.Cut(env, "=")
+			if _, ok := wellKnownEnv[name]; ok {
+				issues = append(issues, &Issue{
+					Device:  device,
+					Message: fmt.Sprintf("environment variable %q shadows a well-known variable", name),
+				})
+			}
+		}
+	})
+	return issues
+}
+
+func checkDeviceNames(spec *cdispec.Spec) []*Issue {
+	var issues []*Issue
+	for _, d := range spec.Devices {
+		if !deviceNameRegexp.MatchString(d.Name) {
+			issues = append(issues, &Issue{
+				Device:  d.Name,
+				Message: "name should consist of lowercase letters and digits separated by single dashes",
+			})
+		}
+	}
+	return issues
+}
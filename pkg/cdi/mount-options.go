@@ -0,0 +1,88 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// IDMapMountOption makes a bind mount idmapped, mapping the ownership
+	// of files to the user namespace of the container. Added in v0.10.0.
+	IDMapMountOption = "idmap"
+	// RecursiveIDMapMountOption makes a bind mount and all its submounts
+	// idmapped. Added in v0.10.0.
+	RecursiveIDMapMountOption = "ridmap"
+)
+
+var (
+	// Recognized mount propagation options.
+	propagationMountOptions = map[string]struct{}{
+		"shared":      {},
+		"rshared":     {},
+		"slave":       {},
+		"rslave":      {},
+		"private":     {},
+		"rprivate":    {},
+		"unbindable":  {},
+		"runbindable": {},
+	}
+)
+
+// IsPropagationMountOption returns true if the given mount option sets
+// the propagation type of the mount.
+func IsPropagationMountOption(option string) bool {
+	_, ok := propagationMountOptions[option]
+	return ok
+}
+
+// validateOptions checks the mount options for combinations which can't
+// be honored by the runtime.
+func (m *Mount) validateOptions() error {
+	var (
+		propagation string
+		idmap       string
+	)
+
+	for _, o := range m.Options {
+		switch {
+		case IsPropagationMountOption(o):
+			if propagation != "" && propagation != o {
+				return fmt.Errorf("invalid mount %q, conflicting propagation options %q and %q",
+					m.ContainerPath, propagation, o)
+			}
+			propagation = o
+		case o == IDMapMountOption || o == RecursiveIDMapMountOption:
+			if idmap != "" && idmap != o {
+				return fmt.Errorf("invalid mount %q, conflicting options %q and %q",
+					m.ContainerPath, idmap, o)
+			}
+			idmap = o
+		case strings.HasPrefix(o, IDMapMountOption+"=") || strings.HasPrefix(o, RecursiveIDMapMountOption+"="):
+			return fmt.Errorf("invalid mount %q, unsupported option %q, explicit mappings are not supported",
+				m.ContainerPath, o)
+		}
+	}
+
+	if idmap != "" && !isBindMount(m.Mount) {
+		return fmt.Errorf("invalid mount %q, option %q requires a bind mount",
+			m.ContainerPath, idmap)
+	}
+
+	return nil
+}
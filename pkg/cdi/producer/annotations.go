@@ -0,0 +1,117 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"fmt"
+	"strings"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+)
+
+// AnnotationRequest is a single plugin-specific CDI device injection
+// request to be turned into a container annotation.
+type AnnotationRequest struct {
+	// PluginName is the name of the requesting plugin, by convention in
+	// the format "vendor.device-type".
+	PluginName string
+	// DeviceID is the ID of the device allocated by the plugin.
+	DeviceID string
+	// Devices are the fully qualified CDI devices to inject.
+	Devices []string
+}
+
+// AnnotationError describes why a single AnnotationRequest failed.
+type AnnotationError struct {
+	// Index is the index of the failed request.
+	Index int
+	// Request is the failed request.
+	Request AnnotationRequest
+	// Err is the reason for the failure.
+	Err error
+}
+
+// Error returns the error string for the failed request.
+func (e *AnnotationError) Error() string {
+	return fmt.Sprintf("annotation request #%d (%s, %s): %v",
+		e.Index, e.Request.PluginName, e.Request.DeviceID, e.Err)
+}
+
+// Unwrap returns the reason for the failure.
+func (e *AnnotationError) Unwrap() error {
+	return e.Err
+}
+
+// AnnotationErrors collects all errors for a set of AnnotationRequests.
+type AnnotationErrors []*AnnotationError
+
+// Error returns the combined error string for all failed requests.
+func (e AnnotationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// BuildAnnotations creates CDI device injection annotations for the given
+// set of requests. All requests are validated before any annotations are
+// generated: the annotation keys must be valid and unique, and all devices
+// must be valid fully qualified CDI device names. If any request fails,
+// BuildAnnotations returns a nil map and an AnnotationErrors error listing
+// every failed request. Otherwise it returns the complete set of annotations.
+func BuildAnnotations(requests []AnnotationRequest) (map[string]string, error) {
+	var (
+		annotations = make(map[string]string, len(requests))
+		owners      = make(map[string]int, len(requests))
+		errs        AnnotationErrors
+	)
+
+	for idx, req := range requests {
+		fail := func(err error) {
+			errs = append(errs, &AnnotationError{Index: idx, Request: req, Err: err})
+		}
+
+		key, err := cdi.AnnotationKey(req.PluginName, req.DeviceID)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		if other, ok := owners[key]; ok {
+			fail(fmt.Errorf("annotation key %q conflicts with request #%d", key, other))
+			continue
+		}
+		if len(req.Devices) == 0 {
+			fail(fmt.Errorf("no devices for annotation key %q", key))
+			continue
+		}
+		value, err := cdi.AnnotationValue(req.Devices)
+		if err != nil {
+			fail(err)
+			continue
+		}
+
+		owners[key] = idx
+		annotations[key] = value
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return annotations, nil
+}
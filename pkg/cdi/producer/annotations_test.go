@@ -0,0 +1,108 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildAnnotations(t *testing.T) {
+	type testCase struct {
+		name        string
+		requests    []AnnotationRequest
+		annotations map[string]string
+		failed      []int
+	}
+	for _, tc := range []*testCase{
+		{
+			name:        "no requests",
+			annotations: map[string]string{},
+		},
+		{
+			name: "multiple plugins, multiple devices",
+			requests: []AnnotationRequest{
+				{
+					PluginName: "vendor.gpu",
+					DeviceID:   "gpu0",
+					Devices:    []string{"vendor.com/gpu=0", "vendor.com/gpu=1"},
+				},
+				{
+					PluginName: "vendor.nic",
+					DeviceID:   "nic0",
+					Devices:    []string{"vendor.com/nic=0"},
+				},
+			},
+			annotations: map[string]string{
+				"cdi.k8s.io/vendor.gpu_gpu0": "vendor.com/gpu=0,vendor.com/gpu=1",
+				"cdi.k8s.io/vendor.nic_nic0": "vendor.com/nic=0",
+			},
+		},
+		{
+			name: "conflicting keys, invalid devices, invalid plugin",
+			requests: []AnnotationRequest{
+				{
+					PluginName: "vendor.gpu",
+					DeviceID:   "gpu0",
+					Devices:    []string{"vendor.com/gpu=0"},
+				},
+				{
+					PluginName: "vendor.gpu",
+					DeviceID:   "gpu0",
+					Devices:    []string{"vendor.com/gpu=1"},
+				},
+				{
+					PluginName: "vendor.nic",
+					DeviceID:   "nic0",
+					Devices:    []string{"nic0"},
+				},
+				{
+					PluginName: "",
+					DeviceID:   "nic1",
+					Devices:    []string{"vendor.com/nic=1"},
+				},
+				{
+					PluginName: "vendor.nic",
+					DeviceID:   "nic2",
+				},
+			},
+			failed: []int{1, 2, 3, 4},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			annotations, err := BuildAnnotations(tc.requests)
+			if len(tc.failed) == 0 {
+				require.NoError(t, err)
+				require.Equal(t, tc.annotations, annotations)
+				return
+			}
+
+			require.Error(t, err)
+			require.Nil(t, annotations)
+
+			var errs AnnotationErrors
+			require.True(t, errors.As(err, &errs))
+			failed := []int{}
+			for _, e := range errs {
+				failed = append(failed, e.Index)
+			}
+			require.Equal(t, tc.failed, failed)
+		})
+	}
+}
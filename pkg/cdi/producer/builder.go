@@ -0,0 +1,327 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/parser"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// SpecBuilder constructs CDI Specs programmatically. Its methods can be
+// chained, for instance
+//
+//	spec, err := producer.NewSpecBuilder().
+//		WithKind("vendor.com/gpu").
+//		AddDevice("gpu0").
+//		AddDeviceNode(cdispec.DeviceNode{Path: "/dev/gpu0"}).
+//		AddEnv("GPU", "0").
+//		Build()
+//
+// Every addition is validated as it is made. Validation failures are
+// collected and reported by Build(), which also validates the complete
+// Spec. Unless a version is set explicitly, Build() sets the version of
+// the Spec to the minimum version required by the features it uses.
+type SpecBuilder struct {
+	spec      cdispec.Spec
+	devices   []*DeviceBuilder
+	names     map[string]struct{}
+	version   string
+	validator *Validator
+	errs      []error
+}
+
+// DeviceBuilder constructs a device of a Spec built by a SpecBuilder.
+type DeviceBuilder struct {
+	builder *SpecBuilder
+	device  cdispec.Device
+}
+
+// NewSpecBuilder creates a new SpecBuilder.
+func NewSpecBuilder() *SpecBuilder {
+	return &SpecBuilder{
+		names: map[string]struct{}{},
+	}
+}
+
+// WithKind sets the kind, "vendor.com/class", of the Spec.
+func (b *SpecBuilder) WithKind(kind string) *SpecBuilder {
+	vendor, class := parser.ParseQualifier(kind)
+	if err := parser.ValidateVendorName(vendor); err != nil {
+		b.fail(fmt.Errorf("invalid kind %q: %w", kind, err))
+		return b
+	}
+	if err := parser.ValidateClassName(class); err != nil {
+		b.fail(fmt.Errorf("invalid kind %q: %w", kind, err))
+		return b
+	}
+	b.spec.Kind = kind
+	return b
+}
+
+// WithVersion sets the version of the Spec explicitly. Build() fails if
+// the Spec uses features not available in this version.
+func (b *SpecBuilder) WithVersion(version string) *SpecBuilder {
+	b.version = strings.TrimPrefix(version, "v")
+	return b
+}
+
+// WithValidator sets the Validator used by Build() to validate the Spec.
+// By default DefaultValidator is used.
+func (b *SpecBuilder) WithValidator(v *Validator) *SpecBuilder {
+	b.validator = v
+	return b
+}
+
+// WithAnnotation sets a Spec annotation.
+func (b *SpecBuilder) WithAnnotation(key, value string) *SpecBuilder {
+	b.spec.Annotations = withAnnotation(b.spec.Annotations, key, value)
+	return b
+}
+
+// AddEnv adds a Spec-level environment variable.
+func (b *SpecBuilder) AddEnv(name, value string) *SpecBuilder {
+	b.check("", addEnv(&b.spec.ContainerEdits, name, value))
+	return b
+}
+
+// AddDeviceNode adds a Spec-level device node.
+func (b *SpecBuilder) AddDeviceNode(node cdispec.DeviceNode) *SpecBuilder {
+	b.check("", addDeviceNode(&b.spec.ContainerEdits, node))
+	return b
+}
+
+// AddMount adds a Spec-level mount.
+func (b *SpecBuilder) AddMount(mount cdispec.Mount) *SpecBuilder {
+	b.check("", addMount(&b.spec.ContainerEdits, mount))
+	return b
+}
+
+// AddHook adds a Spec-level hook.
+func (b *SpecBuilder) AddHook(hook cdispec.Hook) *SpecBuilder {
+	b.check("", addHook(&b.spec.ContainerEdits, hook))
+	return b
+}
+
+// AddAdditionalGIDs adds Spec-level additional GIDs.
+func (b *SpecBuilder) AddAdditionalGIDs(gids ...uint32) *SpecBuilder {
+	b.spec.ContainerEdits.AdditionalGIDs = append(b.spec.ContainerEdits.AdditionalGIDs, gids...)
+	return b
+}
+
+// AddCapabilities adds Spec-level capabilities to the container process.
+func (b *SpecBuilder) AddCapabilities(caps ...string) *SpecBuilder {
+	b.check("", addCapabilities(&b.spec.ContainerEdits, caps, nil))
+	return b
+}
+
+// DropCapabilities drops Spec-level capabilities from the container process.
+func (b *SpecBuilder) DropCapabilities(caps ...string) *SpecBuilder {
+	b.check("", addCapabilities(&b.spec.ContainerEdits, nil, caps))
+	return b
+}
+
+// AddDevice adds a device with the given name to the Spec and returns a
+// DeviceBuilder for it.
+func (b *SpecBuilder) AddDevice(name string) *DeviceBuilder {
+	d := &DeviceBuilder{
+		builder: b,
+		device:  cdispec.Device{Name: name},
+	}
+	if err := parser.ValidateDeviceName(name); err != nil {
+		b.fail(err)
+	} else if _, ok := b.names[name]; ok {
+		b.fail(fmt.Errorf("duplicate device %q", name))
+	} else {
+		b.names[name] = struct{}{}
+		b.devices = append(b.devices, d)
+	}
+	return d
+}
+
+// Build returns the Spec. It returns an error if any of the additions
+// or the validation of the Spec failed. The returned Spec shares data
+// with the builder, so the builder should not be modified afterwards.
+func (b *SpecBuilder) Build() (*cdispec.Spec, error) {
+	errs := b.errs
+	if b.spec.Kind == "" {
+		errs = append(errs[:len(errs):len(errs)], errors.New("no kind set"))
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to build CDI Spec: %w", errors.Join(errs...))
+	}
+
+	spec := b.spec
+	spec.Devices = make([]cdispec.Device, 0, len(b.devices))
+	for _, d := range b.devices {
+		spec.Devices = append(spec.Devices, d.device)
+	}
+
+	spec.Version = b.version
+	if spec.Version == "" {
+		// the version is not validated, required version always works
+		spec.Version, _ = cdispec.MinimumRequiredVersion(&spec)
+	}
+
+	if err := b.validator.Validate(&spec); err != nil {
+		return nil, fmt.Errorf("failed to build CDI Spec: %w", err)
+	}
+
+	return &spec, nil
+}
+
+// fail records an error.
+func (b *SpecBuilder) fail(err error) {
+	b.errs = append(b.errs, err)
+}
+
+// check records a non-nil error, for the given device if any.
+func (b *SpecBuilder) check(device string, err error) {
+	if err == nil {
+		return
+	}
+	if device != "" {
+		err = fmt.Errorf("device %q: %w", device, err)
+	}
+	b.fail(err)
+}
+
+// WithAnnotation sets a device annotation.
+func (d *DeviceBuilder) WithAnnotation(key, value string) *DeviceBuilder {
+	d.device.Annotations = withAnnotation(d.device.Annotations, key, value)
+	return d
+}
+
+// AddEnv adds an environment variable to the device.
+func (d *DeviceBuilder) AddEnv(name, value string) *DeviceBuilder {
+	d.builder.check(d.device.Name, addEnv(&d.device.ContainerEdits, name, value))
+	return d
+}
+
+// AddDeviceNode adds a device node to the device.
+func (d *DeviceBuilder) AddDeviceNode(node cdispec.DeviceNode) *DeviceBuilder {
+	d.builder.check(d.device.Name, addDeviceNode(&d.device.ContainerEdits, node))
+	return d
+}
+
+// AddMount adds a mount to the device.
+func (d *DeviceBuilder) AddMount(mount cdispec.Mount) *DeviceBuilder {
+	d.builder.check(d.device.Name, addMount(&d.device.ContainerEdits, mount))
+	return d
+}
+
+// AddHook adds a hook to the device.
+func (d *DeviceBuilder) AddHook(hook cdispec.Hook) *DeviceBuilder {
+	d.builder.check(d.device.Name, addHook(&d.device.ContainerEdits, hook))
+	return d
+}
+
+// AddAdditionalGIDs adds additional GIDs to the device.
+func (d *DeviceBuilder) AddAdditionalGIDs(gids ...uint32) *DeviceBuilder {
+	d.device.ContainerEdits.AdditionalGIDs = append(d.device.ContainerEdits.AdditionalGIDs, gids...)
+	return d
+}
+
+// AddCapabilities adds capabilities to the container process for the device.
+func (d *DeviceBuilder) AddCapabilities(caps ...string) *DeviceBuilder {
+	d.builder.check(d.device.Name, addCapabilities(&d.device.ContainerEdits, caps, nil))
+	return d
+}
+
+// DropCapabilities drops capabilities from the container process for the device.
+func (d *DeviceBuilder) DropCapabilities(caps ...string) *DeviceBuilder {
+	d.builder.check(d.device.Name, addCapabilities(&d.device.ContainerEdits, nil, caps))
+	return d
+}
+
+// AddDevice adds another device to the Spec. It is a shorthand for
+// calling AddDevice on the SpecBuilder.
+func (d *DeviceBuilder) AddDevice(name string) *DeviceBuilder {
+	return d.builder.AddDevice(name)
+}
+
+// Spec returns the SpecBuilder of the device.
+func (d *DeviceBuilder) Spec() *SpecBuilder {
+	return d.builder
+}
+
+// Build builds the Spec of the device. It is a shorthand for calling
+// Build on the SpecBuilder.
+func (d *DeviceBuilder) Build() (*cdispec.Spec, error) {
+	return d.builder.Build()
+}
+
+func withAnnotation(annotations map[string]string, key, value string) map[string]string {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key] = value
+	return annotations
+}
+
+func addEnv(edits *cdispec.ContainerEdits, name, value string) error {
+	env := name + "=" + value
+	if err := cdi.ValidateEnv([]string{env}); err != nil {
+		return err
+	}
+	if strings.Contains(name, "=") {
+		return fmt.Errorf("invalid environment variable name %q", name)
+	}
+	edits.Env = append(edits.Env, env)
+	return nil
+}
+
+func addDeviceNode(edits *cdispec.ContainerEdits, node cdispec.DeviceNode) error {
+	if err := (&cdi.DeviceNode{DeviceNode: &node}).Validate(); err != nil {
+		return err
+	}
+	edits.DeviceNodes = append(edits.DeviceNodes, &node)
+	return nil
+}
+
+func addMount(edits *cdispec.ContainerEdits, mount cdispec.Mount) error {
+	if err := (&cdi.Mount{Mount: &mount}).Validate(); err != nil {
+		return err
+	}
+	edits.Mounts = append(edits.Mounts, &mount)
+	return nil
+}
+
+func addCapabilities(edits *cdispec.ContainerEdits, add, drop []string) error {
+	caps := &cdispec.Capabilities{Add: add, Drop: drop}
+	if err := (&cdi.Capabilities{Capabilities: caps}).Validate(); err != nil {
+		return err
+	}
+	if edits.Capabilities == nil {
+		edits.Capabilities = &cdispec.Capabilities{}
+	}
+	edits.Capabilities.Add = append(edits.Capabilities.Add, add...)
+	edits.Capabilities.Drop = append(edits.Capabilities.Drop, drop...)
+	return nil
+}
+
+func addHook(edits *cdispec.ContainerEdits, hook cdispec.Hook) error {
+	if err := (&cdi.Hook{Hook: &hook}).Validate(); err != nil {
+		return err
+	}
+	edits.Hooks = append(edits.Hooks, &hook)
+	return nil
+}
@@ -0,0 +1,213 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestSpecBuilder(t *testing.T) {
+	type testCase struct {
+		name    string
+		build   func() (*cdispec.Spec, error)
+		spec    *cdispec.Spec
+		invalid []string
+	}
+	for _, tc := range []*testCase{
+		{
+			name: "minimal version",
+			build: func() (*cdispec.Spec, error) {
+				return NewSpecBuilder().
+					WithKind("vendor.com/gpu").
+					AddEnv("VENDOR_GPU", "1").
+					AddDevice("gpu0").
+					AddDeviceNode(cdispec.DeviceNode{Path: "/dev/gpu0"}).
+					AddEnv("GPU", "0").
+					AddDevice("gpu1").
+					AddDeviceNode(cdispec.DeviceNode{Path: "/dev/gpu1"}).
+					AddMount(cdispec.Mount{HostPath: "/lib/gpu", ContainerPath: "/lib/gpu"}).
+					Build()
+			},
+			spec: &cdispec.Spec{
+				Version: "0.3.0",
+				Kind:    "vendor.com/gpu",
+				ContainerEdits: cdispec.ContainerEdits{
+					Env: []string{"VENDOR_GPU=1"},
+				},
+				Devices: []cdispec.Device{
+					{
+						Name: "gpu0",
+						ContainerEdits: cdispec.ContainerEdits{
+							DeviceNodes: []*cdispec.DeviceNode{{Path: "/dev/gpu0"}},
+							Env:         []string{"GPU=0"},
+						},
+					},
+					{
+						Name: "gpu1",
+						ContainerEdits: cdispec.ContainerEdits{
+							DeviceNodes: []*cdispec.DeviceNode{{Path: "/dev/gpu1"}},
+							Mounts:      []*cdispec.Mount{{HostPath: "/lib/gpu", ContainerPath: "/lib/gpu"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "required version from features",
+			build: func() (*cdispec.Spec, error) {
+				return NewSpecBuilder().
+					WithKind("vendor.com/gpu").
+					AddDevice("gpu0").
+					AddDeviceNode(cdispec.DeviceNode{Path: "/dev/gpu0", HostPath: "/dev/vendor-gpu0"}).
+					AddAdditionalGIDs(44).
+					Spec().
+					WithAnnotation("vendor.com/driver", "1.0").
+					Build()
+			},
+			spec: &cdispec.Spec{
+				Version:     "0.7.0",
+				Kind:        "vendor.com/gpu",
+				Annotations: map[string]string{"vendor.com/driver": "1.0"},
+				Devices: []cdispec.Device{
+					{
+						Name: "gpu0",
+						ContainerEdits: cdispec.ContainerEdits{
+							DeviceNodes:    []*cdispec.DeviceNode{{Path: "/dev/gpu0", HostPath: "/dev/vendor-gpu0"}},
+							AdditionalGIDs: []uint32{44},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "explicit version too old",
+			build: func() (*cdispec.Spec, error) {
+				return NewSpecBuilder().
+					WithKind("vendor.com/gpu").
+					WithVersion("v0.3.0").
+					AddDevice("gpu0").
+					AddAdditionalGIDs(44).
+					Build()
+			},
+			invalid: []string{"the spec version must be at least v0.7.0"},
+		},
+		{
+			name: "invalid additions",
+			build: func() (*cdispec.Spec, error) {
+				return NewSpecBuilder().
+					WithKind("vendor.com").
+					AddEnv("", "1").
+					AddDevice("gpu0").
+					AddDeviceNode(cdispec.DeviceNode{}).
+					AddHook(cdispec.Hook{HookName: "badHook", Path: "/bin/hook"}).
+					AddDevice("gpu0").
+					AddDevice("gpu 1").
+					Build()
+			},
+			invalid: []string{
+				`invalid kind "vendor.com"`,
+				`invalid environment variable "=1"`,
+				`device "gpu0": invalid (empty) device path`,
+				`device "gpu0": invalid hook name "badHook"`,
+				`duplicate device "gpu0"`,
+				`invalid character ' ' in device name "gpu 1"`,
+				`no kind set`,
+			},
+		},
+		{
+			name: "invalid capabilities",
+			build: func() (*cdispec.Spec, error) {
+				return NewSpecBuilder().
+					WithKind("vendor.com/disk").
+					DropCapabilities("CAP_NET_RAW").
+					AddDevice("disk0").
+					AddCapabilities("CAP_SYS_RAWIO").
+					AddCapabilities("CAP_FOO").
+					Build()
+			},
+			invalid: []string{`device "disk0": invalid capability edits: invalid capability: CAP_FOO`},
+		},
+		{
+			name: "valid capabilities",
+			build: func() (*cdispec.Spec, error) {
+				return NewSpecBuilder().
+					WithKind("vendor.com/disk").
+					DropCapabilities("CAP_NET_RAW").
+					AddDevice("disk0").
+					AddCapabilities("CAP_SYS_RAWIO", "CAP_IPC_LOCK").
+					Build()
+			},
+			spec: &cdispec.Spec{
+				Version: "0.10.0",
+				Kind:    "vendor.com/disk",
+				ContainerEdits: cdispec.ContainerEdits{
+					Capabilities: &cdispec.Capabilities{Drop: []string{"CAP_NET_RAW"}},
+				},
+				Devices: []cdispec.Device{
+					{
+						Name: "disk0",
+						ContainerEdits: cdispec.ContainerEdits{
+							Capabilities: &cdispec.Capabilities{Add: []string{"CAP_SYS_RAWIO", "CAP_IPC_LOCK"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "empty device",
+			build: func() (*cdispec.Spec, error) {
+				return NewSpecBuilder().
+					WithKind("vendor.com/gpu").
+					AddDevice("gpu0").
+					Build()
+			},
+			invalid: []string{"empty device edits"},
+		},
+		{
+			name: "empty device with custom validator",
+			build: func() (*cdispec.Spec, error) {
+				return NewSpecBuilder().
+					WithKind("vendor.com/gpu").
+					WithValidator(NewValidator(WithEmptyDeviceEdits(true))).
+					AddDevice("gpu0").
+					Build()
+			},
+			spec: &cdispec.Spec{
+				Version: "0.3.0",
+				Kind:    "vendor.com/gpu",
+				Devices: []cdispec.Device{{Name: "gpu0"}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			spec, err := tc.build()
+			if len(tc.invalid) > 0 {
+				require.Error(t, err)
+				require.Nil(t, spec)
+				for _, msg := range tc.invalid {
+					require.Contains(t, err.Error(), msg)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.spec, spec)
+		})
+	}
+}
@@ -0,0 +1,392 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// ChangeType is the type of a SpecChange.
+type ChangeType string
+
+const (
+	// ChangeAdded is the type of a change adding something.
+	ChangeAdded ChangeType = "added"
+	// ChangeRemoved is the type of a change removing something.
+	ChangeRemoved ChangeType = "removed"
+	// ChangeModified is the type of a change modifying something.
+	ChangeModified ChangeType = "changed"
+)
+
+// SpecChange is a single semantic difference between two versions of a
+// Spec, for instance a device added, an annotation removed, or the value
+// of an environment variable changed.
+type SpecChange struct {
+	// Type is the type of the change.
+	Type ChangeType `json:"type"`
+	// Kind is the kind of the changed Spec.
+	Kind string `json:"kind"`
+	// Object identifies the changed part of the Spec, for instance
+	// "device dev0: env FOO", or "spec" for the whole Spec.
+	Object string `json:"object"`
+	// Old is the old value, if any.
+	Old string `json:"old,omitempty"`
+	// New is the new value, if any.
+	New string `json:"new,omitempty"`
+}
+
+// String returns a human-readable description of the change.
+func (c *SpecChange) String() string {
+	s := fmt.Sprintf("%s: %s %s", c.Kind, c.Type, c.Object)
+	switch {
+	case c.Old != "" && c.New != "":
+		s += fmt.Sprintf(": %s -> %s", c.Old, c.New)
+	case c.Old != "":
+		s += ": " + c.Old
+	case c.New != "":
+		s += ": " + c.New
+	}
+	return s
+}
+
+// DiffSpecs returns the semantic differences between two versions of a
+// Spec. A nil old or new Spec stands for a Spec which does not exist. The
+// order of devices, groups, and of container edits which are identified
+// by a key, like environment variables by name or mounts by container
+// path, is ignored.
+func DiffSpecs(old, new *cdispec.Spec) []*SpecChange {
+	d := &specDiff{}
+
+	switch {
+	case old == nil && new == nil:
+		return nil
+	case old == nil:
+		d.kind = new.Kind
+		d.add(ChangeAdded, "spec", "", new.Version)
+		return d.changes
+	case new == nil:
+		d.kind = old.Kind
+		d.add(ChangeRemoved, "spec", old.Version, "")
+		return d.changes
+	}
+
+	d.kind = new.Kind
+	if old.Kind != new.Kind {
+		d.add(ChangeModified, "kind", old.Kind, new.Kind)
+	}
+	if old.Version != new.Version {
+		d.add(ChangeModified, "version", old.Version, new.Version)
+	}
+	d.diffAnnotations("", old.Annotations, new.Annotations)
+	d.diffEdits("containerEdits: ", &old.ContainerEdits, &new.ContainerEdits)
+	d.diffDevices(old.Devices, new.Devices)
+	d.diffGroups(old.Groups, new.Groups)
+
+	return d.changes
+}
+
+// DiffSpecSets returns the semantic differences between two sets of Specs,
+// for instance the contents of two Spec directories. Specs are paired by
+// their kind. It is an error for a set to contain several Specs of the
+// same kind.
+func DiffSpecSets(old, new []*cdispec.Spec) ([]*SpecChange, error) {
+	oldSpecs, err := specsByKind(old)
+	if err != nil {
+		return nil, err
+	}
+	newSpecs, err := specsByKind(new)
+	if err != nil {
+		return nil, err
+	}
+
+	kinds := &nameSet{}
+	for kind := range oldSpecs {
+		kinds.add(kind)
+	}
+	for kind := range newSpecs {
+		kinds.add(kind)
+	}
+
+	var changes []*SpecChange
+	for _, kind := range kinds.sorted() {
+		changes = append(changes, DiffSpecs(oldSpecs[kind], newSpecs[kind])...)
+	}
+
+	return changes, nil
+}
+
+func specsByKind(specs []*cdispec.Spec) (map[string]*cdispec.Spec, error) {
+	byKind := make(map[string]*cdispec.Spec, len(specs))
+	for _, spec := range specs {
+		if spec == nil {
+			continue
+		}
+		if _, ok := byKind[spec.Kind]; ok {
+			return nil, fmt.Errorf("multiple CDI Specs of kind %q", spec.Kind)
+		}
+		byKind[spec.Kind] = spec
+	}
+	return byKind, nil
+}
+
+// specDiff collects the changes between two Specs.
+type specDiff struct {
+	kind    string
+	changes []*SpecChange
+}
+
+func (d *specDiff) add(typ ChangeType, object, old, new string) {
+	d.changes = append(d.changes, &SpecChange{
+		Type:   typ,
+		Kind:   d.kind,
+		Object: object,
+		Old:    old,
+		New:    new,
+	})
+}
+
+func (d *specDiff) diffAnnotations(scope string, old, new map[string]string) {
+	var oldItems, newItems []diffItem
+	for key, value := range old {
+		oldItems = append(oldItems, diffItem{key, value})
+	}
+	for key, value := range new {
+		newItems = append(newItems, diffItem{key, value})
+	}
+	d.diffItems(scope+"annotation ", oldItems, newItems)
+}
+
+func (d *specDiff) diffDevices(old, new []cdispec.Device) {
+	var (
+		oldDevices = map[string]*cdispec.Device{}
+		newDevices = map[string]*cdispec.Device{}
+		names      = &nameSet{}
+	)
+	for i := range old {
+		oldDevices[old[i].Name] = &old[i]
+		names.add(old[i].Name)
+	}
+	for i := range new {
+		newDevices[new[i].Name] = &new[i]
+		names.add(new[i].Name)
+	}
+
+	for _, name := range names.sorted() {
+		o, n := oldDevices[name], newDevices[name]
+		scope := "device " + name
+		switch {
+		case o == nil:
+			d.add(ChangeAdded, scope, "", "")
+		case n == nil:
+			d.add(ChangeRemoved, scope, "", "")
+		default:
+			d.diffAnnotations(scope+": ", o.Annotations, n.Annotations)
+			d.diffEdits(scope+": ", &o.ContainerEdits, &n.ContainerEdits)
+		}
+	}
+}
+
+func (d *specDiff) diffGroups(old, new []cdispec.DeviceGroup) {
+	var (
+		oldGroups = map[string]*cdispec.DeviceGroup{}
+		newGroups = map[string]*cdispec.DeviceGroup{}
+		names     = &nameSet{}
+	)
+	for i := range old {
+		oldGroups[old[i].Name] = &old[i]
+		names.add(old[i].Name)
+	}
+	for i := range new {
+		newGroups[new[i].Name] = &new[i]
+		names.add(new[i].Name)
+	}
+
+	for _, name := range names.sorted() {
+		var oldMembers, newMembers string
+		if g := oldGroups[name]; g != nil {
+			oldMembers = strings.Join(g.Devices, ",")
+		}
+		if g := newGroups[name]; g != nil {
+			newMembers = strings.Join(g.Devices, ",")
+		}
+		d.diffValue("group "+name, oldGroups[name] != nil, newGroups[name] != nil, oldMembers, newMembers)
+	}
+}
+
+func (d *specDiff) diffEdits(scope string, old, new *cdispec.ContainerEdits) {
+	d.diffItems(scope+"env ", envItems(old.Env), envItems(new.Env))
+	d.diffValue(scope+"envPolicy", old.EnvPolicy != "", new.EnvPolicy != "", old.EnvPolicy, new.EnvPolicy)
+	d.diffItems(scope+"device node ", nodeItems(old.DeviceNodes), nodeItems(new.DeviceNodes))
+	d.diffItems(scope+"mount ", mountItems(old.Mounts), mountItems(new.Mounts))
+	d.diffItems(scope+"hook ", hookItems(old.Hooks), hookItems(new.Hooks))
+	d.diffItems(scope+"additional GID ", gidItems(old.AdditionalGIDs), gidItems(new.AdditionalGIDs))
+	d.diffValue(scope+"intelRdt", old.IntelRdt != nil, new.IntelRdt != nil,
+		marshalValue(old.IntelRdt), marshalValue(new.IntelRdt))
+	d.diffItems(scope+"added capability ", capItems(old.Capabilities, true), capItems(new.Capabilities, true))
+	d.diffItems(scope+"dropped capability ", capItems(old.Capabilities, false), capItems(new.Capabilities, false))
+}
+
+// diffValue records the change, if any, of a single optional value.
+func (d *specDiff) diffValue(object string, hasOld, hasNew bool, old, new string) {
+	switch {
+	case !hasOld && hasNew:
+		d.add(ChangeAdded, object, "", new)
+	case hasOld && !hasNew:
+		d.add(ChangeRemoved, object, old, "")
+	case hasOld && hasNew && old != new:
+		d.add(ChangeModified, object, old, new)
+	}
+}
+
+// diffItem is a keyed item of a Spec being compared.
+type diffItem struct {
+	key   string
+	value string
+}
+
+// diffItems records the changes between two lists of keyed items.
+func (d *specDiff) diffItems(object string, old, new []diffItem) {
+	var (
+		oldItems = map[string]string{}
+		newItems = map[string]string{}
+		keys     = &nameSet{}
+	)
+	for _, item := range old {
+		oldItems[item.key] = item.value
+		keys.add(item.key)
+	}
+	for _, item := range new {
+		newItems[item.key] = item.value
+		keys.add(item.key)
+	}
+
+	for _, key := range keys.sorted() {
+		o, hasOld := oldItems[key]
+		n, hasNew := newItems[key]
+		d.diffValue(object+key, hasOld, hasNew, o, n)
+	}
+}
+
+func envItems(env []string) []diffItem {
+	items := make([]diffItem, 0, len(env))
+	for _, e := range env {
+		name, _, _ := strings.Cut(e, "=")
+		items = append(items, diffItem{name, e})
+	}
+	return uniqueKeys(items)
+}
+
+func gidItems(gids []uint32) []diffItem {
+	items := make([]diffItem, 0, len(gids))
+	for _, gid := range gids {
+		id := strconv.FormatUint(uint64(gid), 10)
+		items = append(items, diffItem{id, id})
+	}
+	return uniqueKeys(items)
+}
+
+func capItems(caps *cdispec.Capabilities, added bool) []diffItem {
+	if caps == nil {
+		return nil
+	}
+	names := caps.Drop
+	if added {
+		names = caps.Add
+	}
+	items := make([]diffItem, 0, len(names))
+	for _, name := range names {
+		items = append(items, diffItem{name, name})
+	}
+	return uniqueKeys(items)
+}
+
+func nodeItems(nodes []*cdispec.DeviceNode) []diffItem {
+	items := make([]diffItem, 0, len(nodes))
+	for _, n := range nodes {
+		if n != nil {
+			items = append(items, diffItem{n.Path, marshalValue(n)})
+		}
+	}
+	return uniqueKeys(items)
+}
+
+func mountItems(mounts []*cdispec.Mount) []diffItem {
+	items := make([]diffItem, 0, len(mounts))
+	for _, m := range mounts {
+		if m != nil {
+			items = append(items, diffItem{m.ContainerPath, marshalValue(m)})
+		}
+	}
+	return uniqueKeys(items)
+}
+
+func hookItems(hooks []*cdispec.Hook) []diffItem {
+	items := make([]diffItem, 0, len(hooks))
+	for _, h := range hooks {
+		if h != nil {
+			items = append(items, diffItem{h.HookName + " " + h.Path, marshalValue(h)})
+		}
+	}
+	return uniqueKeys(items)
+}
+
+// uniqueKeys disambiguates repeated keys by their occurrence.
+func uniqueKeys(items []diffItem) []diffItem {
+	seen := map[string]int{}
+	for i, item := range items {
+		if n := seen[item.key]; n > 0 {
+			items[i].key = fmt.Sprintf("%s (#%d)", item.key, n+1)
+		}
+		seen[item.key]++
+	}
+	return items
+}
+
+func marshalValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// nameSet is a set of names, remembered in the order added.
+type nameSet struct {
+	seen  map[string]struct{}
+	names []string
+}
+
+func (s *nameSet) add(name string) {
+	if s.seen == nil {
+		s.seen = map[string]struct{}{}
+	}
+	if _, ok := s.seen[name]; !ok {
+		s.seen[name] = struct{}{}
+		s.names = append(s.names, name)
+	}
+}
+
+func (s *nameSet) sorted() []string {
+	sort.Strings(s.names)
+	return s.names
+}
@@ -0,0 +1,138 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestDiffSpecs(t *testing.T) {
+	old := &cdispec.Spec{
+		Version:     "0.6.0",
+		Kind:        "vendor.com/gpu",
+		Annotations: map[string]string{"vendor.com/driver": "1.0"},
+		Devices: []cdispec.Device{
+			{
+				Name: "gpu0",
+				ContainerEdits: cdispec.ContainerEdits{
+					Env: []string{"GPU=0", "DEBUG=1"},
+					DeviceNodes: []*cdispec.DeviceNode{
+						{Path: "/dev/gpu0"},
+					},
+				},
+			},
+			{
+				Name: "gpu1",
+				ContainerEdits: cdispec.ContainerEdits{
+					Env: []string{"GPU=1"},
+				},
+			},
+		},
+		ContainerEdits: cdispec.ContainerEdits{
+			Mounts: []*cdispec.Mount{
+				{HostPath: "/usr/lib/gpu", ContainerPath: "/usr/lib/gpu"},
+			},
+		},
+	}
+	new := &cdispec.Spec{
+		Version:     "0.7.0",
+		Kind:        "vendor.com/gpu",
+		Annotations: map[string]string{"vendor.com/driver": "1.1"},
+		Devices: []cdispec.Device{
+			{
+				Name: "gpu2",
+				ContainerEdits: cdispec.ContainerEdits{
+					Env: []string{"GPU=2"},
+				},
+			},
+			{
+				Name: "gpu0",
+				ContainerEdits: cdispec.ContainerEdits{
+					Env: []string{"DEBUG=1", "GPU=0"},
+					DeviceNodes: []*cdispec.DeviceNode{
+						{Path: "/dev/gpu0", Type: "c"},
+					},
+					AdditionalGIDs: []uint32{44},
+				},
+			},
+		},
+		ContainerEdits: cdispec.ContainerEdits{
+			Mounts: []*cdispec.Mount{
+				{HostPath: "/usr/lib/gpu", ContainerPath: "/usr/lib/gpu"},
+			},
+		},
+		Groups: []cdispec.DeviceGroup{
+			{Name: "all", Devices: []string{"*"}},
+		},
+	}
+
+	changes := DiffSpecs(old, new)
+	descriptions := []string{}
+	for _, c := range changes {
+		descriptions = append(descriptions, c.String())
+	}
+	require.Equal(t, []string{
+		`vendor.com/gpu: changed version: 0.6.0 -> 0.7.0`,
+		`vendor.com/gpu: changed annotation vendor.com/driver: 1.0 -> 1.1`,
+		`vendor.com/gpu: changed device gpu0: device node /dev/gpu0: {"path":"/dev/gpu0"} -> {"path":"/dev/gpu0","type":"c"}`,
+		`vendor.com/gpu: added device gpu0: additional GID 44: 44`,
+		`vendor.com/gpu: removed device gpu1`,
+		`vendor.com/gpu: added device gpu2`,
+		`vendor.com/gpu: added group all: *`,
+	}, descriptions)
+
+	require.Empty(t, DiffSpecs(old, old))
+	require.Nil(t, DiffSpecs(nil, nil))
+	require.Equal(t, []*SpecChange{
+		{Type: ChangeAdded, Kind: "vendor.com/gpu", Object: "spec", New: "0.7.0"},
+	}, DiffSpecs(nil, new))
+}
+
+func TestDiffSpecSets(t *testing.T) {
+	spec := func(kind string, env ...string) *cdispec.Spec {
+		return &cdispec.Spec{
+			Version: "0.3.0",
+			Kind:    kind,
+			Devices: []cdispec.Device{
+				{
+					Name:           "dev0",
+					ContainerEdits: cdispec.ContainerEdits{Env: env},
+				},
+			},
+		}
+	}
+
+	changes, err := DiffSpecSets(
+		[]*cdispec.Spec{spec("vendor.com/a", "A=1"), spec("vendor.com/b")},
+		[]*cdispec.Spec{spec("vendor.com/c"), spec("vendor.com/a", "A=2")},
+	)
+	require.NoError(t, err)
+	require.Equal(t, []*SpecChange{
+		{Type: ChangeModified, Kind: "vendor.com/a", Object: "device dev0: env A", Old: "A=1", New: "A=2"},
+		{Type: ChangeRemoved, Kind: "vendor.com/b", Object: "spec", Old: "0.3.0"},
+		{Type: ChangeAdded, Kind: "vendor.com/c", Object: "spec", New: "0.3.0"},
+	}, changes)
+
+	_, err = DiffSpecSets(
+		[]*cdispec.Spec{spec("vendor.com/a"), spec("vendor.com/a")},
+		nil,
+	)
+	require.Error(t, err)
+}
@@ -0,0 +1,100 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+	"sigs.k8s.io/yaml"
+
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// EncodingYAML is the YAML encoding for formatted Specs.
+	EncodingYAML = "yaml"
+	// EncodingJSON is the JSON encoding for formatted Specs.
+	EncodingJSON = "json"
+)
+
+// FormatOptions control how Format() formats a Spec.
+type FormatOptions struct {
+	// Encoding is the encoding to format the Spec in, EncodingYAML
+	// or EncodingJSON. The default is EncodingYAML.
+	Encoding string
+	// MinimumVersion sets the version of the Spec to the minimum
+	// version required by the features the Spec uses.
+	MinimumVersion bool
+}
+
+// Format returns the given Spec in canonical form, suitable for writing
+// into a Spec file. In canonical form, the version of the Spec is fully
+// qualified, without a leading 'v' ("0.6.0" instead of "v0.6"). YAML is
+// formatted as a single document with keys in alphabetical order, JSON
+// with keys in the order they are defined by the CDI Spec and indented by
+// two spaces. The given Spec is not modified. Formatting an already
+// formatted Spec results in the same data, so the formatting of a Spec
+// file can be checked by comparing it to the result of Format.
+func Format(spec *cdispec.Spec, opts FormatOptions) ([]byte, error) {
+	if spec == nil {
+		return nil, fmt.Errorf("can't format nil CDI Spec")
+	}
+
+	formatted := spec.DeepCopy()
+	formatted.Version = normalizeVersion(formatted.Version)
+
+	if opts.MinimumVersion {
+		version, err := cdispec.MinimumRequiredVersion(formatted)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format CDI Spec: %w", err)
+		}
+		formatted.Version = version
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	switch opts.Encoding {
+	case "", EncodingYAML:
+		data, err = yaml.Marshal(formatted)
+		data = append([]byte("---\n"), data...)
+	case EncodingJSON:
+		data, err = json.MarshalIndent(formatted, "", "  ")
+		data = append(data, '\n')
+	default:
+		return nil, fmt.Errorf("invalid CDI Spec encoding %q", opts.Encoding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to format CDI Spec: %w", err)
+	}
+
+	return data, nil
+}
+
+// normalizeVersion returns the canonical form of a version, or the
+// version itself if it is not a valid semantic version.
+func normalizeVersion(version string) string {
+	canonical := semver.Canonical("v" + strings.TrimPrefix(version, "v"))
+	if canonical == "" {
+		return version
+	}
+	return strings.TrimPrefix(canonical, "v")
+}
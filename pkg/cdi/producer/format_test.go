@@ -0,0 +1,85 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestFormat(t *testing.T) {
+	spec := &cdispec.Spec{
+		Version: "v0.6",
+		Kind:    "vendor.com/gpu",
+		Devices: []cdispec.Device{
+			{
+				Name: "gpu0",
+				ContainerEdits: cdispec.ContainerEdits{
+					Env: []string{"GPU=0"},
+				},
+			},
+		},
+	}
+
+	data, err := Format(spec, FormatOptions{})
+	require.NoError(t, err)
+	require.Equal(t, `---
+cdiVersion: 0.6.0
+containerEdits: {}
+devices:
+- containerEdits:
+    env:
+    - GPU=0
+  name: gpu0
+kind: vendor.com/gpu
+`, string(data))
+	require.Equal(t, "v0.6", spec.Version)
+
+	data, err = Format(spec, FormatOptions{Encoding: EncodingJSON, MinimumVersion: true})
+	require.NoError(t, err)
+	require.Equal(t, `{
+  "cdiVersion": "0.3.0",
+  "kind": "vendor.com/gpu",
+  "devices": [
+    {
+      "name": "gpu0",
+      "containerEdits": {
+        "env": [
+          "GPU=0"
+        ]
+      }
+    }
+  ],
+  "containerEdits": {}
+}
+`, string(data))
+
+	// formatting is idempotent
+	parsed := &cdispec.Spec{}
+	require.NoError(t, yaml.Unmarshal(data, parsed))
+	again, err := Format(parsed, FormatOptions{Encoding: EncodingJSON})
+	require.NoError(t, err)
+	require.Equal(t, data, again)
+
+	_, err = Format(spec, FormatOptions{Encoding: "toml"})
+	require.Error(t, err)
+	_, err = Format(nil, FormatOptions{})
+	require.Error(t, err)
+}
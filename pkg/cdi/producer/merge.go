@@ -0,0 +1,227 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// MergeSpecs merges the given Specs of the same kind into a single Spec,
+// for instance to consolidate Specs generated per device into a single
+// Spec file. The merged Spec
+//   - keeps container edits common to all Specs as Spec-level edits,
+//   - moves any other Spec-level edits of a Spec to the devices of that
+//     Spec, so that injecting a device results in the same edits as
+//     before merging,
+//   - contains every device and group once, deduplicating identical
+//     definitions in multiple Specs,
+//   - has the minimum version required by the features it uses.
+//
+// MergeSpecs fails if the Specs are of different kinds, if a device or a
+// group is defined differently in multiple Specs, if Spec annotations
+// with the same key have different values, or if the merged Spec is not
+// valid. The given Specs are not modified.
+func MergeSpecs(specs ...*cdispec.Spec) (*cdispec.Spec, error) {
+	if len(specs) == 0 {
+		return nil, errors.New("no CDI Specs to merge")
+	}
+
+	copies := make([]*cdispec.Spec, 0, len(specs))
+	for idx, s := range specs {
+		if s == nil {
+			return nil, fmt.Errorf("failed to merge CDI Specs: Spec #%d is nil", idx)
+		}
+		if s.Kind != specs[0].Kind {
+			return nil, fmt.Errorf("failed to merge CDI Specs: kind %q of Spec #%d differs from %q",
+				s.Kind, idx, specs[0].Kind)
+		}
+		copies = append(copies, s.DeepCopy())
+	}
+
+	var (
+		merged = &cdispec.Spec{
+			Kind:    specs[0].Kind,
+			Devices: []cdispec.Device{},
+		}
+		common  = commonEdits(copies)
+		devices = map[string]int{}
+		groups  = map[string]int{}
+	)
+
+	merged.ContainerEdits = *joinEdits(common)
+
+	for idx, s := range copies {
+		for key, value := range s.Annotations {
+			if old, ok := merged.Annotations[key]; ok && old != value {
+				return nil, fmt.Errorf("failed to merge CDI Specs: conflicting values %q and %q for annotation %q",
+					old, value, key)
+			}
+			if merged.Annotations == nil {
+				merged.Annotations = map[string]string{}
+			}
+			merged.Annotations[key] = value
+		}
+
+		extra := subtractEdits(splitEdits(&s.ContainerEdits), common)
+		for _, d := range s.Devices {
+			d.ContainerEdits = *joinEdits(append(extra[:len(extra):len(extra)], splitEdits(&d.ContainerEdits)...))
+			if other, ok := devices[d.Name]; ok {
+				if !merged.Devices[other].Equal(&d) {
+					return nil, fmt.Errorf("failed to merge CDI Specs: conflicting definitions of device %q in Spec #%d",
+						d.Name, idx)
+				}
+				continue
+			}
+			devices[d.Name] = len(merged.Devices)
+			merged.Devices = append(merged.Devices, d)
+		}
+
+		for _, g := range s.Groups {
+			if other, ok := groups[g.Name]; ok {
+				if !merged.Groups[other].Equal(&g) {
+					return nil, fmt.Errorf("failed to merge CDI Specs: conflicting definitions of group %q in Spec #%d",
+						g.Name, idx)
+				}
+				continue
+			}
+			groups[g.Name] = len(merged.Groups)
+			merged.Groups = append(merged.Groups, g)
+		}
+	}
+
+	version, err := cdispec.MinimumRequiredVersion(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge CDI Specs: %w", err)
+	}
+	merged.Version = version
+
+	if err := DefaultValidator.Validate(merged); err != nil {
+		return nil, fmt.Errorf("failed to merge CDI Specs: %w", err)
+	}
+
+	return merged, nil
+}
+
+// editEntry is a single container edit, an environment variable, device
+// node, hook, mount, IntelRdt setting, additional GID or capability, with a key which
+// identifies equal entries.
+type editEntry struct {
+	key   string
+	edits *cdispec.ContainerEdits
+}
+
+// splitEdits splits container edits into single entries.
+func splitEdits(e *cdispec.ContainerEdits) []*editEntry {
+	var entries []*editEntry
+
+	add := func(edits *cdispec.ContainerEdits) {
+		// marshalling container edits never fails
+		data, _ := json.Marshal(edits)
+		entries = append(entries, &editEntry{key: string(data), edits: edits})
+	}
+
+	for _, env := range e.Env {
+		add(&cdispec.ContainerEdits{Env: []string{env}})
+	}
+	for _, dn := range e.DeviceNodes {
+		add(&cdispec.ContainerEdits{DeviceNodes: []*cdispec.DeviceNode{dn}})
+	}
+	for _, h := range e.Hooks {
+		add(&cdispec.ContainerEdits{Hooks: []*cdispec.Hook{h}})
+	}
+	for _, m := range e.Mounts {
+		add(&cdispec.ContainerEdits{Mounts: []*cdispec.Mount{m}})
+	}
+	if e.IntelRdt != nil {
+		add(&cdispec.ContainerEdits{IntelRdt: e.IntelRdt})
+	}
+	for _, gid := range e.AdditionalGIDs {
+		add(&cdispec.ContainerEdits{AdditionalGIDs: []uint32{gid}})
+	}
+	if c := e.Capabilities; c != nil {
+		for _, name := range c.Add {
+			add(&cdispec.ContainerEdits{Capabilities: &cdispec.Capabilities{Add: []string{name}}})
+		}
+		for _, name := range c.Drop {
+			add(&cdispec.ContainerEdits{Capabilities: &cdispec.Capabilities{Drop: []string{name}}})
+		}
+	}
+
+	return entries
+}
+
+// joinEdits joins single entries into container edits.
+func joinEdits(entries []*editEntry) *cdispec.ContainerEdits {
+	e := &cdispec.ContainerEdits{}
+	for _, entry := range entries {
+		e.Env = append(e.Env, entry.edits.Env...)
+		e.DeviceNodes = append(e.DeviceNodes, entry.edits.DeviceNodes...)
+		e.Hooks = append(e.Hooks, entry.edits.Hooks...)
+		e.Mounts = append(e.Mounts, entry.edits.Mounts...)
+		if entry.edits.IntelRdt != nil {
+			e.IntelRdt = entry.edits.IntelRdt
+		}
+		e.AdditionalGIDs = append(e.AdditionalGIDs, entry.edits.AdditionalGIDs...)
+		if c := entry.edits.Capabilities; c != nil {
+			if e.Capabilities == nil {
+				e.Capabilities = &cdispec.Capabilities{}
+			}
+			e.Capabilities.Add = append(e.Capabilities.Add, c.Add...)
+			e.Capabilities.Drop = append(e.Capabilities.Drop, c.Drop...)
+		}
+	}
+	return e
+}
+
+// commonEdits returns the Spec-level edit entries common to all Specs.
+func commonEdits(specs []*cdispec.Spec) []*editEntry {
+	common := splitEdits(&specs[0].ContainerEdits)
+	for _, s := range specs[1:] {
+		keys := map[string]struct{}{}
+		for _, entry := range splitEdits(&s.ContainerEdits) {
+			keys[entry.key] = struct{}{}
+		}
+		var kept []*editEntry
+		for _, entry := range common {
+			if _, ok := keys[entry.key]; ok {
+				kept = append(kept, entry)
+			}
+		}
+		common = kept
+	}
+	return common
+}
+
+// subtractEdits returns the entries which are not among the given
+// common entries.
+func subtractEdits(entries, common []*editEntry) []*editEntry {
+	keys := map[string]struct{}{}
+	for _, entry := range common {
+		keys[entry.key] = struct{}{}
+	}
+	var rest []*editEntry
+	for _, entry := range entries {
+		if _, ok := keys[entry.key]; !ok {
+			rest = append(rest, entry)
+		}
+	}
+	return rest
+}
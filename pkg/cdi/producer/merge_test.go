@@ -0,0 +1,239 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestMergeSpecs(t *testing.T) {
+	gpuSpec := func(idx string, extraEnv ...string) *cdispec.Spec {
+		return &cdispec.Spec{
+			Version: "0.3.0",
+			Kind:    "vendor.com/gpu",
+			ContainerEdits: cdispec.ContainerEdits{
+				Env: append([]string{"VENDOR_GPU=1"}, extraEnv...),
+				Mounts: []*cdispec.Mount{
+					{HostPath: "/lib/gpu", ContainerPath: "/lib/gpu"},
+				},
+			},
+			Devices: []cdispec.Device{
+				{
+					Name: "gpu" + idx,
+					ContainerEdits: cdispec.ContainerEdits{
+						DeviceNodes: []*cdispec.DeviceNode{{Path: "/dev/gpu" + idx}},
+					},
+				},
+			},
+		}
+	}
+
+	type testCase struct {
+		name    string
+		specs   []*cdispec.Spec
+		merged  *cdispec.Spec
+		invalid bool
+	}
+	for _, tc := range []*testCase{
+		{
+			name:    "no Specs",
+			invalid: true,
+		},
+		{
+			name: "per-device Specs",
+			specs: []*cdispec.Spec{
+				gpuSpec("0", "GPU_DRIVER=0"),
+				gpuSpec("1"),
+				gpuSpec("1"),
+			},
+			merged: &cdispec.Spec{
+				Version: "0.3.0",
+				Kind:    "vendor.com/gpu",
+				ContainerEdits: cdispec.ContainerEdits{
+					Env: []string{"VENDOR_GPU=1"},
+					Mounts: []*cdispec.Mount{
+						{HostPath: "/lib/gpu", ContainerPath: "/lib/gpu"},
+					},
+				},
+				Devices: []cdispec.Device{
+					{
+						Name: "gpu0",
+						ContainerEdits: cdispec.ContainerEdits{
+							Env:         []string{"GPU_DRIVER=0"},
+							DeviceNodes: []*cdispec.DeviceNode{{Path: "/dev/gpu0"}},
+						},
+					},
+					{
+						Name: "gpu1",
+						ContainerEdits: cdispec.ContainerEdits{
+							DeviceNodes: []*cdispec.DeviceNode{{Path: "/dev/gpu1"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "minimum version recomputed",
+			specs: []*cdispec.Spec{
+				func() *cdispec.Spec {
+					s := gpuSpec("0")
+					s.Version = "0.9.0"
+					return s
+				}(),
+				func() *cdispec.Spec {
+					s := gpuSpec("1")
+					s.Version = "0.7.0"
+					s.Devices[0].ContainerEdits.AdditionalGIDs = []uint32{44}
+					return s
+				}(),
+			},
+			merged: &cdispec.Spec{
+				Version: "0.7.0",
+				Kind:    "vendor.com/gpu",
+				ContainerEdits: cdispec.ContainerEdits{
+					Env: []string{"VENDOR_GPU=1"},
+					Mounts: []*cdispec.Mount{
+						{HostPath: "/lib/gpu", ContainerPath: "/lib/gpu"},
+					},
+				},
+				Devices: []cdispec.Device{
+					{
+						Name: "gpu0",
+						ContainerEdits: cdispec.ContainerEdits{
+							DeviceNodes: []*cdispec.DeviceNode{{Path: "/dev/gpu0"}},
+						},
+					},
+					{
+						Name: "gpu1",
+						ContainerEdits: cdispec.ContainerEdits{
+							DeviceNodes:    []*cdispec.DeviceNode{{Path: "/dev/gpu1"}},
+							AdditionalGIDs: []uint32{44},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "different kinds",
+			specs: []*cdispec.Spec{
+				gpuSpec("0"),
+				func() *cdispec.Spec {
+					s := gpuSpec("1")
+					s.Kind = "vendor.com/nic"
+					return s
+				}(),
+			},
+			invalid: true,
+		},
+		{
+			name: "conflicting device",
+			specs: []*cdispec.Spec{
+				gpuSpec("0"),
+				gpuSpec("0", "GPU_DRIVER=1"),
+			},
+			invalid: true,
+		},
+		{
+			name: "conflicting annotations",
+			specs: []*cdispec.Spec{
+				func() *cdispec.Spec {
+					s := gpuSpec("0")
+					s.Version = "0.6.0"
+					s.Annotations = map[string]string{"vendor.com/driver": "1.0"}
+					return s
+				}(),
+				func() *cdispec.Spec {
+					s := gpuSpec("1")
+					s.Version = "0.6.0"
+					s.Annotations = map[string]string{"vendor.com/driver": "2.0"}
+					return s
+				}(),
+			},
+			invalid: true,
+		},
+		{
+			name: "conflicting groups",
+			specs: []*cdispec.Spec{
+				func() *cdispec.Spec {
+					s := gpuSpec("0")
+					s.Version = "0.9.0"
+					s.Groups = []cdispec.DeviceGroup{{Name: "all", Devices: []string{"gpu0"}}}
+					return s
+				}(),
+				func() *cdispec.Spec {
+					s := gpuSpec("1")
+					s.Version = "0.9.0"
+					s.Groups = []cdispec.DeviceGroup{{Name: "all", Devices: []string{"gpu1"}}}
+					return s
+				}(),
+			},
+			invalid: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			merged, err := MergeSpecs(tc.specs...)
+			if tc.invalid {
+				require.Error(t, err)
+				require.Nil(t, merged)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.merged, merged)
+		})
+	}
+}
+
+func TestMergeSpecsDoesNotModifyInput(t *testing.T) {
+	specs := []*cdispec.Spec{
+		{
+			Version: "0.3.0",
+			Kind:    "vendor.com/gpu",
+			ContainerEdits: cdispec.ContainerEdits{
+				Env: []string{"GPU0=1"},
+			},
+			Devices: []cdispec.Device{
+				{
+					Name: "gpu0",
+					ContainerEdits: cdispec.ContainerEdits{
+						Env: []string{"GPU=0"},
+					},
+				},
+			},
+		},
+		{
+			Version: "0.3.0",
+			Kind:    "vendor.com/gpu",
+			Devices: []cdispec.Device{
+				{
+					Name: "gpu1",
+					ContainerEdits: cdispec.ContainerEdits{
+						Env: []string{"GPU=1"},
+					},
+				},
+			},
+		},
+	}
+
+	merged, err := MergeSpecs(specs...)
+	require.NoError(t, err)
+	require.Equal(t, []string{"GPU0=1", "GPU=0"}, merged.Devices[0].ContainerEdits.Env)
+	require.Equal(t, []string{"GPU0=1"}, specs[0].ContainerEdits.Env)
+	require.Equal(t, []string{"GPU=0"}, specs[0].Devices[0].ContainerEdits.Env)
+}
@@ -0,0 +1,135 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// MigrateSpec migrates the given Spec in place to the given CDI version,
+// or to cdispec.CurrentVersion if version is empty. Migration fails, and
+// the Spec is left intact, if the version is invalid or if the Spec uses
+// features which are not available in that version. Migrating to an older
+// version is possible as long as the Spec does not use newer features.
+func MigrateSpec(spec *cdispec.Spec, version string) error {
+	if spec == nil {
+		return fmt.Errorf("can't migrate nil CDI Spec")
+	}
+	if version == "" {
+		version = cdispec.CurrentVersion
+	}
+
+	old := spec.Version
+	spec.Version = version
+	if err := cdispec.ValidateVersion(spec); err != nil {
+		spec.Version = old
+		return fmt.Errorf("failed to migrate CDI Spec to version %s: %w", version, err)
+	}
+
+	return nil
+}
+
+// MigrateSpecData parses the given Spec data, rewriting any legacy layouts
+// to their current form, and migrates the resulting Spec to the given CDI
+// version, like MigrateSpec. It returns the migrated Spec and a description
+// of every change made. Currently the following legacy layouts are
+// recognized:
+//   - device nodes with a containerPath instead of a path, and a hostPath
+//     which is dropped if it is the same as the containerPath.
+func MigrateSpecData(data []byte, version string) (*cdispec.Spec, []string, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CDI Spec: %w", err)
+	}
+
+	var changes []string
+
+	if edits, ok := doc["containerEdits"].(map[string]interface{}); ok {
+		changes = append(changes, migrateLegacyEdits("Spec", edits)...)
+	}
+	if devices, ok := doc["devices"].([]interface{}); ok {
+		for _, d := range devices {
+			device, ok := d.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if edits, ok := device["containerEdits"].(map[string]interface{}); ok {
+				scope := fmt.Sprintf("device %v", device["name"])
+				changes = append(changes, migrateLegacyEdits(scope, edits)...)
+			}
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to migrate CDI Spec: %w", err)
+	}
+	spec, err := cdi.ParseSpec(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	old := spec.Version
+	if err := MigrateSpec(spec, version); err != nil {
+		return nil, nil, err
+	}
+	if old != spec.Version {
+		if old == "" {
+			changes = append(changes, fmt.Sprintf("set version %s", spec.Version))
+		} else {
+			changes = append(changes, fmt.Sprintf("changed version %s to %s", old, spec.Version))
+		}
+	}
+
+	return spec, changes, nil
+}
+
+// migrateLegacyEdits rewrites legacy layouts in the given container edits.
+func migrateLegacyEdits(scope string, edits map[string]interface{}) []string {
+	var changes []string
+
+	nodes, _ := edits["deviceNodes"].([]interface{})
+	for _, n := range nodes {
+		node, ok := n.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		containerPath, ok := node["containerPath"]
+		if !ok {
+			continue
+		}
+		delete(node, "containerPath")
+		if _, ok := node["path"]; !ok {
+			node["path"] = containerPath
+		}
+		changes = append(changes, fmt.Sprintf("%s: device node %v: renamed containerPath to path",
+			scope, node["path"]))
+		if hostPath, ok := node["hostPath"]; ok && hostPath == node["path"] {
+			delete(node, "hostPath")
+			changes = append(changes, fmt.Sprintf("%s: device node %v: dropped redundant hostPath",
+				scope, node["path"]))
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,163 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestMigrateSpec(t *testing.T) {
+	spec := &cdispec.Spec{
+		Version: "0.3.0",
+		Kind:    "vendor.com/gpu",
+		Devices: []cdispec.Device{
+			{
+				Name: "gpu0",
+				ContainerEdits: cdispec.ContainerEdits{
+					AdditionalGIDs: []uint32{44},
+				},
+			},
+		},
+	}
+
+	require.NoError(t, MigrateSpec(spec, ""))
+	require.Equal(t, cdispec.CurrentVersion, spec.Version)
+
+	require.NoError(t, MigrateSpec(spec, "0.7.0"))
+	require.Equal(t, "0.7.0", spec.Version)
+
+	require.Error(t, MigrateSpec(spec, "0.6.0"))
+	require.Equal(t, "0.7.0", spec.Version)
+
+	require.Error(t, MigrateSpec(spec, "9.9.9"))
+	require.Equal(t, "0.7.0", spec.Version)
+
+	require.Error(t, MigrateSpec(nil, ""))
+}
+
+func TestMigrateSpecData(t *testing.T) {
+	type testCase struct {
+		name    string
+		data    string
+		version string
+		spec    *cdispec.Spec
+		changes []string
+		invalid bool
+	}
+	for _, tc := range []*testCase{
+		{
+			name: "legacy device nodes",
+			data: `
+cdiVersion: "0.3.0"
+kind: "vendor.com/gpu"
+containerEdits:
+  deviceNodes:
+  - hostPath: "/dev/gpuctl"
+    containerPath: "/dev/gpuctl"
+devices:
+- name: "gpu0"
+  containerEdits:
+    deviceNodes:
+    - hostPath: "/dev/vendor-gpu0"
+      containerPath: "/dev/gpu0"
+      permissions: "rw"
+`,
+			spec: &cdispec.Spec{
+				Version: cdispec.CurrentVersion,
+				Kind:    "vendor.com/gpu",
+				ContainerEdits: cdispec.ContainerEdits{
+					DeviceNodes: []*cdispec.DeviceNode{{Path: "/dev/gpuctl"}},
+				},
+				Devices: []cdispec.Device{
+					{
+						Name: "gpu0",
+						ContainerEdits: cdispec.ContainerEdits{
+							DeviceNodes: []*cdispec.DeviceNode{
+								{Path: "/dev/gpu0", HostPath: "/dev/vendor-gpu0", Permissions: "rw"},
+							},
+						},
+					},
+				},
+			},
+			changes: []string{
+				"Spec: device node /dev/gpuctl: renamed containerPath to path",
+				"Spec: device node /dev/gpuctl: dropped redundant hostPath",
+				"device gpu0: device node /dev/gpu0: renamed containerPath to path",
+				"changed version 0.3.0 to " + cdispec.CurrentVersion,
+			},
+		},
+		{
+			name: "legacy layout, version too old for the result",
+			data: `
+cdiVersion: "0.3.0"
+kind: "vendor.com/gpu"
+devices:
+- name: "gpu0"
+  containerEdits:
+    deviceNodes:
+    - hostPath: "/dev/vendor-gpu0"
+      containerPath: "/dev/gpu0"
+`,
+			version: "0.4.0",
+			invalid: true,
+		},
+		{
+			name: "current layout, unchanged",
+			data: `
+cdiVersion: "0.5.0"
+kind: "vendor.com/gpu"
+devices:
+- name: "gpu0"
+  containerEdits:
+    env: [ "GPU=0" ]
+`,
+			version: "0.5.0",
+			spec: &cdispec.Spec{
+				Version: "0.5.0",
+				Kind:    "vendor.com/gpu",
+				Devices: []cdispec.Device{
+					{
+						Name: "gpu0",
+						ContainerEdits: cdispec.ContainerEdits{
+							Env: []string{"GPU=0"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:    "unknown fields",
+			data:    `{"cdiVersion": "0.3.0", "kind": "vendor.com/gpu", "unknown": 1}`,
+			invalid: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			spec, changes, err := MigrateSpecData([]byte(tc.data), tc.version)
+			if tc.invalid {
+				require.Error(t, err)
+				require.Nil(t, spec)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.spec, spec)
+			require.Equal(t, tc.changes, changes)
+		})
+	}
+}
@@ -0,0 +1,222 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/parser"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// DefaultMockKind is the default kind of mock Specs.
+	DefaultMockKind = "mock.cdi.example.com/device"
+	// DefaultMockNamePrefix is the default prefix of mock device names.
+	DefaultMockNamePrefix = "mock"
+	// DefaultMockDeviceDir is the default container directory of mock
+	// device nodes.
+	DefaultMockDeviceDir = "/dev/cdi-mock"
+	// MockHostDevice is the host device node backing all mock device
+	// nodes.
+	MockHostDevice = "/dev/null"
+)
+
+// MockOptions describe the synthetic devices of a mock Spec.
+type MockOptions struct {
+	// Kind is the kind of the Spec. The default is DefaultMockKind.
+	Kind string
+	// Count is the number of devices. Devices are named by NamePrefix
+	// followed by their index.
+	Count int
+	// NamePrefix is the prefix of the device names. The default is
+	// DefaultMockNamePrefix.
+	NamePrefix string
+	// NodesPerDevice is the number of device nodes of every device. The
+	// device nodes are backed by MockHostDevice.
+	NodesPerDevice int
+	// DeviceDir is the container directory of the device nodes. The
+	// default is DefaultMockDeviceDir.
+	DeviceDir string
+	// EnvPrefix, if set, adds an environment variable to every device
+	// which marks the device as injected. The variable is named by the
+	// prefix followed by the upper case device name, and set to the
+	// qualified name of the device.
+	EnvPrefix string
+}
+
+// MockSpec is a mock Spec written into a Spec directory.
+type MockSpec struct {
+	// Spec is the written Spec.
+	Spec *cdispec.Spec
+	// Path is the path of the Spec file.
+	Path string
+	// Devices are the qualified names of the devices of the Spec.
+	Devices []string
+}
+
+// NewMockSpec creates a Spec with synthetic devices, for testing CDI
+// consumers without real hardware. Every device gets the configured
+// number of device nodes, backed by MockHostDevice, and optionally an
+// environment variable marking it as injected. At least one of them is
+// needed, since CDI devices can't be empty. The same options always
+// create the same Spec.
+func NewMockSpec(opts MockOptions) (*cdispec.Spec, error) {
+	opts = opts.withDefaults()
+	if opts.Count < 1 {
+		return nil, fmt.Errorf("invalid mock device count %d", opts.Count)
+	}
+	if opts.NodesPerDevice < 0 {
+		return nil, fmt.Errorf("invalid mock device node count %d", opts.NodesPerDevice)
+	}
+	if opts.NodesPerDevice == 0 && opts.EnvPrefix == "" {
+		return nil, errors.New("mock devices need device nodes or environment variables")
+	}
+
+	var (
+		vendor, class = parser.ParseQualifier(opts.Kind)
+		b             = NewSpecBuilder().WithKind(opts.Kind)
+	)
+	for i := 0; i < opts.Count; i++ {
+		name := opts.NamePrefix + strconv.Itoa(i)
+		d := b.AddDevice(name)
+		for n := 0; n < opts.NodesPerDevice; n++ {
+			path := filepath.Join(opts.DeviceDir, name)
+			if opts.NodesPerDevice > 1 {
+				path += "-" + strconv.Itoa(n)
+			}
+			d.AddDeviceNode(cdispec.DeviceNode{
+				Path:     path,
+				HostPath: MockHostDevice,
+			})
+		}
+		if opts.EnvPrefix != "" {
+			d.AddEnv(mockEnvName(opts.EnvPrefix, name), parser.QualifiedName(vendor, class, name))
+		}
+	}
+
+	return b.Build()
+}
+
+// WriteMockSpec creates a mock Spec like NewMockSpec() and writes it into
+// the given Spec directory, creating the directory if necessary. The Spec
+// file replaces any existing one for the same kind. It can be removed
+// using the Remove() method of the returned MockSpec.
+func WriteMockSpec(dir string, opts MockOptions) (*MockSpec, error) {
+	spec, err := NewMockSpec(opts)
+	if err != nil {
+		return nil, err
+	}
+	data, err := Format(spec, FormatOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := MockSpecPath(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create Spec directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "mock-spec-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to write mock Spec: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write mock Spec: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write mock Spec: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write mock Spec: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to write mock Spec: %w", err)
+	}
+
+	m := &MockSpec{
+		Spec: spec,
+		Path: path,
+	}
+	vendor, class := parser.ParseQualifier(spec.Kind)
+	for _, d := range spec.Devices {
+		m.Devices = append(m.Devices, parser.QualifiedName(vendor, class, d.Name))
+	}
+	return m, nil
+}
+
+// MockSpecPath returns the path of the Spec file WriteMockSpec() writes
+// into the given Spec directory for the given options.
+func MockSpecPath(dir string, opts MockOptions) (string, error) {
+	opts = opts.withDefaults()
+	vendor, class := parser.ParseQualifier(opts.Kind)
+	if err := parser.ValidateVendorName(vendor); err != nil {
+		return "", fmt.Errorf("invalid mock Spec kind %q: %w", opts.Kind, err)
+	}
+	if err := parser.ValidateClassName(class); err != nil {
+		return "", fmt.Errorf("invalid mock Spec kind %q: %w", opts.Kind, err)
+	}
+	return filepath.Join(dir, cdi.GenerateSpecName(vendor, class)+".yaml"), nil
+}
+
+// Remove removes the Spec file of the mock Spec. Removing an already
+// removed Spec file is not an error.
+func (m *MockSpec) Remove() error {
+	if err := os.Remove(m.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove mock Spec: %w", err)
+	}
+	return nil
+}
+
+// withDefaults returns the options with defaults for unset ones.
+func (opts MockOptions) withDefaults() MockOptions {
+	if opts.Kind == "" {
+		opts.Kind = DefaultMockKind
+	}
+	if opts.NamePrefix == "" {
+		opts.NamePrefix = DefaultMockNamePrefix
+	}
+	if opts.DeviceDir == "" {
+		opts.DeviceDir = DefaultMockDeviceDir
+	}
+	return opts
+}
+
+// mockEnvName returns the name of the environment variable marking the
+// mock device with the given name as injected.
+func mockEnvName(prefix, device string) string {
+	return prefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, device)
+}
@@ -0,0 +1,102 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestNewMockSpec(t *testing.T) {
+	spec, err := NewMockSpec(MockOptions{
+		Count:          2,
+		NodesPerDevice: 2,
+		EnvPrefix:      "CDI_MOCK_",
+	})
+	require.NoError(t, err)
+	require.Equal(t, DefaultMockKind, spec.Kind)
+	require.Equal(t, "0.5.0", spec.Version)
+	require.Len(t, spec.Devices, 2)
+	require.Equal(t, cdispec.Device{
+		Name: "mock1",
+		ContainerEdits: cdispec.ContainerEdits{
+			Env: []string{"CDI_MOCK_MOCK1=" + DefaultMockKind + "=mock1"},
+			DeviceNodes: []*cdispec.DeviceNode{
+				{Path: "/dev/cdi-mock/mock1-0", HostPath: MockHostDevice},
+				{Path: "/dev/cdi-mock/mock1-1", HostPath: MockHostDevice},
+			},
+		},
+	}, spec.Devices[1])
+
+	again, err := NewMockSpec(MockOptions{
+		Count:          2,
+		NodesPerDevice: 2,
+		EnvPrefix:      "CDI_MOCK_",
+	})
+	require.NoError(t, err)
+	require.True(t, spec.Equal(again))
+
+	for _, opts := range []MockOptions{
+		{Count: 0, NodesPerDevice: 1},
+		{Count: 1, NodesPerDevice: -1},
+		{Count: 1},
+		{Count: 1, NodesPerDevice: 1, Kind: "invalid"},
+		{Count: 1, NodesPerDevice: 1, NamePrefix: "in/valid"},
+	} {
+		_, err := NewMockSpec(opts)
+		require.Error(t, err, "options %+v", opts)
+	}
+}
+
+func TestWriteMockSpec(t *testing.T) {
+	dir := t.TempDir()
+
+	mock, err := WriteMockSpec(dir, MockOptions{
+		Kind:           "vendor.com/fake",
+		Count:          3,
+		NamePrefix:     "gpu",
+		NodesPerDevice: 1,
+		DeviceDir:      "/dev/fake",
+		EnvPrefix:      "FAKE_",
+	})
+	require.NoError(t, err)
+	path, err := MockSpecPath(dir, MockOptions{Kind: "vendor.com/fake"})
+	require.NoError(t, err)
+	require.Equal(t, path, mock.Path)
+	require.Equal(t, []string{"vendor.com/fake=gpu0", "vendor.com/fake=gpu1", "vendor.com/fake=gpu2"},
+		mock.Devices)
+
+	cache, err := cdi.NewCache(cdi.WithSpecDirs(dir), cdi.WithAutoRefresh(false))
+	require.NoError(t, err)
+	require.Equal(t, mock.Devices, cache.ListDevices())
+
+	ociSpec := &oci.Spec{}
+	_, err = cache.InjectDevices(ociSpec, "vendor.com/fake=gpu1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"FAKE_GPU1=vendor.com/fake=gpu1"}, ociSpec.Process.Env)
+	require.Len(t, ociSpec.Linux.Devices, 1)
+	require.Equal(t, "/dev/fake/gpu1", ociSpec.Linux.Devices[0].Path)
+
+	require.NoError(t, mock.Remove())
+	require.NoError(t, mock.Remove())
+	require.NoError(t, cache.Refresh())
+	require.Empty(t, cache.ListDevices())
+}
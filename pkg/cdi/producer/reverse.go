@@ -0,0 +1,465 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// SpecFromOCIDiff generates a CDI Spec with a single device of the given
+// kind and name whose container edits make the changes between the given
+// original and modified OCI Specs. This eases migrating legacy injectors,
+// like OCI runtime hooks which modify the OCI Spec, to CDI: the OCI Spec
+// before and after the legacy injection can be turned into an equivalent
+// CDI Spec.
+//
+// Added or changed environment variables, device nodes, mounts, hooks,
+// additional GIDs and Intel RDT parameters are converted to container
+// edits. Device node permissions are taken from the device cgroup rules
+// added for them. All other changes, including removals, can't be made
+// by CDI and are returned as a list of descriptions, for the caller to
+// review. An error is returned if there are no changes CDI can make or
+// if the generated Spec is invalid.
+func SpecFromOCIDiff(original, modified *oci.Spec, kind, device string) (*cdispec.Spec, []string, error) {
+	if original == nil || modified == nil {
+		return nil, nil, errors.New("can't diff nil OCI Spec")
+	}
+
+	var (
+		edits       cdispec.ContainerEdits
+		unsupported []string
+		changes     []string
+	)
+
+	edits.Env, changes = diffOCIEnv(original, modified)
+	unsupported = append(unsupported, changes...)
+	edits.DeviceNodes, changes = diffOCIDevices(original, modified)
+	unsupported = append(unsupported, changes...)
+	edits.Mounts, changes = diffOCIMounts(original, modified)
+	unsupported = append(unsupported, changes...)
+	edits.Hooks, changes = diffOCIHooks(original, modified)
+	unsupported = append(unsupported, changes...)
+	edits.AdditionalGIDs = diffOCIAdditionalGIDs(original, modified)
+	edits.IntelRdt = diffOCIIntelRdt(original, modified)
+
+	changes, err := diffOCIOther(original, modified)
+	if err != nil {
+		return nil, nil, err
+	}
+	unsupported = append(unsupported, changes...)
+
+	if reflect.DeepEqual(edits, cdispec.ContainerEdits{}) {
+		return nil, unsupported, errors.New("no changes expressible as CDI container edits")
+	}
+
+	spec := &cdispec.Spec{
+		Kind: kind,
+		Devices: []cdispec.Device{
+			{
+				Name:           device,
+				ContainerEdits: edits,
+			},
+		},
+	}
+	// the version is not validated, required version always works
+	spec.Version, _ = cdispec.MinimumRequiredVersion(spec)
+
+	if err := DefaultValidator.Validate(spec); err != nil {
+		return nil, unsupported, fmt.Errorf("failed to generate CDI Spec: %w", err)
+	}
+
+	return spec, unsupported, nil
+}
+
+// diffOCIEnv returns the added or changed environment variables.
+func diffOCIEnv(original, modified *oci.Spec) ([]string, []string) {
+	var oldEnv, newEnv []string
+	if original.Process != nil {
+		oldEnv = original.Process.Env
+	}
+	if modified.Process != nil {
+		newEnv = modified.Process.Env
+	}
+
+	present := map[string]struct{}{}
+	for _, e := range oldEnv {
+		present[e] = struct{}{}
+	}
+	names := map[string]struct{}{}
+	for _, e := range newEnv {
+		names[envName(e)] = struct{}{}
+	}
+
+	var (
+		env         []string
+		unsupported []string
+	)
+	for _, e := range newEnv {
+		if _, ok := present[e]; !ok {
+			env = append(env, e)
+		}
+	}
+	for _, e := range oldEnv {
+		if _, ok := names[envName(e)]; !ok {
+			unsupported = append(unsupported, fmt.Sprintf("removed environment variable %s", envName(e)))
+		}
+	}
+
+	return env, unsupported
+}
+
+// envName returns the name of an environment variable.
+func envName(e string) string {
+	name, _, _ := strings.Cut(e, "=")
+	return name
+}
+
+// diffOCIDevices returns the added or changed device nodes.
+func diffOCIDevices(original, modified *oci.Spec) ([]*cdispec.DeviceNode, []string) {
+	var (
+		oldDevs, newDevs   []oci.LinuxDevice
+		oldRules, newRules []oci.LinuxDeviceCgroup
+	)
+	if l := original.Linux; l != nil {
+		oldDevs = l.Devices
+		if l.Resources != nil {
+			oldRules = l.Resources.Devices
+		}
+	}
+	if l := modified.Linux; l != nil {
+		newDevs = l.Devices
+		if l.Resources != nil {
+			newRules = l.Resources.Devices
+		}
+	}
+
+	present := map[string]oci.LinuxDevice{}
+	for _, d := range oldDevs {
+		present[d.Path] = d
+	}
+	paths := map[string]struct{}{}
+	for _, d := range newDevs {
+		paths[d.Path] = struct{}{}
+	}
+
+	// permissions are granted by the device cgroup rules added
+	var added []oci.LinuxDeviceCgroup
+	for _, r := range newRules {
+		found := false
+		for _, o := range oldRules {
+			if reflect.DeepEqual(r, o) {
+				found = true
+				break
+			}
+		}
+		if !found && r.Allow {
+			added = append(added, r)
+		}
+	}
+	permissions := func(d oci.LinuxDevice) string {
+		for _, r := range added {
+			if r.Type == d.Type && r.Major != nil && *r.Major == d.Major &&
+				r.Minor != nil && *r.Minor == d.Minor {
+				return r.Access
+			}
+		}
+		return ""
+	}
+
+	var (
+		nodes       []*cdispec.DeviceNode
+		unsupported []string
+	)
+	for _, d := range newDevs {
+		if old, ok := present[d.Path]; ok && reflect.DeepEqual(old, d) {
+			continue
+		}
+		nodes = append(nodes, &cdispec.DeviceNode{
+			Path:        d.Path,
+			Type:        d.Type,
+			Major:       d.Major,
+			Minor:       d.Minor,
+			FileMode:    d.FileMode,
+			Permissions: permissions(d),
+			UID:         d.UID,
+			GID:         d.GID,
+		})
+	}
+	for _, d := range oldDevs {
+		if _, ok := paths[d.Path]; !ok {
+			unsupported = append(unsupported, fmt.Sprintf("removed device node %s", d.Path))
+		}
+	}
+
+	return nodes, unsupported
+}
+
+// diffOCIMounts returns the added or changed mounts.
+func diffOCIMounts(original, modified *oci.Spec) ([]*cdispec.Mount, []string) {
+	present := map[string]oci.Mount{}
+	for _, m := range original.Mounts {
+		present[m.Destination] = m
+	}
+	paths := map[string]struct{}{}
+	for _, m := range modified.Mounts {
+		paths[m.Destination] = struct{}{}
+	}
+
+	var (
+		mounts      []*cdispec.Mount
+		unsupported []string
+	)
+	for _, m := range modified.Mounts {
+		if old, ok := present[m.Destination]; ok && reflect.DeepEqual(old, m) {
+			continue
+		}
+		mounts = append(mounts, &cdispec.Mount{
+			HostPath:      m.Source,
+			ContainerPath: m.Destination,
+			Options:       m.Options,
+			Type:          m.Type,
+		})
+	}
+	for _, m := range original.Mounts {
+		if _, ok := paths[m.Destination]; !ok {
+			unsupported = append(unsupported, fmt.Sprintf("removed mount %s", m.Destination))
+		}
+	}
+
+	return mounts, unsupported
+}
+
+// diffOCIHooks returns the added hooks.
+func diffOCIHooks(original, modified *oci.Spec) ([]*cdispec.Hook, []string) {
+	oldHooks, newHooks := ociHooksByName(original), ociHooksByName(modified)
+
+	var (
+		hooks       []*cdispec.Hook
+		unsupported []string
+	)
+	for _, name := range cdi.ValidHookNames() {
+		for _, h := range newHooks[name] {
+			if containsOCIHook(oldHooks[name], h) {
+				continue
+			}
+			hooks = append(hooks, &cdispec.Hook{
+				HookName: name,
+				Path:     h.Path,
+				Args:     h.Args,
+				Env:      h.Env,
+				Timeout:  h.Timeout,
+			})
+		}
+		for _, h := range oldHooks[name] {
+			if !containsOCIHook(newHooks[name], h) {
+				unsupported = append(unsupported, fmt.Sprintf("removed %s hook %s", name, h.Path))
+			}
+		}
+	}
+
+	return hooks, unsupported
+}
+
+// ociHooksByName returns the hooks of the given OCI Spec by hook name.
+func ociHooksByName(spec *oci.Spec) map[string][]oci.Hook {
+	h := spec.Hooks
+	if h == nil {
+		return nil
+	}
+	return map[string][]oci.Hook{
+		cdi.PrestartHook:        h.Prestart, //nolint:staticcheck
+		cdi.CreateRuntimeHook:   h.CreateRuntime,
+		cdi.CreateContainerHook: h.CreateContainer,
+		cdi.StartContainerHook:  h.StartContainer,
+		cdi.PoststartHook:       h.Poststart,
+		cdi.PoststopHook:        h.Poststop,
+	}
+}
+
+// containsOCIHook returns true if hooks contains the given hook.
+func containsOCIHook(hooks []oci.Hook, hook oci.Hook) bool {
+	for _, h := range hooks {
+		if reflect.DeepEqual(h, hook) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffOCIAdditionalGIDs returns the added additional GIDs.
+func diffOCIAdditionalGIDs(original, modified *oci.Spec) []uint32 {
+	var oldGIDs, newGIDs []uint32
+	if original.Process != nil {
+		oldGIDs = original.Process.User.AdditionalGids
+	}
+	if modified.Process != nil {
+		newGIDs = modified.Process.User.AdditionalGids
+	}
+
+	present := map[uint32]struct{}{}
+	for _, gid := range oldGIDs {
+		present[gid] = struct{}{}
+	}
+
+	var gids []uint32
+	for _, gid := range newGIDs {
+		if _, ok := present[gid]; !ok {
+			gids = append(gids, gid)
+		}
+	}
+	return gids
+}
+
+// diffOCIIntelRdt returns the changed Intel RDT parameters.
+func diffOCIIntelRdt(original, modified *oci.Spec) *cdispec.IntelRdt {
+	var oldRdt, newRdt *oci.LinuxIntelRdt
+	if original.Linux != nil {
+		oldRdt = original.Linux.IntelRdt
+	}
+	if modified.Linux != nil {
+		newRdt = modified.Linux.IntelRdt
+	}
+	if newRdt == nil || reflect.DeepEqual(oldRdt, newRdt) {
+		return nil
+	}
+	return &cdispec.IntelRdt{
+		ClosID:        newRdt.ClosID,
+		L3CacheSchema: newRdt.L3CacheSchema,
+		MemBwSchema:   newRdt.MemBwSchema,
+		EnableCMT:     newRdt.EnableCMT,
+		EnableMBM:     newRdt.EnableMBM,
+	}
+}
+
+// diffOCIOther describes the changes of the OCI Spec other than the ones
+// converted to container edits.
+func diffOCIOther(original, modified *oci.Spec) ([]string, error) {
+	oldDoc, err := ociOtherFields(original)
+	if err != nil {
+		return nil, err
+	}
+	newDoc, err := ociOtherFields(modified)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []string
+	for _, key := range unionKeys(oldDoc, newDoc) {
+		oldVal, newVal := oldDoc[key], newDoc[key]
+		if reflect.DeepEqual(oldVal, newVal) {
+			continue
+		}
+		oldMap, oldOk := oldVal.(map[string]interface{})
+		newMap, newOk := newVal.(map[string]interface{})
+		if !oldOk || !newOk {
+			changes = append(changes, fmt.Sprintf("changed %s", key))
+			continue
+		}
+		for _, sub := range unionKeys(oldMap, newMap) {
+			if !reflect.DeepEqual(oldMap[sub], newMap[sub]) {
+				changes = append(changes, fmt.Sprintf("changed %s.%s", key, sub))
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// ociOtherFields returns the OCI Spec as a generic document, without the
+// fields converted to container edits and any empty values.
+func ociOtherFields(spec *oci.Spec) (map[string]interface{}, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal OCI Spec: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal OCI Spec: %w", err)
+	}
+
+	delete(doc, "mounts")
+	delete(doc, "hooks")
+	if process, ok := doc["process"].(map[string]interface{}); ok {
+		delete(process, "env")
+		if user, ok := process["user"].(map[string]interface{}); ok {
+			delete(user, "additionalGids")
+		}
+	}
+	if linux, ok := doc["linux"].(map[string]interface{}); ok {
+		delete(linux, "devices")
+		delete(linux, "intelRdt")
+		if resources, ok := linux["resources"].(map[string]interface{}); ok {
+			delete(resources, "devices")
+		}
+	}
+
+	pruned, _ := pruneEmpty(doc).(map[string]interface{})
+	if pruned == nil {
+		pruned = map[string]interface{}{}
+	}
+	return pruned, nil
+}
+
+// pruneEmpty removes empty objects and arrays from a generic document,
+// returning nil if the value itself is empty.
+func pruneEmpty(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, e := range val {
+			if e = pruneEmpty(e); e == nil {
+				delete(val, k)
+			} else {
+				val[k] = e
+			}
+		}
+		if len(val) == 0 {
+			return nil
+		}
+	case []interface{}:
+		if len(val) == 0 {
+			return nil
+		}
+	}
+	return v
+}
+
+// unionKeys returns the sorted union of the keys of the given maps.
+func unionKeys(a, b map[string]interface{}) []string {
+	set := map[string]struct{}{}
+	for k := range a {
+		set[k] = struct{}{}
+	}
+	for k := range b {
+		set[k] = struct{}{}
+	}
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,125 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"os"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestSpecFromOCIDiff(t *testing.T) {
+	var (
+		major, minor = int64(195), int64(0)
+		mode         = os.FileMode(0666)
+		gid          = uint32(44)
+	)
+
+	original := func() *oci.Spec {
+		return &oci.Spec{
+			Version: oci.Version,
+			Process: &oci.Process{
+				Env: []string{"PATH=/usr/bin", "HOME=/root", "TERM=xterm"},
+			},
+			Mounts: []oci.Mount{
+				{Destination: "/proc", Type: "proc", Source: "proc"},
+				{Destination: "/tmp", Type: "tmpfs", Source: "tmpfs"},
+			},
+			Linux: &oci.Linux{
+				Resources: &oci.LinuxResources{
+					Devices: []oci.LinuxDeviceCgroup{
+						{Allow: false, Access: "rwm"},
+					},
+				},
+			},
+		}
+	}
+
+	modified := original()
+	modified.Process.Env = []string{"PATH=/usr/bin", "HOME=/root", "VENDOR_VISIBLE_DEVICES=0"}
+	modified.Process.User.AdditionalGids = []uint32{gid}
+	modified.Process.Capabilities = &oci.LinuxCapabilities{Bounding: []string{"CAP_SYS_ADMIN"}}
+	modified.Mounts = []oci.Mount{
+		{Destination: "/proc", Type: "proc", Source: "proc"},
+		{Destination: "/usr/lib/libvendor.so", Type: "bind", Source: "/usr/lib/libvendor.so", Options: []string{"ro", "bind"}},
+	}
+	modified.Hooks = &oci.Hooks{
+		CreateContainer: []oci.Hook{{Path: "/usr/bin/vendor-hook", Args: []string{"vendor-hook", "update"}}},
+	}
+	modified.Linux.Devices = []oci.LinuxDevice{
+		{Path: "/dev/vendor0", Type: "c", Major: major, Minor: minor, FileMode: &mode},
+	}
+	modified.Linux.Resources.Devices = append(modified.Linux.Resources.Devices,
+		oci.LinuxDeviceCgroup{Allow: true, Type: "c", Major: &major, Minor: &minor, Access: "rw"})
+
+	spec, unsupported, err := SpecFromOCIDiff(original(), modified, "vendor.com/device", "dev0")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"removed environment variable TERM",
+		"removed mount /tmp",
+		"changed process.capabilities",
+	}, unsupported)
+	require.Equal(t, &cdispec.Spec{
+		Version: "0.7.0",
+		Kind:    "vendor.com/device",
+		Devices: []cdispec.Device{
+			{
+				Name: "dev0",
+				ContainerEdits: cdispec.ContainerEdits{
+					Env: []string{"VENDOR_VISIBLE_DEVICES=0"},
+					DeviceNodes: []*cdispec.DeviceNode{
+						{
+							Path:        "/dev/vendor0",
+							Type:        "c",
+							Major:       major,
+							Minor:       minor,
+							FileMode:    &mode,
+							Permissions: "rw",
+						},
+					},
+					Hooks: []*cdispec.Hook{
+						{
+							HookName: "createContainer",
+							Path:     "/usr/bin/vendor-hook",
+							Args:     []string{"vendor-hook", "update"},
+						},
+					},
+					Mounts: []*cdispec.Mount{
+						{
+							HostPath:      "/usr/lib/libvendor.so",
+							ContainerPath: "/usr/lib/libvendor.so",
+							Options:       []string{"ro", "bind"},
+							Type:          "bind",
+						},
+					},
+					AdditionalGIDs: []uint32{gid},
+				},
+			},
+		},
+	}, spec)
+
+	_, _, err = SpecFromOCIDiff(original(), original(), "vendor.com/device", "dev0")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no changes")
+
+	_, _, err = SpecFromOCIDiff(original(), modified, "vendor.com/device", "")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to generate CDI Spec")
+}
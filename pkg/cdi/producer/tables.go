@@ -0,0 +1,60 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+)
+
+// The functions below expose the tables used to validate Specs, for
+// instance to populate choices in user interfaces. They return copies,
+// so modifying the results does not affect validation.
+
+// ValidHookNames returns the sorted names of the recognized hooks.
+func ValidHookNames() []string {
+	return cdi.ValidHookNames()
+}
+
+// IsValidHookName returns true if the given name is a recognized hook name.
+func IsValidHookName(name string) bool {
+	return cdi.IsValidHookName(name)
+}
+
+// ValidDeviceTypes returns the sorted recognized device node types. The
+// type of a device node may also be left empty, to be filled in from the
+// host device.
+func ValidDeviceTypes() []string {
+	return cdi.ValidDeviceTypes()
+}
+
+// IsValidDeviceType returns true if the given device node type is valid.
+// The empty type is valid.
+func IsValidDeviceType(deviceType string) bool {
+	return cdi.IsValidDeviceType(deviceType)
+}
+
+// ValidDevicePermissions returns the valid device node permission
+// characters.
+func ValidDevicePermissions() string {
+	return cdi.ValidDevicePermissions()
+}
+
+// IsValidDevicePermissions returns true if the given device node
+// permissions are valid. Empty permissions are valid.
+func IsValidDevicePermissions(permissions string) bool {
+	return cdi.IsValidDevicePermissions(permissions)
+}
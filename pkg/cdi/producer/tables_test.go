@@ -0,0 +1,67 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestValidationTables(t *testing.T) {
+	hooks := ValidHookNames()
+	require.Equal(t, []string{
+		cdi.CreateContainerHook,
+		cdi.CreateRuntimeHook,
+		cdi.PoststartHook,
+		cdi.PoststopHook,
+		cdi.PrestartHook,
+		cdi.StartContainerHook,
+	}, hooks)
+	for _, name := range hooks {
+		require.True(t, IsValidHookName(name))
+		hook := &cdi.Hook{Hook: &cdispec.Hook{HookName: name, Path: "/bin/hook"}}
+		require.NoError(t, hook.Validate())
+	}
+	require.False(t, IsValidHookName("preStart"))
+
+	// modifying the returned table must not affect validation
+	hooks[0] = "invalid"
+	require.False(t, IsValidHookName("invalid"))
+	require.Contains(t, ValidHookNames(), cdi.CreateContainerHook)
+
+	types := ValidDeviceTypes()
+	require.Equal(t, []string{"b", "c", "p", "u"}, types)
+	for _, deviceType := range append(types, "") {
+		require.True(t, IsValidDeviceType(deviceType))
+		node := &cdi.DeviceNode{DeviceNode: &cdispec.DeviceNode{Path: "/dev/foo", Type: deviceType}}
+		require.NoError(t, node.Validate())
+	}
+	require.False(t, IsValidDeviceType("x"))
+
+	require.Equal(t, "rwm", ValidDevicePermissions())
+	for _, permissions := range []string{"", "r", "rw", "rwm", "mwr"} {
+		require.True(t, IsValidDevicePermissions(permissions))
+	}
+	for _, permissions := range []string{"x", "rwx", "R"} {
+		require.False(t, IsValidDevicePermissions(permissions))
+		node := &cdi.DeviceNode{DeviceNode: &cdispec.DeviceNode{Path: "/dev/foo", Permissions: permissions}}
+		require.Error(t, node.Validate())
+	}
+}
@@ -0,0 +1,108 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/parser"
+)
+
+// CleanupStaleTransientSpecs removes stale transient Spec files from the
+// given Spec directory. Transient Spec files are the ones named using
+// cdi.GenerateTransientSpecName() or cdi.GenerateNameForTransientSpec().
+// For every such file isAlive is called with the transient ID of the file
+// and the file is removed if isAlive returns false, together with any
+// signature file for it. Files which can't be parsed are left in place,
+// since their vendor and class, and therefore their transient ID, can't
+// be determined reliably. The transient ID passed to isAlive is the one
+// used in the file name, with any '/' replaced by '_'.
+//
+// CleanupStaleTransientSpecs returns the paths of the removed files. It
+// returns an error if the directory can't be read or if removing any of
+// the files fails, after trying to remove all stale files.
+func CleanupStaleTransientSpecs(dir string, isAlive func(transientID string) bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Spec directory %q: %w", dir, err)
+	}
+
+	var (
+		removed []string
+		errs    []error
+	)
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		ext := filepath.Ext(e.Name())
+		if ext != ".json" && ext != ".yaml" {
+			continue
+		}
+
+		path := filepath.Join(dir, e.Name())
+		transientID, ok := transientIDOf(path)
+		if !ok || isAlive(transientID) {
+			continue
+		}
+
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to remove stale transient Spec %q: %w", path, err))
+			continue
+		}
+		if err := os.Remove(cdi.SignatureFileForSpec(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to remove signature of stale transient Spec %q: %w", path, err))
+		}
+		removed = append(removed, path)
+	}
+
+	sort.Strings(removed)
+	return removed, errors.Join(errs...)
+}
+
+// transientIDOf returns the transient ID of the Spec file with the given
+// path, and whether the file is a transient Spec file.
+func transientIDOf(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	raw, err := cdi.ParseSpec(data)
+	if err != nil {
+		return "", false
+	}
+	vendor, class := parser.ParseQualifier(raw.Kind)
+	if vendor == "" || class == "" {
+		return "", false
+	}
+
+	base := filepath.Base(path)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	prefix := cdi.GenerateSpecName(vendor, class) + "_"
+	if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(name, prefix), true
+}
@@ -0,0 +1,72 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+)
+
+func TestCleanupStaleTransientSpecs(t *testing.T) {
+	const spec = `
+cdiVersion: "0.3.0"
+kind:       "vendor.com/gpu"
+devices:
+  - name: "gpu0"
+    containerEdits:
+      env:
+      - "GPU=0"
+`
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+		return path
+	}
+
+	var (
+		alive     = write(cdi.GenerateTransientSpecName("vendor.com", "gpu", "ctr1")+".yaml", spec)
+		stale     = write(cdi.GenerateTransientSpecName("vendor.com", "gpu", "ctr2")+".yaml", spec)
+		staleSig  = write(cdi.SignatureFileForSpec(filepath.Base(stale)), "signature")
+		regular   = write(cdi.GenerateSpecName("vendor.com", "gpu")+".yaml", spec)
+		otherKind = write(cdi.GenerateTransientSpecName("vendor.com", "nic", "ctr3")+".yaml", spec)
+		invalid   = write(cdi.GenerateTransientSpecName("vendor.com", "gpu", "ctr4")+".yaml", "invalid")
+		queried   []string
+	)
+
+	removed, err := CleanupStaleTransientSpecs(dir, func(transientID string) bool {
+		queried = append(queried, transientID)
+		return transientID == "ctr1"
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{stale}, removed)
+	require.ElementsMatch(t, []string{"ctr1", "ctr2"}, queried)
+
+	for _, path := range []string{alive, regular, otherKind, invalid} {
+		require.FileExists(t, path)
+	}
+	for _, path := range []string{stale, staleSig} {
+		require.NoFileExists(t, path)
+	}
+
+	_, err = CleanupStaleTransientSpecs(filepath.Join(dir, "missing"), func(string) bool { return false })
+	require.Error(t, err)
+}
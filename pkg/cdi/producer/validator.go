@@ -0,0 +1,178 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"errors"
+
+	"tags.cncf.io/container-device-interface/internal/validation"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// Validator validates CDI Specs generated by producers. Specs are checked
+// using the same validation consumers perform when they load a Spec (see
+// cdi.ValidateSpec). Additionally, by default the uniqueness of container
+// paths across the devices of a Spec is checked, which consumers only do
+// with strict Spec validation. Some checks can be disabled using
+// ValidatorOptions, but Specs which fail the disabled checks are then
+// rejected by consumers.
+type Validator struct {
+	allowEmptyDeviceEdits bool
+	skipAnnotations       bool
+	skipVersion           bool
+	skipContainerEdits    bool
+	skipContainerPaths    bool
+}
+
+// ValidatorOption is an option to change some aspect of a Validator.
+type ValidatorOption func(*Validator)
+
+// DefaultValidator is the validator with all checks enabled.
+var DefaultValidator = NewValidator()
+
+// NewValidator creates a Validator with the given options applied.
+func NewValidator(options ...ValidatorOption) *Validator {
+	v := &Validator{}
+	for _, o := range options {
+		o(v)
+	}
+	return v
+}
+
+// WithEmptyDeviceEdits returns an option to control whether devices with
+// empty container edits are accepted. By default they are rejected. This
+// option can be used to allow placeholder devices, for instance ones which
+// get their edits only at the Spec level. Unlike disabling container edits
+// validation, non-empty edits are still validated.
+func WithEmptyDeviceEdits(allow bool) ValidatorOption {
+	return func(v *Validator) {
+		v.allowEmptyDeviceEdits = allow
+	}
+}
+
+// WithAnnotationValidation returns an option to control whether Spec and
+// device annotations are validated. Annotations are validated by default.
+func WithAnnotationValidation(enable bool) ValidatorOption {
+	return func(v *Validator) {
+		v.skipAnnotations = !enable
+	}
+}
+
+// WithVersionValidation returns an option to control whether the Spec
+// version is checked against the features used by the Spec. The version
+// is validated by default.
+func WithVersionValidation(enable bool) ValidatorOption {
+	return func(v *Validator) {
+		v.skipVersion = !enable
+	}
+}
+
+// WithContainerEditsValidation returns an option to control whether Spec
+// and device container edits are validated. This includes checking that
+// every device has some container edits. Container edits are validated
+// by default. Use WithEmptyDeviceEdits to only accept placeholder devices.
+func WithContainerEditsValidation(enable bool) ValidatorOption {
+	return func(v *Validator) {
+		v.skipContainerEdits = !enable
+	}
+}
+
+// WithContainerPathValidation returns an option to control whether the
+// uniqueness of container paths across the devices of a Spec is checked.
+// Container paths are validated by default. Vendors which intentionally
+// inject the same path from multiple devices should prefer annotating
+// those paths using cdi.SharedContainerPathsAnnotation, since consumers
+// using strict validation perform this check, too.
+func WithContainerPathValidation(enable bool) ValidatorOption {
+	return func(v *Validator) {
+		v.skipContainerPaths = !enable
+	}
+}
+
+// Validate the given raw CDI Spec. The signature of this function is
+// compatible with cdi.WithSpecValidator().
+func (v *Validator) Validate(raw *cdispec.Spec) error {
+	if raw == nil {
+		return errors.New("invalid nil CDI Spec")
+	}
+	if v == nil {
+		v = DefaultValidator
+	}
+
+	spec := raw
+	if v.allowEmptyDeviceEdits || v.skipAnnotations || v.skipVersion || v.skipContainerEdits {
+		if v.skipContainerEdits && !v.skipVersion {
+			if err := cdispec.ValidateVersion(raw); err != nil {
+				return err
+			}
+		}
+		spec = v.withoutSkippedChecks(raw)
+	}
+	if err := cdi.ValidateSpec(spec); err != nil {
+		return err
+	}
+
+	if !v.skipContainerPaths {
+		if err := validation.ValidateContainerPaths(raw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// withoutSkippedChecks returns a copy of the Spec with the parts covered
+// by the disabled checks replaced by content which passes them.
+func (v *Validator) withoutSkippedChecks(raw *cdispec.Spec) *cdispec.Spec {
+	spec := *raw
+	if v.skipVersion {
+		spec.Version = cdispec.CurrentVersion
+	}
+	if v.skipAnnotations {
+		spec.Annotations = nil
+	}
+	if v.skipContainerEdits {
+		spec.ContainerEdits = cdispec.ContainerEdits{}
+	}
+
+	spec.Devices = make([]cdispec.Device, len(raw.Devices))
+	for i, d := range raw.Devices {
+		if v.skipAnnotations {
+			d.Annotations = nil
+		}
+		if v.skipContainerEdits || (v.allowEmptyDeviceEdits && isEmpty(&d.ContainerEdits)) {
+			d.ContainerEdits = cdispec.ContainerEdits{
+				Env: []string{"CDI_DEVICE=" + d.Name},
+			}
+		}
+		spec.Devices[i] = d
+	}
+
+	return &spec
+}
+
+// isEmpty returns true if the given container edits are empty.
+func isEmpty(e *cdispec.ContainerEdits) bool {
+	return len(e.Env) == 0 &&
+		len(e.DeviceNodes) == 0 &&
+		len(e.Hooks) == 0 &&
+		len(e.Mounts) == 0 &&
+		len(e.AdditionalGIDs) == 0 &&
+		e.IntelRdt == nil &&
+		e.Capabilities == nil
+}
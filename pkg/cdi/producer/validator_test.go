@@ -0,0 +1,333 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestValidator(t *testing.T) {
+	type testCase struct {
+		name    string
+		options []ValidatorOption
+		spec    *cdi.Spec
+		invalid bool
+	}
+	for _, tc := range []*testCase{
+		{
+			name: "valid spec",
+			spec: &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    "vendor.com/class",
+				Devices: []cdi.Device{
+					{
+						Name: "dev0",
+						ContainerEdits: cdi.ContainerEdits{
+							Env: []string{"FOO=BAR"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:    "nil spec",
+			invalid: true,
+		},
+		{
+			name: "empty device edits, rejected by default",
+			spec: &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    "vendor.com/class",
+				Devices: []cdi.Device{
+					{
+						Name: "placeholder",
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "empty device edits, allowed",
+			options: []ValidatorOption{
+				WithEmptyDeviceEdits(true),
+			},
+			spec: &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    "vendor.com/class",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"FOO=BAR"},
+				},
+				Devices: []cdi.Device{
+					{
+						Name: "placeholder",
+					},
+				},
+			},
+		},
+		{
+			name: "invalid device edits, rejected with empty device edits allowed",
+			options: []ValidatorOption{
+				WithEmptyDeviceEdits(true),
+			},
+			spec: &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    "vendor.com/class",
+				Devices: []cdi.Device{
+					{
+						Name: "placeholder",
+					},
+					{
+						Name: "dev0",
+						ContainerEdits: cdi.ContainerEdits{
+							Env: []string{"=BAR"},
+						},
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "group with unknown member, rejected like by consumers",
+			spec: &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    "vendor.com/class",
+				Devices: []cdi.Device{
+					{
+						Name: "dev0",
+						ContainerEdits: cdi.ContainerEdits{
+							Env: []string{"FOO=BAR"},
+						},
+					},
+				},
+				Groups: []cdi.DeviceGroup{
+					{
+						Name:    "all",
+						Devices: []string{"dev0", "dev1"},
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "invalid annotation, rejected by default",
+			spec: &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    "vendor.com/class",
+				Annotations: map[string]string{
+					"inv$alid": "value",
+				},
+				Devices: []cdi.Device{
+					{
+						Name: "dev0",
+						ContainerEdits: cdi.ContainerEdits{
+							Env: []string{"FOO=BAR"},
+						},
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "invalid annotation, annotation validation disabled",
+			options: []ValidatorOption{
+				WithAnnotationValidation(false),
+			},
+			spec: &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    "vendor.com/class",
+				Annotations: map[string]string{
+					"inv$alid": "value",
+				},
+				Devices: []cdi.Device{
+					{
+						Name: "dev0",
+						ContainerEdits: cdi.ContainerEdits{
+							Env: []string{"FOO=BAR"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "too old version, rejected by default",
+			spec: &cdi.Spec{
+				Version: "0.3.0",
+				Kind:    "vendor.com/class",
+				Devices: []cdi.Device{
+					{
+						Name: "dev0",
+						ContainerEdits: cdi.ContainerEdits{
+							AdditionalGIDs: []uint32{5},
+						},
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "too old version, version validation disabled",
+			options: []ValidatorOption{
+				WithVersionValidation(false),
+			},
+			spec: &cdi.Spec{
+				Version: "0.3.0",
+				Kind:    "vendor.com/class",
+				Devices: []cdi.Device{
+					{
+						Name: "dev0",
+						ContainerEdits: cdi.ContainerEdits{
+							AdditionalGIDs: []uint32{5},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid edits, rejected by default",
+			spec: &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    "vendor.com/class",
+				Devices: []cdi.Device{
+					{
+						Name: "dev0",
+						ContainerEdits: cdi.ContainerEdits{
+							Env: []string{"=BAR"},
+						},
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "invalid edits, edits validation disabled",
+			options: []ValidatorOption{
+				WithContainerEditsValidation(false),
+			},
+			spec: &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    "vendor.com/class",
+				Devices: []cdi.Device{
+					{
+						Name: "dev0",
+						ContainerEdits: cdi.ContainerEdits{
+							Env: []string{"=BAR"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "too old version for edits, edits validation disabled",
+			options: []ValidatorOption{
+				WithContainerEditsValidation(false),
+			},
+			spec: &cdi.Spec{
+				Version: "0.3.0",
+				Kind:    "vendor.com/class",
+				Devices: []cdi.Device{
+					{
+						Name: "dev0",
+						ContainerEdits: cdi.ContainerEdits{
+							AdditionalGIDs: []uint32{5},
+						},
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "duplicate container paths, rejected by default",
+			spec: &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    "vendor.com/class",
+				Devices: []cdi.Device{
+					{
+						Name: "dev0",
+						ContainerEdits: cdi.ContainerEdits{
+							Mounts: []*cdi.Mount{{HostPath: "/a", ContainerPath: "/shared"}},
+						},
+					},
+					{
+						Name: "dev1",
+						ContainerEdits: cdi.ContainerEdits{
+							Mounts: []*cdi.Mount{{HostPath: "/b", ContainerPath: "/shared"}},
+						},
+					},
+				},
+			},
+			invalid: true,
+		},
+		{
+			name: "duplicate container paths, path validation disabled",
+			options: []ValidatorOption{
+				WithContainerPathValidation(false),
+			},
+			spec: &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    "vendor.com/class",
+				Devices: []cdi.Device{
+					{
+						Name: "dev0",
+						ContainerEdits: cdi.ContainerEdits{
+							Mounts: []*cdi.Mount{{HostPath: "/a", ContainerPath: "/shared"}},
+						},
+					},
+					{
+						Name: "dev1",
+						ContainerEdits: cdi.ContainerEdits{
+							Mounts: []*cdi.Mount{{HostPath: "/b", ContainerPath: "/shared"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "duplicate devices, always rejected",
+			options: []ValidatorOption{
+				WithEmptyDeviceEdits(true),
+				WithAnnotationValidation(false),
+				WithVersionValidation(false),
+				WithContainerEditsValidation(false),
+			},
+			spec: &cdi.Spec{
+				Version: cdi.CurrentVersion,
+				Kind:    "vendor.com/class",
+				Devices: []cdi.Device{
+					{
+						Name: "dev0",
+					},
+					{
+						Name: "dev0",
+					},
+				},
+			},
+			invalid: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewValidator(tc.options...).Validate(tc.spec)
+			if tc.invalid {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
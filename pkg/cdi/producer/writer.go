@@ -0,0 +1,133 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// SpecWriter writes a Spec in canonical form, as formatted by Format(),
+// to any io.Writer. This allows producing Spec files without going through
+// the filesystem, for instance for packaging Specs.
+type SpecWriter struct {
+	spec *cdispec.Spec
+	opts FormatOptions
+}
+
+// NewSpecWriter creates a SpecWriter for the given Spec, formatting it
+// with the given options.
+func NewSpecWriter(spec *cdispec.Spec, opts FormatOptions) *SpecWriter {
+	return &SpecWriter{
+		spec: spec,
+		opts: opts,
+	}
+}
+
+// WriteTo writes the formatted Spec to the given writer. It implements
+// io.WriterTo.
+func (w *SpecWriter) WriteTo(out io.Writer) (int64, error) {
+	data, err := Format(w.spec, w.opts)
+	if err != nil {
+		return 0, err
+	}
+	n, err := out.Write(data)
+	if err != nil {
+		return int64(n), fmt.Errorf("failed to write CDI Spec: %w", err)
+	}
+	return int64(n), nil
+}
+
+// FileName returns the canonical name of the Spec file, the name generated
+// for the Spec by cdi.GenerateNameForSpec() with the extension of the
+// encoding of the Spec.
+func (w *SpecWriter) FileName() (string, error) {
+	if w.spec == nil {
+		return "", fmt.Errorf("can't name nil CDI Spec")
+	}
+	name, err := cdi.GenerateNameForSpec(w.spec)
+	if err != nil {
+		return "", err
+	}
+	if w.opts.Encoding == EncodingJSON {
+		return name + ".json", nil
+	}
+	return name + ".yaml", nil
+}
+
+// WriteArchive writes a tar archive with the given Specs to w. Every Spec
+// is formatted as YAML by Format() and stored in the root of the archive
+// under its canonical name, as returned by SpecWriter.FileName(), so the
+// archive can be extracted into a Spec directory as is. Files are stored
+// in sorted order with fixed ownership, permissions and modification time,
+// so the same Specs always result in the same archive. Specs with the same
+// canonical name are an error.
+func WriteArchive(w io.Writer, specs ...*cdispec.Spec) error {
+	files := map[string][]byte{}
+	for _, spec := range specs {
+		sw := NewSpecWriter(spec, FormatOptions{})
+		name, err := sw.FileName()
+		if err != nil {
+			return fmt.Errorf("failed to archive CDI Spec: %w", err)
+		}
+		if _, ok := files[name]; ok {
+			return fmt.Errorf("failed to archive CDI Spec: duplicate Spec file %q", name)
+		}
+		var buf bytes.Buffer
+		if _, err := sw.WriteTo(&buf); err != nil {
+			return fmt.Errorf("failed to archive CDI Spec %q: %w", name, err)
+		}
+		files[name] = buf.Bytes()
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		data := files[name]
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(data)),
+			ModTime:  time.Unix(0, 0),
+			Format:   tar.FormatUSTAR,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write CDI Spec archive: %w", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("failed to write CDI Spec archive: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write CDI Spec archive: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package producer
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestSpecWriter(t *testing.T) {
+	spec := &cdispec.Spec{
+		Version: "v0.3",
+		Kind:    "vendor.com/gpu",
+		Devices: []cdispec.Device{
+			{
+				Name:           "gpu0",
+				ContainerEdits: cdispec.ContainerEdits{Env: []string{"GPU=0"}},
+			},
+		},
+	}
+
+	for _, opts := range []FormatOptions{{}, {Encoding: EncodingJSON}} {
+		w := NewSpecWriter(spec, opts)
+
+		var buf bytes.Buffer
+		n, err := w.WriteTo(&buf)
+		require.NoError(t, err)
+		require.Equal(t, int64(buf.Len()), n)
+
+		expected, err := Format(spec, opts)
+		require.NoError(t, err)
+		require.Equal(t, expected, buf.Bytes())
+
+		name, err := w.FileName()
+		require.NoError(t, err)
+		if opts.Encoding == EncodingJSON {
+			require.Equal(t, "vendor.com-gpu.json", name)
+		} else {
+			require.Equal(t, "vendor.com-gpu.yaml", name)
+		}
+	}
+
+	_, err := NewSpecWriter(nil, FormatOptions{}).WriteTo(io.Discard)
+	require.Error(t, err)
+	_, err = NewSpecWriter(&cdispec.Spec{Kind: "invalid"}, FormatOptions{}).FileName()
+	require.Error(t, err)
+}
+
+func TestWriteArchive(t *testing.T) {
+	mkSpec := func(kind string) *cdispec.Spec {
+		return &cdispec.Spec{
+			Version: "0.3.0",
+			Kind:    kind,
+			Devices: []cdispec.Device{
+				{
+					Name:           "dev0",
+					ContainerEdits: cdispec.ContainerEdits{Env: []string{"KIND=" + kind}},
+				},
+			},
+		}
+	}
+	specs := []*cdispec.Spec{mkSpec("vendor2.com/nic"), mkSpec("vendor1.com/gpu")}
+
+	var archive bytes.Buffer
+	require.NoError(t, WriteArchive(&archive, specs...))
+
+	var again bytes.Buffer
+	require.NoError(t, WriteArchive(&again, specs[1], specs[0]))
+	require.Equal(t, archive.Bytes(), again.Bytes())
+
+	var (
+		tr    = tar.NewReader(&archive)
+		names []string
+	)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, int64(0o644), hdr.Mode)
+		names = append(names, hdr.Name)
+
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		raw, err := cdi.ParseSpec(data)
+		require.NoError(t, err)
+		name, err := cdi.GenerateNameForSpec(raw)
+		require.NoError(t, err)
+		require.Equal(t, name+".yaml", hdr.Name)
+	}
+	require.Equal(t, []string{"vendor1.com-gpu.yaml", "vendor2.com-nic.yaml"}, names)
+
+	err := WriteArchive(io.Discard, mkSpec("vendor1.com/gpu"), mkSpec("vendor1.com/gpu"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "duplicate Spec file")
+}
@@ -0,0 +1,42 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// RenderDevice returns an OCI Spec fragment with the edits injecting the
+// given device, or device group, into a container. The fragment is the
+// result of injecting the device into an empty OCI Spec, including the
+// edits common to all devices of its Spec. It is meant for previewing,
+// documenting, and golden-file testing the effect of a device.
+//
+// Rendering a device is subject to the same Cache configuration as
+// injection. In particular device node details missing from the Spec
+// are looked up from the host, unless the Cache is configured with the
+// NoDeviceInfoResolver, in which case rendering is independent of the
+// host.
+func (c *Cache) RenderDevice(device string) (*oci.Spec, error) {
+	ociSpec := &oci.Spec{}
+	if _, err := c.InjectDevices(ociSpec, device); err != nil {
+		return nil, fmt.Errorf("failed to render CDI device %q: %w", device, err)
+	}
+	return ociSpec, nil
+}
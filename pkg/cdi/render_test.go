@@ -0,0 +1,83 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestRenderDevice(t *testing.T) {
+	cache := newCache(
+		WithSpecDirs(),
+		WithAutoRefresh(false),
+		WithDeviceInfoResolver(NoDeviceInfoResolver),
+	)
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version: "0.3.0",
+		Kind:    "vendor.com/gpu",
+		ContainerEdits: cdi.ContainerEdits{
+			Env: []string{"VENDOR=1"},
+		},
+		Devices: []cdi.Device{
+			{
+				Name: "gpu0",
+				ContainerEdits: cdi.ContainerEdits{
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/gpu0", Type: "c", Major: 240, Minor: 0},
+					},
+				},
+			},
+			{
+				Name: "gpu1",
+				ContainerEdits: cdi.ContainerEdits{
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/gpu1"},
+					},
+				},
+			},
+		},
+	}, 0))
+
+	ociSpec, err := cache.RenderDevice("vendor.com/gpu=gpu0")
+	require.NoError(t, err)
+
+	major, minor := int64(240), int64(0)
+	require.Equal(t, &oci.Spec{
+		Process: &oci.Process{
+			Env: []string{"VENDOR=1"},
+		},
+		Linux: &oci.Linux{
+			Devices: []oci.LinuxDevice{
+				{Path: "/dev/gpu0", Type: "c", Major: 240, Minor: 0},
+			},
+			Resources: &oci.LinuxResources{
+				Devices: []oci.LinuxDeviceCgroup{
+					{Allow: true, Type: "c", Major: &major, Minor: &minor, Access: "rwm"},
+				},
+			},
+		},
+	}, ociSpec)
+
+	_, err = cache.RenderDevice("vendor.com/gpu=gpu1")
+	require.Error(t, err)
+	_, err = cache.RenderDevice("vendor.com/gpu=gpu2")
+	require.Error(t, err)
+}
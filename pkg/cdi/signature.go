@@ -0,0 +1,129 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+const (
+	// SignatureExt is the file name extension of detached Spec signatures.
+	// The signature of a Spec file is stored in a file with the same name
+	// as the Spec file with this extension appended.
+	SignatureExt = ".sig"
+)
+
+var (
+	// ErrNoSignature is returned when a Spec file has no signature.
+	ErrNoSignature = errors.New("no CDI Spec signature")
+	// ErrInvalidSignature is returned when a Spec signature fails verification.
+	ErrInvalidSignature = errors.New("invalid CDI Spec signature")
+)
+
+// WithSignatureVerification returns an option to enable verification of
+// Spec file signatures. Once enabled, Spec files which do not have a
+// detached signature, or whose signature cannot be verified by any of
+// the given ed25519 public keys, are rejected by the Cache with an error.
+//
+// Signatures are expected to be stored in a file next to the Spec file,
+// named by appending SignatureExt to the name of the Spec file. These can
+// be created using SignSpecFile(). In-memory Specs (see AddSpec()) are not
+// subject to signature verification. Snapshots with Specs from Spec files
+// can't be restored once verification is enabled (see RestoreSnapshot()).
+//
+// Calling this option without keys disables signature verification.
+func WithSignatureVerification(keys ...ed25519.PublicKey) Option {
+	return func(c *Cache) {
+		c.signatureKeys = keys
+	}
+}
+
+// SignatureFileForSpec returns the path of the detached signature file for
+// the Spec file with the given path.
+func SignatureFileForSpec(path string) string {
+	return path + SignatureExt
+}
+
+// SignSpecFile creates a detached signature for the Spec file with the
+// given path, using the given ed25519 private key. The signature is
+// written to the file returned by SignatureFileForSpec().
+func SignSpecFile(path string, key ed25519.PrivateKey) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CDI Spec %q: %w", path, err)
+	}
+
+	sig := base64.StdEncoding.EncodeToString(SignSpecData(data, key))
+	err = os.WriteFile(SignatureFileForSpec(path), []byte(sig+"\n"), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write CDI Spec signature: %w", err)
+	}
+
+	return nil
+}
+
+// SignSpecData returns the signature of the given Spec file data.
+func SignSpecData(data []byte, key ed25519.PrivateKey) []byte {
+	return ed25519.Sign(key, data)
+}
+
+// VerifySpecData verifies Spec file data against a base64-encoded detached
+// signature, using the given set of ed25519 public keys. It returns nil if
+// any of the keys verifies the signature.
+func VerifySpecData(data, signature []byte, keys ...ed25519.PublicKey) error {
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	for _, key := range keys {
+		if len(key) == ed25519.PublicKeySize && ed25519.Verify(key, data, sig) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// verifySpecFile verifies the given data read from the named Spec file
+// against its detached signature. If fsys is nil, the name is the path of
+// the Spec file.
+func verifySpecFile(fsys fs.FS, name string, data []byte, keys []ed25519.PublicKey) error {
+	var (
+		signature []byte
+		err       error
+	)
+	if fsys == nil {
+		signature, err = os.ReadFile(SignatureFileForSpec(name))
+	} else {
+		signature, err = fs.ReadFile(fsys, SignatureFileForSpec(name))
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNoSignature
+		}
+		return fmt.Errorf("failed to read CDI Spec signature: %w", err)
+	}
+
+	return VerifySpecData(data, signature, keys...)
+}
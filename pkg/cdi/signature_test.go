@@ -0,0 +1,116 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifySpecData(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	data := []byte("cdiVersion: 0.3.0\n")
+	sig := []byte(base64.StdEncoding.EncodeToString(SignSpecData(data, priv)) + "\n")
+
+	require.NoError(t, VerifySpecData(data, sig, pub))
+	require.NoError(t, VerifySpecData(data, sig, otherPub, pub))
+	require.ErrorIs(t, VerifySpecData(data, sig, otherPub), ErrInvalidSignature)
+	require.ErrorIs(t, VerifySpecData(data, sig), ErrInvalidSignature)
+	require.ErrorIs(t, VerifySpecData([]byte("tampered"), sig, pub), ErrInvalidSignature)
+	require.ErrorIs(t, VerifySpecData(data, []byte("garbage!"), pub), ErrInvalidSignature)
+}
+
+func TestCacheSignatureVerification(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, otherPriv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	etc := map[string]string{
+		"signed.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR1_DEV1=1"
+`,
+		"unsigned.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor2.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR2_DEV1=1"
+`,
+		"foreign.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor3.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR3_DEV1=1"
+`,
+	}
+
+	dir, err := createSpecDirs(t, etc, nil)
+	require.NoError(t, err)
+	etcDir := filepath.Join(dir, "etc")
+
+	require.NoError(t, SignSpecFile(filepath.Join(etcDir, "signed.yaml"), priv))
+	require.NoError(t, SignSpecFile(filepath.Join(etcDir, "foreign.yaml"), otherPriv))
+
+	cache := newCache(
+		WithSpecDirs(etcDir),
+		WithAutoRefresh(false),
+	)
+	require.Equal(t, []string{
+		"vendor1.com/device=dev1",
+		"vendor2.com/device=dev1",
+		"vendor3.com/device=dev1",
+	}, cache.ListDevices())
+
+	require.NoError(t, cache.Configure(WithSignatureVerification(pub)))
+	require.Equal(t, []string{"vendor1.com/device=dev1"}, cache.ListDevices())
+
+	errors := cache.GetErrors()
+	require.Len(t, errors, 2)
+	require.ErrorIs(t, errors[filepath.Join(etcDir, "unsigned.yaml")][0], ErrNoSignature)
+	require.ErrorIs(t, errors[filepath.Join(etcDir, "foreign.yaml")][0], ErrInvalidSignature)
+
+	// tamper with the signed Spec
+	path := filepath.Join(etcDir, "signed.yaml")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, append(data, []byte("      - \"INJECTED=1\"\n")...), 0o644))
+
+	require.Error(t, cache.Refresh())
+	require.Empty(t, cache.ListDevices())
+	require.ErrorIs(t, cache.GetErrors()[path][0], ErrInvalidSignature)
+}
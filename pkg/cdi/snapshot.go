@@ -0,0 +1,248 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// CacheStateVersion is the version of the CacheState encoding.
+	CacheStateVersion = "v1"
+)
+
+// CacheState is a snapshot of the state of a Cache. It has a stable JSON
+// encoding, with all lists sorted, which allows checkpointing the state
+// of a Cache, for instance across restarts, or including it in support
+// bundles.
+type CacheState struct {
+	// Version is the version of the encoding, CacheStateVersion.
+	Version string `json:"version"`
+	// SpecDirs are the Spec directories of the Cache, in priority order.
+	SpecDirs []string `json:"specDirs"`
+	// Specs are the loaded Specs, sorted by path.
+	Specs []*SpecState `json:"specs"`
+	// Devices is the device index, sorted by device name.
+	Devices []*DeviceState `json:"devices"`
+	// Errors are the errors of the Cache by Spec file path.
+	Errors map[string][]string `json:"errors,omitempty"`
+	// DirErrors are the errors of the Cache by Spec directory.
+	DirErrors map[string]string `json:"dirErrors,omitempty"`
+}
+
+// SpecState is the state of a Spec in a CacheState.
+type SpecState struct {
+	// Path is the path of the Spec. In-memory Specs have pseudo-paths
+	// starting with "memory:".
+	Path string `json:"path"`
+	// Document is the index of the Spec document within its Spec file.
+	Document int `json:"document,omitempty"`
+	// Priority is the priority of the Spec.
+	Priority int `json:"priority"`
+	// Digest is the digest of the Spec content.
+	Digest string `json:"digest,omitempty"`
+	// ModTime is the modification time of the Spec file when it was read.
+	ModTime *time.Time `json:"modTime,omitempty"`
+	// Spec is the Spec data.
+	Spec *cdi.Spec `json:"spec"`
+}
+
+// DeviceState is the state of a resolved device in a CacheState.
+type DeviceState struct {
+	// Name is the qualified name of the device.
+	Name string `json:"name"`
+	// Spec is the path of the Spec the device is resolved from.
+	Spec string `json:"spec"`
+	// Priority is the priority of the device, set by PriorityAnnotation.
+	Priority int `json:"priority"`
+}
+
+// Snapshot returns a snapshot of the current state of the Cache. The
+// snapshot shares no data with the Cache.
+func (c *Cache) Snapshot() (*CacheState, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	state := &CacheState{
+		Version:  CacheStateVersion,
+		SpecDirs: append([]string{}, c.specDirs...),
+		Specs:    []*SpecState{},
+		Devices:  []*DeviceState{},
+	}
+
+	for _, specs := range c.specs {
+		for _, spec := range specs {
+			raw, err := copySpec(spec.Spec)
+			if err != nil {
+				return nil, fmt.Errorf("failed to snapshot Spec %q: %w", spec.GetPath(), err)
+			}
+			s := &SpecState{
+				Path:     spec.GetPath(),
+				Document: spec.GetDocument(),
+				Priority: spec.GetPriority(),
+				Digest:   spec.GetDigest(),
+				Spec:     raw,
+			}
+			if modTime := spec.GetModTime(); !modTime.IsZero() {
+				s.ModTime = &modTime
+			}
+			state.Specs = append(state.Specs, s)
+		}
+	}
+	sort.Slice(state.Specs, func(i, j int) bool {
+		if state.Specs[i].Path != state.Specs[j].Path {
+			return state.Specs[i].Path < state.Specs[j].Path
+		}
+		return state.Specs[i].Document < state.Specs[j].Document
+	})
+
+	for _, dev := range c.devices {
+		state.Devices = append(state.Devices, &DeviceState{
+			Name:     dev.GetQualifiedName(),
+			Spec:     dev.GetSpec().GetPath(),
+			Priority: dev.GetPriority(),
+		})
+	}
+	sort.Slice(state.Devices, func(i, j int) bool {
+		return state.Devices[i].Name < state.Devices[j].Name
+	})
+
+	for path, errs := range c.errors {
+		for _, err := range errs {
+			if state.Errors == nil {
+				state.Errors = map[string][]string{}
+			}
+			state.Errors[path] = append(state.Errors[path], err.Error())
+		}
+	}
+	for dir, err := range c.dirErrors {
+		if state.DirErrors == nil {
+			state.DirErrors = map[string]string{}
+		}
+		state.DirErrors[dir] = err.Error()
+	}
+
+	return state, nil
+}
+
+// RestoreSnapshot restores the Specs and errors of a snapshot taken with
+// Snapshot(). The devices are resolved from the restored Specs just like
+// during a refresh, using the current configuration of the Cache. The
+// restored state is replaced by the next full refresh of the Cache, or,
+// for individual Spec files, by any targeted refresh of those files.
+//
+// The signatures of Spec files can't be verified for restored Specs. If
+// signature verification is enabled (see WithSignatureVerification()),
+// snapshots with Specs from Spec files are rejected.
+func (c *Cache) RestoreSnapshot(state *CacheState) error {
+	if state == nil {
+		return errors.New("can't restore nil Cache state")
+	}
+	if state.Version != CacheStateVersion {
+		return fmt.Errorf("unsupported Cache state version %q", state.Version)
+	}
+
+	c.RLock()
+	verify := len(c.signatureKeys) > 0
+	c.RUnlock()
+
+	var (
+		files    []*specFile
+		byPath   = map[string]*specFile{}
+		memSpecs = map[string]*Spec{}
+		restored = map[string]struct{}{}
+	)
+
+	for _, s := range state.Specs {
+		if verify && !strings.HasPrefix(s.Path, memorySpecPrefix) {
+			return fmt.Errorf("failed to restore Spec %q: signature verification enabled", s.Path)
+		}
+		raw, err := copySpec(s.Spec)
+		if err != nil {
+			return fmt.Errorf("failed to restore Spec %q: %w", s.Path, err)
+		}
+		spec, err := newSpecWith(raw, s.Path, s.Priority, c.validateSpec)
+		if err != nil {
+			return fmt.Errorf("failed to restore Spec %q: %w", s.Path, err)
+		}
+		spec.path = s.Path
+		spec.digest = s.Digest
+		if s.ModTime != nil {
+			spec.modTime = *s.ModTime
+		}
+		spec.document = s.Document
+		restored[s.Path] = struct{}{}
+
+		if name, ok := strings.CutPrefix(s.Path, memorySpecPrefix); ok {
+			memSpecs[name] = spec
+			continue
+		}
+		f, ok := byPath[s.Path]
+		if !ok {
+			f = &specFile{
+				path:     s.Path,
+				priority: s.Priority,
+			}
+			byPath[s.Path] = f
+			files = append(files, f)
+		}
+		f.specs = append(f.specs, spec)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].priority < files[j].priority
+	})
+	for _, f := range files {
+		c.internSpecs(f.specs)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.memSpecs = memSpecs
+	c.specFiles = map[string]*specFile{}
+	for _, f := range files {
+		c.specFiles[f.path] = f
+	}
+
+	_ = c.refreshSpecFiles(files) // errors are restored from the snapshot
+
+	// restore errors of Spec files which could not be loaded
+	for path, msgs := range state.Errors {
+		if _, ok := restored[path]; ok {
+			continue
+		}
+		for _, msg := range msgs {
+			c.errors[path] = append(c.errors[path], errors.New(msg))
+		}
+	}
+
+	return nil
+}
+
+// copySpec returns a deep copy of the given Spec data.
+func copySpec(raw *cdi.Spec) (*cdi.Spec, error) {
+	if raw == nil {
+		return nil, errors.New("no Spec data")
+	}
+	return raw.DeepCopy(), nil
+}
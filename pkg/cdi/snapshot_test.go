@@ -0,0 +1,169 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestCacheSnapshot(t *testing.T) {
+	var (
+		etc = map[string]string{
+			"vendor1.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR1_DEV1=1"
+  - name: "dev2"
+    containerEdits:
+      env:
+      - "VENDOR1_DEV2=1"
+`,
+		}
+		run = map[string]string{
+			"vendor1.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev2"
+    containerEdits:
+      env:
+      - "VENDOR1_DEV2=2"
+`,
+		}
+	)
+
+	dir, err := createSpecDirs(t, etc, run)
+	require.NoError(t, err)
+
+	cache, err := NewCache(
+		WithSpecDirs(filepath.Join(dir, "etc"), filepath.Join(dir, "run")),
+		WithAutoRefresh(false),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version: "0.3.0",
+		Kind:    "vendor3.com/device",
+		Devices: []cdi.Device{
+			{
+				Name: "dev1",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"VENDOR3_DEV1=1"},
+				},
+			},
+		},
+	}, 5))
+
+	require.NoError(t, updateSpecDirs(dir, map[string]string{
+		"broken.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor2.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      unknownField: 1
+`,
+	}, nil))
+	require.Error(t, cache.Refresh())
+
+	state, err := cache.Snapshot()
+	require.NoError(t, err)
+	require.Equal(t, CacheStateVersion, state.Version)
+	require.Len(t, state.Specs, 3)
+	require.Equal(t,
+		[]*DeviceState{
+			{Name: "vendor1.com/device=dev1", Spec: filepath.Join(dir, "etc", "vendor1.yaml")},
+			{Name: "vendor1.com/device=dev2", Spec: filepath.Join(dir, "run", "vendor1.yaml")},
+			{Name: "vendor3.com/device=dev1", Spec: "memory:vendor3.com-device"},
+		},
+		state.Devices,
+	)
+	require.Contains(t, state.Errors, filepath.Join(dir, "etc", "broken.yaml"))
+
+	// the encoding is stable
+	data, err := json.Marshal(state)
+	require.NoError(t, err)
+	again, err := cache.Snapshot()
+	require.NoError(t, err)
+	dataAgain, err := json.Marshal(again)
+	require.NoError(t, err)
+	require.Equal(t, string(data), string(dataAgain))
+
+	// restore into a cache without any Spec directories
+	decoded := &CacheState{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+
+	restored, err := NewCache(WithSpecDirs(), WithAutoRefresh(false))
+	require.NoError(t, err)
+	require.NoError(t, restored.RestoreSnapshot(decoded))
+
+	require.Equal(t, cache.ListDevices(), restored.ListDevices())
+	require.Equal(t, cache.ListVendors(), restored.ListVendors())
+	dev := restored.GetDevice("vendor1.com/device=dev2")
+	require.NotNil(t, dev)
+	require.Equal(t, []string{"VENDOR1_DEV2=2"}, dev.ContainerEdits.Env)
+	require.Equal(t, filepath.Join(dir, "run", "vendor1.yaml"), dev.GetSpec().GetPath())
+	require.Equal(t, 1, dev.GetSpec().GetPriority())
+	modTime := cache.GetDevice("vendor1.com/device=dev2").GetSpec().GetModTime()
+	require.False(t, modTime.IsZero())
+	require.True(t, modTime.Equal(dev.GetSpec().GetModTime()))
+	require.Contains(t, restored.GetErrors(), filepath.Join(dir, "etc", "broken.yaml"))
+
+	state2, err := restored.Snapshot()
+	require.NoError(t, err)
+	state2.SpecDirs = state.SpecDirs
+	data2, err := json.Marshal(state2)
+	require.NoError(t, err)
+	require.Equal(t, string(data), string(data2))
+
+	// restored in-memory Specs can be removed
+	require.NoError(t, restored.RemoveSpec("vendor3.com-device"))
+	require.Nil(t, restored.GetDevice("vendor3.com/device=dev1"))
+
+	// Specs from Spec files can't be restored with signature verification
+	pub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	verified, err := NewCache(WithSpecDirs(), WithAutoRefresh(false), WithSignatureVerification(pub))
+	require.NoError(t, err)
+	err = verified.RestoreSnapshot(decoded)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "signature verification enabled")
+	require.Empty(t, verified.ListDevices())
+
+	memOnly := &CacheState{Version: CacheStateVersion}
+	for _, s := range decoded.Specs {
+		if strings.HasPrefix(s.Path, memorySpecPrefix) {
+			memOnly.Specs = append(memOnly.Specs, s)
+		}
+	}
+	require.NoError(t, verified.RestoreSnapshot(memOnly))
+	require.Equal(t, []string{"vendor3.com/device=dev1"}, verified.ListDevices())
+
+	require.Error(t, restored.RestoreSnapshot(nil))
+	require.Error(t, restored.RestoreSnapshot(&CacheState{Version: "v0"}))
+}
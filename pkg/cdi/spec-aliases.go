@@ -0,0 +1,104 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"os"
+	"sort"
+)
+
+// SpecAlias describes a Spec file which the Cache ignores because it is
+// a duplicate of another Spec file. This happens when the same Spec is
+// visible through several paths, for instance through symlinks, hard
+// links, symlinked Spec directories, or bind mounts, and also when two
+// Spec files have identical content.
+type SpecAlias struct {
+	// Path is the path of the ignored Spec file.
+	Path string
+	// AliasOf is the path of the Spec file used instead.
+	AliasOf string
+	// SameFile is true if both paths refer to the same file.
+	SameFile bool
+}
+
+// GetSpecAliases returns the Spec files ignored during the last refresh
+// because they were duplicates of other Spec files, sorted by path. Of
+// the duplicates, the Spec file with the highest priority is used. If
+// the priorities are equal, the first file found by the scan is used.
+func (c *Cache) GetSpecAliases() []*SpecAlias {
+	c.RLock()
+	defer c.RUnlock()
+
+	aliases := make([]*SpecAlias, 0, len(c.aliases))
+	for _, a := range c.aliases {
+		copied := *a
+		aliases = append(aliases, &copied)
+	}
+	return aliases
+}
+
+// dedupSpecs filters duplicate Specs, identified by their digest, from
+// the given Specs. It returns the remaining Specs in their original order
+// and the aliases describing the removed duplicates.
+func dedupSpecs(specs []*Spec) ([]*Spec, []*SpecAlias) {
+	var (
+		selected = map[string]*Spec{}
+		unique   []*Spec
+		aliases  []*SpecAlias
+	)
+
+	for _, spec := range specs {
+		if spec.digest == "" {
+			continue
+		}
+		if other, ok := selected[spec.digest]; !ok || spec.GetPriority() > other.GetPriority() {
+			selected[spec.digest] = spec
+		}
+	}
+
+	for _, spec := range specs {
+		other, ok := selected[spec.digest]
+		if !ok || other == spec {
+			unique = append(unique, spec)
+			continue
+		}
+		aliases = append(aliases, &SpecAlias{
+			Path:     spec.GetPath(),
+			AliasOf:  other.GetPath(),
+			SameFile: isSameFile(spec.GetPath(), other.GetPath()),
+		})
+	}
+
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].Path < aliases[j].Path
+	})
+
+	return unique, aliases
+}
+
+// isSameFile returns true if the given paths refer to the same file.
+func isSameFile(path1, path2 string) bool {
+	info1, err := os.Stat(path1)
+	if err != nil {
+		return false
+	}
+	info2, err := os.Stat(path2)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(info1, info2)
+}
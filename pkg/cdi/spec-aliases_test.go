@@ -0,0 +1,137 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecAliases(t *testing.T) {
+	const vendor1 = `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR1_DEV1=1"
+`
+
+	type testCase struct {
+		name    string
+		setup   func(etc, run string) error
+		device  string
+		aliases func(etc, run string) []*SpecAlias
+	}
+	for _, tc := range []*testCase{
+		{
+			name: "symlinked Spec directory",
+			setup: func(etc, run string) error {
+				return os.Symlink(etc, run)
+			},
+			device: "run/vendor1.yaml",
+			aliases: func(etc, run string) []*SpecAlias {
+				return []*SpecAlias{
+					{
+						Path:     filepath.Join(etc, "vendor1.yaml"),
+						AliasOf:  filepath.Join(run, "vendor1.yaml"),
+						SameFile: true,
+					},
+				}
+			},
+		},
+		{
+			name: "symlinked Spec file",
+			setup: func(etc, run string) error {
+				if err := os.MkdirAll(run, 0o755); err != nil {
+					return err
+				}
+				return os.Symlink(filepath.Join(etc, "vendor1.yaml"), filepath.Join(run, "vendor1.yaml"))
+			},
+			device: "run/vendor1.yaml",
+			aliases: func(etc, run string) []*SpecAlias {
+				return []*SpecAlias{
+					{
+						Path:     filepath.Join(etc, "vendor1.yaml"),
+						AliasOf:  filepath.Join(run, "vendor1.yaml"),
+						SameFile: true,
+					},
+				}
+			},
+		},
+		{
+			name: "hard linked Spec file in the same directory",
+			setup: func(etc, _ string) error {
+				return os.Link(filepath.Join(etc, "vendor1.yaml"), filepath.Join(etc, "vendor1-link.yaml"))
+			},
+			device: "etc/vendor1-link.yaml",
+			aliases: func(etc, _ string) []*SpecAlias {
+				return []*SpecAlias{
+					{
+						Path:     filepath.Join(etc, "vendor1.yaml"),
+						AliasOf:  filepath.Join(etc, "vendor1-link.yaml"),
+						SameFile: true,
+					},
+				}
+			},
+		},
+		{
+			name: "identical Spec file copy",
+			setup: func(_, run string) error {
+				if err := os.MkdirAll(run, 0o755); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(run, "copy.yaml"), []byte(vendor1), 0o644)
+			},
+			device: "run/copy.yaml",
+			aliases: func(etc, run string) []*SpecAlias {
+				return []*SpecAlias{
+					{
+						Path:    filepath.Join(etc, "vendor1.yaml"),
+						AliasOf: filepath.Join(run, "copy.yaml"),
+					},
+				}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			etc, run := filepath.Join(dir, "etc"), filepath.Join(dir, "run")
+			require.NoError(t, os.MkdirAll(etc, 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(etc, "vendor1.yaml"), []byte(vendor1), 0o644))
+			if err := tc.setup(etc, run); err != nil {
+				t.Skipf("failed to set up Spec files: %v", err)
+			}
+
+			cache := newCache(
+				WithSpecDirs(etc, run),
+				WithAutoRefresh(false),
+			)
+			require.Empty(t, cache.GetErrors())
+			require.Equal(t, []string{"vendor1.com/device=dev1"}, cache.ListDevices())
+
+			dev := cache.GetDevice("vendor1.com/device=dev1")
+			require.NotNil(t, dev)
+			require.Equal(t, filepath.Join(dir, tc.device), dev.GetSpec().GetPath())
+			require.Equal(t, tc.aliases(etc, run), cache.GetSpecAliases())
+		})
+	}
+}
@@ -0,0 +1,420 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AnchorPolicy selects how YAML anchors, aliases and merge keys in Spec
+// files are handled. Aliases are always expanded when Spec data is parsed,
+// which makes errors in expanded content hard to locate.
+type AnchorPolicy int
+
+const (
+	// AnchorsExpand silently expands aliases. This is the default.
+	AnchorsExpand AnchorPolicy = iota
+	// AnchorsTrack expands aliases, tracking the expansions. Errors in
+	// Specs are reported as *SpecSourceError with their location in the
+	// Spec document and, if the offending content was expanded from an
+	// alias, the alias and its anchor.
+	AnchorsTrack
+	// AnchorsReject rejects Specs which use anchors, aliases or merge
+	// keys, for instance so that every device can be audited in isolation.
+	AnchorsReject
+)
+
+// maxAliasDepth limits the nesting of aliases followed when tracking
+// expansions.
+const maxAliasDepth = 64
+
+// String returns the name of the AnchorPolicy.
+func (p AnchorPolicy) String() string {
+	switch p {
+	case AnchorsExpand:
+		return "expand"
+	case AnchorsTrack:
+		return "track"
+	case AnchorsReject:
+		return "reject"
+	}
+	return fmt.Sprintf("AnchorPolicy(%d)", int(p))
+}
+
+// ParseAnchorPolicy parses the name of an AnchorPolicy.
+func ParseAnchorPolicy(name string) (AnchorPolicy, error) {
+	for _, p := range []AnchorPolicy{AnchorsExpand, AnchorsTrack, AnchorsReject} {
+		if name == p.String() {
+			return p, nil
+		}
+	}
+	return AnchorsExpand, fmt.Errorf("invalid anchor policy %q", name)
+}
+
+// WithAnchorPolicy returns an option to set how the Cache handles YAML
+// anchors, aliases and merge keys in Spec files. Unknown policies are
+// ignored and AnchorsExpand is used instead.
+func WithAnchorPolicy(p AnchorPolicy) Option {
+	return func(c *Cache) {
+		switch p {
+		case AnchorsTrack, AnchorsReject:
+			c.anchorPolicy = p
+		default:
+			c.anchorPolicy = AnchorsExpand
+		}
+	}
+}
+
+// AnchorExpansion describes the expansion of a YAML alias in Spec data.
+// Lines and columns are counted from 1 at the start of the Spec document.
+type AnchorExpansion struct {
+	// Anchor is the name of the expanded anchor.
+	Anchor string
+	// Path is the Spec field the alias expands to, for instance
+	// "devices[1].containerEdits".
+	Path string
+	// Line and Column are the location of the alias.
+	Line, Column int
+	// AnchorLine and AnchorColumn are the location of the anchor.
+	AnchorLine, AnchorColumn int
+	// Merge is true if the alias is merged into a mapping using the
+	// "<<" merge key.
+	Merge bool
+}
+
+// SpecSourceError is an error in Spec data, located in the Spec source.
+type SpecSourceError struct {
+	// Path is the offending Spec field, for instance "devices[1]".
+	Path string
+	// Line and Column are the location of the field in the Spec document.
+	// For expanded content this is the location within the anchor.
+	Line, Column int
+	// Expansion is the alias expansion the field is part of, if any.
+	Expansion *AnchorExpansion
+	// Err is the original error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *SpecSourceError) Error() string {
+	if x := e.Expansion; x != nil {
+		return fmt.Sprintf("line %d, column %d: %s, expanded from alias *%s at line %d, column %d: %v",
+			e.Line, e.Column, e.Path, x.Anchor, x.Line, x.Column, e.Err)
+	}
+	return fmt.Sprintf("line %d, column %d: %s: %v", e.Line, e.Column, e.Path, e.Err)
+}
+
+// Unwrap returns the original error.
+func (e *SpecSourceError) Unwrap() error {
+	return e.Err
+}
+
+// FindSpecAnchors returns the alias expansions in the given Spec data,
+// in the order they appear in the data. Auditing tools can use it to
+// list the content which is not spelled out where it is used.
+func FindSpecAnchors(data []byte) ([]*AnchorExpansion, error) {
+	src, err := parseSpecSource(data)
+	if err != nil {
+		return nil, err
+	}
+	return src.expansions, nil
+}
+
+// specPathError annotates an error with the Spec field it is about,
+// without changing its message.
+type specPathError struct {
+	path string
+	err  error
+}
+
+func (e *specPathError) Error() string {
+	return e.err.Error()
+}
+
+func (e *specPathError) Unwrap() error {
+	return e.err
+}
+
+// fullPath returns the path of the field, including the paths of any
+// nested annotations of the error, which are relative to this one.
+func (e *specPathError) fullPath() string {
+	path := e.path
+	for err := e.err; ; {
+		var nested *specPathError
+		if !errors.As(err, &nested) {
+			return path
+		}
+		if strings.HasPrefix(nested.path, "[") {
+			path += nested.path
+		} else {
+			path = joinSpecPath(path, nested.path)
+		}
+		err = nested.err
+	}
+}
+
+// withSpecPath annotates a non-nil error with the given Spec field.
+func withSpecPath(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &specPathError{path: path, err: err}
+}
+
+// sourceLocation is the location of a Spec field in the Spec source.
+type sourceLocation struct {
+	line, column int
+	expansion    *AnchorExpansion
+}
+
+// specSource is the parsed source of a Spec document.
+type specSource struct {
+	locations  map[string]*sourceLocation
+	anchors    []*yaml.Node
+	expansions []*AnchorExpansion
+	merges     []*yaml.Node
+}
+
+// parseSpecSource parses Spec data, recording the location of all Spec
+// fields, the anchors defined and the aliases expanded.
+func parseSpecSource(data []byte) (*specSource, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	src := &specSource{locations: map[string]*sourceLocation{}}
+	for _, n := range doc.Content {
+		src.walk(n, "", nil, 0)
+	}
+	return src, nil
+}
+
+// walk records the given node, found at the given path, and its children.
+// Nodes within an alias expansion are recorded with that expansion.
+func (s *specSource) walk(n *yaml.Node, path string, x *AnchorExpansion, depth int) {
+	if depth > maxAliasDepth {
+		return
+	}
+	if n.Anchor != "" && x == nil {
+		s.anchors = append(s.anchors, n)
+	}
+
+	if n.Kind == yaml.AliasNode {
+		if n.Alias == nil {
+			return
+		}
+		if x == nil {
+			x = s.expansion(n, path, false)
+		}
+		s.walk(n.Alias, path, x, depth+1)
+		return
+	}
+
+	if _, ok := s.locations[path]; !ok {
+		s.locations[path] = &sourceLocation{line: n.Line, column: n.Column, expansion: x}
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		var merges []*yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Tag == "!!merge" {
+				if x == nil {
+					s.merges = append(s.merges, key)
+				}
+				merges = append(merges, value)
+				continue
+			}
+			s.walk(value, joinSpecPath(path, key.Value), x, depth+1)
+		}
+		// keys set explicitly take precedence over merged ones
+		for _, m := range merges {
+			s.walkMerge(m, path, x, depth+1)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			s.walk(c, path+"["+strconv.Itoa(i)+"]", x, depth+1)
+		}
+	}
+}
+
+// walkMerge records the mappings merged into the mapping at the given path.
+func (s *specSource) walkMerge(n *yaml.Node, path string, x *AnchorExpansion, depth int) {
+	if n.Kind == yaml.SequenceNode {
+		for _, c := range n.Content {
+			s.walkMerge(c, path, x, depth)
+		}
+		return
+	}
+	if depth > maxAliasDepth {
+		return
+	}
+	target := n
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		if x == nil {
+			x = s.expansion(n, path, true)
+		}
+		target = n.Alias
+	}
+	if target.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(target.Content); i += 2 {
+		s.walk(target.Content[i+1], joinSpecPath(path, target.Content[i].Value), x, depth+1)
+	}
+}
+
+// expansion records the expansion of the given alias at the given path.
+func (s *specSource) expansion(alias *yaml.Node, path string, merge bool) *AnchorExpansion {
+	x := &AnchorExpansion{
+		Anchor:       alias.Value,
+		Path:         path,
+		Line:         alias.Line,
+		Column:       alias.Column,
+		AnchorLine:   alias.Alias.Line,
+		AnchorColumn: alias.Alias.Column,
+		Merge:        merge,
+	}
+	s.expansions = append(s.expansions, x)
+	return x
+}
+
+// locate returns the location of the given Spec field, or of its closest
+// enclosing field with a known location.
+func (s *specSource) locate(path string) (string, *sourceLocation) {
+	for {
+		if loc, ok := s.locations[path]; ok {
+			return path, loc
+		}
+		if path == "" {
+			return "", nil
+		}
+		path = parentSpecPath(path)
+	}
+}
+
+// locateKey returns the first field with the given key.
+func (s *specSource) locateKey(key string) (string, *sourceLocation) {
+	var (
+		found string
+		first *sourceLocation
+	)
+	for path, loc := range s.locations {
+		if path != key && !strings.HasSuffix(path, "."+key) {
+			continue
+		}
+		if first == nil || loc.line < first.line || (loc.line == first.line && loc.column < first.column) {
+			found, first = path, loc
+		}
+	}
+	return found, first
+}
+
+// unknownFieldRe matches the unknown field errors of strict parsing.
+var unknownFieldRe = regexp.MustCompile(`unknown field "([^"]*)"`)
+
+// locateSpecError annotates an error about the given Spec data with the
+// location of the offending field, if it can be determined.
+func locateSpecError(data []byte, err error) error {
+	var (
+		pathErr *specPathError
+		srcErr  *SpecSourceError
+		path    string
+		loc     *sourceLocation
+	)
+
+	if errors.As(err, &srcErr) {
+		return err
+	}
+
+	src, perr := parseSpecSource(data)
+	if perr != nil {
+		return err
+	}
+
+	switch m := unknownFieldRe.FindStringSubmatch(err.Error()); {
+	case errors.As(err, &pathErr):
+		path, loc = src.locate(pathErr.fullPath())
+	case m != nil:
+		path, loc = src.locateKey(m[1])
+	}
+	if loc == nil {
+		return err
+	}
+
+	if path == "" {
+		path = "Spec"
+	}
+	return &SpecSourceError{
+		Path:      path,
+		Line:      loc.line,
+		Column:    loc.column,
+		Expansion: loc.expansion,
+		Err:       err,
+	}
+}
+
+// rejectSpecAnchors returns an error if the given Spec data uses anchors,
+// aliases or merge keys.
+func rejectSpecAnchors(data []byte) error {
+	if !bytes.ContainsAny(data, "&*") && !bytes.Contains(data, []byte("<<")) {
+		return nil
+	}
+	src, err := parseSpecSource(data)
+	if err != nil {
+		// leave reporting invalid YAML to the Spec parser
+		return nil
+	}
+	switch {
+	case len(src.anchors) > 0:
+		n := src.anchors[0]
+		return fmt.Errorf("line %d, column %d: YAML anchor &%s not allowed", n.Line, n.Column, n.Anchor)
+	case len(src.expansions) > 0:
+		x := src.expansions[0]
+		return fmt.Errorf("line %d, column %d: YAML alias *%s not allowed", x.Line, x.Column, x.Anchor)
+	case len(src.merges) > 0:
+		n := src.merges[0]
+		return fmt.Errorf("line %d, column %d: YAML merge key not allowed", n.Line, n.Column)
+	}
+	return nil
+}
+
+// joinSpecPath returns the path of the given field of the given path.
+func joinSpecPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// parentSpecPath returns the path of the field containing the given one.
+func parentSpecPath(path string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '.' || path[i] == '[' {
+			return path[:i]
+		}
+	}
+	return ""
+}
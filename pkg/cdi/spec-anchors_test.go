@@ -0,0 +1,183 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const anchoredSpec = `cdiVersion: "0.6.0"
+kind: vendor.com/device
+devices:
+- name: dev0
+  containerEdits: &edits
+    env:
+    - "=BAD"
+- name: dev1
+  containerEdits: *edits
+`
+
+const mergedSpec = `cdiVersion: "0.10.0"
+kind: vendor.com/device
+devices:
+- name: dev0
+  containerEdits:
+    deviceNodes:
+    - &node
+      path: /dev/dev0
+      permissions: rw
+    - <<: *node
+      denyAccess: true
+`
+
+func TestFindSpecAnchors(t *testing.T) {
+	expansions, err := FindSpecAnchors([]byte(anchoredSpec))
+	require.NoError(t, err)
+	require.Equal(t, []*AnchorExpansion{
+		{
+			Anchor:       "edits",
+			Path:         "devices[1].containerEdits",
+			Line:         9,
+			Column:       19,
+			AnchorLine:   5,
+			AnchorColumn: 19,
+		},
+	}, expansions)
+
+	expansions, err = FindSpecAnchors([]byte(mergedSpec))
+	require.NoError(t, err)
+	require.Equal(t, []*AnchorExpansion{
+		{
+			Anchor:       "node",
+			Path:         "devices[0].containerEdits.deviceNodes[1]",
+			Line:         10,
+			Column:       11,
+			AnchorLine:   7,
+			AnchorColumn: 7,
+			Merge:        true,
+		},
+	}, expansions)
+}
+
+func TestReadSpecDataAnchorPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		data      string
+		policy    AnchorPolicy
+		location  *SpecSourceError
+		errString string
+	}{
+		{
+			name:      "expand",
+			data:      anchoredSpec,
+			policy:    AnchorsExpand,
+			errString: `invalid environment variable "=BAD"`,
+		},
+		{
+			name:   "track, error in anchor",
+			data:   anchoredSpec,
+			policy: AnchorsTrack,
+			location: &SpecSourceError{
+				Path:   "devices[0].containerEdits.env",
+				Line:   7,
+				Column: 5,
+			},
+		},
+		{
+			name:   "track, error in merged mapping",
+			data:   mergedSpec,
+			policy: AnchorsTrack,
+			location: &SpecSourceError{
+				Path:   "devices[0].containerEdits.deviceNodes[1]",
+				Line:   10,
+				Column: 7,
+			},
+		},
+		{
+			name:      "track, unknown field",
+			data:      "cdiVersion: \"0.6.0\"\nkind: vendor.com/device\ndevices:\n- name: dev0\n  bogus: 1\n",
+			policy:    AnchorsTrack,
+			errString: `line 5, column 10: devices[0].bogus:`,
+		},
+		{
+			name:      "reject",
+			data:      anchoredSpec,
+			policy:    AnchorsReject,
+			errString: "line 5, column 19: YAML anchor &edits not allowed",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := readSpecData([]byte(tc.data), "/etc/cdi/vendor.yaml", 0, validateSpec, specParsing{anchors: tc.policy})
+			require.Error(t, err)
+			if tc.errString != "" {
+				require.Contains(t, err.Error(), tc.errString)
+			}
+			var srcErr *SpecSourceError
+			if tc.location == nil {
+				if tc.policy != AnchorsTrack {
+					require.False(t, errors.As(err, &srcErr))
+				}
+				return
+			}
+			require.True(t, errors.As(err, &srcErr), "error %v", err)
+			require.Equal(t, tc.location.Path, srcErr.Path)
+			require.Equal(t, tc.location.Line, srcErr.Line)
+			require.Equal(t, tc.location.Column, srcErr.Column)
+		})
+	}
+}
+
+func TestAnchorExpansionInError(t *testing.T) {
+	data := `cdiVersion: "0.6.0"
+kind: vendor.com/device
+devices:
+- name: dev0
+  containerEdits:
+    mounts: &mounts
+    - hostPath: ""
+      containerPath: /lib
+containerEdits:
+  mounts: *mounts
+`
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor.yaml"), []byte(data), 0o644))
+
+	cache := newCache(
+		WithSpecDirs(dir),
+		WithAutoRefresh(false),
+		WithAnchorPolicy(AnchorsTrack),
+	)
+	require.Error(t, cache.Refresh())
+	errs := cache.GetErrors()[filepath.Join(dir, "vendor.yaml")]
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Error(), `line 7, column 7: containerEdits.mounts[0], expanded from alias *mounts at line 10, column 11`)
+}
+
+func TestParseAnchorPolicy(t *testing.T) {
+	for _, p := range []AnchorPolicy{AnchorsExpand, AnchorsTrack, AnchorsReject} {
+		parsed, err := ParseAnchorPolicy(p.String())
+		require.NoError(t, err)
+		require.Equal(t, p, parsed)
+	}
+	_, err := ParseAnchorPolicy("ignore")
+	require.Error(t, err)
+}
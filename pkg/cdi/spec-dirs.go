@@ -20,6 +20,7 @@ import (
 	"errors"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 )
 
@@ -55,6 +56,9 @@ func WithSpecDirs(dirs ...string) Option {
 // scanSpecFunc is a function for processing CDI Spec files.
 type scanSpecFunc func(string, int, *Spec, error) error
 
+// specFileFunc is a function for processing CDI Spec file paths.
+type specFileFunc func(string, int, error) error
+
 // ScanSpecDirs scans the given directories looking for CDI Spec files,
 // which are all files with a '.json' or '.yaml' suffix. For every Spec
 // file discovered, ScanSpecDirs loads a Spec from the file then calls
@@ -68,41 +72,23 @@ type scanSpecFunc func(string, int, *Spec, error) error
 // can be used to terminate the scan gracefully without ScanSpecDirs
 // returning an error. ScanSpecDirs silently skips any subdirectories.
 func scanSpecDirs(dirs []string, scanFn scanSpecFunc) error {
-	var (
-		spec *Spec
-		err  error
-	)
+	return walkSpecDirs(dirs, func(path string, priority int, err error) error {
+		if err != nil {
+			return scanFn(path, priority, nil, err)
+		}
+		spec, err := ReadSpec(path, priority)
+		return scanFn(path, priority, spec, err)
+	})
+}
 
+// walkSpecDirs walks the given directories like scanSpecDirs, calling
+// the given function for every Spec file found without reading it.
+func walkSpecDirs(dirs []string, fileFn specFileFunc) error {
 	for priority, dir := range dirs {
-		err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-			// for initial stat failure Walk calls us with nil info
-			if info == nil {
-				if errors.Is(err, fs.ErrNotExist) {
-					return nil
-				}
-				return err
-			}
-			// first call from Walk is for dir itself, others we skip
-			if info.IsDir() {
-				if path == dir {
-					return nil
-				}
-				return filepath.SkipDir
-			}
-
-			// ignore obviously non-Spec files
-			if ext := filepath.Ext(path); ext != ".json" && ext != ".yaml" {
-				return nil
-			}
-
-			if err != nil {
-				return scanFn(path, priority, nil, err)
-			}
-
-			spec, err = ReadSpec(path, priority)
-			return scanFn(path, priority, spec, err)
+		// os.DirFS follows a symlinked Spec directory, under its own path
+		err := walkSpecFS(os.DirFS(dir), func(name string) error {
+			return fileFn(filepath.Join(dir, filepath.FromSlash(name)), priority, nil)
 		})
-
 		if err != nil && err != ErrStopScan {
 			return err
 		}
@@ -110,3 +96,32 @@ func scanSpecDirs(dirs []string, scanFn scanSpecFunc) error {
 
 	return nil
 }
+
+// walkSpecFS walks the root directory of the given file system, calling
+// the given function with the name of every Spec file found. A missing
+// root directory is treated as an empty one. Subdirectories are skipped.
+func walkSpecFS(fsys fs.FS, fileFn func(string) error) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		// for initial stat failure WalkDir calls us with nil entry
+		if d == nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		// first call from WalkDir is for the root itself, others we skip
+		if d.IsDir() {
+			if name == "." {
+				return nil
+			}
+			return fs.SkipDir
+		}
+
+		// ignore obviously non-Spec files
+		if ext := path.Ext(name); ext != ".json" && ext != ".yaml" {
+			return nil
+		}
+
+		return fileFn(name)
+	})
+}
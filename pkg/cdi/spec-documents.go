@@ -0,0 +1,130 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// ReadSpecs reads the given CDI Spec file, which can be a YAML file with
+// multiple Spec documents separated by '---' lines. Each document results
+// in a separate Spec, assigned the given priority. Documents which fail to
+// parse or validate are skipped. ReadSpecs returns the Specs of all other
+// documents together with an error describing the failed ones.
+func ReadSpecs(path string, priority int) ([]*Spec, error) {
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("failed to read CDI Spec %q: %w", path, err)
+	}
+
+	specs, err := readSpecsData(data, path, priority, validateSpec, specParsing{anchors: AnchorsExpand})
+	setSpecModTime(path, specs...)
+
+	return specs, err
+}
+
+// readSpecsData creates Specs from the given data, read from the given
+// path, one for each Spec document. The Specs are assigned the given
+// priority and their content is validated using the given function. The
+// data is parsed as defined by the given specParsing.
+func readSpecsData(data []byte, path string, priority int, validate func(*cdi.Spec) error, parsing specParsing) ([]*Spec, error) {
+	docs := splitSpecDocuments(data, path)
+	if len(docs) <= 1 {
+		spec, err := readSpecData(data, path, priority, validate, parsing)
+		if err != nil {
+			return nil, err
+		}
+		return []*Spec{spec}, nil
+	}
+
+	var (
+		specs []*Spec
+		errs  []error
+	)
+	for i, doc := range docs {
+		spec, err := readSpecData(doc, path, priority, validate, parsing)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("document %d: %w", i+1, err))
+			continue
+		}
+		spec.document = i
+		specs = append(specs, spec)
+	}
+
+	return specs, errors.Join(errs...)
+}
+
+// splitSpecDocuments splits the given Spec data into YAML documents.
+// Documents are separated by lines starting with the '---' marker.
+// Empty documents, for instance before a leading marker, are omitted.
+// JSON Spec data always consists of a single document.
+func splitSpecDocuments(data []byte, path string) [][]byte {
+	if filepath.Ext(path) == ".json" {
+		return [][]byte{data}
+	}
+
+	var (
+		docs [][]byte
+		doc  []byte
+	)
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if isDocumentMarker(line) {
+			if !isEmptyDocument(doc) {
+				docs = append(docs, doc)
+			}
+			// content may follow the marker on the same line
+			doc = append([]byte{}, line[3:]...)
+			continue
+		}
+		doc = append(doc, line...)
+	}
+	if !isEmptyDocument(doc) {
+		docs = append(docs, doc)
+	}
+
+	return docs
+}
+
+// isDocumentMarker returns true if the line starts a new YAML document.
+func isDocumentMarker(line []byte) bool {
+	if !bytes.HasPrefix(line, []byte("---")) {
+		return false
+	}
+	rest := line[3:]
+	return len(rest) == 0 || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n' || rest[0] == '\r'
+}
+
+// isEmptyDocument returns true if the YAML document has no content other
+// than whitespace and comments.
+func isEmptyDocument(doc []byte) bool {
+	for _, line := range bytes.Split(doc, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,174 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitSpecDocuments(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		path   string
+		data   string
+		result []string
+	}{
+		{
+			name:   "single document",
+			path:   "spec.yaml",
+			data:   "a: 1\n",
+			result: []string{"a: 1\n"},
+		},
+		{
+			name:   "single document with leading marker",
+			path:   "spec.yaml",
+			data:   "---\na: 1\n",
+			result: []string{"\na: 1\n"},
+		},
+		{
+			name:   "multiple documents",
+			path:   "spec.yaml",
+			data:   "---\na: 1\n---\nb: 2\n--- # comment\nc: 3\n",
+			result: []string{"\na: 1\n", "\nb: 2\n", " # comment\nc: 3\n"},
+		},
+		{
+			name:   "empty and comment-only documents",
+			path:   "spec.yaml",
+			data:   "# header\n---\n\n---\na: 1\n---\n# trailer\n",
+			result: []string{"\na: 1\n"},
+		},
+		{
+			name:   "content after marker",
+			path:   "spec.yaml",
+			data:   "--- {a: 1}\n--- {b: 2}",
+			result: []string{" {a: 1}\n", " {b: 2}"},
+		},
+		{
+			name:   "markers must start a line",
+			path:   "spec.yaml",
+			data:   "a: \"---\"\nb: |\n  ---\n----\n",
+			result: []string{"a: \"---\"\nb: |\n  ---\n----\n"},
+		},
+		{
+			name:   "JSON",
+			path:   "spec.json",
+			data:   "---\n{}\n---\n{}\n",
+			result: []string{"---\n{}\n---\n{}\n"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var result []string
+			for _, doc := range splitSpecDocuments([]byte(tc.data), tc.path) {
+				result = append(result, string(doc))
+			}
+			require.Equal(t, tc.result, result)
+		})
+	}
+}
+
+func TestMultiDocumentSpecFiles(t *testing.T) {
+	data := `
+---
+cdiVersion: "0.3.0"
+kind: "vendor1.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+        - "VENDOR1=dev1"
+---
+cdiVersion: "0.3.0"
+kind: "vendor2.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+        - "VENDOR2=dev1"
+---
+cdiVersion: "0.3.0"
+kind: "vendor3.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      unknown: field
+`
+
+	dir, err := createSpecDirs(t, map[string]string{"drivers.yaml": data}, nil)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "etc", "drivers.yaml")
+
+	t.Run("ReadSpecs", func(t *testing.T) {
+		specs, err := ReadSpecs(path, 1)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "document 3")
+		require.Len(t, specs, 2)
+		for i, spec := range specs {
+			require.Equal(t, path, spec.GetPath())
+			require.Equal(t, i, spec.GetDocument())
+			require.Equal(t, 1, spec.GetPriority())
+		}
+		require.Equal(t, "vendor1.com", specs[0].GetVendor())
+		require.Equal(t, "vendor2.com", specs[1].GetVendor())
+		require.NotEqual(t, specs[0].GetDigest(), specs[1].GetDigest())
+
+		_, err = ReadSpec(path, 1)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "3 Spec documents found")
+	})
+
+	t.Run("Cache", func(t *testing.T) {
+		cache := newCache(
+			WithSpecDirs(filepath.Join(dir, "etc"), filepath.Join(dir, "run")),
+			WithAutoRefresh(false),
+		)
+		err := cache.Refresh()
+		require.Error(t, err)
+		require.Equal(t, []string{"vendor1.com/device=dev1", "vendor2.com/device=dev1"}, cache.ListDevices())
+
+		errs := cache.GetErrors()
+		require.Len(t, errs[path], 1)
+		require.Contains(t, errs[path][0].Error(), "document 3")
+
+		// fix the broken document
+		fixed := strings.Replace(data, "unknown: field", "env: [\"VENDOR3=dev1\"]", 1)
+		require.NoError(t, os.WriteFile(path, []byte(fixed), 0o644))
+		require.NoError(t, cache.Refresh())
+		require.Equal(t, []string{
+			"vendor1.com/device=dev1",
+			"vendor2.com/device=dev1",
+			"vendor3.com/device=dev1",
+		}, cache.ListDevices())
+
+		// snapshots keep the documents apart
+		state, err := cache.Snapshot()
+		require.NoError(t, err)
+		require.Len(t, state.Specs, 3)
+		for i, s := range state.Specs {
+			require.Equal(t, i, s.Document)
+		}
+
+		restored := newCache(WithSpecDirs(), WithAutoRefresh(false))
+		require.NoError(t, restored.RestoreSnapshot(state))
+		require.Equal(t, cache.ListDevices(), restored.ListDevices())
+		require.Len(t, restored.specFiles[path].specs, 3)
+	})
+}
@@ -0,0 +1,339 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// WithRefreshWorkers returns an option to set the maximum number of Spec
+// files parsed concurrently during a Cache refresh. If workers is not
+// positive, which is the default, the number of workers is the value of
+// runtime.GOMAXPROCS(0).
+func WithRefreshWorkers(workers int) Option {
+	return func(c *Cache) {
+		c.refreshWorkers = workers
+	}
+}
+
+// specFile is a Spec file found during a Cache refresh.
+type specFile struct {
+	path     string
+	priority int
+	fsys     fs.FS       // Spec file system, nil for Spec directories
+	name     string      // name of the file in fsys
+	info     os.FileInfo // stat of the file
+	sigInfo  os.FileInfo // stat of the signature file, if verified
+	varsInfo os.FileInfo // stat of the Spec variables file, if any
+	specs    []*Spec     // one per Spec document
+	err      error
+	retry    bool // err is possibly transient
+}
+
+// loaded returns true if all Spec documents of the file were loaded.
+func (f *specFile) loaded() bool {
+	return f.err == nil && len(f.specs) > 0
+}
+
+// fileName returns the name of the Spec file, which is its path unless
+// the file is in a Spec file system.
+func (f *specFile) fileName() string {
+	if f.fsys != nil {
+		return f.name
+	}
+	return f.path
+}
+
+// unchanged returns true if the given file is known to be unchanged
+// since this one was read. A changed Spec variables file changes the
+// content of all Spec files.
+func (f *specFile) unchanged(o *specFile) bool {
+	return f.priority == o.priority &&
+		sameFileInfo(f.info, o.info) && sameFileInfo(f.sigInfo, o.sigInfo) &&
+		sameFileInfo(f.varsInfo, o.varsInfo)
+}
+
+// sameFileInfo returns true if the given stats are for the same, unchanged file.
+func sameFileInfo(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return os.SameFile(a, b) && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+// RefreshVendor refreshes the Cache by reading the Spec files of the given
+// vendor (as listed by ListVendors) again, without reading the Spec files
+// of other vendors. The Spec directories are rescanned to pick up new and
+// removed files, and any new or previously unreadable Spec files are read
+// as well. Conflicts are then resolved again against the Specs of all
+// vendors. This is useful when a vendor is known to have updated its own
+// Specs. It returns any errors encountered, like Refresh does for a full
+// refresh.
+func (c *Cache) RefreshVendor(vendor string) error {
+	return c.RefreshVendorContext(context.Background(), vendor)
+}
+
+// RefreshVendorContext is like RefreshVendor but honors cancellation and
+// deadlines of the given context. If the context is done before the
+// refresh is complete, the Cache is left unchanged and the error of the
+// context is returned.
+func (c *Cache) RefreshVendorContext(ctx context.Context, vendor string) error {
+	if err := c.lockContext(ctx); err != nil {
+		return err
+	}
+	defer c.Unlock()
+
+	files, err := c.updateSpecFiles(ctx, c.scanSpecFiles(), func(_, prev *specFile) bool {
+		if !prev.loaded() {
+			return false
+		}
+		for _, spec := range prev.specs {
+			if spec.GetVendor() == vendor {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.refreshSpecFiles(files)
+}
+
+// RefreshPaths refreshes the Cache by reading the given Spec files again,
+// without rescanning the Spec directories or reading other Spec files.
+// Given files which no longer exist are removed from the Cache. Paths
+// outside of the Spec directories are ignored. Conflicts are then resolved
+// again against all cached Specs. It returns any errors encountered, like
+// Refresh does for a full refresh.
+func (c *Cache) RefreshPaths(paths ...string) error {
+	return c.RefreshPathsContext(context.Background(), paths...)
+}
+
+// RefreshPathsContext is like RefreshPaths but honors cancellation and
+// deadlines of the given context. If the context is done before the
+// refresh is complete, the Cache is left unchanged and the error of the
+// context is returned.
+func (c *Cache) RefreshPathsContext(ctx context.Context, paths ...string) error {
+	if err := c.lockContext(ctx); err != nil {
+		return err
+	}
+	defer c.Unlock()
+
+	return c.refreshPaths(ctx, paths...)
+}
+
+// refreshPaths refreshes the Cache by reading the given Spec files again.
+func (c *Cache) refreshPaths(ctx context.Context, paths ...string) error {
+	targets := map[string]struct{}{}
+	for _, path := range paths {
+		targets[filepath.Clean(path)] = struct{}{}
+	}
+
+	var files []*specFile
+	for path, f := range c.specFiles {
+		if _, ok := targets[path]; !ok {
+			files = append(files, &specFile{
+				path:     f.path,
+				priority: f.priority,
+				fsys:     f.fsys,
+				name:     f.name,
+			})
+		}
+	}
+	for path := range targets {
+		if ext := filepath.Ext(path); ext != ".json" && ext != ".yaml" {
+			continue
+		}
+		priority := c.specDirPriority(path)
+		if priority < 0 {
+			continue
+		}
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		files = append(files, &specFile{path: path, priority: priority})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].priority != files[j].priority {
+			return files[i].priority < files[j].priority
+		}
+		return files[i].path < files[j].path
+	})
+
+	files, err := c.updateSpecFiles(ctx, files, func(f, _ *specFile) bool {
+		_, ok := targets[f.path]
+		return !ok
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.refreshSpecFiles(files)
+}
+
+// specDirPriority returns the priority of the Spec directory containing
+// the given Spec file, or -1 if the file is not in any Spec directory.
+func (c *Cache) specDirPriority(path string) int {
+	dir := filepath.Dir(path)
+	for priority := len(c.specDirs) - 1; priority >= 0; priority-- {
+		if c.specDirs[priority] == dir {
+			return priority
+		}
+	}
+	return -1
+}
+
+// readSpecFiles scans the Spec directories of the Cache and reads all
+// Spec files found. Files unchanged since they were last read are not
+// read again, but the previously read Spec is reused.
+func (c *Cache) readSpecFiles(ctx context.Context) ([]*specFile, error) {
+	return c.updateSpecFiles(ctx, c.scanSpecFiles(), func(f, prev *specFile) bool {
+		return prev.loaded() && c.statSpecFile(f) == nil && f.unchanged(prev)
+	})
+}
+
+// statSpecFile updates the stats of the given Spec file.
+func (c *Cache) statSpecFile(f *specFile) error {
+	if f.info != nil {
+		return nil
+	}
+	if c.specVarsFile != "" {
+		// a missing variables file is reported when the Spec is read
+		f.varsInfo, _ = os.Stat(c.specVarsFile)
+	}
+	if f.fsys != nil {
+		info, err := fs.Stat(f.fsys, f.name)
+		if err != nil {
+			return err
+		}
+		f.info = info
+		if len(c.signatureKeys) > 0 {
+			f.sigInfo, _ = fs.Stat(f.fsys, SignatureFileForSpec(f.name))
+		}
+		return nil
+	}
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	f.info = info
+	if len(c.signatureKeys) > 0 {
+		// a missing signature is reported when the Spec is read
+		f.sigInfo, _ = os.Stat(SignatureFileForSpec(f.path))
+	}
+	return nil
+}
+
+// scanSpecFiles scans the Spec directories and Spec file systems of the
+// Cache for Spec files. The files are returned in scan order, without
+// reading them.
+func (c *Cache) scanSpecFiles() []*specFile {
+	var files []*specFile
+
+	_ = walkSpecDirs(c.specDirs, func(path string, priority int, err error) error {
+		files = append(files, &specFile{
+			path:     filepath.Clean(path),
+			priority: priority,
+			err:      err,
+		})
+		return nil
+	})
+
+	return append(files, c.scanSpecFS()...)
+}
+
+// updateSpecFiles reads the given Spec files, using a bounded pool of
+// workers. For files read before, the reuse function decides whether the
+// previous result should be reused instead. The given files are returned
+// and remembered for subsequent updates. If the given context is done
+// before all files are read, the error of the context is returned and
+// nothing is remembered.
+func (c *Cache) updateSpecFiles(ctx context.Context, files []*specFile, reuse func(f, prev *specFile) bool) ([]*specFile, error) {
+	var (
+		pending []*specFile
+		known   = map[string]*specFile{}
+	)
+
+	for i, f := range files {
+		if f.err != nil {
+			continue
+		}
+		if prev, ok := c.specFiles[f.path]; ok && prev.priority == f.priority && reuse(f, prev) {
+			files[i] = prev
+		} else if f.err = c.statSpecFile(f); f.err == nil {
+			pending = append(pending, f)
+		}
+		known[f.path] = files[i]
+	}
+
+	workers := c.refreshWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(pending) {
+		workers = len(pending)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		queue = make(chan *specFile)
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range queue {
+				f.specs, f.retry, f.err = c.readSpecs(f)
+			}
+		}()
+	}
+enqueue:
+	for _, f := range pending {
+		select {
+		case queue <- f:
+		case <-ctx.Done():
+			break enqueue
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var fresh []*Spec
+	for _, f := range pending {
+		fresh = append(fresh, f.specs...)
+	}
+	c.internSpecs(fresh)
+	c.scheduleReadRetries(pending)
+
+	c.specFiles = known
+
+	return files, nil
+}
@@ -0,0 +1,247 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshSpecFiles(t *testing.T) {
+	specs := map[string]string{}
+	for vf := 0; vf < 32; vf++ {
+		specs[fmt.Sprintf("vf%d.yaml", vf)] = vfSpec(vf)
+	}
+
+	var expected []string
+	for _, workers := range []int{1, 4, 0} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			dir, err := createSpecDirs(t, specs, nil)
+			require.NoError(t, err)
+			etc := filepath.Join(dir, "etc")
+
+			cache := newCache(
+				WithSpecDirs(etc),
+				WithAutoRefresh(false),
+				WithRefreshWorkers(workers),
+			)
+			require.Empty(t, cache.GetErrors())
+
+			devices := cache.ListDevices()
+			require.Len(t, devices, 32)
+			if expected == nil {
+				expected = devices
+			}
+			require.Equal(t, expected, devices)
+
+			// unchanged Spec files are not parsed again
+			vf0 := cache.GetDevice("vendor.com/vf=vf0").GetSpec()
+			vf1 := cache.GetDevice("vendor.com/vf=vf1").GetSpec()
+			require.NoError(t, cache.Refresh())
+			require.Same(t, vf0, cache.GetDevice("vendor.com/vf=vf0").GetSpec())
+			require.Same(t, vf1, cache.GetDevice("vendor.com/vf=vf1").GetSpec())
+
+			// updated Spec files are parsed again
+			require.NoError(t, os.WriteFile(filepath.Join(etc, "vf1.yaml"), []byte(vfSpec(100)), 0o644))
+			require.NoError(t, cache.Refresh())
+			require.Same(t, vf0, cache.GetDevice("vendor.com/vf=vf0").GetSpec())
+			require.Nil(t, cache.GetDevice("vendor.com/vf=vf1"))
+			require.NotNil(t, cache.GetDevice("vendor.com/vf=vf100"))
+
+			// removed Spec files are forgotten
+			require.NoError(t, os.Remove(filepath.Join(etc, "vf0.yaml")))
+			require.NoError(t, cache.Refresh())
+			require.Nil(t, cache.GetDevice("vendor.com/vf=vf0"))
+			require.NotContains(t, cache.specFiles, filepath.Join(etc, "vf0.yaml"))
+		})
+	}
+}
+
+func BenchmarkRefreshSpecFiles(b *testing.B) {
+	const count = 500
+
+	dir := b.TempDir()
+	for vf := 0; vf < count; vf++ {
+		name := filepath.Join(dir, fmt.Sprintf("vf%d.yaml", vf))
+		require.NoError(b, os.WriteFile(name, []byte(vfSpec(vf)), 0o644))
+	}
+
+	for _, bc := range []struct {
+		workers int
+		cached  bool
+	}{
+		{workers: 1},
+		{workers: 4},
+		{workers: runtime.GOMAXPROCS(0), cached: true},
+	} {
+		b.Run(fmt.Sprintf("workers=%d/cached=%v", bc.workers, bc.cached), func(b *testing.B) {
+			cache := newCache(
+				WithSpecDirs(dir),
+				WithAutoRefresh(false),
+				WithRefreshWorkers(bc.workers),
+			)
+			require.Len(b, cache.ListDevices(), count)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !bc.cached {
+					cache.specFiles = nil
+				}
+				require.NoError(b, cache.refresh())
+			}
+		})
+	}
+}
+
+func TestTargetedRefresh(t *testing.T) {
+	spec := func(vendor, device, value string) string {
+		return fmt.Sprintf(`
+cdiVersion: "0.3.0"
+kind:       "%s.com/device"
+devices:
+  - name: "%s"
+    containerEdits:
+      env:
+      - "VALUE=%s"
+`, vendor, device, value)
+	}
+	value := func(cache *Cache, device string) string {
+		d := cache.GetDevice(device)
+		if d == nil {
+			return ""
+		}
+		return d.ContainerEdits.Env[0]
+	}
+
+	dir, err := createSpecDirs(t,
+		map[string]string{
+			"vendor1.yaml": spec("vendor1", "dev1", "etc"),
+			"vendor2.yaml": spec("vendor2", "dev1", "etc"),
+		},
+		map[string]string{
+			"vendor1.yaml": spec("vendor1", "dev1", "run"),
+		},
+	)
+	require.NoError(t, err)
+	etc, run := filepath.Join(dir, "etc"), filepath.Join(dir, "run")
+
+	cache := newCache(
+		WithSpecDirs(etc, run),
+		WithAutoRefresh(false),
+	)
+	require.Empty(t, cache.GetErrors())
+	require.Equal(t, "VALUE=run", value(cache, "vendor1.com/device=dev1"))
+	require.Equal(t, "VALUE=etc", value(cache, "vendor2.com/device=dev1"))
+
+	// update both vendors, but only refresh the files of vendor1
+	require.NoError(t, os.WriteFile(filepath.Join(run, "vendor1.yaml"), []byte(spec("vendor1", "dev2", "run")), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(run, "vendor1-new.yaml"), []byte(spec("vendor1", "dev3", "new")), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(etc, "vendor2.yaml"), []byte(spec("vendor2", "dev1", "updated")), 0o644))
+
+	require.NoError(t, cache.RefreshVendor("vendor1.com"))
+	require.Equal(t, "VALUE=etc", value(cache, "vendor1.com/device=dev1"), "conflict must be resolved again")
+	require.Equal(t, "VALUE=run", value(cache, "vendor1.com/device=dev2"))
+	require.Equal(t, "VALUE=new", value(cache, "vendor1.com/device=dev3"))
+	require.Equal(t, "VALUE=etc", value(cache, "vendor2.com/device=dev1"))
+
+	// refresh individual files
+	require.NoError(t, os.Remove(filepath.Join(run, "vendor1-new.yaml")))
+	require.NoError(t, os.WriteFile(filepath.Join(run, "vendor2.yaml"), []byte(spec("vendor2", "dev1", "run")), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(etc, "vendor1.yaml"), []byte(spec("vendor1", "dev1", "updated")), 0o644))
+
+	require.NoError(t, cache.RefreshPaths(
+		filepath.Join(run, "vendor1-new.yaml"),
+		filepath.Join(run, "vendor2.yaml"),
+		filepath.Join(dir, "elsewhere.yaml"),
+	))
+	require.Nil(t, cache.GetDevice("vendor1.com/device=dev3"))
+	require.Equal(t, "VALUE=run", value(cache, "vendor2.com/device=dev1"))
+	require.Equal(t, "VALUE=etc", value(cache, "vendor1.com/device=dev1"))
+
+	// a full refresh picks up all changes
+	require.NoError(t, cache.Refresh())
+	require.Equal(t, "VALUE=updated", value(cache, "vendor1.com/device=dev1"))
+	require.Equal(t, []string{
+		"vendor1.com/device=dev1",
+		"vendor1.com/device=dev2",
+		"vendor2.com/device=dev1",
+	}, cache.ListDevices())
+}
+
+func TestWatchRefreshesChangedFiles(t *testing.T) {
+	var (
+		dir       = filepath.Join(t.TempDir(), "cdi")
+		dirErrors = map[string]error{}
+		lock      sync.RWMutex
+		refreshes = make(chan []string, 64)
+		w         = &watch{}
+	)
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+
+	w.setup([]string{dir}, dirErrors)
+	require.Empty(t, dirErrors)
+	w.start(&lock, func(paths ...string) error {
+		refreshes <- paths
+		return nil
+	}, dirErrors)
+	defer w.stop()
+
+	// next returns the next refresh, skipping repeated ones
+	var last []string
+	next := func() []string {
+		for {
+			select {
+			case paths := <-refreshes:
+				if last != nil && fmt.Sprint(paths) == fmt.Sprint(last) {
+					continue
+				}
+				last = paths
+				return paths
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "timeout waiting for refresh")
+			}
+		}
+	}
+
+	spec := filepath.Join(dir, "vendor1.yaml")
+	require.NoError(t, os.WriteFile(spec, []byte(vfSpec(0)), 0o644))
+	require.Equal(t, []string{spec}, next())
+
+	// non-Spec files are ignored, signatures refresh their Spec
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor2.json"+SignatureExt), []byte("sig"), 0o644))
+	require.Equal(t, []string{filepath.Join(dir, "vendor2.json")}, next())
+
+	require.NoError(t, os.Remove(spec))
+	require.Equal(t, []string{spec}, next())
+
+	// removing the directory causes a full refresh
+	require.NoError(t, os.RemoveAll(dir))
+	for {
+		if paths := next(); len(paths) == 0 {
+			break
+		}
+	}
+}
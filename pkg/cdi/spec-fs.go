@@ -0,0 +1,83 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// WithSpecFS returns an option to add a file system to read CDI Spec
+// files from, in addition to the Spec directories. Spec files are read
+// from the root directory of the file system, like they are from a Spec
+// directory. The Specs are assigned the given priority, which can be
+// used to choose a priority relative to Spec directories, for which the
+// priority is the index of the directory (see WithSpecDirs). The option
+// can be given multiple times to add several file systems.
+//
+// Spec file systems allow embedding Specs into binaries (embed.FS), using
+// in-memory file systems in tests, or reading Specs from other backends.
+// They are not monitored for changes, but they are read again on every
+// full Cache refresh. The path of a Spec read from a Spec file system,
+// as returned by Spec.GetPath(), has the form "fs:<index>/<name>", where
+// index is the index of the file system among those added to the Cache.
+func WithSpecFS(fsys fs.FS, priority int) Option {
+	return func(c *Cache) {
+		c.specFS = append(c.specFS, &specFS{
+			fsys:     fsys,
+			priority: priority,
+		})
+	}
+}
+
+// specFS is a file system to read Spec files from.
+type specFS struct {
+	fsys     fs.FS
+	priority int
+}
+
+// specFSPath returns the path used for the named Spec file in the Spec
+// file system with the given index.
+func specFSPath(idx int, name string) string {
+	return fmt.Sprintf("fs:%d/%s", idx, name)
+}
+
+// scanSpecFS scans the Spec file systems of the Cache for Spec files.
+func (c *Cache) scanSpecFS() []*specFile {
+	var files []*specFile
+
+	for idx, sfs := range c.specFS {
+		err := walkSpecFS(sfs.fsys, func(name string) error {
+			files = append(files, &specFile{
+				path:     specFSPath(idx, name),
+				priority: sfs.priority,
+				fsys:     sfs.fsys,
+				name:     name,
+			})
+			return nil
+		})
+		if err != nil {
+			files = append(files, &specFile{
+				path:     specFSPath(idx, "."),
+				priority: sfs.priority,
+				err:      err,
+			})
+		}
+	}
+
+	return files
+}
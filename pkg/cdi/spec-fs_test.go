@@ -0,0 +1,116 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecFS(t *testing.T) {
+	const (
+		fsSpec = `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+  - name: dev0
+    containerEdits:
+      env:
+        - SOURCE=fs
+  - name: dev1
+    containerEdits:
+      env:
+        - SOURCE=fs
+`
+		dirSpec = `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+  - name: dev1
+    containerEdits:
+      env:
+        - SOURCE=dir
+`
+	)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor.yaml"), []byte(dirSpec), 0o644))
+
+	fsys := fstest.MapFS{
+		"vendor.yaml":        {Data: []byte(fsSpec)},
+		"README.md":          {Data: []byte("not a Spec")},
+		"subdir/other.yaml":  {Data: []byte(fsSpec)},
+		"broken.json":        {Data: []byte("{")},
+		"empty/placeholder":  {},
+		"another/dummy.yaml": {},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		priority int
+		source   map[string]string
+	}{
+		{
+			name:     "file system overrides Spec directory",
+			priority: 1,
+			source: map[string]string{
+				"vendor.com/device=dev0": "fs",
+				"vendor.com/device=dev1": "fs",
+			},
+		},
+		{
+			name:     "Spec directory overrides file system",
+			priority: -1,
+			source: map[string]string{
+				"vendor.com/device=dev0": "fs",
+				"vendor.com/device=dev1": "dir",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := newCache(
+				WithSpecDirs(dir),
+				WithSpecFS(fsys, tc.priority),
+				WithAutoRefresh(false),
+			)
+
+			require.Equal(t, []string{"vendor.com/device=dev0", "vendor.com/device=dev1"}, cache.ListDevices())
+			for name, source := range tc.source {
+				dev := cache.GetDevice(name)
+				require.NotNil(t, dev)
+				require.Equal(t, []string{"SOURCE=" + source}, dev.ContainerEdits.Env)
+			}
+
+			spec := cache.GetDevice("vendor.com/device=dev0").GetSpec()
+			require.Equal(t, "fs:0/vendor.yaml", spec.GetPath())
+			require.Equal(t, tc.priority, spec.GetPriority())
+
+			errs := cache.GetErrors()
+			require.Len(t, errs, 1)
+			require.Contains(t, errs, "fs:0/broken.json")
+
+			fsys["vendor.yaml"] = &fstest.MapFile{Data: []byte(dirSpec)}
+			defer func() { fsys["vendor.yaml"] = &fstest.MapFile{Data: []byte(fsSpec)} }()
+			require.Error(t, cache.Refresh())
+			require.Nil(t, cache.GetDevice("vendor.com/device=dev0"))
+		})
+	}
+}
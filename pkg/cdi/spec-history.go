@@ -0,0 +1,210 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultErrorRetentionLimit is the default maximum number of distinct
+	// errors retained per Spec file.
+	DefaultErrorRetentionLimit = 16
+	// DefaultErrorRetentionAge is the default maximum age of retained errors.
+	DefaultErrorRetentionAge = 24 * time.Hour
+)
+
+// WithErrorRetention returns an option to bound the history of Spec file
+// errors kept by the Cache. At most limit distinct errors are retained per
+// Spec file, and errors last seen longer than maxAge ago are dropped, as
+// is the status of Spec files removed longer than maxAge ago. Non-positive
+// values select DefaultErrorRetentionLimit and DefaultErrorRetentionAge.
+func WithErrorRetention(limit int, maxAge time.Duration) Option {
+	return func(c *Cache) {
+		h := c.getHistory()
+		h.limit = limit
+		if limit <= 0 {
+			h.limit = DefaultErrorRetentionLimit
+		}
+		h.maxAge = maxAge
+		if maxAge <= 0 {
+			h.maxAge = DefaultErrorRetentionAge
+		}
+		h.evict(h.now())
+	}
+}
+
+// SpecError is an error recorded for a Spec file by the Cache. Identical
+// errors seen during consecutive refreshes are recorded once.
+type SpecError struct {
+	// Err is the error.
+	Err error
+	// FirstSeen is the time the error was first seen.
+	FirstSeen time.Time
+	// LastSeen is the time the error was last seen.
+	LastSeen time.Time
+	// Count is the number of refreshes the error was seen during.
+	Count int
+}
+
+// Error returns the error message.
+func (e *SpecError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the recorded error.
+func (e *SpecError) Unwrap() error {
+	return e.Err
+}
+
+// SpecFileStatus is the load history of a Spec file.
+type SpecFileStatus struct {
+	// Path is the path of the Spec file.
+	Path string
+	// LastSuccess is the last time the file was loaded without errors.
+	LastSuccess time.Time
+	// LastFailure is the last time the file had errors.
+	LastFailure time.Time
+	// Errors are the retained errors of the file, oldest first.
+	Errors []*SpecError
+}
+
+// GetSpecFileStatus returns the load history of all Spec files known to
+// the Cache, including files which were removed recently. The history is
+// bounded as set by the WithErrorRetention option.
+func (c *Cache) GetSpecFileStatus() map[string]*SpecFileStatus {
+	c.Lock()
+	defer c.Unlock()
+
+	status := map[string]*SpecFileStatus{}
+	for path, s := range c.getHistory().files {
+		cp := *s
+		cp.Errors = make([]*SpecError, 0, len(s.Errors))
+		for _, e := range s.Errors {
+			ecp := *e
+			cp.Errors = append(cp.Errors, &ecp)
+		}
+		status[path] = &cp
+	}
+	return status
+}
+
+// GetSpecErrorsSince returns the retained errors of Spec files which were
+// seen at or after the given time. The errors are of type *SpecError.
+func (c *Cache) GetSpecErrorsSince(t time.Time) map[string][]error {
+	c.Lock()
+	defer c.Unlock()
+
+	errors := map[string][]error{}
+	for path, s := range c.getHistory().files {
+		for _, e := range s.Errors {
+			if !e.LastSeen.Before(t) {
+				ecp := *e
+				errors[path] = append(errors[path], &ecp)
+			}
+		}
+	}
+	return errors
+}
+
+// errorHistory is the bounded history of Spec file errors.
+type errorHistory struct {
+	limit  int
+	maxAge time.Duration
+	now    func() time.Time
+	files  map[string]*SpecFileStatus
+}
+
+// getHistory returns the error history of the Cache.
+func (c *Cache) getHistory() *errorHistory {
+	if c.history == nil {
+		c.history = &errorHistory{
+			limit:  DefaultErrorRetentionLimit,
+			maxAge: DefaultErrorRetentionAge,
+			now:    time.Now,
+			files:  map[string]*SpecFileStatus{},
+		}
+	}
+	return c.history
+}
+
+// record the outcome of a refresh for the given Spec file paths.
+func (h *errorHistory) record(paths []string, specErrors map[string][]error) {
+	now := h.now()
+
+	for _, path := range paths {
+		s, ok := h.files[path]
+		if !ok {
+			s = &SpecFileStatus{Path: path}
+			h.files[path] = s
+		}
+
+		errs := specErrors[path]
+		if len(errs) == 0 {
+			s.LastSuccess = now
+			continue
+		}
+
+		s.LastFailure = now
+		for _, err := range errs {
+			s.add(err, now)
+		}
+	}
+
+	h.evict(now)
+}
+
+// add an error seen at the given time.
+func (s *SpecFileStatus) add(err error, now time.Time) {
+	msg := err.Error()
+	for i, e := range s.Errors {
+		if e.Err.Error() == msg {
+			e.LastSeen = now
+			e.Count++
+			// keep errors ordered by the time they were last seen
+			copy(s.Errors[i:], s.Errors[i+1:])
+			s.Errors[len(s.Errors)-1] = e
+			return
+		}
+	}
+	s.Errors = append(s.Errors, &SpecError{
+		Err:       err,
+		FirstSeen: now,
+		LastSeen:  now,
+		Count:     1,
+	})
+}
+
+// evict errors and Spec file status beyond the configured bounds.
+func (h *errorHistory) evict(now time.Time) {
+	cutoff := now.Add(-h.maxAge)
+
+	for path, s := range h.files {
+		idx := sort.Search(len(s.Errors), func(i int) bool {
+			return !s.Errors[i].LastSeen.Before(cutoff)
+		})
+		if n := len(s.Errors) - idx; n > h.limit {
+			idx = len(s.Errors) - h.limit
+		}
+		s.Errors = append([]*SpecError(nil), s.Errors[idx:]...)
+
+		if s.LastSuccess.Before(cutoff) && s.LastFailure.Before(cutoff) {
+			delete(h.files, path)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecErrorHistory(t *testing.T) {
+	const (
+		valid = `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+  - name: dev0
+    containerEdits:
+      env:
+        - FOO=BAR
+`
+		invalid = `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+  - name: "dev 0"
+    containerEdits:
+      env:
+        - FOO=BAR
+`
+	)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vendor.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(invalid), 0o644))
+
+	cache := newCache(
+		WithSpecDirs(dir),
+		WithAutoRefresh(false),
+		WithErrorRetention(2, time.Hour),
+	)
+
+	now := time.Now()
+	cache.getHistory().now = func() time.Time { return now }
+
+	t0 := now
+	require.Error(t, cache.Refresh())
+	status := cache.GetSpecFileStatus()[path]
+	require.NotNil(t, status)
+	require.Equal(t, t0, status.LastFailure)
+	require.True(t, status.LastSuccess.IsZero())
+	require.Len(t, status.Errors, 1)
+	require.Equal(t, 2, status.Errors[0].Count)
+	require.Equal(t, t0, status.Errors[0].LastSeen)
+
+	now = now.Add(time.Minute)
+	t1 := now
+	require.NoError(t, os.WriteFile(path, []byte(valid), 0o644))
+	require.NoError(t, cache.Refresh())
+	status = cache.GetSpecFileStatus()[path]
+	require.Equal(t, t1, status.LastSuccess)
+	require.Equal(t, t0, status.LastFailure)
+	require.Len(t, status.Errors, 1)
+	require.Empty(t, cache.GetSpecErrors(cache.GetVendorSpecs("vendor.com")[0]))
+
+	since := cache.GetSpecErrorsSince(t0)
+	require.Len(t, since[path], 1)
+	var specErr *SpecError
+	require.True(t, errors.As(since[path][0], &specErr))
+	require.Empty(t, cache.GetSpecErrorsSince(t1))
+
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, cache.Refresh())
+	status = cache.GetSpecFileStatus()[path]
+	require.Empty(t, status.Errors)
+
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, cache.Refresh())
+	require.Contains(t, cache.GetSpecFileStatus(), path)
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, cache.Refresh())
+	require.NotContains(t, cache.GetSpecFileStatus(), path)
+}
+
+func TestErrorHistoryLimit(t *testing.T) {
+	now := time.Now()
+	h := &errorHistory{
+		limit:  2,
+		maxAge: time.Hour,
+		now:    func() time.Time { return now },
+		files:  map[string]*SpecFileStatus{},
+	}
+
+	for _, msg := range []string{"a", "b", "a", "c", "c"} {
+		now = now.Add(time.Second)
+		h.record([]string{"spec"}, map[string][]error{"spec": {errors.New(msg)}})
+	}
+
+	status := h.files["spec"]
+	require.Len(t, status.Errors, 2)
+	require.Equal(t, "a", status.Errors[0].Error())
+	require.Equal(t, 2, status.Errors[0].Count)
+	require.Equal(t, "c", status.Errors[1].Error())
+	require.Equal(t, 2, status.Errors[1].Count)
+	require.Equal(t, now, status.LastFailure)
+}
@@ -0,0 +1,208 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// ParseSpecStrict parses CDI Spec data like ParseSpec, but also rejects
+// duplicate keys, including those of Specs of newer CDI versions, and keys
+// which only match a Spec field when ignoring case. Errors are located in
+// the Spec data, see SpecSourceError. A Cache parses Specs strictly at the
+// SpecValidationStrict level.
+func ParseSpecStrict(data []byte) (*cdi.Spec, error) {
+	if err := checkSpecKeys(data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CDI Spec: %w", err)
+	}
+	return ParseSpec(data)
+}
+
+// checkSpecKeys checks the keys of the given Spec data for duplicates and
+// for unknown fields. Unknown fields are accepted in Specs of newer CDI
+// versions. Invalid YAML is left to the Spec parser to report.
+func checkSpecKeys(data []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+	for _, n := range doc.Content {
+		c := &specKeyChecker{allowUnknown: isNewerVersion(specVersionOf(n))}
+		if err := c.check(n, reflect.TypeOf(cdi.Spec{}), "", 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// specKeyChecker checks the keys of a Spec document against the Spec types.
+type specKeyChecker struct {
+	allowUnknown bool
+}
+
+// check checks the keys of the given node, found at the given path, and
+// of its children against the given type.
+func (c *specKeyChecker) check(n *yaml.Node, t reflect.Type, path string, depth int) error {
+	if depth > maxAliasDepth {
+		return nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch n.Kind {
+	case yaml.AliasNode:
+		if n.Alias == nil {
+			return nil
+		}
+		return c.check(n.Alias, t, path, depth+1)
+	case yaml.SequenceNode:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return nil
+		}
+		for i, item := range n.Content {
+			if err := c.check(item, t.Elem(), path+"["+strconv.Itoa(i)+"]", depth+1); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		return c.checkMapping(n, t, path, depth)
+	}
+	return nil
+}
+
+// checkMapping checks the keys of the given mapping node and its values.
+func (c *specKeyChecker) checkMapping(n *yaml.Node, t reflect.Type, path string, depth int) error {
+	var fields map[string]reflect.Type
+	switch t.Kind() {
+	case reflect.Struct:
+		fields = jsonFields(t)
+	case reflect.Map:
+	default:
+		return nil
+	}
+
+	seen := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.Tag == "!!merge" {
+			if err := c.checkMerge(value, t, path, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+
+		keyPath := joinSpecPath(path, key.Value)
+		if first, ok := seen[key.Value]; ok {
+			return &SpecSourceError{
+				Path:   keyPath,
+				Line:   key.Line,
+				Column: key.Column,
+				Err: fmt.Errorf("duplicate key %q, first set at line %d, column %d",
+					key.Value, first.Line, first.Column),
+			}
+		}
+		seen[key.Value] = key
+
+		var valueType reflect.Type
+		if fields == nil {
+			valueType = t.Elem()
+		} else {
+			ft, ok := fields[key.Value]
+			if !ok {
+				if c.allowUnknown {
+					continue
+				}
+				err := fmt.Errorf("unknown field %q", key.Value)
+				if name := matchFieldFold(fields, key.Value); name != "" {
+					err = fmt.Errorf("unknown field %q, did you mean %q", key.Value, name)
+				}
+				return &SpecSourceError{Path: keyPath, Line: key.Line, Column: key.Column, Err: err}
+			}
+			valueType = ft
+		}
+		if err := c.check(value, valueType, keyPath, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkMerge checks the mappings merged into a mapping of the given type.
+func (c *specKeyChecker) checkMerge(n *yaml.Node, t reflect.Type, path string, depth int) error {
+	if n.Kind != yaml.SequenceNode {
+		return c.check(n, t, path, depth)
+	}
+	for _, m := range n.Content {
+		if err := c.check(m, t, path, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonFields returns the types of the fields of the given struct type by
+// their JSON names.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// matchFieldFold returns the name of the field matching the given key when
+// ignoring case, or an empty string if there is none.
+func matchFieldFold(fields map[string]reflect.Type, key string) string {
+	for name := range fields {
+		if strings.EqualFold(name, key) {
+			return name
+		}
+	}
+	return ""
+}
+
+// specVersionOf returns the cdiVersion set in the given Spec document node.
+func specVersionOf(n *yaml.Node) string {
+	if n.Kind != yaml.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == "cdiVersion" {
+			return n.Content[i+1].Value
+		}
+	}
+	return ""
+}
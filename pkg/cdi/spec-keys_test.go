@@ -0,0 +1,181 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSpecStrict(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		data       string
+		lenient    bool
+		errPath    string
+		errLine    int
+		errMessage string
+	}{
+		{
+			name: "valid",
+			data: `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+  - name: dev0
+    annotations:
+      a: b
+    containerEdits:
+      env: [A=1]
+`,
+			lenient: true,
+		},
+		{
+			name: "duplicate key",
+			data: `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+  - name: dev0
+    containerEdits:
+      env: [A=1]
+      env: [B=1]
+`,
+			errPath:    "devices[0].containerEdits.env",
+			errLine:    8,
+			errMessage: `duplicate key "env", first set at line 7, column 7`,
+		},
+		{
+			name: "key matching a field ignoring case",
+			data: `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+Kind: other.com/device
+devices:
+  - name: dev0
+    containerEdits:
+      env: [A=1]
+`,
+			lenient:    true,
+			errPath:    "Kind",
+			errLine:    4,
+			errMessage: `unknown field "Kind", did you mean "kind"`,
+		},
+		{
+			name: "duplicate map key in newer version",
+			data: `
+cdiVersion: "1.99.0"
+kind: vendor.com/device
+devices:
+  - name: dev0
+    annotations:
+      a: b
+      a: c
+    containerEdits:
+      env: [A=1]
+`,
+			lenient:    true,
+			errPath:    "devices[0].annotations.a",
+			errLine:    8,
+			errMessage: `duplicate key "a"`,
+		},
+		{
+			name: "unknown field in newer version",
+			data: `
+cdiVersion: "1.99.0"
+kind: vendor.com/device
+devices:
+  - name: dev0
+    futureField: 1
+    containerEdits:
+      env: [A=1]
+`,
+			lenient: true,
+		},
+		{
+			name: "unknown field in merged mapping",
+			data: `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+  - name: dev0
+    containerEdits: &edits
+      env: [A=1]
+  - name: dev1
+    containerEdits:
+      <<: *edits
+      ENV: [B=1]
+`,
+			lenient:    true,
+			errPath:    "devices[1].containerEdits.ENV",
+			errLine:    11,
+			errMessage: `did you mean "env"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseSpec([]byte(tc.data))
+			if tc.lenient {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+
+			raw, err := ParseSpecStrict([]byte(tc.data))
+			if tc.errMessage == "" {
+				require.NoError(t, err)
+				require.NotNil(t, raw)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.errMessage)
+			var srcErr *SpecSourceError
+			require.True(t, errors.As(err, &srcErr))
+			require.Equal(t, tc.errPath, srcErr.Path)
+			require.Equal(t, tc.errLine, srcErr.Line)
+		})
+	}
+}
+
+func TestCacheStrictParsing(t *testing.T) {
+	const data = `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+  - name: dev0
+    containerEdits:
+      env: [A=1]
+      Env: [B=1]
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vendor.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+	cache, err := NewCache(WithSpecDirs(dir), WithAutoRefresh(false))
+	require.NoError(t, err)
+	require.Equal(t, []string{"vendor.com/device=dev0"}, cache.ListDevices())
+
+	require.NoError(t, cache.Configure(WithSpecContentValidation(SpecValidationStrict)))
+	require.Error(t, cache.Refresh())
+	require.Empty(t, cache.ListDevices())
+	errs := cache.GetErrors()[path]
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Error(), `line 8, column 7: devices[0].containerEdits.Env: unknown field "Env", did you mean "env"`)
+}
@@ -0,0 +1,100 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// DefaultSpecSizeLimit is the default maximum size of Spec files read by
+// a Cache.
+const DefaultSpecSizeLimit = 16 << 20
+
+var (
+	// ErrSpecTooLarge is returned for Spec files exceeding the size limit.
+	ErrSpecTooLarge = errors.New("CDI Spec file too large")
+)
+
+// WithSpecSizeLimit returns an option to set the maximum size of Spec
+// files read by the Cache. Spec directories are usually writable only by
+// root, but the Cache should still not exhaust memory on bogus files. Files
+// larger than the limit are rejected with ErrSpecTooLarge. A zero limit
+// restores DefaultSpecSizeLimit and a negative limit disables the check.
+func WithSpecSizeLimit(limit int64) Option {
+	return func(c *Cache) {
+		c.specSizeLimit = limit
+	}
+}
+
+// getSpecSizeLimit returns the Spec size limit of the Cache.
+func (c *Cache) getSpecSizeLimit() int64 {
+	if c.specSizeLimit == 0 {
+		return DefaultSpecSizeLimit
+	}
+	return c.specSizeLimit
+}
+
+// readSpecFile reads the named Spec file, rejecting files larger than the
+// given limit, unless the limit is negative. If fsys is nil, the name is
+// the path of the Spec file. Files on the host are locked while reading,
+// ErrSpecLocked is returned for files locked by someone else.
+func readSpecFile(fsys fs.FS, name string, limit int64) ([]byte, error) {
+	var (
+		f   fs.File
+		err error
+	)
+	if fsys == nil {
+		f, err = os.Open(name)
+	} else {
+		f, err = fsys.Open(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// don't read files being written
+	if osf, ok := f.(*os.File); ok {
+		unlock, err := lockFile(osf, false)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	if limit < 0 {
+		return io.ReadAll(f)
+	}
+
+	if info, err := f.Stat(); err == nil && info.Size() > limit {
+		return nil, fmt.Errorf("%w (%d > %d bytes)", ErrSpecTooLarge, info.Size(), limit)
+	}
+
+	// the file might grow while being read, so limit the read itself too
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w (more than %d bytes)", ErrSpecTooLarge, limit)
+	}
+	return data, nil
+}
@@ -0,0 +1,101 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecSizeLimit(t *testing.T) {
+	spec := `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+  - name: dev0
+    containerEdits:
+      env:
+        - FOO=BAR
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vendor.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(spec), 0o644))
+
+	for _, tc := range []struct {
+		name    string
+		limit   int64
+		tooBig  bool
+		devices []string
+	}{
+		{
+			name:    "default limit",
+			devices: []string{"vendor.com/device=dev0"},
+		},
+		{
+			name:   "limit exceeded",
+			limit:  int64(len(spec) - 1),
+			tooBig: true,
+		},
+		{
+			name:    "limit not exceeded",
+			limit:   int64(len(spec)),
+			devices: []string{"vendor.com/device=dev0"},
+		},
+		{
+			name:    "limit disabled",
+			limit:   -1,
+			devices: []string{"vendor.com/device=dev0"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := newCache(
+				WithSpecDirs(dir),
+				WithAutoRefresh(false),
+				WithSpecSizeLimit(tc.limit),
+			)
+			require.Equal(t, tc.devices, cache.ListDevices())
+
+			errs := cache.GetErrors()[path]
+			if !tc.tooBig {
+				require.Empty(t, errs)
+				return
+			}
+			require.Len(t, errs, 1)
+			require.True(t, errors.Is(errs[0], ErrSpecTooLarge))
+		})
+	}
+}
+
+func TestReadSpecFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("x", 100)), 0o644))
+
+	data, err := readSpecFile(nil, path, 100)
+	require.NoError(t, err)
+	require.Len(t, data, 100)
+
+	_, err = readSpecFile(nil, path, 99)
+	require.True(t, errors.Is(err, ErrSpecTooLarge))
+
+	_, err = readSpecFile(nil, filepath.Join(filepath.Dir(path), "missing"), 100)
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,149 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+const (
+	// specLockTimeout is the minimum time to retry reading a locked Spec
+	// file for, even if WithSpecReadRetry is not used.
+	specLockTimeout = time.Second
+	// specReadRetryDelay is the initial delay of Spec read retries.
+	specReadRetryDelay = 10 * time.Millisecond
+)
+
+// ErrSpecLocked is the error recorded for a Spec file which could not be
+// read because a producer keeps it locked while writing it.
+var ErrSpecLocked = errors.New("CDI Spec file locked")
+
+// Spec files are coordinated using advisory file locks (flock(2) on
+// Unix). The Cache tries to take a shared lock while reading a Spec file.
+// Producers which write Spec files in place, instead of atomically renaming
+// them into place, should hold an exclusive lock while writing, so that the
+// Cache does not read partially written files. The Cache never waits for
+// locks while it is locked itself. A Spec file found locked is reported as
+// an ErrSpecLocked error and read again a bit later, like Spec files which
+// fail to parse with WithSpecReadRetry. So misbehaving producers can't
+// block the Cache.
+
+// WithSpecReadRetry returns an option to retry reading Spec files which
+// fail to parse or validate for up to the given time, with exponential
+// backoff. This lets the Cache skip over Spec files caught while being
+// written in place by producers not using locks, instead of reporting
+// them as invalid until the next refresh. Retries are done in the
+// background, by refreshing the Cache for the failed Spec files, so the
+// refresh which first reads an incomplete Spec file still reports it as
+// invalid. A zero or negative window, the default, disables retries.
+func WithSpecReadRetry(window time.Duration) Option {
+	return func(c *Cache) {
+		c.readRetryWindow = window
+	}
+}
+
+// readRetry is the state of retrying to read a Spec file.
+type readRetry struct {
+	deadline time.Time
+	delay    time.Duration
+	timer    *time.Timer
+}
+
+// scheduleReadRetries schedules reading the given Spec files again, if
+// reading them just failed for a possibly transient reason, within the
+// retry window of the Cache. Locked Spec files are always retried for a
+// while. Spec files from file systems are not retried. It must be called
+// with the Cache locked.
+func (c *Cache) scheduleReadRetries(files []*specFile) {
+	now := time.Now()
+	for _, f := range files {
+		r, ok := c.readRetries[f.path]
+		if ok {
+			r.timer.Stop()
+		}
+
+		window := c.readRetryWindow
+		if errors.Is(f.err, ErrSpecLocked) && window < specLockTimeout {
+			window = specLockTimeout
+		}
+		if f.err == nil || !f.retry || f.fsys != nil || window <= 0 {
+			delete(c.readRetries, f.path)
+			continue
+		}
+
+		if !ok {
+			r = &readRetry{
+				deadline: now.Add(window),
+				delay:    specReadRetryDelay,
+			}
+		} else {
+			r.delay *= 2
+		}
+		if now.Add(r.delay).After(r.deadline) {
+			delete(c.readRetries, f.path)
+			continue
+		}
+
+		if c.readRetries == nil {
+			c.readRetries = map[string]*readRetry{}
+		}
+		c.readRetries[f.path] = r
+		path := f.path
+		r.timer = time.AfterFunc(r.delay, func() { c.retryRead(path) })
+	}
+}
+
+// retryRead refreshes the Cache for a Spec file scheduled to be read again.
+func (c *Cache) retryRead(path string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, ok := c.readRetries[path]; !ok {
+		return
+	}
+	if c.deferRefresh(false, path) {
+		return
+	}
+	_ = c.refreshPaths(context.Background(), path) // errors are recorded
+}
+
+// stopReadRetries cancels all scheduled Spec file read retries. It must be
+// called with the Cache locked.
+func (c *Cache) stopReadRetries() {
+	for _, r := range c.readRetries {
+		r.timer.Stop()
+	}
+	c.readRetries = nil
+}
+
+// lockFile locks the given file, exclusively or shared, without waiting.
+// It returns a function to unlock the file, or ErrSpecLocked if the file
+// is locked by someone else. If the file system does not support locking,
+// the file is not locked.
+func lockFile(f *os.File, exclusive bool) (func(), error) {
+	locked, err := tryLockFile(f, exclusive)
+	switch {
+	case err != nil:
+		return func() {}, nil
+	case !locked:
+		return nil, ErrSpecLocked
+	}
+	return func() { unlockFile(f) }, nil
+}
@@ -0,0 +1,151 @@
+//go:build !windows
+// +build !windows
+
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const lockingTestSpec = `
+cdiVersion: "0.3.0"
+kind: "vendor.com/device"
+devices:
+  - name: "dev0"
+    containerEdits:
+      env:
+        - "DEVICE=dev0"
+`
+
+func TestSpecFileLocking(t *testing.T) {
+	dir, err := createSpecDirs(t, map[string]string{"vendor.yaml": lockingTestSpec}, nil)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "etc", "vendor.yaml")
+
+	t.Run("locked files are read again later", func(t *testing.T) {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0o644)
+		require.NoError(t, err)
+		defer f.Close()
+		unlock, err := lockFile(f, true)
+		require.NoError(t, err)
+
+		half := len(lockingTestSpec) / 2
+		_, err = f.WriteString(lockingTestSpec[:half])
+		require.NoError(t, err)
+
+		cache := newCache(
+			WithSpecDirs(filepath.Join(dir, "etc")),
+			WithAutoRefresh(false),
+		)
+		t.Cleanup(func() { cache.Close() })
+
+		err = cache.Refresh()
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrSpecLocked))
+		require.Empty(t, cache.ListDevices())
+
+		_, err = f.WriteString(lockingTestSpec[half:])
+		require.NoError(t, err)
+		unlock()
+
+		require.Eventually(t, func() bool {
+			return len(cache.ListDevices()) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		require.Empty(t, cache.GetErrors())
+	})
+
+	t.Run("writes don't wait for readers", func(t *testing.T) {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+		unlock, err := lockFile(f, false)
+		require.NoError(t, err)
+		defer unlock()
+
+		cache := newCache(
+			WithSpecDirs(filepath.Join(dir, "etc")),
+			WithAutoRefresh(false),
+		)
+		require.NoError(t, cache.WriteSpec(&cdi.Spec{
+			Version: cdi.CurrentVersion,
+			Kind:    "vendor.com/device",
+			Devices: []cdi.Device{
+				{
+					Name:           "dev0",
+					ContainerEdits: cdi.ContainerEdits{Env: []string{"DEVICE=dev0"}},
+				},
+			},
+		}, "vendor"))
+	})
+}
+
+func TestSpecReadRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		window time.Duration
+		valid  bool
+	}{
+		{
+			name: "no retries",
+		},
+		{
+			name:   "retried",
+			window: 5 * time.Second,
+			valid:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			truncated := lockingTestSpec[:len(lockingTestSpec)-12]
+			dir, err := createSpecDirs(t, map[string]string{"vendor.yaml": truncated}, nil)
+			require.NoError(t, err)
+			path := filepath.Join(dir, "etc", "vendor.yaml")
+
+			cache := newCache(
+				WithSpecDirs(filepath.Join(dir, "etc")),
+				WithAutoRefresh(false),
+				WithSpecReadRetry(tc.window),
+			)
+			t.Cleanup(func() { cache.Close() })
+
+			require.Error(t, cache.Refresh())
+			require.Empty(t, cache.ListDevices())
+
+			// complete the Spec file without locking it
+			require.NoError(t, os.WriteFile(path, []byte(lockingTestSpec), 0o644))
+
+			if tc.valid {
+				require.Eventually(t, func() bool {
+					return len(cache.ListDevices()) == 1
+				}, 5*time.Second, 10*time.Millisecond)
+				require.Empty(t, cache.GetErrors())
+			} else {
+				require.Never(t, func() bool {
+					return len(cache.ListDevices()) > 0
+				}, 200*time.Millisecond, 10*time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"os"
+	"time"
+)
+
+// SpecProvenance identifies exactly which version of which Spec file a
+// Spec, or a device, was loaded from.
+type SpecProvenance struct {
+	// Path is the path of the Spec file. In-memory Specs have
+	// pseudo-paths starting with "memory:".
+	Path string `json:"path"`
+	// Document is the index of the Spec document within its Spec file.
+	Document int `json:"document,omitempty"`
+	// ModTime is the modification time of the Spec file when it was read.
+	ModTime time.Time `json:"modTime"`
+	// Digest is the digest of the Spec content.
+	Digest string `json:"digest,omitempty"`
+}
+
+// GetProvenance returns the provenance of this Spec.
+func (s *Spec) GetProvenance() SpecProvenance {
+	return SpecProvenance{
+		Path:     s.GetPath(),
+		Document: s.GetDocument(),
+		ModTime:  s.GetModTime(),
+		Digest:   s.GetDigest(),
+	}
+}
+
+// GetProvenance returns the provenance of the Spec this device was
+// loaded from.
+func (d *Device) GetProvenance() SpecProvenance {
+	return d.GetSpec().GetProvenance()
+}
+
+// setSpecModTime sets the modification time of the given Specs, read
+// from the given path, to that of the file.
+func setSpecModTime(path string, specs ...*Spec) {
+	if len(specs) == 0 {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	for _, spec := range specs {
+		spec.modTime = info.ModTime()
+	}
+}
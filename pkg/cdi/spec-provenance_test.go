@@ -0,0 +1,83 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestSpecProvenance(t *testing.T) {
+	const specData = `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+  - name: dev0
+    containerEdits:
+      env:
+        - DEV0=0
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vendor.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(specData), 0o644))
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+	spec, err := ReadSpec(path, 0)
+	require.NoError(t, err)
+	require.True(t, modTime.Equal(spec.GetModTime()))
+	require.Equal(t, specDigest([]byte(specData)), spec.GetDigest())
+
+	specs, err := ReadSpecs(path, 0)
+	require.NoError(t, err)
+	require.Len(t, specs, 1)
+	require.True(t, modTime.Equal(specs[0].GetModTime()))
+
+	cache, err := NewCache(WithSpecDirs(dir), WithAutoRefresh(false))
+	require.NoError(t, err)
+
+	dev := cache.GetDevice("vendor.com/device=dev0")
+	require.NotNil(t, dev)
+	provenance := dev.GetProvenance()
+	require.Equal(t, path, provenance.Path)
+	require.Equal(t, 0, provenance.Document)
+	require.True(t, modTime.Equal(provenance.ModTime))
+	require.Equal(t, spec.GetDigest(), provenance.Digest)
+
+	before := time.Now()
+	require.NoError(t, cache.AddSpec(&cdi.Spec{
+		Version: "0.3.0",
+		Kind:    "other.com/device",
+		Devices: []cdi.Device{
+			{
+				Name:           "dev0",
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"OTHER=0"}},
+			},
+		},
+	}, 0))
+	provenance = cache.GetDevice("other.com/device=dev0").GetProvenance()
+	require.Equal(t, "memory:other.com-device", provenance.Path)
+	require.False(t, provenance.ModTime.Before(before))
+	require.NotEmpty(t, provenance.Digest)
+
+	require.True(t, (&Spec{}).GetModTime().IsZero())
+}
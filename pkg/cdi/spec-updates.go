@@ -0,0 +1,187 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"time"
+)
+
+// DefaultUpdateBatchTimeout is the default time a batch of Spec updates
+// can stay open (see WithUpdateBatchTimeout).
+const DefaultUpdateBatchTimeout = 5 * time.Minute
+
+var (
+	errUpdatesCommitted = errors.New("CDI Spec updates already committed")
+	errUpdatesClosed    = errors.New("CDI Spec updates already closed")
+	errUpdatesExpired   = errors.New("CDI Spec updates expired")
+)
+
+// SpecUpdate is a batch of Spec updates started by BeginUpdates().
+type SpecUpdate struct {
+	cache  *Cache
+	timer  *time.Timer
+	closed error // why the batch is closed, nil while it is open
+}
+
+// WithUpdateBatchTimeout returns an option to set how long a batch of
+// Spec updates can stay open. A batch open for longer is considered
+// abandoned and is closed by the Cache, refreshing it for the recorded
+// changes, so that a producer which never commits a batch can't keep the
+// Cache from refreshing. Committing an expired batch returns an error.
+// A timeout of 0 selects DefaultUpdateBatchTimeout. A negative timeout
+// disables it.
+func WithUpdateBatchTimeout(timeout time.Duration) Option {
+	return func(c *Cache) {
+		c.updateBatchTimeout = timeout
+	}
+}
+
+// deferredRefresh records the refresh needed for the changes made while
+// batches of Spec updates are open.
+type deferredRefresh struct {
+	full  bool
+	paths map[string]struct{}
+}
+
+// BeginUpdates starts a batch of Spec updates. While any batch is open
+// the Cache is not refreshed for changes to Specs, neither by WriteSpecs(),
+// AddSpec() and RemoveSpec(), nor for changes detected by monitoring the
+// Spec directories in auto-refresh mode. The changes are recorded instead,
+// together with the Spec files written by WriteSpec(), and the Cache is
+// refreshed once for all of them when the last open batch is committed.
+// This lets producers which write many Spec files, possibly concurrently,
+// for instance DRA drivers, avoid redundant refreshes and the devices of
+// a partially updated set of Specs becoming visible.
+//
+// Batches can be open concurrently. Every batch must be committed or
+// closed, the Cache is not refreshed for any changes until then, or until
+// the batch expires (see WithUpdateBatchTimeout). Deferring Close() right
+// after BeginUpdates() ensures that the batch is closed on all paths:
+//
+//	update := cache.BeginUpdates()
+//	defer update.Close()
+func (c *Cache) BeginUpdates() *SpecUpdate {
+	c.Lock()
+	defer c.Unlock()
+
+	c.openUpdates++
+	u := &SpecUpdate{cache: c}
+
+	timeout := c.updateBatchTimeout
+	if timeout == 0 {
+		timeout = DefaultUpdateBatchTimeout
+	}
+	if timeout > 0 {
+		u.timer = time.AfterFunc(timeout, u.expire)
+	}
+
+	return u
+}
+
+// Commit closes the batch of Spec updates. If this was the last open
+// batch, the Cache is refreshed for all recorded changes, returning any
+// errors encountered, like Refresh() does. Committing a batch more than
+// once, or after it was closed or has expired, is an error.
+func (u *SpecUpdate) Commit() error {
+	u.cache.Lock()
+	defer u.cache.Unlock()
+
+	if u.closed != nil {
+		return u.closed
+	}
+	return u.close(errUpdatesCommitted)
+}
+
+// Close closes the batch of Spec updates unless it is already closed,
+// refreshing the Cache for the recorded changes like Commit() does.
+// Changes already made are not undone. Closing a committed or already
+// closed batch does nothing, so Close() can be deferred to make sure a
+// batch is closed if the producer fails before committing it.
+func (u *SpecUpdate) Close() error {
+	u.cache.Lock()
+	defer u.cache.Unlock()
+
+	if u.closed != nil {
+		return nil
+	}
+	return u.close(errUpdatesClosed)
+}
+
+// expire closes the batch of Spec updates once it has been open for too
+// long. Any errors of the refresh are recorded by the Cache.
+func (u *SpecUpdate) expire() {
+	u.cache.Lock()
+	defer u.cache.Unlock()
+
+	if u.closed == nil {
+		_ = u.close(errUpdatesExpired)
+	}
+}
+
+// close closes the batch of Spec updates for the given reason, refreshing
+// the Cache if this was the last open batch. It must be called with the
+// Cache locked.
+func (u *SpecUpdate) close(reason error) error {
+	c := u.cache
+
+	u.closed = reason
+	if u.timer != nil {
+		u.timer.Stop()
+	}
+
+	c.openUpdates--
+	if c.openUpdates > 0 || c.deferred == nil {
+		return nil
+	}
+
+	deferred := c.deferred
+	c.deferred = nil
+
+	if deferred.full {
+		return c.refresh()
+	}
+	paths := make([]string, 0, len(deferred.paths))
+	for path := range deferred.paths {
+		paths = append(paths, path)
+	}
+	return c.refreshPaths(context.Background(), paths...)
+}
+
+// deferRefresh records a refresh of the given Spec files, or a full one,
+// if any batch of Spec updates is open. Without any paths it records a
+// refresh which only resolves the already parsed Specs again. It returns
+// false if no batch is open. It must be called with the Cache locked.
+func (c *Cache) deferRefresh(full bool, paths ...string) bool {
+	if c.openUpdates == 0 {
+		return false
+	}
+	if c.deferred == nil {
+		c.deferred = &deferredRefresh{
+			paths: map[string]struct{}{},
+		}
+	}
+	if full {
+		c.deferred.full = true
+	}
+	for _, path := range paths {
+		c.deferred.paths[filepath.Clean(path)] = struct{}{}
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func mkUpdateSpec(kind, device string) *cdi.Spec {
+	return &cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    kind,
+		Devices: []cdi.Device{
+			{
+				Name:           device,
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"DEVICE=" + device}},
+			},
+		},
+	}
+}
+
+func TestSpecUpdates(t *testing.T) {
+	dir, err := mkTestDir(t, map[string]map[string]string{"etc": {}})
+	require.NoError(t, err)
+
+	cache, err := NewCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+	)
+	require.NoError(t, err)
+
+	outer := cache.BeginUpdates()
+	inner := cache.BeginUpdates()
+
+	require.NoError(t, cache.WriteSpecs(map[string]*cdi.Spec{
+		"vendor1": mkUpdateSpec("vendor1.com/dev", "dev0"),
+	}))
+	require.NoError(t, cache.WriteSpec(mkUpdateSpec("vendor2.com/dev", "dev0"), "vendor2"))
+	require.NoError(t, cache.AddSpec(mkUpdateSpec("vendor3.com/dev", "dev0"), 0))
+	require.Empty(t, cache.ListDevices())
+
+	require.NoError(t, inner.Commit())
+	require.Error(t, inner.Commit())
+	require.Empty(t, cache.ListDevices())
+
+	require.NoError(t, outer.Commit())
+	require.Equal(t, []string{"vendor1.com/dev=dev0", "vendor2.com/dev=dev0", "vendor3.com/dev=dev0"},
+		cache.ListDevices())
+
+	update := cache.BeginUpdates()
+	require.NoError(t, cache.RemoveSpec("vendor2"))
+	require.NoError(t, cache.RemoveSpec(GenerateSpecName("vendor3.com", "dev")))
+	require.Len(t, cache.ListDevices(), 3)
+	require.NoError(t, update.Commit())
+	require.Equal(t, []string{"vendor1.com/dev=dev0"}, cache.ListDevices())
+
+	// without open batches changes take effect as usual
+	require.NoError(t, cache.AddSpec(mkUpdateSpec("vendor3.com/dev", "dev0"), 0))
+	require.Len(t, cache.ListDevices(), 2)
+}
+
+func TestSpecUpdatesAutoRefresh(t *testing.T) {
+	dir, err := mkTestDir(t, map[string]map[string]string{"etc": {}})
+	require.NoError(t, err)
+
+	cache, err := NewCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(true),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { cache.Close() })
+
+	update := cache.BeginUpdates()
+	for _, vendor := range []string{"vendor1", "vendor2", "vendor3"} {
+		require.NoError(t, cache.WriteSpec(mkUpdateSpec(vendor+".com/dev", "dev0"), vendor))
+	}
+	require.Never(t, func() bool {
+		return len(cache.ListDevices()) > 0
+	}, 200*time.Millisecond, 10*time.Millisecond)
+
+	require.NoError(t, update.Commit())
+	require.Len(t, cache.ListDevices(), 3)
+}
+
+func TestSpecUpdatesClose(t *testing.T) {
+	dir, err := mkTestDir(t, map[string]map[string]string{"etc": {}})
+	require.NoError(t, err)
+
+	cache, err := NewCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+	)
+	require.NoError(t, err)
+
+	writeSpecs := func(vendor string, fail bool) error {
+		update := cache.BeginUpdates()
+		defer update.Close()
+
+		require.NoError(t, cache.WriteSpec(mkUpdateSpec(vendor+".com/dev", "dev0"), vendor))
+		if fail {
+			return errors.New("failed")
+		}
+		return update.Commit()
+	}
+
+	require.Error(t, writeSpecs("vendor1", true))
+	require.Equal(t, []string{"vendor1.com/dev=dev0"}, cache.ListDevices())
+
+	require.NoError(t, writeSpecs("vendor2", false))
+	require.Equal(t, []string{"vendor1.com/dev=dev0", "vendor2.com/dev=dev0"}, cache.ListDevices())
+
+	update := cache.BeginUpdates()
+	require.NoError(t, update.Close())
+	require.NoError(t, update.Close())
+	require.Error(t, update.Commit())
+}
+
+func TestSpecUpdatesExpired(t *testing.T) {
+	dir, err := mkTestDir(t, map[string]map[string]string{"etc": {}})
+	require.NoError(t, err)
+
+	cache, err := NewCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+		WithUpdateBatchTimeout(500*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	// an abandoned batch must not keep the Cache from refreshing
+	abandoned := cache.BeginUpdates()
+	require.NoError(t, cache.WriteSpec(mkUpdateSpec("vendor1.com/dev", "dev0"), "vendor1"))
+	require.Empty(t, cache.ListDevices())
+
+	require.Eventually(t, func() bool {
+		return len(cache.ListDevices()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	err = abandoned.Commit()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expired")
+
+	require.NoError(t, cache.WriteSpec(mkUpdateSpec("vendor2.com/dev", "dev0"), "vendor2"))
+	require.NoError(t, cache.Refresh())
+	require.Len(t, cache.ListDevices(), 2)
+}
@@ -0,0 +1,217 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"tags.cncf.io/container-device-interface/internal/validation"
+	"tags.cncf.io/container-device-interface/schema"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// SpecValidationLevel selects how the content of Specs is validated when a
+// Cache reads or writes them. Independent of the level, Specs are always
+// checked as far as necessary to use them, for instance their version and
+// the names of their vendor, class and devices.
+type SpecValidationLevel int
+
+const (
+	// SpecValidationDefault validates Specs using the validator set by
+	// WithSpecValidator(), if any.
+	SpecValidationDefault SpecValidationLevel = iota
+	// SpecValidationOff trusts Spec producers. No validation is done
+	// beyond the checks necessary to use the Specs. The validator set by
+	// WithSpecValidator() is not used.
+	SpecValidationOff
+	// SpecValidationSchema validates Specs against the builtin JSON
+	// schema only. The validator set by WithSpecValidator() is not used.
+	SpecValidationSchema
+	// SpecValidationStrict validates Specs against the builtin JSON
+	// schema, using the validator set by WithSpecValidator(), if any, and
+	// with additional checks of annotations and paths. Annotations must
+	// not use the keys reserved for CDI device requests or injection
+	// results, other than PriorityAnnotation and
+	// SharedContainerPathsAnnotation. Device node, hook and mount
+	// container paths must be absolute, as must the host paths of device
+	// nodes and bind mounts. No two devices of a Spec may inject the same
+	// container path, unless it is shared using
+	// SharedContainerPathsAnnotation. Spec data is parsed strictly, rejecting
+	// duplicate keys (see ParseSpecStrict).
+	SpecValidationStrict
+)
+
+// String returns the name of the SpecValidationLevel.
+func (l SpecValidationLevel) String() string {
+	switch l {
+	case SpecValidationDefault:
+		return "default"
+	case SpecValidationOff:
+		return "off"
+	case SpecValidationSchema:
+		return "schema"
+	case SpecValidationStrict:
+		return "strict"
+	}
+	return fmt.Sprintf("SpecValidationLevel(%d)", int(l))
+}
+
+// ParseSpecValidationLevel parses the name of a SpecValidationLevel.
+func ParseSpecValidationLevel(name string) (SpecValidationLevel, error) {
+	for _, l := range []SpecValidationLevel{
+		SpecValidationDefault,
+		SpecValidationOff,
+		SpecValidationSchema,
+		SpecValidationStrict,
+	} {
+		if name == l.String() {
+			return l, nil
+		}
+	}
+	return SpecValidationDefault, fmt.Errorf("invalid Spec validation level %q", name)
+}
+
+// WithSpecContentValidation returns an option to set the level of Spec
+// content validation applied by the Cache when refreshing and when writing
+// Specs with WriteSpec(), WriteSpecs() or AddSpec(). Unknown levels are
+// ignored and SpecValidationDefault is used instead.
+func WithSpecContentValidation(level SpecValidationLevel) Option {
+	return func(c *Cache) {
+		switch level {
+		case SpecValidationOff, SpecValidationSchema, SpecValidationStrict:
+			c.specValidation = level
+		default:
+			c.specValidation = SpecValidationDefault
+		}
+	}
+}
+
+// WithSpecValidator returns an option to set a function for validating the
+// content of Specs, for instance one created by the validate package. The
+// Cache uses the function whenever it loads or writes a Spec, unless this
+// is disabled by WithSpecContentValidation(). Setting a nil function
+// disables it.
+func WithSpecValidator(fn func(*cdi.Spec) error) Option {
+	return func(c *Cache) {
+		c.specValidator = fn
+	}
+}
+
+// validateSpec validates the content of the given Spec at the level set
+// for the Cache.
+func (c *Cache) validateSpec(raw *cdi.Spec) error {
+	switch c.specValidation {
+	case SpecValidationOff:
+		return nil
+	case SpecValidationSchema:
+		return validateSpecSchema(raw)
+	case SpecValidationStrict:
+		if err := validateSpecSchema(raw); err != nil {
+			return err
+		}
+		if err := runSpecValidator(c.specValidator, raw); err != nil {
+			return err
+		}
+		if err := validateSpecStrict(raw); err != nil {
+			return fmt.Errorf("Spec validation failed: %w", err)
+		}
+		return nil
+	}
+	return runSpecValidator(c.specValidator, raw)
+}
+
+// validateSpecSchema validates the Spec against the builtin JSON schema
+// for its CDI version (see schema.ForSpecVersion).
+func validateSpecSchema(raw *cdi.Spec) error {
+	if err := schema.ForSpecVersion(raw.Version).ValidateType(raw); err != nil {
+		return fmt.Errorf("Spec validation failed: %w", err)
+	}
+	return nil
+}
+
+// validateSpecStrict performs the additional checks of strict validation.
+func validateSpecStrict(raw *cdi.Spec) error {
+	if err := validateStrictAnnotations(raw.Kind, raw.Annotations); err != nil {
+		return err
+	}
+	if err := validateStrictEdits(raw.Kind, &raw.ContainerEdits); err != nil {
+		return err
+	}
+	for _, d := range raw.Devices {
+		name := raw.Kind + "=" + d.Name
+		if err := validateStrictAnnotations(name, d.Annotations); err != nil {
+			return err
+		}
+		if err := validateStrictEdits(name, &d.ContainerEdits); err != nil {
+			return err
+		}
+	}
+	if err := validation.ValidateContainerPaths(raw); err != nil {
+		return fmt.Errorf("%s: %w", raw.Kind, err)
+	}
+	return nil
+}
+
+// validateStrictAnnotations checks that the annotations of the named Spec
+// or device do not use reserved keys.
+func validateStrictAnnotations(name string, annotations map[string]string) error {
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch {
+		case key == PriorityAnnotation, key == SharedContainerPathsAnnotation:
+		case strings.HasPrefix(key, AnnotationPrefix),
+			strings.HasPrefix(key, InjectionAnnotationPrefix):
+			return fmt.Errorf("%s: reserved annotation key %q", name, key)
+		}
+	}
+	return nil
+}
+
+// validateStrictEdits checks that the paths in the edits of the named Spec
+// or device are absolute.
+func validateStrictEdits(name string, e *cdi.ContainerEdits) error {
+	for _, d := range e.DeviceNodes {
+		if !filepath.IsAbs(d.Path) {
+			return fmt.Errorf("%s: device node path %q is not absolute", name, d.Path)
+		}
+		if d.HostPath != "" && !filepath.IsAbs(d.HostPath) {
+			return fmt.Errorf("%s: device node host path %q is not absolute", name, d.HostPath)
+		}
+	}
+	for _, h := range e.Hooks {
+		if !filepath.IsAbs(h.Path) {
+			return fmt.Errorf("%s: hook path %q is not absolute", name, h.Path)
+		}
+	}
+	for _, m := range e.Mounts {
+		if !filepath.IsAbs(m.ContainerPath) {
+			return fmt.Errorf("%s: mount container path %q is not absolute", name, m.ContainerPath)
+		}
+		if isBindMount(m) && !filepath.IsAbs(m.HostPath) {
+			return fmt.Errorf("%s: bind mount host path %q is not absolute", name, m.HostPath)
+		}
+	}
+	return nil
+}
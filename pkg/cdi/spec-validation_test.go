@@ -0,0 +1,241 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestSpecContentValidation(t *testing.T) {
+	const (
+		valid    = "valid"
+		rejected = "rejected by validator"
+		relative = "relative mount path"
+		reserved = "reserved annotation"
+		shared   = "shared container path"
+		unshared = "duplicate container path"
+	)
+
+	sharedPathSpec := func(kind string, annotations map[string]string) *cdi.Spec {
+		spec := &cdi.Spec{
+			Version:     cdi.CurrentVersion,
+			Kind:        kind,
+			Annotations: annotations,
+		}
+		for _, name := range []string{"dev0", "dev1"} {
+			spec.Devices = append(spec.Devices, cdi.Device{
+				Name: name,
+				ContainerEdits: cdi.ContainerEdits{
+					Mounts: []*cdi.Mount{
+						{HostPath: "/opt/vendor/" + name, ContainerPath: "/usr/lib/vendor"},
+					},
+				},
+			})
+		}
+		return spec
+	}
+
+	specs := map[string]*cdi.Spec{
+		valid: {
+			Version: cdi.CurrentVersion,
+			Kind:    "vendor.com/valid",
+			Devices: []cdi.Device{
+				{
+					Name: "dev0",
+					ContainerEdits: cdi.ContainerEdits{
+						Mounts: []*cdi.Mount{
+							{HostPath: "/usr/lib/vendor", ContainerPath: "/usr/lib/vendor"},
+						},
+					},
+				},
+			},
+		},
+		rejected: {
+			Version:     cdi.CurrentVersion,
+			Kind:        "vendor.com/rejected",
+			Annotations: map[string]string{"reject": "true"},
+			Devices: []cdi.Device{
+				{
+					Name:           "dev0",
+					ContainerEdits: cdi.ContainerEdits{Env: []string{"A=1"}},
+				},
+			},
+		},
+		relative: {
+			Version: cdi.CurrentVersion,
+			Kind:    "vendor.com/relative",
+			Devices: []cdi.Device{
+				{
+					Name: "dev0",
+					ContainerEdits: cdi.ContainerEdits{
+						Mounts: []*cdi.Mount{
+							{HostPath: "usr/lib/vendor", ContainerPath: "/usr/lib/vendor"},
+						},
+					},
+				},
+			},
+		},
+		reserved: {
+			Version: cdi.CurrentVersion,
+			Kind:    "vendor.com/reserved",
+			Devices: []cdi.Device{
+				{
+					Name:           "dev0",
+					Annotations:    map[string]string{InjectedDevicesAnnotation: "vendor.com/reserved=dev0"},
+					ContainerEdits: cdi.ContainerEdits{Env: []string{"A=1"}},
+				},
+			},
+		},
+		shared: sharedPathSpec("vendor.com/shared", map[string]string{
+			SharedContainerPathsAnnotation: "/usr/lib/vendor",
+		}),
+		unshared: sharedPathSpec("vendor.com/unshared", nil),
+	}
+
+	validator := WithSpecValidator(func(spec *cdi.Spec) error {
+		if spec.Annotations["reject"] == "true" {
+			return errors.New("rejected")
+		}
+		return nil
+	})
+
+	for _, tc := range []struct {
+		level  SpecValidationLevel
+		failed []string
+	}{
+		{
+			level:  SpecValidationDefault,
+			failed: []string{rejected},
+		},
+		{
+			level: SpecValidationOff,
+		},
+		{
+			level: SpecValidationSchema,
+		},
+		{
+			level:  SpecValidationStrict,
+			failed: []string{rejected, relative, reserved, unshared},
+		},
+	} {
+		t.Run(tc.level.String(), func(t *testing.T) {
+			names := []string{valid, rejected, relative, reserved, shared, unshared}
+
+			cache := newCache(
+				WithSpecDirs(),
+				WithAutoRefresh(false),
+				WithSpecContentValidation(tc.level),
+				validator,
+			)
+			var failed []string
+			for _, name := range names {
+				if err := cache.AddSpec(specs[name], 0); err != nil {
+					failed = append(failed, name)
+				}
+			}
+			require.Equal(t, tc.failed, failed)
+
+			// the same level applies to writing Spec files
+			dir := filepath.Join(t.TempDir(), "cdi")
+			cache = newCache(
+				WithSpecDirs(dir),
+				WithAutoRefresh(false),
+				WithSpecContentValidation(tc.level),
+				validator,
+			)
+			failed = nil
+			for _, name := range names {
+				if err := cache.WriteSpec(specs[name], "vendor-"+strings.ReplaceAll(name, " ", "-")); err != nil {
+					failed = append(failed, name)
+				}
+			}
+			require.Equal(t, tc.failed, failed)
+
+			// and to reading them, written with validation off
+			writer := newCache(
+				WithSpecDirs(dir),
+				WithAutoRefresh(false),
+				WithSpecContentValidation(SpecValidationOff),
+			)
+			for _, name := range names {
+				require.NoError(t, writer.WriteSpec(specs[name], "vendor-"+strings.ReplaceAll(name, " ", "-")))
+			}
+			_ = cache.Refresh()
+			failed = nil
+			for _, name := range names {
+				if cache.GetDevice(specs[name].Kind+"=dev0") == nil {
+					failed = append(failed, name)
+				}
+			}
+			require.Equal(t, tc.failed, failed)
+		})
+	}
+}
+
+func TestParseSpecValidationLevel(t *testing.T) {
+	for _, level := range []SpecValidationLevel{
+		SpecValidationDefault,
+		SpecValidationOff,
+		SpecValidationSchema,
+		SpecValidationStrict,
+	} {
+		parsed, err := ParseSpecValidationLevel(level.String())
+		require.NoError(t, err)
+		require.Equal(t, level, parsed)
+	}
+
+	_, err := ParseSpecValidationLevel("paranoid")
+	require.Error(t, err)
+}
+
+func TestWithSpecValidator(t *testing.T) {
+	spec := &cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/dev",
+		Devices: []cdi.Device{
+			{
+				Name:           "dev0",
+				ContainerEdits: cdi.ContainerEdits{Env: []string{"A=1"}},
+			},
+		},
+	}
+	reject := func(*cdi.Spec) error {
+		return errors.New("rejected")
+	}
+
+	strict := newCache(WithSpecDirs(), WithAutoRefresh(false), WithSpecValidator(reject))
+	lenient := newCache(WithSpecDirs(), WithAutoRefresh(false))
+	require.Error(t, strict.AddSpec(spec, 0))
+	require.NoError(t, lenient.AddSpec(spec, 0))
+
+	// the global validator only affects the default cache
+	prev := SetDefaultCache(newCache(WithSpecDirs(), WithAutoRefresh(false)))
+	defer SetDefaultCache(prev)
+
+	SetSpecValidator(reject)
+	defer SetSpecValidator(nil)
+
+	require.Error(t, GetDefaultCache().AddSpec(spec, 0))
+	other := newCache(WithSpecDirs(), WithAutoRefresh(false))
+	require.NoError(t, other.AddSpec(spec, 0))
+}
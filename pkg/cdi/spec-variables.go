@@ -0,0 +1,271 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// Variables in Spec files are referenced as ${NAME}, where NAME consists
+// of letters, digits and underscores and does not start with a digit. A
+// literal "${" is written as "$${". Variables are substituted in all string
+// values of a Spec file, before the Spec is validated. This lets vendors
+// ship the same Spec file for hosts which differ only in details, like the
+// root of the driver installation:
+//
+//	containerEdits:
+//	  mounts:
+//	    - hostPath: "${DRIVER_ROOT}/lib/libvendor.so"
+//	      containerPath: "/usr/lib/libvendor.so"
+//
+// Substitution is strict: Spec files referencing any variables which are
+// not set fail to load.
+
+// WithSpecVariables returns an option to substitute the given variables
+// in Spec files. Variables given with this option take precedence over
+// those of a file (see WithSpecVariablesFile) and of the environment (see
+// WithSpecVariablesFromEnv). Substitution is disabled unless any of these
+// options is given.
+func WithSpecVariables(vars map[string]string) Option {
+	return func(c *Cache) {
+		c.specVars = vars
+	}
+}
+
+// WithSpecVariablesFile returns an option to substitute variables from
+// the given host configuration file in Spec files. The file consists of
+// NAME=VALUE lines. Empty lines and lines starting with '#' are ignored.
+// The file is read whenever Spec files referencing variables are loaded.
+// A refresh reloads all Spec files if the file has changed. Variables of
+// the file take precedence over those of the environment.
+func WithSpecVariablesFile(path string) Option {
+	return func(c *Cache) {
+		c.specVarsFile = path
+	}
+}
+
+// WithSpecVariablesFromEnv returns an option to control whether variables
+// in Spec files are substituted from the environment of the process.
+func WithSpecVariablesFromEnv(enable bool) Option {
+	return func(c *Cache) {
+		c.specVarsFromEnv = enable
+	}
+}
+
+// hasSpecVariables returns true if variable substitution is enabled.
+func (c *Cache) hasSpecVariables() bool {
+	return c.specVars != nil || c.specVarsFile != "" || c.specVarsFromEnv
+}
+
+// specPreprocessor returns the function to prepare and validate the Spec
+// parsed from the given Spec file data.
+func (c *Cache) specPreprocessor(data []byte) func(*cdi.Spec) error {
+	if !c.hasSpecVariables() || !bytes.Contains(data, []byte("${")) {
+		return c.validateSpec
+	}
+	return func(raw *cdi.Spec) error {
+		lookup, err := c.specVariableLookup()
+		if err != nil {
+			return err
+		}
+		if err := expandSpecVariables(raw, lookup); err != nil {
+			return err
+		}
+		return c.validateSpec(raw)
+	}
+}
+
+// specVariableLookup returns a function to look up Spec variables.
+func (c *Cache) specVariableLookup() (func(string) (string, bool), error) {
+	var fileVars map[string]string
+	if c.specVarsFile != "" {
+		vars, err := readSpecVariablesFile(c.specVarsFile)
+		if err != nil {
+			return nil, err
+		}
+		fileVars = vars
+	}
+
+	return func(name string) (string, bool) {
+		if value, ok := c.specVars[name]; ok {
+			return value, true
+		}
+		if value, ok := fileVars[name]; ok {
+			return value, true
+		}
+		if c.specVarsFromEnv {
+			return os.LookupEnv(name)
+		}
+		return "", false
+	}, nil
+}
+
+// readSpecVariablesFile reads the given Spec variables file.
+func readSpecVariablesFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CDI Spec variables: %w", err)
+	}
+
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(text, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !isSpecVariableName(name) {
+			return nil, fmt.Errorf("invalid CDI Spec variable in %s, line %d: %q", path, line, text)
+		}
+		vars[name] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read CDI Spec variables: %w", err)
+	}
+
+	return vars, nil
+}
+
+// expandSpecVariables substitutes variables in all string values of the
+// given Spec, failing if any variables are not set.
+func expandSpecVariables(raw *cdi.Spec, lookup func(string) (string, bool)) error {
+	unresolved := map[string]struct{}{}
+	expand := func(s string) (string, error) {
+		return expandVariables(s, lookup, unresolved)
+	}
+
+	if err := expandStrings(reflect.ValueOf(raw).Elem(), expand); err != nil {
+		return err
+	}
+	if len(unresolved) > 0 {
+		names := make([]string, 0, len(unresolved))
+		for name := range unresolved {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unresolved variables in CDI Spec: %s", strings.Join(names, ", "))
+	}
+
+	return nil
+}
+
+// expandStrings replaces all strings in the given value using expand.
+func expandStrings(v reflect.Value, expand func(string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return expandStrings(v.Elem(), expand)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).PkgPath != "" || t.Field(i).Tag.Get("json") == "-" {
+				continue
+			}
+			if err := expandStrings(v.Field(i), expand); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := expandStrings(v.Index(i), expand); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			s, err := expand(iter.Value().String())
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), reflect.ValueOf(s).Convert(v.Type().Elem()))
+		}
+	case reflect.String:
+		s, err := expand(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	}
+	return nil
+}
+
+// expandVariables substitutes the variables in s, recording the names of
+// variables which are not set.
+func expandVariables(s string, lookup func(string) (string, bool), unresolved map[string]struct{}) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for {
+		idx := strings.Index(s, "${")
+		if idx < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if idx > 0 && s[idx-1] == '$' {
+			b.WriteString(s[:idx-1] + "${")
+			s = s[idx+2:]
+			continue
+		}
+		end := strings.IndexByte(s[idx:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference in %q", s)
+		}
+		name := s[idx+2 : idx+end]
+		if !isSpecVariableName(name) {
+			return "", fmt.Errorf("invalid variable name %q", name)
+		}
+		value, ok := lookup(name)
+		if !ok {
+			unresolved[name] = struct{}{}
+		}
+		b.WriteString(s[:idx] + value)
+		s = s[idx+end+1:]
+	}
+}
+
+// isSpecVariableName returns true if name is a valid variable name.
+func isSpecVariableName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
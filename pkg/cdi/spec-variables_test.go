@@ -0,0 +1,171 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecVariables(t *testing.T) {
+	const spec = `
+cdiVersion: "0.3.0"
+kind: "vendor.com/device"
+devices:
+  - name: "dev0"
+    containerEdits:
+      env:
+        - "DRIVER_ROOT=${DRIVER_ROOT}"
+        - "LITERAL=$${DRIVER_ROOT}"
+      mounts:
+        - hostPath: "${DRIVER_ROOT}/lib/libvendor.so.${DRIVER_VERSION}"
+          containerPath: "/usr/lib/libvendor.so"
+`
+
+	for _, tc := range []struct {
+		name      string
+		vars      map[string]string
+		file      string
+		env       map[string]string
+		fromEnv   bool
+		source    string
+		literal   string
+		errSubstr string
+	}{
+		{
+			name:    "disabled",
+			source:  "${DRIVER_ROOT}/lib/libvendor.so.${DRIVER_VERSION}",
+			literal: "$${DRIVER_ROOT}",
+		},
+		{
+			name: "explicit variables",
+			vars: map[string]string{
+				"DRIVER_ROOT":    "/opt/vendor",
+				"DRIVER_VERSION": "1.0",
+			},
+			source: "/opt/vendor/lib/libvendor.so.1.0",
+		},
+		{
+			name: "file and environment",
+			vars: map[string]string{"DRIVER_VERSION": "2.0"},
+			file: `
+# vendor driver installation
+DRIVER_ROOT=/run/vendor
+DRIVER_VERSION=1.0
+`,
+			env:     map[string]string{"DRIVER_ROOT": "/ignored"},
+			fromEnv: true,
+			source:  "/run/vendor/lib/libvendor.so.2.0",
+		},
+		{
+			name:    "environment",
+			env:     map[string]string{"DRIVER_ROOT": "/usr/local/vendor", "DRIVER_VERSION": "3.0"},
+			fromEnv: true,
+			source:  "/usr/local/vendor/lib/libvendor.so.3.0",
+		},
+		{
+			name:      "unresolved variables",
+			vars:      map[string]string{},
+			errSubstr: "unresolved variables in CDI Spec: DRIVER_ROOT, DRIVER_VERSION",
+		},
+		{
+			name:      "invalid variables file",
+			file:      "DRIVER ROOT=/opt\n",
+			errSubstr: "line 1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := createSpecDirs(t, map[string]string{"vendor.yaml": spec}, nil)
+			require.NoError(t, err)
+
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+
+			options := []Option{
+				WithSpecDirs(filepath.Join(dir, "etc")),
+				WithAutoRefresh(false),
+				WithSpecVariablesFromEnv(tc.fromEnv),
+			}
+			if tc.vars != nil {
+				options = append(options, WithSpecVariables(tc.vars))
+			}
+			if tc.file != "" {
+				path := filepath.Join(dir, "variables")
+				require.NoError(t, os.WriteFile(path, []byte(tc.file), 0o644))
+				options = append(options, WithSpecVariablesFile(path))
+			}
+
+			cache := newCache(options...)
+			err = cache.Refresh()
+			if tc.errSubstr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errSubstr)
+				require.Empty(t, cache.ListDevices())
+				return
+			}
+			require.NoError(t, err)
+
+			ociSpec := &oci.Spec{}
+			_, err = cache.InjectDevices(ociSpec, "vendor.com/device=dev0")
+			require.NoError(t, err)
+			require.Len(t, ociSpec.Mounts, 1)
+			require.Equal(t, tc.source, ociSpec.Mounts[0].Source)
+			literal := tc.literal
+			if literal == "" {
+				literal = "${DRIVER_ROOT}"
+			}
+			require.Contains(t, ociSpec.Process.Env, "LITERAL="+literal)
+		})
+	}
+}
+
+func TestSpecVariablesFileChange(t *testing.T) {
+	const spec = `
+cdiVersion: "0.3.0"
+kind: "vendor.com/device"
+devices:
+  - name: "dev0"
+    containerEdits:
+      env:
+        - "DRIVER_ROOT=${DRIVER_ROOT}"
+`
+	dir, err := createSpecDirs(t, map[string]string{"vendor.yaml": spec}, nil)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "variables")
+	require.NoError(t, os.WriteFile(path, []byte("DRIVER_ROOT=/opt\n"), 0o644))
+
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+		WithSpecVariablesFile(path),
+	)
+	require.NoError(t, cache.Refresh())
+	require.Equal(t, []string{"DRIVER_ROOT=/opt"},
+		cache.GetDevice("vendor.com/device=dev0").ContainerEdits.Env)
+
+	// unchanged Spec files are read again if the variables change
+	require.NoError(t, os.WriteFile(path, []byte("DRIVER_ROOT=/opt/vendor\n"), 0o644))
+	require.NoError(t, cache.Refresh())
+	require.Equal(t, []string{"DRIVER_ROOT=/opt/vendor"},
+		cache.GetDevice("vendor.com/device=dev0").ContainerEdits.Env)
+}
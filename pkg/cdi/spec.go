@@ -21,8 +21,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	oci "github.com/opencontainers/runtime-spec/specs-go"
 	"sigs.k8s.io/yaml"
@@ -55,12 +57,18 @@ type Spec struct {
 	class    string
 	path     string
 	priority int
+	digest   string
+	modTime  time.Time
 	devices  map[string]*Device
+	groups   map[string][]string
+	document int
 }
 
 // ReadSpec reads the given CDI Spec file. The resulting Spec is
 // assigned the given priority. If reading or parsing the Spec
-// data fails ReadSpec returns a nil Spec and an error.
+// data fails ReadSpec returns a nil Spec and an error. Files with
+// multiple Spec documents are rejected, these can be read using
+// ReadSpecs.
 func ReadSpec(path string, priority int) (*Spec, error) {
 	data, err := os.ReadFile(path)
 	switch {
@@ -70,18 +78,64 @@ func ReadSpec(path string, priority int) (*Spec, error) {
 		return nil, fmt.Errorf("failed to read CDI Spec %q: %w", path, err)
 	}
 
-	raw, err := ParseSpec(data)
+	if docs := splitSpecDocuments(data, path); len(docs) > 1 {
+		return nil, fmt.Errorf("failed to parse CDI Spec %q, %d Spec documents found",
+			path, len(docs))
+	}
+
+	spec, err := readSpecData(data, path, priority, validateSpec, specParsing{anchors: AnchorsExpand})
+	if err != nil {
+		return nil, err
+	}
+	setSpecModTime(path, spec)
+
+	return spec, nil
+}
+
+// specParsing defines how Spec data is parsed.
+type specParsing struct {
+	// anchors is the policy for YAML anchors.
+	anchors AnchorPolicy
+	// strict enables strict parsing, see ParseSpecStrict.
+	strict bool
+}
+
+// readSpecData creates a Spec from the given data, read from the given
+// path. The resulting Spec is assigned the given priority. Its content
+// is validated using the given function. The data is parsed as defined
+// by the given specParsing.
+func readSpecData(data []byte, path string, priority int, validate func(*cdi.Spec) error, parsing specParsing) (*Spec, error) {
+	anchors := parsing.anchors
+	if anchors == AnchorsReject {
+		if err := rejectSpecAnchors(data); err != nil {
+			return nil, fmt.Errorf("failed to parse CDI Spec %q: %w", path, err)
+		}
+	}
+
+	parse := ParseSpec
+	if parsing.strict {
+		parse = ParseSpecStrict
+	}
+
+	raw, err := parse(data)
 	if err != nil {
+		if anchors == AnchorsTrack {
+			err = locateSpecError(data, err)
+		}
 		return nil, fmt.Errorf("failed to parse CDI Spec %q: %w", path, err)
 	}
 	if raw == nil {
 		return nil, fmt.Errorf("failed to parse CDI Spec %q, no Spec data", path)
 	}
 
-	spec, err := newSpec(raw, path, priority)
+	spec, err := newSpecWith(raw, path, priority, validate)
 	if err != nil {
+		if anchors == AnchorsTrack {
+			err = locateSpecError(data, err)
+		}
 		return nil, err
 	}
+	spec.digest = specDigest(data)
 
 	return spec, nil
 }
@@ -91,7 +145,13 @@ func ReadSpec(path string, priority int) (*Spec, error) {
 // priority. If Spec data validation fails newSpec returns a nil
 // Spec and an error.
 func newSpec(raw *cdi.Spec, path string, priority int) (*Spec, error) {
-	err := validateSpec(raw)
+	return newSpecWith(raw, path, priority, validateSpec)
+}
+
+// newSpecWith creates a new Spec like newSpec, validating its content
+// using the given function instead of the external validator.
+func newSpecWith(raw *cdi.Spec, path string, priority int, validate func(*cdi.Spec) error) (*Spec, error) {
+	err := validate(raw)
 	if err != nil {
 		return nil, err
 	}
@@ -111,6 +171,12 @@ func newSpec(raw *cdi.Spec, path string, priority int) (*Spec, error) {
 	if spec.devices, err = spec.validate(); err != nil {
 		return nil, fmt.Errorf("invalid CDI Spec: %w", err)
 	}
+	if spec.groups, err = spec.validateGroups(spec.devices); err != nil {
+		return nil, fmt.Errorf("invalid CDI Spec: %w", err)
+	}
+	if err = spec.validateRenames(spec.devices); err != nil {
+		return nil, fmt.Errorf("invalid CDI Spec: %w", err)
+	}
 
 	return spec, nil
 }
@@ -118,18 +184,30 @@ func newSpec(raw *cdi.Spec, path string, priority int) (*Spec, error) {
 // Write the CDI Spec to the file associated with it during instantiation
 // by newSpec() or ReadSpec().
 func (s *Spec) write(overwrite bool) error {
+	tmp, err := s.writeTemp()
+	if err != nil {
+		return err
+	}
+
+	err = renameIn(filepath.Dir(s.path), filepath.Base(tmp), filepath.Base(s.path), overwrite)
+
+	if err != nil {
+		os.Remove(tmp)
+		err = fmt.Errorf("failed to write Spec file: %w", err)
+	}
+
+	return err
+}
+
+// writeTemp writes the Spec, validated when it was created, into a
+// temporary file in the directory of the Spec file. It returns the path of the temporary file.
+func (s *Spec) writeTemp() (string, error) {
 	var (
 		data []byte
 		dir  string
-		tmp  *os.File
 		err  error
 	)
 
-	err = validateSpec(s.Spec)
-	if err != nil {
-		return err
-	}
-
 	if filepath.Ext(s.path) == ".yaml" {
 		data, err = yaml.Marshal(s.Spec)
 		data = append([]byte("---\n"), data...)
@@ -137,33 +215,33 @@ func (s *Spec) write(overwrite bool) error {
 		data, err = json.Marshal(s.Spec)
 	}
 	if err != nil {
-		return fmt.Errorf("failed to marshal Spec file: %w", err)
+		return "", fmt.Errorf("failed to marshal Spec file: %w", err)
 	}
 
 	dir = filepath.Dir(s.path)
 	err = os.MkdirAll(dir, 0o755)
 	if err != nil {
-		return fmt.Errorf("failed to create Spec dir: %w", err)
+		return "", fmt.Errorf("failed to create Spec dir: %w", err)
 	}
 
-	tmp, err = os.CreateTemp(dir, "spec.*.tmp")
+	return writeTempFile(dir, data)
+}
+
+// writeTempFile writes data into a new temporary Spec file in dir. It
+// returns the path of the temporary file.
+func writeTempFile(dir string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp(dir, "spec.*.tmp")
 	if err != nil {
-		return fmt.Errorf("failed to create Spec file: %w", err)
+		return "", fmt.Errorf("failed to create Spec file: %w", err)
 	}
 	_, err = tmp.Write(data)
 	tmp.Close()
-	if err != nil {
-		return fmt.Errorf("failed to write Spec file: %w", err)
-	}
-
-	err = renameIn(dir, filepath.Base(tmp.Name()), filepath.Base(s.path), overwrite)
-
 	if err != nil {
 		os.Remove(tmp.Name())
-		err = fmt.Errorf("failed to write Spec file: %w", err)
+		return "", fmt.Errorf("failed to write Spec file: %w", err)
 	}
 
-	return err
+	return tmp.Name(), nil
 }
 
 // GetVendor returns the vendor of this Spec.
@@ -186,6 +264,29 @@ func (s *Spec) GetPath() string {
 	return s.path
 }
 
+// GetDigest returns the digest of the content this Spec was read from,
+// in the form "sha256:<hex>". For Specs added to a Cache by AddSpec() the
+// digest is calculated from the JSON encoding of the Spec. The digest is
+// empty for Specs which were not read or added to a Cache.
+func (s *Spec) GetDigest() string {
+	return s.digest
+}
+
+// GetModTime returns the modification time of the Spec file at the time
+// this Spec was read from it. For Specs added to a Cache by AddSpec() it
+// is the time the Spec was added. It is the zero time for Specs which were
+// not read or added to a Cache.
+func (s *Spec) GetModTime() time.Time {
+	return s.modTime
+}
+
+// GetDocument returns the index of the document this Spec was read from
+// within its Spec file. It is 0 unless the Spec file has multiple Spec
+// documents.
+func (s *Spec) GetDocument() int {
+	return s.document
+}
+
 // GetPriority returns the priority of this Spec.
 func (s *Spec) GetPriority() int {
 	return s.priority
@@ -198,7 +299,7 @@ func (s *Spec) ApplyEdits(ociSpec *oci.Spec) error {
 
 // edits returns the applicable global container edits for this spec.
 func (s *Spec) edits() *ContainerEdits {
-	return &ContainerEdits{&s.ContainerEdits}
+	return &ContainerEdits{ContainerEdits: &s.ContainerEdits}
 }
 
 // MinimumRequiredVersion determines the minimum spec version for the input spec.
@@ -207,32 +308,60 @@ func MinimumRequiredVersion(spec *cdi.Spec) (string, error) {
 	return cdi.MinimumRequiredVersion(spec)
 }
 
+// ValidateSpec validates the given raw Spec using the same checks which
+// are done when a Spec is loaded into a Cache. Any external Spec validator
+// is not run. Producers can use this to check the Specs they generate.
+func ValidateSpec(raw *cdi.Spec) error {
+	if raw == nil {
+		return fmt.Errorf("invalid nil CDI Spec")
+	}
+	_, err := newSpecWith(raw, "", 0, func(*cdi.Spec) error { return nil })
+	return err
+}
+
 // Validate the Spec.
 func (s *Spec) validate() (map[string]*Device, error) {
 	if err := cdi.ValidateVersion(s.Spec); err != nil {
-		return nil, err
+		return nil, withSpecPath("cdiVersion", err)
 	}
 	if err := parser.ValidateVendorName(s.vendor); err != nil {
-		return nil, err
+		return nil, withSpecPath("kind", err)
 	}
 	if err := parser.ValidateClassName(s.class); err != nil {
-		return nil, err
+		return nil, withSpecPath("kind", err)
 	}
 	if err := validation.ValidateSpecAnnotations(s.Kind, s.Annotations); err != nil {
-		return nil, err
+		return nil, withSpecPath("annotations", err)
+	}
+	if err := s.validateLifecycle(); err != nil {
+		return nil, withSpecPath("lifecycle", err)
+	}
+	if err := validatePlatform(s.Kind, s.Platform); err != nil {
+		return nil, withSpecPath("platform", err)
 	}
 	if err := s.edits().Validate(); err != nil {
-		return nil, err
+		return nil, withSpecPath("containerEdits", err)
+	}
+	if s.hasHookTemplates() {
+		if err := s.edits().validateHookTemplates(); err != nil {
+			return nil, withSpecPath("containerEdits", err)
+		}
+	}
+	if s.hasHookTimeoutLimit() {
+		if err := s.edits().validateHookTimeouts(); err != nil {
+			return nil, withSpecPath("containerEdits", err)
+		}
 	}
 
 	devices := make(map[string]*Device)
-	for _, d := range s.Devices {
+	for i, d := range s.Devices {
+		path := "devices[" + strconv.Itoa(i) + "]"
 		dev, err := newDevice(s, d)
 		if err != nil {
-			return nil, fmt.Errorf("failed add device %q: %w", d.Name, err)
+			return nil, withSpecPath(path, fmt.Errorf("failed add device %q: %w", d.Name, err))
 		}
 		if _, conflict := devices[d.Name]; conflict {
-			return nil, fmt.Errorf("invalid spec, multiple device %q", d.Name)
+			return nil, withSpecPath(path+".name", fmt.Errorf("invalid spec, multiple device %q", d.Name))
 		}
 		devices[d.Name] = dev
 	}
@@ -240,35 +369,87 @@ func (s *Spec) validate() (map[string]*Device, error) {
 	return devices, nil
 }
 
-// ParseSpec parses CDI Spec data into a raw CDI Spec.
+// ParseSpec parses CDI Spec data into a raw CDI Spec. Unknown fields
+// are rejected, unless the Spec declares a CDI version newer than the
+// current one. The unknown fields of such a Spec are preserved in its
+// UnknownFields and written back whenever the Spec is marshaled, unless
+// its version has been changed to a known one.
 func ParseSpec(data []byte) (*cdi.Spec, error) {
 	var raw *cdi.Spec
 	err := yaml.UnmarshalStrict(data, &raw)
-	if err != nil {
+	if err == nil {
+		return raw, nil
+	}
+
+	newer, nerr := parseNewerSpec(data)
+	if nerr != nil || newer == nil {
 		return nil, fmt.Errorf("failed to unmarshal CDI Spec: %w", err)
 	}
+	return newer, nil
+}
+
+// parseNewerSpec parses CDI Spec data leniently, capturing unknown
+// fields. It returns nil if the Spec is not of a newer CDI version.
+func parseNewerSpec(data []byte) (*cdi.Spec, error) {
+	var (
+		raw *cdi.Spec
+		doc map[string]interface{}
+	)
+	if err := yaml.Unmarshal(data, &raw); err != nil || raw == nil {
+		return nil, err
+	}
+	if !isNewerVersion(raw.Version) {
+		return nil, nil
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if err := cdi.CaptureUnknownFields(raw, doc); err != nil {
+		return nil, err
+	}
 	return raw, nil
 }
 
 // SetSpecValidator sets a CDI Spec validator function. This function
-// is used for extra CDI Spec content validation whenever a Spec file
-// loaded (using ReadSpec() or written (using WriteSpec()).
+// is used for extra CDI Spec content validation whenever a Spec file is
+// loaded using ReadSpec() or ReadSpecs(), and by the default cache (see
+// GetDefaultCache()) whenever it loads or writes a Spec file. Other
+// caches are not affected.
+//
+// Deprecated: SetSpecValidator changes process-wide state. Set the
+// validator of a Cache with the WithSpecValidator option instead.
 func SetSpecValidator(fn func(*cdi.Spec) error) {
 	validatorLock.Lock()
-	defer validatorLock.Unlock()
 	specValidator = fn
+	validatorLock.Unlock()
+
+	defaultLock.Lock()
+	cache := defaultCache
+	defaultLock.Unlock()
+
+	if cache != nil {
+		_ = cache.Configure(WithSpecValidator(fn))
+	}
 }
 
-// validateSpec validates the Spec using the extneral validator.
-func validateSpec(raw *cdi.Spec) error {
+// getSpecValidator returns the validator set by SetSpecValidator().
+func getSpecValidator() func(*cdi.Spec) error {
 	validatorLock.RLock()
 	defer validatorLock.RUnlock()
+	return specValidator
+}
 
-	if specValidator == nil {
+// validateSpec validates the Spec using the external validator.
+func validateSpec(raw *cdi.Spec) error {
+	return runSpecValidator(getSpecValidator(), raw)
+}
+
+// runSpecValidator validates the Spec using the given validator, if any.
+func runSpecValidator(fn func(*cdi.Spec) error, raw *cdi.Spec) error {
+	if fn == nil {
 		return nil
 	}
-	err := specValidator(raw)
-	if err != nil {
+	if err := fn(raw); err != nil {
 		return fmt.Errorf("Spec validation failed: %w", err)
 	}
 	return nil
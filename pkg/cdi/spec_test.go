@@ -229,6 +229,117 @@ devices:
     containerEdits:
       env:
         - "SPACE=BAR"
+`,
+		},
+		{
+			name: "valid, container path injected by multiple devices",
+			data: `
+cdiVersion: "0.6.0"
+kind: vendor.com/device
+devices:
+  - name: "dev1"
+    containerEdits:
+      deviceNodes:
+        - path: "/dev/vendor-ctl"
+          type: c
+          major: 10
+          minor: 1
+  - name: "dev2"
+    containerEdits:
+      mounts:
+        - hostPath: "/dev/vendor-ctl"
+          containerPath: "/dev/vendor-ctl"
+`,
+		},
+		{
+			name: "valid, container path injected by spec and device",
+			data: `
+cdiVersion: "0.6.0"
+kind: vendor.com/device
+containerEdits:
+  mounts:
+    - hostPath: "/usr/lib/vendor"
+      containerPath: "/usr/lib/vendor"
+devices:
+  - name: "dev1"
+    containerEdits:
+      mounts:
+        - hostPath: "/opt/vendor/lib"
+          containerPath: "/usr/lib/vendor/"
+`,
+		},
+		{
+			name: "valid, container path shared by only one device",
+			data: `
+cdiVersion: "0.6.0"
+kind: vendor.com/device
+devices:
+  - name: "dev1"
+    annotations:
+      cdi.k8s.io/shared-container-paths: "/dev/vendor-ctl"
+    containerEdits:
+      deviceNodes:
+        - path: "/dev/vendor-ctl"
+          type: c
+          major: 10
+          minor: 1
+  - name: "dev2"
+    containerEdits:
+      deviceNodes:
+        - path: "/dev/vendor-ctl"
+          type: c
+          major: 10
+          minor: 2
+`,
+		},
+		{
+			name: "valid, container path shared by all devices",
+			data: `
+cdiVersion: "0.6.0"
+kind: vendor.com/device
+devices:
+  - name: "dev1"
+    annotations:
+      cdi.k8s.io/shared-container-paths: "/dev/vendor-ctl"
+    containerEdits:
+      deviceNodes:
+        - path: "/dev/vendor-ctl"
+          type: c
+          major: 10
+          minor: 1
+  - name: "dev2"
+    annotations:
+      cdi.k8s.io/shared-container-paths: "/dev/vendor-ctl,/dev/other"
+    containerEdits:
+      deviceNodes:
+        - path: "/dev/vendor-ctl"
+          type: c
+          major: 10
+          minor: 2
+`,
+		},
+		{
+			name: "valid, container path shared at the spec level",
+			data: `
+cdiVersion: "0.6.0"
+kind: vendor.com/device
+annotations:
+  cdi.k8s.io/shared-container-paths: "*"
+devices:
+  - name: "dev1"
+    containerEdits:
+      deviceNodes:
+        - path: "/dev/vendor-ctl"
+          type: c
+          major: 10
+          minor: 1
+  - name: "dev2"
+    containerEdits:
+      deviceNodes:
+        - path: "/dev/vendor-ctl"
+          type: c
+          major: 10
+          minor: 2
 `,
 		},
 	} {
@@ -247,6 +358,7 @@ devices:
 			require.NoError(t, err)
 
 			spec, err = newSpec(raw, tc.name, 0)
+			require.Equal(t, err == nil, ValidateSpec(raw) == nil)
 			if tc.invalid || tc.schemaFail {
 				require.Error(t, err)
 				require.Nil(t, spec)
@@ -719,6 +831,208 @@ func TestRequiredVersion(t *testing.T) {
 			},
 			expectedVersion: "0.7.0",
 		},
+		{
+			description: "device groups require v0.9.0",
+			spec: &cdi.Spec{
+				Groups: []cdi.DeviceGroup{
+					{
+						Name:    "all",
+						Devices: []string{"*"},
+					},
+				},
+			},
+			expectedVersion: "0.9.0",
+		},
+		{
+			description: "hook templates require v0.9.0",
+			spec: &cdi.Spec{
+				ContainerEdits: cdi.ContainerEdits{
+					Hooks: []*cdi.Hook{
+						{
+							HookName: "createContainer",
+							Path:     "/bin/hook",
+							Args:     []string{"hook", "--vendor={{ .Vendor }}"},
+						},
+					},
+				},
+				Devices: []cdi.Device{
+					{
+						Name: "device0",
+						ContainerEdits: cdi.ContainerEdits{
+							Hooks: []*cdi.Hook{
+								{
+									HookName: "createContainer",
+									Path:     "/bin/hook",
+									Env:      []string{"DEVICE={{ .DeviceName }}"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedVersion: "0.9.0",
+		},
+		{
+			description: "device hook environment templates require v0.9.0",
+			spec: &cdi.Spec{
+				Devices: []cdi.Device{
+					{
+						Name: "device0",
+						ContainerEdits: cdi.ContainerEdits{
+							Hooks: []*cdi.Hook{
+								{
+									HookName: "createContainer",
+									Path:     "/bin/hook",
+									Args:     []string{"hook", "{ not a template }"},
+									Env:      []string{"DEVICE={{ .DeviceName }}"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedVersion: "0.9.0",
+		},
+		{
+			description: "idmapped mounts require v0.10.0",
+			spec: &cdi.Spec{
+				Devices: []cdi.Device{
+					{
+						Name: "device0",
+						ContainerEdits: cdi.ContainerEdits{
+							Mounts: []*cdi.Mount{
+								{
+									HostPath:      "/host/data",
+									ContainerPath: "/data",
+									Options:       []string{"rbind", "ridmap"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedVersion: "0.10.0",
+		},
+		{
+			description: "propagation options do not require v0.10.0",
+			spec: &cdi.Spec{
+				ContainerEdits: cdi.ContainerEdits{
+					Mounts: []*cdi.Mount{
+						{
+							HostPath:      "/host/data",
+							ContainerPath: "/data",
+							Options:       []string{"bind", "rslave"},
+						},
+					},
+				},
+			},
+			expectedVersion: "0.3.0",
+		},
+		{
+			description: "device properties require v0.10.0",
+			spec: &cdi.Spec{
+				Devices: []cdi.Device{
+					{
+						Name: "device0",
+						Properties: &cdi.DeviceProperties{
+							Attributes: map[string]string{"model": "X100"},
+						},
+					},
+				},
+			},
+			expectedVersion: "0.10.0",
+		},
+		{
+			description: "device capacity requires v0.10.0",
+			spec: &cdi.Spec{
+				Devices: []cdi.Device{
+					{
+						Name:     "device0",
+						Capacity: &cdi.DeviceCapacity{MaxConsumers: 2},
+					},
+				},
+			},
+			expectedVersion: "0.10.0",
+		},
+		{
+			description: "spec lifecycle requires v0.10.0",
+			spec: &cdi.Spec{
+				Lifecycle: &cdi.Lifecycle{Deprecated: true},
+			},
+			expectedVersion: "0.10.0",
+		},
+		{
+			description: "device lifecycle requires v0.10.0",
+			spec: &cdi.Spec{
+				Devices: []cdi.Device{
+					{
+						Name:      "device0",
+						Lifecycle: &cdi.Lifecycle{Deprecated: true},
+					},
+				},
+			},
+			expectedVersion: "0.10.0",
+		},
+		{
+			description: "spec platform requires v0.10.0",
+			spec: &cdi.Spec{
+				Platform: &cdi.PlatformConstraints{OS: []string{"linux"}},
+			},
+			expectedVersion: "0.10.0",
+		},
+		{
+			description: "device platform requires v0.10.0",
+			spec: &cdi.Spec{
+				Devices: []cdi.Device{
+					{
+						Name:     "device0",
+						Platform: &cdi.PlatformConstraints{Arch: []string{"arm64"}},
+					},
+				},
+			},
+			expectedVersion: "0.10.0",
+		},
+		{
+			description: "device readiness probes require v0.10.0",
+			spec: &cdi.Spec{
+				Devices: []cdi.Device{
+					{
+						Name:      "device0",
+						Readiness: []cdi.ReadinessProbe{{FileExists: "/dev/vendor0"}},
+					},
+				},
+			},
+			expectedVersion: "0.10.0",
+		},
+		{
+			description: "device nodes without access require v0.10.0",
+			spec: &cdi.Spec{
+				Devices: []cdi.Device{
+					{
+						Name: "device0",
+						ContainerEdits: cdi.ContainerEdits{
+							DeviceNodes: []*cdi.DeviceNode{
+								{
+									Path:        "/dev/vendorctl",
+									Permissions: cdi.DevicePermissionsNone,
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedVersion: "0.10.0",
+		},
+		{
+			description: "environment merge policies require v0.10.0",
+			spec: &cdi.Spec{
+				ContainerEdits: cdi.ContainerEdits{
+					Env:       []string{"FOO=BAR"},
+					EnvPolicy: cdi.EnvPolicySkipIfPresent,
+				},
+			},
+			expectedVersion: "0.10.0",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -730,3 +1044,325 @@ func TestRequiredVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestRequiredVersionForEditsAndDevices(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		edits       *cdi.ContainerEdits
+		device      *cdi.Device
+		expected    string
+	}{
+		{
+			description: "nil edits",
+			expected:    "0.3.0",
+		},
+		{
+			description: "mount type requires v0.4.0",
+			edits: &cdi.ContainerEdits{
+				Mounts: []*cdi.Mount{{HostPath: "/a", ContainerPath: "/a", Type: "bind"}},
+			},
+			expected: "0.4.0",
+		},
+		{
+			description: "additional GIDs require v0.7.0",
+			edits: &cdi.ContainerEdits{
+				AdditionalGIDs: []uint32{5},
+			},
+			expected: "0.7.0",
+		},
+		{
+			description: "environment merge policy requires v0.10.0",
+			edits: &cdi.ContainerEdits{
+				Env:       []string{"FOO=BAR"},
+				EnvPolicy: cdi.EnvPolicyAppend,
+			},
+			expected: "0.10.0",
+		},
+		{
+			description: "hooks inheriting the environment require v0.10.0",
+			edits: &cdi.ContainerEdits{
+				Hooks: []*cdi.Hook{{HookName: "createContainer", Path: "/bin/hook", InheritEnv: true}},
+			},
+			expected: "0.10.0",
+		},
+		{
+			description: "capabilities require v0.10.0",
+			edits: &cdi.ContainerEdits{
+				Capabilities: &cdi.Capabilities{Add: []string{"CAP_SYS_RAWIO"}},
+			},
+			expected: "0.10.0",
+		},
+		{
+			description: "device name starting with a digit requires v0.5.0",
+			device: &cdi.Device{
+				Name: "0",
+			},
+			expected: "0.5.0",
+		},
+		{
+			description: "device annotations require v0.6.0",
+			device: &cdi.Device{
+				Name:        "dev0",
+				Annotations: map[string]string{"foo": "bar"},
+			},
+			expected: "0.6.0",
+		},
+		{
+			description: "device edits are taken into account",
+			device: &cdi.Device{
+				Name: "dev0",
+				ContainerEdits: cdi.ContainerEdits{
+					IntelRdt: &cdi.IntelRdt{ClosID: "clos"},
+				},
+			},
+			expected: "0.7.0",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			var (
+				v   string
+				err error
+			)
+			if tc.device != nil {
+				v, err = cdi.RequiredVersionForDevice(tc.device)
+			} else {
+				v, err = cdi.RequiredVersionForEdits(tc.edits)
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, v)
+		})
+	}
+
+	v, err := cdi.RequiredVersionForDevice(nil)
+	require.NoError(t, err)
+	require.Equal(t, "0.3.0", v)
+}
+
+func FuzzParseSpec(f *testing.F) {
+	for _, seed := range []string{
+		`{"cdiVersion": "0.3.0", "kind": "vendor.com/device", "devices": [{"name": "dev0", "containerEdits": {"env": ["FOO=BAR"]}}]}`,
+		"cdiVersion: \"0.6.0\"\nkind: vendor.com/device\ndevices:\n  - name: dev0\n    containerEdits:\n      deviceNodes:\n        - path: /dev/dev0\n",
+		"---\ncdiVersion: \"0.6.0\"\nkind: vendor.com/a\n---\ncdiVersion: \"0.6.0\"\nkind: vendor.com/b\n",
+		"cdiVersion: \"0.6.0\"\nkind: \"vendor.com/dev\x80\"\n",
+		strings.Repeat("[", 20000),
+		"a: &a [x,x,x,x]\nb: &b [*a,*a,*a,*a]\nc: [*b,*b,*b,*b]\n",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		raw, err := ParseSpec(data)
+		if err == nil && raw != nil {
+			_, _ = newSpec(raw, "fuzz.yaml", 0)
+		}
+		_, _ = readSpecsData(data, "fuzz.yaml", 0, validateSpec, specParsing{anchors: AnchorsTrack, strict: true})
+	})
+}
+
+func TestParseSpecUnknownFields(t *testing.T) {
+	const data = `
+cdiVersion: "%s"
+kind: vendor.com/device
+future: top-level
+devices:
+  - name: dev0
+    future: device-level
+    containerEdits:
+      env:
+        - FOO=bar
+      future:
+        nested: true
+  - name: dev1
+    containerEdits:
+      deviceNodes:
+        - path: /dev/vendor1
+          future: node-level
+containerEdits:
+  future: [1, 2]
+`
+
+	_, err := ParseSpec([]byte(fmt.Sprintf(data, cdi.CurrentVersion)))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown field")
+
+	raw, err := ParseSpec([]byte(fmt.Sprintf(data, "99.0.0")))
+	require.NoError(t, err)
+	require.Len(t, raw.Devices, 2)
+	require.Equal(t, []string{"FOO=bar"}, raw.Devices[0].ContainerEdits.Env)
+	require.Equal(t, "top-level", raw.UnknownFields["future"])
+
+	// modify the Spec and move the devices around
+	raw.Devices = append([]cdi.Device{{
+		Name:           "new",
+		ContainerEdits: cdi.ContainerEdits{Env: []string{"NEW=1"}},
+	}}, raw.Devices[1], raw.Devices[0])
+	raw.Devices[2].ContainerEdits.Env = []string{"FOO=baz"}
+
+	for _, ext := range []string{".yaml", ".json"} {
+		t.Run(ext, func(t *testing.T) {
+			data, err := yaml.Marshal(raw)
+			require.NoError(t, err)
+			if ext == ".json" {
+				data, err = yaml.YAMLToJSON(data)
+				require.NoError(t, err)
+			}
+
+			var doc map[string]interface{}
+			require.NoError(t, yaml.Unmarshal(data, &doc))
+			require.Equal(t, "top-level", doc["future"])
+			require.Equal(t, []interface{}{1.0, 2.0},
+				doc["containerEdits"].(map[string]interface{})["future"])
+
+			devices := doc["devices"].([]interface{})
+			require.Len(t, devices, 3)
+			require.NotContains(t, devices[0], "future")
+
+			dev1 := devices[1].(map[string]interface{})
+			require.Equal(t, "dev1", dev1["name"])
+			node := dev1["containerEdits"].(map[string]interface{})["deviceNodes"].([]interface{})[0]
+			require.Equal(t, "node-level", node.(map[string]interface{})["future"])
+
+			dev0 := devices[2].(map[string]interface{})
+			require.Equal(t, "dev0", dev0["name"])
+			require.Equal(t, "device-level", dev0["future"])
+			edits := dev0["containerEdits"].(map[string]interface{})
+			require.Equal(t, []interface{}{"FOO=baz"}, edits["env"])
+			require.Equal(t, map[string]interface{}{"nested": true}, edits["future"])
+
+			reparsed, err := ParseSpec(data)
+			require.NoError(t, err)
+			require.Equal(t, "top-level", reparsed.UnknownFields["future"])
+			require.Len(t, reparsed.Devices, 3)
+		})
+	}
+
+	// unknown fields are dropped once the Spec is migrated to the current
+	// version, so the written Spec can be read back
+	raw.Version = cdi.CurrentVersion
+	for _, ext := range []string{".yaml", ".json"} {
+		t.Run("migrated"+ext, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "cdi")
+			cache := newCache(
+				WithSpecDirs(dir),
+				WithAutoRefresh(false),
+			)
+			require.NoError(t, cache.WriteSpec(raw, "vendor"+ext))
+
+			data, err := os.ReadFile(filepath.Join(dir, "vendor"+ext))
+			require.NoError(t, err)
+			require.NotContains(t, string(data), "future")
+
+			require.NoError(t, cache.Refresh())
+			require.Empty(t, cache.GetErrors())
+			require.Equal(t,
+				[]string{
+					"vendor.com/device=dev0",
+					"vendor.com/device=dev1",
+					"vendor.com/device=new",
+				},
+				cache.ListDevices(),
+			)
+			require.Equal(t, []string{"FOO=baz"},
+				cache.GetDevice("vendor.com/device=dev0").ContainerEdits.Env)
+		})
+	}
+}
+
+func TestSpecDeepCopyAndEqual(t *testing.T) {
+	numa, timeout, uid := 1, 5, uint32(1000)
+	mode := os.FileMode(0o600)
+	spec := &cdi.Spec{
+		Version:     cdi.CurrentVersion,
+		Kind:        "vendor.com/device",
+		Annotations: map[string]string{"vendor.com/key": "value"},
+		Devices: []cdi.Device{
+			{
+				Name:        "dev0",
+				Annotations: map[string]string{"vendor.com/dev": "0"},
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"FOO=bar"},
+					DeviceNodes: []*cdi.DeviceNode{
+						{Path: "/dev/vendor0", FileMode: &mode, UID: &uid},
+					},
+					Hooks: []*cdi.Hook{
+						{HookName: "createContainer", Path: "/bin/hook", Args: []string{"hook"}, Timeout: &timeout},
+					},
+					Mounts: []*cdi.Mount{
+						{HostPath: "/lib/vendor", ContainerPath: "/lib/vendor", Options: []string{"ro"}},
+					},
+					IntelRdt:       &cdi.IntelRdt{ClosID: "clos"},
+					AdditionalGIDs: []uint32{5},
+					Capabilities:   &cdi.Capabilities{Add: []string{"CAP_SYS_RAWIO"}},
+				},
+				Properties: &cdi.DeviceProperties{
+					Topology: &cdi.DeviceTopology{
+						NUMANode: &numa,
+						Links:    []cdi.DeviceLink{{Device: "dev1", Type: "nvlink"}},
+					},
+					Attributes: map[string]string{"model": "x"},
+				},
+				Capacity:  &cdi.DeviceCapacity{MaxConsumers: 2},
+				Lifecycle: &cdi.Lifecycle{Deprecated: true},
+				Platform: &cdi.PlatformConstraints{
+					Arch:          []string{"amd64"},
+					KernelVersion: &cdi.KernelVersionRange{Min: "5.15"},
+				},
+				Readiness: []cdi.ReadinessProbe{
+					{SysfsAttribute: &cdi.SysfsAttributeProbe{Path: "/sys/module/vendor/initstate", Value: "live"}},
+				},
+			},
+		},
+		ContainerEdits: cdi.ContainerEdits{Env: []string{"SPEC=1"}},
+		Groups:         []cdi.DeviceGroup{{Name: "all", Devices: []string{"*"}}},
+		UnknownFields:  map[string]interface{}{"future": []interface{}{"x"}},
+	}
+
+	copied := spec.DeepCopy()
+	require.Equal(t, spec, copied)
+	require.True(t, spec.Equal(copied))
+
+	for _, mutate := range []func(*cdi.Spec){
+		func(s *cdi.Spec) { s.Annotations["vendor.com/key"] = "other" },
+		func(s *cdi.Spec) { s.Devices[0].Annotations["vendor.com/dev"] = "1" },
+		func(s *cdi.Spec) { s.Devices[0].ContainerEdits.Env[0] = "FOO=baz" },
+		func(s *cdi.Spec) { *s.Devices[0].ContainerEdits.DeviceNodes[0].FileMode = 0o666 },
+		func(s *cdi.Spec) { *s.Devices[0].ContainerEdits.DeviceNodes[0].UID = 0 },
+		func(s *cdi.Spec) { s.Devices[0].ContainerEdits.Hooks[0].Args[0] = "other" },
+		func(s *cdi.Spec) { *s.Devices[0].ContainerEdits.Hooks[0].Timeout = 10 },
+		func(s *cdi.Spec) { s.Devices[0].ContainerEdits.Hooks[0].InheritEnv = true },
+		func(s *cdi.Spec) { s.Devices[0].ContainerEdits.Mounts[0].Options[0] = "rw" },
+		func(s *cdi.Spec) { s.Devices[0].ContainerEdits.IntelRdt.ClosID = "other" },
+		func(s *cdi.Spec) { s.Devices[0].ContainerEdits.AdditionalGIDs[0] = 6 },
+		func(s *cdi.Spec) { s.Devices[0].ContainerEdits.Capabilities.Add[0] = "CAP_IPC_LOCK" },
+		func(s *cdi.Spec) { *s.Devices[0].Properties.Topology.NUMANode = 0 },
+		func(s *cdi.Spec) { s.Devices[0].Properties.Topology.Links[0].Type = "pcie" },
+		func(s *cdi.Spec) { s.Devices[0].Properties.Attributes["model"] = "y" },
+		func(s *cdi.Spec) { s.Devices[0].Capacity.MaxConsumers = 3 },
+		func(s *cdi.Spec) { s.Devices[0].Lifecycle.ReplacedBy = "vendor.com/device=dev1" },
+		func(s *cdi.Spec) { s.Devices[0].Platform.Arch[0] = "arm64" },
+		func(s *cdi.Spec) { s.Devices[0].Platform.KernelVersion.Min = "6.1" },
+		func(s *cdi.Spec) { s.Devices[0].Readiness[0].SysfsAttribute.Value = "coming" },
+		func(s *cdi.Spec) { s.ContainerEdits.Env = nil },
+		func(s *cdi.Spec) { s.Groups[0].Devices[0] = "dev0" },
+		func(s *cdi.Spec) { s.UnknownFields["future"].([]interface{})[0] = "y" },
+	} {
+		c := spec.DeepCopy()
+		mutate(c)
+		require.False(t, spec.Equal(c))
+		require.True(t, spec.Equal(copied))
+	}
+
+	// nil and empty slices and maps are equal
+	empty := &cdi.Spec{
+		Annotations: map[string]string{},
+		Devices:     []cdi.Device{},
+		ContainerEdits: cdi.ContainerEdits{
+			Env:    []string{},
+			Mounts: []*cdi.Mount{},
+		},
+	}
+	require.True(t, empty.Equal(&cdi.Spec{}))
+	require.False(t, empty.Equal(nil))
+	require.True(t, (*cdi.Spec)(nil).Equal(nil))
+	require.Nil(t, (*cdi.Spec)(nil).DeepCopy())
+}
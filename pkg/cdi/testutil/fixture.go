@@ -0,0 +1,122 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package testutil
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// FixtureSpec describes the initial state of a Fixture. Spec files are
+// given as Go structs instead of YAML strings.
+type FixtureSpec struct {
+	// Etc are the Specs, by file name, in the etc Spec directory.
+	Etc map[string]*cdispec.Spec
+	// Run are the Specs, by file name, in the run Spec directory.
+	Run map[string]*cdispec.Spec
+	// Options are additional options for the Cache.
+	Options []cdi.Option
+}
+
+// Fixture is a Harness with Spec files written from Go structs. It keeps
+// the Specs it wrote, so tests can mutate them, for instance by adding
+// devices, without rewriting whole Spec files. Like for the Harness, all
+// changes are picked up by the next Refresh.
+type Fixture struct {
+	*Harness
+
+	specs map[string]*cdispec.Spec
+}
+
+// NewFixture creates a Fixture with the given Specs. The Specs are not
+// modified, the Fixture keeps copies of them.
+func NewFixture(t testing.TB, spec FixtureSpec) *Fixture {
+	t.Helper()
+
+	f := &Fixture{
+		Harness: newHarness(t),
+		specs:   map[string]*cdispec.Spec{},
+	}
+	for dir, specs := range map[string]map[string]*cdispec.Spec{
+		EtcDir: spec.Etc,
+		RunDir: spec.Run,
+	} {
+		for name, s := range specs {
+			f.WriteSpecObject(dir, name, s)
+		}
+	}
+
+	f.createCache(spec.Options...)
+
+	return f
+}
+
+// Spec returns the Spec last written to the named Spec file in the given
+// directory, or nil if the file was not written by the Fixture. The Spec
+// must not be modified, use WriteSpecObject to change it.
+func (f *Fixture) Spec(dir, name string) *cdispec.Spec {
+	return f.specs[path.Join(dir, name)]
+}
+
+// WriteSpecObject writes the given Spec to the named Spec file in the
+// given directory.
+func (f *Fixture) WriteSpecObject(dir, name string, spec *cdispec.Spec) {
+	f.t.Helper()
+
+	spec = spec.DeepCopy()
+	data, err := yaml.Marshal(spec)
+	require.NoError(f.t, err)
+	f.WriteSpec(dir, name, string(data))
+	f.specs[path.Join(dir, name)] = spec
+}
+
+// AddDevice adds the given devices to the named Spec file in the given
+// directory, which must have been written by the Fixture.
+func (f *Fixture) AddDevice(dir, name string, devices ...cdispec.Device) {
+	f.t.Helper()
+
+	spec := f.Spec(dir, name)
+	require.NotNil(f.t, spec, "no Spec %s written by the Fixture", path.Join(dir, name))
+	spec = spec.DeepCopy()
+	for _, d := range devices {
+		spec.Devices = append(spec.Devices, *d.DeepCopy())
+	}
+	f.WriteSpecObject(dir, name, spec)
+}
+
+// Corrupt overwrites the named Spec file in the given directory with
+// data which fails to parse.
+func (f *Fixture) Corrupt(dir, name string) {
+	f.t.Helper()
+
+	f.WriteSpec(dir, name, "cdiVersion: [corrupt\n")
+	delete(f.specs, path.Join(dir, name))
+}
+
+// Remove removes the named Spec file from the given directory.
+func (f *Fixture) Remove(dir, name string) {
+	f.t.Helper()
+
+	f.RemoveSpec(dir, name)
+	delete(f.specs, path.Join(dir, name))
+}
@@ -0,0 +1,151 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package testutil provides a harness for testing integrations of CDI,
+// for instance in container runtimes, against this library. It sets up
+// Spec directories, drives Cache refreshes and runs table-driven device
+// injection scenarios, asserting the resulting OCI Spec changes. A Fixture
+// sets up Spec files from Go structs instead of YAML strings.
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+)
+
+const (
+	// EtcDir is the Spec directory with lower priority, like /etc/cdi.
+	EtcDir = "etc"
+	// RunDir is the Spec directory with higher priority, like /var/run/cdi.
+	RunDir = "run"
+)
+
+// Harness is a Cache using a pair of temporary Spec directories, EtcDir
+// and RunDir. The Cache is refreshed manually, so tests control when
+// changes of the Spec files are picked up.
+type Harness struct {
+	// Cache is the Cache under test.
+	Cache *cdi.Cache
+	// Root is the directory containing the Spec directories.
+	Root string
+
+	t testing.TB
+}
+
+// NewHarness creates a Harness with the given Spec files, by name, in
+// the etc and run Spec directories. The Cache is created with the given
+// options, in addition to its Spec directories and manual refresh, and
+// refreshed once. The Spec directories are removed when the test ends.
+func NewHarness(t testing.TB, etc, run map[string]string, options ...cdi.Option) *Harness {
+	t.Helper()
+
+	h := newHarness(t)
+	h.WriteSpecs(EtcDir, etc)
+	h.WriteSpecs(RunDir, run)
+	h.createCache(options...)
+
+	return h
+}
+
+// newHarness creates a Harness with empty Spec directories, without a
+// Cache.
+func newHarness(t testing.TB) *Harness {
+	t.Helper()
+
+	h := &Harness{
+		Root: t.TempDir(),
+		t:    t,
+	}
+	for _, dir := range []string{EtcDir, RunDir} {
+		require.NoError(t, os.MkdirAll(h.Dir(dir), 0o755))
+	}
+	return h
+}
+
+// createCache creates the Cache of the Harness with the given options, in
+// addition to its Spec directories and manual refresh.
+func (h *Harness) createCache(options ...cdi.Option) {
+	h.t.Helper()
+
+	options = append([]cdi.Option{
+		cdi.WithSpecDirs(h.Dir(EtcDir), h.Dir(RunDir)),
+		cdi.WithAutoRefresh(false),
+	}, options...)
+	cache, err := cdi.NewCache(options...)
+	require.NoError(h.t, err)
+	h.t.Cleanup(func() { _ = cache.Close() })
+	h.Cache = cache
+}
+
+// Dir returns the path of the given Spec directory, EtcDir or RunDir.
+func (h *Harness) Dir(dir string) string {
+	return filepath.Join(h.Root, dir)
+}
+
+// Path returns the path of the named Spec file in the given directory.
+func (h *Harness) Path(dir, name string) string {
+	return filepath.Join(h.Dir(dir), name)
+}
+
+// WriteSpec atomically writes the named Spec file in the given directory.
+// The change is picked up by the next Refresh.
+func (h *Harness) WriteSpec(dir, name, data string) {
+	h.t.Helper()
+
+	path := h.Path(dir, name)
+	tmp := path + ".tmp"
+	require.NoError(h.t, os.WriteFile(tmp, []byte(data), 0o644))
+	require.NoError(h.t, os.Rename(tmp, path))
+}
+
+// WriteSpecs writes the given Spec files, by name, in the given directory.
+func (h *Harness) WriteSpecs(dir string, specs map[string]string) {
+	h.t.Helper()
+
+	for name, data := range specs {
+		h.WriteSpec(dir, name, data)
+	}
+}
+
+// RemoveSpec removes the named Spec file from the given directory. The
+// change is picked up by the next Refresh.
+func (h *Harness) RemoveSpec(dir, name string) {
+	h.t.Helper()
+
+	require.NoError(h.t, os.Remove(h.Path(dir, name)))
+}
+
+// Refresh refreshes the Cache, returning any errors of Spec files.
+func (h *Harness) Refresh() error {
+	return h.Cache.Refresh()
+}
+
+// Inject injects the given devices into the given OCI Spec, or into an
+// empty one if it is nil, returning the OCI Spec, the unresolved devices
+// and any error.
+func (h *Harness) Inject(ociSpec *oci.Spec, devices ...string) (*oci.Spec, []string, error) {
+	if ociSpec == nil {
+		ociSpec = &oci.Spec{}
+	}
+	unresolved, err := h.Cache.InjectDevices(ociSpec, devices...)
+	return ociSpec, unresolved, err
+}
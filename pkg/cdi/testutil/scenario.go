@@ -0,0 +1,156 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package testutil
+
+import (
+	"encoding/json"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+)
+
+// Scenario is a table-driven end-to-end test of device injection. The
+// Spec files are set up, the requested devices are injected into the OCI
+// Spec and the result is checked against the expectations. Expectations
+// which are not set are not checked.
+type Scenario struct {
+	// Name is the name of the scenario, used as the name of the subtest.
+	Name string
+	// EtcSpecs are the Spec files, by name, in the etc Spec directory.
+	EtcSpecs map[string]string
+	// RunSpecs are the Spec files, by name, in the run Spec directory.
+	RunSpecs map[string]string
+	// Options are additional options for the Cache.
+	Options []cdi.Option
+	// OCISpec is the OCI Spec to inject into. It is not modified, the
+	// scenario injects into a copy. An empty OCI Spec is used if nil.
+	OCISpec *oci.Spec
+	// Devices are the devices to inject.
+	Devices []string
+	// Annotations are OCI Spec annotations with injection requests, as
+	// set by container orchestrators. The requested devices are injected
+	// after Devices.
+	Annotations map[string]string
+
+	// ExpectRefreshError requires an error from refreshing the Cache,
+	// for instance for invalid Spec files.
+	ExpectRefreshError bool
+	// ExpectError requires injection to fail with an error containing
+	// the given string.
+	ExpectError string
+	// ExpectUnresolved are the devices expected to be unresolved.
+	ExpectUnresolved []string
+	// Expect is the expected OCI Spec after injection.
+	Expect *oci.Spec
+	// ExpectEnv are the expected environment variables after injection.
+	ExpectEnv []string
+	// ExpectDevices are the expected device nodes after injection.
+	ExpectDevices []oci.LinuxDevice
+	// ExpectMounts are the expected mounts after injection.
+	ExpectMounts []oci.Mount
+	// Check, if set, makes additional checks of the Harness and the
+	// OCI Spec after injection.
+	Check func(t *testing.T, h *Harness, ociSpec *oci.Spec)
+}
+
+// Run runs the given scenarios, each as a subtest.
+func Run(t *testing.T, scenarios []Scenario) {
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.Name, func(t *testing.T) {
+			s.Run(t)
+		})
+	}
+}
+
+// Run runs the scenario.
+func (s *Scenario) Run(t *testing.T) {
+	t.Helper()
+
+	h := NewHarness(t, s.EtcSpecs, s.RunSpecs, s.Options...)
+	if err := h.Refresh(); s.ExpectRefreshError {
+		require.Error(t, err, "expected Cache refresh to fail")
+	} else {
+		require.NoError(t, err, "unexpected Cache refresh error")
+	}
+
+	devices := append([]string{}, s.Devices...)
+	if s.Annotations != nil {
+		_, requested, err := cdi.ParseAnnotations(s.Annotations)
+		if err != nil {
+			if s.ExpectError != "" {
+				require.Contains(t, err.Error(), s.ExpectError)
+				return
+			}
+			require.NoError(t, err, "invalid injection annotations")
+		}
+		devices = append(devices, requested...)
+	}
+
+	ociSpec, unresolved, err := h.Inject(copyOCISpec(t, s.OCISpec), devices...)
+	require.Equal(t, s.ExpectUnresolved, unresolved, "unresolved devices")
+	if s.ExpectError != "" {
+		require.Error(t, err)
+		require.Contains(t, err.Error(), s.ExpectError)
+		return
+	}
+	if s.ExpectUnresolved != nil {
+		require.Error(t, err)
+		return
+	}
+	require.NoError(t, err)
+
+	if s.Expect != nil {
+		require.Equal(t, s.Expect, ociSpec, "OCI Spec")
+	}
+	if s.ExpectEnv != nil {
+		var env []string
+		if ociSpec.Process != nil {
+			env = ociSpec.Process.Env
+		}
+		require.Equal(t, s.ExpectEnv, env, "environment variables")
+	}
+	if s.ExpectDevices != nil {
+		var devices []oci.LinuxDevice
+		if ociSpec.Linux != nil {
+			devices = ociSpec.Linux.Devices
+		}
+		require.Equal(t, s.ExpectDevices, devices, "device nodes")
+	}
+	if s.ExpectMounts != nil {
+		require.Equal(t, s.ExpectMounts, ociSpec.Mounts, "mounts")
+	}
+	if s.Check != nil {
+		s.Check(t, h, ociSpec)
+	}
+}
+
+// copyOCISpec returns a deep copy of the given OCI Spec, or an empty OCI
+// Spec if it is nil.
+func copyOCISpec(t *testing.T, ociSpec *oci.Spec) *oci.Spec {
+	if ociSpec == nil {
+		return &oci.Spec{}
+	}
+	data, err := json.Marshal(ociSpec)
+	require.NoError(t, err)
+	c := &oci.Spec{}
+	require.NoError(t, json.Unmarshal(data, c))
+	return c
+}
@@ -0,0 +1,265 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package validate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// DefaultExternalTimeout is the default timeout for consulting an
+	// external validation service.
+	DefaultExternalTimeout = 5 * time.Second
+	// maxExternalResponseSize is the maximum size of a validation response.
+	maxExternalResponseSize = 1 << 20
+	// maxCachedVerdicts is the maximum number of cached verdicts.
+	maxCachedVerdicts = 1024
+)
+
+// ErrExternalValidatorUnavailable is wrapped by the errors returned when
+// an external validation service can't be consulted and the failure policy
+// is FailClosed.
+var ErrExternalValidatorUnavailable = errors.New("external CDI Spec validator unavailable")
+
+// FailurePolicy defines how Specs are treated if an external validation
+// service can't be consulted.
+type FailurePolicy int
+
+const (
+	// FailClosed rejects Specs if the service can't be consulted.
+	FailClosed FailurePolicy = iota
+	// FailOpen accepts Specs if the service can't be consulted.
+	FailOpen
+)
+
+// ExternalValidationError is the error for a Spec rejected by an external
+// validation service.
+type ExternalValidationError struct {
+	// URL is the URL of the validation service.
+	URL string
+	// Errors are the reasons for the rejection given by the service.
+	Errors []string
+}
+
+// Error returns a description of the rejection.
+func (e *ExternalValidationError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("CDI Spec rejected by %s", e.URL)
+	}
+	return fmt.Sprintf("CDI Spec rejected by %s: %s", e.URL, strings.Join(e.Errors, "; "))
+}
+
+// ExternalOption is an option for WithExternalValidator.
+type ExternalOption func(*externalValidator)
+
+// WithRequestTimeout returns an option to set the timeout for consulting
+// the validation service. The default is DefaultExternalTimeout.
+func WithRequestTimeout(timeout time.Duration) ExternalOption {
+	return func(v *externalValidator) {
+		v.timeout = timeout
+	}
+}
+
+// WithTLSConfig returns an option to set the TLS configuration used for
+// https validation services, for instance for client certificates or a
+// private CA. It is ignored if an HTTP client is set.
+func WithTLSConfig(config *tls.Config) ExternalOption {
+	return func(v *externalValidator) {
+		v.tlsConfig = config
+	}
+}
+
+// WithHTTPClient returns an option to set the HTTP client used to consult
+// the validation service.
+func WithHTTPClient(client *http.Client) ExternalOption {
+	return func(v *externalValidator) {
+		v.client = client
+	}
+}
+
+// WithFailurePolicy returns an option to set how Specs are treated if the
+// validation service can't be consulted. The default is FailClosed.
+func WithFailurePolicy(policy FailurePolicy) ExternalOption {
+	return func(v *externalValidator) {
+		v.policy = policy
+	}
+}
+
+// externalValidator consults an external validation service.
+type externalValidator struct {
+	url       string
+	timeout   time.Duration
+	tlsConfig *tls.Config
+	client    *http.Client
+	policy    FailurePolicy
+
+	lock     sync.Mutex
+	verdicts map[[sha256.Size]byte]error
+}
+
+// externalResponse is the response of an external validation service.
+type externalResponse struct {
+	// Valid is true if the Spec is accepted.
+	Valid *bool `json:"valid"`
+	// Errors are the reasons for rejecting the Spec.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// WithExternalValidator returns a CDI Spec validator which consults the
+// validation service at the given URL. The Spec is POSTed to the service
+// as JSON. The service is expected to respond with status 200 and a JSON
+// document of the form
+//
+//	{"valid": false, "errors": ["reason", ...]}
+//
+// Specs rejected by the service fail validation with an
+// *ExternalValidationError. If the service can't be consulted, because
+// it can't be reached, it times out, or responds with an unexpected status
+// or document, the Spec is rejected with an error wrapping
+// ErrExternalValidatorUnavailable, unless the failure policy is FailOpen.
+//
+// The validator is called synchronously, while the Cache is being
+// refreshed. Until the service responds or the request times out, the
+// refresh, and any Cache operation waiting for it, is blocked. To avoid
+// consulting the service again for Specs which have not changed, the
+// verdicts given by the service are cached by the digest of the Spec for
+// the lifetime of the validator. Failures to consult the service are not
+// cached.
+func WithExternalValidator(url string, options ...ExternalOption) func(*cdi.Spec) error {
+	v := &externalValidator{
+		url:      url,
+		timeout:  DefaultExternalTimeout,
+		verdicts: make(map[[sha256.Size]byte]error),
+	}
+	for _, o := range options {
+		o(v)
+	}
+
+	if v.client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if v.tlsConfig != nil {
+			transport.TLSClientConfig = v.tlsConfig
+		}
+		v.client = &http.Client{Transport: transport}
+	}
+
+	return v.validate
+}
+
+// validate consults the validation service about the given Spec.
+func (v *externalValidator) validate(spec *cdi.Spec) error {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal CDI Spec: %w", err)
+	}
+	digest := sha256.Sum256(data)
+
+	v.lock.Lock()
+	verdict, ok := v.verdicts[digest]
+	v.lock.Unlock()
+	if ok {
+		return verdict
+	}
+
+	err = v.consult(data)
+
+	var rejected *ExternalValidationError
+	if err == nil || errors.As(err, &rejected) {
+		v.cacheVerdict(digest, err)
+		return err
+	}
+	if v.policy == FailOpen {
+		return nil
+	}
+	return fmt.Errorf("%w: %s: %v", ErrExternalValidatorUnavailable, v.url, err)
+}
+
+// cacheVerdict caches the verdict of the validation service for a Spec.
+func (v *externalValidator) cacheVerdict(digest [sha256.Size]byte, verdict error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if len(v.verdicts) >= maxCachedVerdicts {
+		v.verdicts = make(map[[sha256.Size]byte]error)
+	}
+	v.verdicts[digest] = verdict
+}
+
+// consult the validation service about the given JSON-encoded Spec,
+// returning an *ExternalValidationError if the Spec is rejected and any
+// other error if the service fails.
+func (v *externalValidator) consult(data []byte) error {
+	ctx := context.Background()
+	if v.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, v.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	rsp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", rsp.Status)
+	}
+
+	var result externalResponse
+	body, err := io.ReadAll(io.LimitReader(rsp.Body, maxExternalResponseSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(body) > maxExternalResponseSize {
+		return fmt.Errorf("response exceeds %d bytes", maxExternalResponseSize)
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	if result.Valid == nil {
+		return errors.New("invalid response: missing \"valid\"")
+	}
+
+	if !*result.Valid {
+		return &ExternalValidationError{
+			URL:    v.url,
+			Errors: result.Errors,
+		}
+	}
+
+	return nil
+}
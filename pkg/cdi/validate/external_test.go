@@ -0,0 +1,197 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestExternalValidator(t *testing.T) {
+	spec := &cdi.Spec{
+		Version: cdi.CurrentVersion,
+		Kind:    "vendor.com/device",
+		Devices: []cdi.Device{
+			{
+				Name: "dev0",
+				ContainerEdits: cdi.ContainerEdits{
+					Env: []string{"FOO=BAR"},
+				},
+			},
+		},
+	}
+
+	type testCase struct {
+		name        string
+		handler     http.HandlerFunc
+		options     []ExternalOption
+		rejected    []string
+		unavailable bool
+	}
+	for _, tc := range []*testCase{
+		{
+			name: "accepted",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				var received cdi.Spec
+				if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&received) != nil ||
+					received.Kind != spec.Kind {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				_, _ = w.Write([]byte(`{"valid": true}`))
+			},
+		},
+		{
+			name: "rejected",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(`{"valid": false, "errors": ["vendor not allowed"]}`))
+			},
+			rejected: []string{"vendor not allowed"},
+		},
+		{
+			name: "rejected, fail open",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(`{"valid": false}`))
+			},
+			options:  []ExternalOption{WithFailurePolicy(FailOpen)},
+			rejected: []string{},
+		},
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			unavailable: true,
+		},
+		{
+			name: "server error, fail open",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			options: []ExternalOption{WithFailurePolicy(FailOpen)},
+		},
+		{
+			name: "invalid response",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(`{"errors": []}`))
+			},
+			unavailable: true,
+		},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+				_, _ = w.Write([]byte(`{"valid": true}`))
+			},
+			options:     []ExternalOption{WithRequestTimeout(20 * time.Millisecond)},
+			unavailable: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(tc.handler)
+			defer srv.Close()
+
+			err := WithExternalValidator(srv.URL, tc.options...)(spec)
+
+			switch {
+			case tc.rejected != nil:
+				var rejected *ExternalValidationError
+				require.Error(t, err)
+				require.True(t, errors.As(err, &rejected))
+				require.Equal(t, srv.URL, rejected.URL)
+				if len(tc.rejected) > 0 {
+					require.Equal(t, tc.rejected, rejected.Errors)
+				}
+			case tc.unavailable:
+				require.Error(t, err)
+				require.True(t, errors.Is(err, ErrExternalValidatorUnavailable))
+			default:
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestExternalValidatorTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"valid": true}`))
+	}))
+	defer srv.Close()
+
+	spec := &cdi.Spec{Version: cdi.CurrentVersion, Kind: "vendor.com/device"}
+
+	err := WithExternalValidator(srv.URL)(spec)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrExternalValidatorUnavailable))
+
+	tlsConfig := srv.Client().Transport.(*http.Transport).TLSClientConfig
+	err = WithExternalValidator(srv.URL, WithTLSConfig(tlsConfig))(spec)
+	require.NoError(t, err)
+}
+
+func TestExternalValidatorVerdictCache(t *testing.T) {
+	var (
+		requests int
+		status   = http.StatusOK
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		var received cdi.Spec
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if received.Kind == "vendor.com/device" {
+			_, _ = w.Write([]byte(`{"valid": true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"valid": false, "errors": ["vendor not allowed"]}`))
+	}))
+	defer srv.Close()
+
+	validate := WithExternalValidator(srv.URL)
+	accepted := &cdi.Spec{Version: cdi.CurrentVersion, Kind: "vendor.com/device"}
+	rejected := &cdi.Spec{Version: cdi.CurrentVersion, Kind: "other.com/device"}
+
+	require.NoError(t, validate(accepted))
+	require.NoError(t, validate(accepted))
+	require.Equal(t, 1, requests)
+
+	var verdict *ExternalValidationError
+	require.True(t, errors.As(validate(rejected), &verdict))
+	require.True(t, errors.As(validate(rejected), &verdict))
+	require.Equal(t, 2, requests)
+
+	status = http.StatusInternalServerError
+	changed := &cdi.Spec{Version: cdi.CurrentVersion, Kind: "vendor.com/device", Annotations: map[string]string{"a": "b"}}
+	require.True(t, errors.Is(validate(changed), ErrExternalValidatorUnavailable))
+	require.True(t, errors.Is(validate(changed), ErrExternalValidatorUnavailable))
+	require.Equal(t, 4, requests)
+
+	status = http.StatusOK
+	require.NoError(t, validate(changed))
+	require.NoError(t, validate(accepted))
+	require.Equal(t, 5, requests)
+}
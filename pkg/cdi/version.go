@@ -0,0 +1,140 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// IsVersionSupportedBy returns true if a Spec of the given CDI version
+// can be consumed by a consumer supporting CDI versions up to the given
+// maximum version. Versions can be given with or without a leading 'v'.
+// Invalid versions are never supported.
+func IsVersionSupportedBy(specVersion, consumerMaxVersion string) bool {
+	spec, max := semverOf(specVersion), semverOf(consumerMaxVersion)
+	if !semver.IsValid(spec) || !semver.IsValid(max) {
+		return false
+	}
+	return semver.Compare(spec, max) <= 0
+}
+
+// WithMaximumVersion returns an option to set the highest CDI version the
+// Cache should accept. This allows runtimes which pin an older version of
+// this package to handle Specs of newer versions gracefully:
+//   - a newer Spec which only uses features of the maximum version or
+//     earlier is accepted and reported as a *VersionDowngrade,
+//   - any other newer Spec is rejected and reported as an
+//     *UnsupportedVersionError.
+//
+// Both are reported by GetSpecErrors(). Rejections are also reported by
+// GetErrors() and fail the refresh. An empty or invalid version removes
+// any maximum, which is the default.
+func WithMaximumVersion(version string) Option {
+	return func(c *Cache) {
+		c.maxVersion = ""
+		if v := semverOf(version); version != "" && semver.IsValid(v) {
+			c.maxVersion = strings.TrimPrefix(v, "v")
+		}
+	}
+}
+
+// UnsupportedVersionError is the error for a Spec rejected by the Cache
+// because it requires a CDI version newer than the configured maximum.
+type UnsupportedVersionError struct {
+	// Path is the path of the Spec.
+	Path string
+	// Version is the CDI version of the Spec.
+	Version string
+	// RequiredVersion is the minimum CDI version required by the Spec.
+	RequiredVersion string
+	// MaxVersion is the maximum CDI version accepted by the Cache.
+	MaxVersion string
+}
+
+// Error returns a description of the unsupported version.
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("CDI Spec %q requires version %s (declared %s), newer than supported version %s",
+		e.Path, e.RequiredVersion, e.Version, e.MaxVersion)
+}
+
+// VersionDowngrade is reported for a Spec accepted by the Cache despite
+// declaring a CDI version newer than the configured maximum, because it
+// only uses features of the maximum version or earlier.
+type VersionDowngrade struct {
+	// Path is the path of the Spec.
+	Path string
+	// Version is the CDI version of the Spec.
+	Version string
+	// RequiredVersion is the minimum CDI version required by the Spec.
+	RequiredVersion string
+	// MaxVersion is the maximum CDI version accepted by the Cache.
+	MaxVersion string
+}
+
+// Error returns a description of the version downgrade.
+func (d *VersionDowngrade) Error() string {
+	return fmt.Sprintf("CDI Spec %q of version %s accepted as version %s (requires %s)",
+		d.Path, d.Version, d.MaxVersion, d.RequiredVersion)
+}
+
+// checkSpecVersion checks the version of the given Spec against the
+// configured maximum version. It returns false and an error if the Spec
+// must be rejected. It returns true and a *VersionDowngrade if the Spec
+// is accepted despite declaring a newer version.
+func (c *Cache) checkSpecVersion(spec *Spec) (bool, error) {
+	if c.maxVersion == "" || IsVersionSupportedBy(spec.Version, c.maxVersion) {
+		return true, nil
+	}
+
+	required, err := cdi.MinimumRequiredVersion(spec.Spec)
+	if err != nil {
+		return false, fmt.Errorf("failed to determine required version of CDI Spec %q: %w",
+			spec.GetPath(), err)
+	}
+
+	if IsVersionSupportedBy(required, c.maxVersion) {
+		return true, &VersionDowngrade{
+			Path:            spec.GetPath(),
+			Version:         spec.Version,
+			RequiredVersion: required,
+			MaxVersion:      c.maxVersion,
+		}
+	}
+
+	return false, &UnsupportedVersionError{
+		Path:            spec.GetPath(),
+		Version:         spec.Version,
+		RequiredVersion: required,
+		MaxVersion:      c.maxVersion,
+	}
+}
+
+// isNewerVersion returns true if the given version is a valid version
+// newer than the current CDI version.
+func isNewerVersion(version string) bool {
+	v := semverOf(version)
+	return semver.IsValid(v) && semver.Compare(v, semverOf(cdi.CurrentVersion)) > 0
+}
+
+// semverOf returns the given version with a leading 'v'.
+func semverOf(version string) string {
+	return "v" + strings.TrimPrefix(version, "v")
+}
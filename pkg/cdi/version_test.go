@@ -0,0 +1,136 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsVersionSupportedBy(t *testing.T) {
+	for _, tc := range []struct {
+		spec      string
+		max       string
+		supported bool
+	}{
+		{spec: "0.3.0", max: "0.8.0", supported: true},
+		{spec: "0.8.0", max: "0.8.0", supported: true},
+		{spec: "v0.8.0", max: "0.8.0", supported: true},
+		{spec: "0.9.0", max: "v0.8.0", supported: false},
+		{spec: "1.0.0", max: "0.9.0", supported: false},
+		{spec: "invalid", max: "0.9.0", supported: false},
+		{spec: "0.3.0", max: "", supported: false},
+	} {
+		require.Equal(t, tc.supported, IsVersionSupportedBy(tc.spec, tc.max),
+			"spec version %q, maximum version %q", tc.spec, tc.max)
+	}
+}
+
+func TestCacheMaximumVersion(t *testing.T) {
+	etc := map[string]string{
+		"old.yaml": `
+cdiVersion: "0.6.0"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR1_DEV1=1"
+`,
+		"downgraded.yaml": `
+cdiVersion: "0.9.0"
+kind:       "vendor2.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR2_DEV1=1"
+`,
+		"rejected.yaml": `
+cdiVersion: "0.9.0"
+kind:       "vendor3.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR3_DEV1=1"
+groups:
+  - name: "all"
+    devices: [ "dev1" ]
+`,
+	}
+
+	dir, err := createSpecDirs(t, etc, nil)
+	require.NoError(t, err)
+
+	cache, err := NewCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+		WithMaximumVersion("0.8.0"),
+	)
+	require.NoError(t, err)
+
+	require.Equal(t,
+		[]string{
+			"vendor1.com/device=dev1",
+			"vendor2.com/device=dev1",
+		},
+		cache.ListDevices(),
+	)
+
+	errs := cache.GetErrors()
+	require.Len(t, errs, 1)
+	rejectedPath := filepath.Join(dir, "etc", "rejected.yaml")
+	require.Len(t, errs[rejectedPath], 1)
+	var unsupported *UnsupportedVersionError
+	require.True(t, errors.As(errs[rejectedPath][0], &unsupported))
+	require.Equal(t,
+		&UnsupportedVersionError{
+			Path:            rejectedPath,
+			Version:         "0.9.0",
+			RequiredVersion: "0.9.0",
+			MaxVersion:      "0.8.0",
+		},
+		unsupported,
+	)
+
+	old := cache.GetDevice("vendor1.com/device=dev1").GetSpec()
+	require.Empty(t, cache.GetSpecErrors(old))
+
+	downgraded := cache.GetDevice("vendor2.com/device=dev1").GetSpec()
+	require.Equal(t,
+		[]error{
+			&VersionDowngrade{
+				Path:            downgraded.GetPath(),
+				Version:         "0.9.0",
+				RequiredVersion: "0.3.0",
+				MaxVersion:      "0.8.0",
+			},
+		},
+		cache.GetSpecErrors(downgraded),
+	)
+
+	require.Error(t, cache.Refresh())
+
+	// lifting the maximum accepts all Specs
+	require.NoError(t, cache.Configure(WithMaximumVersion("")))
+	require.Empty(t, cache.GetErrors())
+	require.Len(t, cache.ListDevices(), 3)
+}
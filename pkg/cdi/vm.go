@@ -0,0 +1,222 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+const (
+	// VMAnnotationPrefix is the prefix of the annotations used to pass
+	// device information to VM-based runtimes.
+	VMAnnotationPrefix = "vm.cdi.k8s.io/"
+
+	// VMDevicesAnnotation is the OCI Spec annotation set by VMApplier to
+	// the comma-separated qualified names of the injected devices.
+	VMDevicesAnnotation = VMAnnotationPrefix + "devices"
+	// VMVFIODevicesAnnotation is the OCI Spec annotation set by VMApplier
+	// to the comma-separated PCI addresses (BDFs) of the VFIO devices the
+	// VMM should pass through to the VM.
+	VMVFIODevicesAnnotation = VMAnnotationPrefix + "vfio-devices"
+	// VMVFIOGroupsAnnotation is the OCI Spec annotation set by VMApplier
+	// to the comma-separated host paths of the injected VFIO group device
+	// nodes (/dev/vfio/<group>).
+	VMVFIOGroupsAnnotation = VMAnnotationPrefix + "vfio-groups"
+	// VMBlockDevicesAnnotation is the OCI Spec annotation set by VMApplier
+	// to the comma-separated host paths of the injected block device nodes.
+	VMBlockDevicesAnnotation = VMAnnotationPrefix + "block-devices"
+
+	// PCIAddressAnnotation is a CDI device annotation which lists the
+	// comma-separated PCI addresses (BDFs) of the VFIO devices behind a
+	// CDI device. If it is not set, VMApplier looks up the addresses of
+	// the device's VFIO groups in sysfs.
+	PCIAddressAnnotation = VMAnnotationPrefix + "pci-address"
+
+	// vfioDir is the directory of VFIO device nodes.
+	vfioDir = "/dev/vfio"
+	// vfioContainer is the name of the VFIO container device node.
+	vfioContainer = "vfio"
+)
+
+// VMApplier is an Applier for VM-based runtimes. It applies the edits like
+// DefaultApplier, then renders the parts of the edits the VMM needs to
+// know about into a documented set of OCI Spec annotations:
+//
+//   - VMDevicesAnnotation: the qualified names of the injected devices
+//   - VMVFIODevicesAnnotation: the PCI addresses of the VFIO devices
+//   - VMVFIOGroupsAnnotation: the host paths of the VFIO group device nodes
+//   - VMBlockDevicesAnnotation: the host paths of the block device nodes
+//
+// Annotations without any value are not set. Existing annotations with
+// the same keys are overwritten.
+type VMApplier struct {
+	sysfsRoot string
+}
+
+// VMApplierOption is an option for a VMApplier.
+type VMApplierOption func(*VMApplier)
+
+// WithSysfsRoot returns an option to set the root of the sysfs hierarchy
+// used to look up the PCI addresses of VFIO groups. The default is /sys.
+func WithSysfsRoot(root string) VMApplierOption {
+	return func(a *VMApplier) {
+		a.sysfsRoot = root
+	}
+}
+
+// NewVMApplier creates a new VMApplier with the given options.
+func NewVMApplier(options ...VMApplierOption) *VMApplier {
+	a := &VMApplier{
+		sysfsRoot: "/sys",
+	}
+	for _, o := range options {
+		o(a)
+	}
+	return a
+}
+
+// Apply implements Applier. The VFIO and block devices are taken from the
+// given resolved edits, so they reflect any host root, conflict resolution
+// and deduplication applied during injection.
+func (a *VMApplier) Apply(ociSpec *oci.Spec, edits *ContainerEdits, devices []*Device) error {
+	if err := edits.Apply(ociSpec); err != nil {
+		return err
+	}
+
+	var (
+		names     []string
+		pci       = &stringList{}
+		groups    = &stringList{}
+		block     = &stringList{}
+		annotated = map[string]struct{}{}
+	)
+
+	// the PCI addresses of the VFIO groups of annotated devices are known,
+	// VFIO groups are matched by their container path
+	for _, d := range devices {
+		names = append(names, d.GetQualifiedName())
+		addresses := splitAnnotationList(d.Annotations[PCIAddressAnnotation])
+		if len(addresses) == 0 {
+			continue
+		}
+		pci.add(addresses...)
+		for _, dn := range d.ContainerEdits.DeviceNodes {
+			annotated[dn.Path] = struct{}{}
+		}
+	}
+
+	if edits != nil && edits.ContainerEdits != nil {
+		for _, dn := range edits.DeviceNodes {
+			hostPath := dn.HostPath
+			if hostPath == "" {
+				hostPath = dn.Path
+			}
+			if dn.Type == BlockDeviceType {
+				block.add(hostPath)
+			}
+			if !isVFIOGroup(hostPath) {
+				continue
+			}
+			groups.add(hostPath)
+			if _, ok := annotated[dn.Path]; ok {
+				continue
+			}
+			addresses, err := a.vfioGroupDevices(path.Base(hostPath))
+			if err != nil {
+				return err
+			}
+			pci.add(addresses...)
+		}
+	}
+
+	if ociSpec.Annotations == nil {
+		ociSpec.Annotations = map[string]string{}
+	}
+	for key, values := range map[string][]string{
+		VMDevicesAnnotation:      names,
+		VMVFIODevicesAnnotation:  pci.values,
+		VMVFIOGroupsAnnotation:   groups.values,
+		VMBlockDevicesAnnotation: block.values,
+	} {
+		if len(values) > 0 {
+			ociSpec.Annotations[key] = strings.Join(values, ",")
+		}
+	}
+
+	return nil
+}
+
+// vfioGroupDevices returns the PCI addresses of the devices in the given
+// VFIO (IOMMU) group.
+func (a *VMApplier) vfioGroupDevices(group string) ([]string, error) {
+	dir := filepath.Join(a.sysfsRoot, "kernel", "iommu_groups", group, "devices")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up devices of VFIO group %q: %w", group, err)
+	}
+	var addresses []string
+	for _, e := range entries {
+		addresses = append(addresses, e.Name())
+	}
+	sort.Strings(addresses)
+	return addresses, nil
+}
+
+// isVFIOGroup returns true if the given path is a VFIO group device node,
+// possibly under a host root.
+func isVFIOGroup(hostPath string) bool {
+	dir, name := path.Split(path.Clean(hostPath))
+	dir = path.Clean(dir)
+	return (dir == vfioDir || strings.HasSuffix(dir, vfioDir)) && name != vfioContainer
+}
+
+// splitAnnotationList splits a comma-separated annotation value.
+func splitAnnotationList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// stringList is a list of unique strings in insertion order.
+type stringList struct {
+	values []string
+	seen   map[string]struct{}
+}
+
+// add the given strings to the list, skipping any duplicates.
+func (l *stringList) add(values ...string) {
+	if l.seen == nil {
+		l.seen = map[string]struct{}{}
+	}
+	for _, v := range values {
+		if _, ok := l.seen[v]; !ok {
+			l.seen[v] = struct{}{}
+			l.values = append(l.values, v)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+)
+
+const vmTestSpecs = `
+cdiVersion: "0.6.0"
+kind:       "vendor1.com/gpu"
+containerEdits:
+  deviceNodes:
+  - path: "/dev/vfio/vfio"
+    type: c
+    major: 10
+    minor: 196
+devices:
+  - name: "gpu0"
+    annotations:
+      vm.cdi.k8s.io/pci-address: "0000:3b:00.0, 0000:3b:00.1"
+    containerEdits:
+      deviceNodes:
+      - path: "/dev/vfio/7"
+        type: c
+        major: 240
+        minor: 7
+  - name: "gpu1"
+    containerEdits:
+      deviceNodes:
+      - path: "/dev/vfio/12"
+        type: c
+        major: 240
+        minor: 12
+  - name: "disk0"
+    containerEdits:
+      deviceNodes:
+      - path: "/dev/xvda"
+        hostPath: "/dev/vdb"
+        type: b
+        major: 253
+        minor: 16
+`
+
+func TestVMApplier(t *testing.T) {
+	dir, err := createSpecDirs(t, map[string]string{"vendor1.yaml": vmTestSpecs}, nil)
+	require.NoError(t, err)
+
+	sysfs := t.TempDir()
+	for _, bdf := range []string{"0000:af:00.1", "0000:af:00.0"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(sysfs, "kernel", "iommu_groups", "12", "devices", bdf), 0o755))
+	}
+
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+		WithApplier(NewVMApplier(WithSysfsRoot(sysfs))),
+	)
+	require.Empty(t, cache.GetErrors())
+
+	ociSpec := &oci.Spec{
+		Annotations: map[string]string{
+			"other": "annotation",
+		},
+	}
+	_, err = cache.InjectDevices(ociSpec, "vendor1.com/gpu=gpu0", "vendor1.com/gpu=gpu1", "vendor1.com/gpu=disk0")
+	require.NoError(t, err)
+
+	require.Equal(t,
+		map[string]string{
+			"other":                  "annotation",
+			VMDevicesAnnotation:      "vendor1.com/gpu=gpu0,vendor1.com/gpu=gpu1,vendor1.com/gpu=disk0",
+			VMVFIODevicesAnnotation:  "0000:3b:00.0,0000:3b:00.1,0000:af:00.0,0000:af:00.1",
+			VMVFIOGroupsAnnotation:   "/dev/vfio/7,/dev/vfio/12",
+			VMBlockDevicesAnnotation: "/dev/vdb",
+		},
+		ociSpec.Annotations,
+	)
+
+	// edits are applied as usual
+	var paths []string
+	for _, d := range ociSpec.Linux.Devices {
+		paths = append(paths, d.Path)
+	}
+	require.Equal(t, []string{"/dev/vfio/vfio", "/dev/vfio/7", "/dev/vfio/12", "/dev/xvda"}, paths)
+
+	// annotations are derived from the resolved edits
+	ociSpec = &oci.Spec{}
+	cache = newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+		WithHostRoot("/host"),
+		WithApplier(NewVMApplier(WithSysfsRoot(sysfs))),
+	)
+	_, err = cache.InjectDevices(ociSpec, "vendor1.com/gpu=gpu0", "vendor1.com/gpu=gpu1", "vendor1.com/gpu=disk0")
+	require.NoError(t, err)
+	require.Equal(t,
+		map[string]string{
+			VMDevicesAnnotation:      "vendor1.com/gpu=gpu0,vendor1.com/gpu=gpu1,vendor1.com/gpu=disk0",
+			VMVFIODevicesAnnotation:  "0000:3b:00.0,0000:3b:00.1,0000:af:00.0,0000:af:00.1",
+			VMVFIOGroupsAnnotation:   "/host/dev/vfio/7,/host/dev/vfio/12",
+			VMBlockDevicesAnnotation: "/host/dev/vdb",
+		},
+		ociSpec.Annotations,
+	)
+
+	// the PCI addresses of VFIO groups must be resolvable
+	ociSpec = &oci.Spec{}
+	cache = newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+		WithApplier(NewVMApplier(WithSysfsRoot(t.TempDir()))),
+	)
+	_, err = cache.InjectDevices(ociSpec, "vendor1.com/gpu=gpu1")
+	require.Error(t, err)
+
+	// without VFIO or block devices only the devices are annotated
+	ociSpec = &oci.Spec{}
+	_, err = cache.InjectDevices(ociSpec, "vendor1.com/gpu=gpu0")
+	require.NoError(t, err)
+	require.Equal(t,
+		map[string]string{
+			VMDevicesAnnotation:     "vendor1.com/gpu=gpu0",
+			VMVFIODevicesAnnotation: "0000:3b:00.0,0000:3b:00.1",
+			VMVFIOGroupsAnnotation:  "/dev/vfio/7",
+		},
+		ociSpec.Annotations,
+	)
+}
+
+func TestWithApplier(t *testing.T) {
+	dir, err := createSpecDirs(t, map[string]string{"vendor1.yaml": vmTestSpecs}, nil)
+	require.NoError(t, err)
+
+	var injected []string
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithAutoRefresh(false),
+		WithApplier(ApplierFunc(func(_ *oci.Spec, edits *ContainerEdits, devices []*Device) error {
+			require.Len(t, edits.DeviceNodes, 3)
+			for _, d := range devices {
+				injected = append(injected, d.GetQualifiedName())
+			}
+			return nil
+		})),
+	)
+
+	ociSpec := &oci.Spec{}
+	_, err = cache.InjectDevices(ociSpec, "vendor1.com/gpu=disk0", "vendor1.com/gpu=gpu0")
+	require.NoError(t, err)
+	require.Equal(t, []string{"vendor1.com/gpu=disk0", "vendor1.com/gpu=gpu0"}, injected)
+	require.Equal(t, &oci.Spec{}, ociSpec)
+
+	require.NoError(t, cache.Configure(WithApplier(nil)))
+	_, err = cache.InjectDevices(ociSpec, "vendor1.com/gpu=disk0")
+	require.NoError(t, err)
+	require.Len(t, ociSpec.Linux.Devices, 2)
+}
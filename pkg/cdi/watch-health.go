@@ -0,0 +1,147 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// DefaultWatchRetryInterval is the default interval of attempts to
+	// re-arm a degraded Spec directory watch.
+	DefaultWatchRetryInterval = 30 * time.Second
+)
+
+// newWatcher creates the watcher for Spec directories. It is a variable
+// so that tests can simulate failures.
+var newWatcher = fsnotify.NewWatcher
+
+// WithWatchRetryInterval returns an option to set the interval at which
+// the Cache tries to re-arm a degraded Spec directory watch, for instance
+// after hitting the limits of inotify instances (EMFILE) or watches
+// (ENOSPC). Once re-armed, the Cache is refreshed to pick up any changes
+// missed meanwhile. An interval of 0 selects DefaultWatchRetryInterval.
+// A negative interval disables re-arm attempts, leaving it to lookups and
+// injections to retry watching directories not watched yet.
+func WithWatchRetryInterval(interval time.Duration) Option {
+	return func(c *Cache) {
+		c.watchRetryInterval = interval
+	}
+}
+
+// WithWatchDegradedHandler returns an option to set a function to call
+// when the Spec directory watch of the Cache becomes degraded, with the
+// error which caused it. While the watch is degraded, changes to Spec
+// files might go unnoticed until the watch is re-armed. The function is
+// called from a separate goroutine, so it can safely use the Cache.
+func WithWatchDegradedHandler(fn func(error)) Option {
+	return func(c *Cache) {
+		c.onWatchDegraded = fn
+	}
+}
+
+// WatcherHealthy returns true if the Cache is watching all of its existing
+// Spec directories for changes. It returns false if auto-refresh is off,
+// if the watcher could not be created, or if any existing Spec directory
+// could not be watched.
+func (c *Cache) WatcherHealthy() bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.autoRefresh && c.watch.healthy()
+}
+
+// WatcherError returns the last error encountered by the Spec directory
+// watch of the Cache, or nil if there was none. The error is retained
+// after the watch recovers.
+func (c *Cache) WatcherError() error {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.watch.err
+}
+
+// getWatchRetryInterval returns the interval of watch re-arm attempts.
+func (c *Cache) getWatchRetryInterval() time.Duration {
+	if c.watchRetryInterval == 0 {
+		return DefaultWatchRetryInterval
+	}
+	return c.watchRetryInterval
+}
+
+// watchDegraded is called with the Cache locked when its watch becomes
+// degraded. It notifies any handler and starts re-arm attempts.
+func (c *Cache) watchDegraded(err error) {
+	if fn := c.onWatchDegraded; fn != nil {
+		go fn(err)
+	}
+
+	w := c.watch
+	if w.retrying || c.getWatchRetryInterval() < 0 {
+		return
+	}
+	w.retrying = true
+	go c.retryWatch(w.done, c.getWatchRetryInterval())
+}
+
+// retryWatch periodically tries to re-arm the watch until it is healthy
+// again or stopped.
+func (c *Cache) retryWatch(done <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		c.Lock()
+		select {
+		case <-done:
+			c.Unlock()
+			return
+		default:
+		}
+		if c.rearmWatch() {
+			c.watch.retrying = false
+			c.Unlock()
+			return
+		}
+		c.Unlock()
+	}
+}
+
+// rearmWatch tries to re-arm the watch, recreating the watcher if
+// necessary. It refreshes the Cache if any Spec directory is watched
+// again. It returns true if the watch is healthy afterwards.
+func (c *Cache) rearmWatch() bool {
+	w := c.watch
+	if w.watcher == nil {
+		if !w.create(c.dirErrors) {
+			return false
+		}
+		w.start(&c.RWMutex, c.refreshWatched, c.dirErrors)
+	}
+	if w.update(c.dirErrors) {
+		_ = c.refresh() // we record but ignore errors
+	}
+	return w.healthy()
+}
@@ -0,0 +1,112 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cdi
+
+import (
+	"errors"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchRecovery(t *testing.T) {
+	dir, err := createSpecDirs(t, map[string]string{
+		"vendor1.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor1.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR1_VAR1=VAL1"
+`,
+	}, nil)
+	require.NoError(t, err)
+
+	newWatcher = func() (*fsnotify.Watcher, error) {
+		return nil, syscall.EMFILE
+	}
+	defer func() {
+		newWatcher = fsnotify.NewWatcher
+	}()
+
+	degraded := make(chan error, 1)
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc")),
+		WithWatchRetryInterval(10*time.Millisecond),
+		WithWatchDegradedHandler(func(err error) {
+			degraded <- err
+		}),
+	)
+	defer cache.Close()
+
+	select {
+	case err := <-degraded:
+		require.True(t, errors.Is(err, syscall.EMFILE))
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watch degradation was not reported")
+	}
+	require.NotNil(t, cache.GetDevice("vendor1.com/device=dev1"))
+
+	// still degraded while the watcher can't be created
+	time.Sleep(50 * time.Millisecond)
+	require.False(t, cache.WatcherHealthy())
+	require.True(t, errors.Is(cache.WatcherError(), syscall.EMFILE))
+
+	cache.Lock()
+	newWatcher = fsnotify.NewWatcher
+	cache.Unlock()
+
+	require.Eventually(t, cache.WatcherHealthy, 5*time.Second, 10*time.Millisecond)
+
+	// the re-armed watch picks up changes
+	require.NoError(t, updateSpecDirs(dir, map[string]string{
+		"vendor2.yaml": `
+cdiVersion: "0.3.0"
+kind:       "vendor2.com/device"
+devices:
+  - name: "dev1"
+    containerEdits:
+      env:
+      - "VENDOR2_VAR1=VAL1"
+`,
+	}, nil))
+	require.Eventually(t, func() bool {
+		return cache.GetDevice("vendor2.com/device=dev1") != nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestWatcherHealth(t *testing.T) {
+	dir, err := createSpecDirs(t, nil, nil)
+	require.NoError(t, err)
+
+	cache := newCache(
+		WithSpecDirs(filepath.Join(dir, "etc"), filepath.Join(dir, "missing")),
+	)
+	defer cache.Close()
+
+	// missing Spec directories do not degrade the watch
+	require.True(t, cache.WatcherHealthy())
+	require.NoError(t, cache.WatcherError())
+
+	require.NoError(t, cache.Configure(WithAutoRefresh(false)))
+	require.False(t, cache.WatcherHealthy())
+}
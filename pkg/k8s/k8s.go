@@ -0,0 +1,172 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package k8s provides helpers for Kubernetes device plugins and Dynamic
+// Resource Allocation (DRA) drivers which hand CDI devices to the kubelet.
+//
+// The types in this package mirror the corresponding kubelet API types
+// without depending on the Kubernetes modules. They have the same fields,
+// so converting them to the kubelet types is a plain field copy:
+//
+//	for _, d := range k8s.ToCDIDevices(devices...) {
+//	    resp.CDIDevices = append(resp.CDIDevices, &v1beta1.CDIDevice{Name: d.Name})
+//	}
+package k8s
+
+import (
+	"fmt"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/parser"
+)
+
+// CDIDevice mirrors the CDIDevice type of the kubelet device plugin API
+// (k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1).
+type CDIDevice struct {
+	// Name is the fully qualified CDI device name.
+	Name string
+}
+
+// ToCDIDevices converts the given fully qualified CDI device names to
+// CDIDevices. Invalid device names are not checked, use ValidateDevices
+// for that.
+func ToCDIDevices(devices ...string) []*CDIDevice {
+	if len(devices) == 0 {
+		return nil
+	}
+	cdiDevices := make([]*CDIDevice, 0, len(devices))
+	for _, d := range devices {
+		cdiDevices = append(cdiDevices, &CDIDevice{Name: d})
+	}
+	return cdiDevices
+}
+
+// FromCDIDevices converts the given CDIDevices to fully qualified CDI
+// device names. It returns an error if any of the names is invalid.
+func FromCDIDevices(cdiDevices []*CDIDevice) ([]string, error) {
+	var devices []string
+	for _, d := range cdiDevices {
+		if d == nil {
+			continue
+		}
+		devices = append(devices, d.Name)
+	}
+	if err := ValidateDevices(devices...); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// ValidateDevices checks that the given devices are fully qualified CDI
+// device names, as expected by the kubelet in device plugin CDIDevices
+// and in the CDIDeviceIDs of DRA drivers.
+func ValidateDevices(devices ...string) error {
+	for _, d := range devices {
+		if _, _, _, err := parser.ParseQualifiedName(d); err != nil {
+			return fmt.Errorf("invalid CDI device %q: %w", d, err)
+		}
+	}
+	return nil
+}
+
+// Mode defines how CDI devices are passed to the container runtime in a
+// device plugin allocation.
+type Mode int
+
+const (
+	// ModeCDIDevices passes devices in the CDIDevices field, which the
+	// kubelet forwards to the runtime over CRI. This requires the
+	// DevicePluginCDIDevices feature of the kubelet.
+	ModeCDIDevices Mode = iota
+	// ModeAnnotations passes devices in CDI annotations, for kubelets and
+	// runtimes without support for the CDIDevices field.
+	ModeAnnotations
+	// ModeBoth passes devices both ways, for clusters with a mix of
+	// kubelet and runtime versions. Runtimes deduplicate the devices.
+	ModeBoth
+)
+
+// ContainerAllocation is the CDI part of a kubelet device plugin
+// ContainerAllocateResponse.
+type ContainerAllocation struct {
+	// CDIDevices are the devices to pass in the CDIDevices field.
+	CDIDevices []*CDIDevice
+	// Annotations are the annotations to pass in the Annotations field.
+	Annotations map[string]string
+}
+
+// NewContainerAllocation returns the CDI part of a device plugin allocation
+// for the given devices, using the given Mode. The plugin and deviceID are
+// used for generating the annotation key, as described for
+// cdi.UpdateAnnotations. The deviceID is typically the ID of the allocated
+// device, or a unique ID of the allocation.
+func NewContainerAllocation(mode Mode, plugin, deviceID string, devices ...string) (*ContainerAllocation, error) {
+	if err := ValidateDevices(devices...); err != nil {
+		return nil, err
+	}
+
+	switch mode {
+	case ModeCDIDevices, ModeAnnotations, ModeBoth:
+	default:
+		return nil, fmt.Errorf("invalid CDI allocation mode %d", mode)
+	}
+
+	alloc := &ContainerAllocation{}
+
+	if mode != ModeAnnotations {
+		alloc.CDIDevices = ToCDIDevices(devices...)
+	}
+	if mode != ModeCDIDevices && len(devices) > 0 {
+		annotations, err := cdi.UpdateAnnotations(nil, plugin, deviceID, devices)
+		if err != nil {
+			return nil, err
+		}
+		alloc.Annotations = annotations
+	}
+
+	return alloc, nil
+}
+
+// Devices returns the CDI devices of the allocation, collected from both
+// the CDIDevices and any CDI annotations, with duplicates removed. Devices
+// from the CDIDevices field come first, followed by the devices from the
+// annotations in the order defined by cdi.ParseAnnotations.
+func (a *ContainerAllocation) Devices() ([]string, error) {
+	fromField, err := FromCDIDevices(a.CDIDevices)
+	if err != nil {
+		return nil, err
+	}
+	_, fromAnnotations, err := cdi.ParseAnnotations(a.Annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		devices []string
+		seen    = map[string]struct{}{}
+	)
+	for _, list := range [][]string{fromField, fromAnnotations} {
+		for _, d := range list {
+			if _, ok := seen[d]; ok {
+				continue
+			}
+			seen[d] = struct{}{}
+			devices = append(devices, d)
+		}
+	}
+
+	return devices, nil
+}
@@ -0,0 +1,102 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCDIDevices(t *testing.T) {
+	devices := []string{"vendor.com/gpu=0", "vendor.com/gpu=1"}
+
+	cdiDevices := ToCDIDevices(devices...)
+	require.Equal(t, []*CDIDevice{{Name: "vendor.com/gpu=0"}, {Name: "vendor.com/gpu=1"}}, cdiDevices)
+	require.Nil(t, ToCDIDevices())
+
+	converted, err := FromCDIDevices(cdiDevices)
+	require.NoError(t, err)
+	require.Equal(t, devices, converted)
+
+	_, err = FromCDIDevices([]*CDIDevice{{Name: "gpu0"}})
+	require.Error(t, err)
+}
+
+func TestContainerAllocation(t *testing.T) {
+	devices := []string{"vendor.com/gpu=0", "vendor.com/gpu=1"}
+
+	for _, tc := range []struct {
+		name        string
+		mode        Mode
+		devices     []string
+		cdiDevices  bool
+		annotations bool
+		invalid     bool
+	}{
+		{
+			name:       "CDIDevices field",
+			mode:       ModeCDIDevices,
+			devices:    devices,
+			cdiDevices: true,
+		},
+		{
+			name:        "annotations",
+			mode:        ModeAnnotations,
+			devices:     devices,
+			annotations: true,
+		},
+		{
+			name:        "both",
+			mode:        ModeBoth,
+			devices:     devices,
+			cdiDevices:  true,
+			annotations: true,
+		},
+		{
+			name: "no devices",
+			mode: ModeBoth,
+		},
+		{
+			name:    "invalid mode",
+			mode:    Mode(42),
+			devices: devices,
+			invalid: true,
+		},
+		{
+			name:    "invalid device",
+			mode:    ModeCDIDevices,
+			devices: []string{"gpu0"},
+			invalid: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc, err := NewContainerAllocation(tc.mode, "vendor.gpu", "alloc-1", tc.devices...)
+			if tc.invalid {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.cdiDevices, alloc.CDIDevices != nil)
+			require.Equal(t, tc.annotations, alloc.Annotations != nil)
+
+			parsed, err := alloc.Devices()
+			require.NoError(t, err)
+			require.Equal(t, tc.devices, parsed)
+		})
+	}
+}
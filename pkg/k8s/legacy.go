@@ -0,0 +1,160 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package k8s
+
+import (
+	"fmt"
+	"strings"
+
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// DeviceSpec mirrors the DeviceSpec type of the kubelet device plugin API.
+type DeviceSpec struct {
+	// ContainerPath is the path of the device in the container.
+	ContainerPath string
+	// HostPath is the path of the device on the host.
+	HostPath string
+	// Permissions are the cgroup permissions of the device, a combination
+	// of r (read), w (write) and m (mknod).
+	Permissions string
+}
+
+// Mount mirrors the Mount type of the kubelet device plugin API.
+type Mount struct {
+	// ContainerPath is the path of the mount in the container.
+	ContainerPath string
+	// HostPath is the path of the mount on the host.
+	HostPath string
+	// ReadOnly is set for read-only mounts.
+	ReadOnly bool
+}
+
+// LegacyAllocation holds the fields of a kubelet device plugin
+// ContainerAllocateResponse used by plugins for kubelets and runtimes
+// without CDI support.
+type LegacyAllocation struct {
+	// Envs are the environment variables to set in the container.
+	Envs map[string]string
+	// Mounts are the mounts to add to the container.
+	Mounts []*Mount
+	// Devices are the device nodes to add to the container.
+	Devices []*DeviceSpec
+}
+
+// NewLegacyAllocation converts the given, resolved container edits to a
+// LegacyAllocation. This lets device plugins use their CDI Specs as the
+// single source of truth for both CDI-enabled and legacy kubelets. The
+// edits are typically obtained with cdi.Cache.GetDeviceEdits().
+//
+// The device plugin API only covers a part of what the edits can express.
+// Environment variables are set in the order of the edits, later values
+// replacing earlier ones. Mount options other than read-only are dropped.
+// An error is returned for any other edits, like hooks or non-bind mounts,
+// which the device plugin API can't express.
+func NewLegacyAllocation(edits *cdi.ContainerEdits) (*LegacyAllocation, error) {
+	alloc := &LegacyAllocation{}
+	if edits == nil || edits.ContainerEdits == nil {
+		return alloc, nil
+	}
+
+	var unsupported []string
+	if len(edits.Hooks) > 0 {
+		unsupported = append(unsupported, "hooks")
+	}
+	if edits.IntelRdt != nil {
+		unsupported = append(unsupported, "Intel RDT")
+	}
+	if len(edits.AdditionalGIDs) > 0 {
+		unsupported = append(unsupported, "additional GIDs")
+	}
+	if edits.Capabilities != nil {
+		unsupported = append(unsupported, "capabilities")
+	}
+	if len(unsupported) > 0 {
+		return nil, fmt.Errorf("CDI edits not supported by the device plugin API: %s",
+			strings.Join(unsupported, ", "))
+	}
+
+	for _, env := range edits.Env {
+		name, value, ok := strings.Cut(env, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid environment variable %q", env)
+		}
+		if alloc.Envs == nil {
+			alloc.Envs = map[string]string{}
+		}
+		alloc.Envs[name] = value
+	}
+
+	for _, d := range edits.DeviceNodes {
+		dev, err := toDeviceSpec(d)
+		if err != nil {
+			return nil, err
+		}
+		alloc.Devices = append(alloc.Devices, dev)
+	}
+
+	for _, m := range edits.Mounts {
+		mnt, err := toMount(m)
+		if err != nil {
+			return nil, err
+		}
+		alloc.Mounts = append(alloc.Mounts, mnt)
+	}
+
+	return alloc, nil
+}
+
+func toDeviceSpec(d *cdispec.DeviceNode) (*DeviceSpec, error) {
+	if d.DenyAccess || d.Permissions == cdispec.DevicePermissionsNone {
+		return nil, fmt.Errorf("device %q: device plugin API can't restrict device access", d.Path)
+	}
+	dev := &DeviceSpec{
+		ContainerPath: d.Path,
+		HostPath:      d.HostPath,
+		Permissions:   d.Permissions,
+	}
+	if dev.HostPath == "" {
+		dev.HostPath = d.Path
+	}
+	if dev.Permissions == "" {
+		dev.Permissions = "rwm"
+	}
+	return dev, nil
+}
+
+func toMount(m *cdispec.Mount) (*Mount, error) {
+	if m.Type != "" && m.Type != "bind" {
+		return nil, fmt.Errorf("mount %q: device plugin API only supports bind mounts, got type %q",
+			m.ContainerPath, m.Type)
+	}
+	mnt := &Mount{
+		ContainerPath: m.ContainerPath,
+		HostPath:      m.HostPath,
+	}
+	for _, o := range m.Options {
+		switch o {
+		case "ro":
+			mnt.ReadOnly = true
+		case "rw":
+			mnt.ReadOnly = false
+		}
+	}
+	return mnt, nil
+}
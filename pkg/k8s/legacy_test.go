@@ -0,0 +1,81 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestNewLegacyAllocation(t *testing.T) {
+	edits := &cdi.ContainerEdits{
+		ContainerEdits: &cdispec.ContainerEdits{
+			Env: []string{"A=1", "B=x=y", "A=2"},
+			DeviceNodes: []*cdispec.DeviceNode{
+				{Path: "/dev/vendor0"},
+				{Path: "/dev/vendor1", HostPath: "/dev/vendor-1", Permissions: "r"},
+			},
+			Mounts: []*cdispec.Mount{
+				{HostPath: "/opt/lib", ContainerPath: "/usr/lib/vendor", Options: []string{"ro", "nosuid"}},
+				{HostPath: "/opt/data", ContainerPath: "/data", Type: "bind"},
+			},
+		},
+	}
+
+	alloc, err := NewLegacyAllocation(edits)
+	require.NoError(t, err)
+	require.Equal(t, &LegacyAllocation{
+		Envs: map[string]string{"A": "2", "B": "x=y"},
+		Devices: []*DeviceSpec{
+			{ContainerPath: "/dev/vendor0", HostPath: "/dev/vendor0", Permissions: "rwm"},
+			{ContainerPath: "/dev/vendor1", HostPath: "/dev/vendor-1", Permissions: "r"},
+		},
+		Mounts: []*Mount{
+			{ContainerPath: "/usr/lib/vendor", HostPath: "/opt/lib", ReadOnly: true},
+			{ContainerPath: "/data", HostPath: "/opt/data"},
+		},
+	}, alloc)
+
+	alloc, err = NewLegacyAllocation(nil)
+	require.NoError(t, err)
+	require.Equal(t, &LegacyAllocation{}, alloc)
+}
+
+func TestNewLegacyAllocationUnsupported(t *testing.T) {
+	for name, edits := range map[string]*cdispec.ContainerEdits{
+		"hooks": {
+			Hooks: []*cdispec.Hook{{HookName: "createContainer", Path: "/bin/hook"}},
+		},
+		"tmpfs": {
+			Mounts: []*cdispec.Mount{{HostPath: "tmpfs", ContainerPath: "/tmp", Type: "tmpfs"}},
+		},
+		"deny": {
+			DeviceNodes: []*cdispec.DeviceNode{{Path: "/dev/vendor0", Permissions: "none"}},
+		},
+		"env": {
+			Env: []string{"NOVALUE"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewLegacyAllocation(&cdi.ContainerEdits{ContainerEdits: edits})
+			require.Error(t, err)
+		})
+	}
+}
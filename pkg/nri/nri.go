@@ -0,0 +1,295 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package nri exposes CDI device injection as an NRI (Node Resource
+// Interface) plugin for containerd and CRI-O.
+//
+// The types in this package mirror the corresponding NRI API types
+// without depending on the NRI modules. Adjustments are expressed in OCI
+// Spec terms, so an NRI plugin stub converts them with the conversion
+// helpers of the NRI API package:
+//
+//	adj, err := plugin.CreateContainer(pod, ctr)
+//	...
+//	for _, m := range api.FromOCIMounts(adj.Mounts) {
+//	    nriAdj.AddMount(m)
+//	}
+package nri
+
+import (
+	"fmt"
+	"strings"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	"tags.cncf.io/container-device-interface/pkg/parser"
+)
+
+const (
+	// ContainerDevicesAnnotationPrefix is the prefix of pod annotations
+	// requesting CDI devices for a single container of the pod. The rest
+	// of the key is the name of the container and the value is a comma
+	// separated list of fully qualified CDI device names.
+	ContainerDevicesAnnotationPrefix = "cdi.nri.io/container."
+)
+
+// PodSandbox mirrors the parts of the NRI PodSandbox type used by Plugin.
+type PodSandbox struct {
+	// Name is the name of the pod.
+	Name string
+	// Namespace is the namespace of the pod.
+	Namespace string
+	// Annotations are the annotations of the pod.
+	Annotations map[string]string
+}
+
+// Container mirrors the parts of the NRI Container type used by Plugin.
+type Container struct {
+	// Name is the name of the container.
+	Name string
+	// Annotations are the annotations of the container.
+	Annotations map[string]string
+	// Env is the environment of the container, in "KEY=VALUE" form.
+	Env []string
+	// Mounts are the mounts of the container.
+	Mounts []oci.Mount
+}
+
+// ContainerAdjustment mirrors the NRI ContainerAdjustment type, with the
+// changes CDI device injection makes to a container.
+type ContainerAdjustment struct {
+	// Annotations are the annotations to add or update.
+	Annotations map[string]string
+	// Env are the "KEY=VALUE" variables to add or update.
+	Env []string
+	// RemoveEnv are the names of the variables to remove.
+	RemoveEnv []string
+	// Mounts are the mounts to add.
+	Mounts []oci.Mount
+	// RemoveMounts are the destinations of the mounts to remove, before
+	// adding Mounts.
+	RemoveMounts []string
+	// Hooks are the OCI hooks to add.
+	Hooks *oci.Hooks
+	// Devices are the device nodes to add.
+	Devices []oci.LinuxDevice
+	// DeviceRules are the device cgroup rules to add.
+	DeviceRules []oci.LinuxDeviceCgroup
+}
+
+// Plugin implements the CreateContainer event of an NRI plugin which
+// injects CDI devices.
+type Plugin struct {
+	resolver cdi.DeviceResolver
+}
+
+// New returns a Plugin resolving CDI devices using the given resolver,
+// typically a cdi.Cache.
+func New(resolver cdi.DeviceResolver) *Plugin {
+	return &Plugin{resolver: resolver}
+}
+
+// CreateContainer returns the adjustment injecting the CDI devices
+// requested for the given container of the pod. Devices are requested
+// by CDI annotations of the container, as described for
+// cdi.ParseAnnotations, or by an annotation of the pod with the key
+// ContainerDevicesAnnotationPrefix and the name of the container. It
+// returns nil if no devices are requested. An error is returned if any
+// of the devices can't be resolved, or if injecting them needs changes
+// which NRI adjustments can't express.
+func (p *Plugin) CreateContainer(pod *PodSandbox, ctr *Container) (*ContainerAdjustment, error) {
+	devices, err := RequestedDevices(pod, ctr)
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, nil
+	}
+
+	spec := &oci.Spec{
+		Process:     &oci.Process{Env: append([]string(nil), ctr.Env...)},
+		Mounts:      append([]oci.Mount(nil), ctr.Mounts...),
+		Annotations: copyAnnotations(ctr.Annotations),
+		Linux:       &oci.Linux{},
+	}
+
+	if _, err := p.resolver.InjectDevices(spec, devices...); err != nil {
+		return nil, err
+	}
+
+	return adjustment(ctr, spec)
+}
+
+// RequestedDevices returns the CDI devices requested for the given
+// container of the pod, with duplicates removed. Devices from the CDI
+// annotations of the container come first, followed by the devices from
+// the pod annotation for the container.
+func RequestedDevices(pod *PodSandbox, ctr *Container) ([]string, error) {
+	if ctr == nil {
+		return nil, fmt.Errorf("can't get CDI devices of nil container")
+	}
+
+	_, fromContainer, err := cdi.ParseAnnotations(ctr.Annotations)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CDI annotations of container %q: %w", ctr.Name, err)
+	}
+
+	var fromPod []string
+	if pod != nil {
+		key := ContainerDevicesAnnotationPrefix + ctr.Name
+		if value, ok := pod.Annotations[key]; ok {
+			for _, d := range strings.Split(value, ",") {
+				d = strings.TrimSpace(d)
+				if _, _, _, err := parser.ParseQualifiedName(d); err != nil {
+					return nil, fmt.Errorf("invalid CDI device %q in pod annotation %q: %w", d, key, err)
+				}
+				fromPod = append(fromPod, d)
+			}
+		}
+	}
+
+	var (
+		devices []string
+		seen    = map[string]struct{}{}
+	)
+	for _, list := range [][]string{fromContainer, fromPod} {
+		for _, d := range list {
+			if _, ok := seen[d]; ok {
+				continue
+			}
+			seen[d] = struct{}{}
+			devices = append(devices, d)
+		}
+	}
+
+	return devices, nil
+}
+
+// adjustment returns the adjustment turning the given container into
+// the given, injected OCI Spec.
+func adjustment(ctr *Container, spec *oci.Spec) (*ContainerAdjustment, error) {
+	var unsupported []string
+	if spec.Process.Capabilities != nil {
+		unsupported = append(unsupported, "capabilities")
+	}
+	if len(spec.Process.User.AdditionalGids) > 0 {
+		unsupported = append(unsupported, "additional GIDs")
+	}
+	if spec.Linux.IntelRdt != nil {
+		unsupported = append(unsupported, "Intel RDT")
+	}
+	if len(unsupported) > 0 {
+		return nil, fmt.Errorf("CDI edits not supported by NRI adjustments: %s",
+			strings.Join(unsupported, ", "))
+	}
+
+	adj := &ContainerAdjustment{
+		Devices: spec.Linux.Devices,
+		Hooks:   spec.Hooks,
+	}
+	if spec.Linux.Resources != nil {
+		adj.DeviceRules = spec.Linux.Resources.Devices
+	}
+
+	for key, value := range spec.Annotations {
+		if old, ok := ctr.Annotations[key]; ok && old == value {
+			continue
+		}
+		if adj.Annotations == nil {
+			adj.Annotations = map[string]string{}
+		}
+		adj.Annotations[key] = value
+	}
+
+	adj.Env, adj.RemoveEnv = diffEnv(ctr.Env, spec.Process.Env)
+	adj.Mounts, adj.RemoveMounts = diffMounts(ctr.Mounts, spec.Mounts)
+
+	return adj, nil
+}
+
+// diffEnv returns the variables to add or update and the names of the
+// variables to remove for turning the old environment into the new one.
+func diffEnv(oldEnv, newEnv []string) ([]string, []string) {
+	var (
+		added   []string
+		removed []string
+		oldVars = map[string]string{}
+		newVars = map[string]struct{}{}
+	)
+	for _, v := range oldEnv {
+		oldVars[envName(v)] = v
+	}
+	for _, v := range newEnv {
+		name := envName(v)
+		newVars[name] = struct{}{}
+		if old, ok := oldVars[name]; ok && old == v {
+			continue
+		}
+		added = append(added, v)
+	}
+	for _, v := range oldEnv {
+		if _, ok := newVars[envName(v)]; !ok {
+			removed = append(removed, envName(v))
+		}
+	}
+	return added, removed
+}
+
+// diffMounts returns the mounts to add and the destinations of the mounts
+// to remove for turning the old mounts into the new ones.
+func diffMounts(oldMounts, newMounts []oci.Mount) ([]oci.Mount, []string) {
+	var (
+		added   []oci.Mount
+		removed []string
+	)
+	for _, m := range newMounts {
+		if !hasMount(oldMounts, m) {
+			added = append(added, m)
+		}
+	}
+	for _, m := range oldMounts {
+		if !hasMount(newMounts, m) {
+			removed = append(removed, m.Destination)
+		}
+	}
+	return added, removed
+}
+
+func hasMount(mounts []oci.Mount, mnt oci.Mount) bool {
+	for _, m := range mounts {
+		if m.Destination == mnt.Destination && m.Source == mnt.Source &&
+			m.Type == mnt.Type && strings.Join(m.Options, ",") == strings.Join(mnt.Options, ",") {
+			return true
+		}
+	}
+	return false
+}
+
+func envName(v string) string {
+	name, _, _ := strings.Cut(v, "=")
+	return name
+}
+
+func copyAnnotations(annotations map[string]string) map[string]string {
+	if annotations == nil {
+		return nil
+	}
+	c := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		c[k] = v
+	}
+	return c
+}
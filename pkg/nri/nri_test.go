@@ -0,0 +1,138 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package nri
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	oci "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+)
+
+const testSpec = `
+cdiVersion: "0.7.0"
+kind: vendor.com/device
+devices:
+  - name: dev0
+    containerEdits:
+      env:
+        - DEV0=0
+      deviceNodes:
+        - path: /dev/vendor0
+          type: c
+          major: 10
+          minor: 1
+  - name: dev1
+    containerEdits:
+      env:
+        - SHARED=dev1
+      mounts:
+        - hostPath: /opt/vendor/lib
+          containerPath: /usr/lib/vendor
+          options: [ro, bind]
+  - name: rdt
+    containerEdits:
+      intelRdt:
+        closID: clos0
+containerEdits:
+  hooks:
+    - hookName: createContainer
+      path: /bin/vendor-hook
+`
+
+func newTestPlugin(t *testing.T) *Plugin {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor.yaml"), []byte(testSpec), 0o644))
+	cache, err := cdi.NewCache(cdi.WithSpecDirs(dir), cdi.WithAutoRefresh(false))
+	require.NoError(t, err)
+	return New(cache)
+}
+
+func TestCreateContainer(t *testing.T) {
+	plugin := newTestPlugin(t)
+
+	pod := &PodSandbox{
+		Name: "pod",
+		Annotations: map[string]string{
+			ContainerDevicesAnnotationPrefix + "ctr": "vendor.com/device=dev1, vendor.com/device=dev0",
+		},
+	}
+	ctr := &Container{
+		Name: "ctr",
+		Annotations: map[string]string{
+			"cdi.k8s.io/plugin_dev0": "vendor.com/device=dev0",
+		},
+		Env: []string{"PATH=/bin", "SHARED=ctr"},
+		Mounts: []oci.Mount{
+			{Destination: "/usr/lib/vendor", Source: "/tmp", Type: "bind", Options: []string{"bind"}},
+		},
+	}
+
+	devices, err := RequestedDevices(pod, ctr)
+	require.NoError(t, err)
+	require.Equal(t, []string{"vendor.com/device=dev0", "vendor.com/device=dev1"}, devices)
+
+	adj, err := plugin.CreateContainer(pod, ctr)
+	require.NoError(t, err)
+	require.NotNil(t, adj)
+
+	require.Equal(t, []string{"SHARED=dev1", "DEV0=0"}, adj.Env)
+	require.Nil(t, adj.RemoveEnv)
+	require.Equal(t, []oci.Mount{
+		{Destination: "/usr/lib/vendor", Source: "/opt/vendor/lib", Options: []string{"ro", "bind"}},
+	}, adj.Mounts)
+	require.Equal(t, []string{"/usr/lib/vendor"}, adj.RemoveMounts)
+	require.Len(t, adj.Devices, 1)
+	require.Equal(t, "/dev/vendor0", adj.Devices[0].Path)
+	require.Len(t, adj.DeviceRules, 1)
+	require.Equal(t, int64(10), *adj.DeviceRules[0].Major)
+	require.NotNil(t, adj.Hooks)
+	require.Len(t, adj.Hooks.CreateContainer, 1)
+	require.Nil(t, adj.Annotations)
+
+	require.Equal(t, []string{"PATH=/bin", "SHARED=ctr"}, ctr.Env, "container changed")
+}
+
+func TestCreateContainerErrors(t *testing.T) {
+	plugin := newTestPlugin(t)
+
+	adj, err := plugin.CreateContainer(&PodSandbox{}, &Container{Name: "ctr"})
+	require.NoError(t, err)
+	require.Nil(t, adj)
+
+	_, err = plugin.CreateContainer(nil, &Container{
+		Name:        "ctr",
+		Annotations: map[string]string{"cdi.k8s.io/plugin": "vendor.com/device=missing"},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "vendor.com/device=missing")
+
+	_, err = plugin.CreateContainer(nil, &Container{
+		Name:        "ctr",
+		Annotations: map[string]string{"cdi.k8s.io/plugin": "vendor.com/device=rdt"},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Intel RDT")
+
+	_, err = RequestedDevices(&PodSandbox{
+		Annotations: map[string]string{ContainerDevicesAnnotationPrefix + "ctr": "dev0"},
+	}, &Container{Name: "ctr"})
+	require.Error(t, err)
+}
@@ -33,10 +33,23 @@ import (
 // A valid device name may contain the following runes:
 //
 //	'A'-'Z', 'a'-'z', '0'-'9', '-', '_', '.', ':'
+//
+// Vendor, class and device names are case-sensitive. "vendor.com/gpu=dev0"
+// and "Vendor.com/GPU=Dev0" are two different devices. Callers which want
+// to match names case-insensitively should compare the canonical form of
+// names, as returned by CanonicalName().
 func QualifiedName(vendor, class, name string) string {
 	return vendor + "/" + class + "=" + name
 }
 
+// CanonicalName returns the canonical form of a (qualified or unqualified)
+// device name or a device qualifier. Names which only differ by case have
+// the same canonical form. Since all valid names consist of ASCII runes,
+// the canonical form is the lowercase version of the name.
+func CanonicalName(name string) string {
+	return strings.ToLower(name)
+}
+
 // IsQualifiedName tests if a device name is qualified.
 func IsQualifiedName(device string) bool {
 	_, _, _, err := ParseQualifiedName(device)
@@ -150,6 +163,9 @@ func validateVendorOrClassName(name string) error {
 	if !IsLetter(rune(name[0])) {
 		return fmt.Errorf("%q, should start with letter", name)
 	}
+	if len(name) == 1 {
+		return nil
+	}
 	for _, c := range string(name[1 : len(name)-1]) {
 		switch {
 		case IsAlphaNumeric(c):
@@ -47,6 +47,13 @@ func TestQualifiedName(t *testing.T) {
 			name:        "0",
 			isQualified: true,
 		},
+		{
+			device:      "vendor.com/c=dev",
+			vendor:      "vendor.com",
+			class:       "c",
+			name:        "dev",
+			isQualified: true,
+		},
 		{
 			device:      "vendor1.com/class1=dev1",
 			vendor:      "vendor1.com",
@@ -75,6 +82,13 @@ func TestQualifiedName(t *testing.T) {
 			name:        "dev_1:2.3",
 			isQualified: true,
 		},
+		{
+			device:      "Vendor.com/GPU=Dev0",
+			vendor:      "Vendor.com",
+			class:       "GPU",
+			name:        "Dev0",
+			isQualified: true,
+		},
 		{
 			device:     "_invalid.com/class=dev",
 			vendor:     "_invalid.com",
@@ -151,3 +165,65 @@ func TestQualifiedName(t *testing.T) {
 		})
 	}
 }
+
+func TestCanonicalName(t *testing.T) {
+	type testCase = struct {
+		name      string
+		other     string
+		canonical string
+		equal     bool
+	}
+
+	for _, tc := range []*testCase{
+		{
+			name:      "vendor.com/class=dev0",
+			other:     "vendor.com/class=dev0",
+			canonical: "vendor.com/class=dev0",
+			equal:     true,
+		},
+		{
+			name:      "Vendor.com/GPU=Dev0",
+			other:     "vendor.com/gpu=dev0",
+			canonical: "vendor.com/gpu=dev0",
+			equal:     false,
+		},
+		{
+			name:      "VENDOR.COM/GPU",
+			other:     "vendor.com/gpu",
+			canonical: "vendor.com/gpu",
+			equal:     false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.equal, tc.name == tc.other, "case-sensitive comparison")
+			require.Equal(t, tc.canonical, CanonicalName(tc.name))
+			require.Equal(t, CanonicalName(tc.name), CanonicalName(tc.other))
+		})
+	}
+}
+
+func FuzzParseQualifiedName(f *testing.F) {
+	for _, device := range []string{
+		"vendor.com/class=dev",
+		"vendor.com/c=0",
+		"v/c=d",
+		"vendor.com/class=*dev*",
+		"/dev/null",
+		"=",
+		"",
+	} {
+		f.Add(device)
+	}
+
+	f.Fuzz(func(t *testing.T, device string) {
+		vendor, class, name, err := ParseQualifiedName(device)
+		if err != nil {
+			require.False(t, IsQualifiedName(device))
+			return
+		}
+		require.True(t, IsQualifiedName(device))
+		require.Equal(t, device, QualifiedName(vendor, class, name))
+		ParseDevice(device)
+		CanonicalName(device)
+	})
+}
@@ -0,0 +1,143 @@
+/*
+   Copyright © 2022 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	schema "github.com/xeipuuv/gojsonschema"
+)
+
+// Violation is a single violation of a JSON schema.
+type Violation struct {
+	// Pointer is the JSON pointer (RFC 6901) to the offending value. It
+	// is empty for the document root. For missing required properties it
+	// points to the object missing the property.
+	Pointer string
+	// Keyword is the schema keyword which failed, for instance "required",
+	// "type" or "additionalProperties".
+	Keyword string
+	// Value is the offending value.
+	Value interface{}
+	// Description is a human readable description of the violation.
+	Description string
+	// Details are details specific to the violation, for instance the
+	// name of a missing or unexpected "property".
+	Details map[string]interface{}
+}
+
+// Error returns the violation as an error string.
+func (v *Violation) Error() string {
+	pointer := v.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return fmt.Sprintf("%s: %s", pointer, v.Description)
+}
+
+// Violations returns the individual violations of the validation result.
+func (e *Error) Violations() []*Violation {
+	if e == nil || e.Result == nil || e.Result.Valid() {
+		return nil
+	}
+
+	var violations []*Violation
+	for _, err := range e.Result.Errors() {
+		v := &Violation{
+			Pointer:     jsonPointer(err.Context()),
+			Keyword:     schemaKeyword(err.Type()),
+			Value:       err.Value(),
+			Description: err.Description(),
+		}
+		if details := err.Details(); len(details) > 0 {
+			v.Details = map[string]interface{}{}
+			for key, value := range details {
+				// context is already available as Pointer
+				if key != "context" && key != "field" {
+					v.Details[key] = value
+				}
+			}
+		}
+		violations = append(violations, v)
+	}
+
+	return violations
+}
+
+// Unwrap returns the individual violations of the validation result.
+func (e *Error) Unwrap() []error {
+	var errs []error
+	for _, v := range e.Violations() {
+		errs = append(errs, v)
+	}
+	return errs
+}
+
+// jsonPointer returns the JSON pointer for the given JSON context.
+func jsonPointer(ctx *schema.JsonContext) string {
+	if ctx == nil {
+		return ""
+	}
+
+	const sep = "\x00"
+	parts := strings.Split(ctx.String(sep), sep)
+
+	pointer := ""
+	for _, p := range parts[1:] { // skip the root
+		p = strings.ReplaceAll(p, "~", "~0")
+		p = strings.ReplaceAll(p, "/", "~1")
+		pointer += "/" + p
+	}
+	return pointer
+}
+
+// schemaKeyword returns the schema keyword for a validation error type.
+func schemaKeyword(errorType string) string {
+	if keyword, ok := schemaKeywords[errorType]; ok {
+		return keyword
+	}
+	return errorType
+}
+
+// schemaKeywords maps validation error types to schema keywords.
+var schemaKeywords = map[string]string{
+	"invalid_type":                    "type",
+	"number_any_of":                   "anyOf",
+	"number_one_of":                   "oneOf",
+	"number_all_of":                   "allOf",
+	"number_not":                      "not",
+	"missing_dependency":              "dependencies",
+	"array_no_additional_items":       "additionalItems",
+	"array_min_items":                 "minItems",
+	"array_max_items":                 "maxItems",
+	"unique":                          "uniqueItems",
+	"array_min_properties":            "minProperties",
+	"array_max_properties":            "maxProperties",
+	"additional_property_not_allowed": "additionalProperties",
+	"invalid_property_pattern":        "patternProperties",
+	"invalid_property_name":           "propertyNames",
+	"string_gte":                      "minLength",
+	"string_lte":                      "maxLength",
+	"multiple_of":                     "multipleOf",
+	"number_gte":                      "minimum",
+	"number_gt":                       "exclusiveMinimum",
+	"number_lte":                      "maximum",
+	"number_lt":                       "exclusiveMaximum",
+	"condition_then":                  "then",
+	"condition_else":                  "else",
+}
@@ -0,0 +1,87 @@
+/*
+   Copyright © 2022 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package schema_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"tags.cncf.io/container-device-interface/schema"
+)
+
+func TestViolations(t *testing.T) {
+	s, err := schema.ForVersion("0.9.0")
+	require.NoError(t, err)
+
+	err = s.ValidateData([]byte(`{
+  "cdiVersion": "0.9.0",
+  "kind": 1,
+  "annotations": {"vendor.com/a~b": 1},
+  "devices": [
+    {
+      "name": "dev1",
+      "containerEdits": {
+        "deviceNodes": [{"permissions": "rw"}],
+        "hostPaths/~": 1
+      }
+    }
+  ]
+}`))
+	require.Error(t, err)
+
+	var schemaErr *schema.Error
+	require.True(t, errors.As(err, &schemaErr))
+
+	violations := schemaErr.Violations()
+	require.ElementsMatch(t,
+		[]struct{ pointer, keyword string }{
+			{"/kind", "type"},
+			{"/annotations/vendor.com~1a~0b", "type"},
+			{"/devices/0/containerEdits/deviceNodes/0", "required"},
+			{"/devices/0/containerEdits", "additionalProperties"},
+		},
+		func() []struct{ pointer, keyword string } {
+			var result []struct{ pointer, keyword string }
+			for _, v := range violations {
+				result = append(result, struct{ pointer, keyword string }{v.Pointer, v.Keyword})
+			}
+			return result
+		}(),
+	)
+
+	for _, v := range violations {
+		switch v.Keyword {
+		case "type":
+			require.Equal(t, json.Number("1"), v.Value)
+		case "required":
+			require.Equal(t, "path", v.Details["property"])
+		case "additionalProperties":
+			require.Equal(t, "hostPaths/~", v.Details["property"])
+		}
+	}
+
+	unwrapped := schemaErr.Unwrap()
+	require.Len(t, unwrapped, len(violations))
+	var violation *schema.Violation
+	require.True(t, errors.As(err, &violation))
+	require.Contains(t, violation.Error(), violation.Pointer+": ")
+
+	require.Nil(t, (&schema.Error{}).Violations())
+}
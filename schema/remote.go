@@ -0,0 +1,335 @@
+/*
+   Copyright © 2022 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package schema
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	schema "github.com/xeipuuv/gojsonschema"
+)
+
+const (
+	// DefaultCacheTTL is the default time a cached remote schema is used
+	// without trying to refresh it.
+	DefaultCacheTTL = 24 * time.Hour
+	// maxRemoteSchemaSize is the maximum size of a remote schema document.
+	maxRemoteSchemaSize = 4 << 20
+)
+
+// LoadOption is an option for LoadWithOptions.
+type LoadOption func(*loadConfig)
+
+type loadConfig struct {
+	ctx      context.Context
+	timeout  time.Duration
+	cacheDir string
+	ttl      time.Duration
+	fallback bool
+	client   *http.Client
+}
+
+// WithContext returns an option to fetch remote schemas using the given
+// context.
+func WithContext(ctx context.Context) LoadOption {
+	return func(c *loadConfig) {
+		c.ctx = ctx
+	}
+}
+
+// WithTimeout returns an option to set a timeout for fetching remote
+// schemas, including any documents they refer to.
+func WithTimeout(timeout time.Duration) LoadOption {
+	return func(c *loadConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithCacheDir returns an option to cache remote schemas in the given
+// directory. A cached schema is used without fetching it for the cache
+// TTL. If fetching a schema fails, a stale cached copy is used instead.
+func WithCacheDir(dir string) LoadOption {
+	return func(c *loadConfig) {
+		c.cacheDir = dir
+	}
+}
+
+// WithCacheTTL returns an option to set the time a cached remote schema
+// is used without fetching it. The default is DefaultCacheTTL.
+func WithCacheTTL(ttl time.Duration) LoadOption {
+	return func(c *loadConfig) {
+		c.ttl = ttl
+	}
+}
+
+// WithBuiltinFallback returns an option to fall back to the builtin schema
+// if a remote schema can't be fetched and there is no cached copy of it.
+func WithBuiltinFallback(enable bool) LoadOption {
+	return func(c *loadConfig) {
+		c.fallback = enable
+	}
+}
+
+// WithHTTPClient returns an option to set the HTTP client used to fetch
+// remote schemas. By default http.DefaultClient is used.
+func WithHTTPClient(client *http.Client) LoadOption {
+	return func(c *loadConfig) {
+		c.client = client
+	}
+}
+
+// LoadWithOptions loads the given JSON Schema like Load(), using the given
+// options for http and https sources. Remote schemas are fetched together
+// with any other documents they refer to using relative references, so
+// that they can be cached and loaded offline. Absolute references to other
+// remote documents are not cached.
+func LoadWithOptions(source string, options ...LoadOption) (*Schema, error) {
+	source = strings.TrimSpace(source)
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return Load(source)
+	}
+
+	c := &loadConfig{
+		ctx:    context.Background(),
+		ttl:    DefaultCacheTTL,
+		client: http.DefaultClient,
+	}
+	for _, o := range options {
+		o(c)
+	}
+
+	return c.loadRemote(source)
+}
+
+// loadRemote loads a remote schema, using the cache if possible.
+func (c *loadConfig) loadRemote(source string) (*Schema, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema URL %q: %w", source, err)
+	}
+	u.Fragment = ""
+
+	var (
+		name   = path.Base(u.Path)
+		mirror string
+	)
+	if name == "/" || name == "." {
+		name = "schema.json"
+	}
+	if c.cacheDir != "" {
+		sum := sha256.Sum256([]byte(u.String()))
+		mirror = filepath.Join(c.cacheDir, hex.EncodeToString(sum[:8]))
+		if info, err := os.Stat(filepath.Join(mirror, name)); err == nil && time.Since(info.ModTime()) < c.ttl {
+			return loadFS(os.DirFS(mirror), name)
+		}
+	}
+
+	docs, fetchErr := c.fetch(u, name)
+	if fetchErr == nil {
+		if mirror != "" {
+			if err := writeMirror(mirror, docs); err == nil {
+				return loadFS(os.DirFS(mirror), name)
+			}
+		}
+		// load from a temporary copy, all references are resolved by now
+		tmp, err := os.MkdirTemp("", "cdi-schema-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JSON schema %s: %w", source, err)
+		}
+		defer os.RemoveAll(tmp)
+		mirror := filepath.Join(tmp, "schema")
+		if err := writeMirror(mirror, docs); err != nil {
+			return nil, fmt.Errorf("failed to load JSON schema %s: %w", source, err)
+		}
+		return loadFS(os.DirFS(mirror), name)
+	}
+
+	if mirror != "" {
+		if _, err := os.Stat(filepath.Join(mirror, name)); err == nil {
+			return loadFS(os.DirFS(mirror), name)
+		}
+	}
+	if c.fallback {
+		return BuiltinSchema(), nil
+	}
+
+	return nil, fmt.Errorf("failed to load JSON schema %s: %w", source, fetchErr)
+}
+
+// fetch the remote schema document and the documents it refers to using
+// relative references.
+func (c *loadConfig) fetch(u *url.URL, name string) (map[string][]byte, error) {
+	ctx := c.ctx
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var (
+		base  = u.ResolveReference(&url.URL{Path: "."})
+		docs  = map[string][]byte{}
+		queue = []string{name}
+	)
+
+	for len(queue) > 0 {
+		rel := queue[0]
+		queue = queue[1:]
+		if _, ok := docs[rel]; ok {
+			continue
+		}
+
+		data, err := c.get(ctx, base.ResolveReference(&url.URL{Path: rel}).String())
+		if err != nil {
+			return nil, err
+		}
+		docs[rel] = data
+
+		refs, err := relativeRefs(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON schema document %s: %w", rel, err)
+		}
+		for _, ref := range refs {
+			ref = path.Join(path.Dir(rel), ref)
+			if ref == ".." || strings.HasPrefix(ref, "../") {
+				return nil, fmt.Errorf("JSON schema reference %q outside of schema directory", ref)
+			}
+			queue = append(queue, ref)
+		}
+	}
+
+	return docs, nil
+}
+
+// get fetches a single document.
+func (c *loadConfig) get(ctx context.Context, address string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", address, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteSchemaSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", address, err)
+	}
+	if len(data) > maxRemoteSchemaSize {
+		return nil, fmt.Errorf("failed to fetch %s: document too large", address)
+	}
+
+	return data, nil
+}
+
+// relativeRefs returns the documents referred to by relative references
+// in the given JSON schema document.
+func relativeRefs(data []byte) ([]string, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var (
+		refs []string
+		walk func(interface{})
+	)
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				if ref, ok := value.(string); ok && key == "$ref" {
+					ref, _, _ = strings.Cut(ref, "#")
+					if u, err := url.Parse(ref); err == nil && ref != "" && !u.IsAbs() && !path.IsAbs(u.Path) {
+						refs = append(refs, u.Path)
+					}
+					continue
+				}
+				walk(value)
+			}
+		case []interface{}:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	walk(doc)
+
+	return refs, nil
+}
+
+// writeMirror atomically replaces the cached copy of a remote schema.
+func writeMirror(mirror string, docs map[string][]byte) error {
+	if err := os.MkdirAll(filepath.Dir(mirror), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(mirror), filepath.Base(mirror)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	for rel, data := range docs {
+		file := filepath.Join(tmp, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			return err
+		}
+	}
+
+	old := mirror + ".old"
+	_ = os.RemoveAll(old)
+	if err := os.Rename(mirror, old); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Rename(tmp, mirror); err != nil {
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+// loadFS loads the named schema from the given file system.
+func loadFS(fsys fs.FS, name string) (*Schema, error) {
+	s, err := schema.NewSchema(
+		schema.NewReferenceLoaderFileSystem("file:///"+name, http.FS(fsys)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JSON schema: %w", err)
+	}
+	return &Schema{schema: s}, nil
+}
@@ -0,0 +1,103 @@
+/*
+   Copyright © 2022 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package schema_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"tags.cncf.io/container-device-interface/schema"
+)
+
+func TestLoadWithOptions(t *testing.T) {
+	var (
+		requests int32
+		delay    int64
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(time.Duration(atomic.LoadInt64(&delay)))
+		http.StripPrefix("/cdi/", http.FileServer(http.Dir("."))).ServeHTTP(w, r)
+	}))
+	source := server.URL + "/cdi/schema.json"
+
+	validSpec, err := os.ReadFile(filepath.Join("testdata", "good", "minimal.json"))
+	require.NoError(t, err)
+	invalidSpec := []byte(`{"cdiVersion": "0.3.0", "kind": "vendor.com/device"}`)
+
+	validate := func(s *schema.Schema) {
+		require.NotNil(t, s)
+		require.NoError(t, s.ValidateData(validSpec))
+		require.Error(t, s.ValidateData(invalidSpec))
+	}
+
+	cacheDir := t.TempDir()
+
+	// without a cache every load fetches the schema and its definitions
+	s, err := schema.LoadWithOptions(source)
+	require.NoError(t, err)
+	validate(s)
+	require.Equal(t, int32(2), atomic.SwapInt32(&requests, 0))
+
+	// a fresh cached copy is used without fetching
+	s, err = schema.LoadWithOptions(source, schema.WithCacheDir(cacheDir))
+	require.NoError(t, err)
+	validate(s)
+	require.Equal(t, int32(2), atomic.SwapInt32(&requests, 0))
+
+	s, err = schema.LoadWithOptions(source, schema.WithCacheDir(cacheDir))
+	require.NoError(t, err)
+	validate(s)
+	require.Equal(t, int32(0), atomic.SwapInt32(&requests, 0))
+
+	// a stale cached copy is refreshed
+	s, err = schema.LoadWithOptions(source, schema.WithCacheDir(cacheDir), schema.WithCacheTTL(0))
+	require.NoError(t, err)
+	validate(s)
+	require.Equal(t, int32(2), atomic.SwapInt32(&requests, 0))
+
+	// timeouts
+	atomic.StoreInt64(&delay, int64(200*time.Millisecond))
+	_, err = schema.LoadWithOptions(source, schema.WithTimeout(10*time.Millisecond))
+	require.Error(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = schema.LoadWithOptions(source, schema.WithContext(ctx))
+	require.Error(t, err)
+	atomic.StoreInt64(&delay, 0)
+
+	// offline, a stale cached copy is used
+	server.Close()
+	s, err = schema.LoadWithOptions(source, schema.WithCacheDir(cacheDir), schema.WithCacheTTL(0))
+	require.NoError(t, err)
+	validate(s)
+
+	// offline, without a cached copy
+	_, err = schema.LoadWithOptions(source, schema.WithCacheDir(t.TempDir()))
+	require.Error(t, err)
+	s, err = schema.LoadWithOptions(source, schema.WithBuiltinFallback(true))
+	require.NoError(t, err)
+	require.Equal(t, schema.BuiltinSchema(), s)
+}
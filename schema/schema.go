@@ -27,6 +27,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
 	"sigs.k8s.io/yaml"
 
@@ -178,6 +179,10 @@ func (s *Schema) ValidateData(data []byte) error {
 		err error
 	)
 
+	if !utf8.Valid(data) {
+		return errors.New("failed to validate data: invalid UTF-8")
+	}
+
 	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte{'{'}) {
 		err = yaml.Unmarshal(data, &any)
 		if err != nil {
@@ -131,6 +131,31 @@ func TestValidateData(t *testing.T) {
 	}
 }
 
+func TestValidateDataInvalidUTF8(t *testing.T) {
+	data := []byte("{\"cdiVersion\": \"0.6.0\", \"kind\": \"vendor.com/dev\x80\", \"devices\": []}")
+	require.Error(t, schema.BuiltinSchema().ValidateData(data))
+	require.Error(t, none.ValidateData(data))
+}
+
+func FuzzValidateData(f *testing.F) {
+	for _, dir := range []string{"./testdata/good", "./testdata/bad"} {
+		entries, err := os.ReadDir(dir)
+		require.NoError(f, err)
+		for _, e := range entries {
+			data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			require.NoError(f, err)
+			f.Add(data)
+		}
+	}
+	f.Add([]byte("{\"cdiVersion\": \"0.6.0\", \"kind\": \"vendor.com/dev\x80\"}"))
+	f.Add(bytes.Repeat([]byte("["), 20000))
+
+	scm := schema.BuiltinSchema()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_ = scm.ValidateData(data)
+	})
+}
+
 func TestValidateReader(t *testing.T) {
 	type testCase struct {
 		testName   string
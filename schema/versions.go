@@ -0,0 +1,131 @@
+/*
+   Copyright © 2022 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package schema
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/semver"
+	"sigs.k8s.io/yaml"
+
+	schema "github.com/xeipuuv/gojsonschema"
+)
+
+// Versions returns the CDI versions with a builtin versioned schema,
+// sorted from oldest to newest. Unlike the builtin schema, versioned
+// schemas reject fields which were not available in their version, as
+// well as any unknown fields.
+func Versions() []string {
+	var versions []string
+
+	entries, _ := fs.ReadDir(versionFS, versionDir)
+	for _, e := range entries {
+		if e.IsDir() {
+			versions = append(versions, strings.TrimPrefix(e.Name(), "v"))
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return semver.Compare("v"+versions[i], "v"+versions[j]) < 0
+	})
+
+	return versions
+}
+
+// ForVersion returns the builtin versioned schema for the given CDI version.
+func ForVersion(version string) (*Schema, error) {
+	version = "v" + strings.TrimPrefix(version, "v")
+
+	versionLock.Lock()
+	defer versionLock.Unlock()
+
+	if s, ok := versioned[version]; ok {
+		return s, nil
+	}
+
+	if _, err := fs.Stat(versionFS, versionDir+"/"+version+"/schema.json"); err != nil {
+		return nil, fmt.Errorf("no JSON schema for CDI version %q", strings.TrimPrefix(version, "v"))
+	}
+
+	s, err := schema.NewSchema(
+		schema.NewReferenceLoaderFileSystem(
+			"file:///"+versionDir+"/"+version+"/schema.json",
+			http.FS(versionFS),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JSON schema for CDI version %q: %w",
+			strings.TrimPrefix(version, "v"), err)
+	}
+
+	versioned[version] = &Schema{schema: s}
+	return versioned[version], nil
+}
+
+// ForSpecVersion returns the builtin versioned schema for the given CDI
+// version, or the builtin schema if there is no versioned schema for it,
+// for instance for versions older than the oldest versioned schema.
+func ForSpecVersion(version string) *Schema {
+	if s, err := ForVersion(version); err == nil {
+		return s
+	}
+	return BuiltinSchema()
+}
+
+// ValidateDataForVersion validates the given JSON or YAML Spec data
+// against the builtin versioned schema for the given CDI version. If
+// version is empty, the schema for the cdiVersion of the Spec is used.
+func ValidateDataForVersion(data []byte, version string) error {
+	if version == "" {
+		var doc struct {
+			Version string `json:"cdiVersion"`
+		}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to unmarshal data for validation: %w", err)
+		}
+		if doc.Version == "" {
+			return fmt.Errorf("no cdiVersion in data for validation")
+		}
+		version = doc.Version
+	}
+
+	s, err := ForVersion(version)
+	if err != nil {
+		return err
+	}
+
+	return s.ValidateData(data)
+}
+
+const (
+	// versionDir is the directory of versioned schemas in versionFS.
+	versionDir = "versions"
+)
+
+var (
+	versionLock sync.Mutex
+	// versioned schemas loaded so far
+	versioned = map[string]*Schema{}
+)
+
+//go:embed versions
+var versionFS embed.FS
@@ -0,0 +1,177 @@
+/*
+   Copyright © 2022 The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"tags.cncf.io/container-device-interface/schema"
+	cdi "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func TestVersions(t *testing.T) {
+	versions := schema.Versions()
+	require.Equal(t, "0.3.0", versions[0])
+	require.Equal(t, cdi.CurrentVersion, versions[len(versions)-1])
+
+	for _, v := range versions {
+		s, err := schema.ForVersion(v)
+		require.NoError(t, err, v)
+		require.NotNil(t, s, v)
+	}
+
+	_, err := schema.ForVersion("0.2.0")
+	require.Error(t, err)
+}
+
+func TestValidateDataForVersion(t *testing.T) {
+	type testCase struct {
+		name    string
+		data    string
+		version string
+		invalid bool
+	}
+	for _, tc := range []*testCase{
+		{
+			name: "0.3.0 Spec",
+			data: `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+- name: dev1
+  containerEdits:
+    deviceNodes:
+    - path: /dev/dev1
+`,
+		},
+		{
+			name: "0.3.0 Spec with a newer field",
+			data: `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+- name: dev1
+  containerEdits:
+    deviceNodes:
+    - path: /dev/dev1
+      hostPath: /dev/vendor-dev1
+`,
+			invalid: true,
+		},
+		{
+			name: "0.3.0 Spec validated as 0.5.0",
+			data: `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+devices:
+- name: dev1
+  containerEdits:
+    deviceNodes:
+    - path: /dev/dev1
+      hostPath: /dev/vendor-dev1
+`,
+			version: "0.5.0",
+		},
+		{
+			name: "0.3.0 Spec with Spec-level containerEdits",
+			data: `
+cdiVersion: "0.3.0"
+kind: vendor.com/device
+containerEdits:
+  env: [ "A=1" ]
+devices:
+- name: dev1
+  containerEdits:
+    env: [ "B=2" ]
+`,
+		},
+		{
+			name: "0.6.0 Spec with annotations",
+			data: `{
+  "cdiVersion": "0.6.0",
+  "kind": "vendor.com/device",
+  "annotations": {"vendor.com/key": "value"},
+  "devices": [{"name": "dev1", "containerEdits": {"env": ["A=1"]}}]
+}`,
+		},
+		{
+			name: "0.8.0 Spec with groups",
+			data: `
+cdiVersion: "0.8.0"
+kind: vendor.com/device
+devices:
+- name: dev1
+  containerEdits:
+    env: [ "A=1" ]
+groups:
+- name: all
+  devices: [ dev1 ]
+`,
+			invalid: true,
+		},
+		{
+			name: "unknown field",
+			data: `
+cdiVersion: "0.9.0"
+kind: vendor.com/device
+devices:
+- name: dev1
+  containerEdits:
+    env: [ "A=1" ]
+    unknown: 1
+`,
+			invalid: true,
+		},
+		{
+			name: "unsupported version",
+			data: `
+cdiVersion: "0.2.0"
+kind: vendor.com/device
+devices:
+- name: dev1
+  containerEdits:
+    env: [ "A=1" ]
+`,
+			invalid: true,
+		},
+		{
+			name:    "no version",
+			data:    `{"kind": "vendor.com/device", "devices": []}`,
+			invalid: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := schema.ValidateDataForVersion([]byte(tc.data), tc.version)
+			if tc.invalid {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestForSpecVersion(t *testing.T) {
+	s, err := schema.ForVersion("0.5.0")
+	require.NoError(t, err)
+	require.Same(t, s, schema.ForSpecVersion("0.5.0"))
+
+	require.Same(t, schema.BuiltinSchema(), schema.ForSpecVersion("0.2.0"))
+	require.Same(t, schema.BuiltinSchema(), schema.ForSpecVersion(""))
+}
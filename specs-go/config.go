@@ -11,6 +11,30 @@ type Spec struct {
 	Annotations    map[string]string `json:"annotations,omitempty"`
 	Devices        []Device          `json:"devices"`
 	ContainerEdits ContainerEdits    `json:"containerEdits,omitempty"`
+	// Groups define named groups of the devices in the Spec.
+	// Added in v0.9.0.
+	Groups []DeviceGroup `json:"groups,omitempty"`
+	// Lifecycle describes the lifecycle state of all devices in the Spec.
+	// Added in v0.10.0.
+	Lifecycle *Lifecycle `json:"lifecycle,omitempty"`
+	// Platform constrains the hosts all devices in the Spec are usable on.
+	// Added in v0.10.0.
+	Platform *PlatformConstraints `json:"platform,omitempty"`
+	// UnknownFields holds the fields of the parsed Spec data which are
+	// not known to this version of the Spec, for instance fields added
+	// by a newer CDI version. They are written back when the Spec is
+	// marshaled, as long as it declares that newer version, so rewriting
+	// a Spec does not lose them.
+	UnknownFields map[string]interface{} `json:"-"`
+}
+
+// DeviceGroup is a named group of devices which can be requested like a
+// single device. Requesting a group injects all its member devices.
+type DeviceGroup struct {
+	Name string `json:"name"`
+	// Devices lists the names of the member devices or groups in the
+	// same Spec. The special member "*" stands for all devices.
+	Devices []string `json:"devices"`
 }
 
 // Device is a "Device" a container runtime can add to a container
@@ -20,6 +44,131 @@ type Device struct {
 	// Added in v0.6.0.
 	Annotations    map[string]string `json:"annotations,omitempty"`
 	ContainerEdits ContainerEdits    `json:"containerEdits"`
+	// Properties describe the device, for instance its topology. They do
+	// not affect the container.
+	// Added in v0.10.0.
+	Properties *DeviceProperties `json:"properties,omitempty"`
+	// Capacity describes how the device can be shared by containers.
+	// Added in v0.10.0.
+	Capacity *DeviceCapacity `json:"capacity,omitempty"`
+	// Lifecycle describes the lifecycle state of the device. It takes
+	// precedence over the lifecycle of the Spec.
+	// Added in v0.10.0.
+	Lifecycle *Lifecycle `json:"lifecycle,omitempty"`
+	// RenamedFrom is the previous name of the device in the same Spec.
+	// Requests for the previous name resolve to this device, so users
+	// can migrate to the new name.
+	// Added in v0.10.0.
+	RenamedFrom string `json:"renamedFrom,omitempty"`
+	// Platform constrains the hosts the device is usable on, in addition
+	// to the constraints of the Spec.
+	// Added in v0.10.0.
+	Platform *PlatformConstraints `json:"platform,omitempty"`
+	// Readiness lists checks whether the device is ready to be used, for
+	// instance whether its driver is loaded. Added in v0.10.0.
+	Readiness []ReadinessProbe `json:"readiness,omitempty"`
+}
+
+// ReadinessProbe is a check whether a device is ready to be used. Exactly
+// one of the checks must be set. Paths are host paths.
+type ReadinessProbe struct {
+	// FileExists is the path of a file which must exist.
+	FileExists string `json:"fileExists,omitempty"`
+	// DeviceOpenable is the path of a device node which must be openable
+	// for reading.
+	DeviceOpenable string `json:"deviceOpenable,omitempty"`
+	// SysfsAttribute is a sysfs attribute which must have a given value.
+	SysfsAttribute *SysfsAttributeProbe `json:"sysfsAttribute,omitempty"`
+}
+
+// SysfsAttributeProbe checks the value of a sysfs attribute.
+type SysfsAttributeProbe struct {
+	// Path is the path of the attribute, for instance
+	// "/sys/module/vendor/initstate".
+	Path string `json:"path"`
+	// Value is the expected value of the attribute. Leading and trailing
+	// white space of the attribute is ignored.
+	Value string `json:"value"`
+}
+
+// PlatformConstraints constrain the hosts devices are usable on. This lets
+// vendors distribute the same Spec files to heterogeneous hosts. Unset
+// constraints match any host.
+type PlatformConstraints struct {
+	// OS lists the operating systems the devices are usable on, using
+	// the names of GOOS, for instance "linux".
+	OS []string `json:"os,omitempty"`
+	// Arch lists the architectures the devices are usable on, using the
+	// names of GOARCH, for instance "amd64" or "arm64".
+	Arch []string `json:"arch,omitempty"`
+	// KernelVersion is the range of kernel versions the devices are
+	// usable with.
+	KernelVersion *KernelVersionRange `json:"kernelVersion,omitempty"`
+}
+
+// KernelVersionRange is a range of kernel versions, given as dot separated
+// numbers, for instance "5.15". Only the numeric prefix of the kernel
+// release of a host, like 6.1.0 for 6.1.0-13-amd64, is compared.
+type KernelVersionRange struct {
+	// Min is the lowest kernel version in the range.
+	Min string `json:"min,omitempty"`
+	// Max is the first kernel version past the range.
+	Max string `json:"max,omitempty"`
+}
+
+// Lifecycle describes the lifecycle state of a Spec or a device. Vendors
+// use it to steer users away from devices which are going to be removed.
+type Lifecycle struct {
+	// Deprecated marks the Spec or device as deprecated.
+	Deprecated bool `json:"deprecated,omitempty"`
+	// ReplacedBy names the replacement of a deprecated device, as a fully
+	// qualified device name, or of all devices of a deprecated Spec, as a
+	// Spec kind.
+	ReplacedBy string `json:"replacedBy,omitempty"`
+	// RemovalDate is the date after which a deprecated Spec or device may
+	// be removed, in the format YYYY-MM-DD.
+	RemovalDate string `json:"removalDate,omitempty"`
+}
+
+// DeviceCapacity describes how a device can be shared by containers.
+type DeviceCapacity struct {
+	// MaxConsumers is the maximum number of containers which can use the
+	// device at the same time. Zero means no limit.
+	MaxConsumers int `json:"maxConsumers,omitempty"`
+	// Partitions is the number of partitions the device is split into.
+	// Every container using the device is assigned a partition of its
+	// own. Zero means the device is not partitioned.
+	Partitions int `json:"partitions,omitempty"`
+}
+
+// DeviceProperties are structured properties of a device.
+type DeviceProperties struct {
+	// Topology describes where the device is attached to the host.
+	Topology *DeviceTopology `json:"topology,omitempty"`
+	// Attributes are free-form properties of the device, for instance its
+	// model or memory size. Keys follow the same rules as annotation keys.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// DeviceTopology describes where a device is attached to the host.
+type DeviceTopology struct {
+	// NUMANode is the NUMA node the device is attached to.
+	NUMANode *int `json:"numaNode,omitempty"`
+	// PCIAddress is the PCI address of the device, in the extended BDF
+	// notation: domain:bus:device.function, for instance 0000:3b:00.0.
+	PCIAddress string `json:"pciAddress,omitempty"`
+	// Links are the direct links of the device to other devices.
+	Links []DeviceLink `json:"links,omitempty"`
+}
+
+// DeviceLink is a direct link between two devices, for instance a GPU
+// interconnect.
+type DeviceLink struct {
+	// Device is the name of the linked device in the same Spec, or the
+	// fully qualified name of a device in another Spec.
+	Device string `json:"device"`
+	// Type is the type of the link, for instance "nvlink".
+	Type string `json:"type,omitempty"`
 }
 
 // ContainerEdits are edits a container runtime must make to the OCI spec to expose the device.
@@ -30,6 +179,31 @@ type ContainerEdits struct {
 	Mounts         []*Mount      `json:"mounts,omitempty"`
 	IntelRdt       *IntelRdt     `json:"intelRdt,omitempty"`       // Added in v0.7.0
 	AdditionalGIDs []uint32      `json:"additionalGids,omitempty"` // Added in v0.7.0
+	// EnvPolicy defines how Env is merged with the environment variables
+	// already set in the OCI spec. If unset, the runtime decides.
+	// Added in v0.10.0.
+	EnvPolicy string `json:"envPolicy,omitempty"`
+	// Capabilities are changes to the capabilities of the container
+	// process. Added in v0.10.0.
+	Capabilities *Capabilities `json:"capabilities,omitempty"`
+}
+
+// Environment variable merge policies for ContainerEdits.EnvPolicy.
+const (
+	// EnvPolicyAppend appends variables, even if already set.
+	EnvPolicyAppend = "append"
+	// EnvPolicyOverride overrides variables already set.
+	EnvPolicyOverride = "override"
+	// EnvPolicySkipIfPresent only sets variables not set yet.
+	EnvPolicySkipIfPresent = "skip-if-present"
+)
+
+// Capabilities are capabilities to add to or drop from the bounding,
+// effective and permitted capability sets of the container process.
+// Capabilities are named like "CAP_SYS_RAWIO".
+type Capabilities struct {
+	Add  []string `json:"add,omitempty"`
+	Drop []string `json:"drop,omitempty"`
 }
 
 // DeviceNode represents a device node that needs to be added to the OCI spec.
@@ -43,8 +217,16 @@ type DeviceNode struct {
 	Permissions string       `json:"permissions,omitempty"`
 	UID         *uint32      `json:"uid,omitempty"`
 	GID         *uint32      `json:"gid,omitempty"`
+	// DenyAccess adds a rule explicitly denying access to the device if
+	// Permissions is DevicePermissionsNone. Added in v0.10.0.
+	DenyAccess bool `json:"denyAccess,omitempty"`
 }
 
+// DevicePermissionsNone are device node permissions granting no access
+// to the device. The device node is created but it can't be used until
+// access is granted by other means. Added in v0.10.0.
+const DevicePermissionsNone = "none"
+
 // Mount represents a mount that needs to be added to the OCI spec.
 type Mount struct {
 	HostPath      string   `json:"hostPath"`
@@ -60,6 +242,9 @@ type Hook struct {
 	Args     []string `json:"args,omitempty"`
 	Env      []string `json:"env,omitempty"`
 	Timeout  *int     `json:"timeout,omitempty"`
+	// InheritEnv passes the environment of the container to the hook, in
+	// addition to Env. Added in v0.10.0.
+	InheritEnv bool `json:"inheritEnv,omitempty"`
 }
 
 // IntelRdt describes the Linux IntelRdt parameters to set in the OCI spec.
@@ -0,0 +1,591 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package specs
+
+import (
+	"os"
+	"reflect"
+)
+
+// Deep copies and semantic equality of Specs and their parts. Equal()
+// treats nil and empty slices and maps as equal, since they marshal to
+// the same data. All methods accept a nil receiver.
+
+// DeepCopy returns a deep copy of the Spec.
+func (in *Spec) DeepCopy() *Spec {
+	if in == nil {
+		return nil
+	}
+	out := &Spec{}
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto deep copies the Spec into out.
+func (in *Spec) DeepCopyInto(out *Spec) {
+	*out = *in
+	out.Annotations = copyStringMap(in.Annotations)
+	if in.Devices != nil {
+		out.Devices = make([]Device, len(in.Devices))
+		for i := range in.Devices {
+			in.Devices[i].DeepCopyInto(&out.Devices[i])
+		}
+	}
+	in.ContainerEdits.DeepCopyInto(&out.ContainerEdits)
+	if in.Groups != nil {
+		out.Groups = make([]DeviceGroup, len(in.Groups))
+		for i := range in.Groups {
+			in.Groups[i].DeepCopyInto(&out.Groups[i])
+		}
+	}
+	out.Lifecycle = in.Lifecycle.DeepCopy()
+	out.Platform = in.Platform.DeepCopy()
+	if in.UnknownFields != nil {
+		out.UnknownFields, _ = copyGeneric(in.UnknownFields).(map[string]interface{})
+	}
+}
+
+// Equal returns true if the Spec is equal to the other one.
+func (in *Spec) Equal(other *Spec) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	if in.Version != other.Version || in.Kind != other.Kind ||
+		!equalStringMap(in.Annotations, other.Annotations) ||
+		len(in.Devices) != len(other.Devices) ||
+		len(in.Groups) != len(other.Groups) ||
+		!in.ContainerEdits.Equal(&other.ContainerEdits) ||
+		!in.Lifecycle.Equal(other.Lifecycle) ||
+		!in.Platform.Equal(other.Platform) {
+		return false
+	}
+	for i := range in.Devices {
+		if !in.Devices[i].Equal(&other.Devices[i]) {
+			return false
+		}
+	}
+	for i := range in.Groups {
+		if !in.Groups[i].Equal(&other.Groups[i]) {
+			return false
+		}
+	}
+	if len(in.UnknownFields) != 0 || len(other.UnknownFields) != 0 {
+		return reflect.DeepEqual(in.UnknownFields, other.UnknownFields)
+	}
+	return true
+}
+
+// DeepCopy returns a deep copy of the DeviceGroup.
+func (in *DeviceGroup) DeepCopy() *DeviceGroup {
+	if in == nil {
+		return nil
+	}
+	out := &DeviceGroup{}
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto deep copies the DeviceGroup into out.
+func (in *DeviceGroup) DeepCopyInto(out *DeviceGroup) {
+	*out = *in
+	out.Devices = copyStrings(in.Devices)
+}
+
+// Equal returns true if the DeviceGroup is equal to the other one.
+func (in *DeviceGroup) Equal(other *DeviceGroup) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	return in.Name == other.Name && equalStrings(in.Devices, other.Devices)
+}
+
+// DeepCopy returns a deep copy of the Device.
+func (in *Device) DeepCopy() *Device {
+	if in == nil {
+		return nil
+	}
+	out := &Device{}
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto deep copies the Device into out.
+func (in *Device) DeepCopyInto(out *Device) {
+	*out = *in
+	out.Annotations = copyStringMap(in.Annotations)
+	in.ContainerEdits.DeepCopyInto(&out.ContainerEdits)
+	out.Properties = in.Properties.DeepCopy()
+	out.Capacity = in.Capacity.DeepCopy()
+	out.Lifecycle = in.Lifecycle.DeepCopy()
+	out.Platform = in.Platform.DeepCopy()
+	if in.Readiness != nil {
+		out.Readiness = make([]ReadinessProbe, len(in.Readiness))
+		for i := range in.Readiness {
+			in.Readiness[i].DeepCopyInto(&out.Readiness[i])
+		}
+	}
+}
+
+// Equal returns true if the Device is equal to the other one.
+func (in *Device) Equal(other *Device) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	if len(in.Readiness) != len(other.Readiness) {
+		return false
+	}
+	for i := range in.Readiness {
+		if !in.Readiness[i].Equal(&other.Readiness[i]) {
+			return false
+		}
+	}
+	return in.Name == other.Name &&
+		equalStringMap(in.Annotations, other.Annotations) &&
+		in.ContainerEdits.Equal(&other.ContainerEdits) &&
+		in.Properties.Equal(other.Properties) &&
+		in.Capacity.Equal(other.Capacity) &&
+		in.Lifecycle.Equal(other.Lifecycle) &&
+		in.RenamedFrom == other.RenamedFrom &&
+		in.Platform.Equal(other.Platform)
+}
+
+// DeepCopyInto deep copies the ReadinessProbe into out.
+func (in *ReadinessProbe) DeepCopyInto(out *ReadinessProbe) {
+	*out = *in
+	if in.SysfsAttribute != nil {
+		attr := *in.SysfsAttribute
+		out.SysfsAttribute = &attr
+	}
+}
+
+// Equal returns true if the ReadinessProbe is equal to the other one.
+func (in *ReadinessProbe) Equal(other *ReadinessProbe) bool {
+	if in.FileExists != other.FileExists || in.DeviceOpenable != other.DeviceOpenable {
+		return false
+	}
+	if in.SysfsAttribute == nil || other.SysfsAttribute == nil {
+		return in.SysfsAttribute == other.SysfsAttribute
+	}
+	return *in.SysfsAttribute == *other.SysfsAttribute
+}
+
+// DeepCopy returns a deep copy of the PlatformConstraints.
+func (in *PlatformConstraints) DeepCopy() *PlatformConstraints {
+	if in == nil {
+		return nil
+	}
+	out := &PlatformConstraints{
+		OS:   copyStrings(in.OS),
+		Arch: copyStrings(in.Arch),
+	}
+	if in.KernelVersion != nil {
+		kernel := *in.KernelVersion
+		out.KernelVersion = &kernel
+	}
+	return out
+}
+
+// Equal returns true if the PlatformConstraints are equal to the other ones.
+func (in *PlatformConstraints) Equal(other *PlatformConstraints) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	if !equalStrings(in.OS, other.OS) || !equalStrings(in.Arch, other.Arch) {
+		return false
+	}
+	if in.KernelVersion == nil || other.KernelVersion == nil {
+		return in.KernelVersion == other.KernelVersion
+	}
+	return *in.KernelVersion == *other.KernelVersion
+}
+
+// DeepCopy returns a deep copy of the Lifecycle.
+func (in *Lifecycle) DeepCopy() *Lifecycle {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
+
+// Equal returns true if the Lifecycle is equal to the other one.
+func (in *Lifecycle) Equal(other *Lifecycle) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	return *in == *other
+}
+
+// DeepCopy returns a deep copy of the DeviceCapacity.
+func (in *DeviceCapacity) DeepCopy() *DeviceCapacity {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
+
+// Equal returns true if the DeviceCapacity is equal to the other one.
+func (in *DeviceCapacity) Equal(other *DeviceCapacity) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	return *in == *other
+}
+
+// DeepCopy returns a deep copy of the DeviceProperties.
+func (in *DeviceProperties) DeepCopy() *DeviceProperties {
+	if in == nil {
+		return nil
+	}
+	return &DeviceProperties{
+		Topology:   in.Topology.DeepCopy(),
+		Attributes: copyStringMap(in.Attributes),
+	}
+}
+
+// Equal returns true if the DeviceProperties are equal to the other ones.
+func (in *DeviceProperties) Equal(other *DeviceProperties) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	return in.Topology.Equal(other.Topology) &&
+		equalStringMap(in.Attributes, other.Attributes)
+}
+
+// DeepCopy returns a deep copy of the DeviceTopology.
+func (in *DeviceTopology) DeepCopy() *DeviceTopology {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	out.NUMANode = copyInt(in.NUMANode)
+	if in.Links != nil {
+		out.Links = make([]DeviceLink, len(in.Links))
+		copy(out.Links, in.Links)
+	}
+	return &out
+}
+
+// Equal returns true if the DeviceTopology is equal to the other one.
+func (in *DeviceTopology) Equal(other *DeviceTopology) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	if in.PCIAddress != other.PCIAddress || !equalInt(in.NUMANode, other.NUMANode) ||
+		len(in.Links) != len(other.Links) {
+		return false
+	}
+	for i := range in.Links {
+		if in.Links[i] != other.Links[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// DeepCopy returns a deep copy of the ContainerEdits.
+func (in *ContainerEdits) DeepCopy() *ContainerEdits {
+	if in == nil {
+		return nil
+	}
+	out := &ContainerEdits{}
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto deep copies the ContainerEdits into out.
+func (in *ContainerEdits) DeepCopyInto(out *ContainerEdits) {
+	*out = *in
+	out.Env = copyStrings(in.Env)
+	if in.DeviceNodes != nil {
+		out.DeviceNodes = make([]*DeviceNode, len(in.DeviceNodes))
+		for i, d := range in.DeviceNodes {
+			out.DeviceNodes[i] = d.DeepCopy()
+		}
+	}
+	if in.Hooks != nil {
+		out.Hooks = make([]*Hook, len(in.Hooks))
+		for i, h := range in.Hooks {
+			out.Hooks[i] = h.DeepCopy()
+		}
+	}
+	if in.Mounts != nil {
+		out.Mounts = make([]*Mount, len(in.Mounts))
+		for i, m := range in.Mounts {
+			out.Mounts[i] = m.DeepCopy()
+		}
+	}
+	out.IntelRdt = in.IntelRdt.DeepCopy()
+	if in.AdditionalGIDs != nil {
+		out.AdditionalGIDs = make([]uint32, len(in.AdditionalGIDs))
+		copy(out.AdditionalGIDs, in.AdditionalGIDs)
+	}
+	out.Capabilities = in.Capabilities.DeepCopy()
+}
+
+// Equal returns true if the ContainerEdits are equal to the other ones.
+func (in *ContainerEdits) Equal(other *ContainerEdits) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	if in.EnvPolicy != other.EnvPolicy ||
+		!equalStrings(in.Env, other.Env) ||
+		!in.IntelRdt.Equal(other.IntelRdt) ||
+		!in.Capabilities.Equal(other.Capabilities) ||
+		len(in.DeviceNodes) != len(other.DeviceNodes) ||
+		len(in.Hooks) != len(other.Hooks) ||
+		len(in.Mounts) != len(other.Mounts) ||
+		len(in.AdditionalGIDs) != len(other.AdditionalGIDs) {
+		return false
+	}
+	for i := range in.DeviceNodes {
+		if !in.DeviceNodes[i].Equal(other.DeviceNodes[i]) {
+			return false
+		}
+	}
+	for i := range in.Hooks {
+		if !in.Hooks[i].Equal(other.Hooks[i]) {
+			return false
+		}
+	}
+	for i := range in.Mounts {
+		if !in.Mounts[i].Equal(other.Mounts[i]) {
+			return false
+		}
+	}
+	for i := range in.AdditionalGIDs {
+		if in.AdditionalGIDs[i] != other.AdditionalGIDs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// DeepCopy returns a deep copy of the DeviceNode.
+func (in *DeviceNode) DeepCopy() *DeviceNode {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	out.UID = copyUint32(in.UID)
+	out.GID = copyUint32(in.GID)
+	if in.FileMode != nil {
+		mode := *in.FileMode
+		out.FileMode = &mode
+	}
+	return &out
+}
+
+// Equal returns true if the DeviceNode is equal to the other one.
+func (in *DeviceNode) Equal(other *DeviceNode) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	return in.Path == other.Path &&
+		in.HostPath == other.HostPath &&
+		in.Type == other.Type &&
+		in.Major == other.Major &&
+		in.Minor == other.Minor &&
+		equalFileMode(in.FileMode, other.FileMode) &&
+		in.Permissions == other.Permissions &&
+		equalUint32(in.UID, other.UID) &&
+		equalUint32(in.GID, other.GID) &&
+		in.DenyAccess == other.DenyAccess
+}
+
+// DeepCopy returns a deep copy of the Mount.
+func (in *Mount) DeepCopy() *Mount {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	out.Options = copyStrings(in.Options)
+	return &out
+}
+
+// Equal returns true if the Mount is equal to the other one.
+func (in *Mount) Equal(other *Mount) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	return in.HostPath == other.HostPath &&
+		in.ContainerPath == other.ContainerPath &&
+		in.Type == other.Type &&
+		equalStrings(in.Options, other.Options)
+}
+
+// DeepCopy returns a deep copy of the Hook.
+func (in *Hook) DeepCopy() *Hook {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	out.Args = copyStrings(in.Args)
+	out.Env = copyStrings(in.Env)
+	out.Timeout = copyInt(in.Timeout)
+	return &out
+}
+
+// Equal returns true if the Hook is equal to the other one.
+func (in *Hook) Equal(other *Hook) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	return in.HookName == other.HookName &&
+		in.Path == other.Path &&
+		equalStrings(in.Args, other.Args) &&
+		equalStrings(in.Env, other.Env) &&
+		equalInt(in.Timeout, other.Timeout) &&
+		in.InheritEnv == other.InheritEnv
+}
+
+// DeepCopy returns a deep copy of the IntelRdt.
+func (in *IntelRdt) DeepCopy() *IntelRdt {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
+
+// Equal returns true if the IntelRdt is equal to the other one.
+func (in *IntelRdt) Equal(other *IntelRdt) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	return *in == *other
+}
+
+// DeepCopy returns a deep copy of the Capabilities.
+func (in *Capabilities) DeepCopy() *Capabilities {
+	if in == nil {
+		return nil
+	}
+	return &Capabilities{
+		Add:  copyStrings(in.Add),
+		Drop: copyStrings(in.Drop),
+	}
+}
+
+// Equal returns true if the Capabilities are equal to the other ones.
+func (in *Capabilities) Equal(other *Capabilities) bool {
+	if in == nil || other == nil {
+		return in == other
+	}
+	return equalStrings(in.Add, other.Add) && equalStrings(in.Drop, other.Drop)
+}
+
+func copyStrings(in []string) []string {
+	if in == nil {
+		return nil
+	}
+	out := make([]string, len(in))
+	copy(out, in)
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func copyStringMap(in map[string]string) map[string]string {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+func equalStringMap(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+func copyInt(in *int) *int {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
+
+func equalInt(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func copyUint32(in *uint32) *uint32 {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
+
+func equalUint32(a, b *uint32) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalFileMode(a, b *os.FileMode) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// copyGeneric returns a deep copy of generic JSON data.
+func copyGeneric(in interface{}) interface{} {
+	switch v := in.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			out[key] = copyGeneric(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = copyGeneric(val)
+		}
+		return out
+	}
+	return in
+}
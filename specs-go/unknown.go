@@ -0,0 +1,167 @@
+/*
+   Copyright © The CDI Authors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package specs
+
+import (
+	"encoding/json"
+)
+
+// MarshalJSON marshals the Spec, merging any UnknownFields back into
+// the known ones. Known fields always take precedence. UnknownFields are
+// only written for Specs still declaring a CDI version newer than the
+// current one. Once a Spec is migrated to a known version they are
+// dropped, since they would make the Spec invalid for that version.
+func (s Spec) MarshalJSON() ([]byte, error) {
+	type plain Spec
+	data, err := json.Marshal(plain(s))
+	if err != nil || len(s.UnknownFields) == 0 || !newVersion(s.Version).isGreaterThan(vCurrent) {
+		return data, err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	mergeUnknown(doc, s.UnknownFields)
+
+	return json.Marshal(doc)
+}
+
+// CaptureUnknownFields sets the UnknownFields of the Spec to the fields
+// of the given generic document, usually the one the Spec was parsed
+// from, which are not known to the Spec. Elements of lists are matched
+// by position. Captured list elements with a "name" keep it, so they can
+// be matched by name once the Spec has been modified.
+func CaptureUnknownFields(spec *Spec, doc map[string]interface{}) error {
+	spec.UnknownFields = nil
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	var known map[string]interface{}
+	if err := json.Unmarshal(data, &known); err != nil {
+		return err
+	}
+
+	if unknown, ok := diffUnknown(doc, known).(map[string]interface{}); ok {
+		spec.UnknownFields = unknown
+	}
+	return nil
+}
+
+// diffUnknown returns the parts of doc missing from known, or nil.
+func diffUnknown(doc, known interface{}) interface{} {
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		k, ok := known.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		unknown := map[string]interface{}{}
+		for key, val := range d {
+			kval, ok := k[key]
+			if !ok {
+				unknown[key] = val
+				continue
+			}
+			if diff := diffUnknown(val, kval); diff != nil {
+				unknown[key] = diff
+			}
+		}
+		if len(unknown) == 0 {
+			return nil
+		}
+		return unknown
+
+	case []interface{}:
+		k, ok := known.([]interface{})
+		if !ok {
+			return nil
+		}
+		var unknown []interface{}
+		for i := 0; i < len(d) && i < len(k); i++ {
+			diff := diffUnknown(d[i], k[i])
+			if diff == nil {
+				continue
+			}
+			if m, ok := diff.(map[string]interface{}); ok {
+				if elem, ok := d[i].(map[string]interface{}); ok {
+					if name, ok := elem["name"].(string); ok {
+						m["name"] = name
+					}
+				}
+			}
+			for len(unknown) < i {
+				unknown = append(unknown, nil)
+			}
+			unknown = append(unknown, diff)
+		}
+		if len(unknown) == 0 {
+			return nil
+		}
+		return unknown
+	}
+
+	return nil
+}
+
+// mergeUnknown merges unknown fields into the given known document.
+func mergeUnknown(known map[string]interface{}, unknown map[string]interface{}) {
+	for key, val := range unknown {
+		kval, ok := known[key]
+		if !ok {
+			known[key] = val
+			continue
+		}
+		switch u := val.(type) {
+		case map[string]interface{}:
+			if k, ok := kval.(map[string]interface{}); ok {
+				mergeUnknown(k, u)
+			}
+		case []interface{}:
+			if k, ok := kval.([]interface{}); ok {
+				mergeUnknownList(k, u)
+			}
+		}
+	}
+}
+
+// mergeUnknownList merges unknown list elements into the known list.
+// Elements with a name are matched by name, others by position.
+func mergeUnknownList(known []interface{}, unknown []interface{}) {
+	for i, val := range unknown {
+		u, ok := val.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var target map[string]interface{}
+		if name, ok := u["name"].(string); ok {
+			for _, kval := range known {
+				if k, ok := kval.(map[string]interface{}); ok && k["name"] == name {
+					target = k
+					break
+				}
+			}
+		} else if i < len(known) {
+			target, _ = known[i].(map[string]interface{})
+		}
+		if target != nil {
+			mergeUnknown(target, u)
+		}
+	}
+}
@@ -25,20 +25,22 @@ import (
 
 const (
 	// CurrentVersion is the current version of the Spec.
-	CurrentVersion = "0.8.0"
+	CurrentVersion = "0.10.0"
 
 	// vCurrent is the current version as a semver-comparable type
 	vCurrent version = "v" + CurrentVersion
 
 	// These represent the released versions of the CDI specification
-	v010 version = "v0.1.0"
-	v020 version = "v0.2.0"
-	v030 version = "v0.3.0"
-	v040 version = "v0.4.0"
-	v050 version = "v0.5.0"
-	v060 version = "v0.6.0"
-	v070 version = "v0.7.0"
-	v080 version = "v0.8.0"
+	v010  version = "v0.1.0"
+	v020  version = "v0.2.0"
+	v030  version = "v0.3.0"
+	v040  version = "v0.4.0"
+	v050  version = "v0.5.0"
+	v060  version = "v0.6.0"
+	v070  version = "v0.7.0"
+	v080  version = "v0.8.0"
+	v090  version = "v0.9.0"
+	v0100 version = "v0.10.0"
 
 	// vEarliest is the earliest supported version of the CDI specification
 	vEarliest version = v030
@@ -48,14 +50,16 @@ const (
 // Adding new fields / spec versions requires that a `requiredFunc` be implemented and
 // this map be updated.
 var validSpecVersions = requiredVersionMap{
-	v010: nil,
-	v020: nil,
-	v030: nil,
-	v040: requiresV040,
-	v050: requiresV050,
-	v060: requiresV060,
-	v070: requiresV070,
-	v080: requiresV080,
+	v010:  nil,
+	v020:  nil,
+	v030:  nil,
+	v040:  requiresV040,
+	v050:  requiresV050,
+	v060:  requiresV060,
+	v070:  requiresV070,
+	v080:  requiresV080,
+	v090:  requiresV090,
+	v0100: requiresV0100,
 }
 
 // ValidateVersion checks whether the specified spec version is valid.
@@ -82,6 +86,27 @@ func MinimumRequiredVersion(spec *Spec) (string, error) {
 	return minVersion.String(), nil
 }
 
+// RequiredVersionForEdits determines the minimum spec version for the
+// given container edits, regardless of whether they are used at the Spec
+// or at the device level. Producers assembling a Spec incrementally can use
+// it to check early whether some edits require a newer spec version.
+func RequiredVersionForEdits(edits *ContainerEdits) (string, error) {
+	if edits == nil {
+		return vEarliest.String(), nil
+	}
+	return MinimumRequiredVersion(&Spec{ContainerEdits: *edits})
+}
+
+// RequiredVersionForDevice determines the minimum spec version for the
+// given device, taking its name, annotations, other properties and its
+// container edits into account.
+func RequiredVersionForDevice(device *Device) (string, error) {
+	if device == nil {
+		return vEarliest.String(), nil
+	}
+	return MinimumRequiredVersion(&Spec{Devices: []Device{*device}})
+}
+
 // version represents a semantic version string
 type version string
 
@@ -138,6 +163,113 @@ func (r requiredVersionMap) requiredVersion(spec *Spec) version {
 	return minVersion
 }
 
+// requiresV0100 returns true if the spec uses v0.10.0 features.
+func requiresV0100(spec *Spec) bool {
+	// The v0.10.0 spec allows device properties, capacity, lifecycle,
+	// renames, platform constraints and readiness probes.
+	if spec.Lifecycle != nil || spec.Platform != nil {
+		return true
+	}
+	for _, d := range spec.Devices {
+		if d.Properties != nil || d.Capacity != nil || d.Lifecycle != nil || d.RenamedFrom != "" ||
+			d.Platform != nil || len(d.Readiness) > 0 {
+			return true
+		}
+	}
+
+	edits := []*ContainerEdits{&spec.ContainerEdits}
+	for i := range spec.Devices {
+		edits = append(edits, &spec.Devices[i].ContainerEdits)
+	}
+
+	// The v0.10.0 spec allows environment merge policies and capabilities.
+	for _, e := range edits {
+		if e.EnvPolicy != "" || e.Capabilities != nil {
+			return true
+		}
+	}
+
+	// The v0.10.0 spec allows device nodes without access.
+	for _, e := range edits {
+		for _, d := range e.DeviceNodes {
+			if d != nil && (d.Permissions == DevicePermissionsNone || d.DenyAccess) {
+				return true
+			}
+		}
+	}
+
+	// The v0.10.0 spec allows idmapped mounts.
+	for _, e := range edits {
+		for _, m := range e.Mounts {
+			if m != nil && hasIDMapOption(m) {
+				return true
+			}
+		}
+	}
+
+	// The v0.10.0 spec allows hooks inheriting the container environment.
+	for _, e := range edits {
+		for _, h := range e.Hooks {
+			if h != nil && h.InheritEnv {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasIDMapOption returns true if the mount is an idmapped mount, in
+// other words if its options include either idmap or ridmap.
+func hasIDMapOption(m *Mount) bool {
+	for _, o := range m.Options {
+		if o == "idmap" || o == "ridmap" {
+			return true
+		}
+	}
+	return false
+}
+
+// requiresV090 returns true if the spec uses v0.9.0 features.
+func requiresV090(spec *Spec) bool {
+	// The v0.9.0 spec allows named device groups to be specified.
+	if len(spec.Groups) > 0 {
+		return true
+	}
+
+	edits := []*ContainerEdits{&spec.ContainerEdits}
+	for i := range spec.Devices {
+		edits = append(edits, &spec.Devices[i].ContainerEdits)
+	}
+
+	// The v0.9.0 spec allows templates in hook arguments and environment.
+	for _, e := range edits {
+		for _, h := range e.Hooks {
+			if h != nil && hasHookTemplate(h) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasHookTemplate returns true if any argument or environment variable of
+// the hook is a template, in other words if it contains "{{".
+func hasHookTemplate(h *Hook) bool {
+	for _, s := range h.Args {
+		if strings.Contains(s, "{{") {
+			return true
+		}
+	}
+	for _, s := range h.Env {
+		if strings.Contains(s, "{{") {
+			return true
+		}
+	}
+	return false
+}
+
 // requiresV080 returns true if the spec uses v0.8.0 features.
 // Since the v0.8.0 spec bump was due to the removed .ToOCI functions on the
 // spec types, there are explicit spec changes.